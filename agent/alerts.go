@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	alertsFile = "alerts.json"
+
+	alertWebhookTimeout = 10 * time.Second
+
+	alertStatusFiring   = "firing"
+	alertStatusResolved = "resolved"
+)
+
+// Resultados de alertCondition.evaluate.
+const (
+	alertConditionUnknown = iota - 1
+	alertConditionOK
+	alertConditionFiring
+)
+
+// ActiveAlert e um alerta em disparo, enviado no payload e no --status.
+type ActiveAlert struct {
+	Name  string    `json:"name"`
+	When  string    `json:"when"`
+	Value string    `json:"value,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+type alertCondition struct {
+	metric    string
+	op        string
+	threshold float64
+	container string
+	check     string
+}
+
+func parseAlertCondition(when string) (alertCondition, error) {
+	f := strings.Fields(when)
+	switch {
+	case len(f) == 4 && f[0] == "container" && f[2] == "not" && f[3] == "running":
+		return alertCondition{container: f[1]}, nil
+	case len(f) == 3 && f[0] == "check" && f[2] == "down":
+		return alertCondition{check: f[1]}, nil
+	case len(f) == 3:
+		if _, ok := metricValue(Metrics{}, f[0]); !ok {
+			return alertCondition{}, fmt.Errorf("unknown metric %q", f[0])
+		}
+		switch f[1] {
+		case ">", ">=", "<", "<=", "==", "!=":
+		default:
+			return alertCondition{}, fmt.Errorf("unknown operator %q", f[1])
+		}
+		v, err := strconv.ParseFloat(f[2], 64)
+		if err != nil {
+			return alertCondition{}, fmt.Errorf("invalid value %q", f[2])
+		}
+		return alertCondition{metric: f[0], op: f[1], threshold: v}, nil
+	}
+	return alertCondition{}, errors.New("expected \"<metric> <op> <value>\", \"container <name> not running\" or \"check <name> down\"")
+}
+
+func validateAlerts(a config.AlertsConfig) error {
+	for key, raw := range map[string]string{"webhook_url": a.WebhookURL, "slack_webhook_url": a.SlackWebhookURL} {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("alerts: invalid %s %q", key, raw)
+		}
+	}
+	if err := a.Telegram.Validate(); err != nil {
+		return err
+	}
+	if err := a.Email.Validate(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(a.Rules))
+	for _, r := range a.Rules {
+		if r.Name == "" {
+			return errors.New("alerts: name is required")
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("alerts: duplicate name %q", r.Name)
+		}
+		seen[r.Name] = true
+		if _, err := parseAlertCondition(r.When); err != nil {
+			return fmt.Errorf("alerts: %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// metricValue le um campo numerico de Metrics pelo nome no JSON.
+func metricValue(m Metrics, name string) (float64, bool) {
+	v := reflect.ValueOf(m)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return numericValue(v.Field(i))
+		}
+	}
+	return 0, false
+}
+
+// evaluate devolve alertConditionUnknown quando o ciclo nao tem o dado (ex.:
+// metricas em heartbeat, check nao configurado); o estado anterior e mantido.
+func (c alertCondition) evaluate(p Payload) (int, string) {
+	switch {
+	case c.container != "":
+		if p.ContainerRuntimeStatus == "" || p.ContainerRuntimeStatus == runtimeTimeout {
+			return alertConditionUnknown, ""
+		}
+		for _, ct := range p.allContainers {
+			if ct.Name == c.container {
+				if ct.muted() {
+					return alertConditionUnknown, ct.State
+				}
+				if ct.State == "running" {
+					return alertConditionOK, ct.State
+				}
+				return alertConditionFiring, ct.State
+			}
+		}
+		return alertConditionFiring, "missing"
+	case c.check != "":
+		for _, r := range p.Checks {
+			if r.Name == c.check {
+				// check instavel nao abre nem resolve alerta: o estado
+				// anterior fica ate ela estabilizar
+				if r.Stability != nil && r.Stability.Flapping {
+					return alertConditionUnknown, ""
+				}
+				if r.Up {
+					return alertConditionOK, ""
+				}
+				return alertConditionFiring, r.Error
+			}
+		}
+		return alertConditionUnknown, ""
+	}
+
+	if p.Heartbeat {
+		return alertConditionUnknown, ""
+	}
+	v, _ := metricValue(p.Metrics, c.metric)
+	var firing bool
+	switch c.op {
+	case ">":
+		firing = v > c.threshold
+	case ">=":
+		firing = v >= c.threshold
+	case "<":
+		firing = v < c.threshold
+	case "<=":
+		firing = v <= c.threshold
+	case "==":
+		firing = v == c.threshold
+	case "!=":
+		firing = v != c.threshold
+	}
+	value := strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	if firing {
+		return alertConditionFiring, value
+	}
+	return alertConditionOK, value
+}
+
+// alertState e o estado persistido de uma regra entre ciclos, que no modo
+// cron sao processos diferentes.
+type alertState struct {
+	When         string    `json:"when"`
+	Firing       bool      `json:"firing"`
+	Since        time.Time `json:"since,omitempty"`
+	Value        string    `json:"value,omitempty"`
+	LastNotified time.Time `json:"last_notified,omitempty"`
+	Notified     bool      `json:"notified,omitempty"`
+}
+
+func alertsPath(stateDir string) string {
+	return filepath.Join(stateDir, alertsFile)
+}
+
+func loadAlertStates(stateDir string) map[string]*alertState {
+	states := map[string]*alertState{}
+	if b, err := os.ReadFile(alertsPath(stateDir)); err == nil {
+		json.Unmarshal(b, &states)
+	}
+	return states
+}
+
+func saveAlertStates(stateDir string, states map[string]*alertState) error {
+	if err := fsutil.EnsureDir(stateDir); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(alertsPath(stateDir), b, 0o600)
+}
+
+// activeAlerts lista os alertas em disparo segundo o estado salvo.
+func activeAlerts(states map[string]*alertState) []ActiveAlert {
+	var active []ActiveAlert
+	for _, name := range sortedKeys(states) {
+		if s := states[name]; s.Firing {
+			active = append(active, ActiveAlert{Name: name, When: s.When, Value: s.Value, Since: s.Since})
+		}
+	}
+	return active
+}
+
+// evaluateAlerts avalia as regras contra o payload do ciclo, avisa o webhook
+// nas transicoes (e de novo apos o cooldown, se continuar disparando) e
+// devolve os alertas ativos e as transicoes como eventos.
+func evaluateAlerts(cfg config.Config, stateDir string, p Payload) ([]ActiveAlert, []PayloadEvent) {
+	if len(cfg.Alerts.Rules) == 0 {
+		os.Remove(alertsPath(stateDir))
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	previous := loadAlertStates(stateDir)
+	states := make(map[string]*alertState, len(cfg.Alerts.Rules))
+	var events []PayloadEvent
+
+	for _, rule := range cfg.Alerts.Rules {
+		cond, err := parseAlertCondition(rule.When)
+		if err != nil {
+			continue
+		}
+		st := previous[rule.Name]
+		if st == nil || st.When != rule.When {
+			st = &alertState{When: rule.When}
+		}
+		states[rule.Name] = st
+
+		result, value := cond.evaluate(p)
+		if result == alertConditionUnknown {
+			continue
+		}
+		st.Value = value
+
+		switch {
+		case result == alertConditionFiring && !st.Firing:
+			st.Firing, st.Since, st.Notified = true, now, false
+			events = append(events, newEvent(eventAlertFiring, rule.Name, value, now))
+			if now.Sub(st.LastNotified) >= cfg.Alerts.Cooldown() {
+				st.Notified = notifyAlert(cfg, p, rule, st, alertStatusFiring, now)
+			}
+		case result == alertConditionFiring && now.Sub(st.LastNotified) >= cfg.Alerts.Cooldown():
+			st.Notified = notifyAlert(cfg, p, rule, st, alertStatusFiring, now) || st.Notified
+		case result == alertConditionOK && st.Firing:
+			events = append(events, newEvent(eventAlertResolved, rule.Name, value, now))
+			// so avisa a resolucao de um disparo que foi avisado
+			if st.Notified {
+				notifyAlert(cfg, p, rule, st, alertStatusResolved, now)
+			}
+			st.Firing, st.Since, st.Notified = false, time.Time{}, false
+		}
+	}
+
+	if err := saveAlertStates(stateDir, states); err != nil {
+		fmt.Fprintf(os.Stderr, "alerts: %v\n", err)
+	}
+	return activeAlerts(states), events
+}
+
+// alertWebhook e o corpo enviado ao webhook; "text" deixa o aviso legivel
+// em webhooks de chat sem nenhuma configuracao extra.
+type alertWebhook struct {
+	Alert    string               `json:"alert"`
+	When     string               `json:"when"`
+	Status   string               `json:"status"`
+	Value    string               `json:"value,omitempty"`
+	Hostname string               `json:"hostname"`
+	Metadata *config.HostMetadata `json:"metadata,omitempty"`
+	Since    time.Time            `json:"since,omitempty"`
+	Time     time.Time            `json:"time"`
+	Text     string               `json:"text"`
+}
+
+// notifyAlert envia o aviso a todos os canais configurados e diz se algum o
+// entregou; sem canais o alerta vai so no payload.
+func notifyAlert(cfg config.Config, p Payload, rule config.AlertRule, st *alertState, status string, now time.Time) bool {
+	sinks := alertSinks(cfg.Alerts)
+	if len(sinks) == 0 {
+		return false
+	}
+	hostname := cfg.Hostname
+	if p.Host != nil && p.Host.Hostname != "" {
+		hostname = p.Host.Hostname
+	}
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	msg := alertWebhook{
+		Alert:    rule.Name,
+		When:     rule.When,
+		Status:   status,
+		Value:    st.Value,
+		Hostname: hostname,
+		Metadata: payloadMetadata(cfg.Metadata),
+		Since:    st.Since,
+		Time:     now,
+		Text:     fmt.Sprintf("[%s] %s on %s: %s", strings.ToUpper(status), rule.Name, hostname, rule.When),
+	}
+	if st.Value != "" {
+		msg.Text += " (" + st.Value + ")"
+	}
+	delivered := false
+	for _, sink := range sinks {
+		if err := sink.send(cfg, msg); err != nil {
+			fmt.Fprintf(os.Stderr, "alerts: %s %s: %v\n", sink.name, rule.Name, err)
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		st.LastNotified = now
+	}
+	return delivered
+}
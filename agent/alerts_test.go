@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestParseAlertCondition(t *testing.T) {
+	tests := []struct {
+		when    string
+		want    alertCondition
+		wantErr bool
+	}{
+		{when: "disk_percent > 90", want: alertCondition{metric: "disk_percent", op: ">", threshold: 90}},
+		{when: "load_avg_1 <= 0.5", want: alertCondition{metric: "load_avg_1", op: "<=", threshold: 0.5}},
+		{when: "container web not running", want: alertCondition{container: "web"}},
+		{when: "check api down", want: alertCondition{check: "api"}},
+		{when: "disk > 90", wantErr: true},
+		{when: "disk_percent >> 90", wantErr: true},
+		{when: "disk_percent > ninety", wantErr: true},
+		{when: "container web stopped", wantErr: true},
+		{when: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAlertCondition(tt.when)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.when, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%q = %+v, want %+v", tt.when, got, tt.want)
+		}
+	}
+}
+
+// alertStep e um ciclo: o disco medido (negativo = heartbeat), se o alerta
+// deve estar ativo depois dele e o aviso esperado ("" = nenhum).
+type alertStep struct {
+	disk       float64
+	wantActive bool
+	wantNotify string
+}
+
+func TestEvaluateAlerts(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook bool
+		steps   []alertStep
+	}{
+		{
+			name:    "fires once and resolves",
+			webhook: true,
+			steps: []alertStep{
+				{disk: 50},
+				{disk: 95, wantActive: true, wantNotify: alertStatusFiring},
+				{disk: 97, wantActive: true}, // dentro do cooldown
+				{disk: 40, wantNotify: alertStatusResolved},
+				{disk: 40},
+			},
+		},
+		{
+			name:    "heartbeat keeps the state",
+			webhook: true,
+			steps: []alertStep{
+				{disk: 95, wantActive: true, wantNotify: alertStatusFiring},
+				{disk: -1, wantActive: true},
+				{disk: 40, wantNotify: alertStatusResolved},
+			},
+		},
+		{
+			// cooldown vale entre disparos: o segundo nao avisa de novo
+			name:    "flapping within cooldown",
+			webhook: true,
+			steps: []alertStep{
+				{disk: 95, wantActive: true, wantNotify: alertStatusFiring},
+				{disk: 40, wantNotify: alertStatusResolved},
+				{disk: 95, wantActive: true},
+				{disk: 40},
+			},
+		},
+		{
+			name: "no sinks",
+			steps: []alertStep{
+				{disk: 95, wantActive: true},
+				{disk: 40},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var msg alertWebhook
+				json.NewDecoder(r.Body).Decode(&msg)
+				mu.Lock()
+				got = append(got, msg.Status)
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			cfg := config.Config{Alerts: config.AlertsConfig{Rules: []config.AlertRule{{Name: "disk", When: "disk_percent > 90"}}}}
+			if tt.webhook {
+				cfg.Alerts.WebhookURL = srv.URL
+			}
+			stateDir := t.TempDir()
+			for i, step := range tt.steps {
+				mu.Lock()
+				got = nil
+				mu.Unlock()
+
+				p := Payload{Host: &HostInfo{Hostname: "web1"}, Metrics: Metrics{DiskPercent: step.disk}, Timestamp: time.Now()}
+				if step.disk < 0 {
+					p.Metrics, p.Heartbeat = Metrics{}, true
+				}
+				active, _ := evaluateAlerts(cfg, stateDir, p)
+				if (len(active) == 1) != step.wantActive {
+					t.Errorf("step %d: active = %+v, want active %v", i, active, step.wantActive)
+				}
+
+				var want []string
+				if step.wantNotify != "" {
+					want = []string{step.wantNotify}
+				}
+				mu.Lock()
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("step %d: notified %v, want %v", i, got, want)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	defaultTelegramAPI = "https://api.telegram.org"
+	defaultSMTPPort    = 587
+)
+
+// alertSink e um canal de aviso configurado.
+type alertSink struct {
+	name string
+	send func(cfg config.Config, msg alertWebhook) error
+}
+
+func alertSinks(a config.AlertsConfig) []alertSink {
+	var sinks []alertSink
+	if a.WebhookURL != "" {
+		sinks = append(sinks, alertSink{"webhook", func(cfg config.Config, msg alertWebhook) error {
+			return postAlertJSON(cfg, a.WebhookURL, msg)
+		}})
+	}
+	if a.SlackWebhookURL != "" {
+		sinks = append(sinks, alertSink{"slack", func(cfg config.Config, msg alertWebhook) error {
+			return postAlertJSON(cfg, a.SlackWebhookURL, map[string]string{"text": msg.Text})
+		}})
+	}
+	if a.Telegram != nil {
+		sinks = append(sinks, alertSink{"telegram", func(cfg config.Config, msg alertWebhook) error {
+			return sendTelegram(a.Telegram, cfg, msg)
+		}})
+	}
+	if a.Email != nil {
+		sinks = append(sinks, alertSink{"email", func(cfg config.Config, msg alertWebhook) error {
+			return sendEmail(a.Email, cfg, msg)
+		}})
+	}
+	return sinks
+}
+
+func postAlertJSON(cfg config.Config, target string, v any) error {
+	// sem escape de HTML: "disk_percent > 90" fica legivel no chat
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", target, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vaultrix-agent/"+version)
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	client.Timeout = alertWebhookTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &httpStatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func sendTelegram(t *config.TelegramSink, cfg config.Config, msg alertWebhook) error {
+	api := strings.TrimSuffix(t.APIURL, "/")
+	if api == "" {
+		api = defaultTelegramAPI
+	}
+	err := postAlertJSON(cfg, api+"/bot"+t.BotToken+"/sendMessage", map[string]string{
+		"chat_id": string(t.ChatID),
+		"text":    msg.Text,
+	})
+	// o erro do cliente HTTP traz a URL, e nela o token do bot
+	return redactSecret(err, t.BotToken)
+}
+
+func redactSecret(err error, secret string) error {
+	if err == nil || secret == "" || !strings.Contains(err.Error(), secret) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), secret, "***"))
+}
+
+func sendEmail(e *config.EmailSink, cfg config.Config, msg alertWebhook) error {
+	port := e.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(e.SMTPHost, strconv.Itoa(port))
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Text)
+	fmt.Fprintf(&body, "Date: %s\r\n", msg.Time.Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&body, "Alert: %s\r\nStatus: %s\r\nHost: %s\r\nCondition: %s\r\n", msg.Alert, msg.Status, msg.Hostname, msg.When)
+	if msg.Value != "" {
+		fmt.Fprintf(&body, "Value: %s\r\n", msg.Value)
+	}
+	if !msg.Since.IsZero() {
+		fmt.Fprintf(&body, "Since: %s\r\n", msg.Since.Format(time.RFC3339))
+	}
+	if m := msg.Metadata; m != nil {
+		for _, line := range [][2]string{{"Owner", m.Owner}, {"Contact", m.Contact}, {"Runbook", m.Runbook}, {"Criticality", m.Criticality}} {
+			if line[1] != "" {
+				fmt.Fprintf(&body, "%s: %s\r\n", line[0], line[1])
+			}
+		}
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.SMTPHost)
+	}
+	dialer := &net.Dialer{Timeout: alertWebhookTimeout}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: e.SMTPHost})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	// smtp.SendMail nao tem prazo; um servidor mudo travaria o ciclo
+	conn.SetDeadline(time.Now().Add(alertWebhookTimeout))
+	c, err := smtp.NewClient(conn, e.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && port != 465 {
+		if err := c.StartTLS(&tls.Config{ServerName: e.SMTPHost}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(body.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
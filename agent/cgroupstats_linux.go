@@ -0,0 +1,328 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// cgroupRoot e variavel para os testes montarem uma arvore num diretorio
+// temporario.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// containerCgroup localiza o cgroup de um container. No v2 ha uma unica
+// arvore; no v1 cada controlador tem a sua, com o mesmo caminho relativo.
+type containerCgroup struct {
+	v2  bool
+	rel string
+}
+
+func (c containerCgroup) path(controller, file string) string {
+	if c.v2 {
+		return filepath.Join(cgroupRoot, c.rel, file)
+	}
+	return filepath.Join(cgroupRoot, controller, c.rel, file)
+}
+
+// cgroupStatsUsable indica se os cgroups do daemon sao visiveis daqui. Um
+// daemon remoto (tcp://, ssh://) roda em outra maquina.
+func cgroupStatsUsable(ep dockerEndpoint) bool {
+	return !ep.remote() && fsutil.FileExists(cgroupRoot)
+}
+
+// readCgroupStats le CPU, memoria, disco, rede e pids de cada container
+// direto do cgroup, sem passar pelo daemon. Devolve em missing os IDs cujo
+// cgroup nao foi encontrado (ex.: daemon com cgroupns proprio), para que o
+// chamador recorra ao docker stats.
+func readCgroupStats(ctx context.Context, targets []ContainerStatus) (stats []ContainerStatus, missing []string) {
+	index := cgroupContainerIndex()
+
+	groups := make([]containerCgroup, 0, len(targets))
+	found := make([]ContainerStatus, 0, len(targets))
+	for _, c := range targets {
+		cg, ok := lookupContainerCgroup(index, c.ID)
+		if !ok && c.pid > 0 {
+			cg, ok = cgroupForPID(c.pid)
+		}
+		if !ok {
+			missing = append(missing, c.ID)
+			continue
+		}
+		groups = append(groups, cg)
+		found = append(found, c)
+	}
+	if len(found) == 0 {
+		return nil, missing
+	}
+
+	// O uso de CPU sai da diferenca entre duas leituras, como no host.
+	before := make([]uint64, len(groups))
+	for i, cg := range groups {
+		before[i] = cgroupCPUUsage(cg)
+	}
+	start := time.Now()
+	select {
+	case <-time.After(cpuSampleInterval):
+	case <-ctx.Done():
+		return nil, missing
+	}
+	elapsed := time.Since(start)
+
+	var hostMemory uint64
+	if mem, err := readMemory(ctx); err == nil {
+		hostMemory = mem.total
+	}
+
+	stats = make([]ContainerStatus, 0, len(found))
+	for i, cg := range groups {
+		entry := ContainerStatus{ID: found[i].ID, Name: found[i].Name}
+
+		if after := cgroupCPUUsage(cg); after > before[i] && elapsed > 0 {
+			entry.CPUPercent = float64(after-before[i]) / float64(elapsed.Nanoseconds()) * 100
+		}
+
+		used, limit := cgroupMemory(cg)
+		if limit == 0 || (hostMemory > 0 && limit > hostMemory) {
+			limit = hostMemory
+		}
+		entry.MemUsage = formatBinaryBytes(used) + " / " + formatBinaryBytes(limit)
+		entry.MemUsageBytes, entry.MemLimitBytes = int64(used), int64(limit)
+		if limit > 0 {
+			entry.MemPercent = float64(used) / float64(limit) * 100
+		}
+
+		read, write := cgroupBlockIO(cg)
+		entry.BlockIO = formatDecimalBytes(read) + " / " + formatDecimalBytes(write)
+		entry.BlockReadBytes, entry.BlockWriteBytes = int64(read), int64(write)
+
+		rx, tx := cgroupNetIO(cg)
+		entry.NetIO = formatDecimalBytes(rx) + " / " + formatDecimalBytes(tx)
+		entry.NetRxBytes, entry.NetTxBytes = int64(rx), int64(tx)
+
+		entry.PIDs = collectors.ParseInt64(fsutil.ReadTrimmed(cg.path("pids", "pids.current")))
+		stats = append(stats, entry)
+	}
+	return stats, missing
+}
+
+// cgroupContainerIndex mapeia o ID completo de cada container ao seu cgroup,
+// cobrindo os drivers cgroupfs (docker/<id>) e systemd (docker-<id>.scope,
+// libpod-<id>.scope), inclusive de instalacoes rootless sob
+// user@<uid>.service.
+func cgroupContainerIndex() map[string]containerCgroup {
+	v2 := fsutil.FileExists(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	base := cgroupRoot
+	if !v2 {
+		base = filepath.Join(cgroupRoot, "memory")
+	}
+
+	patterns := []string{
+		"docker/*",
+		"system.slice/docker-*.scope",
+		"user.slice/user-*.slice/user@*.service/docker-*.scope",
+		"user.slice/user-*.slice/user@*.service/user.slice/docker-*.scope",
+		// podman
+		"machine.slice/libpod-*.scope",
+		"user.slice/user-*.slice/user@*.service/user.slice/libpod-*.scope",
+	}
+	index := make(map[string]containerCgroup)
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(base, pattern))
+		for _, dir := range matches {
+			id := strings.TrimSuffix(filepath.Base(dir), ".scope")
+			id = strings.TrimPrefix(strings.TrimPrefix(id, "docker-"), "libpod-")
+			if len(id) != 64 {
+				continue
+			}
+			rel, err := filepath.Rel(base, dir)
+			if err != nil {
+				continue
+			}
+			index[id] = containerCgroup{v2: v2, rel: rel}
+		}
+	}
+	return index
+}
+
+// lookupContainerCgroup aceita o ID curto do docker ps.
+func lookupContainerCgroup(index map[string]containerCgroup, id string) (containerCgroup, bool) {
+	if id == "" {
+		return containerCgroup{}, false
+	}
+	if cg, ok := index[id]; ok {
+		return cg, true
+	}
+	for full, cg := range index {
+		if strings.HasPrefix(full, id) {
+			return cg, true
+		}
+	}
+	return containerCgroup{}, false
+}
+
+// cgroupForPID localiza o cgroup pelo /proc/<pid>/cgroup, para runtimes cujo
+// layout nao segue o do docker (ex.: containerd, onde o caminho padrao e
+// /<namespace>/<id>).
+func cgroupForPID(pid int) (containerCgroup, bool) {
+	v2 := fsutil.FileExists(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return containerCgroup{}, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if v2 && parts[0] == "0" || !v2 && slices.Contains(strings.Split(parts[1], ","), "memory") {
+			rel := strings.TrimPrefix(parts[2], "/")
+			// "/" indica um cgroup namespace proprio: o caminho real nao e
+			// visivel daqui.
+			return containerCgroup{v2: v2, rel: rel}, rel != ""
+		}
+	}
+	return containerCgroup{}, false
+}
+
+// cgroupCPUUsage devolve o tempo de CPU acumulado em nanossegundos.
+func cgroupCPUUsage(cg containerCgroup) uint64 {
+	if cg.v2 {
+		return readKeyedFile(cg.path("", "cpu.stat"))["usage_usec"] * 1000
+	}
+	return uint64(collectors.ParseInt64(fsutil.ReadTrimmed(cg.path("cpuacct", "cpuacct.usage"))))
+}
+
+// cgroupMemory segue o docker stats: o uso desconta o page cache inativo.
+func cgroupMemory(cg containerCgroup) (used, limit uint64) {
+	var usage uint64
+	var inactive uint64
+	if cg.v2 {
+		usage = uint64(collectors.ParseInt64(fsutil.ReadTrimmed(cg.path("", "memory.current"))))
+		inactive = readKeyedFile(cg.path("", "memory.stat"))["inactive_file"]
+		if max := fsutil.ReadTrimmed(cg.path("", "memory.max")); max != "max" {
+			limit = uint64(collectors.ParseInt64(max))
+		}
+	} else {
+		usage = uint64(collectors.ParseInt64(fsutil.ReadTrimmed(cg.path("memory", "memory.usage_in_bytes"))))
+		inactive = readKeyedFile(cg.path("memory", "memory.stat"))["total_inactive_file"]
+		limit = uint64(collectors.ParseInt64(fsutil.ReadTrimmed(cg.path("memory", "memory.limit_in_bytes"))))
+	}
+	if inactive < usage {
+		usage -= inactive
+	}
+	return usage, limit
+}
+
+func cgroupBlockIO(cg containerCgroup) (read, write uint64) {
+	if cg.v2 {
+		f, err := os.Open(cg.path("", "io.stat"))
+		if err != nil {
+			return 0, 0
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			for _, field := range strings.Fields(scanner.Text()) {
+				key, value, _ := strings.Cut(field, "=")
+				switch key {
+				case "rbytes":
+					read += uint64(collectors.ParseInt64(value))
+				case "wbytes":
+					write += uint64(collectors.ParseInt64(value))
+				}
+			}
+		}
+		return read, write
+	}
+
+	for _, name := range []string{"blkio.throttle.io_service_bytes_recursive", "blkio.io_service_bytes_recursive"} {
+		f, err := os.Open(cg.path("blkio", name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 3 {
+				continue
+			}
+			switch fields[1] {
+			case "Read":
+				read += uint64(collectors.ParseInt64(fields[2]))
+			case "Write":
+				write += uint64(collectors.ParseInt64(fields[2]))
+			}
+		}
+		f.Close()
+		if read+write > 0 {
+			break
+		}
+	}
+	return read, write
+}
+
+// cgroupNetIO le os contadores de rede do namespace do container a partir de
+// qualquer processo dele.
+func cgroupNetIO(cg containerCgroup) (rx, tx uint64) {
+	procs := cg.path("", "cgroup.procs")
+	if !cg.v2 {
+		procs = cg.path("memory", "cgroup.procs")
+	}
+	pid, _, _ := strings.Cut(fsutil.ReadTrimmed(procs), "\n")
+	if pid == "" {
+		return 0, 0
+	}
+
+	f, err := os.Open(filepath.Join("/proc", pid, "net", "dev"))
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		rx += uint64(collectors.ParseInt64(fields[0]))
+		tx += uint64(collectors.ParseInt64(fields[8]))
+	}
+	return rx, tx
+}
+
+// readKeyedFile le arquivos "chave valor" como cpu.stat e memory.stat.
+func readKeyedFile(path string) map[string]uint64 {
+	values := make(map[string]uint64)
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			values[key] = n
+		}
+	}
+	return values
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+// fakeCgroupRoot aponta cgroupRoot para um diretorio temporario com os
+// arquivos dados, relativos a raiz.
+func fakeCgroupRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	saved := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() { cgroupRoot = saved })
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCgroupContainerIndex(t *testing.T) {
+	systemdID := strings.Repeat("a", 64)
+	cgroupfsID := strings.Repeat("b", 64)
+	rootlessID := strings.Repeat("c", 64)
+	podmanID := strings.Repeat("e", 64)
+	fakeCgroupRoot(t, map[string]string{
+		"cgroup.controllers": "cpu memory io pids",
+		"system.slice/docker-" + systemdID + ".scope/cgroup.procs":                                             "",
+		"docker/" + cgroupfsID + "/cgroup.procs":                                                               "",
+		"user.slice/user-1000.slice/user@1000.service/user.slice/docker-" + rootlessID + ".scope/cgroup.procs": "",
+		"machine.slice/libpod-" + podmanID + ".scope/cgroup.procs":                                             "",
+		// nao e um container
+		"system.slice/docker-healthcheck.scope/cgroup.procs": "",
+	})
+
+	index := cgroupContainerIndex()
+	if len(index) != 4 {
+		t.Fatalf("index = %v, want 4 containers", index)
+	}
+	tests := []struct {
+		id      string
+		wantRel string
+	}{
+		{systemdID[:12], "system.slice/docker-" + systemdID + ".scope"},
+		{cgroupfsID, "docker/" + cgroupfsID},
+		{podmanID[:12], "machine.slice/libpod-" + podmanID + ".scope"},
+		{rootlessID[:12], "user.slice/user-1000.slice/user@1000.service/user.slice/docker-" + rootlessID + ".scope"},
+	}
+	for _, tt := range tests {
+		cg, ok := lookupContainerCgroup(index, tt.id)
+		if !ok || !cg.v2 || cg.rel != tt.wantRel {
+			t.Errorf("lookup(%s) = %+v, %v; want %s", tt.id, cg, ok, tt.wantRel)
+		}
+	}
+	if _, ok := lookupContainerCgroup(index, "dddddddddddd"); ok {
+		t.Error("found a container that has no cgroup")
+	}
+	if _, ok := lookupContainerCgroup(index, ""); ok {
+		t.Error("an empty id matched")
+	}
+}
+
+func TestCgroupV2Readers(t *testing.T) {
+	fakeCgroupRoot(t, map[string]string{
+		"c/cpu.stat":       "usage_usec 2500\nuser_usec 2000\nsystem_usec 500\n",
+		"c/memory.current": "104857600\n",
+		"c/memory.stat":    "anon 73400320\nfile 31457280\ninactive_file 20971520\n",
+		"c/memory.max":     "max\n",
+		"c/io.stat":        "8:0 rbytes=1000 wbytes=2000 rios=1 wios=2\n8:16 rbytes=500 wbytes=0 rios=1 wios=0\n",
+		"c/pids.current":   "7\n",
+	})
+	cg := containerCgroup{v2: true, rel: "c"}
+
+	if got := cgroupCPUUsage(cg); got != 2500000 {
+		t.Errorf("cpu = %d ns, want 2500000", got)
+	}
+	// o uso desconta o inactive_file, como o docker stats
+	if used, limit := cgroupMemory(cg); used != 83886080 || limit != 0 {
+		t.Errorf("memory = %d / %d, want 83886080 / 0", used, limit)
+	}
+	if read, write := cgroupBlockIO(cg); read != 1500 || write != 2000 {
+		t.Errorf("block io = %d / %d, want 1500 / 2000", read, write)
+	}
+	if got := fsutil.ReadTrimmed(cg.path("pids", "pids.current")); got != "7" {
+		t.Errorf("pids = %q", got)
+	}
+}
+
+func TestCgroupV1Readers(t *testing.T) {
+	fakeCgroupRoot(t, map[string]string{
+		"cpuacct/docker/c/cpuacct.usage":                           "123456789\n",
+		"memory/docker/c/memory.usage_in_bytes":                    "209715200\n",
+		"memory/docker/c/memory.stat":                              "cache 0\ntotal_inactive_file 9715200\n",
+		"memory/docker/c/memory.limit_in_bytes":                    "536870912\n",
+		"blkio/docker/c/blkio.throttle.io_service_bytes_recursive": "8:0 Read 4096\n8:0 Write 8192\n8:0 Sync 0\n8:0 Total 12288\nTotal 12288\n",
+	})
+	cg := containerCgroup{rel: "docker/c"}
+
+	if got := cgroupCPUUsage(cg); got != 123456789 {
+		t.Errorf("cpu = %d ns, want 123456789", got)
+	}
+	if used, limit := cgroupMemory(cg); used != 200000000 || limit != 536870912 {
+		t.Errorf("memory = %d / %d, want 200000000 / 536870912", used, limit)
+	}
+	if read, write := cgroupBlockIO(cg); read != 4096 || write != 8192 {
+		t.Errorf("block io = %d / %d, want 4096 / 8192", read, write)
+	}
+}
+
+func TestReadKeyedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.stat")
+	os.WriteFile(path, []byte("anon 10\nfile 20\nbroken\nnegative -1\n"), 0o644)
+	got := readKeyedFile(path)
+	if len(got) != 2 || got["anon"] != 10 || got["file"] != 20 {
+		t.Errorf("got %v", got)
+	}
+	if got := readKeyedFile(filepath.Join(t.TempDir(), "missing")); len(got) != 0 {
+		t.Errorf("missing file = %v", got)
+	}
+}
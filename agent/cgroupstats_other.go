@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+)
+
+// Fora do Linux nao ha cgroups; as estatisticas vem sempre do docker stats.
+func cgroupStatsUsable(ep dockerEndpoint) bool {
+	return false
+}
+
+func readCgroupStats(ctx context.Context, targets []ContainerStatus) (stats []ContainerStatus, missing []string) {
+	for _, c := range targets {
+		missing = append(missing, c.ID)
+	}
+	return nil, missing
+}
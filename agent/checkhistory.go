@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const checkHistoryFile = "checks.json"
+
+// Padroes da deteccao de flapping: uma check esta instavel quando mudou de
+// estado flap_threshold vezes nas ultimas flap_window execucoes, e so volta
+// a estavel quando as mudancas caem para a metade disso.
+const (
+	maxFlapWindow = 100
+)
+
+func validateFlapSettings(c config.Check) error {
+	if c.FlapWindow < 0 || c.FlapWindow > maxFlapWindow {
+		return fmt.Errorf("checks: %s: flap_window must be between 1 and %d", c.Name, maxFlapWindow)
+	}
+	if c.FlapThreshold < 0 || c.FlapThreshold >= c.FlapWindowOrDefault() {
+		return fmt.Errorf("checks: %s: flap_threshold must be between 1 and flap_window - 1", c.Name)
+	}
+	return nil
+}
+
+// CheckStability resume o historico recente de uma check, para o servidor
+// nao abrir incidente a cada oscilacao de um alvo instavel.
+type CheckStability struct {
+	// Samples sao as execucoes no historico; Transitions, quantas vezes o
+	// estado mudou entre elas.
+	Samples     int  `json:"samples"`
+	Transitions int  `json:"transitions"`
+	UpPercent   int  `json:"upPercent"`
+	Flapping    bool `json:"flapping"`
+	// Since e desde quando a check esta no estado atual.
+	Since time.Time `json:"since"`
+}
+
+// checkHistory e o estado persistido de uma check entre ciclos.
+type checkHistory struct {
+	Target   string    `json:"target"`
+	Results  []bool    `json:"results"`
+	Since    time.Time `json:"since"`
+	Flapping bool      `json:"flapping,omitempty"`
+}
+
+func checkHistoryPath(stateDir string) string {
+	return filepath.Join(stateDir, checkHistoryFile)
+}
+
+func loadCheckHistory(stateDir string) map[string]*checkHistory {
+	history := map[string]*checkHistory{}
+	if b, err := os.ReadFile(checkHistoryPath(stateDir)); err == nil {
+		json.Unmarshal(b, &history)
+	}
+	return history
+}
+
+func saveCheckHistory(stateDir string, history map[string]*checkHistory) error {
+	if err := fsutil.EnsureDir(stateDir); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(checkHistoryPath(stateDir), b, 0o600)
+}
+
+// trackChecks acrescenta os resultados do ciclo ao historico, preenche a
+// estabilidade de cada um e devolve as mudancas de estado como eventos.
+// Checks que mudaram de alvo recomecam do zero, e as removidas do config
+// saem do arquivo.
+func trackChecks(cfg config.Config, stateDir string, results []CheckResult, now time.Time) []PayloadEvent {
+	if len(cfg.Checks) == 0 {
+		os.Remove(checkHistoryPath(stateDir))
+		return nil
+	}
+	previous := loadCheckHistory(stateDir)
+	history := make(map[string]*checkHistory, len(cfg.Checks))
+	for _, c := range cfg.Checks {
+		if h := previous[c.Name]; h != nil && h.Target == c.Target() {
+			history[c.Name] = h
+		}
+	}
+
+	var events []PayloadEvent
+	for i := range results {
+		r := &results[i]
+		var check config.Check
+		for _, c := range cfg.Checks {
+			if c.Name == r.Name {
+				check = c
+			}
+		}
+		h := history[r.Name]
+		if h == nil {
+			h = &checkHistory{Target: r.Target, Since: now}
+			history[r.Name] = h
+		}
+		if n := len(h.Results); n > 0 && h.Results[n-1] != r.Up {
+			if r.Up {
+				events = append(events, newEvent(eventCheckUp, r.Name, "", now))
+			} else {
+				events = append(events, newEvent(eventCheckDown, r.Name, r.Error, now))
+			}
+		}
+		r.Stability = h.record(r.Up, now, check.FlapWindowOrDefault(), check.FlapThresholdOrDefault())
+	}
+
+	if err := saveCheckHistory(stateDir, history); err != nil {
+		fmt.Fprintf(os.Stderr, "checks: %v\n", err)
+	}
+	return events
+}
+
+// record guarda um resultado, mantendo so a janela, e recalcula o flapping
+// com histerese: entra em threshold mudancas e sai abaixo da metade.
+func (h *checkHistory) record(up bool, now time.Time, window, threshold int) *CheckStability {
+	if n := len(h.Results); n > 0 && h.Results[n-1] != up {
+		h.Since = now
+	}
+	h.Results = append(h.Results, up)
+	if len(h.Results) > window {
+		h.Results = h.Results[len(h.Results)-window:]
+	}
+
+	s := &CheckStability{Samples: len(h.Results), Since: h.Since}
+	ups := 0
+	for i, v := range h.Results {
+		if v {
+			ups++
+		}
+		if i > 0 && v != h.Results[i-1] {
+			s.Transitions++
+		}
+	}
+	s.UpPercent = ups * 100 / len(h.Results)
+
+	switch {
+	case s.Transitions >= threshold:
+		h.Flapping = true
+	case s.Transitions*2 < threshold:
+		h.Flapping = false
+	}
+	s.Flapping = h.Flapping
+	return s
+}
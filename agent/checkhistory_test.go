@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestCheckHistoryRecord(t *testing.T) {
+	tests := []struct {
+		name            string
+		results         []bool
+		window          int
+		threshold       int
+		wantTransitions int
+		wantUpPercent   int
+		wantFlapping    bool
+	}{
+		{"stable up", []bool{true, true, true, true}, 10, 4, 0, 100, false},
+		{"single outage", []bool{true, true, false, false}, 10, 4, 1, 50, false},
+		{"flapping", []bool{true, false, true, false, true}, 10, 4, 4, 60, true},
+		{"window drops old changes", []bool{true, false, true, false, true, true, true, true}, 4, 3, 0, 100, false},
+		// histerese: 2 mudancas com limite 4 ainda e flapping (sai abaixo de 2)
+		{"hysteresis keeps flapping", []bool{true, false, true, false, true, true, true}, 5, 4, 2, 80, true},
+		{"hysteresis clears", []bool{true, false, true, false, true, true, true, true}, 5, 4, 1, 80, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &checkHistory{}
+			var s *CheckStability
+			for _, up := range tt.results {
+				s = h.record(up, time.Now(), tt.window, tt.threshold)
+			}
+			if s.Transitions != tt.wantTransitions || s.UpPercent != tt.wantUpPercent || s.Flapping != tt.wantFlapping {
+				t.Errorf("got transitions=%d up=%d%% flapping=%v, want %d %d%% %v",
+					s.Transitions, s.UpPercent, s.Flapping, tt.wantTransitions, tt.wantUpPercent, tt.wantFlapping)
+			}
+		})
+	}
+}
+
+func TestTrackChecks(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Config{Checks: []config.Check{{Name: "api", URL: "https://api.example.com"}}}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var last []CheckResult
+	var events []PayloadEvent
+	for i, up := range []bool{true, true, false} {
+		last = []CheckResult{{Name: "api", Target: "https://api.example.com", Up: up, Error: "timeout"}}
+		events = append(events, trackChecks(cfg, dir, last, start.Add(time.Duration(i)*time.Minute))...)
+	}
+	if len(events) != 1 || events[0].Type != eventCheckDown || events[0].Detail != "timeout" || !events[0].Time.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("events = %+v, want one check_down", events)
+	}
+	s := last[0].Stability
+	if s == nil || s.Samples != 3 || s.Transitions != 1 || !s.Since.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("stability = %+v", s)
+	}
+
+	// alvo novo recomeca o historico
+	cfg.Checks[0].URL = "https://api2.example.com"
+	last = []CheckResult{{Name: "api", Target: "https://api2.example.com", Up: true}}
+	trackChecks(cfg, dir, last, start.Add(3*time.Minute))
+	if s := last[0].Stability; s.Samples != 1 {
+		t.Errorf("samples after target change = %d, want 1", s.Samples)
+	}
+
+	trackChecks(config.Config{}, dir, nil, start)
+	if _, err := os.Stat(checkHistoryPath(dir)); !os.IsNotExist(err) {
+		t.Errorf("history file kept without checks: %v", err)
+	}
+}
+
+func TestValidateFlapSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   config.Check
+		wantErr bool
+	}{
+		{"defaults", config.Check{}, false},
+		{"custom", config.Check{FlapWindow: 20, FlapThreshold: 6}, false},
+		{"threshold above default window", config.Check{FlapThreshold: 10}, true},
+		{"window too large", config.Check{FlapWindow: maxFlapWindow + 1}, true},
+		{"negative threshold", config.Check{FlapThreshold: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateFlapSettings(tt.check); (err != nil) != tt.wantErr {
+				t.Errorf("validateFlapSettings() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckAlertFlapping(t *testing.T) {
+	cond := alertCondition{check: "api"}
+	tests := []struct {
+		name   string
+		result CheckResult
+		want   int
+	}{
+		{"down", CheckResult{Name: "api"}, alertConditionFiring},
+		{"up", CheckResult{Name: "api", Up: true}, alertConditionOK},
+		{"down while flapping", CheckResult{Name: "api", Stability: &CheckStability{Flapping: true}}, alertConditionUnknown},
+		{"up while flapping", CheckResult{Name: "api", Up: true, Stability: &CheckStability{Flapping: true}}, alertConditionUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := cond.evaluate(Payload{Checks: []CheckResult{tt.result}}); got != tt.want {
+				t.Errorf("evaluate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+const (
+	defaultPingCount = 3
+	maxPingCount     = 20
+)
+
+// CheckResult e o resultado de um Check no payload. Para ping, LatencyMS e o
+// RTT medio.
+type CheckResult struct {
+	Name              string   `json:"name"`
+	Type              string   `json:"type"`
+	URL               string   `json:"url,omitempty"`
+	Target            string   `json:"target"`
+	Up                bool     `json:"up"`
+	StatusCode        int      `json:"statusCode,omitempty"`
+	LatencyMS         int64    `json:"latencyMs"`
+	PacketLoss        *float64 `json:"packetLoss,omitempty"`
+	CertExpiresInDays *int     `json:"certExpiresInDays,omitempty"`
+	Error             string   `json:"error,omitempty"`
+
+	// Stability vem do historico local (ver checkhistory.go); fica de fora
+	// no --dry-run e no primeiro envio da instalacao.
+	Stability *CheckStability `json:"stability,omitempty"`
+}
+
+func validateChecks(checks []config.Check, proxyURL string) error {
+	seen := make(map[string]bool, len(checks))
+	for _, c := range checks {
+		if c.Name == "" {
+			return errors.New("checks: name is required")
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("checks: duplicate name %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		if c.UseProxy {
+			proxy, err := transport.ParseProxyURL(proxyURL)
+			switch {
+			case proxyURL == "":
+				return fmt.Errorf("checks: %s: use_proxy requires proxy_url", c.Name)
+			case err != nil:
+				return err
+			case c.Kind() == config.CheckPing:
+				return fmt.Errorf("checks: %s: ping checks cannot use a proxy", c.Name)
+			case c.Kind() == config.CheckTCP && !transport.IsSOCKS5(proxy):
+				return fmt.Errorf("checks: %s: tcp checks need a socks5 proxy_url", c.Name)
+			}
+		}
+
+		switch c.Kind() {
+		case config.CheckHTTP:
+			u, err := url.Parse(c.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("checks: %s: invalid url %q", c.Name, c.URL)
+			}
+			if c.ExpectedStatus != 0 && (c.ExpectedStatus < 100 || c.ExpectedStatus > 599) {
+				return fmt.Errorf("checks: %s: invalid expected_status %d", c.Name, c.ExpectedStatus)
+			}
+		case config.CheckTCP:
+			if c.Host == "" {
+				return fmt.Errorf("checks: %s: host is required", c.Name)
+			}
+			if c.Port < 1 || c.Port > 65535 {
+				return fmt.Errorf("checks: %s: invalid port %d", c.Name, c.Port)
+			}
+		case config.CheckPing:
+			// o host vai para a linha de comando do ping
+			if c.Host == "" || strings.HasPrefix(c.Host, "-") || strings.ContainsAny(c.Host, " \t") {
+				return fmt.Errorf("checks: %s: invalid host %q", c.Name, c.Host)
+			}
+			if c.Count < 0 || c.Count > maxPingCount {
+				return fmt.Errorf("checks: %s: count must be between 1 and %d", c.Name, maxPingCount)
+			}
+			if c.MaxPacketLoss != nil && (*c.MaxPacketLoss < 0 || *c.MaxPacketLoss > 100) {
+				return fmt.Errorf("checks: %s: max_packet_loss must be between 0 and 100", c.Name)
+			}
+		default:
+			return fmt.Errorf("checks: %s: unknown type %q", c.Name, c.Type)
+		}
+		if err := validateFlapSettings(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runChecks executa todas as verificacoes em paralelo, cada uma com seu
+// prazo.
+func runChecks(checks []config.Check, proxyURL string) []CheckResult {
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c config.Check) {
+			defer wg.Done()
+			results[i] = runCheck(c, proxyURL)
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+func runCheck(c config.Check, proxyURL string) CheckResult {
+	res := CheckResult{Name: c.Name, Type: c.Kind(), Target: c.Target()}
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout())
+	defer cancel()
+
+	var proxy *url.URL
+	if c.UseProxy {
+		var err error
+		if proxy, err = transport.ParseProxyURL(proxyURL); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+
+	switch c.Kind() {
+	case config.CheckTCP:
+		runTCPCheck(ctx, c, proxy, &res)
+	case config.CheckPing:
+		runPingCheck(ctx, c, &res)
+	default:
+		res.URL = c.URL
+		runHTTPCheck(ctx, c, proxy, &res)
+	}
+	return res
+}
+
+func runHTTPCheck(ctx context.Context, c config.Check, proxy *url.URL, res *CheckResult) {
+	// Sem keep-alive: cada ciclo mede uma conexao nova, como um usuario.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.SkipTLSVerify}
+	// Proxy vem do DefaultTransport (variaveis HTTP_PROXY); use_proxy troca
+	// pelo proxy_url.
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+	req.Header.Set("User-Agent", "vaultrix-agent/"+version)
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.LatencyMS = time.Since(started).Milliseconds()
+		res.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	var body []byte
+	if c.Keyword != "" {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	} else {
+		_, err = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	}
+	res.LatencyMS = time.Since(started).Milliseconds()
+	res.StatusCode = resp.StatusCode
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		days := int(time.Until(resp.TLS.PeerCertificates[0].NotAfter).Hours() / 24)
+		res.CertExpiresInDays = &days
+	}
+
+	switch {
+	case err != nil:
+		res.Error = err.Error()
+	case c.ExpectedStatus != 0 && resp.StatusCode != c.ExpectedStatus:
+		res.Error = fmt.Sprintf("status %d, expected %d", resp.StatusCode, c.ExpectedStatus)
+	case c.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299):
+		res.Error = fmt.Sprintf("status %d", resp.StatusCode)
+	case c.Keyword != "" && !strings.Contains(string(body), c.Keyword):
+		res.Error = fmt.Sprintf("keyword %q not found", c.Keyword)
+	case c.MaxLatencyMS > 0 && res.LatencyMS > c.MaxLatencyMS:
+		res.Error = fmt.Sprintf("latency %dms exceeds %dms", res.LatencyMS, c.MaxLatencyMS)
+	default:
+		res.Up = true
+	}
+}
+
+// runTCPCheck mede o tempo de abertura da conexao; a porta esta aberta se o
+// handshake completa.
+func runTCPCheck(ctx context.Context, c config.Check, proxy *url.URL, res *CheckResult) {
+	var d net.Dialer
+	started := time.Now()
+	var conn net.Conn
+	var err error
+	if proxy != nil {
+		// o tempo inclui o handshake com o proxy e a conexao dele ate o alvo
+		conn, err = transport.DialSOCKS5(ctx, proxy, res.Target)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", res.Target)
+	}
+	res.LatencyMS = time.Since(started).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+	conn.Close()
+	res.Up = true
+	if c.MaxLatencyMS > 0 && res.LatencyMS > c.MaxLatencyMS {
+		res.Up = false
+		res.Error = fmt.Sprintf("latency %dms exceeds %dms", res.LatencyMS, c.MaxLatencyMS)
+	}
+}
+
+// runPingCheck usa o ping do sistema, que ja tem as permissoes de ICMP que o
+// agente pode nao ter.
+func runPingCheck(ctx context.Context, c config.Check, res *CheckResult) {
+	count := c.Count
+	if count == 0 {
+		count = defaultPingCount
+	}
+	out, err := pingCommand(ctx, c.Host, count, c.Timeout()).Output()
+	// ping sai com codigo diferente de zero quando ha perda; o resumo basta.
+	loss, avg, ok := parsePingOutput(string(out))
+	if !ok {
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() != nil:
+			res.Error = ctx.Err().Error()
+		case errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0:
+			res.Error = string(bytes.TrimSpace(exitErr.Stderr))
+		case err != nil:
+			res.Error = err.Error()
+		default:
+			res.Error = "unexpected ping output"
+		}
+		return
+	}
+	res.PacketLoss = &loss
+	res.LatencyMS = int64(math.Round(avg))
+
+	switch {
+	case loss >= 100:
+		res.Error = "no reply"
+	case c.MaxPacketLoss != nil && loss > *c.MaxPacketLoss:
+		res.Error = fmt.Sprintf("packet loss %.0f%% exceeds %.0f%%", loss, *c.MaxPacketLoss)
+	case c.MaxLatencyMS > 0 && res.LatencyMS > c.MaxLatencyMS:
+		res.Error = fmt.Sprintf("latency %dms exceeds %dms", res.LatencyMS, c.MaxLatencyMS)
+	default:
+		res.Up = true
+	}
+}
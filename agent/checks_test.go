@@ -0,0 +1,257 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestRunCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/moved":
+			w.WriteHeader(http.StatusMovedPermanently)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		check   config.Check
+		wantUp  bool
+		wantErr string
+	}{
+		{"2xx", config.Check{URL: srv.URL + "/health"}, true, ""},
+		{"5xx", config.Check{URL: srv.URL + "/fail"}, false, "status 500"},
+		{"expected status", config.Check{URL: srv.URL + "/moved", ExpectedStatus: 301}, true, ""},
+		{"unexpected status", config.Check{URL: srv.URL + "/health", ExpectedStatus: 204}, false, "status 200, expected 204"},
+		{"keyword", config.Check{URL: srv.URL + "/health", Keyword: `"ok"`}, true, ""},
+		{"missing keyword", config.Check{URL: srv.URL + "/health", Keyword: "degraded"}, false, `keyword "degraded" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.Name = tt.name
+			res := runCheck(tt.check, "")
+			if res.Up != tt.wantUp || res.Error != tt.wantErr {
+				t.Errorf("got up=%v err=%q, want up=%v err=%q", res.Up, res.Error, tt.wantUp, tt.wantErr)
+			}
+			if res.Name != tt.name || res.URL != tt.check.URL {
+				t.Errorf("result = %+v", res)
+			}
+			if res.CertExpiresInDays != nil {
+				t.Error("plain http has no certificate")
+			}
+		})
+	}
+}
+
+func TestRunCheckTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// o certificado do teste nao e confiavel; sem skip_tls_verify a check cai
+	res := runCheck(config.Check{Name: "tls", URL: srv.URL}, "")
+	if res.Up || !strings.Contains(res.Error, "certificate") {
+		t.Errorf("got up=%v err=%q, want a certificate error", res.Up, res.Error)
+	}
+
+	res = runCheck(config.Check{Name: "tls", URL: srv.URL, SkipTLSVerify: true}, "")
+	if !res.Up || res.StatusCode != 200 {
+		t.Fatalf("got %+v", res)
+	}
+	if res.CertExpiresInDays == nil || *res.CertExpiresInDays <= 0 {
+		t.Errorf("CertExpiresInDays = %v", res.CertExpiresInDays)
+	}
+}
+
+func TestRunTCPCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+
+	res := runCheck(config.Check{Name: "db", Type: "tcp", Host: "127.0.0.1", Port: addr.Port}, "")
+	if !res.Up || res.Type != "tcp" || res.Target != addr.String() || res.URL != "" {
+		t.Errorf("open port: %+v", res)
+	}
+
+	// com o listener fechado a conexao e recusada
+	ln.Close()
+	res = runCheck(config.Check{Name: "db", Type: "tcp", Host: "127.0.0.1", Port: addr.Port}, "")
+	if res.Up || res.Error == "" {
+		t.Errorf("closed port: %+v", res)
+	}
+}
+
+func TestRunChecksOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	checks := []config.Check{
+		{Name: "a", URL: srv.URL + "/a"},
+		{Name: "b", URL: "http://127.0.0.1:1/"},
+		{Name: "c", URL: srv.URL + "/c"},
+	}
+	results := runChecks(checks, "")
+	for i, res := range results {
+		if res.Name != checks[i].Name {
+			t.Errorf("results[%d] = %s, want %s", i, res.Name, checks[i].Name)
+		}
+	}
+	if !results[0].Up || results[1].Up || results[1].Error == "" || !results[2].Up {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestValidateChecks(t *testing.T) {
+	loss := 120.0
+	tests := []struct {
+		name    string
+		checks  []config.Check
+		wantErr string
+	}{
+		{"valid", []config.Check{{Name: "api", URL: "https://api.example.com/health", ExpectedStatus: 200}}, ""},
+		{"no name", []config.Check{{URL: "https://example.com"}}, "name is required"},
+		{"duplicate", []config.Check{{Name: "a", URL: "https://a.example.com"}, {Name: "a", URL: "https://b.example.com"}}, `duplicate name "a"`},
+		{"scheme", []config.Check{{Name: "a", URL: "ftp://example.com"}}, "invalid url"},
+		{"no host", []config.Check{{Name: "a", URL: "https://"}}, "invalid url"},
+		{"status", []config.Check{{Name: "a", URL: "https://example.com", ExpectedStatus: 99}}, "invalid expected_status 99"},
+		{"tcp", []config.Check{{Name: "db", Type: "tcp", Host: "10.0.0.5", Port: 5432}}, ""},
+		{"tcp without host", []config.Check{{Name: "db", Type: "tcp", Port: 5432}}, "host is required"},
+		{"tcp port", []config.Check{{Name: "db", Type: "tcp", Host: "10.0.0.5", Port: 70000}}, "invalid port 70000"},
+		{"ping", []config.Check{{Name: "gw", Type: "ping", Host: "10.0.0.1", Count: 5}}, ""},
+		{"ping flag as host", []config.Check{{Name: "gw", Type: "ping", Host: "-f"}}, `invalid host "-f"`},
+		{"ping count", []config.Check{{Name: "gw", Type: "ping", Host: "10.0.0.1", Count: 50}}, "count must be between 1 and 20"},
+		{"ping loss", []config.Check{{Name: "gw", Type: "ping", Host: "10.0.0.1", MaxPacketLoss: &loss}}, "max_packet_loss must be between 0 and 100"},
+		{"unknown type", []config.Check{{Name: "x", Type: "udp", Host: "10.0.0.1"}}, `unknown type "udp"`},
+	}
+	for _, tt := range tests {
+		err := validateChecks(tt.checks, "")
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateChecksProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   config.Check
+		proxy   string
+		wantErr string
+	}{
+		{"http via http proxy", config.Check{URL: "https://intranet.example.com", UseProxy: true}, "http://proxy:3128", ""},
+		{"tcp via socks5", config.Check{Type: "tcp", Host: "db.internal", Port: 5432, UseProxy: true}, "socks5h://127.0.0.1:1080", ""},
+		{"no proxy_url", config.Check{URL: "https://intranet.example.com", UseProxy: true}, "", "use_proxy requires proxy_url"},
+		{"tcp via http proxy", config.Check{Type: "tcp", Host: "db.internal", Port: 5432, UseProxy: true}, "http://proxy:3128", "tcp checks need a socks5 proxy_url"},
+		{"ping", config.Check{Type: "ping", Host: "10.0.0.1", UseProxy: true}, "socks5://127.0.0.1:1080", "ping checks cannot use a proxy"},
+		{"bad proxy", config.Check{URL: "https://intranet.example.com", UseProxy: true}, "ftp://proxy", "unsupported scheme"},
+	}
+	for _, tt := range tests {
+		tt.check.Name = "c"
+		err := validateChecks([]config.Check{tt.check}, tt.proxy)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunTCPCheckThroughSOCKS5(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	target := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(target)
+			return
+		}
+		defer conn.Close()
+		fakeSOCKS5(conn, 0x00, 0, 0x00, target)
+	}()
+
+	// o nome do alvo vai para o proxy sem ser resolvido aqui
+	c := config.Check{Name: "db", Type: "tcp", Host: "db.internal", Port: 5432, UseProxy: true}
+	res := runCheck(c, "socks5h://"+ln.Addr().String())
+	if !res.Up || res.Target != "db.internal:5432" {
+		t.Errorf("result = %+v", res)
+	}
+	if got := <-target; got != "db.internal:5432" {
+		t.Errorf("proxy got target %q", got)
+	}
+}
+
+// fakeSOCKS5 responde ao handshake com o metodo, o resultado da autenticacao
+// e o codigo de resposta dados, e devolve o destino pedido pelo cliente.
+func fakeSOCKS5(conn net.Conn, method, authStatus, reply byte, target chan<- string) {
+	defer close(target)
+	var head [2]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return
+	}
+	if _, err := io.CopyN(io.Discard, conn, int64(head[1])); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{0x05, method}); err != nil || method == 0xff {
+		return
+	}
+	if method == 0x02 {
+		var user, pass [256]byte
+		var n [1]byte
+		io.ReadFull(conn, head[:])
+		io.ReadFull(conn, user[:head[1]])
+		io.ReadFull(conn, n[:])
+		io.ReadFull(conn, pass[:n[0]])
+		if _, err := conn.Write([]byte{0x01, authStatus}); err != nil || authStatus != 0 {
+			return
+		}
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 0x01:
+		var ip [4]byte
+		io.ReadFull(conn, ip[:])
+		host = net.IP(ip[:]).String()
+	case 0x03:
+		var n [1]byte
+		io.ReadFull(conn, n[:])
+		name := make([]byte, n[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	var port [2]byte
+	io.ReadFull(conn, port[:])
+	target <- net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+	conn.Write([]byte{0x05, reply, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+}
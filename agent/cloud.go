@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cloudTimeout = 5 * time.Second
+	// endereco link-local do servico de metadados em todos os provedores
+	cloudMetadataHost = "169.254.169.254"
+)
+
+// CloudInfo identifica o provedor e traz as manutencoes que ele anuncia ao
+// proprio host, para o servidor explicar de antemao um host que vai sumir.
+// So AWS, GCP e Azure publicam manutencoes nos metadados; em DigitalOcean,
+// Hetzner e OpenStack (OVH) vai so o provedor, que o servidor pode cruzar
+// com a pagina de status de cada um.
+type CloudInfo struct {
+	Provider    string             `json:"provider"`
+	Maintenance []MaintenanceEvent `json:"maintenance,omitempty"`
+}
+
+// MaintenanceEvent e uma manutencao agendada. Kind e o codigo do provedor
+// (system-reboot, Reboot, MIGRATE_ON_HOST_MAINTENANCE...).
+type MaintenanceEvent struct {
+	Kind        string     `json:"kind"`
+	Status      string     `json:"status,omitempty"`
+	NotBefore   *time.Time `json:"not_before,omitempty"`
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+	Description string     `json:"description,omitempty"`
+}
+
+// cloudProvider sabe reconhecer um provedor e ler suas manutencoes.
+type cloudProvider struct {
+	name   string
+	detect func(ctx context.Context, c *http.Client) bool
+	events func(ctx context.Context, c *http.Client) ([]MaintenanceEvent, error)
+}
+
+var cloudProviders = []cloudProvider{
+	{"aws", detectAWS, awsEvents},
+	{"gcp", detectGCP, gcpEvents},
+	{"azure", detectAzure, azureEvents},
+	{"digitalocean", probeMetadata("/metadata/v1/id", nil), nil},
+	{"hetzner", probeMetadata("/hetzner/v1/metadata/instance-id", nil), nil},
+	{"openstack", probeMetadata("/openstack/latest/meta_data.json", nil), nil},
+}
+
+// O provedor nao muda durante a vida do processo; no daemon a deteccao
+// roda uma vez. provider nil com done significa que nenhum respondeu.
+var cloudDetection struct {
+	sync.Mutex
+	done     bool
+	provider *cloudProvider
+}
+
+// metadataClient nunca usa proxy: o servico de metadados so e alcancavel do
+// proprio host.
+func metadataClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:       nil,
+			DialContext: (&net.Dialer{Timeout: time.Second}).DialContext,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+func collectCloud(ctx context.Context) (*CloudInfo, error) {
+	client := metadataClient()
+	provider := detectCloud(ctx, client)
+	if provider == nil {
+		return nil, nil
+	}
+	info := &CloudInfo{Provider: provider.name}
+	if provider.events == nil {
+		return info, nil
+	}
+	events, err := provider.events(ctx, client)
+	if err != nil {
+		return info, fmt.Errorf("%s: %w", provider.name, err)
+	}
+	info.Maintenance = events
+	return info, nil
+}
+
+// detectCloud consulta todos os provedores em paralelo e fica com o
+// primeiro da lista que respondeu.
+func detectCloud(ctx context.Context, client *http.Client) *cloudProvider {
+	cloudDetection.Lock()
+	defer cloudDetection.Unlock()
+	if cloudDetection.done {
+		return cloudDetection.provider
+	}
+	found := make([]bool, len(cloudProviders))
+	var wg sync.WaitGroup
+	for i, p := range cloudProviders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found[i] = p.detect(ctx, client)
+		}()
+	}
+	wg.Wait()
+	// um prazo estourado nao prova que o host esta fora da nuvem
+	if ctx.Err() != nil {
+		return nil
+	}
+	cloudDetection.done = true
+	for i := range cloudProviders {
+		if found[i] {
+			cloudDetection.provider = &cloudProviders[i]
+			break
+		}
+	}
+	return cloudDetection.provider
+}
+
+func metadataGet(ctx context.Context, c *http.Client, method, path string, header map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+cloudMetadataHost+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode}
+	}
+	return body, nil
+}
+
+func probeMetadata(path string, header map[string]string) func(context.Context, *http.Client) bool {
+	return func(ctx context.Context, c *http.Client) bool {
+		_, err := metadataGet(ctx, c, http.MethodGet, path, header)
+		return err == nil
+	}
+}
+
+// AWS: IMDSv2 exige um token obtido com PUT.
+func awsToken(ctx context.Context, c *http.Client) (string, error) {
+	b, err := metadataGet(ctx, c, http.MethodPut, "/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	return strings.TrimSpace(string(b)), err
+}
+
+func detectAWS(ctx context.Context, c *http.Client) bool {
+	token, err := awsToken(ctx, c)
+	return err == nil && token != ""
+}
+
+func awsEvents(ctx context.Context, c *http.Client) ([]MaintenanceEvent, error) {
+	token, err := awsToken(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	b, err := metadataGet(ctx, c, http.MethodGet, "/latest/meta-data/events/maintenance/scheduled", map[string]string{"X-aws-ec2-metadata-token": token})
+	if err != nil {
+		var status *httpStatusError
+		if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var raw []struct {
+		Code        string
+		Description string
+		State       string
+		NotBefore   string
+		NotAfter    string
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	var events []MaintenanceEvent
+	for _, e := range raw {
+		// eventos concluidos ou cancelados continuam listados
+		if e.State != "" && e.State != "active" {
+			continue
+		}
+		events = append(events, MaintenanceEvent{
+			Kind:        e.Code,
+			Status:      e.State,
+			NotBefore:   parseCloudTime("02 Jan 2006 15:04:05 MST", e.NotBefore),
+			NotAfter:    parseCloudTime("02 Jan 2006 15:04:05 MST", e.NotAfter),
+			Description: e.Description,
+		})
+	}
+	return events, nil
+}
+
+var gcpHeader = map[string]string{"Metadata-Flavor": "Google"}
+
+func detectGCP(ctx context.Context, c *http.Client) bool {
+	return probeMetadata("/computeMetadata/v1/instance/id", gcpHeader)(ctx, c)
+}
+
+// GCP: maintenance-event diz se uma manutencao esta em curso;
+// upcoming-maintenance, se ha uma agendada.
+func gcpEvents(ctx context.Context, c *http.Client) ([]MaintenanceEvent, error) {
+	var events []MaintenanceEvent
+	b, err := metadataGet(ctx, c, http.MethodGet, "/computeMetadata/v1/instance/maintenance-event", gcpHeader)
+	if err != nil {
+		return nil, err
+	}
+	if kind := strings.TrimSpace(string(b)); kind != "" && kind != "NONE" {
+		events = append(events, MaintenanceEvent{Kind: kind, Status: "started"})
+	}
+
+	b, err = metadataGet(ctx, c, http.MethodGet, "/computeMetadata/v1/instance/upcoming-maintenance", gcpHeader)
+	var status *httpStatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		return events, nil
+	}
+	if err != nil {
+		return events, err
+	}
+	var upcoming struct {
+		Type        string `json:"type"`
+		Status      string `json:"maintenance_status"`
+		WindowStart string `json:"window_start_time"`
+		WindowEnd   string `json:"window_end_time"`
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return events, nil
+	}
+	if err := json.Unmarshal(b, &upcoming); err != nil {
+		return events, err
+	}
+	if upcoming.Type != "" {
+		events = append(events, MaintenanceEvent{
+			Kind:      upcoming.Type,
+			Status:    strings.ToLower(upcoming.Status),
+			NotBefore: parseCloudTime(time.RFC3339, upcoming.WindowStart),
+			NotAfter:  parseCloudTime(time.RFC3339, upcoming.WindowEnd),
+		})
+	}
+	return events, nil
+}
+
+var azureHeader = map[string]string{"Metadata": "true"}
+
+const azureEventsPath = "/metadata/scheduledevents?api-version=2020-07-01"
+
+func detectAzure(ctx context.Context, c *http.Client) bool {
+	return probeMetadata("/metadata/instance?api-version=2021-02-01", azureHeader)(ctx, c)
+}
+
+func azureEvents(ctx context.Context, c *http.Client) ([]MaintenanceEvent, error) {
+	b, err := metadataGet(ctx, c, http.MethodGet, azureEventsPath, azureHeader)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Events []struct {
+			EventType         string
+			EventStatus       string
+			NotBefore         string
+			Description       string
+			DurationInSeconds int
+		}
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	var events []MaintenanceEvent
+	for _, e := range doc.Events {
+		ev := MaintenanceEvent{
+			Kind:        e.EventType,
+			Status:      strings.ToLower(e.EventStatus),
+			NotBefore:   parseCloudTime(time.RFC1123, e.NotBefore),
+			Description: e.Description,
+		}
+		if ev.NotBefore != nil && e.DurationInSeconds > 0 {
+			end := ev.NotBefore.Add(time.Duration(e.DurationInSeconds) * time.Second)
+			ev.NotAfter = &end
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func parseCloudTime(layout, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+// payloadCollector registra um coletor do payload (ver
+// internal/collectors).
+type payloadCollector = collectors.Entry[*Payload]
+
+// payloadCollectors e o registro dos coletores, na ordem em que os erros
+// aparecem em collector_errors.
+var payloadCollectors = collectors.Registry[*Payload]{
+	{
+		Timeout: metricsTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func("metrics", func(ctx context.Context) (any, error) {
+				return collectMetrics(ctx, cfg)
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Metrics, _ = v.(Metrics)
+			p.Heartbeat = err != nil
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorHost),
+		Timeout: hostInfoTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorHost, func(ctx context.Context) (any, error) {
+				if err := collectorFault(ctx, config.CollectorHost); err != nil {
+					return nil, err
+				}
+				return collectHostInfo(ctx, cfg)
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if host, ok := v.(HostInfo); ok && err == nil {
+				p.Host = &host
+			}
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorPlugins),
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorPlugins, func(ctx context.Context) (any, error) {
+				return collectPlugins(cfg), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Plugins, _ = v.(map[string]PluginResult)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorChecks) && len(cfg.Checks) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorChecks, func(ctx context.Context) (any, error) {
+				return runChecks(cfg.Checks, cfg.ProxyURL), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Checks, _ = v.([]CheckResult)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorSystemd),
+		Timeout: systemdTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorSystemd, func(ctx context.Context) (any, error) {
+				if err := collectorFault(ctx, config.CollectorSystemd); err != nil {
+					return nil, err
+				}
+				return collectSystemdUnits(ctx, cfg.SystemdUnits)
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.SystemdUnits, _ = v.([]SystemdUnit)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorGPU),
+		Timeout: collectors.GPUTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorGPU, func(ctx context.Context) (any, error) {
+				return collectors.GPUs(ctx)
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.GPUs, _ = v.([]collectors.GPUInfo)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorSensors),
+		Timeout: collectors.SensorsTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorSensors, func(ctx context.Context) (any, error) {
+				return collectors.Sensors(cfg.Sensors), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Sensors, _ = v.(*collectors.SensorsStatus)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorNVMe),
+		Timeout: collectors.NVMeTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorNVMe, func(ctx context.Context) (any, error) {
+				return collectors.NVMe(), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.NVMe, _ = v.([]collectors.NVMeHealth)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorMDRaid),
+		Timeout: collectors.MDRaidTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorMDRaid, func(ctx context.Context) (any, error) {
+				return collectors.MDRaid(), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.MDRaid, _ = v.([]collectors.MDArray)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorZFS),
+		Timeout: collectors.ZFSTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorZFS, func(ctx context.Context) (any, error) {
+				return collectors.ZFS(ctx), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.ZFS, _ = v.(*collectors.ZFSStatus)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorLVM),
+		Timeout: collectors.LVMTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorLVM, func(ctx context.Context) (any, error) {
+				return collectors.LVM(ctx), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.LVM, _ = v.(*collectors.LVMStatus)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorCloud) && cfg.CloudMetadata
+		},
+		Timeout: cloudTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorCloud, func(ctx context.Context) (any, error) {
+				if err := collectorFault(ctx, config.CollectorCloud); err != nil {
+					return nil, err
+				}
+				return collectCloud(ctx)
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Cloud, _ = v.(*CloudInfo)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorScrape) && len(cfg.Scrape) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorScrape, func(ctx context.Context) (any, error) {
+				return scrapeTargets(cfg.Scrape), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Scraped, _ = v.(map[string]ScrapeResult)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorTextfile) && cfg.TextfileDir != ""
+		},
+		Timeout: textfileTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorTextfile, func(ctx context.Context) (any, error) {
+				return collectTextfiles(ctx, cfg.TextfileDir)
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Textfile, _ = v.(map[string]TextfileResult)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorMySQL) && cfg.MySQL != nil
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorMySQL, func(ctx context.Context) (any, error) {
+				return collectors.Run(cfg.MySQL.Timeout(), func(ctx context.Context) (*MySQLStatus, error) {
+					return collectMySQL(ctx, cfg.MySQL)
+				})
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if s, ok := v.(*MySQLStatus); ok && s != nil {
+				p.apps().MySQL = s
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorPostgres) && cfg.Postgres != nil
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorPostgres, func(ctx context.Context) (any, error) {
+				// o prazo cobre tambem o intervalo entre as duas amostras
+				return collectors.Run(cfg.Postgres.Timeout()+pgSampleInterval, func(ctx context.Context) (*PostgresStatus, error) {
+					return collectPostgres(ctx, cfg.Postgres)
+				})
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if s, ok := v.(*PostgresStatus); ok && s != nil {
+				p.apps().Postgres = s
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorRedis) && len(cfg.Redis) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorRedis, func(ctx context.Context) (any, error) {
+				return collectRedis(cfg.Redis), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]RedisStatus); ok {
+				p.apps().Redis = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorMongoDB) && len(cfg.MongoDB) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorMongoDB, func(ctx context.Context) (any, error) {
+				return collectMongoDB(cfg.MongoDB), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]MongoDBStatus); ok {
+				p.apps().MongoDB = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorNginx) && len(cfg.Nginx) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorNginx, func(ctx context.Context) (any, error) {
+				return collectStatusPages(cfg.Nginx, parseNginxStatus, ""), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]WebServerStatus); ok {
+				p.apps().Nginx = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorApache) && len(cfg.Apache) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorApache, func(ctx context.Context) (any, error) {
+				return collectStatusPages(cfg.Apache, parseApacheStatus, "auto"), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]WebServerStatus); ok {
+				p.apps().Apache = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorRabbitMQ) && len(cfg.RabbitMQ) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorRabbitMQ, func(ctx context.Context) (any, error) {
+				return collectRabbitMQ(cfg.RabbitMQ), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]RabbitMQStatus); ok {
+				p.apps().RabbitMQ = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorElastic) && len(cfg.Elasticsearch) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorElastic, func(ctx context.Context) (any, error) {
+				return collectElasticsearch(cfg.Elasticsearch), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]ElasticsearchStatus); ok {
+				p.apps().Elasticsearch = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorKafka) && len(cfg.Kafka) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorKafka, func(ctx context.Context) (any, error) {
+				return collectKafka(cfg.Kafka), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]KafkaStatus); ok {
+				p.apps().Kafka = m
+			}
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorMemcached) && len(cfg.Memcached) > 0
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorMemcached, func(ctx context.Context) (any, error) {
+				return collectMemcached(cfg.Memcached), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			if m, ok := v.(map[string]MemcachedStatus); ok {
+				p.apps().Memcached = m
+			}
+		},
+	},
+	{
+		// o agregador so existe no modo daemon, com statsd configurado
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorStatsD) && activeStatsD() != nil
+		},
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorStatsD, func(ctx context.Context) (any, error) {
+				return activeStatsD().flush(time.Now().UTC()), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.AppMetrics, _ = v.(*AppMetrics)
+		},
+	},
+	{
+		Enabled: collectorOn(config.CollectorDocker),
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorDocker, func(ctx context.Context) (any, error) {
+				if cfg.Kubernetes {
+					return collectKubernetes(cfg), nil
+				}
+				return collectDocker(cfg), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			docker, _ := v.(dockerResult)
+			p.Containers = docker.containers
+			p.ContainerRuntimeStatus = docker.status
+			p.DockerEndpoints = docker.endpoints
+			p.CollectorErrors = append(p.CollectorErrors, docker.errors...)
+		},
+	},
+	{
+		// no kubernetes o disco dos containers e do kubelet, nao de um daemon
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorDocker) && collectorEnabled(cfg, config.CollectorDockerDisk) && !cfg.Kubernetes
+		},
+		Timeout: dockerDiskTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorDockerDisk, func(ctx context.Context) (any, error) {
+				return collectDockerDisk(ctx, discoverDockerEndpoints(cfg)), nil
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.DockerDisk, _ = v.([]DockerDiskUsage)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorDocker) && collectorEnabled(cfg, config.CollectorDockerVolumes) && !cfg.Kubernetes
+		},
+		Timeout: dockerVolumesTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorDockerVolumes, func(ctx context.Context) (any, error) {
+				return collectDockerVolumes(ctx, discoverDockerEndpoints(cfg))
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Volumes, _ = v.([]VolumeInfo)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorDocker) && collectorEnabled(cfg, config.CollectorDockerImages) && !cfg.Kubernetes
+		},
+		Timeout: dockerImagesTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorDockerImages, func(ctx context.Context) (any, error) {
+				return collectDockerImages(ctx, discoverDockerEndpoints(cfg))
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Images, _ = v.([]ImageInfo)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorDocker) && collectorEnabled(cfg, config.CollectorDockerNetworks) && !cfg.Kubernetes
+		},
+		Timeout: dockerNetworksTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorDockerNetworks, func(ctx context.Context) (any, error) {
+				return collectDockerNetworks(ctx, discoverDockerEndpoints(cfg))
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Networks, _ = v.([]NetworkInfo)
+		},
+	},
+	{
+		Enabled: func(cfg config.Config) bool {
+			return collectorEnabled(cfg, config.CollectorDocker) && collectorEnabled(cfg, config.CollectorDockerSwarm) && !cfg.Kubernetes
+		},
+		Timeout: dockerSwarmTimeout,
+		New: func(cfg config.Config) collectors.Collector {
+			return collectors.Func(config.CollectorDockerSwarm, func(ctx context.Context) (any, error) {
+				return collectSwarm(ctx, discoverDockerEndpoints(cfg))
+			})
+		},
+		Apply: func(p *Payload, v any, err error) {
+			p.Swarm, _ = v.(*SwarmStatus)
+		},
+	},
+}
+
+func collectorOn(name string) func(config.Config) bool {
+	return func(cfg config.Config) bool { return collectorEnabled(cfg, name) }
+}
+
+// runCollectors roda os coletores do registro e aplica os resultados ao
+// payload; cada falha entra em collector_errors.
+func runCollectors(cfg config.Config, p *Payload) {
+	payloadCollectors.Run(cfg, p, func(name string, err error) {
+		logCollectorError(name, err)
+		// docker ausente nao e falha de coleta
+		if err != nil && !errors.Is(err, exec.ErrNotFound) {
+			p.CollectorErrors = append(p.CollectorErrors, newCollectorError(name, err))
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+func TestRunCollectors(t *testing.T) {
+	saved := payloadCollectors
+	t.Cleanup(func() { payloadCollectors = saved })
+
+	fake := func(name string, timeout time.Duration, fn func(ctx context.Context) (any, error)) payloadCollector {
+		return payloadCollector{
+			Timeout: timeout,
+			New:     func(config.Config) collectors.Collector { return collectors.Func(name, fn) },
+			Apply: func(p *Payload, v any, err error) {
+				if s, ok := v.(string); ok {
+					p.Flags = append(p.Flags, s)
+				}
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		collectors []payloadCollector
+		wantFlags  []string
+		wantErrors []string // "<coletor>:<tipo>"
+	}{
+		{
+			name: "results applied in registry order",
+			collectors: []payloadCollector{
+				fake("slow", time.Second, func(ctx context.Context) (any, error) {
+					time.Sleep(20 * time.Millisecond)
+					return "slow", nil
+				}),
+				fake("fast", time.Second, func(ctx context.Context) (any, error) { return "fast", nil }),
+			},
+			wantFlags: []string{"slow", "fast"},
+		},
+		{
+			name: "timeout, error and panic become collector errors",
+			collectors: []payloadCollector{
+				fake("stuck", 10*time.Millisecond, func(ctx context.Context) (any, error) {
+					<-ctx.Done()
+					time.Sleep(50 * time.Millisecond)
+					return "late", nil
+				}),
+				fake("broken", time.Second, func(ctx context.Context) (any, error) { return nil, errors.New("boom") }),
+				fake("panics", time.Second, func(ctx context.Context) (any, error) { panic("oops") }),
+				fake("ok", 0, func(ctx context.Context) (any, error) { return "ok", nil }),
+			},
+			wantFlags:  []string{"ok"},
+			wantErrors: []string{"stuck:timeout", "broken:other", "panics:other"},
+		},
+		{
+			name: "disabled collectors do not run",
+			collectors: []payloadCollector{
+				{
+					Enabled: func(config.Config) bool { return false },
+					New: func(config.Config) collectors.Collector {
+						t.Error("disabled collector was created")
+						return nil
+					},
+				},
+				fake("on", 0, func(ctx context.Context) (any, error) { return "on", nil }),
+			},
+			wantFlags: []string{"on"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloadCollectors = tt.collectors
+			var p Payload
+			runCollectors(config.Config{}, &p)
+			if !reflect.DeepEqual(p.Flags, tt.wantFlags) {
+				t.Errorf("applied %v, want %v", p.Flags, tt.wantFlags)
+			}
+			var got []string
+			for _, e := range p.CollectorErrors {
+				got = append(got, e.Collector+":"+e.Kind)
+			}
+			if !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("errors %v, want %v", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+// registeredCollector devolve o coletor name do registro.
+func registeredCollector(t *testing.T, name string) payloadCollector {
+	t.Helper()
+	for _, pc := range payloadCollectors {
+		if pc.New(config.Config{}).Name() == name {
+			return pc
+		}
+	}
+	t.Fatalf("collector %q is not registered", name)
+	return payloadCollector{}
+}
+
+// appsJSON aplica v com o coletor name e devolve a secao "apps" do payload.
+func appsJSON(t *testing.T, name string, v any, err error) string {
+	t.Helper()
+	var p Payload
+	registeredCollector(t, name).Apply(&p, v, err)
+	if p.Apps == nil {
+		return ""
+	}
+	b, jerr := json.Marshal(p.Apps)
+	if jerr != nil {
+		t.Fatal(jerr)
+	}
+	return string(b)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+func pluginsDir(c config.Config) string {
+	if c.PluginsDir != "" {
+		return c.PluginsDir
+	}
+	return defaultPluginsDir
+}
+
+// collectorEnabled respeita o mapa collectors e, para coletores ligados, a
+// flag "collector.<nome>" (ver flags.go).
+func collectorEnabled(c config.Config, name string) bool {
+	if enabled, ok := c.Collectors[name]; ok && !enabled {
+		return false
+	}
+	return featureEnabled(c, "collector."+name)
+}
+
+func loadConfig(path string) (config.Config, error) {
+	cfg, _, err := loadConfigLayers(path)
+	return cfg, err
+}
+
+// loadConfigLayers le e valida o config, devolvendo tambem as camadas para
+// o "config show".
+func loadConfigLayers(path string) (config.Config, []config.Layer, error) {
+	cfg, layers, err := config.ReadLayers(path)
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	return cfg, layers, validateConfig(cfg)
+}
+
+func validateConfig(cfg config.Config) error {
+	if err := checkConfig(cfg); err != nil {
+		return &configError{err}
+	}
+	return nil
+}
+
+func checkConfig(cfg config.Config) error {
+	if err := cfg.OTLP.Validate(); err != nil {
+		return err
+	}
+	if err := validateSinks(cfg); err != nil {
+		return err
+	}
+	if err := validateOffline(cfg); err != nil {
+		return err
+	}
+	if err := cfg.API.Validate("api: "); err != nil {
+		return err
+	}
+	// offline ou com otlp.mode "only" nada vai para a API do vaultrix
+	if cfg.Token == "" && cfg.SendsToAPI() {
+		return errors.New("token is required")
+	}
+	if cfg.ApiURL == "" && cfg.SendsToAPI() {
+		return errors.New("api-url is required")
+	}
+	if err := validateAPIURLs(cfg.ApiURLs); err != nil {
+		return err
+	}
+	if cfg.Interval < 1 {
+		cfg.Interval = 1
+	}
+	if err := validateSplay(cfg); err != nil {
+		return err
+	}
+	if err := validateCommandPoll(cfg.CommandPollSeconds); err != nil {
+		return err
+	}
+	if err := validateHostRoot(cfg.HostRoot); err != nil {
+		return err
+	}
+	if cfg.ProxyURL != "" {
+		if _, err := transport.ParseProxyURL(cfg.ProxyURL); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Metadata.Validate(); err != nil {
+		return err
+	}
+	if err := validateRollupsMode(cfg.ContainerRollups); err != nil {
+		return err
+	}
+	if err := cfg.ContainerFilter.Validate(); err != nil {
+		return err
+	}
+	if err := validateStatsTiers(cfg.StatsTiers); err != nil {
+		return err
+	}
+	if cfg.AutoUpdate || len(cfg.UpdateTrustedKeys) > 0 {
+		if _, err := parseTrustedKeys("update_trusted_keys", cfg.UpdateTrustedKeys); err != nil {
+			return err
+		}
+	}
+	if err := validateChecks(cfg.Checks, cfg.ProxyURL); err != nil {
+		return err
+	}
+	if err := validateSystemdUnits(cfg.SystemdUnits); err != nil {
+		return err
+	}
+	if err := validateAlerts(cfg.Alerts); err != nil {
+		return err
+	}
+	if err := validateScrapeTargets(cfg.Scrape); err != nil {
+		return err
+	}
+	if err := validateTextfileDir(cfg.TextfileDir); err != nil {
+		return err
+	}
+	if err := cfg.MySQL.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Postgres.Validate(); err != nil {
+		return err
+	}
+	if err := validateRedisInstances(cfg.Redis); err != nil {
+		return err
+	}
+	if err := validateMongoDBInstances(cfg.MongoDB); err != nil {
+		return err
+	}
+	if err := validateStatusPages("nginx", cfg.Nginx); err != nil {
+		return err
+	}
+	if err := validateStatusPages("apache", cfg.Apache); err != nil {
+		return err
+	}
+	if err := validateRabbitMQInstances(cfg.RabbitMQ); err != nil {
+		return err
+	}
+	if err := validateElasticsearchInstances(cfg.Elasticsearch); err != nil {
+		return err
+	}
+	if err := validateKafkaClusters(cfg.Kafka); err != nil {
+		return err
+	}
+	if err := validateMemcachedInstances(cfg.Memcached); err != nil {
+		return err
+	}
+	if err := cfg.StatsD.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.LogScan.Validate(); err != nil {
+		return err
+	}
+	if err := validateDockerHosts(cfg.DockerHosts); err != nil {
+		return err
+	}
+	if err := cfg.Sensors.Validate(); err != nil {
+		return err
+	}
+	if err := validateFieldFilter(cfg.PayloadFields); err != nil {
+		return err
+	}
+	if err := validateFlags(cfg.Flags); err != nil {
+		return err
+	}
+	for name := range cfg.Collectors {
+		if !slices.Contains(config.KnownCollectors, name) {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// configSetting e um aspecto do comportamento do agente derivado da config,
+// ja com os padroes aplicados.
+type configSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// configChange e uma linha do config diff. Old vazio indica item novo; New
+// vazio, item removido.
+type configChange struct {
+	Key string `json:"key"`
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+func runConfigCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, tr("usage: vaultrix-agent config diff --file new.json | config show [--effective]"))
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "diff":
+		runConfigDiff(args[1:])
+	case "show":
+		runConfigShow(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, trf("config: unknown subcommand %q", args[0]))
+		os.Exit(2)
+	}
+}
+
+// runConfigDiff mostra o que mudaria no comportamento do agente se o arquivo
+// novo substituisse o atual, sem aplicar nada. Sai com 1 quando ha mudancas,
+// como o diff.
+func runConfigDiff(args []string) {
+	fs := flag.NewFlagSet("config diff", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Current config"))
+	file := fs.String("file", "", tr("Proposed config"))
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	jsonOut := fs.Bool("json", false, tr("JSON output"))
+	fs.Parse(args)
+	setRolloutStateDir(*stateDir)
+	if *file == "" {
+		fatal(errors.New("config diff: --file is required"))
+	}
+
+	current, err := loadConfig(*configPath)
+	if err != nil {
+		fatal(fmt.Errorf("current config: %w", err))
+	}
+	proposed, err := loadConfig(*file)
+	if err != nil {
+		fatal(fmt.Errorf("proposed config: %w", err))
+	}
+	current = withCachedRemoteConfig(current, *stateDir)
+	proposed = withCachedRemoteConfig(proposed, *stateDir)
+
+	changes := diffSettings(effectiveSettings(current), effectiveSettings(proposed))
+	if *jsonOut {
+		b, _ := json.MarshalIndent(changes, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		printConfigChanges(changes)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// runConfigShow imprime cada ajuste com a fonte de onde veio: padrao,
+// arquivo, variavel de ambiente ou, com --effective, config remota (a ultima
+// em cache, sem ir a rede).
+func runConfigShow(args []string) {
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	effective := fs.Bool("effective", false, tr("Include the cached remote config"))
+	jsonOut := fs.Bool("json", false, tr("JSON output"))
+	fs.Parse(args)
+	setRolloutStateDir(*stateDir)
+
+	cfg, layers, err := loadConfigLayers(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	if *effective && cfg.RemoteConfig {
+		if cache, err := loadRemoteConfigCache(*stateDir); err == nil && len(cache.Config) > 0 {
+			if merged, err := mergeRemoteConfig(cfg, cache.Config); err == nil {
+				cfg = merged
+				layers = append(layers, config.Layer{Source: "remote", Raw: remoteAllowedKeys(cache.Config)})
+			} else {
+				fmt.Fprintf(os.Stderr, "remote config ignored: %v\n", err)
+			}
+		}
+	}
+
+	settings := effectiveSettings(cfg)
+	for i := range settings {
+		settings[i].Source = settingSource(settings[i].Key, layers)
+	}
+	if *jsonOut {
+		b, _ := json.MarshalIndent(settings, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t(%s)\n", s.Key, s.Value, s.Source)
+	}
+	w.Flush()
+}
+
+// remoteAllowedKeys filtra a resposta remota pelas chaves que ela pode
+// alterar.
+func remoteAllowedKeys(raw json.RawMessage) map[string]json.RawMessage {
+	var remote map[string]json.RawMessage
+	json.Unmarshal(raw, &remote)
+	allowed := make(map[string]json.RawMessage)
+	for _, key := range remoteConfigFields {
+		if v, ok := remote[key]; ok {
+			allowed[key] = v
+		}
+	}
+	return allowed
+}
+
+// settingSource procura, da camada mais alta para a mais baixa, quem define
+// o ajuste. Em objetos (containers.include, collectors.docker) vale a
+// subchave, ou o seu primeiro nivel quando ela e aninhada.
+func settingSource(key string, layers []config.Layer) string {
+	top, sub, _ := strings.Cut(key, ".")
+	top, _, indexed := strings.Cut(top, "[")
+	for i := len(layers) - 1; i >= 0; i-- {
+		v, ok := layers[i].Raw[top]
+		if !ok {
+			continue
+		}
+		if sub == "" || indexed {
+			return layers[i].Source
+		}
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(v, &obj) != nil {
+			// listas nomeadas, como checks
+			return layers[i].Source
+		}
+		if _, ok := obj[sub]; ok {
+			return layers[i].Source
+		}
+		// alerts.email.smtp vem do objeto email inteiro
+		if first, _, nested := strings.Cut(sub, "."); nested {
+			if _, ok := obj[first]; ok {
+				return layers[i].Source
+			}
+		}
+	}
+	return "default"
+}
+
+// withCachedRemoteConfig aplica a ultima config remota em cache, sem ir a
+// rede, para que o diff reflita o que o agente realmente faria.
+func withCachedRemoteConfig(cfg config.Config, stateDir string) config.Config {
+	if !cfg.RemoteConfig {
+		return cfg
+	}
+	cache, err := loadRemoteConfigCache(stateDir)
+	if err != nil || cache == nil || len(cache.Config) == 0 {
+		return cfg
+	}
+	if merged, err := mergeRemoteConfig(cfg, cache.Config); err == nil {
+		return merged
+	}
+	return cfg
+}
+
+// effectiveSettings lista o comportamento resultante de cfg. O token nunca
+// aparece; so se ele mudou. Chaves e valores nao sao traduzidos.
+func effectiveSettings(cfg config.Config) []configSetting {
+	var s []configSetting
+	add := func(key string, value any) {
+		var v string
+		switch value := value.(type) {
+		case string:
+			v = value
+		case bool:
+			v = onOff(value)
+		case int:
+			v = strconv.Itoa(value)
+		default:
+			b, _ := json.Marshal(value)
+			v = string(b)
+		}
+		s = append(s, configSetting{Key: key, Value: v})
+	}
+
+	if len(cfg.ApiURLs) > 0 {
+		add("api_url", cfg.ApiURLs)
+	} else {
+		add("api_url", cfg.ApiURL)
+	}
+	add("token", tokenFingerprint(cfg.Token))
+	add("profile", orDefault(cfg.Profile, config.ProfileStandard))
+	add("interval_min", max(cfg.Interval, 1))
+	add("splay_seconds", cfg.SplaySeconds)
+	add("command_poll_seconds", cfg.CommandPollSeconds)
+	// como o token, a senha do proxy nunca aparece
+	add("proxy_url", orDefault(redactURL(cfg.ProxyURL), "(none)"))
+	add("hostname", orDefault(cfg.Hostname, "(system)"))
+	add("host_root", orDefault(cfg.HostRoot, "(auto)"))
+	if m := cfg.Metadata; m != nil {
+		for _, f := range [][2]string{{"owner", m.Owner}, {"contact", m.Contact}, {"runbook", m.Runbook}, {"criticality", m.Criticality}} {
+			if f[1] != "" {
+				add("metadata."+f[0], f[1])
+			}
+		}
+	}
+	for _, name := range config.KnownCollectors {
+		add("collectors."+name, collectorEnabled(cfg, name))
+	}
+	for _, name := range sortedKeys(cfg.Flags) {
+		state := "off"
+		if enabled, _ := flagEnabled(cfg, name); enabled {
+			state = "on"
+		}
+		add("flags."+name, fmt.Sprintf("%g%% (%s)", cfg.Flags[name], state))
+	}
+	add("containers.include", cfg.ContainerFilter.Include)
+	add("containers.exclude", cfg.ContainerFilter.Exclude)
+	add("containers.label", cfg.ContainerFilter.Label)
+	add("containers.exclude_label", cfg.ContainerFilter.ExcludeLabel)
+	add("container_rollups", orDefault(cfg.ContainerRollups, rollupsOn))
+	for i, h := range cfg.DockerHosts {
+		prefix := fmt.Sprintf("docker_hosts[%d].", i)
+		add(prefix+"name", h.Name)
+		add(prefix+"host", h.Host)
+		add(prefix+"runtime", orDefault(h.Runtime, runtimeDocker))
+		if h.TLSCACert != "" {
+			add(prefix+"tls_ca_cert", h.TLSCACert)
+		}
+		if h.TLSCert != "" {
+			add(prefix+"tls_cert", h.TLSCert)
+			add(prefix+"tls_key", h.TLSKey)
+		}
+	}
+	for i, tier := range cfg.StatsTiers {
+		add(fmt.Sprintf("stats_tiers[%d]", i), tier)
+	}
+	if l := cfg.LogScan; l != nil {
+		patterns := l.Patterns
+		if len(patterns) == 0 {
+			patterns = config.DefaultLogPatterns
+		}
+		for _, name := range sortedKeys(patterns) {
+			add("log_scan.patterns."+name, patterns[name])
+		}
+		add("log_scan.max_lines", l.MaxLinesOrDefault())
+	}
+	for _, check := range cfg.Checks {
+		add("checks."+check.Name, check)
+	}
+	add("systemd_units", cfg.SystemdUnits)
+	if s := cfg.Sensors; s != nil {
+		add("sensors.warning_celsius", s.WarningCelsius)
+		add("sensors.critical_celsius", s.CriticalCelsius)
+		add("sensors.min_fan_rpm", s.MinFanRPM)
+		for _, name := range sortedKeys(s.Thresholds) {
+			add("sensors.thresholds."+name, s.Thresholds[name])
+		}
+	}
+	addAlertSettings(cfg.Alerts, add)
+	add("payload_fields", cfg.PayloadFields)
+	add("offline", cfg.Offline)
+	addSinkPolicy("api.", cfg.API, add)
+	if o := cfg.OTLP; o != nil {
+		add("otlp.endpoint", redactWebhookURL(o.Endpoint))
+		add("otlp.mode", orDefault(o.Mode, config.OTLPAlso))
+		for _, name := range sortedKeys(o.Headers) {
+			add("otlp.headers."+name, "***")
+		}
+	}
+	for i, s := range cfg.Sinks {
+		prefix := fmt.Sprintf("sinks[%d].", i)
+		add(prefix+"name", s.NameOrDefault())
+		add(prefix+"type", s.Type)
+		if sinkTypes[s.Type].file {
+			add(prefix+"path", s.Path)
+			maxMB, maxFiles := s.FileLimits()
+			add(prefix+"max_mb", maxMB)
+			add(prefix+"max_files", maxFiles)
+		} else {
+			add(prefix+"url", redactWebhookURL(s.URL))
+		}
+		add(prefix+"spool", s.Spool)
+		addSinkPolicy(prefix, s.SinkPolicy, add)
+		if s.Token != "" {
+			add(prefix+"token", tokenFingerprint(s.Token))
+		}
+		for _, name := range sortedKeys(s.Headers) {
+			add(prefix+"headers."+name, "***")
+		}
+	}
+	for i, s := range cfg.Scrape {
+		prefix := fmt.Sprintf("scrape[%d].", i)
+		add(prefix+"name", s.Name)
+		add(prefix+"url", redactWebhookURL(s.URL))
+		add(prefix+"metrics", s.Metrics)
+		add(prefix+"timeout_seconds", int(s.Timeout().Seconds()))
+		add(prefix+"max_samples", s.MaxSamplesOrDefault())
+		for _, name := range sortedKeys(s.Headers) {
+			add(prefix+"headers."+name, "***")
+		}
+	}
+	add("textfile_dir", cfg.TextfileDir)
+	if m := cfg.MySQL; m != nil {
+		add("mysql.dsn", config.RedactMySQLDSN(m.DSN))
+		add("mysql.timeout_seconds", int(m.Timeout().Seconds()))
+	}
+	if p := cfg.Postgres; p != nil {
+		add("postgres.dsn", config.RedactPostgresDSN(p.DSN))
+		add("postgres.timeout_seconds", int(p.Timeout().Seconds()))
+	}
+	for i, r := range cfg.Redis {
+		prefix := fmt.Sprintf("redis[%d].", i)
+		add(prefix+"name", r.Name)
+		add(prefix+"address", r.Address)
+		if r.Username != "" {
+			add(prefix+"username", r.Username)
+		}
+		if r.Password != "" {
+			add(prefix+"password", "***")
+		}
+		add(prefix+"timeout_seconds", int(r.Timeout().Seconds()))
+	}
+	for i, m := range cfg.MongoDB {
+		prefix := fmt.Sprintf("mongodb[%d].", i)
+		add(prefix+"name", m.Name)
+		add(prefix+"uri", redactMongoDBURI(m.URI))
+		add(prefix+"timeout_seconds", int(m.Timeout().Seconds()))
+	}
+	for _, pages := range []struct {
+		kind  string
+		pages []config.StatusPage
+	}{{"nginx", cfg.Nginx}, {"apache", cfg.Apache}} {
+		for i, p := range pages.pages {
+			prefix := fmt.Sprintf("%s[%d].", pages.kind, i)
+			add(prefix+"name", p.Name)
+			add(prefix+"url", p.URL)
+			add(prefix+"timeout_seconds", int(p.Timeout().Seconds()))
+		}
+	}
+	for i, r := range cfg.RabbitMQ {
+		prefix := fmt.Sprintf("rabbitmq[%d].", i)
+		add(prefix+"name", r.Name)
+		add(prefix+"url", r.URL)
+		add(prefix+"username", r.Username)
+		if r.Password != "" {
+			add(prefix+"password", "***")
+		}
+		if len(r.VHosts) > 0 {
+			add(prefix+"vhosts", strings.Join(r.VHosts, ","))
+		}
+		add(prefix+"max_queues", r.MaxQueuesOrDefault())
+		add(prefix+"timeout_seconds", int(r.Timeout().Seconds()))
+	}
+	for i, e := range cfg.Elasticsearch {
+		prefix := fmt.Sprintf("elasticsearch[%d].", i)
+		add(prefix+"name", e.Name)
+		add(prefix+"url", e.URL)
+		if e.Username != "" {
+			add(prefix+"username", e.Username)
+		}
+		if e.Password != "" {
+			add(prefix+"password", "***")
+		}
+		if e.APIKey != "" {
+			add(prefix+"api_key", "***")
+		}
+		add(prefix+"timeout_seconds", int(e.Timeout().Seconds()))
+	}
+	for i, k := range cfg.Kafka {
+		prefix := fmt.Sprintf("kafka[%d].", i)
+		add(prefix+"name", k.Name)
+		add(prefix+"brokers", strings.Join(k.Brokers, ","))
+		if len(k.Groups) > 0 {
+			add(prefix+"groups", strings.Join(k.Groups, ","))
+		}
+		add(prefix+"max_groups", k.MaxGroupsOrDefault())
+		if k.JolokiaURL != "" {
+			add(prefix+"jolokia_url", k.JolokiaURL)
+		}
+		add(prefix+"timeout_seconds", int(k.Timeout().Seconds()))
+	}
+	for i, m := range cfg.Memcached {
+		prefix := fmt.Sprintf("memcached[%d].", i)
+		add(prefix+"name", m.Name)
+		add(prefix+"address", m.Address)
+		add(prefix+"timeout_seconds", int(m.Timeout().Seconds()))
+	}
+	if s := cfg.StatsD; s != nil {
+		add("statsd.listen", s.ListenOrDefault())
+		add("statsd.max_metrics", s.MaxMetricsOrDefault())
+	}
+	add("http", cfg.HTTP.WithDefaults())
+	add("spool", cfg.Spool.WithDefaults())
+	add("plugins_dir", pluginsDir(cfg))
+	add("plugin_limits", cfg.PluginLimits.WithDefaults())
+	for _, name := range sortedKeys(cfg.WasmCapabilities) {
+		add("wasm_capabilities."+name, cfg.WasmCapabilities[name])
+	}
+	if registry, err := cfg.PluginRegistryURLFor(cfg.ApiURL); err == nil {
+		add("plugin_registry_url", registry)
+	}
+	add("plugin_trusted_keys", len(cfg.PluginTrustedKeys))
+	if u, err := cfg.UpdateURLFor(cfg.ApiURL); err == nil {
+		add("update_url", u)
+	}
+	add("update_trusted_keys", len(cfg.UpdateTrustedKeys))
+	add("auto_update", cfg.AutoUpdate)
+	add("kubernetes", cfg.Kubernetes)
+	if cfg.Kubernetes {
+		add("kubelet_url", kubeletURL(cfg))
+		add("kubelet_insecure_tls", cfg.KubeletInsecureTLS)
+	}
+	add("remote_config", cfg.RemoteConfig)
+	if cfg.RemoteConfig {
+		if u, err := cfg.RemoteConfigURLFor(cfg.ApiURL); err == nil {
+			add("remote_config_url", u)
+		}
+	}
+
+	// Listas vazias sao omitidas para nao poluir o diff.
+	out := s[:0]
+	for _, setting := range s {
+		if setting.Value != "null" && setting.Value != "[]" {
+			out = append(out, setting)
+		}
+	}
+	return out
+}
+
+func diffSettings(old, new []configSetting) []configChange {
+	oldValues := make(map[string]string, len(old))
+	for _, s := range old {
+		oldValues[s.Key] = s.Value
+	}
+	var changes []configChange
+	seen := make(map[string]bool, len(new))
+	for _, s := range new {
+		seen[s.Key] = true
+		if prev, ok := oldValues[s.Key]; !ok || prev != s.Value {
+			changes = append(changes, configChange{Key: s.Key, Old: prev, New: s.Value})
+		}
+	}
+	for _, s := range old {
+		if !seen[s.Key] {
+			changes = append(changes, configChange{Key: s.Key, Old: s.Value})
+		}
+	}
+	return changes
+}
+
+func printConfigChanges(changes []configChange) {
+	if len(changes) == 0 {
+		fmt.Println(tr("No behavior changes."))
+		return
+	}
+	for _, c := range changes {
+		switch {
+		case c.Old == "":
+			fmt.Printf("+ %s: %s\n", c.Key, c.New)
+		case c.New == "":
+			fmt.Printf("- %s: %s\n", c.Key, c.Old)
+		default:
+			fmt.Printf("~ %s: %s -> %s\n", c.Key, c.Old, c.New)
+		}
+	}
+}
+
+// tokenFingerprint permite comparar tokens sem exibi-los.
+// addSinkPolicy lista tentativas e prazo de um sink, com os padroes.
+func addSinkPolicy(prefix string, p config.SinkPolicy, add func(string, any)) {
+	add(prefix+"retries", p.Retries)
+	add(prefix+"retry_backoff_seconds", cmp.Or(p.RetryBackoffSeconds, defaultSinkRetryBackoffSec))
+	add(prefix+"timeout_seconds", p.TimeoutSeconds)
+}
+
+// addAlertSettings lista regras e canais de alerta. URLs de webhook levam o
+// segredo no caminho, entao so o host aparece; do bot do Telegram vale o
+// mesmo resumo do token, e a senha SMTP nunca aparece.
+func addAlertSettings(a config.AlertsConfig, add func(string, any)) {
+	if a.WebhookURL != "" {
+		add("alerts.webhook_url", redactWebhookURL(a.WebhookURL))
+	}
+	if a.SlackWebhookURL != "" {
+		add("alerts.slack_webhook_url", redactWebhookURL(a.SlackWebhookURL))
+	}
+	if t := a.Telegram; t != nil {
+		add("alerts.telegram.bot_token", tokenFingerprint(t.BotToken))
+		add("alerts.telegram.chat_id", string(t.ChatID))
+		if t.APIURL != "" {
+			add("alerts.telegram.api_url", redactURL(t.APIURL))
+		}
+	}
+	if e := a.Email; e != nil {
+		port := e.SMTPPort
+		if port == 0 {
+			port = defaultSMTPPort
+		}
+		add("alerts.email.smtp", net.JoinHostPort(e.SMTPHost, strconv.Itoa(port)))
+		if e.Username != "" {
+			add("alerts.email.username", e.Username)
+		}
+		if e.Password != "" {
+			add("alerts.email.password", "***")
+		}
+		add("alerts.email.from", e.From)
+		add("alerts.email.to", e.To)
+	}
+	if len(a.Rules) > 0 {
+		add("alerts.cooldown_minutes", int(a.Cooldown()/time.Minute))
+	}
+	for _, rule := range a.Rules {
+		add("alerts.rules."+rule.Name, rule.When)
+	}
+}
+
+// redactWebhookURL mantem esquema e host e esconde caminho, query e
+// credenciais.
+func redactWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "***"
+	}
+	return u.Scheme + "://" + u.Host + "/***"
+}
+
+func tokenFingerprint(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return "..." + token[len(token)-4:]
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const containerdSocketPath = "/run/containerd/containerd.sock"
+
+// discoverContainerdEndpoints e usado quando nao ha docker: hosts com
+// containerd puro (nerdctl, ctr) ainda reportam seus containers. O ctr vem
+// junto com o containerd.
+func discoverContainerdEndpoints() []dockerEndpoint {
+	if _, err := exec.LookPath("ctr"); err != nil || !fsutil.FileExists(containerdSocketPath) {
+		return nil
+	}
+	return []dockerEndpoint{{Host: containerdSocketPath, Name: "containerd", Runtime: runtimeContainerd}}
+}
+
+// containerdInfo e o que interessa do ctr containers info.
+type containerdInfo struct {
+	ID     string            `json:"ID"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// collectContainerdPS percorre os namespaces do containerd. O estado vem das
+// tasks (processos); um container sem task nunca foi iniciado ou ja foi
+// removido do runtime. O pid da task e usado depois para achar o cgroup.
+func collectContainerdPS(ctx context.Context, ep dockerEndpoint) ([]ContainerStatus, error) {
+	out, err := dockerCommand(ctx, ep, "namespaces", "ls", "-q").Output()
+	if err != nil {
+		return nil, err
+	}
+	var containers []ContainerStatus
+	for _, ns := range strings.Fields(string(out)) {
+		// "moby" e o namespace do docker, tratado pelo coletor do docker
+		if ns == "moby" {
+			continue
+		}
+		list, err := collectContainerdNamespace(ctx, ep, ns)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		containers = append(containers, list...)
+	}
+	return containers, nil
+}
+
+func collectContainerdNamespace(ctx context.Context, ep dockerEndpoint, ns string) ([]ContainerStatus, error) {
+	out, err := dockerCommand(ctx, ep, "-n", ns, "tasks", "ls").Output()
+	if err != nil {
+		return nil, err
+	}
+	type task struct {
+		pid    int
+		status string
+	}
+	tasks := make(map[string]task)
+	for i, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		// a primeira linha e o cabecalho TASK PID STATUS
+		if i == 0 || len(fields) < 3 {
+			continue
+		}
+		tasks[fields[0]] = task{pid: int(collectors.ParseInt64(fields[1])), status: strings.ToLower(fields[2])}
+	}
+
+	out, err = dockerCommand(ctx, ep, "-n", ns, "containers", "ls", "-q").Output()
+	if err != nil {
+		return nil, err
+	}
+	var containers []ContainerStatus
+	for _, id := range strings.Fields(string(out)) {
+		var info containerdInfo
+		if b, err := dockerCommand(ctx, ep, "-n", ns, "containers", "info", id).Output(); err == nil {
+			json.Unmarshal(b, &info)
+		}
+
+		entry := ContainerStatus{
+			ID:     collectors.ShortContainerID(id),
+			Name:   containerdName(ns, id, info.Labels),
+			Image:  info.Image,
+			State:  "created",
+			Status: "Created",
+			Labels: info.Labels,
+		}
+		if t, ok := tasks[id]; ok {
+			switch t.status {
+			case "running":
+				entry.State, entry.pid = "running", t.pid
+			case "paused", "pausing":
+				entry.State, entry.pid = "paused", t.pid
+			case "stopped":
+				entry.State = "exited"
+			}
+			entry.Status = strings.ToUpper(t.status[:1]) + t.status[1:]
+		}
+		containers = append(containers, entry)
+	}
+	return containers, nil
+}
+
+// containerdName prefere o nome dado pelo nerdctl ou pelo Kubernetes ao ID.
+// Namespaces alem do "default" entram no nome para evitar colisoes.
+func containerdName(ns, id string, labels map[string]string) string {
+	name := labels["nerdctl/name"]
+	if name == "" && labels["io.kubernetes.container.name"] != "" {
+		name = labels["io.kubernetes.pod.name"] + "/" + labels["io.kubernetes.container.name"]
+	}
+	if name == "" {
+		name = collectors.ShortContainerID(id)
+	}
+	if ns != "default" {
+		name = ns + "/" + name
+	}
+	return name
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectContainerdPS(t *testing.T) {
+	const (
+		web     = "3f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d"
+		job     = "9e8d7c6b5a4f3e2d1c0b"
+		pending = "0a1b2c3d4e5f6a7b8c9d"
+		app     = "5d4c3b2a1f0e9d8c7b6a"
+	)
+	ep := dockerEndpoint{Host: containerdSocketPath, Name: "containerd", Runtime: runtimeContainerd}
+	ctr := "--address " + containerdSocketPath + " "
+	fakeCommand(t, "ctr", map[string]string{
+		// "moby" e do docker e fica de fora
+		ctr + "namespaces ls -q": "default\nk8s.io\nmoby\n",
+
+		ctr + "-n default tasks ls": "TASK    PID     STATUS\n" +
+			web + "    4242    RUNNING\n" +
+			job + "    0       STOPPED\n",
+		ctr + "-n default containers ls -q":           web + "\n" + job + "\n" + pending + "\n",
+		ctr + "-n default containers info " + web:     `{"ID": "` + web + `", "Image": "docker.io/library/nginx:1.25", "Labels": {"nerdctl/name": "web"}}`,
+		ctr + "-n default containers info " + job:     `{"ID": "` + job + `", "Image": "docker.io/library/busybox:1.36"}`,
+		ctr + "-n default containers info " + pending: `{"ID": "` + pending + `", "Image": "docker.io/library/redis:7"}`,
+
+		ctr + "-n k8s.io tasks ls":               "TASK    PID     STATUS\n" + app + "    5151    PAUSED\n",
+		ctr + "-n k8s.io containers ls -q":       app + "\n",
+		ctr + "-n k8s.io containers info " + app: `{"ID": "` + app + `", "Image": "registry.example.com/shop/api:3", "Labels": {"io.kubernetes.pod.name": "api-7d9f", "io.kubernetes.container.name": "api"}}`,
+	})
+
+	got, err := collectContainerdPS(context.Background(), ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ContainerStatus{
+		{ID: "3f2a9c1e8b7d", Name: "web", Image: "docker.io/library/nginx:1.25", State: "running", Status: "Running", Labels: map[string]string{"nerdctl/name": "web"}, pid: 4242},
+		{ID: "9e8d7c6b5a4f", Name: "9e8d7c6b5a4f", Image: "docker.io/library/busybox:1.36", State: "exited", Status: "Stopped"},
+		{ID: "0a1b2c3d4e5f", Name: "0a1b2c3d4e5f", Image: "docker.io/library/redis:7", State: "created", Status: "Created"},
+		{ID: "5d4c3b2a1f0e", Name: "k8s.io/api-7d9f/api", Image: "registry.example.com/shop/api:3", State: "paused", Status: "Paused",
+			Labels: map[string]string{"io.kubernetes.pod.name": "api-7d9f", "io.kubernetes.container.name": "api"}, pid: 5151},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCollectContainerdPSError(t *testing.T) {
+	ctr := "--address " + containerdSocketPath + " "
+	fakeCommand(t, "ctr", map[string]string{ctr + "namespaces ls -q": "default\n"})
+	// o tasks ls falha: o erro diz o namespace
+	_, err := collectContainerdPS(context.Background(), dockerEndpoint{Host: containerdSocketPath, Runtime: runtimeContainerd})
+	if err == nil || !strings.HasPrefix(err.Error(), "namespace default") {
+		t.Errorf("err = %v, want a namespace error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+// daemonInterval e o intervalo efetivo entre ciclos do daemon.
+func daemonInterval(cfg config.Config) time.Duration {
+	return time.Duration(max(cfg.Interval, 1)) * time.Minute
+}
+
+// runDaemon mantem o agente em execucao continua, coletando a cada
+// intervalo ate ctx ser cancelado. E usado pelo --daemon (systemd, containers)
+// e pelo servico do Windows; no modo cron cada execucao e um processo novo.
+func runDaemon(ctx context.Context, cfg config.Config, configPath, stateDir string) {
+	transport.SetDNSCacheDir(stateDir)
+	transport.SetEndpointStateDir(stateDir)
+	setRolloutStateDir(stateDir)
+	setLogScanStateDir(stateDir)
+	migrateState(stateDir)
+	recordEvent(stateDir, eventStart, version)
+	defer recordEvent(stateDir, eventStop, "")
+
+	if collectorEnabled(cfg, config.CollectorPlugins) {
+		reg := newPluginRegistry(cfg)
+		setPluginRegistry(reg)
+		defer setPluginRegistry(nil)
+		reg.scan()
+		go reg.watch(ctx)
+	}
+
+	// o endereco do statsd so e lido aqui; mudar exige reiniciar o daemon
+	if cfg.StatsD != nil {
+		agg := newStatsdAggregator(cfg.StatsD.MaxMetricsOrDefault(), time.Now().UTC())
+		if _, err := listenStatsD(ctx, cfg.StatsD, agg); err != nil {
+			fmt.Fprintf(os.Stderr, "statsd: %v\n", err)
+		} else {
+			setStatsD(agg)
+			defer setStatsD(nil)
+		}
+	}
+
+	// como o statsd, o stream de eventos do docker so e assinado na partida
+	if collectorEnabled(cfg, config.CollectorDocker) && collectorEnabled(cfg, config.CollectorDockerEvents) {
+		if endpoints := dockerEventEndpoints(cfg); len(endpoints) > 0 {
+			w := &dockerEventWatcher{}
+			for _, ep := range endpoints {
+				go w.watch(ctx, ep)
+			}
+			setDockerEvents(w)
+			defer setDockerEvents(nil)
+		}
+	}
+
+	// cycle devolve o intervalo efetivo, que a configuracao remota pode mudar.
+	// O arquivo e relido a cada ciclo para pegar edicoes locais e tokens
+	// rotacionados.
+	kubernetes := cfg.Kubernetes
+	var current atomic.Pointer[config.Config]
+	current.Store(&cfg)
+	var lastCycle time.Time
+	cycle := func() time.Duration {
+		if fresh, err := loadConfig(configPath); err == nil {
+			cfg = fresh
+			cfg.Kubernetes = cfg.Kubernetes || kubernetes
+		}
+		effective := applyRemoteConfig(cfg, stateDir)
+		current.Store(&effective)
+		started := time.Now()
+		lastCycle = started
+		err := runCycle(effective, stateDir)
+		recordRun(stateDir, effective, started, err)
+		if autoUpdate(effective, stateDir) {
+			recordEvent(stateDir, eventStop, "auto-update")
+			saveHandoff(stateDir, started.Add(daemonInterval(effective)))
+			if err := restartAgent(); err != nil {
+				fmt.Fprintf(os.Stderr, "auto-update: %v\n", err)
+			}
+		}
+		return daemonInterval(effective)
+	}
+
+	// depois de um restart, o processo anterior diz quando seria o proximo
+	// ciclo; o splay so vale numa partida a frio
+	cached := withCachedRemoteConfig(cfg, stateDir)
+	if wait, ok := takeHandoff(stateDir, daemonInterval(cached)); ok {
+		due := time.Now().Add(wait)
+		if !sleepContext(ctx, wait) {
+			saveHandoff(stateDir, due)
+			return
+		}
+	} else if !waitSplay(ctx, cached) {
+		return
+	}
+	// um pedido feito durante o splay ja e atendido pelo primeiro ciclo
+	takeTrigger(stateDir)
+	triggers := make(chan string, 1)
+	go watchTriggers(ctx, stateDir, func() config.Config { return *current.Load() }, triggers)
+
+	interval := cycle()
+	next := time.Now().Add(interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			saveHandoff(stateDir, next)
+			return
+		case <-ticker.C:
+			if n := cycle(); n != interval {
+				interval = n
+				ticker.Reset(interval)
+			}
+			next = time.Now().Add(interval)
+		case source := <-triggers:
+			// a coleta avulsa nao mexe no agendamento dos ciclos
+			if time.Since(lastCycle) < minTriggerGap {
+				continue
+			}
+			recordEvent(stateDir, eventTrigger, source)
+			if n := cycle(); n != interval {
+				interval = n
+				ticker.Reset(interval)
+				next = time.Now().Add(interval)
+			}
+		}
+	}
+}
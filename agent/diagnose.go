@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	diagnosePass = "pass"
+	diagnoseFail = "fail"
+	diagnoseSkip = "skip"
+
+	// maxClockSkew e a diferenca tolerada entre o relogio local e o da API;
+	// acima disso os timestamps do payload e a validade do TLS ficam
+	// duvidosos.
+	maxClockSkew = 2 * time.Minute
+)
+
+// DiagnoseCheck e uma linha do relatorio do diagnose.
+type DiagnoseCheck struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Detail      string `json:"detail,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// runDiagnose roda todas as verificacoes, mesmo depois de uma falha, para o
+// operador ver o quadro inteiro de uma vez. Nada e enviado para a API.
+func runDiagnose(configPath, stateDir string) []DiagnoseCheck {
+	var checks []DiagnoseCheck
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		checks = append(checks, DiagnoseCheck{Name: "config", Status: diagnoseFail, Detail: err.Error(), Remediation: tr("fix the config file; see vaultrix-agent config show")})
+	} else {
+		checks = append(checks, DiagnoseCheck{Name: "config", Status: diagnosePass, Detail: configPath})
+	}
+
+	if err != nil {
+		for _, name := range []string{"api", "clock"} {
+			checks = append(checks, DiagnoseCheck{Name: name, Status: diagnoseSkip, Detail: tr("config is invalid")})
+		}
+	} else {
+		cfg = withCachedRemoteConfig(cfg, stateDir)
+		checks = append(checks, diagnoseAPI(cfg)...)
+		checks = append(checks, diagnoseClock(cfg))
+	}
+
+	checks = append(checks, diagnoseDocker(), diagnoseScheduler(cfg, stateDir), diagnoseBinary())
+	return checks
+}
+
+// diagnoseAPI testa cada endereco de api_url; um endereco reserva fora do
+// ar tambem e falha, porque o failover dependeria dele.
+func diagnoseAPI(cfg config.Config) []DiagnoseCheck {
+	endpoints := cfg.Endpoints()
+	checks := make([]DiagnoseCheck, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		var steps []string
+		err := checkAPIReachability(cfg, endpoint, func(step, detail string) {
+			steps = append(steps, step+": "+detail)
+		})
+		check := DiagnoseCheck{Name: "api", Status: diagnosePass, Detail: strings.Join(steps, "; ")}
+		if err != nil {
+			check = DiagnoseCheck{Name: "api", Status: diagnoseFail, Detail: err.Error()}
+			var pfErr *preflightError
+			if errors.As(err, &pfErr) {
+				check.Detail = pfErr.Step + ": " + pfErr.Err.Error()
+				check.Remediation = pfErr.Remediation
+			}
+		}
+		if len(endpoints) > 1 {
+			check.Detail = endpoint + ": " + check.Detail
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// diagnoseClock compara o relogio local com o header Date da API, no
+// primeiro endereco que responder. Qualquer resposta serve, ate um 405: so o
+// header importa.
+func diagnoseClock(cfg config.Config) DiagnoseCheck {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return DiagnoseCheck{Name: "clock", Status: diagnoseSkip, Detail: err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+	var sent time.Time
+	date, err := callAPI(cfg, nil, func(endpoint string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+		if err != nil {
+			return "", err
+		}
+		sent = time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		return resp.Header.Get("Date"), nil
+	})
+	if err != nil {
+		return DiagnoseCheck{Name: "clock", Status: diagnoseSkip, Detail: tr("API unreachable")}
+	}
+	remote, err := http.ParseTime(date)
+	if err != nil {
+		return DiagnoseCheck{Name: "clock", Status: diagnoseSkip, Detail: tr("the API response has no Date header")}
+	}
+
+	// o header tem resolucao de segundos; mede contra o meio da requisicao
+	local := sent.Add(time.Since(sent) / 2)
+	skew := local.Sub(remote).Round(time.Second)
+	detail := trf("local clock differs from the API by %s", skew)
+	if skew.Abs() > maxClockSkew {
+		return DiagnoseCheck{Name: "clock", Status: diagnoseFail, Detail: detail, Remediation: tr("enable time synchronization (timedatectl set-ntp true, chrony or w32time)")}
+	}
+	return DiagnoseCheck{Name: "clock", Status: diagnosePass, Detail: detail}
+}
+
+func diagnoseDocker() DiagnoseCheck {
+	if !dockerInstalled() {
+		return DiagnoseCheck{Name: "docker", Status: diagnoseSkip, Detail: tr("docker not installed")}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+	out, err := dockerCommand(ctx, dockerEndpoint{}, "version", "--format", "{{.Server.Version}}").Output()
+	if err != nil {
+		check := DiagnoseCheck{Name: "docker", Status: diagnoseFail, Detail: err.Error()}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			check.Detail = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		switch classifyError(err) {
+		case errKindPermission:
+			check.Remediation = tr("the agent user cannot open the docker socket; run the agent as root or add it to the docker group")
+		case errKindRuntimeMissing:
+			check.Remediation = tr("docker CLI not found in PATH")
+		default:
+			check.Remediation = tr("the docker daemon is not answering; check systemctl status docker")
+		}
+		return check
+	}
+	return DiagnoseCheck{Name: "docker", Status: diagnosePass, Detail: trf("daemon %s", strings.TrimSpace(string(out)))}
+}
+
+// diagnoseScheduler confere que o agente esta agendado e que o agendamento
+// de fato roda: um cron instalado sem execucoes recentes tambem e falha.
+func diagnoseScheduler(cfg config.Config, stateDir string) DiagnoseCheck {
+	name, ok := installedScheduler()
+	if !ok {
+		return DiagnoseCheck{Name: "scheduler", Status: diagnoseFail, Detail: tr("not installed"), Remediation: tr("run vaultrix-agent install")}
+	}
+	j, err := loadJournal(stateDir)
+	if err != nil || j.LastRun == nil {
+		return DiagnoseCheck{Name: "scheduler", Status: diagnoseFail, Detail: trf("%s installed, but the agent never ran", name), Remediation: tr("check that the cron daemon or service is running")}
+	}
+	interval := max(cfg.Interval, 1)
+	age := time.Since(j.LastRun.Time).Round(time.Second)
+	if age > 3*time.Duration(interval)*time.Minute {
+		return DiagnoseCheck{Name: "scheduler", Status: diagnoseFail, Detail: trf("%s installed, but the last run was %s ago", name, age), Remediation: tr("check that the cron daemon or service is running")}
+	}
+	return DiagnoseCheck{Name: "scheduler", Status: diagnosePass, Detail: trf("%s, last run %s ago", name, age)}
+}
+
+// diagnoseBinary verifica o binario instalado (ou o atual, fora de uma
+// instalacao): um binario gravavel por outros usuarios roda como root.
+func diagnoseBinary() DiagnoseCheck {
+	path := agentBinaryPath
+	if !fsutil.FileExists(path) {
+		exe, err := os.Executable()
+		if err != nil {
+			return DiagnoseCheck{Name: "binary", Status: diagnoseSkip, Detail: err.Error()}
+		}
+		path = exe
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return DiagnoseCheck{Name: "binary", Status: diagnoseFail, Detail: err.Error()}
+	}
+	if runtime.GOOS == "windows" {
+		return DiagnoseCheck{Name: "binary", Status: diagnosePass, Detail: path}
+	}
+	mode := info.Mode().Perm()
+	detail := fmt.Sprintf("%s %s", path, mode)
+	switch {
+	case mode&0o022 != 0:
+		return DiagnoseCheck{Name: "binary", Status: diagnoseFail, Detail: detail, Remediation: trf("writable by other users; run chmod 755 %s", path)}
+	case mode&0o100 == 0:
+		return DiagnoseCheck{Name: "binary", Status: diagnoseFail, Detail: detail, Remediation: trf("not executable; run chmod 755 %s", path)}
+	}
+	return DiagnoseCheck{Name: "binary", Status: diagnosePass, Detail: detail}
+}
+
+func runDiagnoseCommand(args []string) {
+	fs := flag.NewFlagSet("diagnose", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	asJSON := fs.Bool("json", false, tr("JSON output"))
+	fs.Parse(args)
+
+	checks := runDiagnose(*configPath, *stateDir)
+	failed := 0
+	for _, c := range checks {
+		if c.Status == diagnoseFail {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		b, _ := json.MarshalIndent(checks, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		for _, c := range checks {
+			fmt.Printf("[%s] %-9s %s\n", c.Status, c.Name, c.Detail)
+			if c.Remediation != "" {
+				fmt.Printf("       -> %s\n", c.Remediation)
+			}
+		}
+		if failed == 0 {
+			fmt.Println(tr("All checks passed"))
+		} else {
+			fmt.Println(trf("%d check(s) failed", failed))
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
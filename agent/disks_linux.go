@@ -0,0 +1,161 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Sistemas de arquivos virtuais ou de camadas de container, que nao
+// representam espaco real em disco.
+var ignoredFSTypes = map[string]bool{
+	"overlay": true, "aufs": true, "tmpfs": true, "devtmpfs": true, "ramfs": true,
+	"proc": true, "sysfs": true, "cgroup": true, "cgroup2": true, "devpts": true,
+	"mqueue": true, "hugetlbfs": true, "debugfs": true, "tracefs": true,
+	"securityfs": true, "pstore": true, "bpf": true, "configfs": true,
+	"fusectl": true, "nsfs": true, "autofs": true, "binfmt_misc": true,
+	"squashfs": true, "efivarfs": true, "rpc_pipefs": true, "nfsd": true,
+	"fuse.lxcfs": true, "shm": true,
+}
+
+type mountEntry struct {
+	device     string
+	mountPoint string
+	fsType     string
+	source     string
+}
+
+// collectDisks lista cada dispositivo de bloco real uma unica vez. Em hosts
+// com muitos containers o mesmo disco aparece em centenas de bind mounts
+// (hostname, resolv.conf, volumes); a chave major:minor elimina essas copias
+// e fica o ponto de montagem mais curto. Com o / do host montado em root, so
+// entram as montagens abaixo dele, com o caminho visto pelo host.
+func collectDisks(ctx context.Context, root string) []DiskUsage {
+	mounts, err := readMountInfo("/proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+
+	byDevice := make(map[string]mountEntry)
+	for _, m := range mounts {
+		if ignoredFSTypes[m.fsType] || isContainerRuntimePath(m.mountPoint) {
+			continue
+		}
+		if _, ok := hostMountPoint(m.mountPoint, root); !ok {
+			continue
+		}
+		prev, ok := byDevice[m.device]
+		if !ok || len(m.mountPoint) < len(prev.mountPoint) {
+			byDevice[m.device] = m
+		}
+	}
+
+	disks := make([]DiskUsage, 0, len(byDevice))
+	for _, m := range byDevice {
+		if ctx.Err() != nil {
+			break
+		}
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(m.mountPoint, &st); err != nil || st.Blocks == 0 {
+			continue
+		}
+		const gb = 1024 * 1024 * 1024
+		total := float64(st.Blocks) * float64(st.Bsize)
+		free := float64(st.Bavail) * float64(st.Bsize)
+		used := total - float64(st.Bfree)*float64(st.Bsize)
+		d := DiskUsage{
+			Device:     m.source,
+			MountPoint: hostPath(m.mountPoint, root),
+			FSType:     m.fsType,
+			TotalGB:    total / gb,
+			UsedGB:     used / gb,
+		}
+		if used+free > 0 {
+			d.Percent = used / (used + free) * 100
+		}
+		disks = append(disks, d)
+	}
+
+	sort.Slice(disks, func(i, j int) bool { return disks[i].MountPoint < disks[j].MountPoint })
+	return disks
+}
+
+// isContainerRuntimePath identifica montagens internas do docker,
+// containerd e kubelet, que nunca sao o disco "de verdade" do host.
+func isContainerRuntimePath(mountPoint string) bool {
+	for _, prefix := range []string{
+		"/var/lib/docker/", "/var/lib/containerd/", "/run/containerd/",
+		"/var/lib/kubelet/pods/", "/run/k3s/", "/var/lib/containers/storage/",
+		"/run/docker/",
+	} {
+		if strings.HasPrefix(mountPoint, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func readMountInfo(path string) ([]mountEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || len(fields) < sep+3 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			device:     fields[2],
+			mountPoint: unescapeMountPath(fields[4]),
+			fsType:     fields[sep+1],
+			source:     fields[sep+2],
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath desfaz os escapes octais (\040 = espaco) do mountinfo.
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			var c byte
+			valid := true
+			for _, d := range path[i+1 : i+4] {
+				if d < '0' || d > '7' {
+					valid = false
+					break
+				}
+				c = c*8 + byte(d-'0')
+			}
+			if valid {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+)
+
+// collectDisks depende de /proc/self/mountinfo; nas demais plataformas so o
+// disco principal e reportado, pelos campos disk_* de Metrics.
+func collectDisks(ctx context.Context, root string) []DiskUsage {
+	return nil
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// dockerEndpoint e um daemon de containers a ser consultado. Host vazio usa
+// o padrao do CLI (DOCKER_HOST ou /var/run/docker.sock). Com Podman, os
+// comandos vao para o CLI do podman e Host vira o --url; com containerd, vao
+// para o ctr e Host e o socket.
+type dockerEndpoint struct {
+	Host     string
+	Name     string
+	Rootless bool
+	Runtime  string
+
+	// tls sao as opcoes de TLS do CLI docker para um docker_hosts tcp://.
+	tls []string
+}
+
+// endpointItem e um item de inventario de daemon (disco, volume, imagem,
+// rede, Swarm) que guarda de qual daemon veio.
+type endpointItem[T any] interface {
+	*T
+	setEndpoint(name string)
+}
+
+// forEachDockerEndpoint junta o que collect devolve em cada daemon docker ou
+// podman; o ctr nao tem esses inventarios. Os itens de daemons alem do
+// padrao levam o nome dele em Endpoint, como os containers. Docker ausente
+// nao e falha de coleta, e um daemon que falha nao impede os demais: o
+// primeiro erro volta junto com o que os outros responderam.
+func forEachDockerEndpoint[T any, PT endpointItem[T]](ctx context.Context, endpoints []dockerEndpoint, collect func(ctx context.Context, ep dockerEndpoint) ([]T, error)) ([]T, error) {
+	var all []T
+	var firstErr error
+	for _, ep := range endpoints {
+		if ep.runtime() == runtimeContainerd {
+			continue
+		}
+		found, err := collect(ctx, ep)
+		if errors.Is(err, exec.ErrNotFound) {
+			continue
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %s", ep.Name, collectors.CommandError(err))
+		}
+		if ep.Name != "default" {
+			for i := range found {
+				PT(&found[i]).setEndpoint(ep.Name)
+			}
+		}
+		all = append(all, found...)
+	}
+	return all, firstErr
+}
+
+// Valores de dockerEndpoint.Runtime; vazio e docker.
+const (
+	runtimeDocker     = "docker"
+	runtimePodman     = "podman"
+	runtimeContainerd = "containerd"
+)
+
+// DockerEndpointInfo descreve no payload cada daemon encontrado.
+type DockerEndpointInfo struct {
+	Name        string `json:"name"`
+	Runtime     string `json:"runtime"`
+	Rootless    bool   `json:"rootless"`
+	UsernsRemap bool   `json:"usernsRemap"`
+	Status      string `json:"status"`
+
+	// Daemon vem do info do daemon quando ele respondeu (ver dockerinfo.go).
+	Daemon *DockerDaemonInfo `json:"daemon,omitempty"`
+}
+
+type dockerResult struct {
+	containers []ContainerStatus
+	status     string
+	endpoints  []DockerEndpointInfo
+	errors     []CollectorError
+}
+
+func dockerCommand(ctx context.Context, ep dockerEndpoint, args ...string) *exec.Cmd {
+	switch ep.runtime() {
+	case runtimePodman:
+		if ep.Host != "" {
+			args = append([]string{"--url", ep.Host}, args...)
+		}
+		return exec.CommandContext(ctx, "podman", args...)
+	case runtimeContainerd:
+		if ep.Host != "" {
+			args = append([]string{"--address", ep.Host}, args...)
+		}
+		return exec.CommandContext(ctx, "ctr", args...)
+	}
+	if ep.Host != "" {
+		args = append(append([]string{"-H", ep.Host}, ep.tls...), args...)
+	}
+	return exec.CommandContext(ctx, "docker", args...)
+}
+
+// discoverDockerEndpoints devolve os daemons locais seguidos dos remotos de
+// docker_hosts.
+func discoverDockerEndpoints(cfg config.Config) []dockerEndpoint {
+	local := localDockerEndpoints(len(cfg.DockerHosts) > 0)
+	return append(local, remoteDockerEndpoints(cfg.DockerHosts)...)
+}
+
+// localDockerEndpoints encontra o daemon padrao e instalacoes rootless. O
+// docker rootless escuta em $XDG_RUNTIME_DIR/docker.sock de cada usuario;
+// como o agente roda como root pelo cron, os sockets de /run/user/* sao
+// procurados diretamente.
+func localDockerEndpoints(hasRemote bool) []dockerEndpoint {
+	if !dockerInstalled() {
+		if others := append(discoverPodmanEndpoints(), discoverContainerdEndpoints()...); len(others) > 0 {
+			return others
+		}
+	}
+
+	var rootless []dockerEndpoint
+	seen := make(map[string]bool)
+	addRootless := func(socket string) {
+		if seen[socket] || !fsutil.FileExists(socket) {
+			return
+		}
+		seen[socket] = true
+		uid := filepath.Base(filepath.Dir(socket))
+		rootless = append(rootless, dockerEndpoint{Host: "unix://" + socket, Name: "rootless:" + uid, Rootless: true})
+	}
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+		addRootless(filepath.Join(xdg, "docker.sock"))
+	}
+	if sockets, err := filepath.Glob("/run/user/*/docker.sock"); err == nil {
+		for _, socket := range sockets {
+			addRootless(socket)
+		}
+	}
+
+	// Sem daemon padrao, mas com rootless ou remotos, o padrao so geraria
+	// erro.
+	if (len(rootless) > 0 || hasRemote) && os.Getenv("DOCKER_HOST") == "" && !fsutil.FileExists(dockerSocketPath) {
+		return rootless
+	}
+	return append([]dockerEndpoint{{Name: "default"}}, rootless...)
+}
+
+// collectDocker consulta todos os daemons em paralelo, cada um com os prazos
+// proprios de ps, stats e rede.
+func collectDocker(cfg config.Config) dockerResult {
+	endpoints := discoverDockerEndpoints(cfg)
+	results := make([]dockerResult, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, ep dockerEndpoint) {
+			defer wg.Done()
+			results[i] = collectDockerEndpoint(cfg, ep)
+		}(i, ep)
+	}
+	wg.Wait()
+
+	// O status geral e ok se qualquer daemon respondeu.
+	merged := dockerResult{status: results[0].status}
+	for _, r := range results {
+		merged.containers = append(merged.containers, r.containers...)
+		merged.endpoints = append(merged.endpoints, r.endpoints...)
+		merged.errors = append(merged.errors, r.errors...)
+		if r.status == runtimeOK {
+			merged.status = runtimeOK
+		}
+	}
+	return merged
+}
+
+func collectDockerEndpoint(cfg config.Config, ep dockerEndpoint) dockerResult {
+	var (
+		wg       sync.WaitGroup
+		ps       []ContainerStatus
+		psErr    error
+		stats    []ContainerStatus
+		statsErr error
+		info     DockerEndpointInfo
+	)
+	info = DockerEndpointInfo{Name: ep.Name, Runtime: ep.runtime(), Rootless: ep.Rootless}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ps, psErr = collectors.Run(cfg.CollectorTimeout(dockerPSTimeout), func(ctx context.Context) ([]ContainerStatus, error) {
+			if err := collectorFault(ctx, config.CollectorDocker); err != nil {
+				return nil, err
+			}
+			containers, err := collectDockerPS(ctx, ep)
+			if err == nil && ep.runtime() != runtimeContainerd {
+				// sem o inspect so faltam o ciclo de vida, o healthcheck e os
+				// eventos de reinicio e OOM
+				inspectContainerLifecycle(ctx, ep, containers)
+			}
+			return containers, err
+		})
+		if psErr == nil {
+			// sem o info o daemon vai ao payload so com nome e status
+			if daemon, err := collectDaemonInfo(ep); err == nil && daemon != nil {
+				info.Daemon = daemon
+				info.Rootless = daemon.rootless || ep.Rootless
+				info.UsernsRemap = daemon.userns
+			}
+		}
+	}()
+	// Com cgroups locais as estatisticas dependem do inventario (IDs) e rodam
+	// depois do ps, assim como com niveis de amostragem; so o docker stats
+	// completo pode rodar em paralelo.
+	statsEnabled := collectorEnabled(cfg, config.CollectorDockerStats)
+	useCgroups := cgroupStatsUsable(ep)
+	if statsEnabled && len(cfg.StatsTiers) == 0 && !useCgroups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, statsErr = collectors.Run(cfg.CollectorTimeout(dockerStatsTimeout), func(ctx context.Context) ([]ContainerStatus, error) {
+				if err := collectorFault(ctx, config.CollectorDockerStats); err != nil {
+					return nil, err
+				}
+				return collectDockerStats(ctx, ep, nil)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if psErr == nil && statsEnabled && (len(cfg.StatsTiers) > 0 || useCgroups) {
+		if ids := statsTargets(cfg.StatsTiers, ps, statsCycle(cfg, time.Now())); len(ids) > 0 {
+			stats, statsErr = collectors.Run(cfg.CollectorTimeout(dockerStatsTimeout), func(ctx context.Context) ([]ContainerStatus, error) {
+				if err := collectorFault(ctx, config.CollectorDockerStats); err != nil {
+					return nil, err
+				}
+				if useCgroups {
+					return collectCgroupStats(ctx, ep, ps, ids)
+				}
+				return collectDockerStats(ctx, ep, ids)
+			})
+		}
+	}
+
+	var result dockerResult
+	suffix := ""
+	if ep.Name != "default" {
+		suffix = " (" + ep.Name + ")"
+	}
+	noteError := func(name string, err error) {
+		logCollectorError(name+suffix, err)
+		// docker ausente nao e falha de coleta
+		if err != nil && !errors.Is(err, exec.ErrNotFound) {
+			result.errors = append(result.errors, newCollectorError(name+suffix, err))
+		}
+	}
+	noteError(ep.runtime()+" ps", psErr)
+	noteError(ep.runtime()+" stats", statsErr)
+
+	result.status = containerRuntimeStatus(psErr)
+	info.Status = result.status
+	result.endpoints = []DockerEndpointInfo{info}
+	if psErr != nil {
+		return result
+	}
+
+	containers := filterContainers(cfg.ContainerFilter, mergeContainers(ps, stats))
+	// as interfaces sao lidas do /proc deste host
+	if len(containers) > 0 && collectorEnabled(cfg, config.CollectorDockerNet) && !ep.remote() {
+		ifaces, err := collectors.Run(cfg.CollectorTimeout(dockerNetTimeout), func(ctx context.Context) (map[string][]ContainerInterface, error) {
+			if err := collectorFault(ctx, config.CollectorDockerNet); err != nil {
+				return nil, err
+			}
+			return collectContainerInterfaces(ctx, ep, containers)
+		})
+		noteError(ep.runtime()+" net", err)
+		for i := range containers {
+			containers[i].Interfaces = ifaces[containers[i].Name]
+		}
+	}
+	if len(containers) > 0 && cfg.LogScan != nil && collectorEnabled(cfg, config.CollectorDockerLogs) && ep.runtime() != runtimeContainerd {
+		now := time.Now()
+		since := logScanWindows(containers, time.Duration(cfg.Interval)*time.Minute, now)
+		scans, err := collectors.Run(cfg.CollectorTimeout(logScanTimeout), func(ctx context.Context) (map[string]logScanResult, error) {
+			if err := collectorFault(ctx, config.CollectorDockerLogs); err != nil {
+				return nil, err
+			}
+			return scanContainerLogs(ctx, ep, cfg.LogScan, since, now)
+		})
+		noteError(ep.runtime()+" logs", err)
+		recordLogScans(scans, now)
+		for i := range containers {
+			if r, ok := scans[containers[i].ID]; ok {
+				containers[i].LogMatches, containers[i].LogTruncated = r.matches, r.truncated
+			}
+		}
+	}
+	if ep.Name != "default" {
+		for i := range containers {
+			containers[i].Endpoint = ep.Name
+		}
+	}
+	result.containers = containers
+	return result
+}
+
+func collectDockerPS(ctx context.Context, ep dockerEndpoint) ([]ContainerStatus, error) {
+	switch ep.runtime() {
+	case runtimePodman:
+		return collectPodmanPS(ctx, ep)
+	case runtimeContainerd:
+		return collectContainerdPS(ctx, ep)
+	}
+	out, err := dockerCommand(ctx, ep, "ps", "-a", "--format", "{{.ID}}|{{.Names}}|{{.Image}}|{{.State}}|{{.Status}}|{{.Labels}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	containers := make([]ContainerStatus, 0, len(lines))
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "|", 6)
+		if len(parts) < 2 {
+			continue
+		}
+		id := strings.TrimSpace(parts[0])
+		name := strings.TrimSpace(parts[1])
+		image := ""
+		state := ""
+		status := ""
+		var labels map[string]string
+		if len(parts) > 2 {
+			image = strings.TrimSpace(parts[2])
+		}
+		if len(parts) > 3 {
+			state = strings.TrimSpace(parts[3])
+		}
+		if len(parts) > 4 {
+			status = strings.TrimSpace(parts[4])
+		}
+		if len(parts) > 5 {
+			labels = parseLabels(parts[5])
+		}
+		containers = append(containers, ContainerStatus{
+			ID:     id,
+			Name:   name,
+			Image:  image,
+			State:  state,
+			Status: status,
+			Labels: labels,
+		})
+	}
+	return containers, nil
+}
+
+// inspectContainerLifecycle preenche reinicios, inicio e a ultima saida dos
+// containers, que vao no payload e alimentam os eventos, alem das portas
+// publicadas e das redes. O docker zera
+// OOMKilled quando o container volta a rodar: com restart policy, um OOM
+// aparece so como reinicio.
+func inspectContainerLifecycle(ctx context.Context, ep dockerEndpoint, containers []ContainerStatus) {
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		if c.ID != "" {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	// um container removido entre o ps e o inspect faz o comando falhar, mas
+	// a saida dos demais continua valendo
+	args := append([]string{"inspect", "--format", containerInspectFormat}, ids...)
+	out, _ := dockerCommand(ctx, ep, args...).Output()
+	applyContainerInspect(containers, string(out))
+}
+
+// containerInspectFormat termina no JSON do healthcheck, que pode ter "|"
+// na saida das provas mas nunca uma quebra de linha.
+const containerInspectFormat = "{{.Name}}|{{.RestartCount}}|{{.State.StartedAt}}|{{.State.OOMKilled}}|{{.State.FinishedAt}}|{{.State.ExitCode}}|{{.State.Running}}|{{json .NetworkSettings.Ports}}|" + containerNetworksFormat + "|{{json .State.Health}}"
+
+// maxHealthOutput limita a saida da ultima prova, que o docker guarda
+// inteira (ate 4 KiB).
+const maxHealthOutput = 512
+
+// applyContainerInspect le a saida de containerInspectFormat, uma linha por
+// container.
+func applyContainerInspect(containers []ContainerStatus, out string) {
+	byName := make(map[string]int, len(containers))
+	for i, c := range containers {
+		byName[c.Name] = i
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "|", 10)
+		if len(parts) != 10 {
+			continue
+		}
+		i, ok := byName[strings.TrimPrefix(parts[0], "/")]
+		if !ok {
+			continue
+		}
+		c := &containers[i]
+		c.Ports = parseContainerPorts(parts[7])
+		c.Networks = parseContainerNetworks(parts[8])
+		c.Health = parseContainerHealth(parts[9])
+		restarts, _ := strconv.Atoi(parts[1])
+		c.RestartCount = &restarts
+		if started := parseRuntimeTime(parts[2]); !started.IsZero() {
+			c.StartedAt = &started
+		}
+		// um container que nunca parou tem FinishedAt zero e nada a contar
+		finished := parseRuntimeTime(parts[4])
+		if finished.IsZero() {
+			continue
+		}
+		c.FinishedAt = &finished
+		c.OOMKilled = parts[3] == "true"
+		if c.OOMKilled {
+			c.oomKilledAt = finished
+		}
+		// rodando, o codigo e o 0 que o docker poe na partida
+		if code, err := strconv.Atoi(parts[5]); err == nil && parts[6] != "true" {
+			c.ExitCode = &code
+		}
+	}
+}
+
+// parseContainerHealth le o {{json .State.Health}}; "null" (ou o "<no
+// value>" de versoes antigas do podman) e um container sem HEALTHCHECK.
+func parseContainerHealth(s string) *ContainerHealth {
+	var raw struct {
+		Status        string `json:"Status"`
+		FailingStreak int    `json:"FailingStreak"`
+		Log           []struct {
+			End      string `json:"End"`
+			ExitCode int    `json:"ExitCode"`
+			Output   string `json:"Output"`
+		} `json:"Log"`
+	}
+	if json.Unmarshal([]byte(s), &raw) != nil || raw.Status == "" || raw.Status == "none" {
+		return nil
+	}
+	h := &ContainerHealth{Status: raw.Status, FailingStreak: raw.FailingStreak}
+	// o log vem do mais antigo para o mais novo
+	if n := len(raw.Log); n > 0 {
+		last := raw.Log[n-1]
+		h.LastCheckAt = parseRuntimeTime(last.End)
+		h.LastExitCode = last.ExitCode
+		h.LastOutput = strings.TrimSpace(last.Output)
+		if len(h.LastOutput) > maxHealthOutput {
+			h.LastOutput = h.LastOutput[:maxHealthOutput]
+		}
+	}
+	return h
+}
+
+// parseRuntimeTime le os horarios do inspect: RFC 3339 no docker, o
+// formato de time.Time.String no podman. O "0001-01-01T00:00:00Z" de quem
+// nunca rodou vira o tempo zero.
+func parseRuntimeTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST"} {
+		if t, err := time.Parse(layout, s); err == nil && t.Year() > 1 {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// collectDockerStats coleta estatisticas dos containers em ids, ou de todos
+// quando ids e nil.
+func collectDockerStats(ctx context.Context, ep dockerEndpoint, ids []string) ([]ContainerStatus, error) {
+	switch ep.runtime() {
+	case runtimePodman:
+		return collectPodmanStats(ctx, ep, ids)
+	case runtimeContainerd:
+		// o ctr nao tem um "stats"; sem cgroup visivel nao ha o que medir
+		return nil, nil
+	}
+	args := []string{"stats", "--no-stream", "--format", "{{.ID}}|{{.Name}}|{{.CPUPerc}}|{{.MemUsage}}|{{.MemPerc}}|{{.NetIO}}|{{.BlockIO}}|{{.PIDs}}"}
+	if ids == nil {
+		args = append(args, "--all")
+	} else {
+		args = append(args, ids...)
+	}
+	stats, err := dockerCommand(ctx, ep, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	statLines := strings.Split(strings.TrimSpace(string(stats)), "\n")
+	containers := make([]ContainerStatus, 0, len(statLines))
+	for _, line := range statLines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "|", 8)
+		if len(parts) < 2 {
+			continue
+		}
+		entry := ContainerStatus{
+			ID:   strings.TrimSpace(parts[0]),
+			Name: strings.TrimSpace(parts[1]),
+		}
+		if len(parts) > 2 {
+			entry.CPUPercent = parsePercent(parts[2])
+		}
+		if len(parts) > 3 {
+			entry.MemUsage = strings.TrimSpace(parts[3])
+		}
+		if len(parts) > 4 {
+			entry.MemPercent = parsePercent(parts[4])
+		}
+		if len(parts) > 5 {
+			entry.NetIO = strings.TrimSpace(parts[5])
+		}
+		if len(parts) > 6 {
+			entry.BlockIO = strings.TrimSpace(parts[6])
+		}
+		if len(parts) > 7 {
+			entry.PIDs = collectors.ParseInt64(parts[7])
+		}
+		entry.setStatBytes()
+		containers = append(containers, entry)
+	}
+	return containers, nil
+}
+
+// collectCgroupStats le as estatisticas dos containers em ids direto dos
+// cgroups. O docker stats --no-stream leva segundos com muitos containers e
+// sobrecarrega o daemon; ele fica apenas para os containers cujo cgroup nao
+// foi encontrado.
+func collectCgroupStats(ctx context.Context, ep dockerEndpoint, containers []ContainerStatus, ids []string) ([]ContainerStatus, error) {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	targets := make([]ContainerStatus, 0, len(ids))
+	for _, c := range containers {
+		if wanted[c.ID] {
+			targets = append(targets, c)
+		}
+	}
+
+	stats, missing := readCgroupStats(ctx, targets)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(missing) == 0 {
+		return stats, nil
+	}
+	rest, err := collectDockerStats(ctx, ep, missing)
+	return append(stats, rest...), err
+}
+
+// Valores de container_runtime_status.
+const (
+	runtimeOK           = "ok"
+	runtimeUnavailable  = "unavailable"
+	runtimeTimeout      = "timeout"
+	runtimeNotInstalled = "not_installed"
+)
+
+const dockerSocketPath = "/var/run/docker.sock"
+
+// dockerInstalled indica se ha um docker a consultar: o CLI, o socket
+// padrao ou um DOCKER_HOST.
+func dockerInstalled() bool {
+	if _, err := exec.LookPath("docker"); err == nil {
+		return true
+	}
+	return fsutil.FileExists(dockerSocketPath) || os.Getenv("DOCKER_HOST") != ""
+}
+
+func containerRuntimeStatus(psErr error) string {
+	switch {
+	case psErr == nil:
+		return runtimeOK
+	case errors.Is(psErr, context.DeadlineExceeded):
+		return runtimeTimeout
+	case errors.Is(psErr, exec.ErrNotFound) && !fsutil.FileExists(dockerSocketPath) && os.Getenv("DOCKER_HOST") == "":
+		return runtimeNotInstalled
+	}
+	return runtimeUnavailable
+}
+
+// mergeContainers combina o inventario do docker ps com as estatisticas do
+// docker stats, usando o nome do container como chave.
+func mergeContainers(ps, stats []ContainerStatus) []ContainerStatus {
+	containerMap := make(map[string]ContainerStatus, len(ps))
+	for _, entry := range ps {
+		containerMap[entry.Name] = entry
+	}
+
+	for _, stat := range stats {
+		entry := containerMap[stat.Name]
+		entry.ID = stat.ID
+		entry.Name = stat.Name
+		entry.CPUPercent = stat.CPUPercent
+		entry.MemUsage = stat.MemUsage
+		entry.MemPercent = stat.MemPercent
+		entry.NetIO = stat.NetIO
+		entry.BlockIO = stat.BlockIO
+		entry.PIDs = stat.PIDs
+		entry.MemUsageBytes, entry.MemLimitBytes = stat.MemUsageBytes, stat.MemLimitBytes
+		entry.NetRxBytes, entry.NetTxBytes = stat.NetRxBytes, stat.NetTxBytes
+		entry.BlockReadBytes, entry.BlockWriteBytes = stat.BlockReadBytes, stat.BlockWriteBytes
+		containerMap[stat.Name] = entry
+	}
+
+	containers := make([]ContainerStatus, 0, len(containerMap))
+	for _, entry := range containerMap {
+		containers = append(containers, entry)
+	}
+	return containers
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+)
+
+// O "system df" soma o tamanho de cada volume e pode demorar em hosts com
+// volumes grandes; por isso tem coletor e prazo proprios, separados do ps.
+const dockerDiskTimeout = 30 * time.Second
+
+// DockerDiskUsage e o "docker system df" de um daemon: quanto do disco e de
+// imagens, containers, volumes e cache de build, e quanto disso um prune
+// liberaria.
+type DockerDiskUsage struct {
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Images     *DockerDiskItem `json:"images,omitempty"`
+	Containers *DockerDiskItem `json:"containers,omitempty"`
+	Volumes    *DockerDiskItem `json:"volumes,omitempty"`
+	BuildCache *DockerDiskItem `json:"buildCache,omitempty"`
+
+	TotalBytes       int64 `json:"totalBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// DockerDiskItem e uma linha do "system df". Os tamanhos vem arredondados
+// pelo CLI ("1.234GB"), com quatro algarismos significativos.
+type DockerDiskItem struct {
+	Count            int64 `json:"count"`
+	Active           int64 `json:"active"`
+	SizeBytes        int64 `json:"sizeBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+func (u *DockerDiskUsage) setEndpoint(name string) { u.Endpoint = name }
+
+// collectDockerDisk le o "system df" de cada daemon. O erro de um daemon vai
+// no item dele, e nao em collector_errors.
+func collectDockerDisk(ctx context.Context, endpoints []dockerEndpoint) []DockerDiskUsage {
+	usage, _ := forEachDockerEndpoint(ctx, endpoints, func(ctx context.Context, ep dockerEndpoint) ([]DockerDiskUsage, error) {
+		out, err := dockerCommand(ctx, ep, "system", "df", "--format", "{{json .}}").Output()
+		if err != nil && !errors.Is(err, exec.ErrNotFound) {
+			return []DockerDiskUsage{{Error: collectors.CommandError(err)}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []DockerDiskUsage{parseDockerDiskUsage(string(out))}, nil
+	})
+	return usage
+}
+
+// parseDockerDiskUsage le as linhas JSON do "system df --format". O docker
+// manda os numeros como texto ("TotalCount": "12") e o podman como numero
+// ("Total": 12).
+func parseDockerDiskUsage(out string) DockerDiskUsage {
+	var u DockerDiskUsage
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var raw map[string]any
+		if json.Unmarshal([]byte(line), &raw) != nil {
+			continue
+		}
+		text := func(key string) string {
+			switch v := raw[key].(type) {
+			case string:
+				return v
+			case float64:
+				return fmt.Sprint(int64(v))
+			}
+			return ""
+		}
+		count := text("TotalCount")
+		if count == "" {
+			count = text("Total")
+		}
+		// "1.2GB (30%)"
+		reclaimable, _, _ := strings.Cut(text("Reclaimable"), "(")
+		item := &DockerDiskItem{
+			Count:            collectors.ParseInt64(count),
+			Active:           collectors.ParseInt64(text("Active")),
+			SizeBytes:        parseByteSize(text("Size")),
+			ReclaimableBytes: parseByteSize(reclaimable),
+		}
+		switch text("Type") {
+		case "Images":
+			u.Images = item
+		case "Containers":
+			u.Containers = item
+		case "Local Volumes":
+			u.Volumes = item
+		case "Build Cache":
+			u.BuildCache = item
+		default:
+			continue
+		}
+		u.TotalBytes += item.SizeBytes
+		u.ReclaimableBytes += item.ReclaimableBytes
+	}
+	return u
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDockerDiskUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want DockerDiskUsage
+	}{
+		{
+			name: "docker",
+			out: `{"Active":"3","Reclaimable":"1.2GB (30%)","Size":"4GB","TotalCount":"10","Type":"Images"}
+{"Active":"2","Reclaimable":"10MB (50%)","Size":"20MB","TotalCount":"5","Type":"Containers"}
+{"Active":"1","Reclaimable":"0B (0%)","Size":"512MB","TotalCount":"1","Type":"Local Volumes"}
+{"Active":"0","Reclaimable":"300MB","Size":"300MB","TotalCount":"12","Type":"Build Cache"}
+`,
+			want: DockerDiskUsage{
+				Images:           &DockerDiskItem{Count: 10, Active: 3, SizeBytes: 4e9, ReclaimableBytes: 1.2e9},
+				Containers:       &DockerDiskItem{Count: 5, Active: 2, SizeBytes: 20e6, ReclaimableBytes: 10e6},
+				Volumes:          &DockerDiskItem{Count: 1, Active: 1, SizeBytes: 512e6},
+				BuildCache:       &DockerDiskItem{Count: 12, SizeBytes: 300e6, ReclaimableBytes: 300e6},
+				TotalBytes:       4e9 + 20e6 + 512e6 + 300e6,
+				ReclaimableBytes: 1.2e9 + 10e6 + 300e6,
+			},
+		},
+		{
+			name: "podman",
+			out: `{"Type":"Images","Total":2,"Active":1,"Size":"1.5GB","Reclaimable":"500MB (33%)"}
+{"Type":"Containers","Total":1,"Active":1,"Size":"4kB","Reclaimable":"0B (0%)"}`,
+			want: DockerDiskUsage{
+				Images:           &DockerDiskItem{Count: 2, Active: 1, SizeBytes: 1.5e9, ReclaimableBytes: 500e6},
+				Containers:       &DockerDiskItem{Count: 1, Active: 1, SizeBytes: 4e3},
+				TotalBytes:       1.5e9 + 4e3,
+				ReclaimableBytes: 500e6,
+			},
+		},
+		{"garbage", "WARNING: something\n", DockerDiskUsage{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDockerDiskUsage(tt.out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// No modo daemon o agente assina o "docker events" de cada daemon docker e
+// guarda as partidas, paradas, mortes e OOM kills de containers ate o
+// proximo ciclo, que os leva em "events". Comparar o ps a cada minuto nao
+// ve um container que morre e volta varias vezes entre dois ciclos; o
+// stream ve cada vez, com o codigo de saida. No modo cron nao ha processo
+// para escutar, e ficam so os reinicios deduzidos por trackContainers.
+const (
+	eventContainerStart = "container_start"
+	eventContainerStop  = "container_stop"
+	eventContainerDie   = "container_die"
+
+	// maxPendingDockerEvents limita o que se guarda entre dois ciclos; um
+	// container em crash loop sem backoff encheria a memoria.
+	maxPendingDockerEvents = 500
+
+	// dockerEventsRetry e a espera antes de reabrir um stream que caiu (o
+	// daemon reiniciou, por exemplo).
+	dockerEventsRetry = 10 * time.Second
+)
+
+// dockerEventWatcher guarda os eventos recebidos ate o proximo ciclo.
+type dockerEventWatcher struct {
+	mu      sync.Mutex
+	pending []PayloadEvent
+	dropped int
+}
+
+func (w *dockerEventWatcher) add(e PayloadEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) >= maxPendingDockerEvents {
+		w.dropped++
+		return
+	}
+	w.pending = append(w.pending, e)
+}
+
+// drain devolve os eventos desde a chamada anterior e quantos foram
+// descartados por passar do limite.
+func (w *dockerEventWatcher) drain() ([]PayloadEvent, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events, dropped := w.pending, w.dropped
+	w.pending, w.dropped = nil, 0
+	return events, dropped
+}
+
+// watch mantem o stream de um daemon aberto ate ctx ser cancelado. Ao
+// reabrir, pede os eventos desde o ultimo recebido, para nao perder o que
+// aconteceu enquanto o daemon estava fora.
+func (w *dockerEventWatcher) watch(ctx context.Context, ep dockerEndpoint) {
+	var last int64
+	failing := false
+	for {
+		err := w.stream(ctx, ep, &last)
+		if ctx.Err() != nil {
+			return
+		}
+		// so a primeira falha seguida vai para o log
+		if err != nil && !failing {
+			fmt.Fprintf(os.Stderr, "docker events (%s): %v\n", ep.Name, err)
+		}
+		failing = err != nil
+		if !sleepContext(ctx, dockerEventsRetry) {
+			return
+		}
+	}
+}
+
+func (w *dockerEventWatcher) stream(ctx context.Context, ep dockerEndpoint, last *int64) error {
+	args := []string{"events", "--format", "{{json .}}", "--filter", "type=container"}
+	for _, action := range []string{"start", "stop", "die", "oom"} {
+		args = append(args, "--filter", "event="+action)
+	}
+	if *last > 0 {
+		args = append(args, "--since", strconv.FormatFloat(float64(*last)/1e9, 'f', 9, 64))
+	}
+	cmd := dockerCommand(ctx, ep, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	*last = w.read(out, *last)
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// read consome as linhas do stream e devolve o instante (em ns) do ultimo
+// evento. Eventos ate after ja foram vistos: o --since os repete.
+func (w *dockerEventWatcher) read(r io.Reader, after int64) int64 {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		e, at, ok := parseDockerEvent(sc.Bytes())
+		if !ok || at <= after {
+			continue
+		}
+		after = at
+		w.add(e)
+	}
+	return after
+}
+
+// parseDockerEvent converte uma linha do "docker events --format
+// '{{json .}}'" em evento do payload, com o instante em ns.
+func parseDockerEvent(line []byte) (PayloadEvent, int64, bool) {
+	var raw struct {
+		Type   string `json:"Type"`
+		Action string `json:"Action"`
+		Actor  struct {
+			Attributes map[string]string `json:"Attributes"`
+		} `json:"Actor"`
+		TimeNano int64 `json:"timeNano"`
+	}
+	if err := json.Unmarshal(line, &raw); err != nil || raw.Type != "container" || raw.TimeNano <= 0 {
+		return PayloadEvent{}, 0, false
+	}
+	attrs := raw.Actor.Attributes
+	name := attrs["name"]
+	if name == "" {
+		return PayloadEvent{}, 0, false
+	}
+	at := time.Unix(0, raw.TimeNano)
+	switch raw.Action {
+	case "start":
+		return newEvent(eventContainerStart, name, attrs["image"], at), raw.TimeNano, true
+	case "stop":
+		return newEvent(eventContainerStop, name, attrs["image"], at), raw.TimeNano, true
+	case "die":
+		return newEvent(eventContainerDie, name, "exit code "+orDefault(attrs["exitCode"], "unknown"), at), raw.TimeNano, true
+	case "oom":
+		return newEvent(eventContainerOOMKill, name, attrs["image"], at), raw.TimeNano, true
+	}
+	return PayloadEvent{}, 0, false
+}
+
+// dockerEventEndpoints sao os daemons docker cujo stream e assinado; o
+// podman e o containerd tem formatos proprios e ficam so com o ps.
+func dockerEventEndpoints(cfg config.Config) []dockerEndpoint {
+	if !dockerInstalled() {
+		return nil
+	}
+	var endpoints []dockerEndpoint
+	for _, ep := range discoverDockerEndpoints(cfg) {
+		if ep.runtime() == runtimeDocker {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints
+}
+
+var (
+	dockerEventsMu       sync.Mutex
+	dockerEventsInstance *dockerEventWatcher
+)
+
+func activeDockerEvents() *dockerEventWatcher {
+	dockerEventsMu.Lock()
+	defer dockerEventsMu.Unlock()
+	return dockerEventsInstance
+}
+
+func setDockerEvents(w *dockerEventWatcher) {
+	dockerEventsMu.Lock()
+	defer dockerEventsMu.Unlock()
+	dockerEventsInstance = w
+}
+
+// streamedContainerEvents junta aos eventos do ciclo os que vieram pelo
+// stream. Com ele ativo, os OOM kills deduzidos do inspect saem: o stream
+// ja os viu, com o instante exato.
+func streamedContainerEvents(cfg config.Config, tracked []PayloadEvent) []PayloadEvent {
+	w := activeDockerEvents()
+	if w == nil {
+		return tracked
+	}
+	streamed, dropped := w.drain()
+	if !collectorEnabled(cfg, config.CollectorDockerEvents) {
+		return tracked
+	}
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "docker events: %d events dropped since the previous cycle\n", dropped)
+	}
+	events := make([]PayloadEvent, 0, len(tracked)+len(streamed))
+	for _, e := range tracked {
+		if e.Type != eventContainerOOMKill {
+			events = append(events, e)
+		}
+	}
+	return append(events, streamed...)
+}
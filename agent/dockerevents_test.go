@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestParseDockerEvent(t *testing.T) {
+	const ns = 1714564800123456789
+	line := func(action, attrs string) string {
+		return fmt.Sprintf(`{"status":%q,"id":"abc","Type":"container","Action":%q,"Actor":{"ID":"abc","Attributes":{%s}},"scope":"local","time":1714564800,"timeNano":%d}`, action, action, attrs, ns)
+	}
+	tests := []struct {
+		name       string
+		line       string
+		wantType   string
+		wantDetail string
+	}{
+		{"start", line("start", `"name":"web","image":"nginx:1.25"`), eventContainerStart, "nginx:1.25"},
+		{"stop", line("stop", `"name":"web","image":"nginx:1.25"`), eventContainerStop, "nginx:1.25"},
+		{"die", line("die", `"name":"web","image":"nginx:1.25","exitCode":"137"`), eventContainerDie, "exit code 137"},
+		{"die without code", line("die", `"name":"web"`), eventContainerDie, "exit code unknown"},
+		{"oom", line("oom", `"name":"web","image":"nginx:1.25"`), eventContainerOOMKill, "nginx:1.25"},
+		{"other action", line("pause", `"name":"web"`), "", ""},
+		{"no name", line("start", `"image":"nginx"`), "", ""},
+		{"network event", `{"Type":"network","Action":"connect","Actor":{"Attributes":{"name":"bridge"}},"timeNano":1}`, "", ""},
+		{"garbage", "not json", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, at, ok := parseDockerEvent([]byte(tt.line))
+			if tt.wantType == "" {
+				if ok {
+					t.Fatalf("got %+v, want no event", e)
+				}
+				return
+			}
+			if !ok || e.Type != tt.wantType || e.Subject != "web" || e.Detail != tt.wantDetail {
+				t.Fatalf("got %+v (ok %v), want %s web %q", e, ok, tt.wantType, tt.wantDetail)
+			}
+			if at != ns || !e.Time.Equal(time.Unix(0, ns)) {
+				t.Errorf("time = %v (%d), want %d", e.Time, at, int64(ns))
+			}
+		})
+	}
+}
+
+func TestDockerEventWatcherRead(t *testing.T) {
+	event := func(action string, ns int64) string {
+		return fmt.Sprintf(`{"Type":"container","Action":%q,"Actor":{"Attributes":{"name":"web","exitCode":"1"}},"timeNano":%d}`+"\n", action, ns)
+	}
+	w := &dockerEventWatcher{}
+	// um crash loop: cada morte e seguida de uma partida
+	stream := event("die", 100) + event("start", 200) + "garbage\n" + event("die", 300) + event("start", 400)
+	if last := w.read(strings.NewReader(stream), 0); last != 400 {
+		t.Fatalf("last = %d, want 400", last)
+	}
+	// ao reabrir, o --since repete o ultimo evento
+	if last := w.read(strings.NewReader(event("start", 400)+event("die", 500)), 400); last != 500 {
+		t.Fatalf("last after reconnect = %d, want 500", last)
+	}
+	events, dropped := w.drain()
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Type)
+	}
+	want := []string{eventContainerDie, eventContainerStart, eventContainerDie, eventContainerStart, eventContainerDie}
+	if strings.Join(got, ",") != strings.Join(want, ",") || dropped != 0 {
+		t.Errorf("events = %v (dropped %d), want %v", got, dropped, want)
+	}
+	if events, _ := w.drain(); len(events) != 0 {
+		t.Errorf("second drain = %+v, want nothing", events)
+	}
+
+	var flood strings.Builder
+	for i := range maxPendingDockerEvents + 3 {
+		flood.WriteString(event("start", int64(i+1)))
+	}
+	w.read(strings.NewReader(flood.String()), 0)
+	if events, dropped := w.drain(); len(events) != maxPendingDockerEvents || dropped != 3 {
+		t.Errorf("flood: %d events, %d dropped; want %d and 3", len(events), dropped, maxPendingDockerEvents)
+	}
+}
+
+func TestStreamedContainerEvents(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracked := []PayloadEvent{
+		newEvent(eventContainerOOMKill, "web", "nginx", at),
+		newEvent(eventContainerRestart, "web", "restarts: 1", at.Add(time.Second)),
+	}
+	streamed := newEvent(eventContainerOOMKill, "web", "nginx", at.Add(-time.Millisecond))
+
+	if got := streamedContainerEvents(config.Config{}, tracked); len(got) != 2 {
+		t.Fatalf("without the stream: got %+v", got)
+	}
+
+	w := &dockerEventWatcher{}
+	setDockerEvents(w)
+	defer setDockerEvents(nil)
+
+	w.add(streamed)
+	got := streamedContainerEvents(config.Config{}, tracked)
+	if len(got) != 2 || got[0].Type != eventContainerRestart || got[1].ID != streamed.ID {
+		t.Errorf("with the stream: got %+v", got)
+	}
+
+	// desligado pela configuracao, o que chegou e descartado
+	w.add(streamed)
+	off := config.Config{Collectors: map[string]bool{config.CollectorDockerEvents: false}}
+	if got := streamedContainerEvents(off, tracked); len(got) != 2 || got[0].Type != eventContainerOOMKill {
+		t.Errorf("turned off: got %+v", got)
+	}
+	if events, _ := w.drain(); len(events) != 0 {
+		t.Errorf("turned off: %d events left in the watcher", len(events))
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+func validateDockerHosts(hosts []config.DockerHost) error {
+	seen := make(map[string]bool, len(hosts))
+	for i, h := range hosts {
+		if !config.SinkNamePattern.MatchString(h.Name) {
+			return fmt.Errorf("docker_hosts[%d]: invalid name %q (lowercase letters, digits, - and _)", i, h.Name)
+		}
+		// nomes dos endpoints locais
+		if h.Name == "default" || h.Name == runtimePodman || h.Name == runtimeContainerd {
+			return fmt.Errorf("docker_hosts[%d]: name %q is reserved for local endpoints", i, h.Name)
+		}
+		if seen[h.Name] {
+			return fmt.Errorf("docker_hosts: duplicate name %q", h.Name)
+		}
+		seen[h.Name] = true
+		if !strings.HasPrefix(h.Host, "tcp://") && !strings.HasPrefix(h.Host, "ssh://") {
+			return fmt.Errorf("docker_hosts: %s: host must start with tcp:// or ssh://", h.Name)
+		}
+		if h.Runtime != "" && h.Runtime != runtimeDocker && h.Runtime != runtimePodman {
+			return fmt.Errorf("docker_hosts: %s: invalid runtime %q (use docker or podman)", h.Name, h.Runtime)
+		}
+		if h.TLSCACert == "" && h.TLSCert == "" && h.TLSKey == "" {
+			continue
+		}
+		switch {
+		case h.Runtime == runtimePodman || !strings.HasPrefix(h.Host, "tcp://"):
+			return fmt.Errorf("docker_hosts: %s: TLS certificates are only used with docker over tcp://", h.Name)
+		case h.TLSCACert == "":
+			return fmt.Errorf("docker_hosts: %s: tls_cert and tls_key need tls_ca_cert", h.Name)
+		case (h.TLSCert == "") != (h.TLSKey == ""):
+			return fmt.Errorf("docker_hosts: %s: set both tls_cert and tls_key", h.Name)
+		}
+		for _, path := range []string{h.TLSCACert, h.TLSCert, h.TLSKey} {
+			if path != "" && !filepath.IsAbs(path) {
+				return fmt.Errorf("docker_hosts: %s: certificate path %q must be absolute", h.Name, path)
+			}
+		}
+	}
+	return nil
+}
+
+// remoteDockerEndpoints converte docker_hosts nos endpoints consultados ao
+// lado dos locais; o nome vira o endpoint dos containers no payload.
+func remoteDockerEndpoints(hosts []config.DockerHost) []dockerEndpoint {
+	endpoints := make([]dockerEndpoint, 0, len(hosts))
+	for _, h := range hosts {
+		ep := dockerEndpoint{Host: h.Host, Name: h.Name, Runtime: h.Runtime}
+		if h.TLSCACert != "" {
+			ep.tls = []string{"--tlsverify", "--tlscacert", h.TLSCACert}
+			if h.TLSCert != "" {
+				ep.tls = append(ep.tls, "--tlscert", h.TLSCert, "--tlskey", h.TLSKey)
+			}
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints
+}
+
+// remote indica um daemon em outra maquina (tcp://, ssh://), inclusive o
+// padrao quando o DOCKER_HOST aponta para fora. Os cgroups, o /proc e os
+// volumes dele nao sao visiveis daqui.
+func (ep dockerEndpoint) remote() bool {
+	host := ep.Host
+	if host == "" && ep.runtime() == runtimeDocker {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	// o containerd so e acessado pelo socket local
+	return host != "" && !strings.HasPrefix(host, "unix://") && ep.runtime() != runtimeContainerd
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateDockerHosts(t *testing.T) {
+	ok := config.DockerHost{Name: "nas", Host: "ssh://admin@nas.lan"}
+	tests := []struct {
+		name    string
+		edit    func(h *config.DockerHost)
+		wantErr string
+	}{
+		{"ssh", func(h *config.DockerHost) {}, ""},
+		{"tcp tls", func(h *config.DockerHost) {
+			h.Host, h.TLSCACert, h.TLSCert, h.TLSKey = "tcp://10.0.0.7:2376", "/etc/ca.pem", "/etc/cert.pem", "/etc/key.pem"
+		}, ""},
+		{"tcp ca only", func(h *config.DockerHost) { h.Host, h.TLSCACert = "tcp://10.0.0.7:2376", "/etc/ca.pem" }, ""},
+		{"podman", func(h *config.DockerHost) { h.Runtime = runtimePodman }, ""},
+		{"bad name", func(h *config.DockerHost) { h.Name = "NAS" }, "invalid name"},
+		{"reserved", func(h *config.DockerHost) { h.Name = "default" }, "reserved"},
+		{"unix", func(h *config.DockerHost) { h.Host = "unix:///var/run/docker.sock" }, "tcp:// or ssh://"},
+		{"runtime", func(h *config.DockerHost) { h.Runtime = "containerd" }, "invalid runtime"},
+		{"tls over ssh", func(h *config.DockerHost) { h.TLSCACert = "/etc/ca.pem" }, "only used with docker over tcp://"},
+		{"cert without ca", func(h *config.DockerHost) {
+			h.Host, h.TLSCert, h.TLSKey = "tcp://10.0.0.7:2376", "/etc/cert.pem", "/etc/key.pem"
+		}, "need tls_ca_cert"},
+		{"cert without key", func(h *config.DockerHost) {
+			h.Host, h.TLSCACert, h.TLSCert = "tcp://10.0.0.7:2376", "/etc/ca.pem", "/etc/cert.pem"
+		}, "both tls_cert and tls_key"},
+		{"relative path", func(h *config.DockerHost) { h.Host, h.TLSCACert = "tcp://10.0.0.7:2376", "ca.pem" }, "absolute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ok
+			tt.edit(&h)
+			err := validateDockerHosts([]config.DockerHost{h})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+	if err := validateDockerHosts([]config.DockerHost{ok, ok}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("duplicate names: got %v", err)
+	}
+}
+
+func TestRemoteDockerCommands(t *testing.T) {
+	endpoints := remoteDockerEndpoints([]config.DockerHost{
+		{Name: "pi", Host: "tcp://10.0.0.7:2376", TLSCACert: "/ca.pem", TLSCert: "/cert.pem", TLSKey: "/key.pem"},
+		{Name: "nas", Host: "ssh://admin@nas.lan", Runtime: runtimePodman},
+	})
+	want := [][]string{
+		{"docker", "-H", "tcp://10.0.0.7:2376", "--tlsverify", "--tlscacert", "/ca.pem", "--tlscert", "/cert.pem", "--tlskey", "/key.pem", "ps"},
+		{"podman", "--url", "ssh://admin@nas.lan", "ps"},
+	}
+	for i, ep := range endpoints {
+		if !ep.remote() {
+			t.Errorf("%s: not remote", ep.Name)
+		}
+		if got := dockerCommand(context.Background(), ep, "ps").Args; !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("%s: got %v, want %v", ep.Name, got, want[i])
+		}
+	}
+}
+
+func TestDockerEndpointRemote(t *testing.T) {
+	tests := []struct {
+		name       string
+		ep         dockerEndpoint
+		dockerHost string
+		want       bool
+	}{
+		{"default", dockerEndpoint{Name: "default"}, "", false},
+		{"default tcp", dockerEndpoint{Name: "default"}, "tcp://10.0.0.7:2375", true},
+		{"default unix", dockerEndpoint{Name: "default"}, "unix:///run/docker.sock", false},
+		{"rootless", dockerEndpoint{Name: "rootless:1000", Host: "unix:///run/user/1000/docker.sock"}, "tcp://x:2375", false},
+		{"containerd", dockerEndpoint{Name: "containerd", Host: "/run/containerd/containerd.sock", Runtime: runtimeContainerd}, "", false},
+		{"ssh", dockerEndpoint{Name: "nas", Host: "ssh://nas"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", tt.dockerHost)
+			if got := tt.ep.remote(); got != tt.want {
+				t.Errorf("remote() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEachDockerEndpoint(t *testing.T) {
+	endpoints := []dockerEndpoint{
+		{Name: "default"},
+		{Name: "ctr", Runtime: runtimeContainerd},
+		{Name: "missing", Host: "tcp://missing:2376"},
+		{Name: "broken", Host: "tcp://broken:2376"},
+		{Name: "nas", Host: "ssh://admin@nas.lan"},
+	}
+	var asked []string
+	images, err := forEachDockerEndpoint(context.Background(), endpoints, func(ctx context.Context, ep dockerEndpoint) ([]ImageInfo, error) {
+		asked = append(asked, ep.Name)
+		switch ep.Name {
+		case "missing":
+			return nil, exec.ErrNotFound
+		case "broken":
+			return []ImageInfo{{ID: "partial"}}, errors.New("daemon down")
+		}
+		return []ImageInfo{{ID: ep.Name + "-1"}, {ID: ep.Name + "-2"}}, nil
+	})
+
+	// o ctr nao e consultado; docker ausente nao vira erro
+	if want := []string{"default", "missing", "broken", "nas"}; !reflect.DeepEqual(asked, want) {
+		t.Errorf("asked %v, want %v", asked, want)
+	}
+	if err == nil || err.Error() != "broken: daemon down" {
+		t.Errorf("err = %v, want the broken daemon's error", err)
+	}
+	var got []string
+	for _, img := range images {
+		got = append(got, img.ID+"@"+img.Endpoint)
+	}
+	want := []string{"default-1@", "default-2@", "partial@broken", "nas-1@nas", "nas-2@nas"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// O "docker images" so le os metadados do daemon, sem tocar nos arquivos.
+const dockerImagesTimeout = 10 * time.Second
+
+// ImageInfo e uma imagem local, uma entrada por repositorio:tag como no
+// "docker images". Imagens sem tag (dangling) vem com Repository e Tag
+// vazios. Digest e o do registry, que identifica a versao publicada; uma
+// imagem construida localmente nao tem.
+type ImageInfo struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository,omitempty"`
+	Tag        string    `json:"tag,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	Created    time.Time `json:"created,omitempty"`
+	Dangling   bool      `json:"dangling,omitempty"`
+
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func (i *ImageInfo) setEndpoint(name string) { i.Endpoint = name }
+
+func collectDockerImages(ctx context.Context, endpoints []dockerEndpoint) ([]ImageInfo, error) {
+	return forEachDockerEndpoint(ctx, endpoints, endpointImages)
+}
+
+func endpointImages(ctx context.Context, ep dockerEndpoint) ([]ImageInfo, error) {
+	if ep.runtime() == runtimePodman {
+		// o template de texto do podman difere do docker; o JSON e estavel
+		out, err := dockerCommand(ctx, ep, "images", "--format", "json").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parsePodmanImages(out)
+	}
+	out, err := dockerCommand(ctx, ep, "images", "--digests", "--no-trunc", "--format", "{{json .}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseDockerImages(string(out)), nil
+}
+
+// parseDockerImages le as linhas do "docker images --format '{{json .}}'",
+// em que tudo vem como texto e o ausente e "<none>".
+func parseDockerImages(out string) []ImageInfo {
+	none := func(s string) string {
+		if s == "<none>" {
+			return ""
+		}
+		return s
+	}
+	var images []ImageInfo
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var raw struct {
+			ID         string `json:"ID"`
+			Repository string `json:"Repository"`
+			Tag        string `json:"Tag"`
+			Digest     string `json:"Digest"`
+			Size       string `json:"Size"`
+			CreatedAt  string `json:"CreatedAt"`
+		}
+		if json.Unmarshal([]byte(line), &raw) != nil || raw.ID == "" {
+			continue
+		}
+		img := ImageInfo{
+			ID:         shortImageID(raw.ID),
+			Repository: none(raw.Repository),
+			Tag:        none(raw.Tag),
+			Digest:     none(raw.Digest),
+			SizeBytes:  parseByteSize(raw.Size),
+			Created:    parseRuntimeTime(raw.CreatedAt),
+		}
+		img.Dangling = img.Repository == ""
+		images = append(images, img)
+	}
+	sortImages(images)
+	return images
+}
+
+// parsePodmanImages le o "podman images --format json": uma entrada por
+// imagem, com as tags e os digests em listas.
+func parsePodmanImages(out []byte) ([]ImageInfo, error) {
+	var list []struct {
+		ID          string   `json:"Id"`
+		RepoTags    []string `json:"RepoTags"`
+		RepoDigests []string `json:"RepoDigests"`
+		Size        int64    `json:"Size"`
+		Created     int64    `json:"Created"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("podman images: %w", err)
+	}
+	var images []ImageInfo
+	for _, raw := range list {
+		base := ImageInfo{ID: shortImageID(raw.ID), SizeBytes: raw.Size}
+		if raw.Created > 0 {
+			base.Created = time.Unix(raw.Created, 0).UTC()
+		}
+		// "docker.io/library/nginx@sha256:..." -> digest por repositorio
+		digests := map[string]string{}
+		for _, d := range raw.RepoDigests {
+			if repo, digest, ok := strings.Cut(d, "@"); ok {
+				digests[repo] = digest
+			}
+		}
+		if len(raw.RepoTags) == 0 {
+			base.Dangling = true
+			images = append(images, base)
+			continue
+		}
+		for _, ref := range raw.RepoTags {
+			img := base
+			img.Repository, img.Tag = splitImageRef(ref)
+			img.Digest = digests[img.Repository]
+			images = append(images, img)
+		}
+	}
+	sortImages(images)
+	return images, nil
+}
+
+// splitImageRef separa "registry:5000/app:1.2" em repositorio e tag; o ":"
+// da porta do registry nao conta.
+func splitImageRef(ref string) (repo, tag string) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || strings.Contains(ref[i:], "/") {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// shortImageID reduz "sha256:<64 hex>" aos 12 caracteres que o CLI mostra.
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// sortImages ordena por repositorio e tag, com as dangling no fim.
+func sortImages(images []ImageInfo) {
+	slices.SortStableFunc(images, func(a, b ImageInfo) int {
+		if a.Dangling != b.Dangling {
+			if a.Dangling {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(cmp.Compare(a.Repository, b.Repository), cmp.Compare(a.Tag, b.Tag), cmp.Compare(a.ID, b.ID))
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDockerImages(t *testing.T) {
+	out := `{"Containers":"N/A","CreatedAt":"2024-05-01 12:00:00 +0000 UTC","Digest":"sha256:aaa","ID":"sha256:0123456789abcdef0123","Repository":"nginx","Size":"187MB","Tag":"latest"}
+{"CreatedAt":"2024-04-01 08:30:00 -0300 -03","Digest":"<none>","ID":"sha256:fedcba9876543210fedc","Repository":"<none>","Size":"1.5GB","Tag":"<none>"}
+{"CreatedAt":"2024-03-01 00:00:00 +0000 UTC","Digest":"<none>","ID":"sha256:1111111111112222","Repository":"registry:5000/app","Size":"12.3kB","Tag":"1.2"}
+not json`
+	want := []ImageInfo{
+		{ID: "0123456789ab", Repository: "nginx", Tag: "latest", Digest: "sha256:aaa", SizeBytes: 187_000_000, Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{ID: "111111111111", Repository: "registry:5000/app", Tag: "1.2", SizeBytes: 12_300, Created: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "fedcba987654", SizeBytes: 1_500_000_000, Created: time.Date(2024, 4, 1, 11, 30, 0, 0, time.UTC), Dangling: true},
+	}
+	if got := parseDockerImages(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParsePodmanImages(t *testing.T) {
+	out := `[
+	{"Id":"0123456789abcdef","RepoTags":["docker.io/library/nginx:latest","docker.io/library/nginx:1.25"],
+	 "RepoDigests":["docker.io/library/nginx@sha256:aaa"],"Size":187000000,"Created":1714564800,"Containers":1},
+	{"Id":"fedcba9876543210","RepoTags":null,"RepoDigests":[],"Size":42,"Created":0}
+]`
+	got, err := parsePodmanImages([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Unix(1714564800, 0).UTC()
+	want := []ImageInfo{
+		{ID: "0123456789ab", Repository: "docker.io/library/nginx", Tag: "1.25", Digest: "sha256:aaa", SizeBytes: 187000000, Created: created},
+		{ID: "0123456789ab", Repository: "docker.io/library/nginx", Tag: "latest", Digest: "sha256:aaa", SizeBytes: 187000000, Created: created},
+		{ID: "fedcba987654", SizeBytes: 42, Dangling: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if _, err := parsePodmanImages([]byte("Error: no such image")); err == nil {
+		t.Error("want an error for non-JSON output")
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	tests := []struct {
+		ref, repo, tag string
+	}{
+		{"nginx:latest", "nginx", "latest"},
+		{"registry:5000/app:1.2", "registry:5000/app", "1.2"},
+		{"registry:5000/app", "registry:5000/app", ""},
+		{"app", "app", ""},
+	}
+	for _, tt := range tests {
+		if repo, tag := splitImageRef(tt.ref); repo != tt.repo || tag != tt.tag {
+			t.Errorf("splitImageRef(%q) = %q, %q; want %q, %q", tt.ref, repo, tag, tt.repo, tt.tag)
+		}
+	}
+}
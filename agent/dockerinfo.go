@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxDaemonWarnings limita os avisos do "docker info"; sao poucos, mas vem
+// um por opcao de kernel ausente.
+const maxDaemonWarnings = 20
+
+// DockerDaemonInfo e a configuracao de um daemon segundo o "docker info"
+// (ou "podman info"), para cruzar problemas dos containers com ela.
+type DockerDaemonInfo struct {
+	ServerVersion     string   `json:"serverVersion,omitempty"`
+	StorageDriver     string   `json:"storageDriver,omitempty"`
+	CgroupDriver      string   `json:"cgroupDriver,omitempty"`
+	CgroupVersion     string   `json:"cgroupVersion,omitempty"`
+	ContainersRunning int      `json:"containersRunning"`
+	ContainersPaused  int      `json:"containersPaused"`
+	ContainersStopped int      `json:"containersStopped"`
+	Warnings          []string `json:"warnings,omitempty"`
+
+	rootless bool
+	userns   bool
+}
+
+// collectDaemonInfo roda o info do daemon; containerd nao tem equivalente.
+func collectDaemonInfo(ep dockerEndpoint) (*DockerDaemonInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerPSTimeout)
+	defer cancel()
+	switch ep.runtime() {
+	case runtimeDocker:
+		out, err := dockerCommand(ctx, ep, "info", "--format", "{{json .}}").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseDockerInfo(out)
+	case runtimePodman:
+		out, err := dockerCommand(ctx, ep, "info", "--format", "json").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parsePodmanInfo(out)
+	}
+	return nil, nil
+}
+
+// parseDockerInfo le o "docker info --format '{{json .}}'". Rootless e
+// userns-remap aparecem nas SecurityOptions, como "name=rootless".
+func parseDockerInfo(out []byte) (*DockerDaemonInfo, error) {
+	var raw struct {
+		ServerVersion     string   `json:"ServerVersion"`
+		Driver            string   `json:"Driver"`
+		CgroupDriver      string   `json:"CgroupDriver"`
+		CgroupVersion     string   `json:"CgroupVersion"`
+		ContainersRunning int      `json:"ContainersRunning"`
+		ContainersPaused  int      `json:"ContainersPaused"`
+		ContainersStopped int      `json:"ContainersStopped"`
+		Warnings          []string `json:"Warnings"`
+		SecurityOptions   []string `json:"SecurityOptions"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("docker info: %w", err)
+	}
+	info := &DockerDaemonInfo{
+		ServerVersion:     raw.ServerVersion,
+		StorageDriver:     raw.Driver,
+		CgroupDriver:      raw.CgroupDriver,
+		CgroupVersion:     raw.CgroupVersion,
+		ContainersRunning: raw.ContainersRunning,
+		ContainersPaused:  raw.ContainersPaused,
+		ContainersStopped: raw.ContainersStopped,
+		Warnings:          daemonWarnings(raw.Warnings),
+	}
+	opts := strings.Join(raw.SecurityOptions, ",")
+	info.rootless = strings.Contains(opts, "name=rootless")
+	info.userns = strings.Contains(opts, "name=userns")
+	return info, nil
+}
+
+// parsePodmanInfo le o "podman info --format json", que agrupa o mesmo em
+// host, store e version. Podman nao tem avisos nem userns-remap de daemon.
+func parsePodmanInfo(out []byte) (*DockerDaemonInfo, error) {
+	var raw struct {
+		Host struct {
+			CgroupManager string `json:"cgroupManager"`
+			CgroupVersion string `json:"cgroupVersion"`
+			Security      struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+		Store struct {
+			GraphDriverName string `json:"graphDriverName"`
+			ContainerStore  struct {
+				Paused  int `json:"paused"`
+				Running int `json:"running"`
+				Stopped int `json:"stopped"`
+			} `json:"containerStore"`
+		} `json:"store"`
+		Version struct {
+			Version string `json:"Version"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("podman info: %w", err)
+	}
+	return &DockerDaemonInfo{
+		ServerVersion: raw.Version.Version,
+		StorageDriver: raw.Store.GraphDriverName,
+		CgroupDriver:  raw.Host.CgroupManager,
+		// "v2" no podman, "2" no docker
+		CgroupVersion:     strings.TrimPrefix(raw.Host.CgroupVersion, "v"),
+		ContainersRunning: raw.Store.ContainerStore.Running,
+		ContainersPaused:  raw.Store.ContainerStore.Paused,
+		ContainersStopped: raw.Store.ContainerStore.Stopped,
+		rootless:          raw.Host.Security.Rootless,
+	}, nil
+}
+
+// daemonWarnings tira o prefixo "WARNING: " repetido em cada aviso e corta
+// a lista em maxDaemonWarnings.
+func daemonWarnings(warnings []string) []string {
+	var out []string
+	for _, w := range warnings {
+		if w = strings.TrimSpace(strings.TrimPrefix(w, "WARNING:")); w != "" {
+			out = append(out, w)
+		}
+	}
+	if len(out) > maxDaemonWarnings {
+		out = out[:maxDaemonWarnings]
+	}
+	return slices.Clip(out)
+}
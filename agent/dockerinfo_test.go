@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDockerInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		security string
+		rootless bool
+		userns   bool
+	}{
+		{"plain", `["name=seccomp,profile=builtin","name=cgroupns"]`, false, false},
+		{"rootless", `["name=seccomp,profile=builtin","name=rootless","name=cgroupns"]`, true, false},
+		{"userns", `["name=apparmor","name=userns"]`, false, true},
+		{"no options", `null`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := fmt.Sprintf(`{"ID":"x","Containers":6,"ContainersRunning":3,"ContainersPaused":1,"ContainersStopped":2,
+				"Driver":"overlay2","CgroupDriver":"systemd","CgroupVersion":"2","ServerVersion":"26.1.3",
+				"Warnings":["WARNING: No swap limit support","  ","WARNING: bridge-nf-call-iptables is disabled"],
+				"SecurityOptions":%s,"ClientInfo":{"Version":"26.1.3"}}`, tt.security)
+			got, err := parseDockerInfo([]byte(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &DockerDaemonInfo{
+				ServerVersion: "26.1.3", StorageDriver: "overlay2", CgroupDriver: "systemd", CgroupVersion: "2",
+				ContainersRunning: 3, ContainersPaused: 1, ContainersStopped: 2,
+				Warnings: []string{"No swap limit support", "bridge-nf-call-iptables is disabled"},
+				rootless: tt.rootless, userns: tt.userns,
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+	if _, err := parseDockerInfo([]byte("Cannot connect to the Docker daemon")); err == nil {
+		t.Error("want an error for non-JSON output")
+	}
+}
+
+func TestParsePodmanInfo(t *testing.T) {
+	out := `{"host":{"cgroupManager":"systemd","cgroupVersion":"v2","security":{"rootless":true}},
+		"store":{"graphDriverName":"overlay","containerStore":{"number":4,"paused":0,"running":1,"stopped":3}},
+		"version":{"APIVersion":"4.9.3","Version":"4.9.3"}}`
+	got, err := parsePodmanInfo([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DockerDaemonInfo{
+		ServerVersion: "4.9.3", StorageDriver: "overlay", CgroupDriver: "systemd", CgroupVersion: "2",
+		ContainersRunning: 1, ContainersStopped: 3, rootless: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDaemonWarningsCap(t *testing.T) {
+	warnings := make([]string, maxDaemonWarnings+5)
+	for i := range warnings {
+		warnings[i] = fmt.Sprintf("WARNING: warning %d", i)
+	}
+	got := daemonWarnings(warnings)
+	if len(got) != maxDaemonWarnings || strings.HasPrefix(got[0], "WARNING") {
+		t.Errorf("got %d warnings, first %q", len(got), got[0])
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+)
+
+const dockerNetworksTimeout = 10 * time.Second
+
+// ContainerPort e uma porta do container publicada no host. Exposed marca
+// as publicadas em todas as interfaces (0.0.0.0, ::), que ficam acessiveis
+// de fora do host salvo firewall; as ligadas a 127.0.0.1 nao.
+type ContainerPort struct {
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"hostIp,omitempty"`
+	HostPort      int    `json:"hostPort"`
+	Exposed       bool   `json:"exposed"`
+}
+
+// ContainerNetwork e uma rede do container com os enderecos dele nela.
+type ContainerNetwork struct {
+	Name        string `json:"name"`
+	IPAddress   string `json:"ipAddress,omitempty"`
+	IPv6Address string `json:"ipv6Address,omitempty"`
+}
+
+// parseContainerPorts le o {{json .NetworkSettings.Ports}}, igual no docker
+// e no podman: {"80/tcp": [{"HostIp": "0.0.0.0", "HostPort": "8080"}]}. Portas
+// so expostas (EXPOSE), sem publicacao, vem com null e ficam de fora.
+func parseContainerPorts(s string) []ContainerPort {
+	var raw map[string][]struct {
+		HostIP   string `json:"HostIp"`
+		HostPort string `json:"HostPort"`
+	}
+	if json.Unmarshal([]byte(s), &raw) != nil {
+		return nil
+	}
+	var ports []ContainerPort
+	for spec, bindings := range raw {
+		port, proto, _ := strings.Cut(spec, "/")
+		containerPort, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		for _, b := range bindings {
+			hostPort, err := strconv.Atoi(b.HostPort)
+			if err != nil {
+				continue
+			}
+			ports = append(ports, ContainerPort{
+				ContainerPort: containerPort,
+				Protocol:      cmp.Or(proto, "tcp"),
+				HostIP:        b.HostIP,
+				HostPort:      hostPort,
+				Exposed:       b.HostIP == "" || b.HostIP == "0.0.0.0" || b.HostIP == "::",
+			})
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.HostPort != b.HostPort {
+			return a.HostPort < b.HostPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.HostIP < b.HostIP
+	})
+	return ports
+}
+
+// containerNetworksFormat lista as redes como "nome,ipv4,ipv6;". Nomes de
+// rede nao tem virgula nem ponto e virgula; o JSON inteiro traria as
+// DriverOpts, texto livre que poderia ter o "|" do inspect.
+const containerNetworksFormat = "{{range $name, $n := .NetworkSettings.Networks}}{{$name}},{{$n.IPAddress}},{{$n.GlobalIPv6Address}};{{end}}"
+
+func parseContainerNetworks(s string) []ContainerNetwork {
+	var networks []ContainerNetwork
+	for _, entry := range strings.Split(s, ";") {
+		parts := strings.Split(strings.TrimSpace(entry), ",")
+		if len(parts) != 3 || parts[0] == "" {
+			continue
+		}
+		networks = append(networks, ContainerNetwork{Name: parts[0], IPAddress: parts[1], IPv6Address: parts[2]})
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks
+}
+
+// NetworkInfo e uma rede de um daemon docker ou podman.
+type NetworkInfo struct {
+	Name     string   `json:"name"`
+	ID       string   `json:"id"`
+	Driver   string   `json:"driver"`
+	Scope    string   `json:"scope,omitempty"`
+	Internal bool     `json:"internal,omitempty"`
+	Subnets  []string `json:"subnets,omitempty"`
+	Gateways []string `json:"gateways,omitempty"`
+
+	// Containers sao os nomes dos containers ligados; so o docker informa,
+	// e so os que estao rodando.
+	Containers []string `json:"containers,omitempty"`
+
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func (n *NetworkInfo) setEndpoint(name string) { n.Endpoint = name }
+
+func collectDockerNetworks(ctx context.Context, endpoints []dockerEndpoint) ([]NetworkInfo, error) {
+	return forEachDockerEndpoint(ctx, endpoints, endpointNetworks)
+}
+
+func endpointNetworks(ctx context.Context, ep dockerEndpoint) ([]NetworkInfo, error) {
+	out, err := dockerCommand(ctx, ep, "network", "ls", "--format", "{{.Name}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	names := strings.Fields(string(out))
+	if len(names) == 0 {
+		return nil, nil
+	}
+	out, err = dockerCommand(ctx, ep, append([]string{"network", "inspect"}, names...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseNetworkInspect(out)
+}
+
+// parseNetworkInspect le o network inspect do docker (IPAM.Config,
+// Containers) e o do podman 4+ (subnets, em minusculas), em ordem de nome.
+func parseNetworkInspect(out []byte) ([]NetworkInfo, error) {
+	var raw []struct {
+		Name     string `json:"Name"`
+		ID       string `json:"Id"`
+		Driver   string `json:"Driver"`
+		Scope    string `json:"Scope"`
+		Internal bool   `json:"Internal"`
+		IPAM     struct {
+			Config []struct {
+				Subnet  string `json:"Subnet"`
+				Gateway string `json:"Gateway"`
+			} `json:"Config"`
+		} `json:"IPAM"`
+		Subnets []struct {
+			Subnet  string `json:"subnet"`
+			Gateway string `json:"gateway"`
+		} `json:"subnets"`
+		Containers map[string]struct {
+			Name string `json:"Name"`
+		} `json:"Containers"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("network inspect: %w", err)
+	}
+	networks := make([]NetworkInfo, 0, len(raw))
+	for _, r := range raw {
+		n := NetworkInfo{Name: r.Name, ID: collectors.ShortContainerID(r.ID), Driver: r.Driver, Scope: r.Scope, Internal: r.Internal}
+		for _, c := range r.IPAM.Config {
+			n.Subnets = appendNonEmpty(n.Subnets, c.Subnet)
+			n.Gateways = appendNonEmpty(n.Gateways, c.Gateway)
+		}
+		for _, s := range r.Subnets {
+			n.Subnets = appendNonEmpty(n.Subnets, s.Subnet)
+			n.Gateways = appendNonEmpty(n.Gateways, s.Gateway)
+		}
+		for _, c := range r.Containers {
+			n.Containers = appendNonEmpty(n.Containers, c.Name)
+		}
+		sort.Strings(n.Containers)
+		networks = append(networks, n)
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, nil
+}
+
+func appendNonEmpty(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContainerPorts(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []ContainerPort
+	}{
+		{
+			name: "published",
+			in: `{"5432/tcp":[{"HostIp":"0.0.0.0","HostPort":"5432"},{"HostIp":"::","HostPort":"5432"}],
+				"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"8080"}],"53/udp":[{"HostIp":"","HostPort":"53"}],"9000/tcp":null}`,
+			want: []ContainerPort{
+				{ContainerPort: 53, Protocol: "udp", HostPort: 53, Exposed: true},
+				{ContainerPort: 5432, Protocol: "tcp", HostIP: "0.0.0.0", HostPort: 5432, Exposed: true},
+				{ContainerPort: 5432, Protocol: "tcp", HostIP: "::", HostPort: 5432, Exposed: true},
+				{ContainerPort: 80, Protocol: "tcp", HostIP: "127.0.0.1", HostPort: 8080},
+			},
+		},
+		{"none", `{}`, nil},
+		{"host network", `null`, nil},
+		{"garbage", `<no value>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseContainerPorts(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseContainerNetworks(t *testing.T) {
+	got := parseContainerNetworks("shop_default,172.20.0.3,fd00::3;bridge,172.17.0.2,;host,,;")
+	want := []ContainerNetwork{
+		{Name: "bridge", IPAddress: "172.17.0.2"},
+		{Name: "host"},
+		{Name: "shop_default", IPAddress: "172.20.0.3", IPv6Address: "fd00::3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := parseContainerNetworks(""); got != nil {
+		t.Errorf("no networks: got %+v", got)
+	}
+}
+
+func TestParseNetworkInspect(t *testing.T) {
+	docker := `[
+	{"Name":"shop_default","Id":"0123456789abcdef0123","Driver":"bridge","Scope":"local","Internal":false,
+	 "IPAM":{"Driver":"default","Config":[{"Subnet":"172.20.0.0/16","Gateway":"172.20.0.1"}]},
+	 "Containers":{"aaa":{"Name":"shop-web-1","IPv4Address":"172.20.0.3/16"},"bbb":{"Name":"shop-db-1"}}},
+	{"Name":"host","Id":"fedcba9876543210fedc","Driver":"host","Scope":"local","IPAM":{"Config":[]},"Containers":{}}
+]`
+	got, err := parseNetworkInspect([]byte(docker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NetworkInfo{
+		{Name: "host", ID: "fedcba987654", Driver: "host", Scope: "local"},
+		{Name: "shop_default", ID: "0123456789ab", Driver: "bridge", Scope: "local",
+			Subnets: []string{"172.20.0.0/16"}, Gateways: []string{"172.20.0.1"}, Containers: []string{"shop-db-1", "shop-web-1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("docker: got %+v\nwant %+v", got, want)
+	}
+
+	podman := `[{"name":"podman","id":"2f259bab93aaaaaaaa","driver":"bridge","network_interface":"podman0","internal":true,
+		"subnets":[{"subnet":"10.88.0.0/16","gateway":"10.88.0.1"}],"ipv6_enabled":false,"dns_enabled":false}]`
+	got, err = parseNetworkInspect([]byte(podman))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []NetworkInfo{{Name: "podman", ID: "2f259bab93aa", Driver: "bridge", Internal: true, Subnets: []string{"10.88.0.0/16"}, Gateways: []string{"10.88.0.1"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("podman: got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := parseNetworkInspect([]byte("Error: no such network")); err == nil {
+		t.Error("want an error for non-JSON output")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Medir um volume e percorrer os arquivos dele, como o docker system df
+// faz; o prazo e o mesmo do docker_disk.
+const dockerVolumesTimeout = 30 * time.Second
+
+// VolumeInfo e um volume nomeado e os containers que o montam.
+type VolumeInfo struct {
+	Name       string `json:"name"`
+	Driver     string `json:"driver"`
+	Mountpoint string `json:"mountpoint,omitempty"`
+
+	// SizeBytes so vem para volumes locais medidos dentro do prazo; de
+	// outros drivers o agente nao ve os arquivos.
+	SizeBytes *int64 `json:"sizeBytes,omitempty"`
+
+	// Containers sao os nomes dos containers, parados inclusive, que montam
+	// o volume; vazio e um volume orfao.
+	Containers []string `json:"containers,omitempty"`
+
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func (v *VolumeInfo) setEndpoint(name string) { v.Endpoint = name }
+
+func collectDockerVolumes(ctx context.Context, endpoints []dockerEndpoint) ([]VolumeInfo, error) {
+	return forEachDockerEndpoint(ctx, endpoints, endpointVolumes)
+}
+
+func endpointVolumes(ctx context.Context, ep dockerEndpoint) ([]VolumeInfo, error) {
+	out, err := dockerCommand(ctx, ep, "volume", "ls", "--format", "{{.Name}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	names := strings.Fields(string(out))
+	if len(names) == 0 {
+		return nil, nil
+	}
+	out, err = dockerCommand(ctx, ep, append([]string{"volume", "inspect"}, names...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := parseVolumeInspect(out)
+	if err != nil {
+		return nil, err
+	}
+
+	// sem a lista de containers os volumes ainda valem
+	users, _ := volumeUsers(ctx, ep)
+	// o ultimo quarto do prazo fica para devolver a lista sem os tamanhos
+	// que faltarem
+	sizeCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		sizeCtx, cancel = context.WithDeadline(ctx, deadline.Add(-time.Until(deadline)/4))
+		defer cancel()
+	}
+	for i := range volumes {
+		v := &volumes[i]
+		v.Containers = users[v.Name]
+		// o mountpoint de um daemon remoto e um caminho da outra maquina
+		if v.Driver == "local" && v.Mountpoint != "" && !ep.remote() {
+			if size, err := dirSize(sizeCtx, v.Mountpoint); err == nil {
+				v.SizeBytes = &size
+			}
+		}
+	}
+	return volumes, nil
+}
+
+// parseVolumeInspect le o JSON do volume inspect, igual no docker e no
+// podman, em ordem de nome.
+func parseVolumeInspect(out []byte) ([]VolumeInfo, error) {
+	var raw []struct {
+		Name       string `json:"Name"`
+		Driver     string `json:"Driver"`
+		Mountpoint string `json:"Mountpoint"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, err
+	}
+	volumes := make([]VolumeInfo, 0, len(raw))
+	for _, r := range raw {
+		volumes = append(volumes, VolumeInfo{Name: r.Name, Driver: r.Driver, Mountpoint: r.Mountpoint})
+	}
+	slices.SortFunc(volumes, func(a, b VolumeInfo) int { return strings.Compare(a.Name, b.Name) })
+	return volumes, nil
+}
+
+// volumeMountsFormat lista os volumes nomeados de cada container; binds e
+// tmpfs ficam de fora.
+const volumeMountsFormat = `{{.Name}}|{{range .Mounts}}{{if eq .Type "volume"}}{{.Name}},{{end}}{{end}}`
+
+// volumeUsers devolve, por volume, os containers que o montam.
+func volumeUsers(ctx context.Context, ep dockerEndpoint) (map[string][]string, error) {
+	out, err := dockerCommand(ctx, ep, "ps", "-aq", "--no-trunc").Output()
+	if err != nil {
+		return nil, err
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	// um container removido entre o ps e o inspect faz o comando falhar, mas
+	// a saida dos demais continua valendo
+	out, _ = dockerCommand(ctx, ep, append([]string{"inspect", "--format", volumeMountsFormat}, ids...)...).Output()
+	return parseVolumeMounts(string(out)), nil
+}
+
+func parseVolumeMounts(out string) map[string][]string {
+	users := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		name, mounts, ok := strings.Cut(strings.TrimSpace(line), "|")
+		if !ok {
+			continue
+		}
+		name = strings.TrimPrefix(name, "/")
+		for _, volume := range strings.Split(mounts, ",") {
+			if volume != "" && !slices.Contains(users[volume], name) {
+				users[volume] = append(users[volume], name)
+			}
+		}
+	}
+	for _, names := range users {
+		slices.Sort(names)
+	}
+	return users
+}
+
+// dirSize soma o tamanho aparente dos arquivos sob root, como o docker
+// system df. Estourado o prazo, a medida e abandonada.
+func dirSize(ctx context.Context, root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		// um arquivo que sumiu no meio da medida so fica de fora
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseVolumeInspect(t *testing.T) {
+	out := `[
+	{"CreatedAt":"2024-05-01T12:00:00Z","Driver":"local","Labels":null,"Mountpoint":"/var/lib/docker/volumes/pgdata/_data","Name":"pgdata","Options":null,"Scope":"local"},
+	{"Driver":"rexray","Mountpoint":"","Name":"backups","Scope":"global"}
+]`
+	got, err := parseVolumeInspect([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []VolumeInfo{
+		{Name: "backups", Driver: "rexray"},
+		{Name: "pgdata", Driver: "local", Mountpoint: "/var/lib/docker/volumes/pgdata/_data"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, err := parseVolumeInspect([]byte("Error: no such volume")); err == nil {
+		t.Error("want an error for non-JSON output")
+	}
+}
+
+func TestParseVolumeMounts(t *testing.T) {
+	out := "/web|pgdata,uploads,\n/db|pgdata,\n/cron|\n/worker|uploads,uploads,\n"
+	want := map[string][]string{
+		"pgdata":  {"db", "web"},
+		"uploads": {"web", "worker"},
+	}
+	if got := parseVolumeMounts(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, size := range map[string]int{"one": 100, "a/two": 20, "a/b/three": 3} {
+		if err := os.WriteFile(filepath.Join(root, path), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := dirSize(context.Background(), root); err != nil || got != 123 {
+		t.Errorf("dirSize = %d, %v; want 123", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dirSize(ctx, root); err == nil {
+		t.Error("want an error after the deadline")
+	}
+	if _, err := dirSize(context.Background(), filepath.Join(root, "missing")); err == nil {
+		t.Error("want an error for a missing volume")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxElasticsearchTimeoutSeconds = 60
+)
+
+// esSampleInterval separa as duas leituras dos contadores de indexacao e
+// busca, como a CPU; os testes o encurtam.
+var esSampleInterval = time.Second
+
+func validateElasticsearchInstances(instances []config.ElasticsearchInstance) error {
+	seen := make(map[string]bool, len(instances))
+	for i, e := range instances {
+		if !config.SinkNamePattern.MatchString(e.Name) {
+			return fmt.Errorf("elasticsearch[%d]: invalid name %q (lowercase letters, digits, - and _)", i, e.Name)
+		}
+		if seen[e.Name] {
+			return fmt.Errorf("elasticsearch: duplicate name %q", e.Name)
+		}
+		seen[e.Name] = true
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("elasticsearch: %s: url must be an http(s) URL", e.Name)
+		}
+		if e.APIKey != "" && e.Username != "" {
+			return fmt.Errorf("elasticsearch: %s: use either api_key or username, not both", e.Name)
+		}
+		if e.TimeoutSeconds < 0 || e.TimeoutSeconds > maxElasticsearchTimeoutSeconds {
+			return fmt.Errorf("elasticsearch: %s: timeout_seconds must be between 0 and %d", e.Name, maxElasticsearchTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// ElasticsearchStatus junta a saude do cluster com o heap e as taxas dos
+// nos. As taxas sao do cluster inteiro, somando os nos, e vem de duas
+// leituras com esSampleInterval de intervalo. Error vem preenchido quando o
+// cluster nao respondeu, e o resto fica vazio.
+type ElasticsearchStatus struct {
+	ClusterName        string  `json:"cluster_name,omitempty"`
+	Status             string  `json:"status,omitempty"`
+	Nodes              int64   `json:"nodes,omitempty"`
+	DataNodes          int64   `json:"data_nodes,omitempty"`
+	ActiveShards       int64   `json:"active_shards,omitempty"`
+	RelocatingShards   int64   `json:"relocating_shards,omitempty"`
+	InitializingShards int64   `json:"initializing_shards,omitempty"`
+	UnassignedShards   int64   `json:"unassigned_shards,omitempty"`
+	HeapUsedBytes      int64   `json:"heap_used_bytes,omitempty"`
+	HeapMaxBytes       int64   `json:"heap_max_bytes,omitempty"`
+	HeapUsedPercent    float64 `json:"heap_used_percent,omitempty"`
+	IndexingPerSec     float64 `json:"indexing_per_sec"`
+	SearchPerSec       float64 `json:"search_per_sec"`
+
+	// NodeHeap e o heap de cada no: um no perto do limite trava o cluster
+	// mesmo com a media baixa.
+	NodeHeap []ElasticsearchNodeHeap `json:"node_heap,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+type ElasticsearchNodeHeap struct {
+	Name        string  `json:"name"`
+	UsedBytes   int64   `json:"used_bytes"`
+	MaxBytes    int64   `json:"max_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// esNodeStatsPath pede so os campos usados; sem o filter_path a resposta
+// traz centenas de estatisticas por no.
+const esNodeStatsPath = "/_nodes/stats/jvm,indices?filter_path=" +
+	"nodes.*.name,nodes.*.jvm.mem.heap_used_in_bytes,nodes.*.jvm.mem.heap_max_in_bytes," +
+	"nodes.*.indices.indexing.index_total,nodes.*.indices.search.query_total"
+
+type esNodeStats struct {
+	Nodes map[string]struct {
+		Name string `json:"name"`
+		JVM  struct {
+			Mem struct {
+				HeapUsed int64 `json:"heap_used_in_bytes"`
+				HeapMax  int64 `json:"heap_max_in_bytes"`
+			} `json:"mem"`
+		} `json:"jvm"`
+		Indices struct {
+			Indexing struct {
+				Total int64 `json:"index_total"`
+			} `json:"indexing"`
+			Search struct {
+				Total int64 `json:"query_total"`
+			} `json:"search"`
+		} `json:"indices"`
+	} `json:"nodes"`
+}
+
+func collectElasticsearch(instances []config.ElasticsearchInstance) map[string]ElasticsearchStatus {
+	return collectors.ByName(instances, func(e config.ElasticsearchInstance) string { return e.Name }, func(e config.ElasticsearchInstance) ElasticsearchStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), e.Timeout()+esSampleInterval)
+		defer cancel()
+		s, err := elasticsearchStatus(ctx, e)
+		if err != nil {
+			return ElasticsearchStatus{Error: err.Error()}
+		}
+		return s
+	})
+}
+
+func elasticsearchStatus(ctx context.Context, e config.ElasticsearchInstance) (ElasticsearchStatus, error) {
+	base := strings.TrimRight(e.URL, "/")
+	auth := func(req *http.Request) {
+		switch {
+		case e.APIKey != "":
+			req.Header.Set("Authorization", "ApiKey "+e.APIKey)
+		case e.Username != "":
+			req.SetBasicAuth(e.Username, e.Password)
+		}
+	}
+
+	var health struct {
+		ClusterName        string `json:"cluster_name"`
+		Status             string `json:"status"`
+		Nodes              int64  `json:"number_of_nodes"`
+		DataNodes          int64  `json:"number_of_data_nodes"`
+		ActiveShards       int64  `json:"active_shards"`
+		RelocatingShards   int64  `json:"relocating_shards"`
+		InitializingShards int64  `json:"initializing_shards"`
+		UnassignedShards   int64  `json:"unassigned_shards"`
+	}
+	if err := localJSON(ctx, "GET", base+"/_cluster/health", nil, auth, &health); err != nil {
+		return ElasticsearchStatus{}, err
+	}
+	s := ElasticsearchStatus{
+		ClusterName: health.ClusterName, Status: health.Status,
+		Nodes: health.Nodes, DataNodes: health.DataNodes,
+		ActiveShards: health.ActiveShards, RelocatingShards: health.RelocatingShards,
+		InitializingShards: health.InitializingShards, UnassignedShards: health.UnassignedShards,
+	}
+
+	var first, second esNodeStats
+	if err := localJSON(ctx, "GET", base+esNodeStatsPath, nil, auth, &first); err != nil {
+		return s, err
+	}
+	started := time.Now()
+	select {
+	case <-time.After(esSampleInterval):
+	case <-ctx.Done():
+		return s, ctx.Err()
+	}
+	if err := localJSON(ctx, "GET", base+esNodeStatsPath, nil, auth, &second); err != nil {
+		return s, err
+	}
+	elapsed := time.Since(started).Seconds()
+
+	var indexed, searched int64
+	for id, n := range second.Nodes {
+		s.HeapUsedBytes += n.JVM.Mem.HeapUsed
+		s.HeapMaxBytes += n.JVM.Mem.HeapMax
+		s.NodeHeap = append(s.NodeHeap, ElasticsearchNodeHeap{
+			Name: n.Name, UsedBytes: n.JVM.Mem.HeapUsed, MaxBytes: n.JVM.Mem.HeapMax,
+			UsedPercent: collectors.PercentOf(n.JVM.Mem.HeapUsed, n.JVM.Mem.HeapMax),
+		})
+		// um no que entrou ou reiniciou entre as leituras nao tem base
+		if prev, ok := first.Nodes[id]; ok {
+			indexed += max(n.Indices.Indexing.Total-prev.Indices.Indexing.Total, 0)
+			searched += max(n.Indices.Search.Total-prev.Indices.Search.Total, 0)
+		}
+	}
+	slices.SortFunc(s.NodeHeap, func(a, b ElasticsearchNodeHeap) int { return cmp.Compare(a.Name, b.Name) })
+	s.HeapUsedPercent = collectors.PercentOf(s.HeapUsedBytes, s.HeapMaxBytes)
+	s.IndexingPerSec = math.Round(float64(indexed)/elapsed*100) / 100
+	s.SearchPerSec = math.Round(float64(searched)/elapsed*100) / 100
+	return s, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateElasticsearchInstances(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance config.ElasticsearchInstance
+		wantErr  string
+	}{
+		{"anonymous", config.ElasticsearchInstance{Name: "logs", URL: "http://127.0.0.1:9200"}, ""},
+		{"basic", config.ElasticsearchInstance{Name: "logs", URL: "https://127.0.0.1:9200", Username: "monitor", Password: "x"}, ""},
+		{"api key", config.ElasticsearchInstance{Name: "logs", URL: "https://127.0.0.1:9200", APIKey: "abc"}, ""},
+		{"both", config.ElasticsearchInstance{Name: "logs", URL: "http://h:9200", APIKey: "abc", Username: "u"}, "either api_key or username"},
+		{"bad url", config.ElasticsearchInstance{Name: "logs", URL: "127.0.0.1:9200"}, "http(s) URL"},
+		{"bad name", config.ElasticsearchInstance{Name: "Logs", URL: "http://h:9200"}, "invalid name"},
+		{"timeout", config.ElasticsearchInstance{Name: "logs", URL: "http://h:9200", TimeoutSeconds: 61}, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateElasticsearchInstances([]config.ElasticsearchInstance{tt.instance})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCollectElasticsearch(t *testing.T) {
+	defer func(d time.Duration) { esSampleInterval = d }(esSampleInterval)
+	esSampleInterval = 50 * time.Millisecond
+
+	// cada leitura de _nodes/stats soma 10 documentos indexados e 5 buscas
+	// em cada no; o no "c" so aparece na segunda
+	var reads atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/_cluster/health":
+			fmt.Fprint(w, `{"cluster_name":"logs","status":"yellow","number_of_nodes":3,"number_of_data_nodes":2,"active_shards":10,"relocating_shards":0,"initializing_shards":1,"unassigned_shards":4}`)
+		case "/_nodes/stats/jvm,indices":
+			if !strings.Contains(r.URL.RawQuery, "filter_path=") {
+				t.Errorf("node stats without filter_path: %s", r.URL)
+			}
+			n := reads.Add(1)
+			node := func(name string, used int64) string {
+				return fmt.Sprintf(`{"name":%q,"jvm":{"mem":{"heap_used_in_bytes":%d,"heap_max_in_bytes":1000}},"indices":{"indexing":{"index_total":%d},"search":{"query_total":%d}}}`, name, used, n*10, n*5)
+			}
+			nodes := `"a":` + node("es-a", 250) + `,"b":` + node("es-b", 750)
+			if n > 1 {
+				nodes += `,"c":` + node("es-c", 500)
+			}
+			fmt.Fprint(w, `{"nodes":{`+nodes+`}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	got := collectElasticsearch([]config.ElasticsearchInstance{
+		{Name: "logs", URL: srv.URL + "/", APIKey: "secret"},
+		{Name: "denied", URL: srv.URL, APIKey: "wrong"},
+	})
+	s := got["logs"]
+	if s.Error != "" || s.ClusterName != "logs" || s.Status != "yellow" || s.Nodes != 3 || s.UnassignedShards != 4 || s.InitializingShards != 1 {
+		t.Fatalf("logs: got %+v", s)
+	}
+	if s.HeapUsedBytes != 1500 || s.HeapMaxBytes != 3000 || s.HeapUsedPercent != 50 {
+		t.Errorf("heap: got %d/%d (%v%%)", s.HeapUsedBytes, s.HeapMaxBytes, s.HeapUsedPercent)
+	}
+	if len(s.NodeHeap) != 3 || s.NodeHeap[0].Name != "es-a" || s.NodeHeap[0].UsedPercent != 25 || s.NodeHeap[1].UsedPercent != 75 {
+		t.Errorf("node heap: got %+v", s.NodeHeap)
+	}
+	// dois nos com base: 20 documentos e 10 buscas em pouco mais de 50ms
+	if s.IndexingPerSec <= 0 || s.IndexingPerSec > 400 || s.SearchPerSec <= 0 || s.SearchPerSec > 200 {
+		t.Errorf("rates: indexing %v/s, search %v/s", s.IndexingPerSec, s.SearchPerSec)
+	}
+	if math.Abs(s.IndexingPerSec-2*s.SearchPerSec) > 0.1 {
+		t.Errorf("indexing %v/s should be twice search %v/s", s.IndexingPerSec, s.SearchPerSec)
+	}
+	if got["denied"].Error != "/_cluster/health: status 401" {
+		t.Errorf("denied: got %+v", got["denied"])
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+// validateAPIURLs confere todos os enderecos da lista ja ao carregar o
+// config, e nao so quando o failover chega neles.
+func validateAPIURLs(list []string) error {
+	for _, raw := range list {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("api_url: invalid endpoint %q; use the full http or https URL", raw)
+		}
+	}
+	return nil
+}
+
+// callAPI chama call em cada endpoint do config, na ordem de endpointOrder,
+// e passa ao seguinte nas mesmas falhas que mandam o payload para o spool.
+// rawURL nil usa o proprio endpoint. Um endereco fixo no config
+// (remote_config_url, update_url) e o mesmo para todos e so e tentado uma
+// vez.
+func callAPI[T any](cfg config.Config, rawURL func(endpoint string) (string, error), call func(rawURL string) (T, error)) (T, error) {
+	return transport.CallEndpoints(cfg.Endpoints(), rawURL, call, spoolable)
+}
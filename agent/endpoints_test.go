@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+// resetEndpoints isola o endereco saudavel num diretorio temporario.
+func resetEndpoints(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	transport.SetEndpointStateDir(dir)
+	t.Cleanup(func() { transport.SetEndpointStateDir("") })
+	return dir
+}
+
+func TestPostPayloadFailover(t *testing.T) {
+	var hits []string
+	handler := func(name string, status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits = append(hits, name)
+			w.WriteHeader(status)
+			w.Write([]byte(`{"ok":true}`))
+		}))
+	}
+	down := handler("down", http.StatusServiceUnavailable)
+	defer down.Close()
+	up := handler("up", http.StatusOK)
+	defer up.Close()
+	rejects := handler("rejects", http.StatusBadRequest)
+	defer rejects.Close()
+
+	tests := []struct {
+		name     string
+		urls     []string
+		wantHits []string
+		wantErr  bool
+	}{
+		{"5xx moves to the next endpoint", []string{down.URL, up.URL}, []string{"down", "up"}, false},
+		{"healthy endpoint goes first", []string{down.URL, up.URL}, []string{"up"}, false},
+		{"4xx does not fail over", []string{rejects.URL, down.URL}, []string{"rejects"}, true},
+		{"all down", []string{down.URL, down.URL + "/b"}, []string{"down", "down"}, true},
+	}
+	resetEndpoints(t)
+	for _, tt := range tests {
+		hits = nil
+		cfg := config.Config{ApiURL: tt.urls[0], ApiURLs: tt.urls, Token: "t"}
+		_, err := postPayload(cfg, Payload{})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(hits, tt.wantHits) {
+			t.Errorf("%s: hits = %v, want %v", tt.name, hits, tt.wantHits)
+		}
+	}
+}
+
+func TestValidateAPIURLs(t *testing.T) {
+	if err := validateAPIURLs([]string{"https://a.example.com/api/telemetry", "http://10.0.0.5:8080/api/telemetry"}); err != nil {
+		t.Error(err)
+	}
+	for _, bad := range []string{"a.example.com/api/telemetry", "ftp://a.example.com", "https://"} {
+		err := validateAPIURLs([]string{"https://a.example.com", bad})
+		if err == nil || !strings.Contains(err.Error(), bad) {
+			t.Errorf("%s: err = %v", bad, err)
+		}
+	}
+}
+
+func TestControlCallsFailOver(t *testing.T) {
+	var hits []string
+	handler := func(name string, status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits = append(hits, name+" "+r.URL.Path)
+			w.WriteHeader(status)
+			w.Write([]byte(`{"collect":true}`))
+		}))
+	}
+	down := handler("down", http.StatusServiceUnavailable)
+	defer down.Close()
+	up := handler("up", http.StatusOK)
+	defer up.Close()
+	rejects := handler("rejects", http.StatusUnauthorized)
+	defer rejects.Close()
+	dir := resetEndpoints(t)
+
+	// comandos seguem para o proximo endereco, no mesmo caminho
+	cfg := config.Config{ApiURL: down.URL + "/api/telemetry", ApiURLs: []string{down.URL + "/api/telemetry", up.URL + "/api/telemetry"}, Token: "t"}
+	cmds, err := fetchCommands(cfg)
+	if err != nil || !cmds.Collect {
+		t.Fatalf("fetchCommands() = %+v, %v", cmds, err)
+	}
+	if want := []string{"down /api/agent/commands", "up /api/agent/commands"}; !reflect.DeepEqual(hits, want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+	// so o envio troca o endereco saudavel
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("a control call changed the healthy endpoint: %v", entries)
+	}
+
+	// e comecam pelo endereco saudavel
+	hits = nil
+	transport.MarkEndpointHealthy(cfg.Endpoints(), up.URL+"/api/telemetry")
+	if _, err := fetchRemoteConfig(cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"up /api/agent/config"}; !reflect.DeepEqual(hits, want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+
+	// um 4xx nao passa adiante
+	hits = nil
+	resetEndpoints(t)
+	cfg.ApiURLs = []string{rejects.URL + "/api/telemetry", up.URL + "/api/telemetry"}
+	if _, err := fetchCommands(cfg); err == nil {
+		t.Error("want the 401 from the first endpoint")
+	}
+	if want := []string{"rejects /api/agent/commands"}; !reflect.DeepEqual(hits, want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+
+	// remote_config_url fixo e o mesmo para todos: uma tentativa so
+	hits = nil
+	cfg.ApiURLs = []string{up.URL + "/api/telemetry", rejects.URL + "/api/telemetry"}
+	cfg.RemoteConfigURL = down.URL + "/config"
+	if _, err := fetchRemoteConfig(cfg, nil); err == nil {
+		t.Error("want the 503 from remote_config_url")
+	}
+	if want := []string{"down /config"}; !reflect.DeepEqual(hits, want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+}
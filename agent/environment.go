@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// Quando o proprio agente roda em container, "/" e o overlay do container
+// e o hostname e o id do container: sem aviso, o servidor mostraria esse
+// disco minusculo como o do host. AgentEnvironment deixa isso explicito e,
+// havendo o / do host montado (host_root, ou /host, /rootfs e /hostfs),
+// disco, discos e identidade passam a ser lidos de la.
+const (
+	diskScopeHost      = "host"
+	diskScopeContainer = "container"
+)
+
+// hostRootCandidates sao os pontos de montagem usuais do / do host
+// (-v /:/host:ro); so valem se tiverem um os-release.
+var hostRootCandidates = []string{"/host", "/rootfs", "/hostfs"}
+
+// AgentEnvironment descreve onde o agente roda. DiskScope diz de quem sao
+// os campos disk_* e metrics.disks: do host ou do proprio container.
+type AgentEnvironment struct {
+	Container string `json:"container,omitempty"`
+	VM        string `json:"vm,omitempty"`
+	HostRoot  string `json:"host_root,omitempty"`
+	DiskScope string `json:"disk_scope"`
+}
+
+var agentEnv = struct {
+	sync.Mutex
+	done     bool
+	hostRoot string
+	env      AgentEnvironment
+}{}
+
+// agentEnvironment detecta o ambiente uma vez por processo; so e refeito se
+// host_root mudar.
+func agentEnvironment(c config.Config) AgentEnvironment {
+	agentEnv.Lock()
+	defer agentEnv.Unlock()
+	if agentEnv.done && agentEnv.hostRoot == c.HostRoot {
+		return agentEnv.env
+	}
+
+	container, vm := detectAgentEnvironment()
+	env := AgentEnvironment{Container: container, VM: vm, HostRoot: c.HostRoot, DiskScope: diskScopeHost}
+	if env.HostRoot == "" && container != "" {
+		env.HostRoot = findHostRoot()
+	}
+	if container != "" && env.HostRoot == "" {
+		env.DiskScope = diskScopeContainer
+	}
+	agentEnv.done, agentEnv.hostRoot, agentEnv.env = true, c.HostRoot, env
+	return env
+}
+
+// payloadEnvironment so vai no payload fora de um host comum.
+func (e AgentEnvironment) payloadEnvironment() *AgentEnvironment {
+	if e.Container == "" && e.VM == "" && e.HostRoot == "" {
+		return nil
+	}
+	return &e
+}
+
+func findHostRoot() string {
+	for _, dir := range hostRootCandidates {
+		if fsutil.FileExists(filepath.Join(dir, "etc", "os-release")) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// containerFromCgroup reconhece o runtime pelo /proc/1/cgroup (cgroup v1, ou
+// v2 sem namespace de cgroup).
+func containerFromCgroup(cgroup string) string {
+	for _, marker := range []struct{ substr, name string }{
+		{"kubepods", "kubernetes"},
+		{"libpod", "podman"},
+		{"docker", "docker"},
+		{"/lxc", "lxc"},
+		{"containerd", "containerd"},
+	} {
+		if strings.Contains(cgroup, marker.substr) {
+			return marker.name
+		}
+	}
+	return ""
+}
+
+// hostMountPoint traduz um ponto de montagem visto no container para o
+// caminho no host; fora de root, ok e falso.
+func hostMountPoint(mountPoint, root string) (string, bool) {
+	switch {
+	case root == "" || root == "/":
+		return mountPoint, true
+	case mountPoint == root:
+		return "/", true
+	case strings.HasPrefix(mountPoint, root+"/"):
+		return mountPoint[len(root):], true
+	}
+	return "", false
+}
+
+// hostPath e hostMountPoint quando o caminho esta abaixo de root.
+func hostPath(mountPoint, root string) string {
+	if p, ok := hostMountPoint(mountPoint, root); ok {
+		return p
+	}
+	return mountPoint
+}
+
+// diskRoot e onde medir o disco principal: o / do host quando montado.
+func diskRoot(c config.Config) string {
+	if root := agentEnvironment(c).HostRoot; root != "" {
+		return root
+	}
+	return "/"
+}
+
+func validateHostRoot(root string) error {
+	if root == "" {
+		return nil
+	}
+	if !filepath.IsAbs(root) {
+		return fmt.Errorf("host_root: %q must be an absolute path", root)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("host_root: %q is not a directory", root)
+	}
+	return nil
+}
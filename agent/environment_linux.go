@@ -0,0 +1,32 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+// detectAgentEnvironment devolve o runtime de container em que o agente
+// roda e a virtualizacao da maquina, pelo DMI.
+func detectAgentEnvironment() (container, vm string) {
+	return detectContainer(), dmiVirtualization()
+}
+
+func detectContainer() string {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	// convencao do systemd, seguida por podman, lxc e nspawn
+	if name := os.Getenv("container"); name != "" {
+		return name
+	}
+	if fsutil.FileExists("/.dockerenv") {
+		return "docker"
+	}
+	if fsutil.FileExists("/run/.containerenv") {
+		return "podman"
+	}
+	return containerFromCgroup(fsutil.ReadTrimmed("/proc/1/cgroup"))
+}
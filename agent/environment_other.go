@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// detectAgentEnvironment: containers e DMI so sao detectados em Linux.
+func detectAgentEnvironment() (container, vm string) {
+	return "", ""
+}
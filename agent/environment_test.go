@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContainerFromCgroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{"kubernetes", "11:memory:/kubepods/burstable/pod1234/abcdef", "kubernetes"},
+		{"docker", "12:pids:/docker/0123456789ab", "docker"},
+		{"podman", "0::/machine.slice/libpod-0123.scope", "podman"},
+		{"lxc", "10:cpu:/lxc/web01", "lxc"},
+		{"containerd", "0::/system.slice/containerd.service/abc", "containerd"},
+		{"cgroup v2 host", "0::/init.scope", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerFromCgroup(tt.cgroup); got != tt.want {
+				t.Errorf("containerFromCgroup(%q) = %q, want %q", tt.cgroup, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostMountPoint(t *testing.T) {
+	tests := []struct {
+		mountPoint string
+		root       string
+		want       string
+		ok         bool
+	}{
+		{"/var/lib", "", "/var/lib", true},
+		{"/var/lib", "/", "/var/lib", true},
+		{"/host", "/host", "/", true},
+		{"/host/boot/efi", "/host", "/boot/efi", true},
+		{"/hostfs/data", "/host", "", false},
+		{"/etc/hosts", "/host", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := hostMountPoint(tt.mountPoint, tt.root)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("hostMountPoint(%q, %q) = %q, %v, want %q, %v", tt.mountPoint, tt.root, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateHostRoot(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		root    string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"directory", dir, false},
+		{"relative", "host", true},
+		{"missing", filepath.Join(dir, "missing"), true},
+		{"file", file, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateHostRoot(tt.root); (err != nil) != tt.wantErr {
+				t.Errorf("validateHostRoot(%q) = %v, wantErr %v", tt.root, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPayloadEnvironment(t *testing.T) {
+	if got := (AgentEnvironment{DiskScope: diskScopeHost}).payloadEnvironment(); got != nil {
+		t.Errorf("bare host: got %+v, want nil", got)
+	}
+	env := AgentEnvironment{Container: "docker", DiskScope: diskScopeContainer}
+	if got := env.payloadEnvironment(); got == nil || *got != env {
+		t.Errorf("container: got %+v, want %+v", got, env)
+	}
+}
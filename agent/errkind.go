@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Tipos de erro enviados junto das mensagens, para o servidor agregar as
+// falhas da frota sem interpretar texto livre.
+const (
+	errKindConfig         = "config"
+	errKindPermission     = "permission"
+	errKindRuntimeMissing = "runtime_missing"
+	errKindTimeout        = "timeout"
+	errKindTransport      = "transport"
+	errKindParse          = "parse"
+	errKindOther          = "other"
+)
+
+// configError marca erros de validacao da configuracao.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+
+// httpStatusError e uma resposta HTTP fora de 2xx de um servico que nao e a
+// API do Vaultrix (kubelet, registry de plugins).
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return "status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+func newCollectorError(name string, err error) CollectorError {
+	return CollectorError{Collector: name, Kind: classifyError(err), Error: err.Error()}
+}
+
+// classifyError reduz um erro a um dos tipos acima. A ordem importa: um
+// timeout dentro de um erro de rede e timeout, nao transporte.
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var cfgErr *configError
+	var apiErr *apiError
+	var statusErr *httpStatusError
+	var netErr net.Error
+	var exitErr *exec.ExitError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return errKindTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errKindTimeout
+	case errors.As(err, &cfgErr):
+		return errKindConfig
+	case errors.Is(err, exec.ErrNotFound):
+		return errKindRuntimeMissing
+	case errors.Is(err, fs.ErrPermission):
+		return errKindPermission
+	case errors.As(err, &apiErr):
+		// token recusado e problema de configuracao do agente
+		if apiErr.StatusCode == 401 || apiErr.StatusCode == 403 {
+			return errKindConfig
+		}
+		return errKindTransport
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == 401 || statusErr.StatusCode == 403 {
+			return errKindPermission
+		}
+		return errKindTransport
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &numErr):
+		return errKindParse
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthority), errors.As(err, &netErr):
+		return errKindTransport
+	case errors.Is(err, fs.ErrNotExist):
+		return errKindRuntimeMissing
+	case errors.As(err, &exitErr):
+		return classifyStderr(string(exitErr.Stderr))
+	}
+	return errKindOther
+}
+
+// classifyStderr cobre os CLIs de runtime, cujas falhas so aparecem no texto
+// do stderr.
+func classifyStderr(stderr string) string {
+	s := strings.ToLower(stderr)
+	switch {
+	case strings.Contains(s, "permission denied"), strings.Contains(s, "access is denied"):
+		return errKindPermission
+	case strings.Contains(s, "no such file or directory"), strings.Contains(s, "command not found"):
+		return errKindRuntimeMissing
+	case strings.Contains(s, "cannot connect"), strings.Contains(s, "connection refused"),
+		strings.Contains(s, "error during connect"):
+		return errKindTransport
+	case strings.Contains(s, "timeout"), strings.Contains(s, "deadline exceeded"):
+		return errKindTimeout
+	}
+	return errKindOther
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+// O payload tem duas partes: o retrato do ciclo (metrics, containers,
+// checks, alertas ativos: o estado agora) e "events", o que aconteceu desde
+// o ciclo anterior. Sem os eventos, o servidor teria de adivinhar reinicios e
+// quedas comparando retratos, e perderia o que comeca e termina entre dois
+// ciclos. Os eventos de um ciclo vao so no payload dele; se o envio falhar,
+// seguem com ele no spool.
+const (
+	eventContainerRestart = "container_restart"
+	eventContainerOOMKill = "container_oom_kill"
+	eventCheckDown        = "check_down"
+	eventCheckUp          = "check_up"
+	eventAlertFiring      = "alert_firing"
+	eventAlertResolved    = "alert_resolved"
+)
+
+const containerEventsFile = "containers.json"
+
+// PayloadEvent e um acontecimento pontual. ID e estavel: o mesmo evento tem
+// o mesmo ID em qualquer reenvio (spool, failover entre enderecos), e o
+// servidor pode descartar repetidos.
+type PayloadEvent struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Subject string    `json:"subject"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+func newEvent(kind, subject, detail string, t time.Time) PayloadEvent {
+	t = t.UTC()
+	sum := sha256.Sum256([]byte(kind + "\x00" + subject + "\x00" + strconv.FormatInt(t.UnixNano(), 10)))
+	return PayloadEvent{ID: hex.EncodeToString(sum[:12]), Type: kind, Time: t, Subject: subject, Detail: detail}
+}
+
+// sortEvents ordena os eventos do ciclo no tempo, para a linha do tempo do
+// servidor nao depender da ordem em que as fontes foram avaliadas.
+func sortEvents(events []PayloadEvent) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+}
+
+// containerMark e o que se guarda de cada container entre ciclos para
+// reconhecer reinicios e OOM kills.
+type containerMark struct {
+	ID          string    `json:"id,omitempty"`
+	Restarts    int       `json:"restarts"`
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	OOMKilledAt time.Time `json:"oom_killed_at,omitempty"`
+}
+
+func containerEventsPath(stateDir string) string {
+	return filepath.Join(stateDir, containerEventsFile)
+}
+
+// trackContainers compara o ciclo com o anterior e devolve os reinicios e
+// OOM kills. Um container visto pela primeira vez (ou recriado, com outro
+// ID) so e registrado: o que aconteceu antes dele nao e novidade.
+func trackContainers(stateDir string, containers []ContainerStatus, now time.Time) []PayloadEvent {
+	previous := map[string]containerMark{}
+	if b, err := os.ReadFile(containerEventsPath(stateDir)); err == nil {
+		json.Unmarshal(b, &previous)
+	}
+
+	var events []PayloadEvent
+	marks := make(map[string]containerMark, len(containers))
+	for _, c := range containers {
+		// sem o ciclo de vida nao ha com o que comparar
+		if c.RestartCount == nil {
+			continue
+		}
+		mark := containerMark{ID: c.ID, Restarts: *c.RestartCount, OOMKilledAt: c.oomKilledAt}
+		if c.StartedAt != nil {
+			mark.StartedAt = *c.StartedAt
+		}
+		marks[c.Name] = mark
+		prev, ok := previous[c.Name]
+		if !ok || prev.ID != c.ID {
+			continue
+		}
+		if !mark.OOMKilledAt.IsZero() && mark.OOMKilledAt.After(prev.OOMKilledAt) {
+			events = append(events, newEvent(eventContainerOOMKill, c.Name, c.Image, mark.OOMKilledAt))
+		}
+		if mark.Restarts > prev.Restarts || (!prev.StartedAt.IsZero() && mark.StartedAt.After(prev.StartedAt)) {
+			at := mark.StartedAt
+			if at.IsZero() {
+				at = now
+			}
+			events = append(events, newEvent(eventContainerRestart, c.Name, fmt.Sprintf("restarts: %d", mark.Restarts), at))
+		}
+	}
+
+	if len(marks) == 0 {
+		os.Remove(containerEventsPath(stateDir))
+		return events
+	}
+	b, err := json.MarshalIndent(marks, "", "  ")
+	if err == nil {
+		err = fsutil.EnsureDir(stateDir)
+	}
+	if err == nil {
+		err = fsutil.WriteFileAtomic(containerEventsPath(stateDir), b, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "events: %v\n", err)
+	}
+	return events
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEventStableID(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := newEvent(eventContainerRestart, "web", "restarts: 1", at)
+	b := newEvent(eventContainerRestart, "web", "restarts: 2", at.In(time.FixedZone("BRT", -3*3600)))
+	if a.ID != b.ID || len(a.ID) != 24 {
+		t.Errorf("ids = %q, %q; want the same 24-char id", a.ID, b.ID)
+	}
+	for _, other := range []PayloadEvent{
+		newEvent(eventContainerOOMKill, "web", "", at),
+		newEvent(eventContainerRestart, "db", "", at),
+		newEvent(eventContainerRestart, "web", "", at.Add(time.Second)),
+	} {
+		if other.ID == a.ID {
+			t.Errorf("%s %s at %s shares id %q", other.Type, other.Subject, other.Time, a.ID)
+		}
+	}
+}
+
+func TestTrackContainers(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	web := func(restarts int, started, oom time.Time) ContainerStatus {
+		return ContainerStatus{ID: "abc", Name: "web", Image: "nginx", RestartCount: &restarts, StartedAt: &started, oomKilledAt: oom}
+	}
+	zero, later := 0, t0.Add(time.Minute)
+	tests := []struct {
+		name string
+		next ContainerStatus
+		want []string
+	}{
+		{"unchanged", web(0, t0, time.Time{}), nil},
+		{"restart policy", web(1, t0.Add(time.Minute), time.Time{}), []string{eventContainerRestart}},
+		{"manual restart", web(0, t0.Add(time.Minute), time.Time{}), []string{eventContainerRestart}},
+		{"oom kill then restart", web(1, t0.Add(time.Minute), t0.Add(30*time.Second)), []string{eventContainerOOMKill, eventContainerRestart}},
+		{"stopped by oom", web(0, t0, t0.Add(30*time.Second)), []string{eventContainerOOMKill}},
+		{"recreated", ContainerStatus{ID: "def", Name: "web", RestartCount: &zero, StartedAt: &later}, nil},
+		{"runtime without lifecycle", ContainerStatus{ID: "abc", Name: "web"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if events := trackContainers(dir, []ContainerStatus{web(0, t0, time.Time{})}, t0); len(events) != 0 {
+				t.Fatalf("first sight: events = %+v", events)
+			}
+			var got []string
+			for _, e := range trackContainers(dir, []ContainerStatus{tt.next}, t0.Add(2*time.Minute)) {
+				got = append(got, e.Type)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("events = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRuntimeTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 0, 0, 500000000, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-05-01T12:00:00.5Z", want},
+		{"2024-05-01T09:00:00.5-03:00", want},
+		{"2024-05-01 12:00:00.5 +0000 UTC", want},
+		{"0001-01-01T00:00:00Z", time.Time{}},
+		{"<no value>", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseRuntimeTime(tt.in); !got.Equal(tt.want) {
+			t.Errorf("parseRuntimeTime(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKubeContainerLifecycle(t *testing.T) {
+	raw := func(s string) map[string]json.RawMessage {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	started, oom := kubeContainerLifecycle(
+		raw(`{"running":{"startedAt":"2024-05-01T12:01:00Z"}}`),
+		raw(`{"terminated":{"reason":"OOMKilled","exitCode":137,"finishedAt":"2024-05-01T12:00:50Z"}}`),
+	)
+	if !started.Equal(time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)) || !oom.Equal(time.Date(2024, 5, 1, 12, 0, 50, 0, time.UTC)) {
+		t.Errorf("started = %s, oom = %s", started, oom)
+	}
+	if _, oom := kubeContainerLifecycle(raw(`{"running":{}}`), raw(`{"terminated":{"reason":"Error"}}`)); !oom.IsZero() {
+		t.Errorf("oom = %s for a non-OOM exit", oom)
+	}
+}
+
+func TestApplyContainerInspect(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)
+	finished := time.Date(2024, 5, 1, 12, 0, 50, 0, time.UTC)
+	out := "/web|3|2024-05-01T12:01:00Z|false|2024-05-01T12:00:50Z|0|true|" + `{"80/tcp":[{"HostIp":"0.0.0.0","HostPort":"8080"}]}` + "|app,172.18.0.2,;|" + `{"Status":"healthy","FailingStreak":0,"Log":[]}` + "\n" +
+		"/worker|5|2024-05-01T12:01:00Z|false|2024-05-01T12:00:50Z|1|false|{}||null\n" +
+		"/batch|0|2024-05-01T12:01:00Z|true|2024-05-01T12:00:50Z|137|false|{}||null\n" +
+		"/fresh|0|0001-01-01T00:00:00Z|false|0001-01-01T00:00:00Z|0|false|{}|bridge,172.17.0.5,;|null\n" +
+		"/gone|0|bad line\n"
+	containers := []ContainerStatus{{Name: "web"}, {Name: "worker"}, {Name: "batch"}, {Name: "fresh"}, {Name: "other"}}
+	applyContainerInspect(containers, out)
+
+	type want struct {
+		restarts  *int
+		exitCode  *int
+		started   bool
+		finished  bool
+		oomKilled bool
+	}
+	ptr := func(n int) *int { return &n }
+	wants := map[string]want{
+		// rodando: o codigo 0 da partida nao vai
+		"web":    {restarts: ptr(3), started: true, finished: true},
+		"worker": {restarts: ptr(5), exitCode: ptr(1), started: true, finished: true},
+		"batch":  {restarts: ptr(0), exitCode: ptr(137), started: true, finished: true, oomKilled: true},
+		"fresh":  {restarts: ptr(0)},
+		"other":  {},
+	}
+	for _, c := range containers {
+		w := wants[c.Name]
+		if !reflect.DeepEqual(c.RestartCount, w.restarts) || !reflect.DeepEqual(c.ExitCode, w.exitCode) || c.OOMKilled != w.oomKilled {
+			t.Errorf("%s: restarts %v, exit code %v, oom %v; want %v, %v, %v", c.Name, c.RestartCount, c.ExitCode, c.OOMKilled, w.restarts, w.exitCode, w.oomKilled)
+		}
+		if (c.StartedAt != nil) != w.started || (c.StartedAt != nil && !c.StartedAt.Equal(started)) {
+			t.Errorf("%s: started at %v", c.Name, c.StartedAt)
+		}
+		if (c.FinishedAt != nil) != w.finished || (c.FinishedAt != nil && !c.FinishedAt.Equal(finished)) {
+			t.Errorf("%s: finished at %v", c.Name, c.FinishedAt)
+		}
+	}
+	if len(containers[0].Ports) != 1 || len(containers[0].Networks) != 1 || containers[3].Networks[0].IPAddress != "172.17.0.5" {
+		t.Errorf("ports %+v, networks %+v, %+v", containers[0].Ports, containers[0].Networks, containers[3].Networks)
+	}
+	if h := containers[0].Health; h == nil || h.Status != "healthy" || containers[1].Health != nil {
+		t.Errorf("health: web %+v, worker %+v", h, containers[1].Health)
+	}
+	if !containers[2].oomKilledAt.Equal(finished) {
+		t.Errorf("batch: oom killed at %s, want %s", containers[2].oomKilledAt, finished)
+	}
+}
+
+func TestKubeLastExit(t *testing.T) {
+	raw := func(s string) map[string]json.RawMessage {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	tests := []struct {
+		name      string
+		state     string
+		last      string
+		exitCode  *int
+		finished  bool
+		oomKilled bool
+	}{
+		{"running after oom", `{"running":{}}`, `{"terminated":{"reason":"OOMKilled","exitCode":137,"finishedAt":"2024-05-01T12:00:50Z"}}`, nil, true, true},
+		{"crash loop", `{"waiting":{"reason":"CrashLoopBackOff"}}`, `{"terminated":{"reason":"Error","exitCode":2,"finishedAt":"2024-05-01T12:00:50Z"}}`, func() *int { n := 2; return &n }(), true, false},
+		{"completed", `{"terminated":{"reason":"Completed","exitCode":0,"finishedAt":"2024-05-01T12:00:50Z"}}`, `{}`, func() *int { n := 0; return &n }(), true, false},
+		{"never exited", `{"running":{}}`, `{}`, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c ContainerStatus
+			kubeLastExit(&c, raw(tt.state), raw(tt.last))
+			if !reflect.DeepEqual(c.ExitCode, tt.exitCode) || (c.FinishedAt != nil) != tt.finished || c.OOMKilled != tt.oomKilled {
+				t.Errorf("got exit code %v, finished %v, oom %v", c.ExitCode, c.FinishedAt, c.OOMKilled)
+			}
+		})
+	}
+}
+
+func TestParseContainerHealth(t *testing.T) {
+	long := strings.Repeat("x", maxHealthOutput+10)
+	tests := []struct {
+		name string
+		in   string
+		want *ContainerHealth
+	}{
+		{"no healthcheck", "null", nil},
+		{"old podman", "<no value>", nil},
+		{"starting", `{"Status":"starting","FailingStreak":0,"Log":null}`, &ContainerHealth{Status: "starting"}},
+		{
+			name: "unhealthy",
+			in: `{"Status":"unhealthy","FailingStreak":3,"Log":[` +
+				`{"Start":"2024-05-01T11:59:50Z","End":"2024-05-01T11:59:51Z","ExitCode":0,"Output":"ok"},` +
+				`{"Start":"2024-05-01T12:00:00Z","End":"2024-05-01T12:00:01.5Z","ExitCode":1,"Output":"curl: (7) Failed to connect | port 80\n"}]}`,
+			want: &ContainerHealth{
+				Status: "unhealthy", FailingStreak: 3, LastCheckAt: time.Date(2024, 5, 1, 12, 0, 1, 500000000, time.UTC),
+				LastExitCode: 1, LastOutput: "curl: (7) Failed to connect | port 80",
+			},
+		},
+		{
+			name: "long output",
+			in:   `{"Status":"healthy","Log":[{"End":"2024-05-01T12:00:01Z","Output":"` + long + `"}]}`,
+			want: &ContainerHealth{Status: "healthy", LastCheckAt: time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC), LastOutput: long[:maxHealthOutput]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseContainerHealth(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Injecao de falhas para testes de integracao e para o usuario conferir que
+// os alertas dele disparam nos modos de falha do agente. Ativada por
+// VAULTRIX_FAULT ou pelo flag oculto --fault, com uma lista separada por
+// virgulas:
+//
+//	send_timeout                 o envio falha como timeout
+//	send_error[:status]          a API responde com status (padrao 503)
+//	collector_panic:<coletor>    o coletor entra em panic
+//	collector_timeout:<coletor>  o coletor estoura o prazo
+//	collector_error:<coletor>    o coletor devolve erro
+//
+// Os coletores sao os nomes da secao "collectors" que rodam com prazo
+// proprio: cpu, memory, disk, disks, load, host, docker, docker_stats,
+// docker_net e systemd.
+const (
+	faultSendTimeout      = "send_timeout"
+	faultSendError        = "send_error"
+	faultCollectorPanic   = "collector_panic"
+	faultCollectorTimeout = "collector_timeout"
+	faultCollectorError   = "collector_error"
+)
+
+// faults guarda as falhas ativas como "tipo" ou "tipo:parametro".
+var faults = map[string]string{}
+
+// initFaults le VAULTRIX_FAULT e remove --fault de args; o flag fica fora
+// do FlagSet para nao aparecer na ajuda.
+func initFaults(args []string) []string {
+	spec := os.Getenv("VAULTRIX_FAULT")
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if i == 0 || !strings.HasPrefix(arg, "-") || name != "fault" {
+			out = append(out, arg)
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		spec = value
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		kind, param, _ := strings.Cut(strings.TrimSpace(item), ":")
+		switch kind {
+		case "":
+			continue
+		case faultSendTimeout, faultSendError:
+			faults[kind] = param
+		case faultCollectorPanic, faultCollectorTimeout, faultCollectorError:
+			faults[kind+":"+param] = param
+		default:
+			fmt.Fprintf(os.Stderr, "fault: unknown fault %q ignored\n", kind)
+			continue
+		}
+	}
+	if len(faults) > 0 {
+		fmt.Fprintf(os.Stderr, "fault: injecting %s\n", spec)
+	}
+	return out
+}
+
+// sendFault devolve a falha de envio injetada, se houver.
+func sendFault() error {
+	if _, ok := faults[faultSendTimeout]; ok {
+		return fmt.Errorf("injected fault %s: %w", faultSendTimeout, os.ErrDeadlineExceeded)
+	}
+	if param, ok := faults[faultSendError]; ok {
+		status, err := strconv.Atoi(param)
+		if err != nil || status < 300 || status > 599 {
+			status = 503
+		}
+		return &apiError{StatusCode: status, Body: "injected fault " + faultSendError}
+	}
+	return nil
+}
+
+// collectorFault e chamado no inicio de cada coletor com prazo proprio.
+func collectorFault(ctx context.Context, name string) error {
+	if len(faults) == 0 {
+		return nil
+	}
+	if _, ok := faults[faultCollectorPanic+":"+name]; ok {
+		panic("injected fault " + faultCollectorPanic + ":" + name)
+	}
+	if _, ok := faults[faultCollectorTimeout+":"+name]; ok {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if _, ok := faults[faultCollectorError+":"+name]; ok {
+		return fmt.Errorf("injected fault %s:%s", faultCollectorError, name)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// requiredPayloadFields nunca sao removidos: sem eles a API recusa o payload.
+var requiredPayloadFields = []string{"token", "timestamp", "agent_version"}
+
+func validateFieldFilter(f config.FieldFilter) error {
+	for _, p := range append(slices.Clone(f.Allow), f.Deny...) {
+		if p == "" || slices.Contains(strings.Split(p, "."), "") {
+			return fmt.Errorf("payload_fields: invalid path %q", p)
+		}
+	}
+	for _, p := range f.Deny {
+		if slices.Contains(requiredPayloadFields, p) {
+			return fmt.Errorf("payload_fields: %s cannot be denied", p)
+		}
+	}
+	return nil
+}
+
+// marshalPayload serializa o payload aplicando o filtro de campos. O filtro
+// roda aqui, no unico ponto de saida para a API, e vale tambem para os
+// reenvios do spool e o replay.
+func marshalPayload(cfg config.Config, payload Payload) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil || cfg.PayloadFields.Empty() {
+		return body, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	filterPayload(cfg.PayloadFields, doc, nil)
+	return json.Marshal(doc)
+}
+
+func filterPayload(f config.FieldFilter, v any, path []string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			p := append(slices.Clip(path), key)
+			if len(path) == 0 && slices.Contains(requiredPayloadFields, key) {
+				continue
+			}
+			if f.Denied(p) || !f.Allowed(p) {
+				delete(v, key)
+				continue
+			}
+			filterPayload(f, child, p)
+		}
+	case []any:
+		for _, item := range v {
+			filterPayload(f, item, path)
+		}
+	}
+}
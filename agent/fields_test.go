@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestMarshalPayloadFields(t *testing.T) {
+	payload := Payload{
+		Token:        "secret-token",
+		Host:         &HostInfo{Hostname: "web1", MachineID: "abc", Arch: "amd64"},
+		Metrics:      Metrics{CPUUsage: 12.5, DiskPercent: 40},
+		Containers:   []ContainerStatus{{Name: "api", Image: "api:1"}, {Name: "db", Image: "pg:16"}},
+		Timestamp:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		AgentVersion: "1.0.0",
+	}
+	tests := []struct {
+		name   string
+		filter config.FieldFilter
+		want   map[string][]string // objeto -> chaves esperadas
+	}{
+		{
+			name: "no filter",
+			want: map[string][]string{"host": {"arch", "hostname", "machine_id"}},
+		},
+		{
+			name:   "deny nested field",
+			filter: config.FieldFilter{Deny: []string{"host.machine_id"}},
+			want:   map[string][]string{"host": {"arch", "hostname"}},
+		},
+		{
+			name:   "deny field in every list item",
+			filter: config.FieldFilter{Deny: []string{"containers.image"}},
+			want:   map[string][]string{"containers[]": {"name"}},
+		},
+		{
+			// os campos obrigatorios sobrevivem mesmo fora do allow
+			name:   "allow keeps ancestors and required fields",
+			filter: config.FieldFilter{Allow: []string{"metrics.cpu", "host.hostname"}},
+			want: map[string][]string{
+				"":        {"agent_version", "host", "metrics", "timestamp", "token"},
+				"host":    {"hostname"},
+				"metrics": {"cpu"},
+			},
+		},
+		{
+			name:   "wildcard",
+			filter: config.FieldFilter{Deny: []string{"*.hostname"}},
+			want:   map[string][]string{"host": {"arch", "machine_id"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := marshalPayload(config.Config{PayloadFields: tt.filter}, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(b, &doc); err != nil {
+				t.Fatal(err)
+			}
+			for where, keys := range tt.want {
+				var obj any = doc
+				switch where {
+				case "":
+				case "containers[]":
+					list, _ := doc["containers"].([]any)
+					if len(list) != 2 {
+						t.Fatalf("containers = %v", doc["containers"])
+					}
+					obj = list[1]
+				default:
+					obj = doc[where]
+				}
+				m, _ := obj.(map[string]any)
+				if got := sortedKeys(m); !reflect.DeepEqual(got, keys) {
+					t.Errorf("%s keys = %v, want %v", where, got, keys)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFieldFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  config.FieldFilter
+		wantErr bool
+	}{
+		{"empty", config.FieldFilter{}, false},
+		{"valid paths", config.FieldFilter{Allow: []string{"metrics"}, Deny: []string{"host.machine_id"}}, false},
+		{"empty segment", config.FieldFilter{Deny: []string{"host..machine_id"}}, true},
+		{"required field", config.FieldFilter{Deny: []string{"token"}}, true},
+	}
+	for _, tt := range tests {
+		if err := validateFieldFilter(tt.filter); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// fileSink acrescenta cada payload, em uma linha JSON, a um arquivo local:
+// para ferramentas que ja coletam logs (Filebeat, Vector, Fluent Bit) e para
+// hosts offline, cujos arquivos sao levados ao servidor por outro meio e
+// importados com o replay. O token nao vai para o arquivo.
+//
+// Passando de max_mb, o arquivo atual vira <nome>-<horario UTC><ext> ao lado
+// dele e um novo comeca; dos rodados ficam os max_files mais recentes. Os
+// nomes ordenam cronologicamente, que e a ordem em que o replay os envia.
+type fileSink struct {
+	cfg  config.Config
+	conf config.SinkConfig
+}
+
+func (s fileSink) Name() string { return s.conf.NameOrDefault() }
+
+func (s fileSink) Send(payload Payload) error {
+	payload.Token = ""
+	b, err := marshalPayload(s.cfg, payload)
+	if err != nil {
+		return err
+	}
+	maxMB, _ := s.conf.FileLimits()
+	if info, err := os.Stat(s.conf.Path); err == nil && info.Size() > 0 && info.Size()+int64(len(b)) > int64(maxMB)<<20 {
+		if err := s.rotate(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if err := fsutil.EnsureDir(filepath.Dir(s.conf.Path)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.conf.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotate fecha o arquivo atual com o horario no nome e apaga os rodados
+// que passarem de max_files.
+func (s fileSink) rotate(now time.Time) error {
+	ext := filepath.Ext(s.conf.Path)
+	base := strings.TrimSuffix(s.conf.Path, ext)
+	if err := os.Rename(s.conf.Path, base+"-"+now.UTC().Format("20060102T150405.000Z")+ext); err != nil {
+		return err
+	}
+
+	_, maxFiles := s.conf.FileLimits()
+	rotated, _ := filepath.Glob(base + "-[0-9]*" + ext)
+	sort.Strings(rotated)
+	for len(rotated) > maxFiles {
+		os.Remove(rotated[0])
+		rotated = rotated[1:]
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+func filterContainers(f config.ContainerFilter, containers []ContainerStatus) []ContainerStatus {
+	if len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.Label) == 0 && len(f.ExcludeLabel) == 0 {
+		return containers
+	}
+	kept := containers[:0]
+	for _, c := range containers {
+		if containerMatches(f, c) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func containerMatches(f config.ContainerFilter, c ContainerStatus) bool {
+	if len(f.Include) > 0 && !matchAnyGlob(f.Include, c.Name, c.Image) {
+		return false
+	}
+	if matchAnyGlob(f.Exclude, c.Name, c.Image) {
+		return false
+	}
+	for _, selector := range f.Label {
+		if !matchLabel(selector, c.Labels) {
+			return false
+		}
+	}
+	for _, selector := range f.ExcludeLabel {
+		if matchLabel(selector, c.Labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAnyGlob(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			if ok, _ := path.Match(pattern, v); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchLabel(selector string, labels map[string]string) bool {
+	key, want, hasValue := strings.Cut(selector, "=")
+	got, ok := labels[strings.TrimSpace(key)]
+	if !ok {
+		return false
+	}
+	return !hasValue || got == strings.TrimSpace(want)
+}
+
+// parseLabels interpreta o formato "k1=v1,k2=v2" do docker ps, que junta
+// os labels com virgula sem escapar nada. Um trecho sem "=" e continuacao
+// do valor anterior, como em com.docker.compose.project.config_files=
+// "a.yml,b.yml". Ja um valor com ",k=v" dentro e indistinguivel de dois
+// labels; validate recusa seletores assim, que nunca casariam.
+func parseLabels(raw string) map[string]string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	labels := make(map[string]string)
+	last := ""
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok && last != "" {
+			labels[last] += "," + pair
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		labels[key] = value
+		last = key
+	}
+	return labels
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestContainerFilterApply(t *testing.T) {
+	containers := []ContainerStatus{
+		{Name: "web", Image: "nginx:1.25", Labels: map[string]string{"monitor": "true", "tier": "front"}},
+		{Name: "db", Image: "postgres:16", Labels: map[string]string{"monitor": "false"}},
+		{Name: "k8s_POD_web-0", Image: "registry.k8s.io/pause:3.9"},
+		{Name: "worker", Image: "app/worker:2", Labels: parseLabels("tier=batch,files=a.yml,b.yml")},
+	}
+	tests := []struct {
+		name   string
+		filter config.ContainerFilter
+		want   []string
+	}{
+		{"no filter", config.ContainerFilter{}, []string{"web", "db", "k8s_POD_web-0", "worker"}},
+		{"exclude by name", config.ContainerFilter{Exclude: []string{"k8s_*"}}, []string{"web", "db", "worker"}},
+		{"exclude by image", config.ContainerFilter{Exclude: []string{"*/pause:*"}}, []string{"web", "db", "worker"}},
+		{"include", config.ContainerFilter{Include: []string{"web", "postgres:*"}}, []string{"web", "db"}},
+		{"label with value", config.ContainerFilter{Label: []string{"monitor=true"}}, []string{"web"}},
+		{"label key only", config.ContainerFilter{Label: []string{"tier"}}, []string{"web", "worker"}},
+		{"exclude label", config.ContainerFilter{ExcludeLabel: []string{"tier=batch"}}, []string{"web", "db", "k8s_POD_web-0"}},
+		{"label value with comma", config.ContainerFilter{Label: []string{"files=a.yml,b.yml"}}, []string{"worker"}},
+		{"include and exclude", config.ContainerFilter{Include: []string{"*"}, Exclude: []string{"db"}, Label: []string{"tier"}}, []string{"web", "worker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]ContainerStatus(nil), containers...)
+			var got []string
+			for _, c := range filterContainers(tt.filter, in) {
+				got = append(got, c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerFilterValidate(t *testing.T) {
+	tests := []struct {
+		filter  config.ContainerFilter
+		wantErr bool
+	}{
+		{config.ContainerFilter{Include: []string{"web-*"}, Label: []string{"monitor=true"}}, false},
+		{config.ContainerFilter{Exclude: []string{"[k8s"}}, true},
+		{config.ContainerFilter{Label: []string{" "}}, true},
+		{config.ContainerFilter{ExcludeLabel: []string{"=true"}}, true},
+		{config.ContainerFilter{Label: []string{"com.docker.compose.project.config_files=a.yml,b.yml"}}, false},
+		{config.ContainerFilter{Label: []string{"note=a,b=c"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) = %v, want error %v", tt.filter, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want map[string]string
+	}{
+		{"", nil},
+		{"monitor=true", map[string]string{"monitor": "true"}},
+		{"a=1,b=", map[string]string{"a": "1", "b": ""}},
+		{"com.docker.compose.project=shop, com.docker.compose.service=web", map[string]string{
+			"com.docker.compose.project": "shop",
+			"com.docker.compose.service": "web",
+		}},
+		// o docker junta os labels com virgula sem escapar as do valor
+		{"com.docker.compose.project.config_files=/srv/shop/a.yml,/srv/shop/b.yml,com.docker.compose.project=shop", map[string]string{
+			"com.docker.compose.project.config_files": "/srv/shop/a.yml,/srv/shop/b.yml",
+			"com.docker.compose.project":              "shop",
+		}},
+		{"description=red, green and blue,tier=front", map[string]string{"description": "red, green and blue", "tier": "front"}},
+	}
+	for _, tt := range tests {
+		if got := parseLabels(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLabels(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// Feature flags liberam coletores e transportes novos aos poucos. O config
+// (em geral o remoto) traz "flags": {"<nome>": <percentual>}; cada host cai
+// num ponto fixo de 0 a 100 pelo hash do nome da flag com o machine-id, e a
+// flag vale para ele quando esse ponto fica abaixo do percentual. Subir o
+// percentual so acrescenta hosts: quem ja estava dentro continua.
+//
+// Flag ausente nao restringe nada. As flags conhecidas sao
+// "collector.<coletor>" (alem do mapa collectors, que continua desligando
+// para todos), "transport.http2" e "transport.dns_cache". Nomes que esta
+// versao nao conhece sao aceitos, para o servidor poder publica-los antes
+// de toda a frota atualizar.
+
+const agentIDFile = "agent-id"
+
+var rollout = struct {
+	sync.Mutex
+	dir string
+	id  string
+}{dir: defaultStateDir}
+
+// setRolloutStateDir aponta onde fica o id gerado para hosts sem machine-id.
+func setRolloutStateDir(stateDir string) {
+	rollout.Lock()
+	defer rollout.Unlock()
+	if rollout.dir != stateDir {
+		rollout.dir, rollout.id = stateDir, ""
+	}
+}
+
+// rolloutID e a chave do host na divisao da frota: o machine-id, ou, quando
+// nao ha um, um id aleatorio gravado no diretorio de estado na primeira vez.
+// O nome do host so entra se nem isso der para gravar, porque ele muda com
+// renomeacoes e tiraria o host da sua coorte.
+func rolloutID() string {
+	rollout.Lock()
+	defer rollout.Unlock()
+	if rollout.id == "" {
+		rollout.id = machineID()
+	}
+	if rollout.id == "" {
+		id, err := loadAgentID(rollout.dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "agent id: %v\n", err)
+			id, _ = os.Hostname()
+		}
+		rollout.id = id
+	}
+	return rollout.id
+}
+
+// loadAgentID le o id gerado, criando-o se ainda nao existe.
+func loadAgentID(stateDir string) (string, error) {
+	path := filepath.Join(stateDir, agentIDFile)
+	b, err := os.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(b)); id != "" {
+			return id, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+	if err := os.MkdirAll(stateDir, 0o700); err != nil {
+		return "", err
+	}
+	if err := fsutil.WriteFileAtomic(path, []byte(id+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// rolloutPoint devolve o ponto do host, em [0, 100), para a flag.
+func rolloutPoint(flag, id string) float64 {
+	sum := sha256.Sum256([]byte(flag + "\x00" + id))
+	return float64(binary.BigEndian.Uint64(sum[:8])%10000) / 100
+}
+
+// flagEnabled diz se a flag vale neste host e se ela esta definida.
+func flagEnabled(c config.Config, name string) (enabled, defined bool) {
+	percent, ok := c.Flags[name]
+	if !ok {
+		return false, false
+	}
+	return rolloutPoint(name, rolloutID()) < percent, true
+}
+
+// enabledFlags lista, em ordem, as flags definidas que valem neste host;
+// vai no payload para o servidor separar as coortes.
+func enabledFlags(c config.Config) []string {
+	var on []string
+	for _, name := range sortedKeys(c.Flags) {
+		if enabled, _ := flagEnabled(c, name); enabled {
+			on = append(on, name)
+		}
+	}
+	return on
+}
+
+// featureEnabled vale para recursos ligados por padrao: sem a flag, ligado.
+func featureEnabled(c config.Config, name string) bool {
+	enabled, defined := flagEnabled(c, name)
+	return enabled || !defined
+}
+
+func validateFlags(flags map[string]float64) error {
+	for name, percent := range flags {
+		if name == "" {
+			return fmt.Errorf("flags: empty flag name")
+		}
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("flags: %s must be a percentage between 0 and 100", name)
+		}
+	}
+	return nil
+}
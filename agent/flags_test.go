@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestRolloutPoint(t *testing.T) {
+	const hosts = 2000
+	tests := []struct {
+		flag    string
+		percent float64
+	}{
+		{"collector.systemd", 0},
+		{"collector.systemd", 10},
+		{"transport.http2", 50},
+		{"transport.dns_cache", 100},
+	}
+	for _, tt := range tests {
+		in := 0
+		for i := 0; i < hosts; i++ {
+			id := fmt.Sprintf("host-%d", i)
+			p := rolloutPoint(tt.flag, id)
+			if p < 0 || p >= 100 {
+				t.Fatalf("rolloutPoint(%q, %q) = %g, outside [0, 100)", tt.flag, id, p)
+			}
+			if p != rolloutPoint(tt.flag, id) {
+				t.Fatalf("rolloutPoint(%q, %q) is not stable", tt.flag, id)
+			}
+			if p < tt.percent {
+				in++
+			}
+		}
+		// a divisao so precisa ficar perto do percentual
+		got := 100 * float64(in) / hosts
+		if got < tt.percent-3 || got > tt.percent+3 {
+			t.Errorf("%s at %g%%: %.1f%% of hosts in", tt.flag, tt.percent, got)
+		}
+	}
+}
+
+func TestFlagEnabled(t *testing.T) {
+	rollout.Lock()
+	saved := rollout.id
+	rollout.id = "host-a"
+	rollout.Unlock()
+	t.Cleanup(func() {
+		rollout.Lock()
+		rollout.id = saved
+		rollout.Unlock()
+	})
+	point := rolloutPoint("collector.docker", "host-a")
+
+	tests := []struct {
+		name        string
+		flags       map[string]float64
+		wantEnabled bool
+		wantDefined bool
+		wantFeature bool
+	}{
+		{name: "undefined", wantFeature: true},
+		{name: "zero", flags: map[string]float64{"collector.docker": 0}, wantDefined: true},
+		{name: "everyone", flags: map[string]float64{"collector.docker": 100}, wantEnabled: true, wantDefined: true, wantFeature: true},
+		{name: "just above the host", flags: map[string]float64{"collector.docker": point + 0.01}, wantEnabled: true, wantDefined: true, wantFeature: true},
+		{name: "at the host", flags: map[string]float64{"collector.docker": point}, wantDefined: true},
+	}
+	for _, tt := range tests {
+		cfg := config.Config{Flags: tt.flags}
+		enabled, defined := flagEnabled(cfg, "collector.docker")
+		if enabled != tt.wantEnabled || defined != tt.wantDefined {
+			t.Errorf("%s: flagEnabled = %v, %v; want %v, %v", tt.name, enabled, defined, tt.wantEnabled, tt.wantDefined)
+		}
+		if got := featureEnabled(cfg, "collector.docker"); got != tt.wantFeature {
+			t.Errorf("%s: featureEnabled = %v, want %v", tt.name, got, tt.wantFeature)
+		}
+	}
+}
+
+func TestLoadAgentID(t *testing.T) {
+	dir := t.TempDir()
+	first, err := loadAgentID(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 32 {
+		t.Fatalf("id = %q, want 32 hex characters", first)
+	}
+	again, err := loadAgentID(dir)
+	if err != nil || again != first {
+		t.Fatalf("second load = %q, %v; want %q", again, err, first)
+	}
+
+	// um id gravado a mao tambem vale
+	if err := os.WriteFile(filepath.Join(dir, agentIDFile), []byte("custom\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := loadAgentID(dir); id != "custom" {
+		t.Errorf("id = %q, want custom", id)
+	}
+}
@@ -1,3 +1,8 @@
 module vaultrix-agent
 
 go 1.22
+
+require (
+	github.com/tetratelabs/wazero v1.8.2
+	golang.org/x/sys v0.30.0
+)
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"os"
+	"runtime"
+
+	"vaultrix-agent/internal/config"
+)
+
+// HostInfo identifica a maquina, permitindo ao servidor correlacionar tokens
+// com hosts reais.
+type HostInfo struct {
+	Hostname       string `json:"hostname"`
+	MachineID      string `json:"machine_id,omitempty"`
+	OSName         string `json:"os_name,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	KernelVersion  string `json:"kernel_version,omitempty"`
+	Arch           string `json:"arch"`
+	Virtualization string `json:"virtualization,omitempty"`
+	UptimeSeconds  int64  `json:"uptime_seconds,omitempty"`
+}
+
+func collectHostInfo(ctx context.Context, cfg config.Config) (HostInfo, error) {
+	info := HostInfo{Arch: runtime.GOARCH}
+	platformHostInfo(ctx, &info, agentEnvironment(cfg).HostRoot)
+
+	if cfg.Hostname != "" {
+		info.Hostname = cfg.Hostname
+	} else if info.Hostname == "" {
+		info.Hostname, _ = os.Hostname()
+	}
+	return info, ctx.Err()
+}
@@ -0,0 +1,120 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"vaultrix-agent/internal/fsutil"
+)
+
+func machineID() string {
+	return machineIDAt("/")
+}
+
+// machineIDAt le o machine-id da arvore em root; dentro de um container, o
+// do host montado.
+func machineIDAt(root string) string {
+	if id := fsutil.ReadTrimmed(filepath.Join(root, "etc/machine-id")); id != "" {
+		return id
+	}
+	return fsutil.ReadTrimmed(filepath.Join(root, "var/lib/dbus/machine-id"))
+}
+
+// hostArch e a arquitetura do kernel, que pode diferir da do binario (um
+// amd64 rodando por emulacao, um arm em um kernel arm64).
+func hostArch() string {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return ""
+	}
+	return unameArch(unix.ByteSliceToString(u.Machine[:]))
+}
+
+// platformHostInfo identifica o host. Com root (o / do host montado no
+// container), machine-id, hostname e distribuicao vem de la; kernel e
+// uptime sao os mesmos dentro e fora do container.
+func platformHostInfo(ctx context.Context, info *HostInfo, root string) {
+	if root == "" {
+		root = "/"
+	} else {
+		info.Hostname = fsutil.ReadTrimmed(filepath.Join(root, "etc/hostname"))
+	}
+	info.MachineID = machineIDAt(root)
+	info.KernelVersion = fsutil.ReadTrimmed("/proc/sys/kernel/osrelease")
+
+	osRelease := readOSRelease(filepath.Join(root, "etc/os-release"))
+	info.OSName = osRelease["NAME"]
+	info.OSVersion = osRelease["VERSION_ID"]
+	if info.OSName == "" {
+		info.OSName = "Linux"
+	}
+
+	if fields := strings.Fields(fsutil.ReadTrimmed("/proc/uptime")); len(fields) > 0 {
+		info.UptimeSeconds = int64(parseFloat(fields[0]))
+	}
+
+	info.Virtualization = detectVirtualization(ctx)
+}
+
+// readOSRelease le o formato CHAVE="valor" do os-release.
+func readOSRelease(path string) map[string]string {
+	values := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	return values
+}
+
+// detectVirtualization prefere o systemd-detect-virt e recorre ao DMI e a
+// marcadores de container quando ele nao existe.
+func detectVirtualization(ctx context.Context) string {
+	out, err := exec.CommandContext(ctx, "systemd-detect-virt").Output()
+	if v := strings.TrimSpace(string(out)); v != "" {
+		return v
+	}
+	if err == nil {
+		return "none"
+	}
+
+	if fsutil.FileExists("/.dockerenv") {
+		return "docker"
+	}
+	if fsutil.FileExists("/run/.containerenv") {
+		return "podman"
+	}
+	return dmiVirtualization()
+}
+
+// dmiVirtualization reconhece o hypervisor pelo fabricante no DMI, que
+// dentro de um container ainda e o da maquina.
+func dmiVirtualization() string {
+	vendor := strings.ToLower(fsutil.ReadTrimmed("/sys/class/dmi/id/sys_vendor") + " " + fsutil.ReadTrimmed("/sys/class/dmi/id/product_name"))
+	for marker, name := range map[string]string{
+		"kvm": "kvm", "qemu": "qemu", "vmware": "vmware", "virtualbox": "oracle",
+		"microsoft": "microsoft", "xen": "xen", "amazon ec2": "amazon",
+		"google": "google", "digitalocean": "kvm", "hetzner": "kvm",
+	} {
+		if strings.Contains(vendor, marker) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// machineID nao tem fonte estavel fora de Linux e Windows; o rollout de
+// flags usa o nome do host.
+func machineID() string {
+	return ""
+}
+
+// hostArch: fora de Linux vale a arquitetura do proprio binario.
+func hostArch() string {
+	return runtime.GOARCH
+}
+
+func platformHostInfo(ctx context.Context, info *HostInfo, root string) {
+	info.OSName = runtime.GOOS
+	if out, err := exec.CommandContext(ctx, "uname", "-r").Output(); err == nil {
+		info.KernelVersion = strings.TrimSpace(string(out))
+	}
+	if runtime.GOOS == "darwin" {
+		info.OSName = "macOS"
+		if out, err := exec.CommandContext(ctx, "sw_vers", "-productVersion").Output(); err == nil {
+			info.OSVersion = strings.TrimSpace(string(out))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+var procGetTickCount64 = kernel32.NewProc("GetTickCount64")
+
+func machineID() string {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return ""
+	}
+	defer k.Close()
+	id, _, _ := k.GetStringValue("MachineGuid")
+	return id
+}
+
+// hostArch: no Windows vale a arquitetura do proprio binario.
+func hostArch() string {
+	return runtime.GOARCH
+}
+
+func platformHostInfo(ctx context.Context, info *HostInfo, root string) {
+	info.OSName = "Windows"
+	if ms, _, _ := procGetTickCount64.Call(); ms > 0 {
+		info.UptimeSeconds = int64(time.Duration(ms) * time.Millisecond / time.Second)
+	}
+
+	info.MachineID = machineID()
+
+	if k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE); err == nil {
+		if name, _, err := k.GetStringValue("ProductName"); err == nil {
+			info.OSName = name
+		}
+		info.OSVersion, _, _ = k.GetStringValue("DisplayVersion")
+		build, _, _ := k.GetStringValue("CurrentBuild")
+		if ubr, _, err := k.GetIntegerValue("UBR"); err == nil && build != "" {
+			build = fmt.Sprintf("%s.%d", build, ubr)
+		}
+		info.KernelVersion = build
+		k.Close()
+	}
+}
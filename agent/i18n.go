@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Idiomas das mensagens do CLI. Ingles e o padrao; --lang pt-BR (ou
+// VAULTRIX_LANG) mantem as mensagens em portugues. Saidas lidas por
+// maquinas (JSON, INSTALLED/NOT_INSTALLED, chaves e valores do config show,
+// logs de erro) nunca passam pela traducao.
+const (
+	langEN   = "en"
+	langPTBR = "pt-BR"
+)
+
+var lang = langEN
+
+// messages usa o texto em ingles como chave; mensagens sem traducao saem em
+// ingles.
+var messages = map[string]map[string]string{
+	langPTBR: {
+		"Machine token":                                   "Token da maquina",
+		"API URL":                                         "URL da API",
+		"HTTP/HTTPS/SOCKS5 proxy for the API":             "Proxy HTTP/HTTPS/SOCKS5 para a API",
+		"Interval in minutes":                             "Intervalo em minutos",
+		"Install and schedule the agent":                  "Instala e agenda o agente",
+		"Install without testing connectivity to the API": "Instala sem testar a conectividade com a API",
+		"Remove the agent":                                "Remove o agente",
+		"Collect once and print the payload instead of sending it":    "Coleta uma vez e imprime o payload em vez de envia-lo",
+		"Run a single collection":                                     "Executa uma coleta unica",
+		"Run continuously, collecting every interval":                 "Executa continuamente, coletando a cada intervalo",
+		"Collect pods from the kubelet (DaemonSet) instead of docker": "Coleta pods pelo kubelet (DaemonSet) em vez do docker",
+		"Show the agent status":                                       "Mostra o estado do agente",
+		"Only report whether a newer version is available":            "Apenas informa se ha uma versao mais nova",
+		"Install the published version even if it is not newer":       "Instala a versao publicada mesmo que nao seja mais nova",
+		"Version %s is available (installed: %s).":                    "A versao %s esta disponivel (instalada: %s).",
+		"Already up to date (%s).":                                    "Ja esta atualizado (%s).",
+		"Updated %s -> %s.":                                           "Atualizado %s -> %s.",
+		"Restart the service to run the new version.":                 "Reinicie o servico para rodar a versao nova.",
+		"Run this collection right away, ignoring splay_seconds":      "Executa esta coleta na hora, ignorando o splay_seconds",
+		"Print the agent version and exit":                            "Mostra a versao do agente e sai",
+		"JSON output (with --status)":                                 "Saida em JSON (com --status)",
+		"Config file path":                                            "Caminho do config",
+		"Agent state directory":                                       "Diretorio de estado do agente",
+		"Schedule removed.":                                           "Agendamento removido.",
+		"Agent installed.":                                            "Agente instalado.",
+		"CLI message language (en, pt-BR)":                            "Idioma das mensagens do CLI (en, pt-BR)",
+		"Current config":                                              "Config atual",
+		"Proposed config":                                             "Config proposto",
+		"JSON output":                                                 "Saida em JSON",
+		"Include the cached remote config":                            "Inclui a config remota em cache",
+		"usage: vaultrix-agent config diff --file new.json | config show [--effective]": "uso: vaultrix-agent config diff --file novo.json | config show [--effective]",
+		"config: unknown subcommand %q": "config: subcomando desconhecido %q",
+		"No behavior changes.":          "Nenhuma mudanca de comportamento.",
+		"Number of days in the summary": "Quantidade de dias no resumo",
+		"Events:":                       "Eventos:",
+		"(none)":                        "(nenhum)",
+		"Sends per day:":                "Envios por dia:",
+		"Grant the .wasm plugin the capabilities requested in its manifest":              "Concede ao plugin .wasm as capacidades pedidas no manifest",
+		"usage: vaultrix-agent plugin install [--config path] [--grant] <name>":          "uso: vaultrix-agent plugin install [--config path] [--grant] <nome>",
+		"Plugin %s %s installed at %s.":                                                  "Plugin %s %s instalado em %s.",
+		"The plugin requests the capabilities %s; run again with --grant to grant them.": "O plugin pede as capacidades %s; rode novamente com --grant para concede-las.",
+		"Token rotated.": "Token rotacionado.",
+		"Data collected and sent with this config:": "Dados coletados e enviados com este config:",
+		"[identifying]":                 "[identificador]",
+		"Fields: %s":                    "Campos: %s",
+		"Installed: %s":                 "Instalados: %s",
+		"Removed by payload_fields: %s": "Removidos por payload_fields: %s",
+		"Disabled: %s":                  "Desligados: %s",
+		"Destinations:":                 "Destinos:",
+		"fix the config file; see vaultrix-agent config show": "corrija o arquivo de config; veja vaultrix-agent config show",
+		"config is invalid":                      "config invalida",
+		"API unreachable":                        "API inacessivel",
+		"the API response has no Date header":    "a resposta da API nao tem header Date",
+		"local clock differs from the API by %s": "o relogio local difere do da API em %s",
+		"enable time synchronization (timedatectl set-ntp true, chrony or w32time)": "ative a sincronizacao de horario (timedatectl set-ntp true, chrony ou w32time)",
+		"docker not installed": "docker nao instalado",
+		"the agent user cannot open the docker socket; run the agent as root or add it to the docker group": "o usuario do agente nao consegue abrir o socket do docker; rode o agente como root ou adicione-o ao grupo docker",
+		"docker CLI not found in PATH":                                      "CLI do docker nao encontrado no PATH",
+		"the docker daemon is not answering; check systemctl status docker": "o daemon do docker nao responde; veja systemctl status docker",
+		"daemon %s":                                                  "daemon %s",
+		"not installed":                                              "nao instalado",
+		"run vaultrix-agent install":                                 "rode vaultrix-agent install",
+		"%s installed, but the agent never ran":                      "%s instalado, mas o agente nunca rodou",
+		"%s installed, but the last run was %s ago":                  "%s instalado, mas a ultima execucao foi ha %s",
+		"check that the cron daemon or service is running":           "verifique se o daemon do cron ou o servico esta rodando",
+		"%s, last run %s ago":                                        "%s, ultima execucao ha %s",
+		"writable by other users; run chmod 755 %s":                  "gravavel por outros usuarios; rode chmod 755 %s",
+		"not executable; run chmod 755 %s":                           "sem permissao de execucao; rode chmod 755 %s",
+		"%s: %d pending":                                             "%s: %d pendente(s)",
+		"Sinks:":                                                     "Sinks:",
+		"%s: paused until %s after %d failures: %s":                  "%s: pausado ate %s apos %d falhas: %s",
+		"%s: %d failures in a row: %s":                               "%s: %d falhas seguidas: %s",
+		"%s: ok, last sent %s":                                       "%s: ok, ultimo envio %s",
+		"All checks passed":                                          "Todas as verificacoes passaram",
+		"Collection requested; the running agent is sending it now.": "Coleta pedida; o agente em execucao esta enviando agora.",
+		"No running agent found; collected and sent.":                "Nenhum agente em execucao; coleta feita e enviada.",
+		"%d check(s) failed":                                         "%d verificacao(oes) falharam",
+		"Agent identity":                                             "Identidade do agente",
+		"Prometheus scrapes":                                         "Coletas Prometheus",
+		"Allowlisted samples, with their labels, from the configured local /metrics endpoints": "Amostras permitidas, com os seus rotulos, dos endpoints /metrics locais configurados",
+		"Textfile metrics": "Metricas de textfile",
+		"Samples, with their labels, from the .prom files in the textfile directory and when each file was last written":                                          "Amostras, com os seus rotulos, dos arquivos .prom do diretorio de textfile e quando cada arquivo foi gravado",
+		"Server version, connections, threads, slow queries, InnoDB buffer pool usage and replication state":                                                      "Versao do servidor, conexoes, threads, slow queries, uso do buffer pool do InnoDB e estado da replicacao",
+		"Server version, connections, transactions per second, cache hit ratio, WAL and database sizes and replication state":                                     "Versao do servidor, conexoes, transacoes por segundo, cache hit, tamanho do WAL e dos bancos e estado da replicacao",
+		"Version, memory, clients, operations per second, keyspace hits, misses and evictions and replication role of each instance":                              "Versao, memoria, clientes, operacoes por segundo, hits, misses e evictions do keyspace e papel na replicacao de cada instancia",
+		"Version, connections, operation counters, memory and replica set lag of each instance":                                                                   "Versao, conexoes, contadores de operacoes, memoria e atraso no replica set de cada instancia",
+		"Active connections, requests per second and connection states from each stub_status page":                                                                "Conexoes ativas, requisicoes por segundo e estados das conexoes de cada pagina stub_status",
+		"Active connections, requests per second and worker states from each mod_status page":                                                                     "Conexoes ativas, requisicoes por segundo e estados dos workers de cada pagina mod_status",
+		"Queue names, depths, unacknowledged messages and consumers, and node memory, file descriptor and disk alarms of each instance":                           "Nomes, profundidade, mensagens sem ack e consumidores das filas, e alarmes de memoria, descritores de arquivo e disco dos nos de cada instancia",
+		"Cluster name and status, node count, shards, heap usage per node and indexing and search rates of each cluster":                                          "Nome e status do cluster, numero de nos, shards, uso de heap por no e taxas de indexacao e busca de cada cluster",
+		"Broker, topic and partition counts, under-replicated and offline partitions, consumer group lag and, with Jolokia, broker request rates of each cluster": "Numero de brokers, topicos e particoes, particoes sub-replicadas e offline, atraso dos grupos de consumidores e, com Jolokia, taxas de requisicoes do broker de cada cluster",
+		"Version, connections, memory usage, items, hit ratio and evictions of each instance":                                                                     "Versao, conexoes, uso de memoria, itens, taxa de acerto e remocoes de cada instancia",
+		"Container log matches": "Ocorrencias nos logs dos containers",
+		"Number of log lines of each container that matched each log_scan pattern since the previous cycle": "Numero de linhas de log de cada container que casaram com cada padrao do log_scan desde o ciclo anterior",
+		"Container runtime disk usage": "Uso de disco do runtime de containers",
+		"Space used by images, containers, volumes and build cache, and how much of it is reclaimable, for each container runtime": "Espaco usado por imagens, containers, volumes e cache de build, e quanto dele pode ser liberado, de cada runtime de containers",
+		"Container volumes": "Volumes de containers",
+		"Names, drivers, mount points and sizes of named volumes and the containers that use them": "Nomes, drivers, pontos de montagem e tamanhos dos volumes nomeados e os containers que os usam",
+		"Container images":   "Imagens de containers",
+		"Container networks": "Redes de containers",
+		"Names, drivers, subnets and gateways of container networks and the containers attached to them": "Nomes, drivers, sub-redes e gateways das redes de containers e os containers ligados a elas",
+		"Swarm services and nodes": "Servicos e nos do Swarm",
+		"On Swarm managers, node hostnames, roles, status and availability, and desired and running replicas and failing tasks of each service": "Em managers do Swarm, hostname, papel, status e disponibilidade dos nos, e replicas desejadas e rodando e tasks com falha de cada servico",
+		"Repository, tag, registry digest, size and creation time of each local image":                                                          "Repositorio, tag, digest do registry, tamanho e data de criacao de cada imagem local",
+		"Application metrics": "Metricas das aplicacoes",
+		"Counters, gauges, timers and sets that local applications send to the StatsD listener": "Counters, gauges, timers e sets que as aplicacoes locais mandam ao listener StatsD",
+		"Cloud maintenance": "Manutencao na nuvem",
+		"Cloud provider name and the maintenance it has scheduled for this instance":                                                                             "Nome do provedor de nuvem e as manutencoes que ele agendou para esta instancia",
+		"The remote config keeps the plugins collector off on this host (collectors or the collector.plugins flag); the plugin will not run until that changes.": "A config remota mantem o coletor de plugins desligado neste host (collectors ou a flag collector.plugins); o plugin so roda quando isso mudar.",
+		"Host metadata": "Metadados do host",
+		"Owner, contact, runbook URL and criticality set in the config":                                                       "Dono, contato, URL do runbook e criticidade definidos no config",
+		"Machine token, agent version and commit, send time, collector error messages and the feature flags on for this host": "Token da maquina, versao e commit do agente, hora do envio, mensagens de erro dos coletores e as feature flags ligadas neste host",
+		"CPU usage":                             "Uso de CPU",
+		"CPU percentage and core count":         "Percentual de CPU e quantidade de nucleos",
+		"Memory usage":                          "Uso de memoria",
+		"Total, available and used memory":      "Memoria total, disponivel e usada",
+		"Root disk usage":                       "Uso do disco raiz",
+		"Size and usage of the root filesystem": "Tamanho e uso do sistema de arquivos raiz",
+		"Disks":                                 "Discos",
+		"Device names, mount points, filesystem types and usage of each disk": "Nomes de dispositivo, pontos de montagem, tipos de sistema de arquivos e uso de cada disco",
+		"Load average":                     "Carga media",
+		"1, 5 and 15 minute load averages": "Carga media de 1, 5 e 15 minutos",
+		"Host identity":                    "Identidade do host",
+		"Host name, machine ID, operating system, kernel, architecture, virtualization and uptime": "Nome do host, machine ID, sistema operacional, kernel, arquitetura, virtualizacao e uptime",
+		"Container inventory": "Inventario de containers",
+		"Container IDs, names, images, states, restart counts, start times, last exits, published ports, networks and IP addresses, healthcheck results, Compose project and service and vaultrix.* label notes, per-image and per-project totals, and the container runtimes found with their version, storage and cgroup drivers, container counts and warnings": "IDs, nomes, imagens, estados, reinicios, inicio, ultima saida, portas publicadas, redes e enderecos IP, resultado do healthcheck, projeto e servico do Compose e notas dos labels vaultrix.* dos containers, totais por imagem e por projeto e os runtimes encontrados com versao, drivers de storage e de cgroup, numero de containers e avisos",
+		"Kubernetes pods": "Pods do Kubernetes",
+		"Namespace, pod name and pod labels of each container": "Namespace, nome do pod e labels do pod de cada container",
+		"Container usage": "Uso dos containers",
+		"CPU, memory, network, disk I/O and process count of each container":   "CPU, memoria, rede, I/O de disco e quantidade de processos de cada container",
+		"Container network interfaces":                                         "Interfaces de rede dos containers",
+		"Interface names of each container and the host interface they map to": "Nomes das interfaces de cada container e a interface do host correspondente",
+		"systemd units": "Units do systemd",
+		"GPUs":          "GPUs",
+		"Model, utilization, memory, temperature and power of each NVIDIA or AMD GPU, and the processes using it with their command name and container": "Modelo, uso, memoria, temperatura e consumo de cada GPU NVIDIA ou AMD, e os processos que a usam com o nome do comando e o container",
+		"Hardware sensors": "Sensores de hardware",
+		"Temperatures, fan speeds and voltages read from the hardware monitoring chips, with their thresholds and status": "Temperaturas, rotacao das ventoinhas e tensoes lidas dos chips de monitoramento do hardware, com os limites e o estado",
+		"NVMe health": "Saude dos NVMe",
+		"Model, serial number and firmware of each NVMe drive with its wear, spare capacity, media errors, critical warnings and temperature": "Modelo, numero de serie e firmware de cada NVMe, com o desgaste, a reserva, os erros de midia, os avisos criticos e a temperatura",
+		"Software RAID": "RAID por software",
+		"State, level, size and member disks of each md array, whether it is degraded and the progress of any resync or rebuild": "Estado, nivel, tamanho e discos de cada array md, se esta degradado e o andamento de resync ou reconstrucao",
+		"ZFS pools": "Pools ZFS",
+		"Health, capacity, fragmentation, device errors and last scrub of each ZFS pool, and the usage and mount point of its largest datasets": "Saude, capacidade, fragmentacao, erros dos discos e ultimo scrub de cada pool ZFS, e o uso e o ponto de montagem dos maiores datasets",
+		"LVM volumes": "Volumes LVM",
+		"Size and free space of each LVM volume group, and the size, type, state and thin pool data and metadata usage of each logical volume": "Tamanho e espaco livre de cada volume group do LVM, e o tamanho, o tipo, o estado e o uso de dados e metadados dos thin pools de cada logical volume",
+		"Name, state, restart count and memory of the monitored units":                                                                         "Nome, estado, quantidade de reinicios e memoria das units acompanhadas",
+		"Endpoint checks": "Verificacoes de endpoints",
+		"Names, URLs and targets of the configured checks with their results and recent stability": "Nomes, URLs e alvos das verificacoes configuradas, com os resultados e a estabilidade recente",
+		"Plugins": "Plugins",
+		"Whatever each installed plugin reports, limited to its declared schema": "O que cada plugin instalado reporta, limitado ao schema declarado",
+		"Local alerts": "Alertas locais",
+		"Events":       "Eventos",
+		"Container starts, stops, exits with exit codes, restarts and OOM kills, check state changes and alert transitions since the previous send, with container, check and alert names": "Partidas, paradas, saidas com codigo de saida, reinicios e OOM kills de containers, mudancas de estado das verificacoes e transicoes dos alertas desde o envio anterior, com os nomes dos containers, verificacoes e alertas",
+		"Names, conditions and current values of firing alerts":                      "Nomes, condicoes e valores atuais dos alertas em disparo",
+		"payload, encrypted when api_url is https; targets of checks with use_proxy": "payload, criptografado quando a api_url e https; alvos das checks com use_proxy",
+		"payload, encrypted when api_url is https":                                   "payload, criptografado quando a api_url e https",
+		"host name, alert names, conditions and values":                              "nome do host, nomes, condicoes e valores dos alertas",
+		"alert webhook": "webhook de alertas",
+		"Directory or file with the payloads to resend":                                                  "Diretorio ou arquivo com os payloads a reenviar",
+		"API URL to send to (default: api_url from the config)":                                          "URL da API de destino (padrao: api_url do config)",
+		"Machine token (default: token from the config)":                                                 "Token da maquina (padrao: token do config)",
+		"Replay speed relative to the original pace (e.g. 1x, 10x) or max":                               "Velocidade em relacao ao ritmo original (ex.: 1x, 10x) ou max",
+		"usage: vaultrix-agent replay --from <dir> [--to <api-url>] [--token <token>] [--speed 10x|max]": "uso: vaultrix-agent replay --from <dir> [--to <api-url>] [--token <token>] [--speed 10x|max]",
+		"Replayed %d of %d payloads; stopped at %s.":                                                     "%d de %d payloads reenviados; parou em %s.",
+		"Replayed %d of %d payloads (%d skipped).":                                                       "%d de %d payloads reenviados (%d ignorados).",
+		"Collects machine metrics and sends them to Vaultrix.":                                           "Coleta metricas da maquina e envia para o Vaultrix.",
+		"use the full URL, e.g. https://vaultrix.example.com/api/telemetry":                              "use a URL completa, ex.: https://vaultrix.example.com/api/telemetry",
+		"fix proxy_url (http://, https:// or socks5://host:port)":                                        "corrija o proxy_url (http://, https:// ou socks5://host:porta)",
+		"check HTTP_PROXY/HTTPS_PROXY or proxy_url":                                                      "verifique HTTP_PROXY/HTTPS_PROXY ou o proxy_url",
+		"token rejected; generate a new machine token in the Vaultrix dashboard":                         "token recusado; gere um novo token da maquina no painel do Vaultrix",
+		"endpoint not found; api-url must end in /api/telemetry":                                         "endpoint nao encontrado; a api-url deve terminar em /api/telemetry",
+		"the API rejected the token probe; check api-url and the server logs":                            "a API recusou o probe do token; verifique a api-url e os logs do servidor",
+		"certificate issued by an unknown CA; install the CA on the system (ca-certificates)":            "certificado emitido por CA desconhecida; instale a CA no sistema (ca-certificates)",
+		"the certificate does not cover this host; use the name in the certificate":                      "o certificado nao cobre este host; use o nome que consta no certificado",
+		"certificate expired or not yet valid; check the machine clock (timedatectl)":                    "certificado expirado ou ainda nao valido; verifique o relogio da maquina (timedatectl)",
+		"TLS handshake failed; check that the port really serves HTTPS":                                  "falha no handshake TLS; verifique se a porta realmente serve HTTPS",
+		"could not resolve %s; check /etc/resolv.conf and the host name":                                 "nao foi possivel resolver %s; verifique /etc/resolv.conf e o nome do host",
+		"port %s on %s unreachable; check firewall, security groups or proxy":                            "porta %s de %s inacessivel; verifique firewall, security groups ou proxy",
+		"%s, %s, expires %s": "%s, %s, expira em %s",
+		"Release URL to fetch the build for this host's architecture on install": "URL de releases para baixar, na instalacao, o build da arquitetura desta maquina",
+		"Comma-separated ed25519 keys that sign the release manifest":            "Chaves ed25519, separadas por virgula, que assinam o manifest de releases",
+		"Installed the %s build %s from the release URL.":                        "Instalado o build %s %s da URL de releases.",
+		"token accepted": "token aceito",
+		"the API does not support probes; token not checked": "a API nao suporta probes; token nao conferido",
+		"%s unreachable: %s":              "%s inacessivel: %s",
+		"Version:       %s":               "Versao:        %s",
+		"Scheduler:     %s":               "Agendamento:   %s",
+		"Config:        %s (invalid: %s)": "Config:        %s (invalida: %s)",
+		"Last run:      never":            "Ultima coleta: nunca",
+		"Last run:      %s (%dms)":        "Ultima coleta: %s (%dms)",
+		"Last send:     ok":               "Ultimo envio:  ok",
+		"Last send:     failed: %s":       "Ultimo envio:  falhou: %s",
+		"Last send:     failed (%s): %s":  "Ultimo envio:  falhou (%s): %s",
+		"Spool:         %d pending":       "Spool:         %d pendente(s)",
+		"Alerts:        none":             "Alertas:       nenhum",
+		"Alerts:        %d active":        "Alertas:       %d ativo(s)",
+	},
+}
+
+// tr traduz msg para o idioma ativo.
+func tr(msg string) string {
+	if t, ok := messages[lang][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
+
+// initLang escolhe o idioma e remove --lang de args, ja que cada subcomando
+// tem seu proprio FlagSet. O --lang e lido antes dos flags porque as
+// descricoes dos flags tambem sao traduzidas.
+func initLang(args []string) []string {
+	chosen := os.Getenv("VAULTRIX_LANG")
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if i == 0 || !strings.HasPrefix(arg, "-") || name != "lang" {
+			out = append(out, arg)
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		chosen = value
+	}
+	lang = normalizeLang(chosen)
+	return out
+}
+
+func normalizeLang(value string) string {
+	switch strings.ToLower(strings.ReplaceAll(value, "_", "-")) {
+	case "pt", "pt-br":
+		return langPTBR
+	}
+	return langEN
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// influxSink envia as metricas em line protocol. A URL e a de escrita
+// completa: /api/v2/write?org=...&bucket=... no InfluxDB 2.x,
+// /write?db=... no 1.x ou o http_listener_v2 do Telegraf.
+type influxSink struct {
+	cfg  config.Config
+	conf config.SinkConfig
+}
+
+func (s influxSink) Name() string { return s.conf.NameOrDefault() }
+
+func (s influxSink) Send(payload Payload) error {
+	body := influxLines(s.cfg, payload)
+	if len(body) == 0 {
+		return nil
+	}
+	headers := s.conf.Headers
+	if s.conf.Token != "" {
+		headers = maps.Clone(s.conf.Headers)
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers["Authorization"] = "Token " + s.conf.Token
+	}
+	return postSink(s.cfg, "POST", s.conf.URL, "text/plain; charset=utf-8", headers, body)
+}
+
+func influxEscape(s, special string) string {
+	if !strings.ContainsAny(s, special+"\\") {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if r == '\\' || strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// influxLines escreve os pontos do payload, todos com o horario da coleta.
+func influxLines(cfg config.Config, payload Payload) []byte {
+	ts := strconv.FormatInt(payload.Timestamp.UnixNano(), 10)
+	var b strings.Builder
+	for _, p := range metricPoints(cfg, payload) {
+		b.WriteString(influxEscape(p.measurement, ", "))
+		for _, t := range p.tags {
+			b.WriteByte(',')
+			b.WriteString(influxEscape(t.key, ",= "))
+			b.WriteByte('=')
+			b.WriteString(influxEscape(t.value, ",= "))
+		}
+		for i, f := range p.fields {
+			if i == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(influxEscape(f.key, ",= "))
+			b.WriteByte('=')
+			if f.integer {
+				b.WriteString(strconv.FormatInt(int64(f.value), 10) + "i")
+			} else {
+				b.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+			}
+		}
+		b.WriteByte(' ')
+		b.WriteString(ts)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
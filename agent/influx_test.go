@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestInfluxEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"web01", "web01"},
+		{"my host", `my\ host`},
+		{"a,b=c", `a\,b\=c`},
+		{`C:\data`, `C:\\data`},
+	}
+	for _, tt := range tests {
+		if got := influxEscape(tt.in, ",= "); got != tt.want {
+			t.Errorf("influxEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInfluxLines(t *testing.T) {
+	payload := Payload{
+		Timestamp: time.Unix(1700000000, 0),
+		Host:      &HostInfo{Hostname: "web 01", MachineID: "abc"},
+		Metrics: Metrics{
+			CPUUsage: 12.5, CPUCores: 4, LoadAvg1: 0.5,
+			Disks: []DiskUsage{{Device: "/dev/sda1", MountPoint: "/data", FSType: "ext4", TotalGB: 100, UsedGB: 25, Percent: 25}},
+		},
+		Containers: []ContainerStatus{{ID: "c1", Name: "api", Image: "api:1", CPUPercent: 3, NetRxBytes: 10}},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		heart   bool
+		want    []string
+		missing []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				`vaultrix_host,host=web\ 01,machine_id=abc cpu=12.5,cpu_cores=4i,`,
+				`vaultrix_disk,host=web\ 01,machine_id=abc,device=/dev/sda1,mount_point=/data,fs_type=ext4 total_gb=100,used_gb=25,percent=25 1700000000000000000`,
+				`vaultrix_container,host=web\ 01,machine_id=abc,name=api,id=c1,image=api:1 cpuPercent=3,memPercent=0,netRxBytes=10i 1700000000000000000`,
+			},
+		},
+		{
+			name:    "field filter",
+			cfg:     config.Config{PayloadFields: config.FieldFilter{Deny: []string{"host.machine_id", "metrics.cpu", "containers.image"}}},
+			want:    []string{`vaultrix_host,host=web\ 01 cpu_cores=4i,`, `vaultrix_container,host=web\ 01,name=api,id=c1 `},
+			missing: []string{"machine_id=", " cpu=", "image="},
+		},
+		{
+			name:    "stats off",
+			cfg:     config.Config{Collectors: map[string]bool{config.CollectorDockerStats: false}},
+			missing: []string{"vaultrix_container"},
+		},
+		{
+			name:    "heartbeat",
+			heart:   true,
+			want:    []string{"vaultrix_container"},
+			missing: []string{"vaultrix_host"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := payload
+			p.Heartbeat = tt.heart
+			if tt.heart {
+				p.Metrics = Metrics{}
+			}
+			got := string(influxLines(tt.cfg, p))
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("missing %q in:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(got, s) {
+					t.Errorf("unexpected %q in:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInfluxSinkSend(t *testing.T) {
+	var body, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	conf := config.SinkConfig{Type: sinkInflux, URL: srv.URL + "/api/v2/write?org=a&bucket=b", Token: "secret"}
+	if err := (influxSink{config.Config{}, conf}).Send(Payload{Timestamp: time.Unix(1, 0), Metrics: Metrics{CPUUsage: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Token secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if !strings.HasPrefix(body, "vaultrix_host cpu=1,") {
+		t.Errorf("body = %q", body)
+	}
+}
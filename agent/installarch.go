@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Instalacao em frotas mistas: o script de bootstrap costuma levar um
+// binario so (em geral amd64) para todas as maquinas. Com --release-url, se
+// a arquitetura da maquina difere da do binario em execucao (que so roda
+// ali por emulacao ou modo de compatibilidade), o --install baixa do mesmo
+// manifest do self-update o build da maquina, com a assinatura e o sha256
+// conferidos, em vez de copiar a si mesmo.
+
+// unameArch traduz o "machine" do uname para o GOARCH correspondente; vazio
+// quando nao ha build do agente para ele.
+func unameArch(machine string) string {
+	switch strings.ToLower(machine) {
+	case "x86_64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64", "aarch64_be":
+		return "arm64"
+	case "armv7l", "armv7", "armv8l", "armhf":
+		return "arm"
+	case "i386", "i486", "i586", "i686", "x86":
+		return "386"
+	}
+	return ""
+}
+
+// releaseBinaryForHost devolve o binario publicado para a arquitetura da
+// maquina e a sua versao. Sem diferenca de arquitetura devolve nil: o
+// proprio binario e instalado.
+func releaseBinaryForHost(cfg config.Config) ([]byte, string, error) {
+	arch := hostArch()
+	if arch == "" || arch == runtime.GOARCH {
+		return nil, "", nil
+	}
+	if cfg.UpdateURL == "" {
+		fmt.Fprintf(os.Stderr, "install: this binary is built for %s but the host is %s; pass --release-url to install the matching build\n", runtime.GOARCH, arch)
+		return nil, "", nil
+	}
+
+	manifest, artifact, artifactURL, err := fetchRelease(cfg, arch)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := downloadRelease(cfg, artifact, artifactURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, manifest.Version, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnameArch(t *testing.T) {
+	tests := []struct {
+		machine string
+		want    string
+	}{
+		{"x86_64", "amd64"},
+		{"aarch64", "arm64"},
+		{"arm64", "arm64"},
+		{"armv7l", "arm"},
+		{"armv8l", "arm"},
+		{"i686", "386"},
+		{"riscv64", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := unameArch(tt.machine); got != tt.want {
+			t.Errorf("unameArch(%q) = %q, want %q", tt.machine, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// package collectors define o contrato dos coletores do agente e o registro
+// que os roda a cada ciclo: em paralelo, cada um com o seu prazo, com panic
+// virando erro. Quem monta o payload so registra onde cada resultado entra.
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Collector e uma fonte de dados do payload. A cada ciclo os coletores sao
+// criados a partir da config e rodam em paralelo, cada um com o seu prazo;
+// quem monta o payload nao precisa saber como cada um coleta.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context) (any, error)
+}
+
+// funcCollector adapta uma funcao a Collector.
+type funcCollector struct {
+	name string
+	fn   func(ctx context.Context) (any, error)
+}
+
+func (c funcCollector) Name() string { return c.name }
+
+func (c funcCollector) Collect(ctx context.Context) (any, error) { return c.fn(ctx) }
+
+// Func devolve um Collector chamado name que coleta com fn.
+func Func(name string, fn func(ctx context.Context) (any, error)) Collector {
+	return funcCollector{name, fn}
+}
+
+// Entry registra um coletor: quando ele roda, com que prazo e onde o
+// resultado entra no payload P. Prazo zero deixa o controle com o proprio
+// coletor (plugins, checks e docker tem prazos por item e reportam os seus
+// erros no resultado).
+type Entry[P any] struct {
+	Enabled func(cfg config.Config) bool
+	Timeout time.Duration
+	New     func(cfg config.Config) Collector
+	Apply   func(p P, v any, err error)
+}
+
+// Registry e a lista de coletores, na ordem em que os resultados sao
+// aplicados.
+type Registry[P any] []Entry[P]
+
+// Run roda os coletores ativos em paralelo e aplica os resultados a p, na
+// ordem do registro. report recebe o nome e o erro de cada coletor antes do
+// Apply, inclusive quando o erro e nil.
+func (r Registry[P]) Run(cfg config.Config, p P, report func(name string, err error)) {
+	type result struct {
+		value any
+		err   error
+	}
+	var active []Entry[P]
+	for _, e := range r {
+		if e.Enabled == nil || e.Enabled(cfg) {
+			active = append(active, e)
+		}
+	}
+
+	collectors := make([]Collector, len(active))
+	results := make([]result, len(active))
+	var wg sync.WaitGroup
+	for i, e := range active {
+		collectors[i] = e.New(cfg)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e.Timeout == 0 {
+				v, err := collectors[i].Collect(context.Background())
+				results[i] = result{v, err}
+				return
+			}
+			v, err := Run(cfg.CollectorTimeout(e.Timeout), collectors[i].Collect)
+			results[i] = result{v, err}
+		}()
+	}
+	wg.Wait()
+
+	for i, e := range active {
+		res := results[i]
+		report(collectors[i].Name(), res.err)
+		e.Apply(p, res.value, res.err)
+	}
+}
+
+// Run executa fn com um prazo proprio. Se fn ignorar o contexto e continuar
+// travada, o resultado e abandonado assim que o prazo expira.
+func Run[T any](timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			// um coletor com panic vira erro, nao derruba o agente
+			if p := recover(); p != nil {
+				var zero T
+				done <- result{zero, fmt.Errorf("panic: %v", p)}
+			}
+		}()
+		v, err := fn(ctx)
+		done <- result{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// ByName roda collect para cada item em paralelo e indexa os resultados
+// pelo nome. Cada item cuida do seu prazo e poe o seu erro no resultado,
+// para que um alvo fora do ar nao derrube os outros.
+func ByName[T, R any](items []T, name func(T) string, collect func(T) R) map[string]R {
+	results := make([]R, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = collect(item)
+		}()
+	}
+	wg.Wait()
+
+	byName := make(map[string]R, len(items))
+	for i, item := range items {
+		byName[name(item)] = results[i]
+	}
+	return byName
+}
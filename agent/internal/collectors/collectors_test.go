@@ -0,0 +1,66 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context) (string, error)
+		want    string
+		wantErr string
+	}{
+		{
+			name: "value",
+			fn:   func(ctx context.Context) (string, error) { return "ok", nil },
+			want: "ok",
+		},
+		{
+			name:    "error",
+			fn:      func(ctx context.Context) (string, error) { return "", errors.New("boom") },
+			wantErr: "boom",
+		},
+		{
+			name: "stuck past the deadline",
+			fn: func(ctx context.Context) (string, error) {
+				<-ctx.Done()
+				time.Sleep(50 * time.Millisecond)
+				return "late", nil
+			},
+			wantErr: context.DeadlineExceeded.Error(),
+		},
+		{
+			name:    "panic",
+			fn:      func(ctx context.Context) (string, error) { panic("oops") },
+			wantErr: "panic: oops",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Run(10*time.Millisecond, tt.fn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Run() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestByName(t *testing.T) {
+	got := ByName([]string{"a", "bb", "ccc"}, func(s string) string { return s }, func(s string) int { return len(s) })
+	want := map[string]int{"a": 1, "bb": 2, "ccc": 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ByName() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,345 @@
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	GPUTimeout = 10 * time.Second
+
+	// maxGPUProcesses limita os processos por placa, os que mais usam
+	// memoria primeiro.
+	maxGPUProcesses = 20
+)
+
+// GPUInfo e uma placa NVIDIA (nvidia-smi) ou AMD (rocm-smi). Os campos
+// ponteiro ficam de fora quando a placa nao os informa: placas sem
+// ventoinha, ou de consumo sem leitura de energia.
+type GPUInfo struct {
+	Index  int    `json:"index"`
+	Vendor string `json:"vendor"`
+	Name   string `json:"name"`
+	UUID   string `json:"uuid,omitempty"`
+
+	UtilizationPercent float64 `json:"utilizationPercent"`
+	MemoryUsedBytes    int64   `json:"memoryUsedBytes"`
+	MemoryTotalBytes   int64   `json:"memoryTotalBytes"`
+	MemoryUsedPercent  float64 `json:"memoryUsedPercent"`
+
+	TemperatureCelsius *float64 `json:"temperatureCelsius,omitempty"`
+	PowerWatts         *float64 `json:"powerWatts,omitempty"`
+	PowerLimitWatts    *float64 `json:"powerLimitWatts,omitempty"`
+	FanPercent         *float64 `json:"fanPercent,omitempty"`
+
+	Processes []GPUProcess `json:"processes,omitempty"`
+}
+
+// GPUProcess e um processo com memoria na placa. Container e o ID curto do
+// container do processo, para cruzar com containers no servidor.
+type GPUProcess struct {
+	PID             int    `json:"pid"`
+	Name            string `json:"name"`
+	MemoryUsedBytes int64  `json:"memoryUsedBytes"`
+	Container       string `json:"container,omitempty"`
+}
+
+// GPUs le as placas NVIDIA e AMD do host. Sem nvidia-smi nem
+// rocm-smi no PATH nao ha nada a coletar e nao e erro; um driver que nao
+// responde e.
+func GPUs(ctx context.Context) ([]GPUInfo, error) {
+	var gpus []GPUInfo
+	var firstErr error
+	for _, vendor := range []struct {
+		tool    string
+		collect func(context.Context) ([]GPUInfo, error)
+	}{
+		{"nvidia-smi", collectNvidiaGPUs},
+		{"rocm-smi", collectAMDGPUs},
+	} {
+		if _, err := exec.LookPath(vendor.tool); err != nil {
+			continue
+		}
+		found, err := vendor.collect(ctx)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", vendor.tool, err)
+		}
+		gpus = append(gpus, found...)
+	}
+	return gpus, firstErr
+}
+
+const (
+	nvidiaGPUQuery     = "index,uuid,name,utilization.gpu,memory.used,memory.total,temperature.gpu,power.draw,power.limit,fan.speed"
+	nvidiaProcessQuery = "gpu_uuid,pid,process_name,used_memory"
+)
+
+func collectNvidiaGPUs(ctx context.Context) ([]GPUInfo, error) {
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu="+nvidiaGPUQuery, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, gpuCommandError(out, err)
+	}
+	gpus, err := parseNvidiaGPUs(out)
+	if err != nil || len(gpus) == 0 {
+		return gpus, err
+	}
+	// sem processos as placas ainda valem; o erro vai junto
+	out, err = exec.CommandContext(ctx, "nvidia-smi", "--query-compute-apps="+nvidiaProcessQuery, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return gpus, gpuCommandError(out, err)
+	}
+	procs, err := parseNvidiaProcesses(out)
+	for i := range gpus {
+		gpus[i].Processes = gpuProcesses(procs[gpus[i].UUID])
+	}
+	return gpus, err
+}
+
+// parseNvidiaGPUs le o CSV do --query-gpu em MiB, watts e porcentagens.
+// Campos que a placa nao suporta vem como "[N/A]" ou "[Not Supported]".
+func parseNvidiaGPUs(out []byte) ([]GPUInfo, error) {
+	records, err := readNvidiaCSV(out, len(strings.Split(nvidiaGPUQuery, ",")))
+	if err != nil {
+		return nil, err
+	}
+	gpus := make([]GPUInfo, 0, len(records))
+	for _, r := range records {
+		index, err := strconv.Atoi(r[0])
+		if err != nil {
+			continue
+		}
+		g := GPUInfo{Index: index, Vendor: "nvidia", UUID: r[1], Name: r[2]}
+		g.UtilizationPercent, _ = gpuValue(r[3])
+		used, _ := gpuValue(r[4])
+		total, _ := gpuValue(r[5])
+		g.MemoryUsedBytes, g.MemoryTotalBytes = int64(used)<<20, int64(total)<<20
+		g.MemoryUsedPercent = PercentOf(g.MemoryUsedBytes, g.MemoryTotalBytes)
+		g.TemperatureCelsius = ParseOptionalFloat(r[6])
+		g.PowerWatts = ParseOptionalFloat(r[7])
+		g.PowerLimitWatts = ParseOptionalFloat(r[8])
+		g.FanPercent = ParseOptionalFloat(r[9])
+		gpus = append(gpus, g)
+	}
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus, nil
+}
+
+// parseNvidiaProcesses agrupa os processos do --query-compute-apps pelo
+// UUID da placa.
+func parseNvidiaProcesses(out []byte) (map[string][]GPUProcess, error) {
+	records, err := readNvidiaCSV(out, len(strings.Split(nvidiaProcessQuery, ",")))
+	if err != nil {
+		return nil, err
+	}
+	procs := make(map[string][]GPUProcess)
+	for _, r := range records {
+		pid, err := strconv.Atoi(r[1])
+		if err != nil {
+			continue
+		}
+		used, _ := gpuValue(r[3])
+		procs[r[0]] = append(procs[r[0]], GPUProcess{PID: pid, Name: r[2], MemoryUsedBytes: int64(used) << 20})
+	}
+	return procs, nil
+}
+
+func readNvidiaCSV(out []byte, fields int) ([][]string, error) {
+	// sem processos o nvidia-smi imprime so uma linha em branco
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	r := csv.NewReader(bytes.NewReader(out))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = fields
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unexpected output: %w", err)
+	}
+	return records, nil
+}
+
+func collectAMDGPUs(ctx context.Context) ([]GPUInfo, error) {
+	out, err := exec.CommandContext(ctx, "rocm-smi", "--showuniqueid", "--showproductname", "--showuse",
+		"--showmeminfo", "vram", "--showtemp", "--showpower", "--showfan", "--json").Output()
+	if err != nil {
+		return nil, gpuCommandError(out, err)
+	}
+	gpus, err := parseROCmGPUs(out)
+	// o --showpids nao diz em qual placa esta cada processo; so com uma
+	// placa da para atribuir
+	if err != nil || len(gpus) != 1 {
+		return gpus, err
+	}
+	out, err = exec.CommandContext(ctx, "rocm-smi", "--showpids", "--json").Output()
+	if err != nil {
+		return gpus, gpuCommandError(out, err)
+	}
+	gpus[0].Processes = gpuProcesses(parseROCmProcesses(out))
+	return gpus, nil
+}
+
+// parseROCmGPUs le o --json do rocm-smi: um objeto por placa ("card0"), com
+// valores em texto cujos nomes mudam entre versoes do ROCm, por isso os
+// nomes alternativos e a busca sem caixa.
+func parseROCmGPUs(out []byte) ([]GPUInfo, error) {
+	cards, err := parseROCmJSON(out)
+	if err != nil {
+		return nil, err
+	}
+	var gpus []GPUInfo
+	for card, fields := range cards {
+		index, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil || !strings.HasPrefix(card, "card") {
+			continue
+		}
+		g := GPUInfo{Index: index, Vendor: "amd", UUID: rocmField(fields, "Unique ID")}
+		g.Name = rocmFirstField(fields, "Card Series", "Card Model")
+		g.UtilizationPercent, _ = gpuValue(rocmField(fields, "GPU use (%)"))
+		used, _ := gpuValue(rocmField(fields, "VRAM Total Used Memory (B)"))
+		total, _ := gpuValue(rocmField(fields, "VRAM Total Memory (B)"))
+		g.MemoryUsedBytes, g.MemoryTotalBytes = int64(used), int64(total)
+		g.MemoryUsedPercent = PercentOf(g.MemoryUsedBytes, g.MemoryTotalBytes)
+		g.TemperatureCelsius = ParseOptionalFloat(rocmFirstField(fields, "Temperature (Sensor edge) (C)", "Temperature (Sensor junction) (C)"))
+		g.PowerWatts = ParseOptionalFloat(rocmFirstField(fields, "Average Graphics Package Power (W)", "Current Socket Graphics Package Power (W)"))
+		g.FanPercent = ParseOptionalFloat(rocmField(fields, "Fan speed (%)"))
+		gpus = append(gpus, g)
+	}
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus, nil
+}
+
+// parseROCmProcesses le o --showpids --json: {"system": {"PID123": "nome,
+// placas, VRAM em bytes, SDMA, ocupacao"}}.
+func parseROCmProcesses(out []byte) []GPUProcess {
+	cards, err := parseROCmJSON(out)
+	if err != nil {
+		return nil
+	}
+	var procs []GPUProcess
+	for key, value := range cards["system"] {
+		pid, err := strconv.Atoi(strings.TrimPrefix(key, "PID"))
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(value, ",")
+		p := GPUProcess{PID: pid, Name: strings.TrimSpace(parts[0])}
+		if len(parts) > 2 {
+			used, _ := gpuValue(parts[2])
+			p.MemoryUsedBytes = int64(used)
+		}
+		procs = append(procs, p)
+	}
+	return procs
+}
+
+// parseROCmJSON tolera os avisos que o rocm-smi imprime antes do JSON e os
+// valores numericos que algumas versoes mandam sem aspas.
+func parseROCmJSON(out []byte) (map[string]map[string]string, error) {
+	start := bytes.IndexByte(out, '{')
+	if start < 0 {
+		return nil, errors.New("unexpected output: no JSON")
+	}
+	var raw map[string]map[string]any
+	if err := json.Unmarshal(out[start:], &raw); err != nil {
+		return nil, fmt.Errorf("unexpected output: %w", err)
+	}
+	cards := make(map[string]map[string]string, len(raw))
+	for card, fields := range raw {
+		cards[card] = make(map[string]string, len(fields))
+		for k, v := range fields {
+			cards[card][k] = fmt.Sprint(v)
+		}
+	}
+	return cards, nil
+}
+
+func rocmField(fields map[string]string, name string) string {
+	for k, v := range fields {
+		if strings.EqualFold(k, name) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func rocmFirstField(fields map[string]string, names ...string) string {
+	for _, name := range names {
+		if v := rocmField(fields, name); v != "" && v != "N/A" {
+			return v
+		}
+	}
+	return ""
+}
+
+// gpuProcesses ordena pelo uso de memoria, corta em maxGPUProcesses e acha o
+// container de cada processo.
+func gpuProcesses(procs []GPUProcess) []GPUProcess {
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].MemoryUsedBytes != procs[j].MemoryUsedBytes {
+			return procs[i].MemoryUsedBytes > procs[j].MemoryUsedBytes
+		}
+		return procs[i].PID < procs[j].PID
+	})
+	if len(procs) > maxGPUProcesses {
+		procs = procs[:maxGPUProcesses]
+	}
+	for i := range procs {
+		procs[i].Container = containerIDForPID(procs[i].PID)
+	}
+	return procs
+}
+
+// cgroupContainerID pega o ID de 64 caracteres no caminho do cgroup, igual
+// no docker (/docker/<id>, docker-<id>.scope), no podman (libpod-<id>) e no
+// containerd.
+var cgroupContainerID = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// containerIDForPID le o /proc/<pid>/cgroup; fora do Linux, ou com o
+// agente num container sem o PID namespace do host, fica vazio.
+func containerIDForPID(pid int) string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	return containerIDFromCgroup(string(data))
+}
+
+func containerIDFromCgroup(cgroup string) string {
+	ids := cgroupContainerID.FindAllString(cgroup, -1)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ShortContainerID(ids[len(ids)-1])
+}
+
+// gpuValue aceita "87", "250.12" e os "[N/A]" das placas que nao informam.
+func gpuValue(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v, err == nil
+}
+
+// gpuCommandError prefere o stderr; o nvidia-smi escreve as falhas do driver
+// ("couldn't communicate with the NVIDIA driver") no stdout.
+func gpuCommandError(out []byte, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		for _, msg := range [][]byte{exitErr.Stderr, out} {
+			if line, _, _ := strings.Cut(strings.TrimSpace(string(msg)), "\n"); line != "" {
+				return errors.New(line)
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package collectors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNvidiaGPUs(t *testing.T) {
+	out := "0, GPU-5f1c0e2a, NVIDIA A100-SXM4-40GB, 87, 30000, 40960, 65, 250.12, 400.00, [N/A]\n" +
+		"1, GPU-9b2d7c11, NVIDIA GeForce RTX 3090, 3, 512, 24576, 41, [Not Supported], [Not Supported], 30\n"
+	got, err := parseNvidiaGPUs([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GPUInfo{
+		{Index: 0, Vendor: "nvidia", Name: "NVIDIA A100-SXM4-40GB", UUID: "GPU-5f1c0e2a", UtilizationPercent: 87,
+			MemoryUsedBytes: 30000 << 20, MemoryTotalBytes: 40960 << 20, MemoryUsedPercent: 73.24,
+			TemperatureCelsius: ptrFloat(65), PowerWatts: ptrFloat(250.12), PowerLimitWatts: ptrFloat(400)},
+		{Index: 1, Vendor: "nvidia", Name: "NVIDIA GeForce RTX 3090", UUID: "GPU-9b2d7c11", UtilizationPercent: 3,
+			MemoryUsedBytes: 512 << 20, MemoryTotalBytes: 24576 << 20, MemoryUsedPercent: 2.08,
+			TemperatureCelsius: ptrFloat(41), FanPercent: ptrFloat(30)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := parseNvidiaGPUs([]byte("NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver.")); err == nil {
+		t.Error("want an error for unexpected output")
+	}
+}
+
+func TestParseNvidiaProcesses(t *testing.T) {
+	out := "GPU-5f1c0e2a, 4242, /usr/bin/python3, 20000\nGPU-5f1c0e2a, 77, ffmpeg, 300\nGPU-9b2d7c11, 900, [N/A], [N/A]\n"
+	got, err := parseNvidiaProcesses([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]GPUProcess{
+		"GPU-5f1c0e2a": {{PID: 4242, Name: "/usr/bin/python3", MemoryUsedBytes: 20000 << 20}, {PID: 77, Name: "ffmpeg", MemoryUsedBytes: 300 << 20}},
+		"GPU-9b2d7c11": {{PID: 900, Name: "[N/A]"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, err := parseNvidiaProcesses([]byte("\n")); err != nil || len(got) != 0 {
+		t.Errorf("no processes: got %v, %v", got, err)
+	}
+}
+
+func TestParseROCmGPUs(t *testing.T) {
+	out := `WARNING: AMD GPU device(s) is/are in a low-power state. Check power control/runtime_status
+
+{"card1": {"Unique ID": "0x2f4b8d91c1a0e3d7", "Card Series": "Radeon RX 7900 XTX", "GPU use (%)": "0",
+  "VRAM Total Memory (B)": "25753026560", "VRAM Total Used Memory (B)": "0", "Temperature (Sensor junction) (C)": "38.0"},
+ "card0": {"Unique ID": "0x18a6d34e0b9f5c22", "Card series": "Instinct MI210", "Card model": "0x740f", "GPU use (%)": 42,
+  "VRAM Total Memory (B)": "68702699520", "VRAM Total Used Memory (B)": "17175674880",
+  "Temperature (Sensor edge) (C)": "51.0", "Temperature (Sensor junction) (C)": "58.0",
+  "Average Graphics Package Power (W)": "183.0", "Fan speed (%)": "N/A"},
+ "system": {"Driver version": "6.7.0"}}`
+	got, err := parseROCmGPUs([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GPUInfo{
+		{Index: 0, Vendor: "amd", Name: "Instinct MI210", UUID: "0x18a6d34e0b9f5c22", UtilizationPercent: 42,
+			MemoryUsedBytes: 17175674880, MemoryTotalBytes: 68702699520, MemoryUsedPercent: 25,
+			TemperatureCelsius: ptrFloat(51), PowerWatts: ptrFloat(183)},
+		{Index: 1, Vendor: "amd", Name: "Radeon RX 7900 XTX", UUID: "0x2f4b8d91c1a0e3d7",
+			MemoryTotalBytes: 25753026560, TemperatureCelsius: ptrFloat(38)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := parseROCmGPUs([]byte("ERROR: GPU[0] : Unable to open device")); err == nil {
+		t.Error("want an error without JSON")
+	}
+}
+
+func TestParseROCmProcesses(t *testing.T) {
+	out := `{"system": {"PID31337": "python3, 1, 4294967296, 0, 0", "PID12": "ollama, 1, 1073741824, 0, 0", "Driver": "x"}}`
+	got := parseROCmProcesses([]byte(out))
+	got = gpuProcesses(got)
+	for i := range got {
+		got[i].Container = ""
+	}
+	want := []GPUProcess{
+		{PID: 31337, Name: "python3", MemoryUsedBytes: 4 << 30},
+		{PID: 12, Name: "ollama", MemoryUsedBytes: 1 << 30},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestContainerIDFromCgroup(t *testing.T) {
+	id := "4f3c2a1b0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b"
+	tests := []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{"docker v1", "12:memory:/docker/" + id + "\n", "4f3c2a1b0e9d"},
+		{"docker v2 systemd", "0::/system.slice/docker-" + id + ".scope\n", "4f3c2a1b0e9d"},
+		{"podman", "0::/machine.slice/libpod-" + id + ".scope/container\n", "4f3c2a1b0e9d"},
+		{"host process", "0::/user.slice/user-1000.slice/session-3.scope\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerIDFromCgroup(tt.cgroup); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptrFloat(f float64) *float64 { return &f }
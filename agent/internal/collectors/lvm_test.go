@@ -0,0 +1,57 @@
+package collectors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLVMVolumeGroups(t *testing.T) {
+	out := `  {
+      "report": [
+          {
+              "vg": [
+                  {"vg_name":"ubuntu-vg", "vg_size":"511570886656", "vg_free":"0", "pv_count":"1", "lv_count":"2"},
+                  {"vg_name":"data", "vg_size":"2000381018112", "vg_free":"500095254528", "pv_count":"2", "lv_count":"4"}
+              ]
+          }
+      ]
+  }`
+	got, err := parseLVMVolumeGroups([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LVMVolumeGroup{
+		{Name: "data", SizeBytes: 2000381018112, FreeBytes: 500095254528, UsedPercent: 75, PVCount: 2, LVCount: 4},
+		{Name: "ubuntu-vg", SizeBytes: 511570886656, UsedPercent: 100, PVCount: 1, LVCount: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := parseLVMVolumeGroups([]byte("  Unrecognised field: vg_foo")); err == nil {
+		t.Error("want an error for non-JSON output")
+	}
+}
+
+func TestParseLVMLogicalVolumes(t *testing.T) {
+	out := `{"report": [{"lv": [
+		{"vg_name":"data", "lv_name":"thinpool", "lv_attr":"twi-aotz--", "lv_size":"1099511627776", "segtype":"thin-pool", "pool_lv":"", "origin":"", "data_percent":"91.27", "metadata_percent":"12.50"},
+		{"vg_name":"data", "lv_name":"app", "lv_attr":"Vwi-aotz--", "lv_size":"214748364800", "segtype":"thin", "pool_lv":"thinpool", "origin":"", "data_percent":"64.02", "metadata_percent":""},
+		{"vg_name":"data", "lv_name":"app-snap", "lv_attr":"Vwi---tz-k", "lv_size":"214748364800", "segtype":"thin", "pool_lv":"thinpool", "origin":"app", "data_percent":"", "metadata_percent":""},
+		{"vg_name":"ubuntu-vg", "lv_name":"root", "lv_attr":"-wi-ao----", "lv_size":"510486626304", "segtype":"linear", "pool_lv":"", "origin":"", "data_percent":"", "metadata_percent":""}
+	]}]}`
+	got, err := parseLVMLogicalVolumes([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LVMLogicalVolume{
+		{Name: "app", VolumeGroup: "data", Type: "thin", Attr: "Vwi-aotz--", Active: true, SizeBytes: 214748364800, Pool: "thinpool", DataPercent: ptrFloat(64.02)},
+		{Name: "app-snap", VolumeGroup: "data", Type: "thin", Attr: "Vwi---tz-k", SizeBytes: 214748364800, Pool: "thinpool", Origin: "app"},
+		{Name: "thinpool", VolumeGroup: "data", Type: "thin-pool", Attr: "twi-aotz--", Active: true, SizeBytes: 1099511627776,
+			DataPercent: ptrFloat(91.27), MetadataPercent: ptrFloat(12.5)},
+		{Name: "root", VolumeGroup: "ubuntu-vg", Type: "linear", Attr: "-wi-ao----", Active: true, SizeBytes: 510486626304},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
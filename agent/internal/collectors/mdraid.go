@@ -0,0 +1,174 @@
+package collectors
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	MDRaidTimeout = 5 * time.Second
+
+	// mdstatPath nao tem namespace: dentro de um container mostra os arrays
+	// do host.
+	mdstatPath = "/proc/mdstat"
+)
+
+// MDArray e um array do RAID por software do Linux (md), como o mdadm o
+// ve. Degraded vale para um array com menos discos ativos que o nivel pede,
+// mesmo que ainda funcione: um RAID1 com um disco so nao aguenta mais
+// nenhuma falha.
+type MDArray struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+	Level     string `json:"level,omitempty"`
+	SizeBytes int64  `json:"sizeBytes,omitempty"`
+
+	// RaidDisks e ActiveDisks sao o [n/m] do mdstat e Status o [UU_], um
+	// caractere por disco; raid0 e linear nao os tem.
+	RaidDisks   int    `json:"raidDisks,omitempty"`
+	ActiveDisks int    `json:"activeDisks,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Degraded    bool   `json:"degraded"`
+
+	Devices []MDDevice `json:"devices"`
+
+	// SyncAction e resync, recovery (reconstrucao apos trocar um disco),
+	// reshape ou check; SyncPending marca um resync ainda na fila.
+	SyncAction        string   `json:"syncAction,omitempty"`
+	SyncPending       bool     `json:"syncPending,omitempty"`
+	SyncPercent       *float64 `json:"syncPercent,omitempty"`
+	SyncFinishMinutes *float64 `json:"syncFinishMinutes,omitempty"`
+	SyncSpeedKBps     int64    `json:"syncSpeedKBps,omitempty"`
+
+	// ArrayState e MismatchCount vem do sysfs (md/array_state e
+	// md/mismatch_cnt); o mismatch e o que o ultimo check achou.
+	ArrayState    string `json:"arrayState,omitempty"`
+	MismatchCount *int64 `json:"mismatchCount,omitempty"`
+}
+
+// MDDevice e um membro do array; Failed e o (F) e Spare o (S) do mdstat.
+type MDDevice struct {
+	Name   string `json:"name"`
+	Role   int    `json:"role"`
+	Failed bool   `json:"failed,omitempty"`
+	Spare  bool   `json:"spare,omitempty"`
+}
+
+// MDRaid le o /proc/mdstat e completa cada array com o sysfs, que
+// traz o mesmo que o "mdadm --detail" sem exigir root. Sem md no kernel, ou
+// fora do Linux, nao ha arrays.
+func MDRaid() []MDArray {
+	f, err := os.Open(mdstatPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	arrays := parseMDStat(f)
+	for i := range arrays {
+		addMDSysfs(&arrays[i], filepath.Join("/sys/block", arrays[i].Name, "md"))
+	}
+	return arrays
+}
+
+var (
+	mdArrayLine  = regexp.MustCompile(`^(md\S+)\s*:\s*(\S+)\s*(.*)$`)
+	mdDevice     = regexp.MustCompile(`^(\S+)\[(\d+)\]((?:\([A-Z]\))*)$`)
+	mdDiskStatus = regexp.MustCompile(`\[(\d+)/(\d+)\]\s+\[([U_]+)\]`)
+	mdBlocks     = regexp.MustCompile(`^(\d+) blocks`)
+	mdSync       = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*([\d.]+)%.*?(?:finish=([\d.]+)min)?\s*(?:speed=(\d+)K/sec)?$`)
+	mdSyncQueued = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*(PENDING|DELAYED)`)
+)
+
+// parseMDStat le o formato do /proc/mdstat: uma linha "md0 : active raid1
+// sdb1[1] sda1[0]" por array, seguida de linhas recuadas com o tamanho, o
+// [n/m] [UU] e o progresso de resync ou reconstrucao.
+func parseMDStat(r io.Reader) []MDArray {
+	var arrays []MDArray
+	var cur *MDArray
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := mdArrayLine.FindStringSubmatch(line); m != nil {
+			arrays = append(arrays, parseMDArrayLine(m[1], m[2], m[3]))
+			cur = &arrays[len(arrays)-1]
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if cur == nil || trimmed == "" || line[0] != ' ' && line[0] != '\t' {
+			cur = nil
+			continue
+		}
+		if m := mdBlocks.FindStringSubmatch(trimmed); m != nil {
+			blocks, _ := strconv.ParseInt(m[1], 10, 64)
+			// blocos de 1 KiB
+			cur.SizeBytes = blocks * 1024
+		}
+		if m := mdDiskStatus.FindStringSubmatch(trimmed); m != nil {
+			cur.RaidDisks, _ = strconv.Atoi(m[1])
+			cur.ActiveDisks, _ = strconv.Atoi(m[2])
+			cur.Status = m[3]
+			cur.Degraded = cur.ActiveDisks < cur.RaidDisks
+		}
+		if m := mdSync.FindStringSubmatch(trimmed); m != nil {
+			cur.SyncAction = m[1]
+			cur.SyncPercent = ParseOptionalFloat(m[2])
+			cur.SyncFinishMinutes = ParseOptionalFloat(m[3])
+			cur.SyncSpeedKBps, _ = strconv.ParseInt(m[4], 10, 64)
+		} else if m := mdSyncQueued.FindStringSubmatch(trimmed); m != nil {
+			cur.SyncAction, cur.SyncPending = m[1], true
+		}
+	}
+	sort.Slice(arrays, func(i, j int) bool { return CompareNatural(arrays[i].Name, arrays[j].Name) < 0 })
+	return arrays
+}
+
+// parseMDArrayLine le "active raid1 sdb1[1] sda1[0](F)"; o "(auto-read-only)"
+// vem entre o estado e o nivel, e arrays inativos nao tem nivel.
+func parseMDArrayLine(name, state, rest string) MDArray {
+	a := MDArray{Name: name, State: state, Devices: []MDDevice{}}
+	for _, field := range strings.Fields(rest) {
+		switch {
+		case strings.HasPrefix(field, "(") && strings.HasSuffix(field, ")"):
+			a.ReadOnly = a.ReadOnly || strings.Contains(field, "read-only")
+		case mdDevice.MatchString(field):
+			m := mdDevice.FindStringSubmatch(field)
+			role, _ := strconv.Atoi(m[2])
+			a.Devices = append(a.Devices, MDDevice{
+				Name:   m[1],
+				Role:   role,
+				Failed: strings.Contains(m[3], "(F)"),
+				Spare:  strings.Contains(m[3], "(S)"),
+			})
+		case a.Level == "" && len(a.Devices) == 0:
+			a.Level = field
+		}
+	}
+	sort.Slice(a.Devices, func(i, j int) bool { return a.Devices[i].Role < a.Devices[j].Role })
+	return a
+}
+
+// addMDSysfs completa o array com md/array_state, md/degraded,
+// md/sync_action e md/mismatch_cnt, quando o sysfs esta visivel.
+func addMDSysfs(a *MDArray, dir string) {
+	a.ArrayState = fsutil.ReadTrimmed(filepath.Join(dir, "array_state"))
+	if n := fsutil.ReadTrimmed(filepath.Join(dir, "degraded")); n != "" && n != "0" {
+		a.Degraded = true
+	}
+	if action := fsutil.ReadTrimmed(filepath.Join(dir, "sync_action")); action != "" && action != "idle" && a.SyncAction == "" {
+		a.SyncAction = action
+	}
+	if raw := fsutil.ReadTrimmed(filepath.Join(dir, "mismatch_cnt")); raw != "" {
+		mismatches := ParseInt64(raw)
+		a.MismatchCount = &mismatches
+	}
+}
@@ -0,0 +1,81 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMDStat = `Personalities : [raid1] [raid6] [raid5] [raid4] [raid0]
+md127 : active (auto-read-only) raid1 sdg1[1] sdf1[0]
+      1048512 blocks super 1.2 [2/2] [UU]
+
+md1 : active raid5 sdd1[3] sdc1[1] sdb2[0](F)
+      1953260544 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [U_U]
+      [=>...................]  recovery =  8.5% (83095552/976630272) finish=92.3min speed=161285K/sec
+      bitmap: 2/8 pages [8KB], 65536KB chunk
+
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/1] [U_]
+      	resync=DELAYED
+
+md10 : active raid0 sdh1[1] sdi1[0]
+      1953260544 blocks super 1.2 512k chunks
+
+md2 : inactive sde1[0](S)
+      976630464 blocks super 1.2
+
+unused devices: <none>
+`
+
+func TestParseMDStat(t *testing.T) {
+	got := parseMDStat(strings.NewReader(testMDStat))
+	want := []MDArray{
+		{Name: "md0", State: "active", Level: "raid1", SizeBytes: 976630464 * 1024,
+			RaidDisks: 2, ActiveDisks: 1, Status: "U_", Degraded: true,
+			Devices:    []MDDevice{{Name: "sda1", Role: 0}, {Name: "sdb1", Role: 1}},
+			SyncAction: "resync", SyncPending: true},
+		{Name: "md1", State: "active", Level: "raid5", SizeBytes: 1953260544 * 1024,
+			RaidDisks: 3, ActiveDisks: 2, Status: "U_U", Degraded: true,
+			Devices:    []MDDevice{{Name: "sdb2", Role: 0, Failed: true}, {Name: "sdc1", Role: 1}, {Name: "sdd1", Role: 3}},
+			SyncAction: "recovery", SyncPercent: ptrFloat(8.5), SyncFinishMinutes: ptrFloat(92.3), SyncSpeedKBps: 161285},
+		{Name: "md2", State: "inactive", SizeBytes: 976630464 * 1024,
+			Devices: []MDDevice{{Name: "sde1", Role: 0, Spare: true}}},
+		{Name: "md10", State: "active", Level: "raid0", SizeBytes: 1953260544 * 1024,
+			Devices: []MDDevice{{Name: "sdi1", Role: 0}, {Name: "sdh1", Role: 1}}},
+		{Name: "md127", State: "active", ReadOnly: true, Level: "raid1", SizeBytes: 1048512 * 1024,
+			RaidDisks: 2, ActiveDisks: 2, Status: "UU",
+			Devices: []MDDevice{{Name: "sdf1", Role: 0}, {Name: "sdg1", Role: 1}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if got := parseMDStat(strings.NewReader("Personalities : \nunused devices: <none>\n")); len(got) != 0 {
+		t.Errorf("no arrays: got %+v", got)
+	}
+}
+
+func TestAddMDSysfs(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"array_state": "clean", "degraded": "1", "sync_action": "check", "mismatch_cnt": "128"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := MDArray{Name: "md0", RaidDisks: 2, ActiveDisks: 2}
+	addMDSysfs(&a, dir)
+	mismatches := int64(128)
+	want := MDArray{Name: "md0", RaidDisks: 2, ActiveDisks: 2, Degraded: true, SyncAction: "check", ArrayState: "clean", MismatchCount: &mismatches}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("got %+v, want %+v", a, want)
+	}
+
+	a = MDArray{Name: "md9"}
+	addMDSysfs(&a, filepath.Join(dir, "missing"))
+	if !reflect.DeepEqual(a, MDArray{Name: "md9"}) {
+		t.Errorf("no sysfs: got %+v", a)
+	}
+}
@@ -0,0 +1,148 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	NVMeTimeout = 10 * time.Second
+
+	// nvmeClassRoot lista os controladores NVMe; fora do Linux nao existe.
+	nvmeClassRoot = "/sys/class/nvme"
+
+	// nvmeSmartLogSize e o tamanho do log page 02h (SMART / Health).
+	nvmeSmartLogSize = 512
+
+	// nvmeDataUnit e a unidade de Data Units Read/Written: mil setores de
+	// 512 bytes.
+	nvmeDataUnit = 512 * 1000
+)
+
+// NVMeHealth e o log page SMART / Health de um controlador NVMe, lido pelo
+// comando de administracao Get Log Page e nao pela tabela SMART generica dos
+// discos SATA.
+type NVMeHealth struct {
+	Device   string `json:"device"`
+	Model    string `json:"model,omitempty"`
+	Serial   string `json:"serial,omitempty"`
+	Firmware string `json:"firmware,omitempty"`
+
+	// CriticalWarning e o byte de avisos do controlador; CriticalWarnings
+	// traz o nome de cada bit ligado.
+	CriticalWarning  int      `json:"criticalWarning"`
+	CriticalWarnings []string `json:"criticalWarnings,omitempty"`
+
+	TemperatureCelsius             int    `json:"temperatureCelsius"`
+	AvailableSparePercent          int    `json:"availableSparePercent"`
+	AvailableSpareThresholdPercent int    `json:"availableSpareThresholdPercent"`
+	PercentageUsed                 int    `json:"percentageUsed"`
+	DataReadBytes                  uint64 `json:"dataReadBytes"`
+	DataWrittenBytes               uint64 `json:"dataWrittenBytes"`
+	PowerCycles                    uint64 `json:"powerCycles"`
+	PowerOnHours                   uint64 `json:"powerOnHours"`
+	UnsafeShutdowns                uint64 `json:"unsafeShutdowns"`
+	MediaErrors                    uint64 `json:"mediaErrors"`
+	ErrorLogEntries                uint64 `json:"errorLogEntries"`
+
+	// Minutos acumulados acima dos limites de temperatura do controlador.
+	WarningTemperatureMinutes  uint32 `json:"warningTemperatureMinutes"`
+	CriticalTemperatureMinutes uint32 `json:"criticalTemperatureMinutes"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// nvmeCriticalWarnings sao os bits do byte 0 do log page, na ordem da
+// especificacao.
+var nvmeCriticalWarnings = []string{
+	"spare_below_threshold",
+	"temperature",
+	"reliability_degraded",
+	"read_only",
+	"volatile_backup_failed",
+	"persistent_memory_read_only",
+}
+
+var nvmeControllerName = regexp.MustCompile(`^nvme\d+$`)
+
+// NVMe le o log page de cada controlador em /sys/class/nvme pelo
+// /dev correspondente. Controladores sem o /dev, como num container sem os
+// dispositivos do host, ficam de fora; sem permissao (o comando exige
+// root) o controlador vem com o erro.
+func NVMe() []NVMeHealth {
+	entries, err := os.ReadDir(nvmeClassRoot)
+	if err != nil {
+		return nil
+	}
+	var devices []NVMeHealth
+	for _, e := range entries {
+		if !nvmeControllerName.MatchString(e.Name()) {
+			continue
+		}
+		sys := filepath.Join(nvmeClassRoot, e.Name())
+		h := NVMeHealth{
+			Device:   e.Name(),
+			Model:    fsutil.ReadTrimmed(filepath.Join(sys, "model")),
+			Serial:   fsutil.ReadTrimmed(filepath.Join(sys, "serial")),
+			Firmware: fsutil.ReadTrimmed(filepath.Join(sys, "firmware_rev")),
+		}
+		page, err := readNVMeSmartLog(filepath.Join("/dev", e.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			h.Error = err.Error()
+		} else if err := parseNVMeSmartLog(page, &h); err != nil {
+			h.Error = err.Error()
+		}
+		devices = append(devices, h)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return CompareNatural(devices[i].Device, devices[j].Device) < 0
+	})
+	return devices
+}
+
+// parseNVMeSmartLog decodifica o log page 02h, little-endian. Os contadores
+// de 128 bits ficam nos 64 de baixo, que so transbordam depois de
+// zettabytes.
+func parseNVMeSmartLog(page []byte, h *NVMeHealth) error {
+	if len(page) < nvmeSmartLogSize {
+		return fmt.Errorf("short SMART log page: %d bytes", len(page))
+	}
+	le := binary.LittleEndian
+	h.CriticalWarning = int(page[0])
+	h.CriticalWarnings = nil
+	for bit, name := range nvmeCriticalWarnings {
+		if page[0]&(1<<bit) != 0 {
+			h.CriticalWarnings = append(h.CriticalWarnings, name)
+		}
+	}
+	// a temperatura composta vem em kelvin; zero e controlador sem leitura
+	if kelvin := int(le.Uint16(page[1:3])); kelvin > 0 {
+		h.TemperatureCelsius = kelvin - 273
+	}
+	h.AvailableSparePercent = int(page[3])
+	h.AvailableSpareThresholdPercent = int(page[4])
+	// percentage used pode passar de 100 quando a vida util estimada acaba
+	h.PercentageUsed = int(page[5])
+	h.DataReadBytes = le.Uint64(page[32:40]) * nvmeDataUnit
+	h.DataWrittenBytes = le.Uint64(page[48:56]) * nvmeDataUnit
+	h.PowerCycles = le.Uint64(page[112:120])
+	h.PowerOnHours = le.Uint64(page[128:136])
+	h.UnsafeShutdowns = le.Uint64(page[144:152])
+	h.MediaErrors = le.Uint64(page[160:168])
+	h.ErrorLogEntries = le.Uint64(page[176:184])
+	h.WarningTemperatureMinutes = le.Uint32(page[192:196])
+	h.CriticalTemperatureMinutes = le.Uint32(page[196:200])
+	return nil
+}
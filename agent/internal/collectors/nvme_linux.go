@@ -0,0 +1,73 @@
+//go:build linux
+
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// nvmeIoctlAdminCmd e _IOWR('N', 0x41, struct nvme_admin_cmd), com a
+	// struct de 72 bytes do linux/nvme_ioctl.h.
+	nvmeIoctlAdminCmd = 0xC0484E41
+
+	nvmeAdminGetLogPage = 0x02
+	nvmeLogSmart        = 0x02
+	nvmeNSIDAll         = 0xFFFFFFFF
+)
+
+// nvmeAdminCmd espelha a struct nvme_admin_cmd do kernel.
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMS   uint32
+	result      uint32
+}
+
+// readNVMeSmartLog manda o Get Log Page do SMART / Health ao controlador,
+// como o "nvme smart-log" do nvme-cli. O ioctl exige CAP_SYS_ADMIN.
+func readNVMeSmartLog(device string) ([]byte, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	page := make([]byte, nvmeSmartLogSize)
+	cmd := nvmeAdminCmd{
+		opcode:  nvmeAdminGetLogPage,
+		nsid:    nvmeNSIDAll,
+		addr:    uint64(uintptr(unsafe.Pointer(&page[0]))),
+		dataLen: nvmeSmartLogSize,
+		// NUMDL (dwords - 1) nos bits 31:16, o ID do log nos 7:0
+		cdw10: (nvmeSmartLogSize/4-1)<<16 | nvmeLogSmart,
+	}
+	status, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	runtime.KeepAlive(page)
+	if errno != 0 {
+		return nil, fmt.Errorf("%s: get log page: %w", device, errno)
+	}
+	// o status NVMe de um comando recusado volta no retorno, sem errno
+	if status != 0 {
+		return nil, fmt.Errorf("%s: get log page: NVMe status %#x", device, status)
+	}
+	return page, nil
+}
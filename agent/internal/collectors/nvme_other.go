@@ -0,0 +1,10 @@
+//go:build !linux
+
+package collectors
+
+import "errors"
+
+// readNVMeSmartLog depende do ioctl de administracao NVMe do Linux.
+func readNVMeSmartLog(device string) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,58 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseNVMeSmartLog(t *testing.T) {
+	page := make([]byte, nvmeSmartLogSize)
+	le := binary.LittleEndian
+	page[0] = 0x05 // bits 0 e 2
+	le.PutUint16(page[1:], 318)
+	page[3], page[4], page[5] = 8, 10, 103
+	le.PutUint64(page[32:], 1000)
+	le.PutUint64(page[48:], 2000)
+	le.PutUint64(page[112:], 41)
+	le.PutUint64(page[128:], 26280)
+	le.PutUint64(page[144:], 7)
+	le.PutUint64(page[160:], 3)
+	le.PutUint64(page[176:], 12)
+	le.PutUint32(page[192:], 90)
+	le.PutUint32(page[196:], 2)
+
+	h := NVMeHealth{Device: "nvme0"}
+	if err := parseNVMeSmartLog(page, &h); err != nil {
+		t.Fatal(err)
+	}
+	want := NVMeHealth{
+		Device:                         "nvme0",
+		CriticalWarning:                5,
+		CriticalWarnings:               []string{"spare_below_threshold", "reliability_degraded"},
+		TemperatureCelsius:             45,
+		AvailableSparePercent:          8,
+		AvailableSpareThresholdPercent: 10,
+		PercentageUsed:                 103,
+		DataReadBytes:                  512000000,
+		DataWrittenBytes:               1024000000,
+		PowerCycles:                    41,
+		PowerOnHours:                   26280,
+		UnsafeShutdowns:                7,
+		MediaErrors:                    3,
+		ErrorLogEntries:                12,
+		WarningTemperatureMinutes:      90,
+		CriticalTemperatureMinutes:     2,
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("got %+v\nwant %+v", h, want)
+	}
+
+	healthy := NVMeHealth{}
+	if err := parseNVMeSmartLog(make([]byte, nvmeSmartLogSize), &healthy); err != nil || healthy.CriticalWarnings != nil || healthy.TemperatureCelsius != 0 {
+		t.Errorf("zero page: got %+v, %v", healthy, err)
+	}
+	if err := parseNVMeSmartLog(page[:64], &h); err == nil {
+		t.Error("want an error for a short page")
+	}
+}
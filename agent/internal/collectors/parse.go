@@ -0,0 +1,55 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ParseInt64 le o inteiro no inicio do texto; zero quando nao ha numero.
+func ParseInt64(value string) int64 {
+	value = strings.TrimSpace(value)
+	var v int64
+	fmt.Sscanf(value, "%d", &v)
+	return v
+}
+
+// ParseOptionalFloat devolve nil para texto que nao e numero ("", "N/A").
+func ParseOptionalFloat(value string) *float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// PercentOf arredonda para duas casas; zero quando o total e zero.
+func PercentOf(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(used)/float64(total)*10000) / 100
+}
+
+// CommandError prefere a mensagem do CLI ao "exit status 1".
+func CommandError(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			return msg
+		}
+	}
+	return err.Error()
+}
+
+// ShortContainerID segue o ID de 12 caracteres do docker ps.
+func ShortContainerID(id string) string {
+	id = strings.TrimSpace(id)
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,237 @@
+package collectors
+
+import (
+	"cmp"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	SensorsTimeout = 5 * time.Second
+
+	// hwmonRoot e onde o kernel expoe os chips de sensores, os mesmos que o
+	// "sensors" do lm-sensors le; fora do Linux nao existe.
+	hwmonRoot = "/sys/class/hwmon"
+
+	sensorStatusOK       = "ok"
+	sensorStatusWarning  = "warning"
+	sensorStatusCritical = "critical"
+)
+
+// SensorsStatus sao as leituras do hwmon. Status e ok, warning ou critical
+// pelos limites de SensorsConfig.
+type SensorsStatus struct {
+	// CPUPackageCelsius e a temperatura do pacote da CPU (coretemp, k10temp
+	// ou o cpu_thermal dos SoCs ARM), a maior entre os sockets.
+	CPUPackageCelsius *float64 `json:"cpuPackageCelsius,omitempty"`
+
+	Temperatures []TemperatureSensor `json:"temperatures,omitempty"`
+	Fans         []FanSensor         `json:"fans,omitempty"`
+	Voltages     []VoltageSensor     `json:"voltages,omitempty"`
+}
+
+type TemperatureSensor struct {
+	Chip            string   `json:"chip"`
+	Label           string   `json:"label"`
+	Celsius         float64  `json:"celsius"`
+	WarningCelsius  *float64 `json:"warningCelsius,omitempty"`
+	CriticalCelsius *float64 `json:"criticalCelsius,omitempty"`
+	Status          string   `json:"status"`
+}
+
+type FanSensor struct {
+	Chip   string `json:"chip"`
+	Label  string `json:"label"`
+	RPM    int    `json:"rpm"`
+	MinRPM int    `json:"minRpm,omitempty"`
+	Status string `json:"status"`
+}
+
+type VoltageSensor struct {
+	Chip  string  `json:"chip"`
+	Label string  `json:"label"`
+	Volts float64 `json:"volts"`
+}
+
+// Sensors le o hwmon; sem chips (VMs, containers sem /sys, fora do
+// Linux) nao ha secao. O prazo do coletor cobre drivers que travam a
+// leitura do sysfs.
+func Sensors(cfg *config.SensorsConfig) *SensorsStatus {
+	s := readHwmon(hwmonRoot, cfg)
+	if len(s.Temperatures) == 0 && len(s.Fans) == 0 && len(s.Voltages) == 0 {
+		return nil
+	}
+	return &s
+}
+
+var hwmonInput = regexp.MustCompile(`^(temp|fan|in)(\d+)_input$`)
+
+// readHwmon percorre root/hwmonN: tempN_input em milesimos de grau, fanN_input
+// em RPM e inN_input em milivolts, com o nome em tempN_label (ou "temp1").
+// Drivers antigos poem os arquivos em hwmonN/device.
+func readHwmon(root string, cfg *config.SensorsConfig) SensorsStatus {
+	var s SensorsStatus
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return s
+	}
+	chips := hwmonChipNames(root, entries)
+	for _, e := range entries {
+		dir := filepath.Join(root, e.Name())
+		if !fsutil.FileExists(filepath.Join(dir, "name")) {
+			dir = filepath.Join(dir, "device")
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		chip := chips[e.Name()]
+		for _, f := range files {
+			m := hwmonInput.FindStringSubmatch(f.Name())
+			if m == nil {
+				continue
+			}
+			prefix := m[1] + m[2]
+			raw := fsutil.ReadTrimmed(filepath.Join(dir, f.Name()))
+			// sensores desligados dao erro na leitura (ENODATA)
+			if raw == "" {
+				continue
+			}
+			value := float64(ParseInt64(raw))
+			label := fsutil.ReadTrimmed(filepath.Join(dir, prefix+"_label"))
+			if label == "" {
+				label = prefix
+			}
+			switch m[1] {
+			case "temp":
+				t := TemperatureSensor{Chip: chip, Label: label, Celsius: value / 1000}
+				t.WarningCelsius, t.CriticalCelsius = cfg.TemperatureLimits(chip, label,
+					hwmonLimit(filepath.Join(dir, prefix+"_max")), hwmonLimit(filepath.Join(dir, prefix+"_crit")))
+				t.Status = temperatureStatus(t)
+				s.Temperatures = append(s.Temperatures, t)
+				if isCPUPackage(chip, label) && (s.CPUPackageCelsius == nil || t.Celsius > *s.CPUPackageCelsius) {
+					celsius := t.Celsius
+					s.CPUPackageCelsius = &celsius
+				}
+			case "fan":
+				fan := FanSensor{Chip: chip, Label: label, RPM: int(value), MinRPM: int(ParseInt64(fsutil.ReadTrimmed(filepath.Join(dir, prefix+"_min"))))}
+				if cfg != nil && cfg.MinFanRPM > 0 {
+					fan.MinRPM = cfg.MinFanRPM
+				}
+				fan.Status = sensorStatusOK
+				if fan.MinRPM > 0 && fan.RPM < fan.MinRPM {
+					fan.Status = sensorStatusCritical
+				}
+				s.Fans = append(s.Fans, fan)
+			case "in":
+				s.Voltages = append(s.Voltages, VoltageSensor{Chip: chip, Label: label, Volts: value / 1000})
+			}
+		}
+	}
+	sortSensors(s.Temperatures, func(t TemperatureSensor) (string, string) { return t.Chip, t.Label })
+	sortSensors(s.Fans, func(f FanSensor) (string, string) { return f.Chip, f.Label })
+	sortSensors(s.Voltages, func(v VoltageSensor) (string, string) { return v.Chip, v.Label })
+	return s
+}
+
+// hwmonChipNames usa o nome do driver ("coretemp", "nvme"); quando ele se
+// repete, como com dois SSDs NVMe, acrescenta o dispositivo ("nvme-nvme0"),
+// que ao contrario do hwmonN nao muda entre boots.
+func hwmonChipNames(root string, entries []os.DirEntry) map[string]string {
+	names := make(map[string]string, len(entries))
+	count := make(map[string]int)
+	for _, e := range entries {
+		dir := filepath.Join(root, e.Name())
+		name := fsutil.ReadTrimmed(filepath.Join(dir, "name"))
+		if name == "" {
+			name = fsutil.ReadTrimmed(filepath.Join(dir, "device", "name"))
+		}
+		names[e.Name()] = cmp.Or(name, e.Name())
+		count[names[e.Name()]]++
+	}
+	for entry, name := range names {
+		if count[name] < 2 {
+			continue
+		}
+		device, err := os.Readlink(filepath.Join(root, entry, "device"))
+		if err != nil {
+			device = entry
+		}
+		names[entry] = name + "-" + filepath.Base(device)
+	}
+	return names
+}
+
+// hwmonLimit le um tempN_max ou tempN_crit; zero e valores absurdos, que
+// alguns chips informam sem ter limite, contam como ausentes.
+func hwmonLimit(path string) *float64 {
+	raw := fsutil.ReadTrimmed(path)
+	if raw == "" {
+		return nil
+	}
+	v := float64(ParseInt64(raw)) / 1000
+	if v <= 0 || v >= 200 {
+		return nil
+	}
+	return &v
+}
+
+func temperatureStatus(t TemperatureSensor) string {
+	switch {
+	case t.CriticalCelsius != nil && t.Celsius >= *t.CriticalCelsius:
+		return sensorStatusCritical
+	case t.WarningCelsius != nil && t.Celsius >= *t.WarningCelsius:
+		return sensorStatusWarning
+	}
+	return sensorStatusOK
+}
+
+// isCPUPackage reconhece a temperatura do pacote: "Package id N" no
+// coretemp (Intel), Tctl/Tdie no k10temp e no zenpower (AMD) e o sensor
+// unico do cpu_thermal (Raspberry Pi e outros SoCs).
+func isCPUPackage(chip, label string) bool {
+	chip, _, _ = strings.Cut(chip, "-")
+	switch chip {
+	case "coretemp":
+		return strings.HasPrefix(label, "Package id")
+	case "k10temp", "zenpower":
+		return label == "Tctl" || label == "Tdie"
+	case "cpu_thermal":
+		return true
+	}
+	return false
+}
+
+func sortSensors[T any](sensors []T, key func(T) (string, string)) {
+	sort.Slice(sensors, func(i, j int) bool {
+		ci, li := key(sensors[i])
+		cj, lj := key(sensors[j])
+		return cmp.Or(cmp.Compare(ci, cj), CompareNatural(li, lj)) < 0
+	})
+}
+
+// CompareNatural poe "Core 2" antes de "Core 10" e md2 antes de md10.
+func CompareNatural(a, b string) int {
+	ta, na := splitSensorLabel(a)
+	tb, nb := splitSensorLabel(b)
+	return cmp.Or(cmp.Compare(ta, tb), cmp.Compare(na, nb), cmp.Compare(a, b))
+}
+
+func splitSensorLabel(label string) (string, float64) {
+	i := len(label)
+	for i > 0 && label[i-1] >= '0' && label[i-1] <= '9' {
+		i--
+	}
+	if i == len(label) {
+		return label, math.Inf(-1)
+	}
+	return label[:i], float64(ParseInt64(label[i:]))
+}
@@ -0,0 +1,136 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateSensors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.SensorsConfig
+		wantErr string
+	}{
+		{"off", nil, ""},
+		{"global", &config.SensorsConfig{WarningCelsius: 80, CriticalCelsius: 95, MinFanRPM: 300}, ""},
+		{"per sensor", &config.SensorsConfig{Thresholds: map[string]config.SensorThreshold{"nvme": {WarningCelsius: 65}}}, ""},
+		{"inverted", &config.SensorsConfig{WarningCelsius: 95, CriticalCelsius: 80}, "below critical_celsius"},
+		{"too hot", &config.SensorsConfig{Thresholds: map[string]config.SensorThreshold{"coretemp/Core 0": {CriticalCelsius: 500}}}, "coretemp/Core 0"},
+		{"empty name", &config.SensorsConfig{Thresholds: map[string]config.SensorThreshold{" ": {WarningCelsius: 60}}}, "empty sensor name"},
+		{"fan", &config.SensorsConfig{MinFanRPM: -1}, "min_fan_rpm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func writeHwmon(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadHwmon(t *testing.T) {
+	root := t.TempDir()
+	writeHwmon(t, filepath.Join(root, "hwmon0"), map[string]string{
+		"name":         "coretemp",
+		"temp1_input":  "71000",
+		"temp1_label":  "Package id 0",
+		"temp1_max":    "84000",
+		"temp1_crit":   "100000",
+		"temp2_input":  "64000",
+		"temp2_label":  "Core 10",
+		"temp3_input":  "58000",
+		"temp3_label":  "Core 2",
+		"temp3_crit":   "0",
+		"temp3_max":    "",
+		"temp10_label": "Core 9",
+	})
+	writeHwmon(t, filepath.Join(root, "hwmon1"), map[string]string{
+		"name":        "nct6775",
+		"fan1_input":  "1200",
+		"fan1_label":  "CPU fan",
+		"fan2_input":  "0",
+		"fan2_min":    "400",
+		"in0_input":   "1192",
+		"in0_label":   "Vcore",
+		"temp1_input": "42500",
+	})
+	writeHwmon(t, filepath.Join(root, "hwmon2"), map[string]string{"name": "nvme", "temp1_input": "48850", "temp1_label": "Composite"})
+	writeHwmon(t, filepath.Join(root, "hwmon3"), map[string]string{"name": "nvme", "temp1_input": "61850", "temp1_label": "Composite"})
+	// driver antigo, com os arquivos em device/
+	writeHwmon(t, filepath.Join(root, "hwmon4", "device"), map[string]string{"name": "w83627ehf", "temp1_input": "35000"})
+
+	cfg := &config.SensorsConfig{Thresholds: map[string]config.SensorThreshold{
+		"coretemp/Package id 0": {WarningCelsius: 70},
+		"nvme":                  {WarningCelsius: 60, CriticalCelsius: 70},
+	}}
+	got := readHwmon(root, cfg)
+
+	want := SensorsStatus{
+		CPUPackageCelsius: ptrFloat(71),
+		Temperatures: []TemperatureSensor{
+			{Chip: "coretemp", Label: "Core 2", Celsius: 58, Status: "ok"},
+			{Chip: "coretemp", Label: "Core 10", Celsius: 64, Status: "ok"},
+			{Chip: "coretemp", Label: "Package id 0", Celsius: 71, WarningCelsius: ptrFloat(70), CriticalCelsius: ptrFloat(100), Status: "warning"},
+			{Chip: "nct6775", Label: "temp1", Celsius: 42.5, Status: "ok"},
+			{Chip: "nvme-hwmon2", Label: "Composite", Celsius: 48.85, WarningCelsius: ptrFloat(60), CriticalCelsius: ptrFloat(70), Status: "ok"},
+			{Chip: "nvme-hwmon3", Label: "Composite", Celsius: 61.85, WarningCelsius: ptrFloat(60), CriticalCelsius: ptrFloat(70), Status: "warning"},
+			{Chip: "w83627ehf", Label: "temp1", Celsius: 35, Status: "ok"},
+		},
+		Fans: []FanSensor{
+			{Chip: "nct6775", Label: "CPU fan", RPM: 1200, Status: "ok"},
+			{Chip: "nct6775", Label: "fan2", RPM: 0, MinRPM: 400, Status: "critical"},
+		},
+		Voltages: []VoltageSensor{{Chip: "nct6775", Label: "Vcore", Volts: 1.192}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if got := readHwmon(filepath.Join(root, "missing"), nil); !reflect.DeepEqual(got, SensorsStatus{}) {
+		t.Errorf("no hwmon: got %+v", got)
+	}
+}
+
+func TestIsCPUPackage(t *testing.T) {
+	tests := []struct {
+		chip, label string
+		want        bool
+	}{
+		{"coretemp", "Package id 0", true},
+		{"coretemp-coretemp.1", "Package id 1", true},
+		{"coretemp", "Core 0", false},
+		{"k10temp", "Tctl", true},
+		{"k10temp", "Tccd1", false},
+		{"cpu_thermal", "temp1", true},
+		{"acpitz", "temp1", false},
+	}
+	for _, tt := range tests {
+		if got := isCPUPackage(tt.chip, tt.label); got != tt.want {
+			t.Errorf("isCPUPackage(%q, %q) = %v, want %v", tt.chip, tt.label, got, tt.want)
+		}
+	}
+}
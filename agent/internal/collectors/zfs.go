@@ -0,0 +1,335 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	ZFSTimeout = 15 * time.Second
+
+	// maxZFSDatasets limita os datasets no payload, os maiores primeiro: o
+	// driver zfs do docker cria um por camada de imagem.
+	maxZFSDatasets = 100
+
+	// zfsDevice so existe com o modulo carregado; sem ele o zpool falha em
+	// hosts que so tem o pacote instalado.
+	zfsDevice = "/dev/zfs"
+)
+
+// ZFSStatus sao os pools e datasets do host.
+type ZFSStatus struct {
+	Pools             []ZFSPool    `json:"pools"`
+	Datasets          []ZFSDataset `json:"datasets,omitempty"`
+	DatasetsTruncated bool         `json:"datasetsTruncated,omitempty"`
+	Error             string       `json:"error,omitempty"`
+}
+
+// ZFSPool junta o "zpool list" e o "zpool status". Health e ONLINE,
+// DEGRADED, FAULTED, OFFLINE, UNAVAIL ou REMOVED; Status e Action sao os
+// textos que o zpool mostra quando ha algo a fazer.
+type ZFSPool struct {
+	Name                 string `json:"name"`
+	Health               string `json:"health"`
+	SizeBytes            int64  `json:"sizeBytes"`
+	AllocatedBytes       int64  `json:"allocatedBytes"`
+	FreeBytes            int64  `json:"freeBytes"`
+	CapacityPercent      int    `json:"capacityPercent"`
+	FragmentationPercent *int   `json:"fragmentationPercent,omitempty"`
+
+	Status string `json:"status,omitempty"`
+	Action string `json:"action,omitempty"`
+
+	// Erros somados dos discos (as folhas da arvore de vdevs) e erros de
+	// dados permanentes, os do "errors:".
+	ReadErrors     int64 `json:"readErrors"`
+	WriteErrors    int64 `json:"writeErrors"`
+	ChecksumErrors int64 `json:"checksumErrors"`
+	DataErrors     int64 `json:"dataErrors"`
+
+	// UnhealthyDevices sao os vdevs fora de ONLINE ou com erros.
+	UnhealthyDevices []ZFSDevice `json:"unhealthyDevices,omitempty"`
+
+	Scan *ZFSScan `json:"scan,omitempty"`
+}
+
+type ZFSDevice struct {
+	Name           string `json:"name"`
+	State          string `json:"state"`
+	ReadErrors     int64  `json:"readErrors"`
+	WriteErrors    int64  `json:"writeErrors"`
+	ChecksumErrors int64  `json:"checksumErrors"`
+}
+
+// ZFSScan e o ultimo scrub ou resilver: Function e scrub ou resilver e State
+// e in_progress, finished ou canceled.
+type ZFSScan struct {
+	Function    string     `json:"function"`
+	State       string     `json:"state"`
+	PercentDone *float64   `json:"percentDone,omitempty"`
+	Errors      int64      `json:"errors"`
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+}
+
+type ZFSDataset struct {
+	Name             string  `json:"name"`
+	Type             string  `json:"type"`
+	UsedBytes        int64   `json:"usedBytes"`
+	AvailableBytes   int64   `json:"availableBytes"`
+	ReferencedBytes  int64   `json:"referencedBytes"`
+	Mountpoint       string  `json:"mountpoint,omitempty"`
+	CompressionRatio float64 `json:"compressionRatio,omitempty"`
+}
+
+// ZFS roda zpool list, zpool status e zfs list. Sem o zpool ou sem
+// o modulo nao ha ZFS no host; uma falha vai no error com o que as outras
+// chamadas trouxeram.
+func ZFS(ctx context.Context) *ZFSStatus {
+	if _, err := exec.LookPath("zpool"); err != nil || !fsutil.FileExists(zfsDevice) {
+		return nil
+	}
+	s := &ZFSStatus{Pools: []ZFSPool{}}
+	fail := func(cmd string, err error) {
+		if s.Error == "" {
+			s.Error = fmt.Sprintf("%s: %s", cmd, CommandError(err))
+		}
+	}
+	out, err := exec.CommandContext(ctx, "zpool", "list", "-Hp", "-o", "name,size,alloc,free,frag,cap,health").Output()
+	if err != nil {
+		fail("zpool list", err)
+		return s
+	}
+	s.Pools = parseZpoolList(string(out))
+	if len(s.Pools) == 0 {
+		return s
+	}
+	if out, err := exec.CommandContext(ctx, "zpool", "status", "-p").Output(); err != nil {
+		fail("zpool status", err)
+	} else {
+		addZpoolStatus(s.Pools, string(out))
+	}
+	if out, err := exec.CommandContext(ctx, "zfs", "list", "-Hp", "-t", "filesystem,volume",
+		"-o", "name,type,used,avail,refer,mountpoint,compressratio").Output(); err != nil {
+		fail("zfs list", err)
+	} else {
+		s.Datasets, s.DatasetsTruncated = parseZFSList(string(out))
+	}
+	return s
+}
+
+// parseZpoolList le o "zpool list -Hp": tabs, bytes exatos e "-" na
+// fragmentacao de pools que nao a calculam.
+func parseZpoolList(out string) []ZFSPool {
+	pools := []ZFSPool{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			continue
+		}
+		p := ZFSPool{
+			Name:           f[0],
+			SizeBytes:      ParseInt64(f[1]),
+			AllocatedBytes: ParseInt64(f[2]),
+			FreeBytes:      ParseInt64(f[3]),
+			Health:         f[6],
+		}
+		if frag, err := strconv.Atoi(strings.TrimSuffix(f[4], "%")); err == nil {
+			p.FragmentationPercent = &frag
+		}
+		p.CapacityPercent, _ = strconv.Atoi(strings.TrimSuffix(f[5], "%"))
+		pools = append(pools, p)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return pools
+}
+
+var (
+	zpoolStatusKey  = regexp.MustCompile(`^\s*(pool|state|status|action|scan|errors|config|see):\s?(.*)$`)
+	zpoolDataErrors = regexp.MustCompile(`^(\d+) data errors`)
+	zpoolScanDone   = regexp.MustCompile(`^(scrub repaired|resilvered) .* with (\d+) errors on (.+)$`)
+	zpoolScanActive = regexp.MustCompile(`^(scrub|resilver) in progress since (.+)$`)
+	zpoolScanCancel = regexp.MustCompile(`^(scrub|resilver) canceled on (.+)$`)
+	zpoolPercent    = regexp.MustCompile(`([\d.]+)% done`)
+)
+
+// addZpoolStatus le o texto do "zpool status -p" de todos os pools: um
+// bloco "pool:" por pool, chaves com continuacao nas linhas recuadas e a
+// tabela de vdevs sob "config:".
+func addZpoolStatus(pools []ZFSPool, out string) {
+	byName := make(map[string]*ZFSPool, len(pools))
+	for i := range pools {
+		byName[pools[i].Name] = &pools[i]
+	}
+	var pool *ZFSPool
+	var key string
+	fields := map[string]string{}
+	var rows []zpoolRow
+	flush := func() {
+		if pool != nil {
+			applyZpoolStatus(pool, fields, rows)
+		}
+		fields, rows = map[string]string{}, nil
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := zpoolStatusKey.FindStringSubmatch(line); m != nil {
+			key = m[1]
+			if key == "pool" {
+				flush()
+				pool = byName[strings.TrimSpace(m[2])]
+			}
+			fields[key] = strings.TrimSpace(m[2])
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if key == "config" {
+			if row, ok := parseZpoolRow(line); ok {
+				rows = append(rows, row)
+			}
+			continue
+		}
+		fields[key] = strings.TrimSpace(fields[key] + " " + strings.TrimSpace(line))
+	}
+	flush()
+}
+
+// zpoolRow e uma linha da tabela de vdevs; os titulos de secao (logs,
+// cache, spares) vem sem estado e os spares sem contadores.
+type zpoolRow struct {
+	indent int
+	device ZFSDevice
+}
+
+func parseZpoolRow(line string) (zpoolRow, bool) {
+	f := strings.Fields(line)
+	if len(f) == 0 || f[0] == "NAME" {
+		return zpoolRow{}, false
+	}
+	row := zpoolRow{indent: len(line) - len(strings.TrimLeft(line, " \t")), device: ZFSDevice{Name: f[0]}}
+	if len(f) >= 2 {
+		row.device.State = f[1]
+	}
+	if len(f) >= 5 {
+		row.device.ReadErrors = ParseInt64(f[2])
+		row.device.WriteErrors = ParseInt64(f[3])
+		row.device.ChecksumErrors = ParseInt64(f[4])
+	}
+	return row, true
+}
+
+func applyZpoolStatus(p *ZFSPool, fields map[string]string, rows []zpoolRow) {
+	p.Status, p.Action = fields["status"], fields["action"]
+	if state := fields["state"]; state != "" {
+		p.Health = state
+	}
+	if m := zpoolDataErrors.FindStringSubmatch(fields["errors"]); m != nil {
+		p.DataErrors = ParseInt64(m[1])
+	}
+	p.Scan = parseZpoolScan(fields["scan"])
+	for i, row := range rows {
+		// a primeira linha e o proprio pool
+		if i == 0 || row.device.State == "" {
+			continue
+		}
+		leaf := i == len(rows)-1 || rows[i+1].indent <= row.indent
+		if leaf {
+			p.ReadErrors += row.device.ReadErrors
+			p.WriteErrors += row.device.WriteErrors
+			p.ChecksumErrors += row.device.ChecksumErrors
+		}
+		d := row.device
+		healthy := d.State == "ONLINE" || d.State == "AVAIL" || d.State == "INUSE"
+		if !healthy || d.ReadErrors+d.WriteErrors+d.ChecksumErrors > 0 {
+			p.UnhealthyDevices = append(p.UnhealthyDevices, d)
+		}
+	}
+}
+
+// parseZpoolScan le a linha "scan:", ja juntada com as continuacoes. As
+// datas vem no formato do ctime, no fuso do host.
+func parseZpoolScan(scan string) *ZFSScan {
+	switch {
+	case scan == "" || strings.HasPrefix(scan, "none requested"):
+		return nil
+	case zpoolScanDone.MatchString(scan):
+		m := zpoolScanDone.FindStringSubmatch(scan)
+		s := &ZFSScan{Function: "scrub", State: "finished", Errors: ParseInt64(m[2]), End: parseZpoolTime(m[3])}
+		if m[1] == "resilvered" {
+			s.Function = "resilver"
+		}
+		return s
+	case zpoolScanActive.MatchString(scan):
+		m := zpoolScanActive.FindStringSubmatch(scan)
+		s := &ZFSScan{Function: m[1], State: "in_progress", Start: parseZpoolTime(m[2])}
+		if p := zpoolPercent.FindStringSubmatch(scan); p != nil {
+			s.PercentDone = ParseOptionalFloat(p[1])
+		}
+		return s
+	case zpoolScanCancel.MatchString(scan):
+		m := zpoolScanCancel.FindStringSubmatch(scan)
+		return &ZFSScan{Function: m[1], State: "canceled", End: parseZpoolTime(m[2])}
+	}
+	return nil
+}
+
+func parseZpoolTime(s string) *time.Time {
+	// "Sun Apr 14 00:36:35 2024" e o resto da linha, quando houver
+	f := strings.Fields(s)
+	if len(f) < 5 {
+		return nil
+	}
+	t, err := time.ParseInLocation(time.ANSIC, strings.Join(f[:5], " "), time.Local)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// parseZFSList le o "zfs list -Hp"; devolve os maxZFSDatasets maiores e se
+// houve corte.
+func parseZFSList(out string) ([]ZFSDataset, bool) {
+	var datasets []ZFSDataset
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			continue
+		}
+		d := ZFSDataset{
+			Name:            f[0],
+			Type:            f[1],
+			UsedBytes:       ParseInt64(f[2]),
+			AvailableBytes:  ParseInt64(f[3]),
+			ReferencedBytes: ParseInt64(f[4]),
+		}
+		// volumes nao tem ponto de montagem
+		if f[5] != "-" && f[5] != "none" {
+			d.Mountpoint = f[5]
+		}
+		d.CompressionRatio, _ = strconv.ParseFloat(strings.TrimSuffix(f[6], "x"), 64)
+		datasets = append(datasets, d)
+	}
+	sort.Slice(datasets, func(i, j int) bool {
+		if datasets[i].UsedBytes != datasets[j].UsedBytes {
+			return datasets[i].UsedBytes > datasets[j].UsedBytes
+		}
+		return datasets[i].Name < datasets[j].Name
+	})
+	if len(datasets) > maxZFSDatasets {
+		return datasets[:maxZFSDatasets], true
+	}
+	return datasets, false
+}
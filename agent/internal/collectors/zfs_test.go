@@ -0,0 +1,133 @@
+package collectors
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseZpoolList(t *testing.T) {
+	out := "tank\t3985729650688\t2791728742400\t1194000908288\t23\t70\tDEGRADED\nboot\t1073741824\t268435456\t805306368\t-\t25\tONLINE\n"
+	frag := 23
+	want := []ZFSPool{
+		{Name: "boot", Health: "ONLINE", SizeBytes: 1073741824, AllocatedBytes: 268435456, FreeBytes: 805306368, CapacityPercent: 25},
+		{Name: "tank", Health: "DEGRADED", SizeBytes: 3985729650688, AllocatedBytes: 2791728742400, FreeBytes: 1194000908288,
+			CapacityPercent: 70, FragmentationPercent: &frag},
+	}
+	if got := parseZpoolList(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if got := parseZpoolList("no pools available\n"); len(got) != 0 {
+		t.Errorf("no pools: got %+v", got)
+	}
+}
+
+const testZpoolStatus = `  pool: boot
+ state: ONLINE
+  scan: scrub repaired 0 in 00:00:04 with 0 errors on Sun Apr 14 00:24:05 2024
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	boot        ONLINE       0     0     0
+	  nvme0n1p2 ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: tank
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.  Sufficient replicas exist for the pool to continue
+	functioning in a degraded state.
+action: Replace the device using 'zpool replace'.
+   see: https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-4J
+  scan: resilver in progress since Mon Apr 15 09:10:00 2024
+	1352671232 scanned at 150296803/s, 1052671232 issued at 116963470/s, 2791728742400 total
+	0 repaired, 37.70% done, 00:20:00 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     0
+	  mirror-0  DEGRADED     0     0     0
+	    sda     ONLINE       0     0     2
+	    sdb     UNAVAIL      3     1     0  cannot open
+	logs
+	  nvme1n1   ONLINE       0     0     0
+	spares
+	  sdc       AVAIL
+
+errors: 2 data errors, use '-v' for a list
+`
+
+func TestAddZpoolStatus(t *testing.T) {
+	pools := []ZFSPool{{Name: "boot", Health: "ONLINE"}, {Name: "tank", Health: "DEGRADED"}}
+	addZpoolStatus(pools, testZpoolStatus)
+
+	end := time.Date(2024, 4, 14, 0, 24, 5, 0, time.Local).UTC()
+	start := time.Date(2024, 4, 15, 9, 10, 0, 0, time.Local).UTC()
+	want := []ZFSPool{
+		{Name: "boot", Health: "ONLINE", Scan: &ZFSScan{Function: "scrub", State: "finished", End: &end}},
+		{Name: "tank", Health: "DEGRADED",
+			Status:         "One or more devices could not be used because the label is missing or invalid.  Sufficient replicas exist for the pool to continue functioning in a degraded state.",
+			Action:         "Replace the device using 'zpool replace'.",
+			ReadErrors:     3,
+			WriteErrors:    1,
+			ChecksumErrors: 2,
+			DataErrors:     2,
+			UnhealthyDevices: []ZFSDevice{
+				{Name: "mirror-0", State: "DEGRADED"},
+				{Name: "sda", State: "ONLINE", ChecksumErrors: 2},
+				{Name: "sdb", State: "UNAVAIL", ReadErrors: 3, WriteErrors: 1},
+			},
+			Scan: &ZFSScan{Function: "resilver", State: "in_progress", PercentDone: ptrFloat(37.7), Start: &start}},
+	}
+	if !reflect.DeepEqual(pools, want) {
+		t.Errorf("got %+v\nwant %+v", pools, want)
+	}
+}
+
+func TestParseZpoolScan(t *testing.T) {
+	end := time.Date(2024, 3, 2, 18, 5, 1, 0, time.Local).UTC()
+	tests := []struct {
+		name string
+		scan string
+		want *ZFSScan
+	}{
+		{"none", "none requested", nil},
+		{"resilvered", "resilvered 1.20G in 00:01:02 with 4 errors on Sat Mar  2 18:05:01 2024", &ZFSScan{Function: "resilver", State: "finished", Errors: 4, End: &end}},
+		{"canceled", "scrub canceled on Sat Mar  2 18:05:01 2024", &ZFSScan{Function: "scrub", State: "canceled", End: &end}},
+		{"unknown", "something new", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseZpoolScan(tt.scan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseZFSList(t *testing.T) {
+	out := "tank\tfilesystem\t2791728742400\t1150000000000\t98304\t/tank\t1.00\n" +
+		"tank/vm-100-disk-0\tvolume\t34359738368\t1184359738368\t12884901888\t-\t1.52x\n" +
+		"tank/backups\tfilesystem\t1999999999999\t1150000000000\t1999999999999\tnone\t2.31\n"
+	got, truncated := parseZFSList(out)
+	want := []ZFSDataset{
+		{Name: "tank", Type: "filesystem", UsedBytes: 2791728742400, AvailableBytes: 1150000000000, ReferencedBytes: 98304, Mountpoint: "/tank", CompressionRatio: 1},
+		{Name: "tank/backups", Type: "filesystem", UsedBytes: 1999999999999, AvailableBytes: 1150000000000, ReferencedBytes: 1999999999999, CompressionRatio: 2.31},
+		{Name: "tank/vm-100-disk-0", Type: "volume", UsedBytes: 34359738368, AvailableBytes: 1184359738368, ReferencedBytes: 12884901888, CompressionRatio: 1.52},
+	}
+	if truncated || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v (truncated %v)\nwant %+v", got, truncated, want)
+	}
+
+	var many strings.Builder
+	for i := 0; i < maxZFSDatasets+5; i++ {
+		many.WriteString("tank/docker/" + strconv.Itoa(i) + "\tfilesystem\t" + strconv.Itoa(1000+i) + "\t0\t0\tlegacy\t1.00\n")
+	}
+	got, truncated = parseZFSList(many.String())
+	if !truncated || len(got) != maxZFSDatasets || got[0].Name != "tank/docker/104" {
+		t.Errorf("truncation: got %d datasets, truncated %v, first %q", len(got), truncated, got[0].Name)
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultAlertCooldown = 60 * time.Minute
+
+// AlertsConfig define alertas avaliados no proprio host a cada ciclo. Eles
+// disparam o webhook mesmo quando a API central e justamente o que caiu.
+type AlertsConfig struct {
+	// Canais de aviso; todos os configurados recebem cada aviso.
+	WebhookURL      string        `json:"webhook_url,omitempty"`
+	SlackWebhookURL string        `json:"slack_webhook_url,omitempty"`
+	Telegram        *TelegramSink `json:"telegram,omitempty"`
+	Email           *EmailSink    `json:"email,omitempty"`
+
+	// CooldownMinutes e o intervalo minimo entre avisos da mesma regra.
+	CooldownMinutes int `json:"cooldown_minutes,omitempty"`
+
+	Rules []AlertRule `json:"rules,omitempty"`
+}
+
+// AlertRule e uma condicao nomeada. When aceita:
+//
+//	<metrica> <op> <valor>        ex.: disk_percent > 90
+//	container <nome> not running
+//	check <nome> down
+//
+// As metricas sao os campos numericos de "metrics" no payload; op e um de
+// >, >=, <, <=, == ou !=.
+type AlertRule struct {
+	Name string `json:"name"`
+	When string `json:"when"`
+}
+
+func (a AlertsConfig) Cooldown() time.Duration {
+	if a.CooldownMinutes > 0 {
+		return time.Duration(a.CooldownMinutes) * time.Minute
+	}
+	return defaultAlertCooldown
+}
+
+// TelegramSink envia avisos por um bot. ChatID aceita o id numerico ou
+// "@canal"; APIURL serve para servidores proprios da Bot API.
+type TelegramSink struct {
+	BotToken string `json:"bot_token"`
+	ChatID   chatID `json:"chat_id"`
+	APIURL   string `json:"api_url,omitempty"`
+}
+
+// chatID aceita numero ou string no JSON: ids de grupo costumam ser escritos
+// como numero (-100123...).
+type chatID string
+
+func (c *chatID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*c = chatID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return errors.New("chat_id must be a number or a string")
+	}
+	*c = chatID(n.String())
+	return nil
+}
+
+func (t *TelegramSink) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.BotToken == "" || t.ChatID == "" {
+		return errors.New("alerts: telegram needs bot_token and chat_id")
+	}
+	return nil
+}
+
+// EmailSink envia avisos por SMTP. Na porta 465 a conexao ja comeca em TLS;
+// nas demais o STARTTLS e usado quando o servidor oferece. A senha so e
+// enviada sobre TLS (ou para localhost).
+type EmailSink struct {
+	SMTPHost string   `json:"smtp_host"`
+	SMTPPort int      `json:"smtp_port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+func (e *EmailSink) Validate() error {
+	if e == nil {
+		return nil
+	}
+	if e.SMTPHost == "" || e.From == "" || len(e.To) == 0 {
+		return errors.New("alerts: email needs smtp_host, from and to")
+	}
+	for _, addr := range append([]string{e.From}, e.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("alerts: invalid email address %q", addr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// SplitAPIURLs troca uma lista em api_url pelo primeiro endereco e devolve
+// a lista inteira; com um endereco so devolve nil.
+func SplitAPIURLs(merged map[string]json.RawMessage) ([]string, error) {
+	v, ok := merged["api_url"]
+	if !ok {
+		return nil, nil
+	}
+	var list []string
+	if json.Unmarshal(v, &list) != nil {
+		return nil, nil
+	}
+	if len(list) == 0 {
+		return nil, errors.New("api_url list is empty")
+	}
+	if slices.Contains(list, "") {
+		return nil, errors.New("api_url list has an empty entry")
+	}
+	merged["api_url"], _ = json.Marshal(list[0])
+	if len(list) == 1 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+// Endpoints devolve todos os enderecos da API, o principal primeiro.
+func (c Config) Endpoints() []string {
+	if len(c.ApiURLs) > 0 {
+		return c.ApiURLs
+	}
+	return []string{c.ApiURL}
+}
+
+// endpointURL troca o caminho de endpoint por path, no mesmo host.
+func endpointURL(endpoint, path string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: path}).String(), nil
+}
+
+// RemoteConfigURLFor usa remote_config_url ou deriva /api/agent/config do host
+// de endpoint.
+func (c Config) RemoteConfigURLFor(endpoint string) (string, error) {
+	if c.RemoteConfigURL != "" {
+		return c.RemoteConfigURL, nil
+	}
+	return endpointURL(endpoint, "/api/agent/config")
+}
+
+// TokenRotateURLFor deriva /api/agent/token/rotate do host de endpoint.
+func (c Config) TokenRotateURLFor(endpoint string) (string, error) {
+	return endpointURL(endpoint, "/api/agent/token/rotate")
+}
+
+// CommandsURLFor deriva /api/agent/commands do host de endpoint.
+func (c Config) CommandsURLFor(endpoint string) (string, error) {
+	return endpointURL(endpoint, "/api/agent/commands")
+}
+
+// UpdateURLFor usa update_url ou deriva /api/agent/releases do host de
+// endpoint.
+func (c Config) UpdateURLFor(endpoint string) (string, error) {
+	if c.UpdateURL != "" {
+		return strings.TrimSuffix(c.UpdateURL, "/"), nil
+	}
+	return endpointURL(endpoint, "/api/agent/releases")
+}
+
+// PluginRegistryURLFor usa plugin_registry_url ou deriva /api/agent/plugins do
+// host de endpoint.
+func (c Config) PluginRegistryURLFor(endpoint string) (string, error) {
+	if c.PluginRegistryURL != "" {
+		return strings.TrimSuffix(c.PluginRegistryURL, "/"), nil
+	}
+	return endpointURL(endpoint, "/api/agent/plugins")
+}
+
+// HTTPTuning ajusta o pool de conexoes. No modo daemon a conexao TLS (HTTP/2
+// quando a API oferece) fica aberta entre ciclos; o idle timeout padrao e
+// maior que o menor intervalo para que o handshake nao se repita a cada
+// envio. Zero usa o padrao; max_idle_conns negativo desliga o
+// reaproveitamento e idle_timeout_seconds negativo mantem as conexoes
+// ociosas sem prazo. DNSTTLSeconds e o tempo que um endereco resolvido sem
+// TTL fica em cache (padrao 300); as respostas do DNS usam o TTL dos
+// registros. Negativo resolve sempre, mantendo so o fallback para o ultimo
+// endereco conhecido quando o DNS falha.
+type HTTPTuning struct {
+	MaxIdleConns       int `json:"max_idle_conns,omitempty"`
+	IdleTimeoutSeconds int `json:"idle_timeout_seconds,omitempty"`
+	DNSTTLSeconds      int `json:"dns_ttl_seconds,omitempty"`
+}
+
+func (t HTTPTuning) WithDefaults() HTTPTuning {
+	pick := func(v, def int) int {
+		switch {
+		case v < 0:
+			return 0
+		case v == 0:
+			return def
+		}
+		return v
+	}
+	return HTTPTuning{
+		MaxIdleConns:       pick(t.MaxIdleConns, 4),
+		IdleTimeoutSeconds: pick(t.IdleTimeoutSeconds, 150),
+		DNSTTLSeconds:      pick(t.DNSTTLSeconds, 300),
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEndpointURL(t *testing.T) {
+	tests := []struct {
+		endpoint, want string
+	}{
+		{"https://a.example.com/api/telemetry", "https://a.example.com/api/agent/commands"},
+		{"http://10.0.0.5:8080/api/telemetry?x=1", "http://10.0.0.5:8080/api/agent/commands"},
+	}
+	for _, tt := range tests {
+		if got, err := endpointURL(tt.endpoint, "/api/agent/commands"); err != nil || got != tt.want {
+			t.Errorf("endpointURL(%q) = %q, %v; want %q", tt.endpoint, got, err, tt.want)
+		}
+	}
+}
+
+func TestSplitAPIURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantList []string
+		wantURL  string
+		wantErr  string
+	}{
+		{"single string", `"https://a.example.com"`, nil, `"https://a.example.com"`, ""},
+		{"one item list", `["https://a.example.com"]`, nil, `"https://a.example.com"`, ""},
+		{"list", `["https://a.example.com","https://b.example.com"]`, []string{"https://a.example.com", "https://b.example.com"}, `"https://a.example.com"`, ""},
+		{"empty list", `[]`, nil, "", "api_url list is empty"},
+		{"empty entry", `["https://a.example.com",""]`, nil, "", "empty entry"},
+	}
+	for _, tt := range tests {
+		merged := map[string]json.RawMessage{"api_url": json.RawMessage(tt.raw)}
+		list, err := SplitAPIURLs(merged)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(list, tt.wantList) || string(merged["api_url"]) != tt.wantURL {
+			t.Errorf("%s: got %v and api_url %s", tt.name, list, merged["api_url"])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+const defaultCheckTimeout = 10 * time.Second
+
+const (
+	CheckHTTP = "http"
+	CheckTCP  = "tcp"
+	CheckPing = "ping"
+)
+
+// Check e uma verificacao feita a partir do host a cada ciclo, complementando
+// as metricas com a disponibilidade vista pela maquina. Alem de URLs, cobre
+// portas TCP e ping, para bancos e servicos internos que nao falam HTTP.
+type Check struct {
+	Name string `json:"name"`
+	// Type vazio e http, o formato original das checks.
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url,omitempty"`
+
+	// Host e Port sao o alvo de tcp; ping usa apenas Host.
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+
+	// ExpectedStatus zero aceita qualquer 2xx.
+	ExpectedStatus int    `json:"expected_status,omitempty"`
+	Keyword        string `json:"keyword,omitempty"`
+	MaxLatencyMS   int64  `json:"max_latency_ms,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+
+	// SkipTLSVerify aceita certificados invalidos; a validade ainda e
+	// reportada em cert_expires_in_days.
+	SkipTLSVerify bool `json:"skip_tls_verify,omitempty"`
+
+	// Count e o numero de pacotes do ping. MaxPacketLoss, em porcentagem,
+	// marca o alvo como fora acima desse valor; sem ele, basta uma resposta.
+	Count         int      `json:"count,omitempty"`
+	MaxPacketLoss *float64 `json:"max_packet_loss,omitempty"`
+
+	// UseProxy passa a check pelo proxy_url, para alvos que so sao
+	// alcancaveis pela mesma saida da API (tunel SSH, Tor). Checks tcp
+	// precisam de um proxy socks5; ping nao passa por proxy.
+	UseProxy bool `json:"use_proxy,omitempty"`
+
+	// FlapWindow e FlapThreshold ajustam a deteccao de instabilidade (ver
+	// checkhistory.go).
+	FlapWindow    int `json:"flap_window,omitempty"`
+	FlapThreshold int `json:"flap_threshold,omitempty"`
+}
+
+func (c Check) Kind() string {
+	if c.Type == "" {
+		return CheckHTTP
+	}
+	return c.Type
+}
+
+// Target e o alvo exibido no resultado.
+func (c Check) Target() string {
+	switch c.Kind() {
+	case CheckTCP:
+		return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	case CheckPing:
+		return c.Host
+	}
+	return c.URL
+}
+
+func (c Check) Timeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return defaultCheckTimeout
+}
+
+const defaultFlapWindow = 10
+
+const defaultFlapThreshold = 4
+
+func (c Check) FlapWindowOrDefault() int {
+	if c.FlapWindow > 0 {
+		return c.FlapWindow
+	}
+	return defaultFlapWindow
+}
+
+func (c Check) FlapThresholdOrDefault() int {
+	if c.FlapThreshold > 0 {
+		return c.FlapThreshold
+	}
+	return defaultFlapThreshold
+}
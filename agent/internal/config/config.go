@@ -0,0 +1,404 @@
+// Package config define o config do agente: os tipos lidos do arquivo, a
+// leitura em camadas (perfil, arquivo e variaveis de ambiente) e a validacao
+// de cada secao. O que cada secao liga fica no pacote main; as referencias
+// "ver arquivo.go" dos campos apontam para la quando o arquivo nao e deste
+// pacote.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+type Config struct {
+	Token    string `json:"token"`
+	ApiURL   string `json:"api_url"`
+	Interval int    `json:"interval_min"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+
+	// HostRoot e onde o / do host esta montado quando o agente roda em
+	// container; vazio procura /host, /rootfs e /hostfs (ver environment.go).
+	HostRoot string `json:"host_root,omitempty"`
+
+	// SplaySeconds espera um tempo sorteado (0 a SplaySeconds) antes de
+	// cada coleta do cron e antes da primeira do daemon.
+	SplaySeconds int `json:"splay_seconds,omitempty"`
+
+	// Metadata vai no payload e nos avisos como contexto de roteamento.
+	Metadata *HostMetadata `json:"metadata,omitempty"`
+
+	// Profile preenche padroes para o porte do host (ver profile.go).
+	Profile string `json:"profile,omitempty"`
+
+	// Collectors liga/desliga coletores individualmente. Coletores ausentes
+	// do mapa ficam ligados.
+	Collectors map[string]bool `json:"collectors,omitempty"`
+
+	// Flags libera recursos por percentual da frota (ver flags.go).
+	Flags map[string]float64 `json:"flags,omitempty"`
+
+	ContainerFilter ContainerFilter `json:"containers,omitempty"`
+
+	// DockerHosts sao daemons remotos (tcp://, ssh://) lidos alem dos
+	// locais (ver dockerhosts.go).
+	DockerHosts []DockerHost `json:"docker_hosts,omitempty"`
+
+	// ContainerRollups: "on" (padrao) envia somatorios por imagem e projeto
+	// compose junto das linhas por container; "only" envia so os somatorios.
+	ContainerRollups string `json:"container_rollups,omitempty"`
+
+	StatsTiers []StatsTier `json:"stats_tiers,omitempty"`
+
+	// LogScan conta as linhas de log dos containers que casam com padroes
+	// (ver logscan.go). Desligado por padrao.
+	LogScan *LogScanConfig `json:"log_scan,omitempty"`
+
+	Checks []Check `json:"checks,omitempty"`
+
+	// CloudMetadata consulta o servico de metadados do provedor de nuvem
+	// (ver cloud.go). Desligado por padrao.
+	CloudMetadata bool `json:"cloud_metadata,omitempty"`
+
+	// SystemdUnits lista as units acompanhadas; vazio reporta so as units
+	// com falha.
+	SystemdUnits []string `json:"systemd_units,omitempty"`
+
+	// Sensors ajusta os limites de temperatura e ventoinhas do hwmon (ver
+	// sensors.go); os sensores sao lidos mesmo sem ela.
+	Sensors *SensorsConfig `json:"sensors,omitempty"`
+
+	Alerts AlertsConfig `json:"alerts,omitempty"`
+
+	PayloadFields FieldFilter `json:"payload_fields,omitempty"`
+
+	// OTLP envia as metricas tambem (ou so) a um coletor OpenTelemetry
+	// (ver otlp.go).
+	OTLP *OTLPExporter `json:"otlp,omitempty"`
+
+	// Sinks sao saidas extras de metricas, como InfluxDB (ver sinks.go).
+	Sinks []SinkConfig `json:"sinks,omitempty"`
+
+	// API ajusta tentativas e prazo do envio a API (ver pipeline.go).
+	API SinkPolicy `json:"api,omitempty"`
+
+	// Offline e para hosts sem acesso a API: os payloads vao so para os
+	// sinks (tipicamente um file) e chegam ao servidor pelo replay.
+	Offline bool `json:"offline,omitempty"`
+
+	// Scrape lista endpoints /metrics locais repassados no payload (ver
+	// scrape.go).
+	Scrape []ScrapeTarget `json:"scrape,omitempty"`
+
+	// TextfileDir e o diretorio de arquivos .prom no formato do textfile
+	// collector do node_exporter (ver textfile.go).
+	TextfileDir string `json:"textfile_dir,omitempty"`
+
+	// MySQL liga o coletor do MySQL/MariaDB (ver mysql.go).
+	MySQL *MySQLConfig `json:"mysql,omitempty"`
+
+	// Postgres liga o coletor do PostgreSQL (ver postgres.go).
+	Postgres *PostgresConfig `json:"postgres,omitempty"`
+
+	// Redis lista as instancias do coletor do Redis (ver redis.go).
+	Redis []RedisInstance `json:"redis,omitempty"`
+
+	// MongoDB lista as instancias do coletor do MongoDB (ver mongodb.go).
+	MongoDB []MongoDBInstance `json:"mongodb,omitempty"`
+
+	// Nginx e Apache listam as paginas de status dos servidores web (ver
+	// webstatus.go).
+	Nginx  []StatusPage `json:"nginx,omitempty"`
+	Apache []StatusPage `json:"apache,omitempty"`
+
+	// RabbitMQ lista as instancias lidas pela API de gerenciamento (ver
+	// rabbitmq.go).
+	RabbitMQ []RabbitMQInstance `json:"rabbitmq,omitempty"`
+
+	// Elasticsearch lista os clusters Elasticsearch e OpenSearch (ver
+	// elasticsearch.go).
+	Elasticsearch []ElasticsearchInstance `json:"elasticsearch,omitempty"`
+
+	// Kafka lista os clusters Kafka lidos pela API de administracao (ver
+	// kafka.go).
+	Kafka []KafkaCluster `json:"kafka,omitempty"`
+
+	// Memcached lista as instancias lidas pelo comando stats (ver
+	// memcached.go).
+	Memcached []MemcachedInstance `json:"memcached,omitempty"`
+
+	// StatsD recebe metricas das aplicacoes no modo daemon (ver statsd.go).
+	StatsD *StatsDConfig `json:"statsd,omitempty"`
+
+	// HTTP ajusta o pool de conexoes com a API.
+	HTTP HTTPTuning `json:"http,omitempty"`
+
+	// CommandPollSeconds consulta a API por pedidos de coleta imediata
+	// ("atualizar agora") no modo daemon; zero desliga (ver trigger.go).
+	CommandPollSeconds int `json:"command_poll_seconds,omitempty"`
+
+	// Spool limita o backlog guardado enquanto a API esta fora.
+	Spool SpoolLimits `json:"spool,omitempty"`
+
+	PluginsDir   string       `json:"plugins_dir,omitempty"`
+	PluginLimits PluginLimits `json:"plugin_limits,omitempty"`
+
+	// WasmCapabilities libera recursos do host por plugin .wasm, pelo nome
+	// do arquivo sem extensao.
+	WasmCapabilities map[string]WasmCapabilities `json:"wasm_capabilities,omitempty"`
+
+	// PluginRegistryURL e PluginTrustedKeys servem ao "plugin install". As
+	// chaves sao ed25519 em base64; manifests sem assinatura valida de uma
+	// delas sao recusados.
+	PluginRegistryURL string   `json:"plugin_registry_url,omitempty"`
+	PluginTrustedKeys []string `json:"plugin_trusted_keys,omitempty"`
+
+	// UpdateURL e UpdateTrustedKeys servem ao "self-update", como as
+	// equivalentes de plugin. AutoUpdate aplica as novas versoes sozinho,
+	// no maximo uma verificacao por dia.
+	UpdateURL         string   `json:"update_url,omitempty"`
+	UpdateTrustedKeys []string `json:"update_trusted_keys,omitempty"`
+	AutoUpdate        bool     `json:"auto_update,omitempty"`
+
+	// Kubernetes troca o docker pelo kubelet do no (ver kubernetes.go).
+	Kubernetes         bool   `json:"kubernetes,omitempty"`
+	KubeletURL         string `json:"kubelet_url,omitempty"`
+	KubeletInsecureTLS bool   `json:"kubelet_insecure_tls,omitempty"`
+
+	// RemoteConfig liga a busca de configuracao em /api/agent/config.
+	RemoteConfig    bool   `json:"remote_config,omitempty"`
+	RemoteConfigURL string `json:"remote_config_url,omitempty"`
+
+	// ConfigPath e o arquivo de origem, usado para regravar o token;
+	// TokenFile, o arquivo do token quando ele vem de token_file, e
+	// TokenEnv, a variavel quando ele vem do ambiente. Sao preenchidos na
+	// leitura e nunca vao para o arquivo.
+	ConfigPath string `json:"-"`
+	TokenFile  string `json:"-"`
+	TokenEnv   string `json:"-"`
+	// ApiURLs e a lista de api_url, quando o config traz mais de um endereco.
+	ApiURLs []string `json:"-"`
+}
+
+// Nomes aceitos na secao "collectors" do config.
+const (
+	CollectorCPU            = "cpu"
+	CollectorMemory         = "memory"
+	CollectorDisk           = "disk"
+	CollectorDisks          = "disks"
+	CollectorLoad           = "load"
+	CollectorDocker         = "docker"
+	CollectorDockerStats    = "docker_stats"
+	CollectorDockerNet      = "docker_net"
+	CollectorDockerEvents   = "docker_events"
+	CollectorDockerDisk     = "docker_disk"
+	CollectorDockerVolumes  = "docker_volumes"
+	CollectorDockerImages   = "docker_images"
+	CollectorDockerSwarm    = "docker_swarm"
+	CollectorDockerLogs     = "docker_logs"
+	CollectorDockerNetworks = "docker_networks"
+	CollectorHost           = "host"
+	CollectorPlugins        = "plugins"
+	CollectorChecks         = "checks"
+	CollectorSystemd        = "systemd"
+	CollectorCloud          = "cloud"
+	CollectorStatsD         = "statsd"
+	CollectorScrape         = "scrape"
+	CollectorTextfile       = "textfile"
+	CollectorMySQL          = "mysql"
+	CollectorPostgres       = "postgres"
+	CollectorRedis          = "redis"
+	CollectorMongoDB        = "mongodb"
+	CollectorNginx          = "nginx"
+	CollectorApache         = "apache"
+	CollectorRabbitMQ       = "rabbitmq"
+	CollectorElastic        = "elasticsearch"
+	CollectorKafka          = "kafka"
+	CollectorMemcached      = "memcached"
+	CollectorGPU            = "gpu"
+	CollectorSensors        = "sensors"
+	CollectorNVMe           = "nvme"
+	CollectorMDRaid         = "mdraid"
+	CollectorZFS            = "zfs"
+	CollectorLVM            = "lvm"
+)
+
+var KnownCollectors = []string{
+	CollectorCPU, CollectorMemory, CollectorDisk, CollectorDisks,
+	CollectorLoad, CollectorDocker, CollectorDockerStats, CollectorDockerNet,
+	CollectorHost, CollectorPlugins, CollectorChecks, CollectorSystemd,
+	CollectorCloud, CollectorStatsD, CollectorScrape,
+	CollectorTextfile, CollectorMySQL, CollectorPostgres, CollectorRedis,
+	CollectorMongoDB, CollectorNginx, CollectorApache, CollectorRabbitMQ,
+	CollectorElastic, CollectorKafka, CollectorMemcached, CollectorDockerEvents,
+	CollectorDockerDisk, CollectorDockerVolumes, CollectorDockerImages,
+	CollectorDockerSwarm, CollectorDockerLogs, CollectorDockerNetworks,
+	CollectorGPU, CollectorSensors, CollectorNVMe, CollectorMDRaid,
+	CollectorZFS, CollectorLVM,
+}
+
+// UpdateFile edita as chaves do arquivo de config em forma bruta,
+// preservando as demais como estao, e grava de forma atomica para nunca
+// deixar um config pela metade. Configs YAML e TOML sao editados no texto
+// (ver rewriteConfigKeys).
+func UpdateFile(path string, edit func(raw map[string]json.RawMessage) error) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	raw, err := DecodeFile(path, b)
+	if err != nil {
+		return err
+	}
+	before := maps.Clone(raw)
+	if err := edit(raw); err != nil {
+		return err
+	}
+	var out []byte
+	if configFormat(path) == configFormatJSON {
+		out, err = json.MarshalIndent(raw, "", "  ")
+	} else {
+		out, err = rewriteConfigKeys(path, b, before, raw)
+	}
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, out, 0o600)
+}
+
+// Layer e uma fonte de configuracao, com as chaves que ela define.
+type Layer struct {
+	Source string
+	Raw    map[string]json.RawMessage
+}
+
+// configEnvVars sao as variaveis de ambiente aceitas; elas prevalecem sobre o
+// arquivo, o que facilita containers e DaemonSets com o token em um Secret.
+var configEnvVars = []struct {
+	name string
+	key  string
+	kind string
+}{
+	{"VAULTRIX_TOKEN", "token", "string"},
+	{"VAULTRIX_TOKEN_FILE", "token", "file"},
+	{"VAULTRIX_API_URL", "api_url", "string"},
+	{"VAULTRIX_PROXY_URL", "proxy_url", "string"},
+	{"VAULTRIX_INTERVAL", "interval_min", "int"},
+	{"VAULTRIX_SPLAY", "splay_seconds", "int"},
+	{"VAULTRIX_HOSTNAME", "hostname", "string"},
+	{"VAULTRIX_HOST_ROOT", "host_root", "string"},
+	{"VAULTRIX_PLUGINS_DIR", "plugins_dir", "string"},
+	{"VAULTRIX_REMOTE_CONFIG", "remote_config", "bool"},
+	{"VAULTRIX_OFFLINE", "offline", "bool"},
+	{"VAULTRIX_TEXTFILE_DIR", "textfile_dir", "string"},
+}
+
+// ReadLayers le o arquivo e aplica as variaveis de ambiente, sem
+// validar o resultado.
+func ReadLayers(path string) (Config, []Layer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	file, err := DecodeFile(path, b)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	var tokenEnv string
+	tokenFile, err := resolveSecretFiles(file, filepath.Dir(path))
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	layers := []Layer{{Source: "file", Raw: file}}
+
+	merged := maps.Clone(file)
+	profile, defaults, err := profileLayer(file)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if defaults != nil {
+		layers = append([]Layer{{Source: "profile:" + profile, Raw: defaults}}, layers...)
+		merged = mergeOver(defaults, file)
+	}
+	for _, env := range configEnvVars {
+		value, ok := os.LookupEnv(env.name)
+		if !ok {
+			continue
+		}
+		var encoded any = value
+		switch env.kind {
+		case "file":
+			secret, err := readSecretFile(value)
+			if err != nil {
+				return Config{}, nil, fmt.Errorf("%s: %w", env.name, err)
+			}
+			encoded = secret
+		case "int":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return Config{}, nil, fmt.Errorf("%s: invalid integer %q", env.name, value)
+			}
+			encoded = n
+		case "bool":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return Config{}, nil, fmt.Errorf("%s: invalid boolean %q", env.name, value)
+			}
+			encoded = v
+		}
+		if env.key == "token" {
+			// a rotacao precisa saber de onde veio o token em uso: de um
+			// arquivo ela o regrava; de uma variavel, nao tem como
+			tokenFile, tokenEnv = "", ""
+			if env.kind == "file" {
+				tokenFile = value
+			} else {
+				tokenEnv = env.name
+			}
+		}
+		raw, _ := json.Marshal(encoded)
+		merged[env.key] = raw
+		layers = append(layers, Layer{Source: "env:" + env.name, Raw: map[string]json.RawMessage{env.key: raw}})
+	}
+
+	apiURLs, err := SplitAPIURLs(merged)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	b, err = json.Marshal(merged)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return Config{}, nil, err
+	}
+	cfg.ConfigPath = path
+	cfg.TokenFile = tokenFile
+	cfg.TokenEnv = tokenEnv
+	cfg.ApiURLs = apiURLs
+	return cfg, layers, nil
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
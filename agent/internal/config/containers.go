@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DockerHost e um daemon remoto lido pelo CLI local, para hosts pequenos
+// que nao rodam o agente (NAS, roteadores). Com ssh:// o CLI usa o ssh do
+// sistema e as chaves do usuario do agente; com tcp:// e TLS, os
+// certificados abaixo.
+type DockerHost struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+
+	// Runtime e docker (padrao) ou podman, que aceita ssh:// e tcp:// no
+	// --url.
+	Runtime string `json:"runtime,omitempty"`
+
+	// Arquivos PEM do TLS do daemon docker; TLSCACert liga o --tlsverify,
+	// e TLSCert e TLSKey, juntos, autenticam o agente.
+	TLSCACert string `json:"tls_ca_cert,omitempty"`
+	TLSCert   string `json:"tls_cert,omitempty"`
+	TLSKey    string `json:"tls_key,omitempty"`
+}
+
+// ContainerFilter seleciona quais containers entram no payload. Padroes de
+// include/exclude sao globs comparados com o nome e com a imagem; seletores
+// de label usam "chave=valor" ou apenas "chave".
+type ContainerFilter struct {
+	Include      []string `json:"include,omitempty"`
+	Exclude      []string `json:"exclude,omitempty"`
+	Label        []string `json:"label,omitempty"`
+	ExcludeLabel []string `json:"exclude_label,omitempty"`
+}
+
+func (f ContainerFilter) Validate() error {
+	for _, list := range [][]string{f.Include, f.Exclude} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid container pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	for _, list := range [][]string{f.Label, f.ExcludeLabel} {
+		for _, selector := range list {
+			if strings.TrimSpace(selector) == "" || strings.HasPrefix(selector, "=") {
+				return fmt.Errorf("invalid label selector %q", selector)
+			}
+			// ver parseLabels: um valor com ",k=v" nunca casaria com o docker
+			if _, value, ok := strings.Cut(selector, "="); ok && ambiguousLabelValue(value) {
+				return fmt.Errorf("invalid label selector %q: a value cannot contain a comma followed by \"=\"", selector)
+			}
+		}
+	}
+	return nil
+}
+
+func ambiguousLabelValue(value string) bool {
+	_, rest, ok := strings.Cut(value, ",")
+	return ok && strings.Contains(rest, "=")
+}
+
+const RollupsOff = "off"
+
+// StatsTier define com que frequencia o docker stats e coletado para os
+// containers que casam com Match (globs de nome/imagem) e Label. A primeira
+// regra que casar vale; containers sem regra ficam em "full".
+type StatsTier struct {
+	Match []string `json:"match,omitempty"`
+	Label []string `json:"label,omitempty"`
+	Tier  string   `json:"tier"`
+	Every int      `json:"every,omitempty"`
+}
+
+// LogScanConfig liga a contagem de linhas de log por container: a cada
+// ciclo o agente le o que cada container escreveu desde o ciclo anterior e
+// conta as linhas que casam com cada padrao. Metricas de CPU e memoria nao
+// mostram uma aplicacao lancando excecoes; o log mostra.
+type LogScanConfig struct {
+	// Patterns sao expressoes regulares (RE2) por nome; vazio usa error,
+	// panic e oom (ver defaultLogPatterns).
+	Patterns map[string]string `json:"patterns,omitempty"`
+
+	// MaxLines limita as linhas lidas por container a cada ciclo; passando
+	// disso so as ultimas sao lidas e o container vem com logTruncated.
+	MaxLines int `json:"max_lines,omitempty"`
+}
+
+const defaultLogScanMaxLines = 10000
+
+const MaxLogScanMaxLines = 100000
+
+const maxLogScanPatterns = 20
+
+var DefaultLogPatterns = map[string]string{
+	"error": `(?i)\berror\b`,
+	"panic": `(?i)\bpanic\b`,
+	"oom":   `(?i)out of memory|outofmemory|\boom\b`,
+}
+
+func (l *LogScanConfig) MaxLinesOrDefault() int {
+	if l.MaxLines > 0 {
+		return l.MaxLines
+	}
+	return defaultLogScanMaxLines
+}
+
+// PatternsOrDefault compila os padroes; o validate ja recusou os invalidos.
+func (l *LogScanConfig) PatternsOrDefault() map[string]*regexp.Regexp {
+	src := l.Patterns
+	if len(src) == 0 {
+		src = DefaultLogPatterns
+	}
+	compiled := make(map[string]*regexp.Regexp, len(src))
+	for name, expr := range src {
+		if re, err := regexp.Compile(expr); err == nil {
+			compiled[name] = re
+		}
+	}
+	return compiled
+}
+
+func (l *LogScanConfig) Validate() error {
+	if l == nil {
+		return nil
+	}
+	if len(l.Patterns) > maxLogScanPatterns {
+		return fmt.Errorf("log_scan: at most %d patterns", maxLogScanPatterns)
+	}
+	for _, name := range sortedKeys(l.Patterns) {
+		if !SinkNamePattern.MatchString(name) {
+			return fmt.Errorf("log_scan: invalid pattern name %q (lowercase letters, digits, - and _)", name)
+		}
+		if _, err := regexp.Compile(l.Patterns[name]); err != nil {
+			return fmt.Errorf("log_scan: pattern %s: %v", name, err)
+		}
+	}
+	if l.MaxLines < 0 || l.MaxLines > MaxLogScanMaxLines {
+		return fmt.Errorf("log_scan: max_lines must be between 0 and %d", MaxLogScanMaxLines)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Formatos aceitos no arquivo de config, pela extensao. YAML e TOML sao
+// convertidos para o mesmo mapa de chaves do JSON; o resto do agente nao
+// sabe de onde o config veio.
+const (
+	configFormatJSON = "json"
+	configFormatYAML = "yaml"
+	configFormatTOML = "toml"
+)
+
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return configFormatYAML
+	case ".toml":
+		return configFormatTOML
+	}
+	return configFormatJSON
+}
+
+// DecodeFile le o conteudo do arquivo de config como chaves de topo
+// em JSON bruto.
+func DecodeFile(path string, b []byte) (map[string]json.RawMessage, error) {
+	raw := make(map[string]json.RawMessage)
+	var doc any
+	var err error
+	switch configFormat(path) {
+	case configFormatYAML:
+		// JSON tambem e YAML valido; o install grava JSON em qualquer caminho
+		if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+			return raw, json.Unmarshal(b, &raw)
+		}
+		doc, err = parseYAML(string(b))
+	case configFormatTOML:
+		doc, err = parseTOML(string(b))
+	default:
+		return raw, json.Unmarshal(b, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, fmt.Errorf("%s: the config must be a mapping of keys", filepath.Base(path))
+	}
+	converted, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return raw, json.Unmarshal(converted, &raw)
+}
+
+// rewriteConfigKeys aplica ao texto de um config YAML ou TOML as chaves que
+// UpdateFile mudou. So chaves de topo com valor escalar (o token, na
+// rotacao) sao reescritas, linha a linha, para preservar comentarios e
+// formatacao; o resto precisa ser editado a mao.
+func rewriteConfigKeys(path string, src []byte, before, after map[string]json.RawMessage) ([]byte, error) {
+	format := configFormat(path)
+	for key, value := range after {
+		if old, ok := before[key]; ok && bytes.Equal(old, value) {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(value, &v); err != nil {
+			return nil, err
+		}
+		switch v.(type) {
+		case string, float64, bool:
+		default:
+			return nil, fmt.Errorf("cannot rewrite %q in a %s config; edit %s by hand", key, format, path)
+		}
+		src = setTopLevelScalar(format, src, key, string(value))
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			return nil, fmt.Errorf("cannot remove %q from a %s config; edit %s by hand", key, format, path)
+		}
+	}
+	return src, nil
+}
+
+// setTopLevelScalar troca ou acrescenta "chave: valor" (YAML) ou
+// "chave = valor" (TOML) no nivel de topo. O valor vem em JSON, que e um
+// escalar valido nos dois formatos.
+func setTopLevelScalar(format string, src []byte, key, value string) []byte {
+	sep := ": "
+	pattern := `^` + regexp.QuoteMeta(key) + `\s*:(\s|$)`
+	if format == configFormatTOML {
+		sep = " = "
+		pattern = `^` + regexp.QuoteMeta(key) + `\s*=`
+	}
+	re := regexp.MustCompile(pattern)
+	line := key + sep + value
+
+	lines := strings.Split(string(src), "\n")
+	for i, l := range lines {
+		// no TOML o topo termina na primeira tabela
+		if format == configFormatTOML && strings.HasPrefix(strings.TrimSpace(l), "[") {
+			lines = append(lines[:i], append([]string{line}, lines[i:]...)...)
+			return []byte(strings.Join(lines, "\n"))
+		}
+		if re.MatchString(l) {
+			lines[i] = line
+			return []byte(strings.Join(lines, "\n"))
+		}
+	}
+	out := strings.TrimRight(string(src), "\n")
+	if out != "" {
+		out += "\n"
+	}
+	return []byte(out + line + "\n")
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewriteConfigKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		src     string
+		set     map[string]string // chave -> valor em JSON; "" remove
+		keep    string            // trecho que precisa sobreviver
+		wantErr string
+	}{
+		{
+			name: "yaml token",
+			path: "config.yaml",
+			src:  "# agente\ntoken: old # rotacionado\ninterval: 5\ncollectors:\n  docker: false\n",
+			set:  map[string]string{"token": `"new"`},
+			keep: "# agente\n",
+		},
+		{
+			name: "yaml new key",
+			path: "config.yml",
+			src:  "token: abc\n",
+			set:  map[string]string{"remote_config": `true`},
+			keep: "token: abc\n",
+		},
+		{
+			name: "toml token",
+			path: "config.toml",
+			src:  "# agente\ntoken = \"old\"\n\n[collectors]\ndocker = false\n",
+			set:  map[string]string{"token": `"new"`},
+			keep: "[collectors]\ndocker = false\n",
+		},
+		{
+			// no TOML a chave nova precisa ficar antes da primeira tabela
+			name: "toml new key before table",
+			path: "config.toml",
+			src:  "token = \"abc\"\n[collectors]\ndocker = false\n",
+			set:  map[string]string{"interval": `3`},
+			keep: "[collectors]\ndocker = false\n",
+		},
+		{
+			name:    "nested value",
+			path:    "config.yaml",
+			src:     "token: abc\n",
+			set:     map[string]string{"collectors": `{"plugins":true}`},
+			wantErr: `cannot rewrite "collectors"`,
+		},
+		{
+			name:    "removed key",
+			path:    "config.toml",
+			src:     "token = \"abc\"\ninterval = 5\n",
+			set:     map[string]string{"interval": ""},
+			wantErr: `cannot remove "interval"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := DecodeFile(tt.path, []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			after := maps.Clone(before)
+			for k, v := range tt.set {
+				if v == "" {
+					delete(after, k)
+				} else {
+					after[k] = json.RawMessage(v)
+				}
+			}
+
+			out, err := rewriteConfigKeys(tt.path, []byte(tt.src), before, after)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), tt.keep) {
+				t.Errorf("lost %q:\n%s", tt.keep, out)
+			}
+			got, err := DecodeFile(tt.path, out)
+			if err != nil {
+				t.Fatalf("rewritten config does not parse: %v\n%s", err, out)
+			}
+			if !reflect.DeepEqual(normalizeRaw(t, got), normalizeRaw(t, after)) {
+				t.Errorf("round trip:\n got %s\nwant %s", mustJSON(t, got), mustJSON(t, after))
+			}
+		})
+	}
+}
+
+// normalizeRaw decodifica os valores para comparar sem depender de espacos.
+func normalizeRaw(t *testing.T, raw map[string]json.RawMessage) map[string]any {
+	t.Helper()
+	out := make(map[string]any, len(raw))
+	for k, v := range raw {
+		var x any
+		if err := json.Unmarshal(v, &x); err != nil {
+			t.Fatal(err)
+		}
+		out[k] = x
+	}
+	return out
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
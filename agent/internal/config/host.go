@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// HostMetadata e o contexto de roteamento definido onde o host e
+// provisionado: quem responde por ele, onde esta o runbook e o quanto ele
+// importa. Vai no payload e nos avisos dos alertas locais sem alteracao.
+type HostMetadata struct {
+	Owner       string `json:"owner,omitempty"`
+	Contact     string `json:"contact,omitempty"`
+	Runbook     string `json:"runbook,omitempty"`
+	Criticality string `json:"criticality,omitempty"`
+}
+
+var criticalityLevels = []string{"low", "medium", "high", "critical"}
+
+func (m *HostMetadata) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.Criticality != "" && !slices.Contains(criticalityLevels, m.Criticality) {
+		return fmt.Errorf("metadata: criticality must be one of %v", criticalityLevels)
+	}
+	if m.Runbook != "" {
+		u, err := url.Parse(m.Runbook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("metadata: runbook must be an http or https URL, got %q", m.Runbook)
+		}
+	}
+	return nil
+}
+
+const maxSensorThresholds = 100
+
+// SensorsConfig ajusta os limites dos sensores de hardware. Sem ela valem os
+// limites do proprio chip (tempN_max e tempN_crit, fanN_min).
+type SensorsConfig struct {
+	// WarningCelsius e CriticalCelsius valem para todas as temperaturas sem
+	// limite proprio em Thresholds.
+	WarningCelsius  float64 `json:"warning_celsius,omitempty"`
+	CriticalCelsius float64 `json:"critical_celsius,omitempty"`
+
+	// MinFanRPM marca como critica uma ventoinha abaixo dessa rotacao.
+	MinFanRPM int `json:"min_fan_rpm,omitempty"`
+
+	// Thresholds sao limites de temperatura por sensor ("chip/label", como
+	// em "coretemp/Package id 0") ou por chip inteiro ("nvme"). O nome do
+	// driver vale tambem para os chips repetidos ("nvme" para "nvme-nvme0").
+	Thresholds map[string]SensorThreshold `json:"thresholds,omitempty"`
+}
+
+type SensorThreshold struct {
+	WarningCelsius  float64 `json:"warning_celsius,omitempty"`
+	CriticalCelsius float64 `json:"critical_celsius,omitempty"`
+}
+
+func (s *SensorsConfig) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.MinFanRPM < 0 {
+		return fmt.Errorf("sensors: min_fan_rpm must not be negative")
+	}
+	if err := validateSensorThreshold("sensors", SensorThreshold{s.WarningCelsius, s.CriticalCelsius}); err != nil {
+		return err
+	}
+	if len(s.Thresholds) > maxSensorThresholds {
+		return fmt.Errorf("sensors: at most %d thresholds", maxSensorThresholds)
+	}
+	for _, name := range sortedKeys(s.Thresholds) {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("sensors: threshold with an empty sensor name")
+		}
+		if err := validateSensorThreshold("sensors: "+name, s.Thresholds[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSensorThreshold(prefix string, t SensorThreshold) error {
+	for _, v := range []float64{t.WarningCelsius, t.CriticalCelsius} {
+		if v < 0 || v > 200 {
+			return fmt.Errorf("%s: temperature thresholds must be between 0 and 200", prefix)
+		}
+	}
+	if t.WarningCelsius > 0 && t.CriticalCelsius > 0 && t.WarningCelsius >= t.CriticalCelsius {
+		return fmt.Errorf("%s: warning_celsius must be below critical_celsius", prefix)
+	}
+	return nil
+}
+
+// TemperatureLimits aplica, do mais especifico ao mais geral, o limite do
+// sensor em Thresholds, o do chip em Thresholds, o global da config e o
+// informado pelo chip.
+func (s *SensorsConfig) TemperatureLimits(chip, label string, chipWarning, chipCritical *float64) (warning, critical *float64) {
+	warning, critical = chipWarning, chipCritical
+	if s == nil {
+		return warning, critical
+	}
+	driver, _, _ := strings.Cut(chip, "-")
+	for _, t := range []SensorThreshold{
+		{s.WarningCelsius, s.CriticalCelsius},
+		s.Thresholds[driver], s.Thresholds[chip],
+		s.Thresholds[driver+"/"+label], s.Thresholds[chip+"/"+label],
+	} {
+		if t.WarningCelsius > 0 {
+			warning = &t.WarningCelsius
+		}
+		if t.CriticalCelsius > 0 {
+			critical = &t.CriticalCelsius
+		}
+	}
+	return warning, critical
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MySQLConfig liga o coletor do MySQL/MariaDB: conexoes, threads, slow
+// queries, buffer pool do InnoDB e atraso de replicacao, ao lado das
+// metricas do host. O usuario so precisa de PROCESS e REPLICATION CLIENT
+// (GRANT PROCESS, REPLICATION CLIENT ON *.* TO ...).
+type MySQLConfig struct {
+	// DSN segue o formato do go-sql-driver:
+	// "usuario:senha@tcp(127.0.0.1:3306)/" ou
+	// "usuario:senha@unix(/run/mysqld/mysqld.sock)/". Com "dsn_file" ele
+	// vem de um arquivo, fora do config.
+	DSN            string `json:"dsn"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+const (
+	defaultMySQLTimeout    = 5 * time.Second
+	maxMySQLTimeoutSeconds = 60
+	defaultMySQLAddr       = "127.0.0.1:3306"
+)
+
+func (m *MySQLConfig) Timeout() time.Duration {
+	if m.TimeoutSeconds > 0 {
+		return time.Duration(m.TimeoutSeconds) * time.Second
+	}
+	return defaultMySQLTimeout
+}
+
+func (m *MySQLConfig) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if _, err := ParseMySQLDSN(m.DSN); err != nil {
+		return err
+	}
+	if m.TimeoutSeconds < 0 || m.TimeoutSeconds > maxMySQLTimeoutSeconds {
+		return fmt.Errorf("mysql: timeout_seconds must be between 0 and %d", maxMySQLTimeoutSeconds)
+	}
+	return nil
+}
+
+type MySQLDSN struct {
+	User     string
+	Password string
+	Net      string
+	Addr     string
+	DB       string
+}
+
+// ParseMySQLDSN interpreta "[usuario[:senha]@][rede[(endereco)]][/[banco]][?params]".
+// A senha pode conter "@" e "/": vale o ultimo "@", e o endereco termina no
+// ")", ja que um socket unix tem "/" no caminho. Sem "/banco" a conexao nao
+// escolhe banco, como com "/" no fim. Os parametros sao ignorados.
+func ParseMySQLDSN(dsn string) (MySQLDSN, error) {
+	var d MySQLDSN
+	head := dsn
+	if at := strings.LastIndex(dsn, "@"); at >= 0 {
+		d.User, d.Password, _ = strings.Cut(dsn[:at], ":")
+		head = dsn[at+1:]
+	}
+	if d.User == "" {
+		return d, errors.New("mysql: the dsn has no user")
+	}
+	addrEnd := strings.Index(head, ")") + 1
+	if i := strings.IndexAny(head[addrEnd:], "/?"); i >= 0 {
+		rest := head[addrEnd+i:]
+		head = head[:addrEnd+i]
+		if db, ok := strings.CutPrefix(rest, "/"); ok {
+			d.DB, _, _ = strings.Cut(db, "?")
+		}
+	}
+
+	d.Net = "tcp"
+	if head != "" {
+		net, addr, ok := strings.Cut(head, "(")
+		if ok && !strings.HasSuffix(addr, ")") {
+			return d, errors.New("mysql: invalid dsn address")
+		}
+		d.Net, d.Addr = net, strings.TrimSuffix(addr, ")")
+	}
+	switch d.Net {
+	case "tcp":
+		if d.Addr == "" {
+			d.Addr = defaultMySQLAddr
+		}
+		if !strings.Contains(d.Addr, ":") || strings.HasSuffix(d.Addr, "]") {
+			d.Addr += ":3306"
+		}
+	case "unix":
+		if d.Addr == "" {
+			return d, errors.New("mysql: unix dsn needs the socket path")
+		}
+	default:
+		return d, fmt.Errorf("mysql: unsupported network %q (use tcp or unix)", d.Net)
+	}
+	return d, nil
+}
+
+// RedactMySQLDSN esconde a senha para o "config show".
+func RedactMySQLDSN(dsn string) string {
+	d, err := ParseMySQLDSN(dsn)
+	if err != nil {
+		return "***"
+	}
+	user := d.User
+	if d.Password != "" {
+		user += ":***"
+	}
+	return fmt.Sprintf("%s@%s(%s)/%s", user, d.Net, d.Addr, d.DB)
+}
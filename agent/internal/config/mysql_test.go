@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseMySQLDSN(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    MySQLDSN
+		wantErr bool
+	}{
+		{"monitor:secret@tcp(db.local:3307)/", MySQLDSN{User: "monitor", Password: "secret", Net: "tcp", Addr: "db.local:3307"}, false},
+		{"monitor:secret@tcp(db.local)/app?timeout=5s", MySQLDSN{User: "monitor", Password: "secret", Net: "tcp", Addr: "db.local:3306", DB: "app"}, false},
+		{"monitor@/", MySQLDSN{User: "monitor", Net: "tcp", Addr: "127.0.0.1:3306"}, false},
+		{"monitor:p@ss/w@rd@unix(/run/mysqld/mysqld.sock)/", MySQLDSN{User: "monitor", Password: "p@ss/w@rd", Net: "unix", Addr: "/run/mysqld/mysqld.sock"}, false},
+		{"monitor@tcp([::1])/", MySQLDSN{User: "monitor", Net: "tcp", Addr: "[::1]:3306"}, false},
+		{"monitor:secret@tcp(db.local:3306)", MySQLDSN{User: "monitor", Password: "secret", Net: "tcp", Addr: "db.local:3306"}, false},
+		{"monitor@tcp(db.local)?timeout=5s", MySQLDSN{User: "monitor", Net: "tcp", Addr: "db.local:3306"}, false},
+		{"monitor:p@ss/w@rd@unix(/run/mysqld/mysqld.sock)", MySQLDSN{User: "monitor", Password: "p@ss/w@rd", Net: "unix", Addr: "/run/mysqld/mysqld.sock"}, false},
+		{"monitor@tcp(db.local)app", MySQLDSN{}, true},
+		{"tcp(127.0.0.1:3306)/", MySQLDSN{}, true},
+		{"monitor@udp(127.0.0.1)/", MySQLDSN{}, true},
+		{"monitor@unix()/", MySQLDSN{}, true},
+		{"monitor@tcp(127.0.0.1/", MySQLDSN{}, true},
+		{"127.0.0.1:3306", MySQLDSN{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMySQLDSN(tt.dsn)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMySQLDSN(%q) err = %v, wantErr %v", tt.dsn, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseMySQLDSN(%q) = %+v, want %+v", tt.dsn, got, tt.want)
+		}
+	}
+	if got := RedactMySQLDSN("monitor:secret@tcp(db.local)/"); got != "monitor:***@tcp(db.local:3306)/" {
+		t.Errorf("redact = %q", got)
+	}
+	if got := RedactMySQLDSN("monitor:secret@tcp(db.local)"); got != "monitor:***@tcp(db.local:3306)/" {
+		t.Errorf("redact without a database = %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+// PluginLimits restringe os recursos de cada processo de plugin. Zero usa o
+// padrao; valores negativos desligam o limite.
+type PluginLimits struct {
+	MemoryMB     int `json:"memory_mb,omitempty"`
+	CPUSeconds   int `json:"cpu_seconds,omitempty"`
+	MaxOpenFiles int `json:"max_open_files,omitempty"`
+}
+
+func (l PluginLimits) WithDefaults() PluginLimits {
+	pick := func(v, def int) int {
+		switch {
+		case v < 0:
+			return 0
+		case v == 0:
+			return def
+		}
+		return v
+	}
+	return PluginLimits{
+		MemoryMB:     pick(l.MemoryMB, 256),
+		CPUSeconds:   pick(l.CPUSeconds, 10),
+		MaxOpenFiles: pick(l.MaxOpenFiles, 64),
+	}
+}
+
+// WasmCapabilities libera recursos do host para um plugin WebAssembly. Sem
+// entrada na config, o plugin so conversa pelo protocolo: nao ve arquivos,
+// rede nem processos.
+type WasmCapabilities struct {
+	// ReadPaths sao arquivos ou diretorios que o plugin pode ler.
+	ReadPaths []string `json:"read_paths,omitempty"`
+	// Commands sao os executaveis que o plugin pode rodar, pelo nome exato.
+	Commands []string `json:"commands,omitempty"`
+	// HTTPHosts sao os hosts (host ou host:porta) aceitos no http_get.
+	HTTPHosts []string `json:"http_hosts,omitempty"`
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Perfis prontos para o tamanho do host. O perfil so muda padroes: o que o
+// config define explicitamente continua valendo, inclusive coletores
+// ligados de volta dentro de "collectors".
+const (
+	profileMinimal  = "minimal"
+	ProfileStandard = "standard"
+	profileFull     = "full"
+)
+
+// configProfiles sao as chaves que cada perfil preenche.
+var configProfiles = map[string]map[string]any{
+	// VPS de 256 MB, roteadores: so as metricas basicas e a lista de
+	// containers, sem somatorios e com um spool pequeno.
+	profileMinimal: {
+		"collectors": map[string]bool{
+			CollectorDisks:         false,
+			CollectorDockerStats:   false,
+			CollectorDockerNet:     false,
+			CollectorDockerDisk:    false,
+			CollectorDockerVolumes: false,
+			CollectorPlugins:       false,
+			CollectorSystemd:       false,
+		},
+		"container_rollups": RollupsOff,
+		"http":              map[string]int{"max_idle_conns": 1},
+		"spool":             map[string]int{"max_mb": 5, "max_files": 200},
+	},
+	ProfileStandard: {},
+	// hosts grandes: tudo ligado e mais espaco para o backlog.
+	profileFull: {
+		"spool": map[string]int{"max_mb": 200, "max_files": 10000},
+	},
+}
+
+// Multiplicador dos prazos dos coletores por perfil: no minimal um coletor
+// lento e abandonado antes, no full ele tem mais tempo.
+var profileTimeoutScale = map[string]float64{
+	profileMinimal: 0.5,
+	profileFull:    2,
+}
+
+// profileLayer devolve as chaves do perfil pedido no arquivo de config.
+func profileLayer(file map[string]json.RawMessage) (string, map[string]json.RawMessage, error) {
+	v, ok := file["profile"]
+	if !ok {
+		return "", nil, nil
+	}
+	var name string
+	if err := json.Unmarshal(v, &name); err != nil {
+		return "", nil, fmt.Errorf("profile must be a string")
+	}
+	values, ok := configProfiles[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown profile %q (minimal, standard or full)", name)
+	}
+	raw := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", nil, err
+		}
+		raw[key] = b
+	}
+	return name, raw, nil
+}
+
+// mergeOver aplica over sobre base. Objetos presentes nos dois sao mesclados
+// chave a chave, para que "collectors": {"docker": false} no arquivo nao
+// descarte o resto do perfil.
+func mergeOver(base, over map[string]json.RawMessage) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		var a, b map[string]json.RawMessage
+		if prev, ok := out[k]; ok && json.Unmarshal(prev, &a) == nil && json.Unmarshal(v, &b) == nil && a != nil && b != nil {
+			if merged, err := json.Marshal(mergeOver(a, b)); err == nil {
+				out[k] = merged
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// CollectorTimeout ajusta o prazo base de um coletor ao perfil.
+func (c Config) CollectorTimeout(base time.Duration) time.Duration {
+	if scale, ok := profileTimeoutScale[c.Profile]; ok {
+		return time.Duration(float64(base) * scale)
+	}
+	return base
+}
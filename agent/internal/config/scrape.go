@@ -0,0 +1,55 @@
+package config
+
+import (
+	"path"
+	"time"
+)
+
+// ScrapeTarget e um endpoint /metrics local (node_exporter, exportadores de
+// aplicacao) que o agente le a cada ciclo e repassa no payload em
+// "scraped": para hosts que ja expoem metricas Prometheus, o vaultrix faz o
+// papel de um gateway leve, sem um Prometheus ao lado.
+type ScrapeTarget struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Metrics e a allowlist de nomes; aceita curingas ("node_load*").
+	// Exportadores expoem centenas de series, e so as listadas vao no
+	// payload.
+	Metrics []string `json:"metrics"`
+
+	Headers        map[string]string `json:"headers,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+
+	// MaxSamples limita as amostras repassadas; as demais sao contadas em
+	// truncated.
+	MaxSamples int `json:"max_samples,omitempty"`
+}
+
+const defaultScrapeTimeout = 5 * time.Second
+
+const DefaultScrapeMaxSamples = 1000
+
+func (s ScrapeTarget) Timeout() time.Duration {
+	if s.TimeoutSeconds > 0 {
+		return time.Duration(s.TimeoutSeconds) * time.Second
+	}
+	return defaultScrapeTimeout
+}
+
+func (s ScrapeTarget) MaxSamplesOrDefault() int {
+	if s.MaxSamples > 0 {
+		return s.MaxSamples
+	}
+	return DefaultScrapeMaxSamples
+}
+
+// Allowed diz se a metrica esta na allowlist.
+func (s ScrapeTarget) Allowed(name string) bool {
+	for _, pattern := range s.Metrics {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Qualquer chave de texto do config pode vir de um arquivo: "token_file"
+// no lugar de "token", "password_file" no lugar de "password" em
+// alerts.email, e assim por diante. O segredo fica num arquivo so do root
+// ou num Secret montado, e o config.json pode circular sem ele.
+const (
+	secretFileSuffix = "_file"
+
+	// Tamanho maximo de um arquivo de segredo.
+	maxSecretFileSize = 64 << 10
+)
+
+// resolveSecretFiles troca, em todos os niveis, cada "<chave>_file" pelo
+// conteudo do arquivo em "<chave>". Caminhos relativos partem do diretorio
+// do config. Devolve tambem o arquivo de onde veio o token, para a rotacao
+// grava-lo ali.
+func resolveSecretFiles(raw map[string]json.RawMessage, configDir string) (string, error) {
+	var tokenFile string
+	if v, ok := raw["token"+secretFileSuffix]; ok && json.Unmarshal(v, &tokenFile) == nil && tokenFile != "" {
+		tokenFile = secretFilePath(tokenFile, configDir)
+	}
+	return tokenFile, resolveSecretObject(raw, configDir, "")
+}
+
+func resolveSecretObject(obj map[string]json.RawMessage, configDir, prefix string) error {
+	for _, key := range sortedKeys(obj) {
+		value := obj[key]
+		base, ok := strings.CutSuffix(key, secretFileSuffix)
+		if !ok || base == "" {
+			// so desce (e reserializa) onde ha alguma chave *_file
+			if !strings.Contains(string(value), secretFileSuffix+`"`) {
+				continue
+			}
+			resolved, err := resolveSecretNested(value, configDir, prefix+key)
+			if err != nil {
+				return err
+			}
+			obj[key] = resolved
+			continue
+		}
+		if _, dup := obj[base]; dup {
+			return fmt.Errorf("%s%s and %s%s are both set; use only one", prefix, base, prefix, key)
+		}
+		var path string
+		if err := json.Unmarshal(value, &path); err != nil || path == "" {
+			return fmt.Errorf("%s%s must be a file path", prefix, key)
+		}
+		secret, err := readSecretFile(secretFilePath(path, configDir))
+		if err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, key, err)
+		}
+		obj[base], _ = json.Marshal(secret)
+		delete(obj, key)
+	}
+	return nil
+}
+
+// resolveSecretNested trata objetos e listas de objetos dentro do config.
+func resolveSecretNested(value json.RawMessage, configDir, path string) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(value, &obj) == nil && obj != nil {
+		if err := resolveSecretObject(obj, configDir, path+"."); err != nil {
+			return nil, err
+		}
+		return json.Marshal(obj)
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(value, &list) != nil {
+		return value, nil
+	}
+	for i, item := range list {
+		resolved, err := resolveSecretNested(item, configDir, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		list[i] = resolved
+	}
+	return json.Marshal(list)
+}
+
+func secretFilePath(path, configDir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(configDir, path)
+}
+
+// readSecretFile le o segredo sem a quebra de linha final que editores e
+// "echo" acrescentam.
+func readSecretFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, maxSecretFileSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(b) > maxSecretFileSize {
+		return "", fmt.Errorf("%s is larger than %d bytes", path, maxSecretFileSize)
+	}
+	secret := strings.TrimRight(string(b), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"token":    "file-token-0123456789\n",
+		"smtp":     "smtp-secret\r\n",
+		"slack":    "https://hooks.slack.com/services/T/B/X",
+		"empty":    "\n",
+		"too-big":  strings.Repeat("x", maxSecretFileSize+1),
+		"basic-pw": "pw",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		config        string
+		want          string
+		wantTokenFile string
+		wantErr       string
+	}{
+		{
+			name:          "token relative to the config",
+			config:        `{"token_file": "token", "api_url": "https://x"}`,
+			want:          `{"api_url": "https://x", "token": "file-token-0123456789"}`,
+			wantTokenFile: filepath.Join(dir, "token"),
+		},
+		{
+			name:   "nested objects and lists",
+			config: `{"alerts": {"slack_webhook_url_file": "` + filepath.Join(dir, "slack") + `", "email": {"password_file": "smtp"}}, "checks": [{"name": "a", "password_file": "basic-pw"}, {"name": "b"}]}`,
+			want:   `{"alerts": {"slack_webhook_url": "https://hooks.slack.com/services/T/B/X", "email": {"password": "smtp-secret"}}, "checks": [{"name": "a", "password": "pw"}, {"name": "b"}]}`,
+		},
+		{
+			name:    "both set",
+			config:  `{"token": "a", "token_file": "token"}`,
+			wantErr: "token and token_file are both set",
+		},
+		{
+			name:    "empty file",
+			config:  `{"alerts": {"email": {"password_file": "empty"}}}`,
+			wantErr: "alerts.email.password_file",
+		},
+		{name: "missing file", config: `{"token_file": "nope"}`, wantErr: "token_file"},
+		{name: "too big", config: `{"token_file": "too-big"}`, wantErr: "larger than"},
+		{name: "not a path", config: `{"token_file": 3}`, wantErr: "must be a file path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.config), &raw); err != nil {
+				t.Fatal(err)
+			}
+			tokenFile, err := resolveSecretFiles(raw, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tokenFile != tt.wantTokenFile {
+				t.Errorf("token file = %q, want %q", tokenFile, tt.wantTokenFile)
+			}
+			b, _ := json.Marshal(raw)
+			var got, want any
+			json.Unmarshal(b, &got)
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %s\nwant %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(filepath.Join(dir, "token"), []byte("file-token-0123456789\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "env-token"), []byte("env-token-0123456789\n"), 0o600)
+	os.WriteFile(path, []byte(`{"token_file": "token", "api_url": "https://vaultrix.example.com/api/telemetry", "interval_min": 5}`), 0o600)
+	// sem variaveis do ambiente de quem roda o teste
+	for _, name := range []string{"VAULTRIX_TOKEN", "VAULTRIX_TOKEN_FILE"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	cfg, _, err := ReadLayers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Token != "file-token-0123456789" || cfg.TokenFile != filepath.Join(dir, "token") {
+		t.Errorf("token = %q from %q", cfg.Token, cfg.TokenFile)
+	}
+
+	// VAULTRIX_TOKEN_FILE vence o arquivo, e a rotacao passa a gravar nele
+	t.Setenv("VAULTRIX_TOKEN_FILE", filepath.Join(dir, "env-token"))
+	if cfg, _, err = ReadLayers(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Token != "env-token-0123456789" || cfg.TokenFile != filepath.Join(dir, "env-token") {
+		t.Errorf("token = %q from %q", cfg.Token, cfg.TokenFile)
+	}
+}
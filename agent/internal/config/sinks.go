@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// SinkConfig e um item de "sinks". Name identifica o sink nos logs e no
+// spool; vazio usa o tipo, o que basta enquanto houver um sink de cada.
+type SinkConfig struct {
+	Name    string            `json:"name,omitempty"`
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Token vai como "Authorization: Token <token>" (InfluxDB 2.x).
+	Token string `json:"token,omitempty"`
+
+	// Spool guarda os payloads que falharem por rede ou 5xx e os reenvia
+	// quando o destino voltar, como a API faz. Sem ele, a falha so e
+	// registrada.
+	Spool bool `json:"spool,omitempty"`
+
+	// MaxMB e o tamanho em que o sink file roda o arquivo; MaxFiles, quantos
+	// arquivos rodados ele guarda.
+	MaxMB    int `json:"max_mb,omitempty"`
+	MaxFiles int `json:"max_files,omitempty"`
+
+	// Tentativas e prazo (ver pipeline.go).
+	SinkPolicy
+}
+
+func (s SinkConfig) NameOrDefault() string {
+	return orDefault(s.Name, s.Type)
+}
+
+var SinkNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// SendsToAPI diz se os payloads vao para a API do vaultrix: nao vao em
+// hosts offline nem com otlp.mode "only".
+func (c Config) SendsToAPI() bool {
+	return !c.Offline && !c.OTLP.only()
+}
+
+const (
+	defaultFileSinkMaxMB    = 10
+	defaultFileSinkMaxFiles = 10
+)
+
+// FileLimits devolve max_mb e max_files com os padroes aplicados.
+func (s SinkConfig) FileLimits() (maxMB, maxFiles int) {
+	maxMB, maxFiles = s.MaxMB, s.MaxFiles
+	if maxMB == 0 {
+		maxMB = defaultFileSinkMaxMB
+	}
+	if maxFiles == 0 {
+		maxFiles = defaultFileSinkMaxFiles
+	}
+	return maxMB, maxFiles
+}
+
+// Modos do exportador OTLP: "also" (padrao) envia para o coletor alem da
+// API do vaultrix; "only" envia so para o coletor.
+const (
+	OTLPAlso = "also"
+	OTLPOnly = "only"
+)
+
+// OTLPExporter envia as metricas coletadas a um coletor OpenTelemetry, por
+// OTLP/HTTP com corpo JSON. Endpoint e a URL base do coletor
+// (http://otel:4318); /v1/metrics e acrescentado quando falta. Headers
+// servem para autenticacao (ex.: "Authorization").
+type OTLPExporter struct {
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Mode     string            `json:"mode,omitempty"`
+}
+
+func (o *OTLPExporter) only() bool {
+	return o != nil && o.Mode == OTLPOnly
+}
+
+func (o *OTLPExporter) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.Mode {
+	case "", OTLPAlso, OTLPOnly:
+	default:
+		return fmt.Errorf("otlp: invalid mode %q (use %q or %q)", o.Mode, OTLPAlso, OTLPOnly)
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("otlp: invalid endpoint %q; use the collector's http or https URL", o.Endpoint)
+	}
+	for name := range o.Headers {
+		if name == "" || strings.ContainsAny(name, ": \r\n") {
+			return fmt.Errorf("otlp: invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
+// SinkPolicy ajusta como um sink reage a falhas. Vale para cada item de
+// "sinks" e, em "api", para a API do vaultrix.
+type SinkPolicy struct {
+	// Retries sao novas tentativas no mesmo ciclo para as falhas que o
+	// spool guardaria, com espera de RetryBackoffSeconds dobrando a cada uma.
+	Retries             int `json:"retries,omitempty"`
+	RetryBackoffSeconds int `json:"retry_backoff_seconds,omitempty"`
+
+	// TimeoutSeconds limita o envio inteiro, tentativas e failover entre
+	// enderecos incluidos; zero deixa so o prazo de cada requisicao.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+const maxSinkRetries = 5
+
+const maxSinkTimeout = 300
+
+func (p SinkPolicy) Validate(prefix string) error {
+	if p.Retries < 0 || p.Retries > maxSinkRetries {
+		return fmt.Errorf("%sretries must be between 0 and %d", prefix, maxSinkRetries)
+	}
+	if p.RetryBackoffSeconds < 0 || p.TimeoutSeconds < 0 || p.TimeoutSeconds > maxSinkTimeout {
+		return fmt.Errorf("%sretry_backoff_seconds must not be negative and timeout_seconds must be between 0 and %d", prefix, maxSinkTimeout)
+	}
+	return nil
+}
+
+// SpoolLimits limita o spool em disco. Zero usa o padrao; valores negativos
+// desligam o limite.
+type SpoolLimits struct {
+	MaxMB    int `json:"max_mb,omitempty"`
+	MaxFiles int `json:"max_files,omitempty"`
+}
+
+func (l SpoolLimits) WithDefaults() SpoolLimits {
+	pick := func(v, def int) int {
+		switch {
+		case v < 0:
+			return 0
+		case v == 0:
+			return def
+		}
+		return v
+	}
+	return SpoolLimits{
+		MaxMB:    pick(l.MaxMB, 50),
+		MaxFiles: pick(l.MaxFiles, 2000),
+	}
+}
+
+// FieldFilter restringe os campos do payload, para implantacoes com regras de
+// minimizacao de dados. Os caminhos usam os nomes do JSON separados por
+// ponto ("host.machine_id", "containers.name"); listas valem para todos os
+// itens e "*" casa qualquer chave ("plugins.*.data"). Com Allow, so os
+// caminhos listados (e o que estiver abaixo deles) sao enviados; Deny
+// remove caminhos mesmo que permitidos.
+type FieldFilter struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+func (f FieldFilter) Empty() bool {
+	return len(f.Allow) == 0 && len(f.Deny) == 0
+}
+
+// Permits diz se o campo ("metrics.cpu") sai do host, para saidas que nao
+// passam por marshalPayload (ver sinks.go).
+func (f FieldFilter) Permits(field string) bool {
+	path := strings.Split(field, ".")
+	return f.Allowed(path) && !f.Denied(path)
+}
+
+func (f FieldFilter) Denied(path []string) bool {
+	for _, d := range f.Deny {
+		if covers(strings.Split(d, "."), path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed aceita o caminho se ele esta sob uma entrada de Allow ou se e um
+// ancestral dela (o objeto e mantido e filtrado por dentro).
+func (f FieldFilter) Allowed(path []string) bool {
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, a := range f.Allow {
+		pattern := strings.Split(a, ".")
+		if covers(pattern, path) || len(path) < len(pattern) && covers(pattern[:len(path)], path) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers diz se path esta em pattern ou abaixo dele.
+func covers(pattern, path []string) bool {
+	if len(path) < len(pattern) {
+		return false
+	}
+	for i, seg := range pattern {
+		if seg != "*" && seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
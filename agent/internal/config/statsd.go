@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// StatsDConfig liga, no modo daemon, um listener UDP de StatsD: as
+// aplicacoes do host mandam counters, gauges, timers e sets para ele, e a
+// cada ciclo o agregado vai no payload em app_metrics, sem outra
+// infraestrutura. No modo cron nao ha processo para escutar entre as
+// execucoes.
+type StatsDConfig struct {
+	// Listen e o endereco UDP; o padrao so aceita o proprio host.
+	Listen string `json:"listen,omitempty"`
+
+	// MaxMetrics limita as series distintas por ciclo; as que passarem
+	// disso sao descartadas e contadas em dropped.
+	MaxMetrics int `json:"max_metrics,omitempty"`
+}
+
+const defaultStatsDListen = "127.0.0.1:8125"
+
+const defaultStatsDMaxMetrics = 1000
+
+func (s *StatsDConfig) ListenOrDefault() string {
+	return orDefault(s.Listen, defaultStatsDListen)
+}
+
+func (s *StatsDConfig) MaxMetricsOrDefault() int {
+	if s.MaxMetrics > 0 {
+		return s.MaxMetrics
+	}
+	return defaultStatsDMaxMetrics
+}
+
+func (s *StatsDConfig) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(s.ListenOrDefault()); err != nil {
+		return fmt.Errorf("statsd: invalid listen address %q: %v", s.Listen, err)
+	}
+	if s.MaxMetrics < 0 {
+		return errors.New("statsd: max_metrics must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,469 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML le um config em TOML 1.0: tabelas, arrays de tabelas, chaves com
+// ponto, strings (basicas, literais e multilinha), numeros, booleanos,
+// arrays e tabelas inline. Datas viram strings, ja que nenhuma opcao do
+// config e data.
+func parseTOML(src string) (map[string]any, error) {
+	p := &tomlParser{s: strings.ReplaceAll(src, "\r\n", "\n"), line: 1}
+	root := map[string]any{}
+	current := root
+	// tabelas definidas explicitamente com [nome], para recusar repeticoes
+	defined := map[string]bool{}
+
+	for {
+		p.skipTrivia(true)
+		if p.eof() {
+			return root, nil
+		}
+		switch {
+		case strings.HasPrefix(p.s[p.i:], "[["):
+			p.i += 2
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume("]]") {
+				return nil, p.errorf("expected ]]")
+			}
+			parent, err := tomlDescend(root, keys[:len(keys)-1])
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			last := keys[len(keys)-1]
+			list, ok := parent[last].([]any)
+			if _, exists := parent[last]; exists && !ok {
+				return nil, p.errorf("%s is not an array of tables", strings.Join(keys, "."))
+			}
+			current = map[string]any{}
+			parent[last] = append(list, current)
+		case p.s[p.i] == '[':
+			p.i++
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume("]") {
+				return nil, p.errorf("expected ]")
+			}
+			name := strings.Join(keys, "\x00")
+			if defined[name] {
+				return nil, p.errorf("table %s defined twice", strings.Join(keys, "."))
+			}
+			defined[name] = true
+			if current, err = tomlDescend(root, keys); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+		default:
+			if err := p.keyValue(current); err != nil {
+				return nil, err
+			}
+		}
+		p.skipTrivia(false)
+		if !p.eof() && p.s[p.i] != '\n' {
+			return nil, p.errorf("expected end of line")
+		}
+	}
+}
+
+// tomlDescend devolve a tabela no caminho keys, criando as que faltam. Em um
+// array de tabelas, o caminho segue pelo ultimo elemento.
+func tomlDescend(t map[string]any, keys []string) (map[string]any, error) {
+	for _, k := range keys {
+		switch v := t[k].(type) {
+		case nil:
+			next := map[string]any{}
+			t[k] = next
+			t = next
+		case map[string]any:
+			t = v
+		case []any:
+			last, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is not a table", k)
+			}
+			t = last
+		default:
+			return nil, fmt.Errorf("%s is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+type tomlParser struct {
+	s    string
+	i    int
+	line int
+}
+
+func (p *tomlParser) eof() bool { return p.i >= len(p.s) }
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("toml line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) consume(tok string) bool {
+	p.skipTrivia(false)
+	if strings.HasPrefix(p.s[p.i:], tok) {
+		p.i += len(tok)
+		return true
+	}
+	return false
+}
+
+// skipTrivia pula espacos e comentarios e, com newlines, tambem quebras de
+// linha.
+func (p *tomlParser) skipTrivia(newlines bool) {
+	for !p.eof() {
+		switch c := p.s[p.i]; {
+		case c == ' ' || c == '\t':
+			p.i++
+		case c == '#':
+			for !p.eof() && p.s[p.i] != '\n' {
+				p.i++
+			}
+		case c == '\n' && newlines:
+			p.line++
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) keyValue(t map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !p.consume("=") {
+		return p.errorf("expected = after %s", strings.Join(keys, "."))
+	}
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := tomlDescend(t, keys[:len(keys)-1])
+	if err != nil {
+		return p.errorf("%v", err)
+	}
+	last := keys[len(keys)-1]
+	if _, dup := parent[last]; dup {
+		return p.errorf("duplicate key %s", strings.Join(keys, "."))
+	}
+	parent[last] = v
+	return nil
+}
+
+// key le uma chave, possivelmente com pontos e partes entre aspas.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipTrivia(false)
+		if p.eof() {
+			return nil, p.errorf("expected a key")
+		}
+		switch p.s[p.i] {
+		case '"':
+			k, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		case '\'':
+			k, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		default:
+			start := p.i
+			for !p.eof() && isTOMLBareKeyChar(p.s[p.i]) {
+				p.i++
+			}
+			if p.i == start {
+				return nil, p.errorf("invalid key character %q", p.s[p.i])
+			}
+			keys = append(keys, p.s[start:p.i])
+		}
+		p.skipTrivia(false)
+		if p.eof() || p.s[p.i] != '.' {
+			return keys, nil
+		}
+		p.i++
+	}
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (any, error) {
+	p.skipTrivia(false)
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+	rest := p.s[p.i:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multilineString(`"""`)
+	case strings.HasPrefix(rest, "'''"):
+		return p.multilineString("'''")
+	case rest[0] == '"':
+		return p.basicString()
+	case rest[0] == '\'':
+		return p.literalString()
+	case rest[0] == '[':
+		return p.array()
+	case rest[0] == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(rest, "true") && !p.bareContinues(4):
+		p.i += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false") && !p.bareContinues(5):
+		p.i += 5
+		return false, nil
+	}
+
+	start := p.i
+	for !p.eof() && !strings.ContainsRune(",]}\n#", rune(p.s[p.i])) {
+		p.i++
+	}
+	raw := strings.TrimSpace(p.s[start:p.i])
+	return p.scalar(raw)
+}
+
+func (p *tomlParser) bareContinues(n int) bool {
+	return p.i+n < len(p.s) && isTOMLBareKeyChar(p.s[p.i+n])
+}
+
+// scalar interpreta numeros e datas.
+func (p *tomlParser) scalar(raw string) (any, error) {
+	switch strings.TrimLeft(raw, "+-") {
+	case "inf", "nan":
+		return nil, p.errorf("%s is not supported", raw)
+	}
+	if raw == "" {
+		return nil, p.errorf("expected a value")
+	}
+	// datas e horas (1979-05-27, 07:32:00, 1979-05-27T07:32:00Z)
+	if len(raw) >= 8 && (raw[4] == '-' || raw[2] == ':') && strings.Trim(raw, "0123456789-:.TZtz+ ") == "" {
+		return raw, nil
+	}
+
+	clean := strings.ReplaceAll(raw, "_", "")
+	if strings.Contains(raw, "__") || strings.HasPrefix(raw, "_") || strings.HasSuffix(raw, "_") {
+		return nil, p.errorf("invalid number %q", raw)
+	}
+	switch {
+	case strings.HasPrefix(clean, "0x"), strings.HasPrefix(clean, "0o"), strings.HasPrefix(clean, "0b"):
+		n, err := strconv.ParseInt(clean, 0, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", raw)
+		}
+		return n, nil
+	}
+	digits := strings.TrimLeft(clean, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return nil, p.errorf("leading zeros are not allowed in %q", raw)
+	}
+	if n, err := strconv.ParseInt(clean, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil && strings.ContainsAny(clean, "0123456789") {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q", raw)
+}
+
+func (p *tomlParser) array() ([]any, error) {
+	p.i++
+	list := []any{}
+	for {
+		p.skipTrivia(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.s[p.i] == ']' {
+			p.i++
+			return list, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		p.skipTrivia(true)
+		if !p.eof() && p.s[p.i] == ',' {
+			p.i++
+			continue
+		}
+		if p.eof() || p.s[p.i] != ']' {
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.i++
+	t := map[string]any{}
+	p.skipTrivia(false)
+	if !p.eof() && p.s[p.i] == '}' {
+		p.i++
+		return t, nil
+	}
+	for {
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipTrivia(false)
+		if p.eof() {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) literalString() (string, error) {
+	p.i++
+	end := strings.IndexAny(p.s[p.i:], "'\n")
+	if end < 0 || p.s[p.i+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.s[p.i : p.i+end]
+	p.i += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) basicString() (string, error) {
+	p.i++
+	var b strings.Builder
+	for {
+		if p.eof() || p.s[p.i] == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.s[p.i]
+		switch c {
+		case '"':
+			p.i++
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.i++
+		}
+	}
+}
+
+// multilineString le strings multilinha, basicas ou literais. A quebra
+// logo apos a abertura e descartada; em strings basicas, "\" no fim da
+// linha junta as linhas.
+func (p *tomlParser) multilineString(delim string) (string, error) {
+	p.i += 3
+	if strings.HasPrefix(p.s[p.i:], "\n") {
+		p.i++
+		p.line++
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.s[p.i:], delim) {
+			// ate duas aspas extras antes do fechamento fazem parte do texto
+			extra := 0
+			for extra < 2 && strings.HasPrefix(p.s[p.i+extra+1:], delim) {
+				extra++
+			}
+			b.WriteString(p.s[p.i : p.i+extra])
+			p.i += extra + 3
+			return b.String(), nil
+		}
+		c := p.s[p.i]
+		switch {
+		case c == '\\' && delim == `"""`:
+			rest := p.s[p.i+1:]
+			trimmed := strings.TrimLeft(rest, " \t")
+			if strings.HasPrefix(trimmed, "\n") {
+				p.i++
+				for !p.eof() && strings.ContainsRune(" \t\n", rune(p.s[p.i])) {
+					if p.s[p.i] == '\n' {
+						p.line++
+					}
+					p.i++
+				}
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+			p.i++
+		}
+	}
+}
+
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.i+1 >= len(p.s) {
+		return p.errorf("unterminated string")
+	}
+	c := p.s[p.i+1]
+	p.i += 2
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.i+n > len(p.s) {
+			return p.errorf("invalid unicode escape")
+		}
+		r, err := strconv.ParseUint(p.s[p.i:p.i+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return p.errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(r))
+		p.i += n
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
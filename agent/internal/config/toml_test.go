@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string // documento em JSON
+		wantErr string
+	}{
+		{
+			name: "top level and table",
+			src:  "token = \"abc\"\ninterval = 5\n[collectors]\ndocker = false\n",
+			want: `{"collectors":{"docker":false},"interval":5,"token":"abc"}`,
+		},
+		{
+			name: "array of tables",
+			src:  "[[checks]]\nname = \"web\"\n[[checks]]\nname = \"db\"\n",
+			want: `{"checks":[{"name":"web"},{"name":"db"}]}`,
+		},
+		{
+			name: "inline table and trailing comma",
+			src:  "http = { max_idle_conns = 2 }\ninclude = [\"a\", 'b',]\n",
+			want: `{"http":{"max_idle_conns":2},"include":["a","b"]}`,
+		},
+		{
+			name: "dotted keys",
+			src:  "a.b.c = 1\n",
+			want: `{"a":{"b":{"c":1}}}`,
+		},
+		{
+			name: "multiline strings",
+			src:  "s = '''\nraw\\n'''\nm = \"\"\"\nx\\ty\"\"\"\n",
+			want: `{"m":"x\ty","s":"raw\\n"}`,
+		},
+		{
+			name: "numbers",
+			src:  "n = 1_000\nh = 0xff\nf = 1.5\n",
+			want: `{"f":1.5,"h":255,"n":1000}`,
+		},
+		{
+			name: "dates stay strings",
+			src:  "d = 1979-05-27\n",
+			want: `{"d":"1979-05-27"}`,
+		},
+		{
+			name:    "duplicate key",
+			src:     "a = 1\na = 2\n",
+			wantErr: "line 2: duplicate key a",
+		},
+		{
+			name:    "leading zero",
+			src:     "a = 01\n",
+			wantErr: "leading zeros are not allowed",
+		},
+		{
+			name:    "inf",
+			src:     "a = inf\n",
+			wantErr: "inf is not supported",
+		},
+		{
+			name:    "unterminated string",
+			src:     "a = \"x\n",
+			wantErr: "unterminated string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseTOML(tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(doc)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
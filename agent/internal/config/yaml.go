@@ -0,0 +1,509 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML le o subconjunto de YAML usado em arquivos de config: mapas e
+// listas em bloco, escalares simples ou entre aspas, blocos | e >, listas e
+// mapas inline de uma linha e comentarios. Ancoras, tags e documentos
+// multiplos nao sao aceitos; o resultado vira JSON e segue o mesmo caminho
+// do config em JSON.
+func parseYAML(src string) (any, error) {
+	p := &yamlParser{}
+	for i, text := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		p.lines = append(p.lines, yamlLine{num: i + 1, raw: text})
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) && strings.TrimSpace(p.lines[p.pos].raw) == "---" {
+		p.pos++
+		p.skipBlank()
+	}
+	if p.pos >= len(p.lines) {
+		return map[string]any{}, nil
+	}
+	v, err := p.node(p.lines[p.pos].indent())
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) && strings.TrimSpace(p.lines[p.pos].raw) != "..." {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	num int
+	raw string
+	// offset substitui a indentacao real quando o conteudo de um "- " e
+	// tratado como uma linha propria.
+	offset int
+	text   string
+	split  bool
+}
+
+func (l yamlLine) indent() int {
+	if l.split {
+		return l.offset
+	}
+	return len(l.raw) - len(strings.TrimLeft(l.raw, " "))
+}
+
+func (l yamlLine) content() string {
+	if l.split {
+		return l.text
+	}
+	return stripYAMLComment(strings.TrimSpace(l.raw))
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	num := len(p.lines)
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	}
+	return fmt.Errorf("yaml line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// skipBlank pula linhas vazias e de comentario.
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].content() == "" {
+		p.pos++
+	}
+}
+
+func isYAMLSeqItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+func (p *yamlParser) node(indent int) (any, error) {
+	if strings.HasPrefix(strings.TrimLeft(p.lines[p.pos].raw, " "), "\t") {
+		return nil, p.errorf("tabs are not allowed for indentation")
+	}
+	if isYAMLSeqItem(p.lines[p.pos].content()) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	list := []any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return list, nil
+		}
+		line := p.lines[p.pos]
+		text := line.content()
+		if line.indent() != indent || !isYAMLSeqItem(text) {
+			if line.indent() > indent {
+				return nil, p.errorf("unexpected indentation")
+			}
+			return list, nil
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			p.skipBlank()
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent() <= indent {
+				list = append(list, nil)
+				continue
+			}
+			v, err := p.node(p.lines[p.pos].indent())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		if isYAMLSeqItem(rest) || yamlKeyEnd(rest) >= 0 {
+			// "- chave: valor" abre um mapa na coluna do conteudo
+			col := indent + len(text) - len(rest)
+			p.lines[p.pos] = yamlLine{num: line.num, raw: line.raw, split: true, offset: col, text: rest}
+			v, err := p.node(col)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		v, err := p.inlineValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	m := map[string]any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return m, nil
+		}
+		line := p.lines[p.pos]
+		text := line.content()
+		if line.indent() < indent || (line.indent() == indent && isYAMLSeqItem(text)) {
+			return m, nil
+		}
+		if line.indent() > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		end := yamlKeyEnd(text)
+		if end < 0 {
+			return nil, p.errorf("expected \"key: value\"")
+		}
+		key, err := yamlScalarString(strings.TrimSpace(text[:end]))
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		rest := strings.TrimSpace(text[end+1:])
+
+		if rest != "" {
+			v, err := p.inlineValue(rest, indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		p.pos++
+		p.skipBlank()
+		switch {
+		case p.pos >= len(p.lines):
+			m[key] = nil
+		case p.lines[p.pos].indent() > indent:
+			v, err := p.node(p.lines[p.pos].indent())
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		case p.lines[p.pos].indent() == indent && isYAMLSeqItem(p.lines[p.pos].content()):
+			// lista na mesma coluna da chave, estilo comum em YAML
+			v, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		default:
+			m[key] = nil
+		}
+	}
+}
+
+// inlineValue le o valor que segue "chave:" ou "- " e avanca a linha.
+func (p *yamlParser) inlineValue(rest string, indent int) (any, error) {
+	switch {
+	case strings.HasPrefix(rest, "&"), strings.HasPrefix(rest, "*"), strings.HasPrefix(rest, "!"):
+		return nil, p.errorf("anchors, aliases and tags are not supported")
+	case rest == "|" || rest == ">" || rest == "|-" || rest == ">-" || rest == "|+" || rest == ">+":
+		return p.blockScalar(rest, indent), nil
+	}
+	p.pos++
+	if strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "{") {
+		f := &yamlFlow{s: rest}
+		v, err := f.value()
+		if err == nil {
+			f.skipSpace()
+			if f.i < len(f.s) {
+				err = fmt.Errorf("unexpected %q after flow collection", f.s[f.i:])
+			}
+		}
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("%v", err)
+		}
+		return v, nil
+	}
+	v, err := yamlScalar(rest)
+	if err != nil {
+		p.pos--
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+// blockScalar le um bloco | (quebras mantidas) ou > (linhas unidas).
+func (p *yamlParser) blockScalar(header string, indent int) string {
+	p.pos++
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		raw := p.lines[p.pos].raw
+		if strings.TrimSpace(raw) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		n := len(raw) - len(strings.TrimLeft(raw, " "))
+		if n <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = n
+		}
+		if n < blockIndent {
+			break
+		}
+		lines = append(lines, raw[blockIndent:])
+		p.pos++
+	}
+	// linhas vazias no fim pertencem ao bloco so com o indicador +
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var s string
+	if header[0] == '|' {
+		s = strings.Join(lines, "\n")
+	} else {
+		var b strings.Builder
+		for i, l := range lines {
+			// linhas vazias viram quebras; as demais se juntam com espaco
+			switch {
+			case i == 0, lines[i-1] == "" && l != "":
+			case l == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(l)
+		}
+		s = b.String()
+	}
+	switch {
+	case strings.HasSuffix(header, "-") || len(lines) == 0:
+	case strings.HasSuffix(header, "+"):
+		s += strings.Repeat("\n", trailing+1)
+	default:
+		s += "\n"
+	}
+	return s
+}
+
+// yamlKeyEnd devolve a posicao do ":" que separa chave e valor, fora de
+// aspas, ou -1.
+func yamlKeyEnd(s string) int {
+	if s == "" || s[0] == '[' || s[0] == '{' {
+		return -1
+	}
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(s)-1 || s[i+1] == ' '):
+			return i
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment remove um "#" precedido de espaco fora de aspas.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" [{,:-", rune(s[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return s
+}
+
+func yamlScalarString(s string) (string, error) {
+	v, err := yamlScalar(s)
+	if err != nil {
+		return "", err
+	}
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	return s, nil
+}
+
+// yamlScalar interpreta um escalar com as regras do YAML 1.2 (core schema).
+func yamlScalar(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'o') {
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return n, nil
+		}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, "0123456789") && !strings.ContainsAny(s, "xX_") {
+		return f, nil
+	}
+	return s, nil
+}
+
+// yamlFlow le colecoes inline como [a, b] e {k: v}, em uma linha so.
+type yamlFlow struct {
+	s string
+	i int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) value() (any, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		list := []any{}
+		for {
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return list, nil
+			}
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.i++
+		m := map[string]any{}
+		for {
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return m, nil
+			}
+			k, err := f.scalar(true)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			f.skipSpace()
+			if f.i >= len(f.s) || f.s[f.i] != ':' {
+				return nil, fmt.Errorf("expected ':' after key %q", key)
+			}
+			f.i++
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar(false)
+}
+
+// separator consome a virgula entre itens, deixando o fechamento para o
+// laco.
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpace()
+	if f.i < len(f.s) && f.s[f.i] == ',' {
+		f.i++
+		return nil
+	}
+	if f.i < len(f.s) && f.s[f.i] == closing {
+		return nil
+	}
+	return fmt.Errorf("expected ',' or '%c'", closing)
+}
+
+func (f *yamlFlow) scalar(key bool) (any, error) {
+	f.skipSpace()
+	start := f.i
+	if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+		quote := f.s[f.i]
+		for f.i++; f.i < len(f.s); f.i++ {
+			if f.s[f.i] == '\\' && quote == '"' {
+				f.i++
+				continue
+			}
+			if f.s[f.i] == quote {
+				if quote == '\'' && f.i+1 < len(f.s) && f.s[f.i+1] == '\'' {
+					f.i++
+					continue
+				}
+				f.i++
+				return yamlScalar(f.s[start:f.i])
+			}
+		}
+		return nil, fmt.Errorf("unterminated string")
+	}
+	for f.i < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.i])) {
+		if key && f.s[f.i] == ':' {
+			break
+		}
+		f.i++
+	}
+	return yamlScalar(strings.TrimSpace(f.s[start:f.i]))
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string // documento em JSON
+		wantErr string
+	}{
+		{
+			name: "scalars",
+			src:  "token: abc\ninterval: 5\nremote_config: true\nhostname: ~\n",
+			want: `{"hostname":null,"interval":5,"remote_config":true,"token":"abc"}`,
+		},
+		{
+			name: "nested mapping and list of mappings",
+			src:  "collectors:\n  docker: false\nchecks:\n  - name: web\n    url: http://x\n",
+			want: `{"checks":[{"name":"web","url":"http://x"}],"collectors":{"docker":false}}`,
+		},
+		{
+			name: "quoted strings and comments",
+			src:  "token: \"a#b\" # comment\nname: 'it''s'\n",
+			want: `{"name":"it's","token":"a#b"}`,
+		},
+		{
+			name: "flow collections",
+			src:  "include: [web, \"db\"]\nhttp: {max_idle_conns: 2}\n",
+			want: `{"http":{"max_idle_conns":2},"include":["web","db"]}`,
+		},
+		{
+			name: "literal block",
+			src:  "note: |\n  line1\n  line2\nnext: 1\n",
+			want: `{"next":1,"note":"line1\nline2\n"}`,
+		},
+		{
+			name: "folded block",
+			src:  "note: >\n  a\n  b\n",
+			want: `{"note":"a b\n"}`,
+		},
+		{
+			// core schema do YAML 1.2: yes e string
+			name: "numbers and yes",
+			src:  "a: 0x1f\nb: 1.5\nc: 1e3\nd: yes\n",
+			want: `{"a":31,"b":1.5,"c":1000,"d":"yes"}`,
+		},
+		{
+			name:    "anchor",
+			src:     "a: &x 1\n",
+			wantErr: "anchors, aliases and tags are not supported",
+		},
+		{
+			name:    "tag",
+			src:     "a: !!str 1\n",
+			wantErr: "anchors, aliases and tags are not supported",
+		},
+		{
+			name:    "bad indentation",
+			src:     "a: 1\n  b: 2\n",
+			wantErr: "line 2: unexpected indentation",
+		},
+		{
+			name:    "unterminated flow list",
+			src:     "a: [1, 2\n",
+			wantErr: "expected ',' or ']'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseYAML(tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(doc)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package fsutil reune as operacoes de arquivo usadas pelo agente e pelos
+// seus pacotes internos.
+package fsutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnsureDir cria o diretorio e os pais; caminho vazio nao faz nada.
+func EnsureDir(path string) error {
+	if path == "" {
+		return nil
+	}
+	return os.MkdirAll(path, 0o755)
+}
+
+func CopyFile(src, dst string) error {
+	input, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	if _, err := io.Copy(output, input); err != nil {
+		return err
+	}
+
+	return output.Sync()
+}
+
+// WriteFileAtomic grava em um arquivo temporario no mesmo diretorio e
+// renomeia, para que leitores nunca vejam um arquivo pela metade.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadTrimmed devolve o conteudo do arquivo sem espacos nas pontas, ou
+// vazio se ele nao puder ser lido.
+func ReadTrimmed(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func FileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
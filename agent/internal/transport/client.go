@@ -0,0 +1,100 @@
+// Package transport monta o cliente HTTP do agente (proxy, pool, HTTP/2 e
+// cache de DNS liberados por flag) e guarda o endereco saudavel da API para o
+// failover entre os enderecos de api_url.
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+var (
+	transportMu  sync.Mutex
+	transport    *http.Transport
+	transportKey string
+)
+
+// Options sao o que define o transporte: o proxy, o ajuste do pool
+// e os recursos liberados por flag.
+type Options struct {
+	ProxyURL string
+	Tuning   config.HTTPTuning
+	HTTP2    bool
+	DNSCache bool
+}
+
+// Shared devolve o transporte do processo, recriado so quando as
+// opcoes mudam (ex.: config recarregado no daemon).
+func Shared(opts Options) (*http.Transport, error) {
+	tuning := opts.Tuning.WithDefaults()
+	key := fmt.Sprintf("%s|%d|%d|%d|%t|%t", opts.ProxyURL, tuning.MaxIdleConns, tuning.IdleTimeoutSeconds, tuning.DNSTTLSeconds, opts.HTTP2, opts.DNSCache)
+
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	if transport != nil && transportKey == key {
+		return transport, nil
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.ProxyURL != "" {
+		proxy, err := ParseProxyURL(opts.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	t.ForceAttemptHTTP2 = opts.HTTP2
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t.DialContext = dialer.DialContext
+	if opts.DNSCache {
+		t.DialContext = cachedDialer(dialer, time.Duration(tuning.DNSTTLSeconds)*time.Second)
+	}
+	t.MaxIdleConns = tuning.MaxIdleConns
+	t.MaxIdleConnsPerHost = tuning.MaxIdleConns
+	t.IdleConnTimeout = time.Duration(tuning.IdleTimeoutSeconds) * time.Second
+	if tuning.MaxIdleConns == 0 {
+		t.DisableKeepAlives = true
+	}
+
+	if transport != nil {
+		transport.CloseIdleConnections()
+	}
+	transport, transportKey = t, key
+	return t, nil
+}
+
+// ParseProxyURL aceita proxies http, https e socks5/socks5h, com usuario e
+// senha na URL. Nos dois esquemas socks o destino e resolvido pelo proxy.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy_url: unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("invalid proxy_url: missing host")
+	}
+	return u, nil
+}
+
+// DefaultPort e a porta usada quando a URL nao traz uma.
+func DefaultPort(scheme string) string {
+	switch scheme {
+	case "https":
+		return "443"
+	case "socks5", "socks5h":
+		return "1080"
+	}
+	return "80"
+}
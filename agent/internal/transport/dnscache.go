@@ -0,0 +1,311 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+const dnsCacheFile = "dns-cache.json"
+
+// O cache guarda cada resposta pelo menor TTL dos seus registros, lido das
+// mensagens DNS que o resolver do Go troca (ver resolveHost). Quando nao ha
+// TTL (nome do /etc/hosts, resolver nativo do Windows), vale
+// http.dns_ttl_seconds. Uma resposta vencida e refeita normalmente; se o DNS
+// falhar, a conexao vai para o ultimo endereco que aceitou conexao, por mais
+// velho que seja. O cache fica em disco porque no modo cron cada ciclo e um
+// processo novo.
+type dnsCacheEntry struct {
+	Addrs    []string  `json:"addrs"`
+	Resolved time.Time `json:"resolved"`
+	Expires  time.Time `json:"expires"`
+	// Good e o ultimo endereco em que o dial funcionou.
+	Good string `json:"good,omitempty"`
+}
+
+var dnsCache = struct {
+	sync.Mutex
+	dir     string
+	loaded  bool
+	entries map[string]*dnsCacheEntry
+}{entries: map[string]*dnsCacheEntry{}}
+
+// SetDNSCacheDir aponta o cache para o diretorio de estado; sem ele o cache
+// vale so para o processo.
+func SetDNSCacheDir(stateDir string) {
+	dnsCache.Lock()
+	defer dnsCache.Unlock()
+	if dnsCache.dir != stateDir {
+		dnsCache.dir, dnsCache.loaded = stateDir, false
+	}
+}
+
+func loadDNSCacheLocked() {
+	if dnsCache.loaded || dnsCache.dir == "" {
+		return
+	}
+	dnsCache.loaded = true
+	b, err := os.ReadFile(filepath.Join(dnsCache.dir, dnsCacheFile))
+	if err != nil {
+		return
+	}
+	entries := map[string]*dnsCacheEntry{}
+	if json.Unmarshal(b, &entries) == nil {
+		for host, e := range entries {
+			if _, ok := dnsCache.entries[host]; !ok {
+				dnsCache.entries[host] = e
+			}
+		}
+	}
+}
+
+func saveDNSCacheLocked() {
+	if dnsCache.dir == "" {
+		return
+	}
+	b, err := json.MarshalIndent(dnsCache.entries, "", "  ")
+	if err == nil {
+		err = fsutil.EnsureDir(dnsCache.dir)
+	}
+	if err == nil {
+		err = fsutil.WriteFileAtomic(filepath.Join(dnsCache.dir, dnsCacheFile), b, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dns cache: %v\n", err)
+	}
+}
+
+// lookupHostCached resolve host respeitando o TTL da resposta e cai no
+// ultimo endereco que funcionou quando o resolver falha. defaultTTL vale
+// para respostas sem TTL; zero resolve sempre.
+func lookupHostCached(ctx context.Context, host string, defaultTTL time.Duration) ([]string, error) {
+	dnsCache.Lock()
+	loadDNSCacheLocked()
+	cached := dnsCache.entries[host]
+	dnsCache.Unlock()
+
+	if cached != nil && defaultTTL > 0 && time.Now().Before(cached.Expires) {
+		return goodFirst(cached.Addrs, cached.Good), nil
+	}
+
+	addrs, ttl, err := resolveHost(ctx, host)
+	if err != nil {
+		if cached != nil && cached.Good != "" && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "dns: %v; using %s, last connected address\n", err, cached.Good)
+			return []string{cached.Good}, nil
+		}
+		return nil, err
+	}
+	if ttl < 0 {
+		ttl = defaultTTL
+	}
+
+	now := time.Now().UTC()
+	entry := &dnsCacheEntry{Addrs: addrs, Resolved: now, Expires: now.Add(ttl)}
+	if cached != nil {
+		entry.Good = cached.Good
+	}
+	dnsCache.Lock()
+	defer dnsCache.Unlock()
+	dnsCache.entries[host] = entry
+	saveDNSCacheLocked()
+	return goodFirst(addrs, entry.Good), nil
+}
+
+// markDNSGood registra o endereco de host que acabou de aceitar conexao,
+// gravando so quando ele muda.
+func markDNSGood(host, addr string) {
+	dnsCache.Lock()
+	defer dnsCache.Unlock()
+	e := dnsCache.entries[host]
+	if e == nil || e.Good == addr {
+		return
+	}
+	updated := *e
+	updated.Good = addr
+	dnsCache.entries[host] = &updated
+	saveDNSCacheLocked()
+}
+
+// goodFirst poe o ultimo endereco que funcionou na frente, quando ele ainda
+// esta na resposta.
+func goodFirst(addrs []string, good string) []string {
+	i := slices.Index(addrs, good)
+	if i <= 0 {
+		return addrs
+	}
+	out := append([]string{good}, addrs[:i]...)
+	return append(out, addrs[i+1:]...)
+}
+
+// cachedDialer substitui o DialContext do transporte: resolve pelo cache e
+// tenta cada endereco em ordem, como o dialer padrao.
+func cachedDialer(dialer *net.Dialer, defaultTTL time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := lookupHostCached(ctx, host, defaultTTL)
+		if err != nil {
+			return nil, err
+		}
+		var errs []error
+		for _, a := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
+			if err == nil {
+				markDNSGood(host, a)
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// dialDNS abre a conexao com o servidor DNS; os testes apontam para um
+// servidor local.
+var dialDNS = func(ctx context.Context, network, address string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+// resolveHost resolve host pelo resolver do Go, que continua lendo o
+// /etc/hosts e o resolv.conf, e le o TTL das respostas que passam pela
+// conexao. ttl e -1 quando nenhuma resposta trouxe registros.
+func resolveHost(ctx context.Context, host string) ([]string, time.Duration, error) {
+	ttl := &dnsTTL{min: -1}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := dialDNS(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			// o resolver decide o enquadramento pelo tipo: datagrama so
+			// para net.PacketConn
+			if udp, ok := conn.(*net.UDPConn); ok {
+				return &dnsPacketConn{UDPConn: udp, ttl: ttl}, nil
+			}
+			if strings.HasPrefix(network, "udp") {
+				return conn, nil
+			}
+			return &dnsStreamConn{Conn: conn, ttl: ttl}, nil
+		},
+	}
+	addrs, err := r.LookupHost(ctx, host)
+	return addrs, ttl.get(), err
+}
+
+// dnsTTL guarda o menor TTL visto; A e AAAA vem em consultas paralelas.
+type dnsTTL struct {
+	mu  sync.Mutex
+	min time.Duration
+}
+
+func (t *dnsTTL) get() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.min
+}
+
+// observe le os registros A, AAAA e CNAME da secao de resposta de msg.
+func (t *dnsTTL) observe(msg []byte) {
+	if len(msg) < 12 {
+		return
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+	for i := 0; i < questions; i++ {
+		if off = skipDNSName(msg, off); off < 0 || off+4 > len(msg) {
+			return
+		}
+		off += 4
+	}
+	for i := 0; i < answers; i++ {
+		if off = skipDNSName(msg, off); off < 0 || off+10 > len(msg) {
+			return
+		}
+		typ := binary.BigEndian.Uint16(msg[off:])
+		ttl := time.Duration(binary.BigEndian.Uint32(msg[off+4:])) * time.Second
+		off += 10 + int(binary.BigEndian.Uint16(msg[off+8:]))
+		if off > len(msg) {
+			return
+		}
+		if typ != 1 && typ != 5 && typ != 28 {
+			continue
+		}
+		t.mu.Lock()
+		if t.min < 0 || ttl < t.min {
+			t.min = ttl
+		}
+		t.mu.Unlock()
+	}
+}
+
+// skipDNSName devolve o offset logo apos o nome em off, ou -1.
+func skipDNSName(msg []byte, off int) int {
+	for off < len(msg) {
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			return off + 1
+		case n&0xc0 == 0xc0:
+			if off+2 > len(msg) {
+				return -1
+			}
+			return off + 2
+		}
+		off += 1 + n
+	}
+	return -1
+}
+
+// dnsPacketConn le o TTL de cada datagrama de resposta.
+type dnsPacketConn struct {
+	*net.UDPConn
+	ttl *dnsTTL
+}
+
+func (c *dnsPacketConn) Read(b []byte) (int, error) {
+	n, err := c.UDPConn.Read(b)
+	c.ttl.observe(b[:n])
+	return n, err
+}
+
+// dnsStreamConn remonta as mensagens do DNS sobre TCP, precedidas pelo
+// tamanho em dois bytes, antes de ler o TTL.
+type dnsStreamConn struct {
+	net.Conn
+	ttl *dnsTTL
+	buf []byte
+}
+
+func (c *dnsStreamConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.buf = append(c.buf, b[:n]...)
+	for len(c.buf) >= 2 {
+		size := int(binary.BigEndian.Uint16(c.buf))
+		if len(c.buf) < 2+size {
+			break
+		}
+		c.ttl.observe(c.buf[2 : 2+size])
+		c.buf = c.buf[2+size:]
+	}
+	return n, err
+}
@@ -0,0 +1,265 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resetDNSCache isola o cache global do processo num diretorio temporario.
+func resetDNSCache(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	dnsCache.Lock()
+	dnsCache.dir, dnsCache.loaded, dnsCache.entries = dir, false, map[string]*dnsCacheEntry{}
+	dnsCache.Unlock()
+	t.Cleanup(func() {
+		dnsCache.Lock()
+		dnsCache.dir, dnsCache.loaded, dnsCache.entries = "", false, map[string]*dnsCacheEntry{}
+		dnsCache.Unlock()
+	})
+	return dir
+}
+
+func readDNSCacheFile(t *testing.T, dir string) map[string]*dnsCacheEntry {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, dnsCacheFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]*dnsCacheEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// fakeDNS responde as consultas A de api.vaultrix.test com os enderecos e o
+// TTL dados; AAAA volta vazio e outros nomes NXDOMAIN. Com servfail ligado
+// toda consulta falha. Todas as consultas do resolver vao para ele.
+type fakeDNS struct {
+	addrs    []string
+	ttl      uint32
+	servfail atomic.Bool
+	queries  atomic.Int32
+}
+
+func startFakeDNS(t *testing.T, addrs []string, ttl uint32) *fakeDNS {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the Go resolver is not used on Windows")
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	f := &fakeDNS{addrs: addrs, ttl: ttl}
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := f.answer(buf[:n]); resp != nil {
+				pc.WriteTo(resp, from)
+			}
+		}
+	}()
+
+	server := pc.LocalAddr().String()
+	previous := dialDNS
+	dialDNS = func(ctx context.Context, network, address string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "udp", server)
+	}
+	t.Cleanup(func() { dialDNS = previous })
+	return f
+}
+
+func (f *fakeDNS) answer(query []byte) []byte {
+	f.queries.Add(1)
+	end := skipDNSName(query, 12)
+	if end < 0 || end+4 > len(query) {
+		return nil
+	}
+	question := query[12 : end+4]
+	typ := binary.BigEndian.Uint16(query[end:])
+	name := strings.ToLower(string(query[12:end]))
+
+	rcode, answers := uint16(0), [][]byte(nil)
+	switch {
+	case f.servfail.Load():
+		rcode = 2
+	case name != "\x03api\x08vaultrix\x04test\x00":
+		rcode = 3
+	case typ == 1:
+		for _, a := range f.addrs {
+			rr := []byte{0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 0, 0, 4}
+			binary.BigEndian.PutUint32(rr[6:], f.ttl)
+			answers = append(answers, append(rr, net.ParseIP(a).To4()...))
+		}
+	}
+
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	binary.BigEndian.PutUint16(resp[2:], 0x8180|rcode)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(answers)))
+	resp = append(resp, question...)
+	for _, rr := range answers {
+		resp = append(resp, rr...)
+	}
+	return resp
+}
+
+func TestLookupHostCachedUsesRecordTTL(t *testing.T) {
+	dir := resetDNSCache(t)
+	dns := startFakeDNS(t, []string{"10.0.0.5", "10.0.0.6"}, 60)
+
+	got, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"10.0.0.5", "10.0.0.6"}) {
+		t.Errorf("addrs = %v", got)
+	}
+	// o TTL do registro vale, e nao o padrao de uma hora
+	e := readDNSCacheFile(t, dir)["api.vaultrix.test"]
+	if e == nil || e.Expires.Sub(e.Resolved) != time.Minute {
+		t.Fatalf("saved entry = %+v", e)
+	}
+
+	// dentro do TTL a resposta vem do cache
+	queries := dns.queries.Load()
+	if _, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if dns.queries.Load() != queries {
+		t.Error("a fresh entry went to the resolver")
+	}
+
+	// vencido o TTL, resolve de novo
+	dnsCache.Lock()
+	expired := *dnsCache.entries["api.vaultrix.test"]
+	expired.Expires = time.Now().Add(-time.Second)
+	dnsCache.entries["api.vaultrix.test"] = &expired
+	dnsCache.Unlock()
+	if _, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if dns.queries.Load() == queries {
+		t.Error("an expired entry was not resolved again")
+	}
+}
+
+func TestLookupHostCachedFallsBackToLastGood(t *testing.T) {
+	resetDNSCache(t)
+	dns := startFakeDNS(t, []string{"10.0.0.5", "10.0.0.6"}, 0)
+
+	if _, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// sem nenhuma conexao feita nao ha para onde cair
+	dns.servfail.Store(true)
+	if _, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour); err == nil {
+		t.Fatal("want the resolver error before any successful dial")
+	}
+
+	// TTL zero: toda conexao resolve, e o endereco que conectou fica salvo
+	dns.servfail.Store(false)
+	if _, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	markDNSGood("api.vaultrix.test", "10.0.0.6")
+	got, err := lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"10.0.0.6", "10.0.0.5"}) {
+		t.Errorf("addrs = %v, want the last good address first", got)
+	}
+
+	dns.servfail.Store(true)
+	got, err = lookupHostCached(context.Background(), "api.vaultrix.test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"10.0.0.6"}) {
+		t.Errorf("addrs = %v, want only the last good address", got)
+	}
+}
+
+func TestDNSTTLObserve(t *testing.T) {
+	f := &fakeDNS{addrs: []string{"10.0.0.5"}, ttl: 300}
+	query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	query = append(query, "\x03api\x08vaultrix\x04test\x00"...)
+	query = append(query, 0, 1, 0, 1)
+
+	ttl := &dnsTTL{min: -1}
+	ttl.observe(f.answer(query))
+	if got := ttl.get(); got != 5*time.Minute {
+		t.Errorf("ttl = %v, want 5m", got)
+	}
+
+	// resposta sem registros e mensagem cortada nao mudam nada
+	f.servfail.Store(true)
+	empty := &dnsTTL{min: -1}
+	empty.observe(f.answer(query))
+	empty.observe(query[:7])
+	if got := empty.get(); got != -1 {
+		t.Errorf("ttl = %v, want -1", got)
+	}
+}
+
+func TestCachedDialerRecordsGoodAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// o primeiro endereco recusa; o dialer segue para o proximo e o guarda
+	dir := resetDNSCache(t)
+	startFakeDNS(t, []string{"127.0.0.2", "127.0.0.1"}, 300)
+	if closed, err := net.Listen("tcp", "127.0.0.2:"+port); err == nil {
+		closed.Close()
+	}
+
+	dial := cachedDialer(&net.Dialer{Timeout: time.Second}, time.Hour)
+	conn, err := dial(context.Background(), "tcp", "api.vaultrix.test:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := conn.RemoteAddr().String(); got != ln.Addr().String() {
+		t.Errorf("connected to %s, want %s", got, ln.Addr())
+	}
+	conn.Close()
+	if e := readDNSCacheFile(t, dir)["api.vaultrix.test"]; e == nil || e.Good != "127.0.0.1" {
+		t.Errorf("saved entry = %+v, want good 127.0.0.1", e)
+	}
+
+	if _, err := dial(context.Background(), "tcp", "unknown.vaultrix.test:"+port); err == nil {
+		t.Error("want an error for a host without cache or DNS")
+	}
+}
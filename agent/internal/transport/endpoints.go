@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+const apiEndpointFile = "api-endpoint.json"
+
+// api_url aceita uma lista de enderecos. Toda chamada a API (envio, config
+// remota, comandos, rotacao de token, atualizacao e plugins) usa o ultimo
+// que recebeu um envio e, numa falha que o spool guardaria (rede, 5xx, 429),
+// tenta os seguintes em ordem. So o envio troca o endereco saudavel, que
+// fica em disco porque no modo cron cada ciclo e um processo novo.
+var apiEndpoints = struct {
+	sync.Mutex
+	dir     string
+	loaded  bool
+	current string
+}{}
+
+// SetEndpointStateDir aponta a memoria do endereco saudavel para o
+// diretorio de estado.
+func SetEndpointStateDir(stateDir string) {
+	apiEndpoints.Lock()
+	defer apiEndpoints.Unlock()
+	if apiEndpoints.dir != stateDir {
+		apiEndpoints.dir, apiEndpoints.loaded, apiEndpoints.current = stateDir, false, ""
+	}
+}
+
+// EndpointOrder devolve os enderecos a tentar, comecando pelo ultimo
+// saudavel.
+func EndpointOrder(list []string) []string {
+	if len(list) == 1 {
+		return list
+	}
+	apiEndpoints.Lock()
+	defer apiEndpoints.Unlock()
+	if !apiEndpoints.loaded && apiEndpoints.dir != "" {
+		apiEndpoints.loaded = true
+		var st struct {
+			URL string `json:"url"`
+		}
+		if b, err := os.ReadFile(filepath.Join(apiEndpoints.dir, apiEndpointFile)); err == nil && json.Unmarshal(b, &st) == nil {
+			apiEndpoints.current = st.URL
+		}
+	}
+	i := slices.Index(list, apiEndpoints.current)
+	if i <= 0 {
+		return list
+	}
+	return append(slices.Clone(list[i:]), list[:i]...)
+}
+
+// MarkEndpointHealthy grava o endereco que acabou de responder, quando ele
+// mudou.
+func MarkEndpointHealthy(list []string, endpoint string) {
+	if len(list) == 1 {
+		return
+	}
+	apiEndpoints.Lock()
+	defer apiEndpoints.Unlock()
+	previous := apiEndpoints.current
+	if previous == "" {
+		previous = list[0]
+	}
+	apiEndpoints.current = endpoint
+	if previous == endpoint {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "api: switched from %s to %s\n", previous, endpoint)
+	if apiEndpoints.dir == "" {
+		return
+	}
+	b, _ := json.Marshal(struct {
+		URL string `json:"url"`
+	}{endpoint})
+	err := fsutil.EnsureDir(apiEndpoints.dir)
+	if err == nil {
+		err = fsutil.WriteFileAtomic(filepath.Join(apiEndpoints.dir, apiEndpointFile), b, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "api: %v\n", err)
+	}
+}
+
+// CallEndpoints chama call com o endereco que rawURL deriva de cada
+// endpoint, a partir do ultimo saudavel, e passa ao seguinte enquanto retry
+// aceitar o erro. Enderecos repetidos sao tentados uma vez so.
+func CallEndpoints[T any](list []string, rawURL func(endpoint string) (string, error), call func(rawURL string) (T, error), retry func(error) bool) (T, error) {
+	var zero T
+	var err error
+	tried := map[string]bool{}
+	for _, endpoint := range EndpointOrder(list) {
+		target := endpoint
+		if rawURL != nil {
+			var urlErr error
+			if target, urlErr = rawURL(endpoint); urlErr != nil {
+				return zero, urlErr
+			}
+		}
+		if tried[target] {
+			continue
+		}
+		tried[target] = true
+		var v T
+		if v, err = call(target); err == nil || !retry(err) {
+			return v, err
+		}
+	}
+	return zero, err
+}
@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// resetEndpoints isola o endereco saudavel num diretorio temporario.
+func resetEndpoints(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	SetEndpointStateDir(dir)
+	t.Cleanup(func() { SetEndpointStateDir("") })
+	return dir
+}
+
+func TestEndpointOrder(t *testing.T) {
+	dir := resetEndpoints(t)
+	list := []string{"https://a", "https://b", "https://c"}
+
+	if got := EndpointOrder(list); !reflect.DeepEqual(got, []string{"https://a", "https://b", "https://c"}) {
+		t.Errorf("initial order = %v", got)
+	}
+
+	// depois de um failover a lista gira a partir do endereco saudavel
+	MarkEndpointHealthy(list, "https://b")
+	want := []string{"https://b", "https://c", "https://a"}
+	if got := EndpointOrder(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("after failover = %v, want %v", got, want)
+	}
+
+	// um processo novo le o endereco do disco
+	SetEndpointStateDir("")
+	SetEndpointStateDir(dir)
+	if got := EndpointOrder(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("after restart = %v, want %v", got, want)
+	}
+
+	// um endereco que saiu da lista e ignorado
+	os.WriteFile(filepath.Join(dir, apiEndpointFile), []byte(`{"url":"https://old"}`), 0o600)
+	SetEndpointStateDir("")
+	SetEndpointStateDir(dir)
+	if got := EndpointOrder(list); got[0] != "https://a" {
+		t.Errorf("with an unknown saved endpoint = %v", got)
+	}
+
+	// com um endereco so nada vai para o disco
+	single := resetEndpoints(t)
+	MarkEndpointHealthy([]string{"https://a"}, "https://a")
+	if _, err := os.Stat(filepath.Join(single, apiEndpointFile)); !os.IsNotExist(err) {
+		t.Errorf("single endpoint wrote state: %v", err)
+	}
+}
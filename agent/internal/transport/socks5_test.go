@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSOCKS5 responde ao handshake com o metodo, o resultado da autenticacao
+// e o codigo de resposta dados, e devolve o destino pedido pelo cliente.
+func fakeSOCKS5(conn net.Conn, method, authStatus, reply byte, target chan<- string) {
+	defer close(target)
+	var head [2]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return
+	}
+	if _, err := io.CopyN(io.Discard, conn, int64(head[1])); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{0x05, method}); err != nil || method == 0xff {
+		return
+	}
+	if method == 0x02 {
+		var user, pass [256]byte
+		var n [1]byte
+		io.ReadFull(conn, head[:])
+		io.ReadFull(conn, user[:head[1]])
+		io.ReadFull(conn, n[:])
+		io.ReadFull(conn, pass[:n[0]])
+		if _, err := conn.Write([]byte{0x01, authStatus}); err != nil || authStatus != 0 {
+			return
+		}
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 0x01:
+		var ip [4]byte
+		io.ReadFull(conn, ip[:])
+		host = net.IP(ip[:]).String()
+	case 0x03:
+		var n [1]byte
+		io.ReadFull(conn, n[:])
+		name := make([]byte, n[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	var port [2]byte
+	io.ReadFull(conn, port[:])
+	target <- net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+	conn.Write([]byte{0x05, reply, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+}
+
+func TestSOCKS5Handshake(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       *url.Userinfo
+		host       string
+		method     byte
+		authStatus byte
+		reply      byte
+		wantTarget string
+		wantErr    string
+	}{
+		{
+			name:       "no auth, host name resolved by the proxy",
+			host:       "api.example.com",
+			wantTarget: "api.example.com:443",
+		},
+		{
+			name:       "ip address",
+			host:       "10.0.0.5",
+			wantTarget: "10.0.0.5:443",
+		},
+		{
+			name:       "user and password",
+			user:       url.UserPassword("u", "p"),
+			host:       "api.example.com",
+			method:     0x02,
+			wantTarget: "api.example.com:443",
+		},
+		{
+			name:    "proxy requires credentials",
+			host:    "api.example.com",
+			method:  0x02,
+			wantErr: "requires a user name and password",
+		},
+		{
+			name:       "bad credentials",
+			user:       url.UserPassword("u", "wrong"),
+			host:       "api.example.com",
+			method:     0x02,
+			authStatus: 0x01,
+			wantErr:    "authentication failed",
+		},
+		{
+			name:    "no acceptable method",
+			host:    "api.example.com",
+			method:  0xff,
+			wantErr: "no acceptable authentication method",
+		},
+		{
+			name:    "connection refused",
+			host:    "api.example.com",
+			reply:   0x05,
+			wantErr: "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			target := make(chan string, 1)
+			go fakeSOCKS5(server, tt.method, tt.authStatus, tt.reply, target)
+
+			err := socks5Handshake(client, tt.user, tt.host, 443)
+			client.Close()
+			got := <-target
+			server.Close()
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantTarget {
+				t.Errorf("target = %q, want %q", got, tt.wantTarget)
+			}
+		})
+	}
+}
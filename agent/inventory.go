@@ -0,0 +1,489 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// dataCategory descreve o que um coletor envia. A lista acompanha os
+// coletores registrados em config.go e os campos do Payload; quem adicionar
+// um campo ao payload deve adiciona-lo aqui.
+type dataCategory struct {
+	collector   string
+	name        string
+	description string
+	fields      []string
+	identifying bool
+	// active diz se a categoria se aplica a este config, alem do coletor
+	// estar ligado (ex.: checks so com checks configuradas).
+	active func(cfg config.Config) bool
+}
+
+var dataCategories = []dataCategory{
+	{
+		name:        "Agent identity",
+		description: "Machine token, agent version and commit, send time, collector error messages and the feature flags on for this host",
+		fields:      []string{"token", "agent_version", "agent_commit", "timestamp", "heartbeat", "collector_errors", "flags"},
+	},
+	{
+		name:        "Host metadata",
+		description: "Owner, contact, runbook URL and criticality set in the config",
+		fields:      []string{"metadata"},
+		identifying: true,
+		active:      func(cfg config.Config) bool { return payloadMetadata(cfg.Metadata) != nil },
+	},
+	{
+		name:        "Agent environment",
+		description: "Container runtime or hypervisor the agent itself runs in, the host root it reads from and whether disk figures are the host's or the container's",
+		fields:      []string{"agent_environment"},
+		active:      func(cfg config.Config) bool { return agentEnvironment(cfg).payloadEnvironment() != nil },
+	},
+	{
+		collector:   config.CollectorCPU,
+		name:        "CPU usage",
+		description: "CPU percentage and core count",
+		fields:      []string{"metrics.cpu", "metrics.cpu_cores"},
+	},
+	{
+		collector:   config.CollectorMemory,
+		name:        "Memory usage",
+		description: "Total, available and used memory",
+		fields:      []string{"metrics.memory_total_mb", "metrics.memory_avail_mb", "metrics.memory_used_mb", "metrics.memory_percent"},
+	},
+	{
+		collector:   config.CollectorDisk,
+		name:        "Root disk usage",
+		description: "Size and usage of the root filesystem",
+		fields:      []string{"metrics.disk_total_gb", "metrics.disk_used_gb", "metrics.disk_percent"},
+	},
+	{
+		collector:   config.CollectorDisks,
+		name:        "Disks",
+		description: "Device names, mount points, filesystem types and usage of each disk",
+		fields:      []string{"metrics.disks.device", "metrics.disks.mount_point", "metrics.disks.fs_type", "metrics.disks.total_gb", "metrics.disks.used_gb", "metrics.disks.percent"},
+	},
+	{
+		collector:   config.CollectorLoad,
+		name:        "Load average",
+		description: "1, 5 and 15 minute load averages",
+		fields:      []string{"metrics.load_avg_1", "metrics.load_avg_5", "metrics.load_avg_15"},
+	},
+	{
+		collector:   config.CollectorHost,
+		name:        "Host identity",
+		description: "Host name, machine ID, operating system, kernel, architecture, virtualization and uptime",
+		fields:      []string{"host.hostname", "host.machine_id", "host.os_name", "host.os_version", "host.kernel_version", "host.arch", "host.virtualization", "host.uptime_seconds"},
+		identifying: true,
+	},
+	{
+		collector:   config.CollectorDocker,
+		name:        "Container inventory",
+		description: "Container IDs, names, images, states, restart counts, start times, last exits, published ports, networks and IP addresses, healthcheck results, Compose project and service and vaultrix.* label notes, per-image and per-project totals, and the container runtimes found with their version, storage and cgroup drivers, container counts and warnings",
+		fields:      []string{"containers.id", "containers.name", "containers.image", "containers.state", "containers.status", "containers.restartCount", "containers.exitCode", "containers.startedAt", "containers.finishedAt", "containers.oomKilled", "containers.ports", "containers.networks", "containers.health", "containers.endpoint", "containers.project", "containers.service", "containers.notes", "rollups", "container_runtime_status", "docker_endpoints"},
+		identifying: true,
+	},
+	{
+		collector:   config.CollectorDocker,
+		name:        "Kubernetes pods",
+		description: "Namespace, pod name and pod labels of each container",
+		fields:      []string{"containers.namespace", "containers.pod", "containers.podLabels"},
+		identifying: true,
+		active:      func(cfg config.Config) bool { return cfg.Kubernetes },
+	},
+	{
+		collector:   config.CollectorDockerStats,
+		name:        "Container usage",
+		description: "CPU, memory, network, disk I/O and process count of each container",
+		fields:      []string{"containers.cpuPercent", "containers.memUsage", "containers.memPercent", "containers.netIO", "containers.blockIO", "containers.pids", "containers.memUsageBytes", "containers.memLimitBytes", "containers.netRxBytes", "containers.netTxBytes", "containers.blockReadBytes", "containers.blockWriteBytes"},
+	},
+	{
+		collector:   config.CollectorDockerNet,
+		name:        "Container network interfaces",
+		description: "Interface names of each container and the host interface they map to",
+		fields:      []string{"containers.interfaces"},
+	},
+	{
+		collector:   config.CollectorDockerLogs,
+		name:        "Container log matches",
+		description: "Number of log lines of each container that matched each log_scan pattern since the previous cycle",
+		fields:      []string{"containers.logMatches", "containers.logTruncated"},
+		active:      func(cfg config.Config) bool { return cfg.LogScan != nil },
+	},
+	{
+		collector:   config.CollectorDockerDisk,
+		name:        "Container runtime disk usage",
+		description: "Space used by images, containers, volumes and build cache, and how much of it is reclaimable, for each container runtime",
+		fields:      []string{"docker_disk"},
+	},
+	{
+		collector:   config.CollectorDockerVolumes,
+		name:        "Container volumes",
+		description: "Names, drivers, mount points and sizes of named volumes and the containers that use them",
+		fields:      []string{"volumes"},
+		identifying: true,
+	},
+	{
+		collector:   config.CollectorDockerImages,
+		name:        "Container images",
+		description: "Repository, tag, registry digest, size and creation time of each local image",
+		fields:      []string{"images"},
+		identifying: true,
+	},
+	{
+		collector:   config.CollectorDockerNetworks,
+		name:        "Container networks",
+		description: "Names, drivers, subnets and gateways of container networks and the containers attached to them",
+		fields:      []string{"networks"},
+		identifying: true,
+	},
+	{
+		collector:   config.CollectorDockerSwarm,
+		name:        "Swarm services and nodes",
+		description: "On Swarm managers, node hostnames, roles, status and availability, and desired and running replicas and failing tasks of each service",
+		fields:      []string{"swarm"},
+		identifying: true,
+	},
+	{
+		collector:   config.CollectorSystemd,
+		name:        "systemd units",
+		description: "Name, state, restart count and memory of the monitored units",
+		fields:      []string{"systemd_units"},
+		active:      func(cfg config.Config) bool { return runtime.GOOS == "linux" },
+	},
+	{
+		collector:   config.CollectorGPU,
+		name:        "GPUs",
+		description: "Model, utilization, memory, temperature and power of each NVIDIA or AMD GPU, and the processes using it with their command name and container",
+		fields:      []string{"gpus"},
+	},
+	{
+		collector:   config.CollectorSensors,
+		name:        "Hardware sensors",
+		description: "Temperatures, fan speeds and voltages read from the hardware monitoring chips, with their thresholds and status",
+		fields:      []string{"sensors"},
+		active:      func(cfg config.Config) bool { return runtime.GOOS == "linux" },
+	},
+	{
+		collector:   config.CollectorNVMe,
+		name:        "NVMe health",
+		description: "Model, serial number and firmware of each NVMe drive with its wear, spare capacity, media errors, critical warnings and temperature",
+		fields:      []string{"nvme"},
+		identifying: true,
+		active:      func(cfg config.Config) bool { return runtime.GOOS == "linux" },
+	},
+	{
+		collector:   config.CollectorMDRaid,
+		name:        "Software RAID",
+		description: "State, level, size and member disks of each md array, whether it is degraded and the progress of any resync or rebuild",
+		fields:      []string{"mdraid"},
+		active:      func(cfg config.Config) bool { return runtime.GOOS == "linux" },
+	},
+	{
+		collector:   config.CollectorZFS,
+		name:        "ZFS pools",
+		description: "Health, capacity, fragmentation, device errors and last scrub of each ZFS pool, and the usage and mount point of its largest datasets",
+		fields:      []string{"zfs"},
+	},
+	{
+		collector:   config.CollectorLVM,
+		name:        "LVM volumes",
+		description: "Size and free space of each LVM volume group, and the size, type, state and thin pool data and metadata usage of each logical volume",
+		fields:      []string{"lvm"},
+		active:      func(cfg config.Config) bool { return runtime.GOOS == "linux" },
+	},
+	{
+		collector:   config.CollectorCloud,
+		name:        "Cloud maintenance",
+		description: "Cloud provider name and the maintenance it has scheduled for this instance",
+		fields:      []string{"cloud"},
+		active:      func(cfg config.Config) bool { return cfg.CloudMetadata },
+	},
+	{
+		collector:   config.CollectorScrape,
+		name:        "Prometheus scrapes",
+		description: "Allowlisted samples, with their labels, from the configured local /metrics endpoints",
+		fields:      []string{"scraped"},
+		active:      func(cfg config.Config) bool { return len(cfg.Scrape) > 0 },
+	},
+	{
+		collector:   config.CollectorTextfile,
+		name:        "Textfile metrics",
+		description: "Samples, with their labels, from the .prom files in the textfile directory and when each file was last written",
+		fields:      []string{"textfile"},
+		active:      func(cfg config.Config) bool { return cfg.TextfileDir != "" },
+	},
+	{
+		collector:   config.CollectorMySQL,
+		name:        "MySQL",
+		description: "Server version, connections, threads, slow queries, InnoDB buffer pool usage and replication state",
+		fields:      []string{"mysql"},
+		active:      func(cfg config.Config) bool { return cfg.MySQL != nil },
+	},
+	{
+		collector:   config.CollectorPostgres,
+		name:        "PostgreSQL",
+		description: "Server version, connections, transactions per second, cache hit ratio, WAL and database sizes and replication state",
+		fields:      []string{"postgres"},
+		active:      func(cfg config.Config) bool { return cfg.Postgres != nil },
+	},
+	{
+		collector:   config.CollectorRedis,
+		name:        "Redis",
+		description: "Version, memory, clients, operations per second, keyspace hits, misses and evictions and replication role of each instance",
+		fields:      []string{"redis"},
+		active:      func(cfg config.Config) bool { return len(cfg.Redis) > 0 },
+	},
+	{
+		collector:   config.CollectorMongoDB,
+		name:        "MongoDB",
+		description: "Version, connections, operation counters, memory and replica set lag of each instance",
+		fields:      []string{"apps.mongodb"},
+		active:      func(cfg config.Config) bool { return len(cfg.MongoDB) > 0 },
+	},
+	{
+		collector:   config.CollectorNginx,
+		name:        "Nginx",
+		description: "Active connections, requests per second and connection states from each stub_status page",
+		fields:      []string{"apps.nginx"},
+		active:      func(cfg config.Config) bool { return len(cfg.Nginx) > 0 },
+	},
+	{
+		collector:   config.CollectorApache,
+		name:        "Apache",
+		description: "Active connections, requests per second and worker states from each mod_status page",
+		fields:      []string{"apps.apache"},
+		active:      func(cfg config.Config) bool { return len(cfg.Apache) > 0 },
+	},
+	{
+		collector:   config.CollectorRabbitMQ,
+		name:        "RabbitMQ",
+		description: "Queue names, depths, unacknowledged messages and consumers, and node memory, file descriptor and disk alarms of each instance",
+		fields:      []string{"apps.rabbitmq"},
+		active:      func(cfg config.Config) bool { return len(cfg.RabbitMQ) > 0 },
+	},
+	{
+		collector:   config.CollectorElastic,
+		name:        "Elasticsearch",
+		description: "Cluster name and status, node count, shards, heap usage per node and indexing and search rates of each cluster",
+		fields:      []string{"apps.elasticsearch"},
+		active:      func(cfg config.Config) bool { return len(cfg.Elasticsearch) > 0 },
+	},
+	{
+		collector:   config.CollectorKafka,
+		name:        "Kafka",
+		description: "Broker, topic and partition counts, under-replicated and offline partitions, consumer group lag and, with Jolokia, broker request rates of each cluster",
+		fields:      []string{"apps.kafka"},
+		active:      func(cfg config.Config) bool { return len(cfg.Kafka) > 0 },
+	},
+	{
+		collector:   config.CollectorMemcached,
+		name:        "Memcached",
+		description: "Version, connections, memory usage, items, hit ratio and evictions of each instance",
+		fields:      []string{"apps.memcached"},
+		active:      func(cfg config.Config) bool { return len(cfg.Memcached) > 0 },
+	},
+	{
+		collector:   config.CollectorStatsD,
+		name:        "Application metrics",
+		description: "Counters, gauges, timers and sets that local applications send to the StatsD listener",
+		fields:      []string{"app_metrics"},
+		active:      func(cfg config.Config) bool { return cfg.StatsD != nil },
+	},
+	{
+		collector:   config.CollectorChecks,
+		name:        "Endpoint checks",
+		description: "Names, URLs and targets of the configured checks with their results and recent stability",
+		fields:      []string{"checks"},
+		active:      func(cfg config.Config) bool { return len(cfg.Checks) > 0 },
+	},
+	{
+		collector:   config.CollectorPlugins,
+		name:        "Plugins",
+		description: "Whatever each installed plugin reports, limited to its declared schema",
+		fields:      []string{"plugins"},
+		active:      func(cfg config.Config) bool { return len(discoverPlugins(pluginsDir(cfg))) > 0 },
+	},
+	{
+		name:        "Local alerts",
+		description: "Names, conditions and current values of firing alerts",
+		fields:      []string{"alerts"},
+		active:      func(cfg config.Config) bool { return len(cfg.Alerts.Rules) > 0 },
+	},
+	{
+		name:        "Events",
+		description: "Container starts, stops, exits with exit codes, restarts and OOM kills, check state changes and alert transitions since the previous send, with container, check and alert names",
+		fields:      []string{"events"},
+		identifying: true,
+	},
+}
+
+// InventoryCategory e uma categoria de dados coletada com o config atual.
+type InventoryCategory struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Collector   string   `json:"collector,omitempty"`
+	Identifying bool     `json:"identifying"`
+	Sent        []string `json:"sent"`
+	Filtered    []string `json:"filtered,omitempty"`
+	Sources     []string `json:"sources,omitempty"`
+}
+
+// InventoryDestination e um lugar para onde dados saem do host.
+type InventoryDestination struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+	Data string `json:"data"`
+}
+
+type DataInventory struct {
+	Categories   []InventoryCategory    `json:"categories"`
+	Disabled     []string               `json:"disabled"`
+	Destinations []InventoryDestination `json:"destinations"`
+}
+
+// buildDataInventory cruza as categorias com os coletores ligados e com o
+// filtro payload_fields.
+func buildDataInventory(cfg config.Config) DataInventory {
+	inv := DataInventory{Categories: []InventoryCategory{}, Disabled: []string{}}
+	for _, c := range dataCategories {
+		if c.active != nil && !c.active(cfg) {
+			continue
+		}
+		if c.collector != "" && !collectorEnabled(cfg, c.collector) {
+			inv.Disabled = append(inv.Disabled, c.name)
+			continue
+		}
+		item := InventoryCategory{
+			Name:        c.name,
+			Description: c.description,
+			Collector:   c.collector,
+			Identifying: c.identifying,
+			Sent:        []string{},
+		}
+		for _, field := range c.fields {
+			path := strings.Split(field, ".")
+			if slices.Contains(requiredPayloadFields, field) || (!cfg.PayloadFields.Denied(path) && cfg.PayloadFields.Allowed(path)) {
+				item.Sent = append(item.Sent, field)
+			} else {
+				item.Filtered = append(item.Filtered, field)
+			}
+		}
+		if c.collector == config.CollectorPlugins {
+			for _, p := range discoverPlugins(pluginsDir(cfg)) {
+				item.Sources = append(item.Sources, filepath.Base(p))
+			}
+		}
+		inv.Categories = append(inv.Categories, item)
+	}
+
+	if cfg.SendsToAPI() {
+		for _, endpoint := range cfg.Endpoints() {
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "Vaultrix API", URL: endpoint, Data: "payload"})
+		}
+	}
+	const sinkData = "host and container metrics, host name, machine ID, OS and container names, images and IDs"
+	if cfg.OTLP != nil {
+		inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "OpenTelemetry collector", URL: otlpMetricsURL(cfg.OTLP), Data: sinkData})
+	}
+	for _, s := range cfg.Sinks {
+		switch s.Type {
+		case sinkFile:
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "file sink " + s.NameOrDefault(), URL: s.Path, Data: "payload"})
+		default:
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: s.Type + " sink " + s.NameOrDefault(), URL: redactWebhookURL(s.URL), Data: sinkData})
+		}
+	}
+	if cfg.ProxyURL != "" {
+		data := "payload, encrypted when api_url is https"
+		if slices.ContainsFunc(cfg.Checks, func(c config.Check) bool { return c.UseProxy }) {
+			data += "; targets of checks with use_proxy"
+		}
+		inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "proxy", URL: redactURL(cfg.ProxyURL), Data: data})
+	}
+	if len(cfg.Alerts.Rules) > 0 {
+		const alertData = "host name, alert names, conditions and values"
+		if cfg.Alerts.WebhookURL != "" {
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "alert webhook", URL: cfg.Alerts.WebhookURL, Data: alertData})
+		}
+		if cfg.Alerts.SlackWebhookURL != "" {
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "Slack", Data: alertData})
+		}
+		if cfg.Alerts.Telegram != nil {
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "Telegram", Data: alertData})
+		}
+		if cfg.Alerts.Email != nil {
+			inv.Destinations = append(inv.Destinations, InventoryDestination{Name: "email", URL: "smtp://" + cfg.Alerts.Email.SMTPHost, Data: alertData})
+		}
+	}
+	return inv
+}
+
+// redactURL esconde a senha de URLs como a do proxy.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+func runDataInventoryCommand(args []string) {
+	fs := flag.NewFlagSet("data-inventory", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	asJSON := fs.Bool("json", false, tr("JSON output"))
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	inv := buildDataInventory(withCachedRemoteConfig(cfg, *stateDir))
+	if *asJSON {
+		b, _ := json.MarshalIndent(inv, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+
+	fmt.Println(tr("Data collected and sent with this config:"))
+	for _, c := range inv.Categories {
+		marker := ""
+		if c.Identifying {
+			marker = " " + tr("[identifying]")
+		}
+		fmt.Printf("\n%s%s\n", tr(c.Name), marker)
+		fmt.Println("  " + tr(c.Description))
+		if len(c.Sent) > 0 {
+			fmt.Println("  " + trf("Fields: %s", strings.Join(c.Sent, ", ")))
+		}
+		if len(c.Sources) > 0 {
+			fmt.Println("  " + trf("Installed: %s", strings.Join(c.Sources, ", ")))
+		}
+		if len(c.Filtered) > 0 {
+			fmt.Println("  " + trf("Removed by payload_fields: %s", strings.Join(c.Filtered, ", ")))
+		}
+	}
+	if len(inv.Disabled) > 0 {
+		translated := make([]string, len(inv.Disabled))
+		for i, name := range inv.Disabled {
+			translated[i] = tr(name)
+		}
+		fmt.Println("\n" + trf("Disabled: %s", strings.Join(translated, ", ")))
+	}
+	fmt.Println("\n" + tr("Destinations:"))
+	for _, d := range inv.Destinations {
+		if d.URL != "" {
+			fmt.Printf("  %s (%s): %s\n", tr(d.Name), d.URL, tr(d.Data))
+		} else {
+			fmt.Printf("  %s: %s\n", tr(d.Name), tr(d.Data))
+		}
+	}
+}
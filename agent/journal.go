@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const journalFile = "journal.json"
+
+// Limites do diario: ele deve continuar pequeno mesmo apos anos rodando.
+const (
+	journalMaxEntries = 200
+	journalMaxDays    = 31
+)
+
+// Eventos de ciclo de vida registrados no diario.
+const (
+	eventStart         = "start"
+	eventStop          = "stop"
+	eventInstall       = "install"
+	eventUninstall     = "uninstall"
+	eventConfigApplied = "config_applied"
+	eventBinaryUpdated = "binary_updated"
+	eventPluginInstall = "plugin_installed"
+	eventTrigger       = "trigger"
+)
+
+type JournalEntry struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+type DailyCounters struct {
+	Runs   int `json:"runs"`
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+}
+
+// RunSummary descreve a execucao mais recente, exibida pelo --status.
+type RunSummary struct {
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+	Sent       bool      `json:"sent"`
+	Error      string    `json:"error,omitempty"`
+	ErrorKind  string    `json:"error_kind,omitempty"`
+}
+
+type Journal struct {
+	Entries       []JournalEntry            `json:"entries"`
+	Days          map[string]*DailyCounters `json:"days"`
+	ConfigVersion string                    `json:"config_version,omitempty"`
+	BinaryVersion string                    `json:"binary_version,omitempty"`
+	LastRun       *RunSummary               `json:"last_run,omitempty"`
+}
+
+func journalPath(stateDir string) string {
+	return filepath.Join(stateDir, journalFile)
+}
+
+func loadJournal(stateDir string) (*Journal, error) {
+	j := &Journal{Days: map[string]*DailyCounters{}}
+	b, err := os.ReadFile(journalPath(stateDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return j, nil
+		}
+		return j, err
+	}
+	if err := json.Unmarshal(b, j); err != nil {
+		return &Journal{Days: map[string]*DailyCounters{}}, err
+	}
+	if j.Days == nil {
+		j.Days = map[string]*DailyCounters{}
+	}
+	return j, nil
+}
+
+func (j *Journal) save(stateDir string) error {
+	j.compact()
+	if err := fsutil.EnsureDir(stateDir); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(journalPath(stateDir), b, 0o600)
+}
+
+func (j *Journal) add(event, detail string) {
+	j.Entries = append(j.Entries, JournalEntry{Time: time.Now().UTC(), Event: event, Detail: detail})
+}
+
+func (j *Journal) today() *DailyCounters {
+	day := time.Now().UTC().Format("2006-01-02")
+	c, ok := j.Days[day]
+	if !ok {
+		c = &DailyCounters{}
+		j.Days[day] = c
+	}
+	return c
+}
+
+// compact descarta os eventos e contadores diarios mais antigos.
+func (j *Journal) compact() {
+	if len(j.Entries) > journalMaxEntries {
+		j.Entries = j.Entries[len(j.Entries)-journalMaxEntries:]
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -journalMaxDays).Format("2006-01-02")
+	for day := range j.Days {
+		if day < cutoff {
+			delete(j.Days, day)
+		}
+	}
+}
+
+// recordRun contabiliza uma execucao e registra mudancas de config ou de
+// binario desde a execucao anterior. Falhas no diario nunca interrompem a
+// coleta.
+func recordRun(stateDir string, cfg config.Config, started time.Time, sendErr error) {
+	j, err := loadJournal(stateDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "journal: %v\n", err)
+	}
+
+	if v := configVersion(cfg); v != j.ConfigVersion {
+		j.add(eventConfigApplied, v)
+		j.ConfigVersion = v
+	}
+	if v := binaryVersion(); v != "" && v != j.BinaryVersion {
+		if j.BinaryVersion != "" {
+			j.add(eventBinaryUpdated, fmt.Sprintf("%s -> %s", j.BinaryVersion, v))
+		}
+		j.BinaryVersion = v
+	}
+
+	day := j.today()
+	day.Runs++
+	if sendErr != nil {
+		day.Failed++
+	} else {
+		day.Sent++
+	}
+
+	j.LastRun = &RunSummary{
+		Time:       started.UTC(),
+		DurationMS: time.Since(started).Milliseconds(),
+		Sent:       sendErr == nil,
+	}
+	if sendErr != nil {
+		j.LastRun.Error = sendErr.Error()
+		j.LastRun.ErrorKind = classifyError(sendErr)
+	}
+
+	if err := j.save(stateDir); err != nil {
+		fmt.Fprintf(os.Stderr, "journal: %v\n", err)
+	}
+}
+
+// recordEvent registra um evento avulso de ciclo de vida (install/uninstall).
+func recordEvent(stateDir, event, detail string) {
+	j, err := loadJournal(stateDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "journal: %v\n", err)
+	}
+	j.add(event, detail)
+	if err := j.save(stateDir); err != nil {
+		fmt.Fprintf(os.Stderr, "journal: %v\n", err)
+	}
+}
+
+// configVersion identifica a config aplicada sem expor o token.
+func configVersion(cfg config.Config) string {
+	cfg.Token = ""
+	b, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:6])
+}
+
+// binaryVersion usa tamanho e data de modificacao do executavel como
+// impressao digital barata, evitando ler o binario inteiro a cada execucao.
+func binaryVersion() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().Unix())
+}
+
+func runLogCommand(args []string) {
+	fs := flag.NewFlagSet("log", flag.ExitOnError)
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	days := fs.Int("days", 7, tr("Number of days in the summary"))
+	asJSON := fs.Bool("json", false, tr("JSON output"))
+	fs.Parse(args)
+
+	j, err := loadJournal(*stateDir)
+	if err != nil {
+		fatal(err)
+	}
+	j.compact()
+
+	dayKeys := make([]string, 0, len(j.Days))
+	for day := range j.Days {
+		dayKeys = append(dayKeys, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dayKeys)))
+	if *days > 0 && len(dayKeys) > *days {
+		dayKeys = dayKeys[:*days]
+	}
+
+	if *asJSON {
+		days := make(map[string]*DailyCounters, len(dayKeys))
+		for _, day := range dayKeys {
+			days[day] = j.Days[day]
+		}
+		out := Journal{Entries: j.Entries, Days: days, ConfigVersion: j.ConfigVersion, BinaryVersion: j.BinaryVersion, LastRun: j.LastRun}
+		b, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+
+	fmt.Println(tr("Events:"))
+	if len(j.Entries) == 0 {
+		fmt.Println("  " + tr("(none)"))
+	}
+	for _, e := range j.Entries {
+		fmt.Printf("  %s  %-15s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Event, e.Detail)
+	}
+	fmt.Println()
+	fmt.Println(tr("Sends per day:"))
+	if len(dayKeys) == 0 {
+		fmt.Println("  " + tr("(none)"))
+	}
+	for _, day := range dayKeys {
+		c := j.Days[day]
+		fmt.Printf("  %s  runs=%d sent=%d failed=%d\n", day, c.Runs, c.Sent, c.Failed)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestJournalCompact(t *testing.T) {
+	j := &Journal{Days: map[string]*DailyCounters{}}
+	for i := 0; i < journalMaxEntries+10; i++ {
+		j.add(eventInstall, fmt.Sprint(i))
+	}
+	now := time.Now().UTC()
+	recent := now.AddDate(0, 0, -1).Format("2006-01-02")
+	old := now.AddDate(0, 0, -journalMaxDays-1).Format("2006-01-02")
+	j.Days[recent] = &DailyCounters{Runs: 1}
+	j.Days[old] = &DailyCounters{Runs: 1}
+
+	j.compact()
+	if len(j.Entries) != journalMaxEntries {
+		t.Fatalf("entries = %d, want %d", len(j.Entries), journalMaxEntries)
+	}
+	// ficam os eventos mais novos
+	if got := j.Entries[0].Detail; got != "10" {
+		t.Errorf("oldest kept entry = %q, want 10", got)
+	}
+	if _, ok := j.Days[old]; ok {
+		t.Errorf("day %s should have been dropped", old)
+	}
+	if _, ok := j.Days[recent]; !ok {
+		t.Errorf("day %s should have been kept", recent)
+	}
+}
+
+func TestRecordRun(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Config{Token: "a", Interval: 5}
+	recordRun(dir, cfg, time.Now(), nil)
+	recordRun(dir, cfg, time.Now(), errors.New("connection refused"))
+	j, err := loadJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.LastRun == nil || j.LastRun.Sent || j.LastRun.Error != "connection refused" {
+		t.Fatalf("last run = %+v, want the failed send", j.LastRun)
+	}
+	// trocar so o token nao conta como config nova
+	cfg.Token = "b"
+	recordRun(dir, cfg, time.Now(), nil)
+	cfg.Interval = 10
+	recordRun(dir, cfg, time.Now(), nil)
+
+	if j, err = loadJournal(dir); err != nil {
+		t.Fatal(err)
+	}
+	if j.LastRun == nil || !j.LastRun.Sent || j.LastRun.Error != "" {
+		t.Errorf("last run = %+v, want a successful send", j.LastRun)
+	}
+	day := j.Days[time.Now().UTC().Format("2006-01-02")]
+	if day == nil || day.Runs != 4 || day.Sent != 3 || day.Failed != 1 {
+		t.Fatalf("today = %+v, want 4 runs, 3 sent, 1 failed", day)
+	}
+	var applied int
+	for _, e := range j.Entries {
+		if e.Event == eventConfigApplied {
+			applied++
+		}
+	}
+	if applied != 2 {
+		t.Errorf("config_applied events = %d, want 2", applied)
+	}
+	if j.ConfigVersion != configVersion(cfg) {
+		t.Errorf("config version = %q, want %q", j.ConfigVersion, configVersion(cfg))
+	}
+}
+
+func TestLoadJournalMissing(t *testing.T) {
+	j, err := loadJournal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Entries) != 0 || j.Days == nil {
+		t.Errorf("journal = %+v, want empty with days", j)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxKafkaTimeoutSeconds = 60
+
+	// maxKafkaUnderReplicated limita a lista de particoes; a contagem e
+	// sempre completa.
+	maxKafkaUnderReplicated = 50
+)
+
+func validateKafkaClusters(clusters []config.KafkaCluster) error {
+	seen := make(map[string]bool, len(clusters))
+	for i, k := range clusters {
+		if !config.SinkNamePattern.MatchString(k.Name) {
+			return fmt.Errorf("kafka[%d]: invalid name %q (lowercase letters, digits, - and _)", i, k.Name)
+		}
+		if seen[k.Name] {
+			return fmt.Errorf("kafka: duplicate name %q", k.Name)
+		}
+		seen[k.Name] = true
+		if len(k.Brokers) == 0 {
+			return fmt.Errorf("kafka: %s: at least one broker is required", k.Name)
+		}
+		for _, b := range k.Brokers {
+			if _, _, err := net.SplitHostPort(b); err != nil {
+				return fmt.Errorf("kafka: %s: invalid broker %q; use host:port", k.Name, b)
+			}
+		}
+		if k.JolokiaURL != "" {
+			u, err := url.Parse(k.JolokiaURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("kafka: %s: jolokia_url must be an http(s) URL", k.Name)
+			}
+		}
+		if k.MaxGroups < 0 {
+			return fmt.Errorf("kafka: %s: max_groups must not be negative", k.Name)
+		}
+		if k.TimeoutSeconds < 0 || k.TimeoutSeconds > maxKafkaTimeoutSeconds {
+			return fmt.Errorf("kafka: %s: timeout_seconds must be between 0 and %d", k.Name, maxKafkaTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// KafkaStatus e a visao do cluster pela API de administracao, mais as
+// metricas JMX do broker local quando ha Jolokia. Error vem preenchido
+// quando nenhum broker respondeu, e o resto fica vazio.
+type KafkaStatus struct {
+	Brokers                   int `json:"brokers,omitempty"`
+	Topics                    int `json:"topics,omitempty"`
+	Partitions                int `json:"partitions,omitempty"`
+	UnderReplicatedPartitions int `json:"under_replicated_partitions"`
+	OfflinePartitions         int `json:"offline_partitions"`
+
+	// UnderReplicated lista as particoes como "topico/particao".
+	UnderReplicated []string `json:"under_replicated,omitempty"`
+
+	ConsumerGroups  []KafkaGroupLag `json:"consumer_groups,omitempty"`
+	GroupsTruncated bool            `json:"groups_truncated,omitempty"`
+
+	BrokerMetrics *KafkaBrokerMetrics `json:"broker_metrics,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// KafkaGroupLag e o atraso de um grupo: quantas mensagens faltam entre o
+// offset confirmado e o fim de cada particao que ele consome.
+type KafkaGroupLag struct {
+	Group           string           `json:"group"`
+	Lag             int64            `json:"lag"`
+	MaxPartitionLag int64            `json:"max_partition_lag"`
+	TopicLag        map[string]int64 `json:"topic_lag,omitempty"`
+	Error           string           `json:"error,omitempty"`
+}
+
+// KafkaBrokerMetrics vem do JMX do broker local. As taxas sao a media do
+// ultimo minuto que o proprio Kafka calcula.
+type KafkaBrokerMetrics struct {
+	UnderReplicatedPartitions int64              `json:"under_replicated_partitions"`
+	ActiveController          bool               `json:"active_controller"`
+	MessagesInPerSec          float64            `json:"messages_in_per_sec"`
+	BytesInPerSec             float64            `json:"bytes_in_per_sec"`
+	BytesOutPerSec            float64            `json:"bytes_out_per_sec"`
+	RequestsPerSec            map[string]float64 `json:"requests_per_sec,omitempty"`
+	Error                     string             `json:"error,omitempty"`
+}
+
+func collectKafka(clusters []config.KafkaCluster) map[string]KafkaStatus {
+	return collectors.ByName(clusters, func(k config.KafkaCluster) string { return k.Name }, func(k config.KafkaCluster) KafkaStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), k.Timeout())
+		defer cancel()
+		s, err := kafkaStatus(ctx, k)
+		if err != nil {
+			return KafkaStatus{Error: err.Error()}
+		}
+		if k.JolokiaURL != "" {
+			s.BrokerMetrics = kafkaBrokerMetrics(ctx, k.JolokiaURL)
+		}
+		return s
+	})
+}
+
+// kafkaPool guarda uma conexao por broker durante uma coleta.
+type kafkaPool struct {
+	ctx   context.Context
+	conns map[string]*kafkaConn
+}
+
+func (p *kafkaPool) get(addr string) (*kafkaConn, error) {
+	if c, ok := p.conns[addr]; ok {
+		return c, nil
+	}
+	c, err := dialKafka(p.ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[addr] = c
+	return c, nil
+}
+
+func (p *kafkaPool) close() {
+	for _, c := range p.conns {
+		c.Close()
+	}
+}
+
+func kafkaStatus(ctx context.Context, k config.KafkaCluster) (KafkaStatus, error) {
+	pool := &kafkaPool{ctx: ctx, conns: map[string]*kafkaConn{}}
+	defer pool.close()
+
+	var boot *kafkaConn
+	var err error
+	for _, addr := range k.Brokers {
+		if boot, err = pool.get(addr); err == nil {
+			break
+		}
+	}
+	if boot == nil {
+		return KafkaStatus{}, err
+	}
+	meta, err := boot.metadata()
+	if err != nil {
+		return KafkaStatus{}, err
+	}
+
+	s := KafkaStatus{Brokers: len(meta.Brokers), Topics: len(meta.Topics)}
+	leaders := map[kafkaTopicPartition]int32{}
+	for _, t := range meta.Topics {
+		for _, p := range t.Partitions {
+			s.Partitions++
+			leaders[kafkaTopicPartition{t.Name, p.Partition}] = p.Leader
+			if p.Leader < 0 {
+				s.OfflinePartitions++
+			}
+			if len(p.ISR) < len(p.Replicas) {
+				s.UnderReplicatedPartitions++
+				if len(s.UnderReplicated) < maxKafkaUnderReplicated {
+					s.UnderReplicated = append(s.UnderReplicated, fmt.Sprintf("%s/%d", t.Name, p.Partition))
+				}
+			}
+		}
+	}
+
+	groups := k.Groups
+	if len(groups) == 0 {
+		// cada broker so lista os grupos que coordena
+		for _, addr := range meta.Brokers {
+			c, err := pool.get(addr)
+			if err != nil {
+				continue
+			}
+			if found, err := c.listGroups(); err == nil {
+				groups = append(groups, found...)
+			}
+		}
+		slices.Sort(groups)
+		groups = slices.Compact(groups)
+	}
+	if len(groups) > k.MaxGroupsOrDefault() {
+		groups, s.GroupsTruncated = groups[:k.MaxGroupsOrDefault()], true
+	}
+	s.ConsumerGroups = kafkaGroupLags(pool, boot, meta, leaders, groups)
+	return s, nil
+}
+
+// kafkaGroupLags busca os offsets confirmados de cada grupo no seu
+// coordenador e o fim de cada particao no seu lider.
+func kafkaGroupLags(pool *kafkaPool, boot *kafkaConn, meta *kafkaMetadata, leaders map[kafkaTopicPartition]int32, groups []string) []KafkaGroupLag {
+	lags := make([]KafkaGroupLag, len(groups))
+	committed := make([]map[kafkaTopicPartition]int64, len(groups))
+	byLeader := map[int32][]kafkaTopicPartition{}
+	wanted := map[kafkaTopicPartition]bool{}
+	for i, group := range groups {
+		lags[i].Group = group
+		offsets, err := kafkaCommitted(pool, boot, group)
+		if err != nil {
+			lags[i].Error = err.Error()
+			continue
+		}
+		committed[i] = offsets
+		for tp := range offsets {
+			leader, ok := leaders[tp]
+			if ok && leader >= 0 && !wanted[tp] {
+				wanted[tp] = true
+				byLeader[leader] = append(byLeader[leader], tp)
+			}
+		}
+	}
+
+	ends := map[kafkaTopicPartition]int64{}
+	for leader, partitions := range byLeader {
+		c, err := pool.get(meta.Brokers[leader])
+		if err != nil {
+			continue
+		}
+		if found, err := c.listOffsets(partitions); err == nil {
+			maps.Copy(ends, found)
+		}
+	}
+
+	for i := range lags {
+		if committed[i] == nil {
+			continue
+		}
+		lags[i].TopicLag = map[string]int64{}
+		for tp, offset := range committed[i] {
+			end, ok := ends[tp]
+			if !ok {
+				continue
+			}
+			lag := max(end-offset, 0)
+			lags[i].Lag += lag
+			lags[i].MaxPartitionLag = max(lags[i].MaxPartitionLag, lag)
+			lags[i].TopicLag[tp.Topic] += lag
+		}
+	}
+	// os mais atrasados primeiro
+	slices.SortStableFunc(lags, func(a, b KafkaGroupLag) int { return cmp.Compare(b.Lag, a.Lag) })
+	return lags
+}
+
+func kafkaCommitted(pool *kafkaPool, boot *kafkaConn, group string) (map[kafkaTopicPartition]int64, error) {
+	addr, err := boot.findCoordinator(group)
+	if err != nil {
+		return nil, err
+	}
+	c, err := pool.get(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.offsetFetch(group)
+}
+
+// kafkaJolokiaReads sao as leituras feitas num unico POST ao Jolokia. A
+// ultima usa um padrao: o Kafka tem um MBean por tipo e versao de pedido.
+var kafkaJolokiaReads = []map[string]string{
+	{"type": "read", "mbean": "kafka.server:type=ReplicaManager,name=UnderReplicatedPartitions", "attribute": "Value"},
+	{"type": "read", "mbean": "kafka.controller:type=KafkaController,name=ActiveControllerCount", "attribute": "Value"},
+	{"type": "read", "mbean": "kafka.server:type=BrokerTopicMetrics,name=MessagesInPerSec", "attribute": "OneMinuteRate"},
+	{"type": "read", "mbean": "kafka.server:type=BrokerTopicMetrics,name=BytesInPerSec", "attribute": "OneMinuteRate"},
+	{"type": "read", "mbean": "kafka.server:type=BrokerTopicMetrics,name=BytesOutPerSec", "attribute": "OneMinuteRate"},
+	{"type": "read", "mbean": "kafka.network:type=RequestMetrics,name=RequestsPerSec,*", "attribute": "OneMinuteRate"},
+}
+
+func kafkaBrokerMetrics(ctx context.Context, jolokiaURL string) *KafkaBrokerMetrics {
+	var results []struct {
+		Status int `json:"status"`
+		Value  any `json:"value"`
+	}
+	m := &KafkaBrokerMetrics{}
+	if err := localJSON(ctx, "POST", jolokiaURL, kafkaJolokiaReads, nil, &results); err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	if len(results) != len(kafkaJolokiaReads) {
+		m.Error = "jolokia: unexpected response"
+		return m
+	}
+	// um MBean que nao existe (ActiveControllerCount num broker KRaft sem
+	// papel de controller) so fica zerado
+	value := func(i int) float64 {
+		f, _ := results[i].Value.(float64)
+		return f
+	}
+	m.UnderReplicatedPartitions = int64(value(0))
+	m.ActiveController = value(1) > 0
+	m.MessagesInPerSec = math.Round(value(2)*100) / 100
+	m.BytesInPerSec = math.Round(value(3)*100) / 100
+	m.BytesOutPerSec = math.Round(value(4)*100) / 100
+
+	// o padrao devolve {"<mbean>": {"OneMinuteRate": x}, ...}
+	if beans, ok := results[5].Value.(map[string]any); ok {
+		m.RequestsPerSec = map[string]float64{}
+		for name, attrs := range beans {
+			request := ""
+			for _, prop := range strings.Split(name, ",") {
+				if v, ok := strings.CutPrefix(prop, "request="); ok {
+					request = v
+				}
+			}
+			attrs, _ := attrs.(map[string]any)
+			rate, _ := attrs["OneMinuteRate"].(float64)
+			if request != "" && rate > 0 {
+				m.RequestsPerSec[request] += rate
+			}
+		}
+		for request, rate := range m.RequestsPerSec {
+			m.RequestsPerSec[request] = math.Round(rate*100) / 100
+		}
+	}
+	return m
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateKafkaClusters(t *testing.T) {
+	ok := config.KafkaCluster{Name: "events", Brokers: []string{"127.0.0.1:9092"}}
+	tests := []struct {
+		name    string
+		edit    func(k *config.KafkaCluster)
+		wantErr string
+	}{
+		{"ok", func(k *config.KafkaCluster) {}, ""},
+		{"jolokia", func(k *config.KafkaCluster) { k.JolokiaURL = "http://127.0.0.1:8778/jolokia" }, ""},
+		{"bad name", func(k *config.KafkaCluster) { k.Name = "Events" }, "invalid name"},
+		{"no brokers", func(k *config.KafkaCluster) { k.Brokers = nil }, "at least one broker"},
+		{"bad broker", func(k *config.KafkaCluster) { k.Brokers = []string{"kafka1"} }, "host:port"},
+		{"bad jolokia", func(k *config.KafkaCluster) { k.JolokiaURL = "127.0.0.1:8778" }, "jolokia_url"},
+		{"negative max", func(k *config.KafkaCluster) { k.MaxGroups = -1 }, "max_groups"},
+		{"timeout", func(k *config.KafkaCluster) { k.TimeoutSeconds = 61 }, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := ok
+			tt.edit(&k)
+			err := validateKafkaClusters([]config.KafkaCluster{k})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeKafka e um broker unico (id 1) que responde as cinco chamadas do
+// coletor. O topico "orders" tem a particao 0 sub-replicada e a 1 sem
+// lider; "events" esta saudavel.
+func fakeKafka(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	ends := map[kafkaTopicPartition]int64{{"orders", 0}: 100, {"events", 0}: 60}
+	committed := map[string]map[kafkaTopicPartition]int64{
+		"billing": {{"orders", 0}: 90, {"events", 0}: 10},
+		"audit":   {{"events", 0}: 50},
+	}
+
+	handle := func(api int16, r *kafkaReader) []byte {
+		var w kafkaWriter
+		w.int32(0) // throttle
+		switch api {
+		case kafkaAPIMetadata:
+			w.int32(1)
+			w.int32(1)
+			w.string(host)
+			w.int32(int32(port))
+			w.int16(-1) // rack nulo
+			w.string("cluster")
+			w.int32(1) // controller
+			type partition struct {
+				id, leader    int32
+				replicas, isr []int32
+			}
+			topics := []struct {
+				name       string
+				partitions []partition
+			}{
+				{"orders", []partition{{0, 1, []int32{1, 2}, []int32{1}}, {1, -1, []int32{2}, []int32{}}}},
+				{"events", []partition{{0, 1, []int32{1}, []int32{1}}}},
+			}
+			w.int32(int32(len(topics)))
+			for _, tp := range topics {
+				w.int16(0)
+				w.string(tp.name)
+				w.bool(false)
+				w.int32(int32(len(tp.partitions)))
+				for _, p := range tp.partitions {
+					w.int16(0)
+					w.int32(p.id)
+					w.int32(p.leader)
+					w.int32(0)
+					for _, ids := range [][]int32{p.replicas, p.isr, nil} {
+						w.int32(int32(len(ids)))
+						for _, id := range ids {
+							w.int32(id)
+						}
+					}
+				}
+			}
+		case kafkaAPIListGroups:
+			w.int16(0)
+			w.int32(3)
+			for _, g := range [][2]string{{"billing", "consumer"}, {"connect-sink", "connect"}, {"audit", "consumer"}} {
+				w.string(g[0])
+				w.string(g[1])
+			}
+		case kafkaAPIFindCoordinator:
+			group := r.string()
+			if _, ok := committed[group]; ok {
+				w.int16(0)
+			} else {
+				w.int16(30)
+			}
+			w.int16(-1)
+			w.int32(1)
+			w.string(host)
+			w.int32(int32(port))
+		case kafkaAPIOffsetFetch:
+			offsets := committed[r.string()]
+			w.int32(int32(len(offsets)))
+			for tp, offset := range offsets {
+				w.string(tp.Topic)
+				w.int32(1)
+				w.int32(tp.Partition)
+				w.int64(offset)
+				w.int32(-1)
+				w.string("")
+				w.int16(0)
+			}
+			w.int16(0)
+		case kafkaAPIListOffsets:
+			r.int32()
+			r.int8()
+			topics := r.arrayLen()
+			w.int32(int32(topics))
+			for range topics {
+				topic := r.string()
+				n := r.arrayLen()
+				w.string(topic)
+				w.int32(int32(n))
+				for range n {
+					p := r.int32()
+					r.int32()
+					r.int64()
+					w.int32(p)
+					w.int16(0)
+					w.int64(-1)
+					w.int64(ends[kafkaTopicPartition{topic, p}])
+					w.int32(0)
+				}
+			}
+		default:
+			t.Errorf("unexpected api %d", api)
+		}
+		return w.buf
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					var size [4]byte
+					if _, err := io.ReadFull(conn, size[:]); err != nil {
+						return
+					}
+					req := make([]byte, binary.BigEndian.Uint32(size[:]))
+					if _, err := io.ReadFull(conn, req); err != nil {
+						return
+					}
+					r := &kafkaReader{buf: req}
+					api, version, correlation := r.int16(), r.int16(), r.int32()
+					if r.string() != "vaultrix-agent" || version != kafkaVersions[api] {
+						t.Errorf("api %d: bad header", api)
+					}
+					body := handle(api, r)
+					resp := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+					resp = binary.BigEndian.AppendUint32(resp, uint32(correlation))
+					if _, err := conn.Write(append(resp, body...)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestCollectKafka(t *testing.T) {
+	addr := fakeKafka(t)
+
+	// o Jolokia devolve as seis leituras na ordem do pedido
+	jolokia := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reads []map[string]string
+		if r.Method != "POST" || json.NewDecoder(r.Body).Decode(&reads) != nil || len(reads) != len(kafkaJolokiaReads) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `[{"status":200,"value":3},{"status":404,"error":"not found"},
+			{"status":200,"value":120.456},{"status":200,"value":2048},{"status":200,"value":4096},
+			{"status":200,"value":{
+				"kafka.network:name=RequestsPerSec,request=Produce,type=RequestMetrics,version=9":{"OneMinuteRate":10.5},
+				"kafka.network:name=RequestsPerSec,request=Produce,type=RequestMetrics,version=8":{"OneMinuteRate":1.25},
+				"kafka.network:name=RequestsPerSec,request=FetchConsumer,type=RequestMetrics,version=13":{"OneMinuteRate":40},
+				"kafka.network:name=RequestsPerSec,request=Heartbeat,type=RequestMetrics,version=4":{"OneMinuteRate":0}}}]`)
+	}))
+	defer jolokia.Close()
+
+	// uma porta sem ninguem escutando, para o bootstrap pular
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	got := collectKafka([]config.KafkaCluster{
+		{Name: "all", Brokers: []string{deadAddr, addr}, JolokiaURL: jolokia.URL},
+		{Name: "listed", Brokers: []string{addr}, Groups: []string{"billing", "unknown"}},
+		{Name: "top", Brokers: []string{addr}, MaxGroups: 1},
+		{Name: "down", Brokers: []string{deadAddr}},
+	})
+
+	cluster := KafkaStatus{
+		Brokers: 1, Topics: 2, Partitions: 3,
+		UnderReplicatedPartitions: 2, OfflinePartitions: 1,
+		UnderReplicated: []string{"orders/0", "orders/1"},
+	}
+	billing := KafkaGroupLag{Group: "billing", Lag: 60, MaxPartitionLag: 50, TopicLag: map[string]int64{"orders": 10, "events": 50}}
+	audit := KafkaGroupLag{Group: "audit", Lag: 10, MaxPartitionLag: 10, TopicLag: map[string]int64{"events": 10}}
+
+	all := cluster
+	all.ConsumerGroups = []KafkaGroupLag{billing, audit}
+	all.BrokerMetrics = &KafkaBrokerMetrics{
+		UnderReplicatedPartitions: 3, MessagesInPerSec: 120.46, BytesInPerSec: 2048, BytesOutPerSec: 4096,
+		RequestsPerSec: map[string]float64{"Produce": 11.75, "FetchConsumer": 40},
+	}
+	listed := cluster
+	listed.ConsumerGroups = []KafkaGroupLag{billing, {Group: "unknown", Error: "kafka error 30: group authorization failed"}}
+	top := cluster
+	top.ConsumerGroups = []KafkaGroupLag{audit}
+	top.GroupsTruncated = true
+
+	want := map[string]KafkaStatus{"all": all, "listed": listed, "top": top}
+	for name, w := range want {
+		if !reflect.DeepEqual(got[name], w) {
+			t.Errorf("%s: got %+v, want %+v", name, got[name], w)
+		}
+	}
+	if s := got["down"]; s.Error == "" || s.Brokers != 0 {
+		t.Errorf("down: got %+v", s)
+	}
+}
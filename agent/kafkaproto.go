@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Cliente minimo do protocolo do Kafka, como os dos bancos: so as chamadas
+// de administracao que o coletor usa, nas versoes nao flexiveis que os
+// brokers aceitam do 2.1 ao 4.x. Sem SASL e sem TLS: o alvo e um listener
+// PLAINTEXT local.
+
+const (
+	kafkaAPIListOffsets     = 2
+	kafkaAPIMetadata        = 3
+	kafkaAPIOffsetFetch     = 9
+	kafkaAPIFindCoordinator = 10
+	kafkaAPIListGroups      = 16
+
+	maxKafkaResponse = 64 << 20
+)
+
+// kafkaVersions e a versao usada de cada chamada.
+var kafkaVersions = map[int16]int16{
+	kafkaAPIListOffsets:     4,
+	kafkaAPIMetadata:        7,
+	kafkaAPIOffsetFetch:     5,
+	kafkaAPIFindCoordinator: 2,
+	kafkaAPIListGroups:      2,
+}
+
+// kafkaError e um codigo de erro do protocolo.
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	switch e {
+	case 15:
+		return "kafka error 15: coordinator not available"
+	case 16:
+		return "kafka error 16: not coordinator"
+	case 29:
+		return "kafka error 29: topic authorization failed"
+	case 30:
+		return "kafka error 30: group authorization failed"
+	case 31:
+		return "kafka error 31: cluster authorization failed"
+	}
+	return fmt.Sprintf("kafka error %d", int16(e))
+}
+
+type kafkaConn struct {
+	conn          net.Conn
+	correlationID int32
+}
+
+func dialKafka(ctx context.Context, addr string) (*kafkaConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return &kafkaConn{conn: conn}, nil
+}
+
+func (c *kafkaConn) Close() error { return c.conn.Close() }
+
+// call manda um pedido com o cabecalho v1 e devolve o corpo da resposta,
+// ja sem o cabecalho.
+func (c *kafkaConn) call(api int16, body []byte) (*kafkaReader, error) {
+	c.correlationID++
+	var w kafkaWriter
+	w.int32(0) // tamanho, preenchido abaixo
+	w.int16(api)
+	w.int16(kafkaVersions[api])
+	w.int32(c.correlationID)
+	w.string("vaultrix-agent")
+	msg := append(w.buf, body...)
+	binary.BigEndian.PutUint32(msg, uint32(len(msg)-4))
+	if _, err := c.conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(c.conn, size[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(size[:]))
+	if n < 4 || n > maxKafkaResponse {
+		return nil, errors.New("kafka: invalid response length")
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, resp); err != nil {
+		return nil, err
+	}
+	if int32(binary.BigEndian.Uint32(resp)) != c.correlationID {
+		return nil, errors.New("kafka: response out of order")
+	}
+	return &kafkaReader{buf: resp[4:]}, nil
+}
+
+type kafkaWriter struct {
+	buf []byte
+}
+
+func (w *kafkaWriter) int8(v int8)   { w.buf = append(w.buf, byte(v)) }
+func (w *kafkaWriter) int16(v int16) { w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v)) }
+func (w *kafkaWriter) int32(v int32) { w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v)) }
+func (w *kafkaWriter) int64(v int64) { w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(v)) }
+
+func (w *kafkaWriter) bool(v bool) {
+	if v {
+		w.int8(1)
+	} else {
+		w.int8(0)
+	}
+}
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// kafkaReader le os tipos do protocolo; como o mysqlReader, o primeiro
+// erro fica guardado e as leituras seguintes devolvem zeros.
+type kafkaReader struct {
+	buf []byte
+	err error
+}
+
+func (r *kafkaReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, max(n, 0))
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *kafkaReader) int8() int8   { return int8(r.bytes(1)[0]) }
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.bytes(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.bytes(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.bytes(8))) }
+func (r *kafkaReader) bool() bool   { return r.int8() != 0 }
+
+// string tambem le strings anulaveis: -1 vira "".
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.bytes(int(n)))
+}
+
+// arrayLen devolve o tamanho de um array; nulo ou invalido vira zero.
+func (r *kafkaReader) arrayLen() int {
+	n := r.int32()
+	if n < 0 || r.err != nil {
+		return 0
+	}
+	// cada elemento tem ao menos um byte
+	if int(n) > len(r.buf) {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return int(n)
+}
+
+func (r *kafkaReader) int32s() []int32 {
+	n := r.arrayLen()
+	out := make([]int32, 0, n)
+	for range n {
+		out = append(out, r.int32())
+	}
+	return out
+}
+
+func (r *kafkaReader) check(api string) error {
+	if r.err != nil {
+		return fmt.Errorf("kafka: malformed %s response", api)
+	}
+	return nil
+}
+
+// kafkaMetadata e o que o coletor usa da resposta de Metadata.
+type kafkaMetadata struct {
+	Brokers map[int32]string // id -> host:porta
+	Topics  []kafkaTopicMetadata
+}
+
+type kafkaTopicMetadata struct {
+	Name       string
+	Internal   bool
+	Partitions []kafkaPartitionMetadata
+}
+
+type kafkaPartitionMetadata struct {
+	Partition int32
+	Leader    int32
+	Replicas  []int32
+	ISR       []int32
+}
+
+func (c *kafkaConn) metadata() (*kafkaMetadata, error) {
+	var w kafkaWriter
+	w.int32(-1) // todos os topicos
+	w.bool(false)
+	r, err := c.call(kafkaAPIMetadata, w.buf)
+	if err != nil {
+		return nil, err
+	}
+	r.int32() // throttle
+	m := &kafkaMetadata{Brokers: map[int32]string{}}
+	for range r.arrayLen() {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // rack
+		m.Brokers[id] = net.JoinHostPort(host, fmt.Sprint(port))
+	}
+	r.string() // cluster id
+	r.int32()  // controller
+	for range r.arrayLen() {
+		code := r.int16()
+		t := kafkaTopicMetadata{Name: r.string(), Internal: r.bool()}
+		for range r.arrayLen() {
+			r.int16() // erro da particao; o lider -1 ja diz que esta offline
+			p := kafkaPartitionMetadata{Partition: r.int32(), Leader: r.int32()}
+			r.int32() // leader epoch
+			p.Replicas = r.int32s()
+			p.ISR = r.int32s()
+			r.int32s() // offline replicas
+			t.Partitions = append(t.Partitions, p)
+		}
+		if code == 0 {
+			m.Topics = append(m.Topics, t)
+		}
+	}
+	return m, r.check("metadata")
+}
+
+// listGroups devolve os grupos de consumidores que este broker coordena.
+func (c *kafkaConn) listGroups() ([]string, error) {
+	r, err := c.call(kafkaAPIListGroups, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.int32() // throttle
+	if code := r.int16(); code != 0 && r.err == nil {
+		return nil, kafkaError(code)
+	}
+	var groups []string
+	for range r.arrayLen() {
+		id, protocol := r.string(), r.string()
+		if protocol == "consumer" {
+			groups = append(groups, id)
+		}
+	}
+	return groups, r.check("list groups")
+}
+
+// findCoordinator devolve o endereco do broker que coordena o grupo.
+func (c *kafkaConn) findCoordinator(group string) (string, error) {
+	var w kafkaWriter
+	w.string(group)
+	w.int8(0) // chave de grupo
+	r, err := c.call(kafkaAPIFindCoordinator, w.buf)
+	if err != nil {
+		return "", err
+	}
+	r.int32() // throttle
+	code := r.int16()
+	r.string() // mensagem
+	r.int32()  // id do no
+	host, port := r.string(), r.int32()
+	if err := r.check("find coordinator"); err != nil {
+		return "", err
+	}
+	if code != 0 {
+		return "", kafkaError(code)
+	}
+	return net.JoinHostPort(host, fmt.Sprint(port)), nil
+}
+
+// kafkaTopicPartition identifica uma particao.
+type kafkaTopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// offsetFetch devolve os offsets confirmados do grupo em todos os topicos;
+// particoes sem offset (-1) ficam de fora.
+func (c *kafkaConn) offsetFetch(group string) (map[kafkaTopicPartition]int64, error) {
+	var w kafkaWriter
+	w.string(group)
+	w.int32(-1) // todos os topicos
+	r, err := c.call(kafkaAPIOffsetFetch, w.buf)
+	if err != nil {
+		return nil, err
+	}
+	r.int32() // throttle
+	offsets := map[kafkaTopicPartition]int64{}
+	for range r.arrayLen() {
+		topic := r.string()
+		for range r.arrayLen() {
+			partition := r.int32()
+			offset := r.int64()
+			r.int32()  // leader epoch
+			r.string() // metadata
+			code := r.int16()
+			if code == 0 && offset >= 0 {
+				offsets[kafkaTopicPartition{topic, partition}] = offset
+			}
+		}
+	}
+	code := r.int16()
+	if err := r.check("offset fetch"); err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, kafkaError(code)
+	}
+	return offsets, nil
+}
+
+// listOffsets devolve o fim (o proximo offset) das particoes, que precisam
+// ter este broker como lider.
+func (c *kafkaConn) listOffsets(partitions []kafkaTopicPartition) (map[kafkaTopicPartition]int64, error) {
+	byTopic := map[string][]int32{}
+	var topics []string
+	for _, tp := range partitions {
+		if _, ok := byTopic[tp.Topic]; !ok {
+			topics = append(topics, tp.Topic)
+		}
+		byTopic[tp.Topic] = append(byTopic[tp.Topic], tp.Partition)
+	}
+	var w kafkaWriter
+	w.int32(-1) // replica id de cliente
+	w.int8(0)   // read uncommitted
+	w.int32(int32(len(topics)))
+	for _, topic := range topics {
+		w.string(topic)
+		w.int32(int32(len(byTopic[topic])))
+		for _, p := range byTopic[topic] {
+			w.int32(p)
+			w.int32(-1) // leader epoch
+			w.int64(-1) // o ultimo offset
+		}
+	}
+	r, err := c.call(kafkaAPIListOffsets, w.buf)
+	if err != nil {
+		return nil, err
+	}
+	r.int32() // throttle
+	ends := map[kafkaTopicPartition]int64{}
+	for range r.arrayLen() {
+		topic := r.string()
+		for range r.arrayLen() {
+			partition := r.int32()
+			code := r.int16()
+			r.int64() // timestamp
+			offset := r.int64()
+			r.int32() // leader epoch
+			if code == 0 {
+				ends[kafkaTopicPartition{topic, partition}] = offset
+			}
+		}
+	}
+	return ends, r.check("list offsets")
+}
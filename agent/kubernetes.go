@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const kubeletTimeout = 15 * time.Second
+
+// Credenciais montadas pelo Kubernetes na ServiceAccount do pod.
+const (
+	serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// kubeletURL usa kubelet_url ou o kubelet do proprio no, cujo nome vem da
+// downward API em NODE_NAME.
+func kubeletURL(c config.Config) string {
+	if c.KubeletURL != "" {
+		return strings.TrimSuffix(c.KubeletURL, "/")
+	}
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		return "https://" + node + ":10250"
+	}
+	return "https://127.0.0.1:10250"
+}
+
+// collectKubernetes substitui o docker no modo --kubernetes: em nos com
+// containerd o docker ps nao enxerga os pods. Os containers vem do /pods do
+// kubelet e o consumo do /stats/summary.
+func collectKubernetes(cfg config.Config) dockerResult {
+	result := dockerResult{endpoints: []DockerEndpointInfo{{Name: "kubelet", Runtime: "kubernetes"}}}
+	containers, err := collectors.Run(cfg.CollectorTimeout(kubeletTimeout), func(ctx context.Context) ([]ContainerStatus, error) {
+		if err := collectorFault(ctx, config.CollectorDocker); err != nil {
+			return nil, err
+		}
+		return collectKubeletContainers(ctx, cfg)
+	})
+	logCollectorError("kubelet", err)
+	result.status = containerRuntimeStatus(err)
+	result.endpoints[0].Status = result.status
+	if err != nil {
+		result.errors = []CollectorError{newCollectorError("kubelet", err)}
+		return result
+	}
+	result.containers = filterContainers(cfg.ContainerFilter, containers)
+	return result
+}
+
+type kubeletPodList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			Containers []struct {
+				Name      string `json:"name"`
+				Resources struct {
+					Limits map[string]string `json:"limits"`
+				} `json:"resources"`
+			} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			ContainerStatuses []struct {
+				Name         string                     `json:"name"`
+				Image        string                     `json:"image"`
+				ContainerID  string                     `json:"containerID"`
+				RestartCount int                        `json:"restartCount"`
+				State        map[string]json.RawMessage `json:"state"`
+				LastState    map[string]json.RawMessage `json:"lastState"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type kubeletSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name string `json:"name"`
+			CPU  struct {
+				UsageNanoCores uint64 `json:"usageNanoCores"`
+			} `json:"cpu"`
+			Memory struct {
+				WorkingSetBytes uint64 `json:"workingSetBytes"`
+			} `json:"memory"`
+		} `json:"containers"`
+		Network struct {
+			RxBytes uint64 `json:"rxBytes"`
+			TxBytes uint64 `json:"txBytes"`
+		} `json:"network"`
+		ProcessStats struct {
+			ProcessCount int64 `json:"process_count"`
+		} `json:"process_stats"`
+	} `json:"pods"`
+}
+
+type kubeletContainerStats struct {
+	cpuNanoCores uint64
+	workingSet   uint64
+}
+
+func collectKubeletContainers(ctx context.Context, cfg config.Config) ([]ContainerStatus, error) {
+	client, err := newKubeletClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var pods kubeletPodList
+	if err := kubeletGet(ctx, client, cfg, "/pods", &pods); err != nil {
+		return nil, err
+	}
+	var summary kubeletSummary
+	statsErr := kubeletGet(ctx, client, cfg, "/stats/summary", &summary)
+
+	// Indices do summary por "namespace/pod" e "namespace/pod/container".
+	stats := make(map[string]kubeletContainerStats)
+	podNet := make(map[string][2]uint64)
+	podPIDs := make(map[string]int64)
+	for _, p := range summary.Pods {
+		podKey := p.PodRef.Namespace + "/" + p.PodRef.Name
+		podNet[podKey] = [2]uint64{p.Network.RxBytes, p.Network.TxBytes}
+		podPIDs[podKey] = p.ProcessStats.ProcessCount
+		for _, c := range p.Containers {
+			stats[podKey+"/"+c.Name] = kubeletContainerStats{cpuNanoCores: c.CPU.UsageNanoCores, workingSet: c.Memory.WorkingSetBytes}
+		}
+	}
+
+	var hostMemory uint64
+	if mem, err := readMemory(ctx); err == nil {
+		hostMemory = mem.total
+	}
+
+	var containers []ContainerStatus
+	for _, pod := range pods.Items {
+		podKey := pod.Metadata.Namespace + "/" + pod.Metadata.Name
+		limits := make(map[string]uint64)
+		for _, c := range pod.Spec.Containers {
+			limits[c.Name] = parseKubeQuantity(c.Resources.Limits["memory"])
+		}
+
+		for _, cs := range pod.Status.ContainerStatuses {
+			// containerID vem como "containerd://<id>".
+			_, id, _ := strings.Cut(cs.ContainerID, "://")
+			entry := ContainerStatus{
+				ID:        collectors.ShortContainerID(id),
+				Name:      podKey + "/" + cs.Name,
+				Image:     cs.Image,
+				Namespace: pod.Metadata.Namespace,
+				Pod:       pod.Metadata.Name,
+				PodLabels: pod.Metadata.Labels,
+				Labels:    pod.Metadata.Labels,
+			}
+			entry.State, entry.Status = kubeContainerState(cs.State, cs.RestartCount)
+			restarts := cs.RestartCount
+			entry.RestartCount = &restarts
+			var started time.Time
+			started, entry.oomKilledAt = kubeContainerLifecycle(cs.State, cs.LastState)
+			if !started.IsZero() {
+				entry.StartedAt = &started
+			}
+			kubeLastExit(&entry, cs.State, cs.LastState)
+
+			if s, ok := stats[podKey+"/"+cs.Name]; ok && entry.State == "running" {
+				entry.CPUPercent = float64(s.cpuNanoCores) / 1e9 * 100
+				limit := limits[cs.Name]
+				if limit == 0 {
+					limit = hostMemory
+				}
+				entry.MemUsage = formatBinaryBytes(s.workingSet) + " / " + formatBinaryBytes(limit)
+				entry.MemUsageBytes, entry.MemLimitBytes = int64(s.workingSet), int64(limit)
+				if limit > 0 {
+					entry.MemPercent = float64(s.workingSet) / float64(limit) * 100
+				}
+				// Rede e processos sao do pod; so sao atribuidos ao container
+				// quando ele e o unico, para nao somar em dobro nos rollups.
+				if len(pod.Status.ContainerStatuses) == 1 {
+					net := podNet[podKey]
+					entry.NetIO = formatDecimalBytes(net[0]) + " / " + formatDecimalBytes(net[1])
+					entry.NetRxBytes, entry.NetTxBytes = int64(net[0]), int64(net[1])
+					entry.PIDs = podPIDs[podKey]
+				}
+			}
+			containers = append(containers, entry)
+		}
+	}
+	if statsErr != nil {
+		return containers, fmt.Errorf("stats/summary: %w", statsErr)
+	}
+	return containers, nil
+}
+
+// kubeContainerState traduz o estado do pod para os valores do docker ps
+// (running, created, exited) e um texto com o motivo.
+func kubeContainerState(state map[string]json.RawMessage, restarts int) (string, string) {
+	var detail struct {
+		Reason   string `json:"reason"`
+		ExitCode int    `json:"exitCode"`
+	}
+	var docker, status string
+	switch {
+	case state["running"] != nil:
+		docker, status = "running", "Running"
+	case state["waiting"] != nil:
+		json.Unmarshal(state["waiting"], &detail)
+		docker, status = "created", orDefault(detail.Reason, "Waiting")
+	case state["terminated"] != nil:
+		json.Unmarshal(state["terminated"], &detail)
+		docker, status = "exited", fmt.Sprintf("%s (%d)", orDefault(detail.Reason, "Terminated"), detail.ExitCode)
+	}
+	if restarts > 0 {
+		status += fmt.Sprintf(", %d restarts", restarts)
+	}
+	return docker, status
+}
+
+// kubeContainerLifecycle devolve o inicio da execucao atual e o fim do
+// ultimo OOM kill, seja o estado atual ou o anterior ao ultimo reinicio.
+func kubeContainerLifecycle(state, last map[string]json.RawMessage) (startedAt, oomKilledAt time.Time) {
+	var running struct {
+		StartedAt time.Time `json:"startedAt"`
+	}
+	if state["running"] != nil {
+		json.Unmarshal(state["running"], &running)
+	}
+	for _, s := range []map[string]json.RawMessage{state, last} {
+		var terminated struct {
+			Reason     string    `json:"reason"`
+			FinishedAt time.Time `json:"finishedAt"`
+		}
+		if s["terminated"] == nil || json.Unmarshal(s["terminated"], &terminated) != nil {
+			continue
+		}
+		if terminated.Reason == "OOMKilled" {
+			return running.StartedAt, terminated.FinishedAt
+		}
+	}
+	return running.StartedAt, time.Time{}
+}
+
+// kubeLastExit preenche a ultima saida do container: o estado atual se ele
+// terminou, senao o anterior ao ultimo reinicio. O codigo so vai quando o
+// container esta parado, como no docker.
+func kubeLastExit(entry *ContainerStatus, state, last map[string]json.RawMessage) {
+	raw := state["terminated"]
+	if raw == nil {
+		raw = last["terminated"]
+	}
+	var terminated struct {
+		Reason     string    `json:"reason"`
+		ExitCode   int       `json:"exitCode"`
+		FinishedAt time.Time `json:"finishedAt"`
+	}
+	if raw == nil || json.Unmarshal(raw, &terminated) != nil {
+		return
+	}
+	if !terminated.FinishedAt.IsZero() {
+		finished := terminated.FinishedAt.UTC()
+		entry.FinishedAt = &finished
+	}
+	entry.OOMKilled = terminated.Reason == "OOMKilled"
+	if state["running"] == nil {
+		entry.ExitCode = &terminated.ExitCode
+	}
+}
+
+// parseKubeQuantity entende as quantidades de memoria usuais (512Mi, 1Gi,
+// 500M, 134217728).
+func parseKubeQuantity(q string) uint64 {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return 0
+	}
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+		{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(q, u.suffix) {
+			return uint64(parseFloat(strings.TrimSuffix(q, u.suffix)) * u.mult)
+		}
+	}
+	return uint64(parseFloat(q))
+}
+
+// newKubeletClient confia na CA do cluster. Muitos kubelets usam certificado
+// autoassinado; nesse caso kubelet_insecure_tls desliga a verificacao.
+func newKubeletClient(cfg config.Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.KubeletInsecureTLS}
+	if ca, err := os.ReadFile(serviceAccountCA); err == nil && !cfg.KubeletInsecureTLS {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+func kubeletGet(ctx context.Context, client *http.Client, cfg config.Config, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", kubeletURL(cfg)+path, nil)
+	if err != nil {
+		return err
+	}
+	// O token projetado e rotacionado pelo kubelet; e relido a cada chamada.
+	if token := fsutil.ReadTrimmed(serviceAccountToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kubelet %s: %w", path, &httpStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(b))})
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
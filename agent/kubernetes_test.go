@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+const kubeletPods = `{"items": [
+	{
+		"metadata": {"name": "api-7d9f", "namespace": "shop", "labels": {"app": "api"}},
+		"spec": {"containers": [{"name": "api", "resources": {"limits": {"memory": "512Mi"}}}]},
+		"status": {"containerStatuses": [
+			{"name": "api", "image": "registry.example.com/shop/api:3", "containerID": "containerd://3f2a9c1e8b7d6a5f4e3d", "restartCount": 2, "state": {"running": {"startedAt": "2026-03-01T10:00:00Z"}}}
+		]}
+	},
+	{
+		"metadata": {"name": "web-0", "namespace": "shop"},
+		"spec": {"containers": [{"name": "nginx"}, {"name": "exporter"}]},
+		"status": {"containerStatuses": [
+			{"name": "nginx", "image": "nginx:1.25", "containerID": "containerd://9e8d7c6b5a4f3e2d", "state": {"waiting": {"reason": "CrashLoopBackOff"}}},
+			{"name": "exporter", "image": "nginx/exporter:1", "containerID": "containerd://0a1b2c3d4e5f6a7b", "state": {"terminated": {"reason": "Error", "exitCode": 1}}}
+		]}
+	}
+]}`
+
+const kubeletStatsSummary = `{"pods": [
+	{
+		"podRef": {"name": "api-7d9f", "namespace": "shop"},
+		"containers": [{"name": "api", "cpu": {"usageNanoCores": 250000000}, "memory": {"workingSetBytes": 134217728}}],
+		"network": {"rxBytes": 2000, "txBytes": 1000},
+		"process_stats": {"process_count": 4}
+	}
+]}`
+
+func TestCollectKubeletContainers(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pods":
+			w.Write([]byte(kubeletPods))
+		case "/stats/summary":
+			w.Write([]byte(kubeletStatsSummary))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{KubeletURL: srv.URL + "/", KubeletInsecureTLS: true}
+	got, err := collectKubeletContainers(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d containers, want 3", len(got))
+	}
+
+	api := got[0]
+	if api.ID != "3f2a9c1e8b7d" || api.Name != "shop/api-7d9f/api" || api.Namespace != "shop" || api.Pod != "api-7d9f" ||
+		api.PodLabels["app"] != "api" || api.State != "running" || api.Status != "Running, 2 restarts" {
+		t.Errorf("api = %+v", api)
+	}
+	// 0.25 core, 128Mi de um limite de 512Mi; rede e processos sao do pod,
+	// que so tem esse container
+	if api.CPUPercent != 25 || api.MemPercent != 25 || api.MemUsage != "128MiB / 512MiB" || api.NetIO != "2kB / 1kB" || api.PIDs != 4 {
+		t.Errorf("api stats = cpu %g, mem %g (%s), net %s, pids %d", api.CPUPercent, api.MemPercent, api.MemUsage, api.NetIO, api.PIDs)
+	}
+
+	if nginx := got[1]; nginx.State != "created" || nginx.Status != "CrashLoopBackOff" || nginx.MemUsage != "" {
+		t.Errorf("nginx = %+v", nginx)
+	}
+	if exporter := got[2]; exporter.State != "exited" || exporter.Status != "Error (1)" {
+		t.Errorf("exporter = %+v", exporter)
+	}
+}
+
+func TestCollectKubeletContainersErrors(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pods" {
+			w.Write([]byte(kubeletPods))
+			return
+		}
+		http.Error(w, "Forbidden (user=system:serviceaccount:vaultrix:agent, verb=get, resource=nodes, subresource=stats)", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	// sem o summary os containers vem sem consumo, junto com o erro
+	got, err := collectKubeletContainers(context.Background(), config.Config{KubeletURL: srv.URL, KubeletInsecureTLS: true})
+	if len(got) != 3 || got[0].MemUsage != "" {
+		t.Errorf("containers = %+v", got)
+	}
+	if err == nil || !strings.Contains(err.Error(), "stats/summary") || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want the stats/summary status", err)
+	}
+
+	// com a verificacao do certificado ligada, o certificado do teste e recusado
+	if _, err := collectKubeletContainers(context.Background(), config.Config{KubeletURL: srv.URL}); err == nil {
+		t.Error("want a TLS error for an unknown certificate")
+	}
+}
+
+func TestKubeContainerState(t *testing.T) {
+	tests := []struct {
+		state      string
+		restarts   int
+		wantDocker string
+		wantStatus string
+	}{
+		{`{"running": {}}`, 0, "running", "Running"},
+		{`{"waiting": {"reason": "ImagePullBackOff"}}`, 0, "created", "ImagePullBackOff"},
+		{`{"waiting": {}}`, 0, "created", "Waiting"},
+		{`{"terminated": {"reason": "OOMKilled", "exitCode": 137}}`, 3, "exited", "OOMKilled (137), 3 restarts"},
+		{`{"terminated": {"exitCode": 0}}`, 0, "exited", "Terminated (0)"},
+	}
+	for _, tt := range tests {
+		var state map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(tt.state), &state); err != nil {
+			t.Fatal(err)
+		}
+		docker, status := kubeContainerState(state, tt.restarts)
+		if docker != tt.wantDocker || status != tt.wantStatus {
+			t.Errorf("%s: got %q, %q; want %q, %q", tt.state, docker, status, tt.wantDocker, tt.wantStatus)
+		}
+	}
+}
+
+func TestParseKubeQuantity(t *testing.T) {
+	for q, want := range map[string]uint64{
+		"":          0,
+		"512Mi":     512 << 20,
+		"1Gi":       1 << 30,
+		"1.5Gi":     3 << 29,
+		"500M":      500e6,
+		"128k":      128e3,
+		"134217728": 134217728,
+	} {
+		if got := parseKubeQuantity(q); got != want {
+			t.Errorf("parseKubeQuantity(%q) = %d, want %d", q, got, want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build darwin || freebsd
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// sysctlLoadAverage decodifica struct loadavg { fixpt_t ldavg[3]; long fscale; }.
+func sysctlLoadAverage() (load1, load5, load15 float64, err error) {
+	raw, err := unix.SysctlRaw("vm.loadavg")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	// fscale e um long: 8 bytes em 64 bits (com padding), 4 em 32 bits.
+	var scale float64
+	switch len(raw) {
+	case 24:
+		scale = float64(binary.LittleEndian.Uint64(raw[16:24]))
+	case 16:
+		scale = float64(binary.LittleEndian.Uint32(raw[12:16]))
+	default:
+		return 0, 0, 0, errors.New("unexpected vm.loadavg size")
+	}
+	if scale == 0 {
+		return 0, 0, 0, errors.New("invalid vm.loadavg scale")
+	}
+	load1 = float64(binary.LittleEndian.Uint32(raw[0:4])) / scale
+	load5 = float64(binary.LittleEndian.Uint32(raw[4:8])) / scale
+	load15 = float64(binary.LittleEndian.Uint32(raw[8:12])) / scale
+	return load1, load5, load15, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	logScanFile    = "logscan.json"
+	logScanTimeout = 20 * time.Second
+
+	// maxLogLine e o trecho de cada linha comparado com os padroes.
+	maxLogLine = 64 * 1024
+
+	// logScanWorkers e quantos "docker logs" rodam ao mesmo tempo.
+	logScanWorkers = 4
+
+	// Marcas de containers nao vistos ha mais que isso sao esquecidas.
+	logScanForget = 24 * time.Hour
+)
+
+// logScanMarks guarda, por ID de container, ate onde o log ja foi lido. Fica
+// em disco porque no modo cron cada ciclo e um processo novo.
+var logScanMarks = struct {
+	sync.Mutex
+	dir    string
+	loaded bool
+	until  map[string]time.Time
+}{until: map[string]time.Time{}}
+
+// setLogScanStateDir aponta as marcas para o diretorio de estado; sem ele
+// elas valem so para o processo.
+func setLogScanStateDir(stateDir string) {
+	logScanMarks.Lock()
+	defer logScanMarks.Unlock()
+	if logScanMarks.dir != stateDir {
+		logScanMarks.dir, logScanMarks.loaded = stateDir, false
+		logScanMarks.until = map[string]time.Time{}
+	}
+}
+
+func loadLogScanMarksLocked() {
+	if logScanMarks.loaded || logScanMarks.dir == "" {
+		return
+	}
+	logScanMarks.loaded = true
+	b, err := os.ReadFile(filepath.Join(logScanMarks.dir, logScanFile))
+	if err != nil {
+		return
+	}
+	json.Unmarshal(b, &logScanMarks.until)
+}
+
+func saveLogScanMarksLocked() {
+	if logScanMarks.dir == "" {
+		return
+	}
+	b, err := json.MarshalIndent(logScanMarks.until, "", "  ")
+	if err == nil {
+		err = fsutil.EnsureDir(logScanMarks.dir)
+	}
+	if err == nil {
+		err = fsutil.WriteFileAtomic(filepath.Join(logScanMarks.dir, logScanFile), b, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log scan: %v\n", err)
+	}
+}
+
+// logScanResult e a leitura do log de um container ate until.
+type logScanResult struct {
+	matches   map[string]int
+	truncated bool
+	until     time.Time
+}
+
+// logScanWindows decide o inicio da leitura de cada container: onde a
+// anterior parou ou, para um container ainda sem marca, um intervalo atras.
+// Containers parados so entram se sairam depois da ultima leitura.
+func logScanWindows(containers []ContainerStatus, interval time.Duration, now time.Time) map[string]time.Time {
+	logScanMarks.Lock()
+	defer logScanMarks.Unlock()
+	loadLogScanMarksLocked()
+
+	since := map[string]time.Time{}
+	for _, c := range containers {
+		if c.ID == "" {
+			continue
+		}
+		from, ok := logScanMarks.until[c.ID]
+		if !ok {
+			from = now.Add(-interval)
+		}
+		if c.State != "running" && (c.FinishedAt == nil || !c.FinishedAt.After(from)) {
+			continue
+		}
+		since[c.ID] = from
+	}
+	return since
+}
+
+// recordLogScans guarda ate onde cada container foi lido. So roda depois
+// que a leitura foi aceita, para um ciclo abandonado no prazo ser relido.
+func recordLogScans(results map[string]logScanResult, now time.Time) {
+	logScanMarks.Lock()
+	defer logScanMarks.Unlock()
+	loadLogScanMarksLocked()
+	for id, r := range results {
+		logScanMarks.until[id] = r.until
+	}
+	for id, until := range logScanMarks.until {
+		if now.Sub(until) > logScanForget {
+			delete(logScanMarks.until, id)
+		}
+	}
+	saveLogScanMarksLocked()
+}
+
+// scanContainerLogs le o log de cada container entre since e until, alguns
+// em paralelo. Um container cujo driver de log nao permite leitura fica de
+// fora, e o primeiro erro volta junto com os demais resultados.
+func scanContainerLogs(ctx context.Context, ep dockerEndpoint, cfg *config.LogScanConfig, since map[string]time.Time, until time.Time) (map[string]logScanResult, error) {
+	patterns := cfg.PatternsOrDefault()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		results  = make(map[string]logScanResult, len(since))
+		firstErr error
+		sem      = make(chan struct{}, logScanWorkers)
+	)
+	for id, from := range since {
+		wg.Add(1)
+		go func(id string, from time.Time) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r, err := scanContainerLog(ctx, ep, id, from, until, cfg.MaxLinesOrDefault(), patterns)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %s", collectors.ShortContainerID(id), collectors.CommandError(err))
+				}
+				return
+			}
+			results[id] = r
+		}(id, from)
+	}
+	wg.Wait()
+	return results, firstErr
+}
+
+func scanContainerLog(ctx context.Context, ep dockerEndpoint, id string, since, until time.Time, maxLines int, patterns map[string]*regexp.Regexp) (logScanResult, error) {
+	cmd := dockerCommand(ctx, ep, "logs",
+		"--since", since.UTC().Format(time.RFC3339Nano),
+		"--until", until.UTC().Format(time.RFC3339Nano),
+		"--tail", strconv.Itoa(maxLines), id)
+	// o container escreve nas duas saidas, e o docker logs repete a separacao
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+	if err := cmd.Start(); err != nil {
+		return logScanResult{}, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		done <- err
+	}()
+
+	matches, lines, last := countLogMatches(pr, patterns)
+	pr.Close()
+	// Com erro, o que saiu e a mensagem do CLI e nao o log ("configured
+	// logging driver does not support reading" casaria com error).
+	if err := <-done; err != nil {
+		if last != "" {
+			return logScanResult{}, errors.New(last)
+		}
+		return logScanResult{}, err
+	}
+	return logScanResult{matches: matches, truncated: lines >= maxLines, until: until}, nil
+}
+
+// countLogMatches conta, por padrao, as linhas de r que casam com ele; uma
+// linha conta uma vez em cada padrao que casar. Devolve tambem o total de
+// linhas e a ultima delas.
+func countLogMatches(r io.Reader, patterns map[string]*regexp.Regexp) (map[string]int, int, string) {
+	matches := make(map[string]int, len(patterns))
+	for name := range patterns {
+		matches[name] = 0
+	}
+	br := bufio.NewReaderSize(r, maxLogLine)
+	lines := 0
+	var last []byte
+	for {
+		line, isPrefix, err := br.ReadLine()
+		if err != nil {
+			break
+		}
+		lines++
+		last = append(last[:0], line...)
+		for name, re := range patterns {
+			if re.Match(line) {
+				matches[name]++
+			}
+		}
+		// o resto de uma linha longa demais e descartado
+		for isPrefix && err == nil {
+			_, isPrefix, err = br.ReadLine()
+		}
+	}
+	// esvazia o pipe para o processo nao travar escrevendo
+	io.Copy(io.Discard, r)
+	return matches, lines, strings.TrimSpace(string(last))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateLogScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.LogScanConfig
+		wantErr string
+	}{
+		{"off", nil, ""},
+		{"defaults", &config.LogScanConfig{}, ""},
+		{"custom", &config.LogScanConfig{Patterns: map[string]string{"timeout": "deadline exceeded"}, MaxLines: 500}, ""},
+		{"bad regexp", &config.LogScanConfig{Patterns: map[string]string{"broken": "(unclosed"}}, "pattern broken"},
+		{"bad name", &config.LogScanConfig{Patterns: map[string]string{"Error": "error"}}, "invalid pattern name"},
+		{"max lines", &config.LogScanConfig{MaxLines: config.MaxLogScanMaxLines + 1}, "max_lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCountLogMatches(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-05-01 INFO started",
+		"2024-05-01 ERROR connection refused",
+		"panic: runtime error: index out of range",
+		"java.lang.OutOfMemoryError: Java heap space",
+		"errors=0 terror-level low",
+		strings.Repeat("x", maxLogLine*2) + " error at the end of a long line",
+		"last line",
+	}, "\n")
+	matches, lines, last := countLogMatches(strings.NewReader(log), (&config.LogScanConfig{}).PatternsOrDefault())
+	want := map[string]int{"error": 2, "panic": 1, "oom": 1}
+	if !reflect.DeepEqual(matches, want) || lines != 7 || last != "last line" {
+		t.Errorf("got %v, %d lines, last %q; want %v, 7 lines", matches, lines, last, want)
+	}
+
+	matches, lines, _ = countLogMatches(strings.NewReader(""), (&config.LogScanConfig{}).PatternsOrDefault())
+	if !reflect.DeepEqual(matches, map[string]int{"error": 0, "panic": 0, "oom": 0}) || lines != 0 {
+		t.Errorf("empty log: got %v, %d lines", matches, lines)
+	}
+}
+
+func TestLogScanWindows(t *testing.T) {
+	setLogScanStateDir(t.TempDir())
+	defer setLogScanStateDir("")
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	exited := now.Add(-30 * time.Second)
+	longAgo := now.Add(-time.Hour)
+	containers := []ContainerStatus{
+		{ID: "web", State: "running"},
+		{ID: "crashed", State: "exited", FinishedAt: &exited},
+		{ID: "old", State: "exited", FinishedAt: &longAgo},
+		{Name: "no-id", State: "running"},
+	}
+	got := logScanWindows(containers, time.Minute, now)
+	want := map[string]time.Time{"web": now.Add(-time.Minute), "crashed": now.Add(-time.Minute)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("first cycle: got %v, want %v", got, want)
+	}
+
+	recordLogScans(map[string]logScanResult{"web": {until: now}, "crashed": {until: now}}, now)
+	// um processo novo, como no modo cron, le as marcas do disco
+	dir := logScanMarks.dir
+	setLogScanStateDir("")
+	setLogScanStateDir(dir)
+
+	next := now.Add(time.Minute)
+	got = logScanWindows(containers, time.Minute, next)
+	if want := map[string]time.Time{"web": now}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second cycle: got %v, want %v", got, want)
+	}
+
+	recordLogScans(nil, now.Add(logScanForget+time.Minute))
+	if len(logScanMarks.until) != 0 {
+		t.Errorf("stale marks kept: %v", logScanMarks.until)
+	}
+}
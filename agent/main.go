@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -9,31 +10,43 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+	"vaultrix-agent/internal/transport"
 )
 
-type Config struct {
-	Token    string `json:"token"`
-	ApiURL   string `json:"api_url"`
-	Interval int    `json:"interval_min"`
+type Metrics struct {
+	CPUUsage      float64 `json:"cpu"`
+	CPUCores      int     `json:"cpu_cores"`
+	MemoryTotalMB int64   `json:"memory_total_mb"`
+	MemoryAvailMB int64   `json:"memory_avail_mb"`
+	MemoryUsedMB  int64   `json:"memory_used_mb"`
+	MemoryPercent float64 `json:"memory_percent"`
+	DiskTotalGB   float64 `json:"disk_total_gb"`
+	DiskUsedGB    float64 `json:"disk_used_gb"`
+	DiskPercent   float64 `json:"disk_percent"`
+	LoadAvg1      float64 `json:"load_avg_1"`
+	LoadAvg5      float64 `json:"load_avg_5"`
+	LoadAvg15     float64 `json:"load_avg_15"`
+
+	Disks []DiskUsage `json:"disks,omitempty"`
 }
 
-type Metrics struct {
-	CPUUsage        float64 `json:"cpu"`
-	CPUCores        int     `json:"cpu_cores"`
-	MemoryTotalMB   int64   `json:"memory_total_mb"`
-	MemoryAvailMB   int64   `json:"memory_avail_mb"`
-	MemoryUsedMB    int64   `json:"memory_used_mb"`
-	MemoryPercent   float64 `json:"memory_percent"`
-	DiskTotalGB     float64 `json:"disk_total_gb"`
-	DiskUsedGB      float64 `json:"disk_used_gb"`
-	DiskPercent     float64 `json:"disk_percent"`
-	LoadAvg1        float64 `json:"load_avg_1"`
-	LoadAvg5        float64 `json:"load_avg_5"`
-	LoadAvg15       float64 `json:"load_avg_15"`
+// DiskUsage descreve um dispositivo de bloco real, reportado uma vez so.
+type DiskUsage struct {
+	Device     string  `json:"device"`
+	MountPoint string  `json:"mount_point"`
+	FSType     string  `json:"fs_type"`
+	TotalGB    float64 `json:"total_gb"`
+	UsedGB     float64 `json:"used_gb"`
+	Percent    float64 `json:"percent"`
 }
 
 type ContainerStatus struct {
@@ -48,344 +61,549 @@ type ContainerStatus struct {
 	NetIO      string  `json:"netIO,omitempty"`
 	BlockIO    string  `json:"blockIO,omitempty"`
 	PIDs       int64   `json:"pids,omitempty"`
+
+	// Os mesmos valores em bytes, para graficos; as strings acima ficam
+	// para servidores que so conhecem elas.
+	MemUsageBytes   int64 `json:"memUsageBytes,omitempty"`
+	MemLimitBytes   int64 `json:"memLimitBytes,omitempty"`
+	NetRxBytes      int64 `json:"netRxBytes,omitempty"`
+	NetTxBytes      int64 `json:"netTxBytes,omitempty"`
+	BlockReadBytes  int64 `json:"blockReadBytes,omitempty"`
+	BlockWriteBytes int64 `json:"blockWriteBytes,omitempty"`
+
+	Interfaces []ContainerInterface `json:"interfaces,omitempty"`
+
+	// Endpoint identifica daemons alem do padrao (ex.: "rootless:1000").
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Project e Service vem dos labels do compose, para agrupar os
+	// containers por stack.
+	Project string `json:"project,omitempty"`
+	Service string `json:"service,omitempty"`
+
+	// Ciclo de vida do inspect (docker, podman) ou do kubelet; fica vazio
+	// quando o runtime nao o informa. ExitCode, FinishedAt e OOMKilled sao
+	// da ultima saida, e ExitCode so vem com o container fora de execucao.
+	RestartCount *int       `json:"restartCount,omitempty"`
+	ExitCode     *int       `json:"exitCode,omitempty"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	OOMKilled    bool       `json:"oomKilled,omitempty"`
+
+	// Ports sao as portas publicadas no host e Networks as redes do
+	// container com os enderecos dele (ver dockernetworks.go).
+	Ports    []ContainerPort    `json:"ports,omitempty"`
+	Networks []ContainerNetwork `json:"networks,omitempty"`
+
+	// Health e o estado do HEALTHCHECK, nos containers que tem um.
+	Health *ContainerHealth `json:"health,omitempty"`
+
+	// LogMatches conta, por padrao de log_scan, as linhas de log desde o
+	// ciclo anterior; LogTruncated diz que o ciclo passou de max_lines.
+	LogMatches   map[string]int `json:"logMatches,omitempty"`
+	LogTruncated bool           `json:"logTruncated,omitempty"`
+
+	// Preenchidos no modo --kubernetes.
+	Namespace string            `json:"namespace,omitempty"`
+	Pod       string            `json:"pod,omitempty"`
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// Notes vem dos labels vaultrix.* (ver notes.go).
+	Notes *ContainerNotes `json:"notes,omitempty"`
+
+	// Labels e usado localmente (filtros); nao e enviado no payload.
+	Labels map[string]string `json:"-"`
+
+	// pid e o processo principal, quando o runtime o informa no inventario.
+	pid int
+
+	// oomKilledAt e o fim do ultimo OOM kill, para os eventos (ver
+	// events.go); no kubelet ele sobrevive a um reinicio, o que OOMKilled
+	// nao faz no docker.
+	oomKilledAt time.Time
+}
+
+// ContainerHealth e o HEALTHCHECK de um container: o estado (starting,
+// healthy ou unhealthy), quantas provas seguidas falharam e a ultima delas.
+type ContainerHealth struct {
+	Status        string    `json:"status"`
+	FailingStreak int       `json:"failingStreak"`
+	LastCheckAt   time.Time `json:"lastCheckAt,omitempty"`
+	LastExitCode  int       `json:"lastExitCode"`
+	LastOutput    string    `json:"lastOutput,omitempty"`
+}
+
+// ContainerInterface liga uma interface do container ao veth do host.
+type ContainerInterface struct {
+	Name          string `json:"name"`
+	HostInterface string `json:"hostInterface"`
 }
 
 type Payload struct {
-	Token      string            `json:"token"`
-	Metrics    Metrics           `json:"metrics"`
-	Containers []ContainerStatus `json:"containers"`
+	Token      string                  `json:"token"`
+	Host       *HostInfo               `json:"host,omitempty"`
+	Metrics    Metrics                 `json:"metrics"`
+	Containers []ContainerStatus       `json:"containers"`
+	Rollups    *Rollups                `json:"rollups,omitempty"`
+	Plugins    map[string]PluginResult `json:"plugins,omitempty"`
+	Checks     []CheckResult           `json:"checks,omitempty"`
+
+	SystemdUnits []SystemdUnit `json:"systemd_units,omitempty"`
+
+	// GPUs sao as placas NVIDIA e AMD do host (ver internal/collectors/gpu.go).
+	GPUs []collectors.GPUInfo `json:"gpus,omitempty"`
+
+	// Sensors sao as temperaturas, ventoinhas e tensoes do hwmon (ver
+	// internal/collectors/sensors.go).
+	Sensors *collectors.SensorsStatus `json:"sensors,omitempty"`
+
+	// NVMe e o log SMART / Health de cada controlador NVMe (ver
+	// internal/collectors/nvme.go).
+	NVMe []collectors.NVMeHealth `json:"nvme,omitempty"`
+
+	// MDRaid sao os arrays do RAID por software (ver
+	// internal/collectors/mdraid.go).
+	MDRaid []collectors.MDArray `json:"mdraid,omitempty"`
+
+	// ZFS sao os pools e datasets ZFS (ver internal/collectors/zfs.go).
+	ZFS *collectors.ZFSStatus `json:"zfs,omitempty"`
+
+	// LVM sao os volume groups e logical volumes, com o uso dos thin pools
+	// (ver internal/collectors/lvm.go).
+	LVM *collectors.LVMStatus `json:"lvm,omitempty"`
+
+	// Cloud traz o provedor de nuvem e as manutencoes agendadas.
+	Cloud *CloudInfo `json:"cloud,omitempty"`
+
+	// Scraped traz as amostras de cada alvo de "scrape", pelo nome.
+	Scraped map[string]ScrapeResult `json:"scraped,omitempty"`
+
+	// Textfile traz os arquivos .prom de textfile_dir, pelo nome.
+	Textfile map[string]TextfileResult `json:"textfile,omitempty"`
+
+	// Apps agrupa os coletores de aplicacao com varias instancias.
+	Apps *AppsStatus `json:"apps,omitempty"`
+
+	// AppMetrics e o que as aplicacoes mandaram ao listener StatsD.
+	AppMetrics *AppMetrics `json:"app_metrics,omitempty"`
+
+	// ContainerRuntimeStatus separa "nenhum container" de "docker fora do
+	// ar": ok, unavailable, timeout ou not_installed.
+	ContainerRuntimeStatus string               `json:"container_runtime_status,omitempty"`
+	DockerEndpoints        []DockerEndpointInfo `json:"docker_endpoints,omitempty"`
+
+	// DockerDisk e o "system df" de cada daemon (ver dockerdisk.go).
+	DockerDisk []DockerDiskUsage `json:"docker_disk,omitempty"`
+
+	// Volumes sao os volumes nomeados de cada daemon (ver dockervolumes.go).
+	Volumes []VolumeInfo `json:"volumes,omitempty"`
+
+	// Images sao as imagens locais de cada daemon (ver dockerimages.go).
+	Images []ImageInfo `json:"images,omitempty"`
+
+	// Networks sao as redes de cada daemon (ver dockernetworks.go).
+	Networks []NetworkInfo `json:"networks,omitempty"`
+
+	// Swarm so vem de managers de um Swarm (ver swarm.go).
+	Swarm *SwarmStatus `json:"swarm,omitempty"`
+
+	Timestamp    time.Time            `json:"timestamp"`
+	AgentVersion string               `json:"agent_version"`
+	AgentCommit  string               `json:"agent_commit,omitempty"`
+	Metadata     *config.HostMetadata `json:"metadata,omitempty"`
+
+	// Environment diz se o agente roda em container ou VM e de quem sao os
+	// discos reportados (ver environment.go).
+	Environment *AgentEnvironment `json:"agent_environment,omitempty"`
+
+	Flags           []string         `json:"flags,omitempty"`
+	CollectorErrors []CollectorError `json:"collector_errors,omitempty"`
+
+	// Heartbeat indica que a coleta de metricas falhou: o host esta vivo, mas
+	// os campos de metrics nao sao validos.
+	Heartbeat bool `json:"heartbeat,omitempty"`
+
+	// Replayed marca payloads reenviados do spool; Aggregate, os que a
+	// compactacao do spool resumiu em uma janela.
+	Replayed  bool            `json:"replayed,omitempty"`
+	Aggregate *SpoolAggregate `json:"aggregate,omitempty"`
+
+	// Alerts sao os alertas locais em disparo (ver alerts.go).
+	Alerts []ActiveAlert `json:"alerts,omitempty"`
+
+	// Events e o que aconteceu desde o ciclo anterior; os demais campos
+	// sao o retrato do ciclo (ver events.go).
+	Events []PayloadEvent `json:"events,omitempty"`
+
+	// allContainers e a lista completa, mesmo com container_rollups "only";
+	// os alertas a usam.
+	allContainers []ContainerStatus
 }
 
-const defaultConfigPath = "/etc/vaultrix-agent/config.json"
-const cronPath = "/etc/cron.d/vaultrix-agent"
+// AppsStatus e a secao "apps" do payload. Os servidores locais (MySQL e
+// PostgreSQL) vem direto; os coletores com varias instancias vem em mapas
+// indexados pelo nome da instancia na configuracao.
+type AppsStatus struct {
+	MySQL    *MySQLStatus    `json:"mysql,omitempty"`
+	Postgres *PostgresStatus `json:"postgres,omitempty"`
+
+	Redis   map[string]RedisStatus     `json:"redis,omitempty"`
+	MongoDB map[string]MongoDBStatus   `json:"mongodb,omitempty"`
+	Nginx   map[string]WebServerStatus `json:"nginx,omitempty"`
+	Apache  map[string]WebServerStatus `json:"apache,omitempty"`
+
+	RabbitMQ      map[string]RabbitMQStatus      `json:"rabbitmq,omitempty"`
+	Elasticsearch map[string]ElasticsearchStatus `json:"elasticsearch,omitempty"`
+	Kafka         map[string]KafkaStatus         `json:"kafka,omitempty"`
+	Memcached     map[string]MemcachedStatus     `json:"memcached,omitempty"`
+}
+
+// apps devolve a secao "apps", criando-a no primeiro coletor que a usa.
+func (p *Payload) apps() *AppsStatus {
+	if p.Apps == nil {
+		p.Apps = &AppsStatus{}
+	}
+	return p.Apps
+}
+
+type CollectorError struct {
+	Collector string `json:"collector"`
+	Kind      string `json:"kind"`
+	Error     string `json:"error"`
+}
+
+// Prazo individual de cada coletor. Um coletor que estoura o prazo e
+// descartado nesta execucao, sem derrubar as demais coletas.
+const (
+	metricsTimeout     = 15 * time.Second
+	dockerPSTimeout    = 10 * time.Second
+	dockerStatsTimeout = 20 * time.Second
+	dockerNetTimeout   = 5 * time.Second
+	hostInfoTimeout    = 5 * time.Second
+)
 
 func main() {
+	if runAsService() {
+		return
+	}
+	os.Args = initLang(os.Args)
+	os.Args = initFaults(os.Args)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "log":
+			runLogCommand(os.Args[2:])
+			return
+		case "rotate-token":
+			runRotateTokenCommand(os.Args[2:])
+			return
+		case "plugin":
+			runPluginCommand(os.Args[2:])
+			return
+		case "plugin-exec":
+			// interno: ver pluginCommand
+			runPluginExec(os.Args[2:])
+			return
+		case "config":
+			runConfigCommand(os.Args[2:])
+			return
+		case "replay":
+			runReplayCommand(os.Args[2:])
+			return
+		case "data-inventory":
+			runDataInventoryCommand(os.Args[2:])
+			return
+		case "diagnose":
+			runDiagnoseCommand(os.Args[2:])
+			return
+		case "self-update":
+			runSelfUpdateCommand(os.Args[2:])
+			return
+		case "trigger":
+			runTriggerCommand(os.Args[2:])
+			return
+		}
+	}
+
 	var token string
 	var apiURL string
+	var proxyURL string
 	var interval int
 	var install bool
+	var skipPreflight bool
 	var uninstall bool
 	var once bool
+	var daemon bool
+	var kubernetes bool
 	var status bool
 	var configPath string
-
-	flag.StringVar(&token, "token", "", "Token da maquina")
-	flag.StringVar(&apiURL, "api-url", "", "URL da API")
-	flag.IntVar(&interval, "interval", 1, "Intervalo em minutos")
-	flag.BoolVar(&install, "install", false, "Instala e agenda o agente")
-	flag.BoolVar(&uninstall, "uninstall", false, "Remove o agente")
-	flag.BoolVar(&once, "once", false, "Executa uma coleta unica")
-	flag.BoolVar(&status, "status", false, "Verifica se esta instalado")
-	flag.StringVar(&configPath, "config", defaultConfigPath, "Caminho do config")
+	var stateDir string
+	var jsonOutput bool
+	var dryRun bool
+	var showVersion bool
+	var noSplay bool
+	var releaseURL string
+	var releaseKeys string
+
+	flag.StringVar(&token, "token", "", tr("Machine token"))
+	flag.StringVar(&apiURL, "api-url", "", tr("API URL"))
+	flag.StringVar(&proxyURL, "proxy-url", "", tr("HTTP/HTTPS/SOCKS5 proxy for the API"))
+	flag.IntVar(&interval, "interval", 1, tr("Interval in minutes"))
+	flag.BoolVar(&install, "install", false, tr("Install and schedule the agent"))
+	flag.BoolVar(&skipPreflight, "skip-preflight", false, tr("Install without testing connectivity to the API"))
+	flag.StringVar(&releaseURL, "release-url", "", tr("Release URL to fetch the build for this host's architecture on install"))
+	flag.StringVar(&releaseKeys, "release-key", "", tr("Comma-separated ed25519 keys that sign the release manifest"))
+	flag.BoolVar(&uninstall, "uninstall", false, tr("Remove the agent"))
+	flag.BoolVar(&once, "once", false, tr("Run a single collection"))
+	flag.BoolVar(&daemon, "daemon", false, tr("Run continuously, collecting every interval"))
+	flag.BoolVar(&kubernetes, "kubernetes", false, tr("Collect pods from the kubelet (DaemonSet) instead of docker"))
+	flag.BoolVar(&dryRun, "dry-run", false, tr("Collect once and print the payload instead of sending it"))
+	flag.BoolVar(&status, "status", false, tr("Show the agent status"))
+	flag.BoolVar(&jsonOutput, "json", false, tr("JSON output (with --status)"))
+	flag.StringVar(&configPath, "config", defaultConfigPath, tr("Config file path"))
+	flag.StringVar(&stateDir, "state-dir", defaultStateDir, tr("Agent state directory"))
+	flag.String("lang", lang, tr("CLI message language (en, pt-BR)"))
+	flag.BoolVar(&noSplay, "no-splay", false, tr("Run this collection right away, ignoring splay_seconds"))
+	flag.BoolVar(&showVersion, "version", false, tr("Print the agent version and exit"))
 	flag.Parse()
 
+	if showVersion {
+		fmt.Println(versionString())
+		return
+	}
+	transport.SetDNSCacheDir(stateDir)
+	transport.SetEndpointStateDir(stateDir)
+	setRolloutStateDir(stateDir)
+	setLogScanStateDir(stateDir)
+
 	if status {
-		installed := fileExists(cronPath)
-		if installed {
-			fmt.Println("INSTALLED")
-		} else {
-			fmt.Println("NOT_INSTALLED")
-		}
+		printStatus(buildStatus(configPath, stateDir), jsonOutput)
 		return
 	}
 
 	if uninstall {
-		_ = os.Remove(cronPath)
-		fmt.Println("Agendamento removido.")
+		if err := removeScheduler(); err != nil {
+			fatal(err)
+		}
+		recordEvent(stateDir, eventUninstall, "")
+		fmt.Println(tr("Schedule removed."))
 		return
 	}
 
 	if install {
-		cfg := Config{Token: token, ApiURL: apiURL, Interval: interval}
+		cfg := config.Config{Token: token, ApiURL: apiURL, Interval: interval, ProxyURL: proxyURL}
+		// a mesma origem serve depois ao self-update
+		cfg.UpdateURL = releaseURL
+		if releaseKeys != "" {
+			cfg.UpdateTrustedKeys = strings.Split(releaseKeys, ",")
+		}
+		if releaseURL != "" && releaseKeys == "" {
+			fatal(errors.New("--release-url needs --release-key to verify the release manifest"))
+		}
 		if err := validateConfig(cfg); err != nil {
 			fatal(err)
 		}
+		if !skipPreflight {
+			if err := runPreflight(cfg); err != nil {
+				fatal(err)
+			}
+		}
 		if err := installAgent(cfg, configPath); err != nil {
 			fatal(err)
 		}
-		fmt.Println("Agente instalado.")
+		if err := runOnce(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "first send: %v\n", err)
+		}
+		recordEvent(stateDir, eventInstall, configPath)
+		fmt.Println(tr("Agent installed."))
 		return
 	}
 
 	cfg, err := loadConfig(configPath)
 	if err != nil {
 		// fallback para flags
-		cfg = Config{Token: token, ApiURL: apiURL, Interval: interval}
-		if err := validateConfig(cfg); err != nil {
+		cfg = config.Config{Token: token, ApiURL: apiURL, Interval: interval, ProxyURL: proxyURL}
+		if err := validateConfig(cfg); err != nil && !dryRun {
 			fatal(err)
 		}
 	}
+	cfg.Kubernetes = cfg.Kubernetes || kubernetes
 
-	if err := runOnce(cfg); err != nil {
-		fatal(err)
-	}
-
-	if !once {
-		// modo padrao: executa uma vez e sai
+	if dryRun {
+		if err := printDryRun(withCachedRemoteConfig(cfg, stateDir)); err != nil {
+			fatal(err)
+		}
 		return
 	}
-}
 
-func runOnce(cfg Config) error {
-	metrics, err := collectMetrics()
-	if err != nil {
-		return err
-	}
-	containers, err := collectContainers()
-	if err != nil {
-		// docker pode nao estar disponivel
-		containers = []ContainerStatus{}
-	}
-
-	payload := Payload{
-		Token:      cfg.Token,
-		Metrics:    metrics,
-		Containers: containers,
+	if daemon {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		runDaemon(ctx, cfg, configPath, stateDir)
+		return
 	}
 
-	return sendPayload(cfg.ApiURL, payload)
-}
-
-func sendPayload(apiURL string, payload Payload) error {
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return err
+	// o splay vem antes da busca do config remoto, que tambem vai a API; vale
+	// o da ultima copia em cache
+	migrateState(stateDir)
+	if !noSplay {
+		waitSplay(context.Background(), withCachedRemoteConfig(cfg, stateDir))
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
+	local := cfg
+	cfg = applyRemoteConfig(cfg, stateDir)
+	syncSchedulerInterval(local, cfg, configPath)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	started := time.Now()
+	err = runCycle(cfg, stateDir)
+	recordRun(stateDir, cfg, started, err)
+	// no cron a versao nova roda a partir do proximo ciclo
+	autoUpdate(cfg, stateDir)
 	if err != nil {
-		return err
+		fatal(err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("api error: %s", string(b))
+	if !once {
+		// modo padrao: executa uma vez e sai
+		return
 	}
-
-	return nil
 }
 
-func collectMetrics() (Metrics, error) {
-	cpu, err := cpuUsageFromTop()
-	if err != nil {
-		cpu = 0
-	}
-
-	cpuCores := getCPUCores()
-	memTotal, memAvail, memUsed, memPercent := getMemoryInfo()
-	diskTotal, diskUsed, diskPercent := getDiskInfo()
-	load1, load5, load15 := getLoadAverage()
-
-	return Metrics{
-		CPUUsage:        cpu,
-		CPUCores:        cpuCores,
-		MemoryTotalMB:   memTotal,
-		MemoryAvailMB:   memAvail,
-		MemoryUsedMB:    memUsed,
-		MemoryPercent:   memPercent,
-		DiskTotalGB:     diskTotal,
-		DiskUsedGB:      diskUsed,
-		DiskPercent:     diskPercent,
-		LoadAvg1:        load1,
-		LoadAvg5:        load5,
-		LoadAvg15:       load15,
-	}, nil
+func runOnce(cfg config.Config) error {
+	return sendPayload(cfg, collectPayload(cfg))
 }
 
-func cpuUsageFromTop() (float64, error) {
-	out, err := exec.Command("sh", "-c", "top -bn1 | grep 'Cpu(s)'").Output()
-	if err != nil {
-		return 0, err
+func collectPayload(cfg config.Config) Payload {
+	payload := Payload{
+		Token:        cfg.Token,
+		AgentVersion: version,
+		AgentCommit:  commit,
+		Metadata:     payloadMetadata(cfg.Metadata),
+		Flags:        enabledFlags(cfg),
+		Environment:  agentEnvironment(cfg).payloadEnvironment(),
+	}
+	runCollectors(cfg, &payload)
+	payload.Timestamp = time.Now().UTC()
+
+	containers := payload.Containers
+	if containers == nil {
+		containers = []ContainerStatus{}
 	}
-	line := string(out)
-	// Exemplo: %Cpu(s):  2.3 us,  1.0 sy,  0.0 ni, 96.4 id,  0.2 wa...
-	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return 0, errors.New("unexpected top output")
+	for i := range containers {
+		containers[i].Notes = containerNotes(containers[i].Labels)
+		containers[i].Project = containers[i].Labels[composeProjectLabel]
+		containers[i].Service = containers[i].Labels[composeServiceLabel]
 	}
+	payload.Containers = containers
+	payload.allContainers = containers
 
-	var us, sy float64
-	for i, p := range parts {
-		if strings.HasPrefix(p, "us") && i > 0 {
-			us = parseFloat(parts[i-1])
-		}
-		if strings.HasPrefix(p, "sy") && i > 0 {
-			sy = parseFloat(parts[i-1])
+	if len(containers) > 0 && cfg.ContainerRollups != config.RollupsOff {
+		payload.Rollups = buildRollups(containers)
+		if cfg.ContainerRollups == rollupsOnly {
+			payload.Containers = []ContainerStatus{}
 		}
 	}
-
-	return us + sy, nil
+	return payload
 }
 
-func getCPUCores() int {
-	out, err := exec.Command("sh", "-c", "nproc").Output()
-	if err != nil {
-		return 0
+// logCollectorError avisa apenas sobre coletores que estouraram o prazo;
+// demais falhas (ex.: docker ausente) continuam silenciosas.
+func logCollectorError(name string, err error) {
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "collector %s: timeout, skipped\n", name)
 	}
-	cores := parseInt64(strings.TrimSpace(string(out)))
-	return int(cores)
 }
 
-func getMemoryInfo() (total, avail, used int64, percent float64) {
-	// free -m output:
-	//               total        used        free      shared  buff/cache   available
-	// Mem:           3911        1540         114         123        2255        1999
-	out, err := exec.Command("sh", "-c", "free -m | awk '/Mem:/ { print $2, $3, $7 }'").Output()
-	if err != nil {
-		return 0, 0, 0, 0
-	}
-	fields := strings.Fields(strings.TrimSpace(string(out)))
-	if len(fields) < 3 {
-		return 0, 0, 0, 0
-	}
-	total = parseInt64(fields[0])
-	used = parseInt64(fields[1])
-	avail = parseInt64(fields[2])
-	if total > 0 {
-		percent = float64(used) / float64(total) * 100
-	}
-	return
+// sendPayload envia a todos os sinks, sem spool.
+func sendPayload(cfg config.Config, payload Payload) error {
+	return deliverPayload(cfg, "", payload)
 }
 
-func getDiskInfo() (totalGB, usedGB, percent float64) {
-	// df output: Filesystem Size Used Avail Use% Mounted
-	out, err := exec.Command("sh", "-c", "df -BG / | awk 'NR==2 { gsub(\"G\",\"\"); print $2, $3, $5 }'").Output()
+// printDryRun coleta e imprime exatamente o que seria enviado, ja com o
+// filtro de campos aplicado. So o token e mascarado, para a saida poder ser
+// colada em issues. Nada e enviado nem gravado no estado.
+func printDryRun(cfg config.Config) error {
+	payload := collectPayload(cfg)
+	if payload.Token != "" {
+		payload.Token = "***"
+	}
+	body, err := marshalPayload(cfg, payload)
 	if err != nil {
-		return 0, 0, 0
+		return err
 	}
-	fields := strings.Fields(strings.TrimSpace(string(out)))
-	if len(fields) < 3 {
-		return 0, 0, 0
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return err
 	}
-	totalGB = parseFloat(fields[0])
-	usedGB = parseFloat(fields[1])
-	percent = parsePercent(fields[2])
-	return
+	out.WriteByte('\n')
+	_, err = os.Stdout.Write(out.Bytes())
+	return err
 }
 
-func getLoadAverage() (load1, load5, load15 float64) {
-	out, err := exec.Command("sh", "-c", "cat /proc/loadavg | awk '{ print $1, $2, $3 }'").Output()
-	if err != nil {
-		return 0, 0, 0
+// postPayload envia o payload e devolve o corpo da resposta, sem reagir a
+// ele. Com varios enderecos em api_url, passa ao seguinte nas falhas que o
+// spool guardaria (ver endpoints.go).
+func postPayload(cfg config.Config, payload Payload) ([]byte, error) {
+	if err := sendFault(); err != nil {
+		return nil, err
 	}
-	fields := strings.Fields(strings.TrimSpace(string(out)))
-	if len(fields) < 3 {
-		return 0, 0, 0
+	body, err := marshalPayload(cfg, payload)
+	if err != nil {
+		return nil, err
 	}
-	load1 = parseFloat(fields[0])
-	load5 = parseFloat(fields[1])
-	load15 = parseFloat(fields[2])
-	return
+
+	return callAPI(cfg, nil, func(endpoint string) ([]byte, error) {
+		b, err := postBody(cfg, endpoint, body)
+		if err == nil {
+			transport.MarkEndpointHealthy(cfg.Endpoints(), endpoint)
+		}
+		return b, err
+	})
 }
 
-func collectContainers() ([]ContainerStatus, error) {
-	out, err := exec.Command("sh", "-c", "docker ps -a --format '{{.ID}}|{{.Names}}|{{.Image}}|{{.State}}|{{.Status}}'").Output()
+func postBody(cfg config.Config, endpoint string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	containerMap := make(map[string]ContainerStatus)
+	req.Header.Set("Content-Type", "application/json")
 
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 5)
-		if len(parts) < 2 {
-			continue
-		}
-		id := strings.TrimSpace(parts[0])
-		name := strings.TrimSpace(parts[1])
-		image := ""
-		state := ""
-		status := ""
-		if len(parts) > 2 {
-			image = strings.TrimSpace(parts[2])
-		}
-		if len(parts) > 3 {
-			state = strings.TrimSpace(parts[3])
-		}
-		if len(parts) > 4 {
-			status = strings.TrimSpace(parts[4])
-		}
-		containerMap[name] = ContainerStatus{
-			ID:     id,
-			Name:   name,
-			Image:  image,
-			State:  state,
-			Status: status,
-		}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
 	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	stats, statsErr := exec.Command("sh", "-c", "docker stats --no-stream --all --format '{{.ID}}|{{.Name}}|{{.CPUPerc}}|{{.MemUsage}}|{{.MemPerc}}|{{.NetIO}}|{{.BlockIO}}|{{.PIDs}}'").Output()
-	if statsErr == nil {
-		statLines := strings.Split(strings.TrimSpace(string(stats)), "\n")
-		for _, line := range statLines {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "|", 8)
-			if len(parts) < 2 {
-				continue
-			}
-			id := strings.TrimSpace(parts[0])
-			name := strings.TrimSpace(parts[1])
-			cpu := 0.0
-			memUsage := ""
-			memPercent := 0.0
-			netIO := ""
-			blockIO := ""
-			var pids int64
-
-			if len(parts) > 2 {
-				cpu = parsePercent(parts[2])
-			}
-			if len(parts) > 3 {
-				memUsage = strings.TrimSpace(parts[3])
-			}
-			if len(parts) > 4 {
-				memPercent = parsePercent(parts[4])
-			}
-			if len(parts) > 5 {
-				netIO = strings.TrimSpace(parts[5])
-			}
-			if len(parts) > 6 {
-				blockIO = strings.TrimSpace(parts[6])
-			}
-			if len(parts) > 7 {
-				pids = parseInt64(parts[7])
-			}
-
-			entry := containerMap[name]
-			entry.ID = id
-			entry.Name = name
-			entry.CPUPercent = cpu
-			entry.MemUsage = memUsage
-			entry.MemPercent = memPercent
-			entry.NetIO = netIO
-			entry.BlockIO = blockIO
-			entry.PIDs = pids
-			containerMap[name] = entry
-		}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return nil, &apiError{StatusCode: resp.StatusCode, Body: string(b)}
 	}
+	return b, nil
+}
 
-	containers := make([]ContainerStatus, 0, len(containerMap))
-	for _, entry := range containerMap {
-		containers = append(containers, entry)
+// newHTTPClient monta o cliente usado para falar com a API. Sem proxy_url,
+// valem HTTP_PROXY/HTTPS_PROXY/NO_PROXY do ambiente. O transporte e
+// compartilhado (ver internal/transport), para o modo daemon reaproveitar
+// conexoes entre ciclos.
+func newHTTPClient(cfg config.Config) (*http.Client, error) {
+	t, err := transport.Shared(transport.Options{
+		ProxyURL: cfg.ProxyURL,
+		Tuning:   cfg.HTTP,
+		HTTP2:    featureEnabled(cfg, "transport.http2"),
+		DNSCache: featureEnabled(cfg, "transport.dns_cache"),
+	})
+	if err != nil {
+		return nil, err
 	}
-	return containers, nil
+	return &http.Client{Timeout: 10 * time.Second, Transport: t}, nil
 }
 
-func installAgent(cfg Config, configPath string) error {
-	if err := ensureDir(filepath.Dir(configPath)); err != nil {
+func installAgent(cfg config.Config, configPath string) error {
+	if err := fsutil.EnsureDir(filepath.Dir(configPath)); err != nil {
 		return err
 	}
 
@@ -401,46 +619,29 @@ func installAgent(cfg Config, configPath string) error {
 	if err != nil {
 		return err
 	}
-	target := "/usr/local/bin/vaultrix-agent"
-	if err := copyFile(exe, target); err != nil {
-		return err
-	}
-	if err := os.Chmod(target, 0o755); err != nil {
+	target := agentBinaryPath
+	if err := fsutil.EnsureDir(filepath.Dir(target)); err != nil {
 		return err
 	}
-
-	cron := fmt.Sprintf("*/%d * * * * root %s --once --config %s\n", cfg.Interval, target, configPath)
-	if err := os.WriteFile(cronPath, []byte(cron), 0o644); err != nil {
-		return err
-	}
-
-	_ = runOnce(cfg)
-	return nil
-}
-
-func loadConfig(path string) (Config, error) {
-	b, err := os.ReadFile(path)
+	body, releaseVersion, err := releaseBinaryForHost(cfg)
 	if err != nil {
-		return Config{}, err
+		return fmt.Errorf("release for this host: %w", err)
 	}
-	var cfg Config
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return Config{}, err
+	if body != nil {
+		if err := installBinary(target, body, releaseVersion); err != nil {
+			return err
+		}
+		fmt.Println(trf("Installed the %s build %s from the release URL.", hostArch(), releaseVersion))
+	} else {
+		if err := fsutil.CopyFile(exe, target); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, 0o755); err != nil {
+			return err
+		}
 	}
-	return cfg, validateConfig(cfg)
-}
 
-func validateConfig(cfg Config) error {
-	if cfg.Token == "" {
-		return errors.New("token is required")
-	}
-	if cfg.ApiURL == "" {
-		return errors.New("api-url is required")
-	}
-	if cfg.Interval < 1 {
-		cfg.Interval = 1
-	}
-	return nil
+	return installScheduler(cfg, target, configPath)
 }
 
 func parseFloat(value string) float64 {
@@ -455,45 +656,6 @@ func parsePercent(value string) float64 {
 	return parseFloat(value)
 }
 
-func parseInt64(value string) int64 {
-	value = strings.TrimSpace(value)
-	var v int64
-	fmt.Sscanf(value, "%d", &v)
-	return v
-}
-
-func ensureDir(path string) error {
-	if path == "" {
-		return nil
-	}
-	return os.MkdirAll(path, 0o755)
-}
-
-func copyFile(src, dst string) error {
-	input, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer input.Close()
-
-	output, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer output.Close()
-
-	if _, err := io.Copy(output, input); err != nil {
-		return err
-	}
-
-	return output.Sync()
-}
-
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
-
 func fatal(err error) {
 	fmt.Fprintln(os.Stderr, err.Error())
 	os.Exit(1)
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxMemcachedTimeoutSeconds = 60
+
+	// maxMemcachedStats limita as linhas lidas; o "stats" tem umas cem.
+	maxMemcachedStats = 1000
+)
+
+func validateMemcachedInstances(instances []config.MemcachedInstance) error {
+	seen := make(map[string]bool, len(instances))
+	for i, m := range instances {
+		if !config.SinkNamePattern.MatchString(m.Name) {
+			return fmt.Errorf("memcached[%d]: invalid name %q (lowercase letters, digits, - and _)", i, m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("memcached: duplicate name %q", m.Name)
+		}
+		seen[m.Name] = true
+		if m.Network() == "tcp" {
+			if _, _, err := net.SplitHostPort(m.Address); err != nil {
+				return fmt.Errorf("memcached: %s: invalid address %q; use host:port or a socket path", m.Name, m.Address)
+			}
+		}
+		if m.TimeoutSeconds < 0 || m.TimeoutSeconds > maxMemcachedTimeoutSeconds {
+			return fmt.Errorf("memcached: %s: timeout_seconds must be between 0 and %d", m.Name, maxMemcachedTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// MemcachedStatus e o "stats" de uma instancia. Hits, misses e evictions
+// sao acumulados desde o inicio do servidor, e HitRatio (em %) tambem.
+// Error vem preenchido quando a instancia nao respondeu, e o resto fica
+// vazio.
+type MemcachedStatus struct {
+	Version             string  `json:"version,omitempty"`
+	UptimeSeconds       int64   `json:"uptime_seconds,omitempty"`
+	CurrConnections     int64   `json:"curr_connections,omitempty"`
+	MaxConnections      int64   `json:"max_connections,omitempty"`
+	RejectedConnections int64   `json:"rejected_connections_total,omitempty"`
+	UsedMemoryBytes     int64   `json:"used_memory_bytes,omitempty"`
+	MaxMemoryBytes      int64   `json:"max_memory_bytes,omitempty"`
+	MemoryUsedPercent   float64 `json:"memory_used_percent,omitempty"`
+	Items               int64   `json:"items,omitempty"`
+	GetHits             int64   `json:"get_hits_total,omitempty"`
+	GetMisses           int64   `json:"get_misses_total,omitempty"`
+	HitRatio            float64 `json:"hit_ratio,omitempty"`
+	Evictions           int64   `json:"evictions_total,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func collectMemcached(instances []config.MemcachedInstance) map[string]MemcachedStatus {
+	return collectors.ByName(instances, func(m config.MemcachedInstance) string { return m.Name }, func(m config.MemcachedInstance) MemcachedStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), m.Timeout())
+		defer cancel()
+		stats, err := memcachedStats(ctx, m)
+		if err != nil {
+			return MemcachedStatus{Error: err.Error()}
+		}
+		return parseMemcachedStats(stats)
+	})
+}
+
+// memcachedStats manda "stats" e le as linhas "STAT nome valor" ate o END.
+func memcachedStats(ctx context.Context, m config.MemcachedInstance) (map[string]string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, m.Network(), m.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := io.WriteString(conn, "stats\r\n"); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	stats := map[string]string{}
+	for len(stats) < maxMemcachedStats {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "END":
+			io.WriteString(conn, "quit\r\n")
+			return stats, nil
+		case strings.HasPrefix(line, "STAT "):
+			name, value, _ := strings.Cut(line[len("STAT "):], " ")
+			stats[name] = value
+		case line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR ") || strings.HasPrefix(line, "SERVER_ERROR "):
+			return nil, fmt.Errorf("memcached: %s", line)
+		default:
+			return nil, fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+	}
+	return nil, errors.New("memcached: stats reply too long")
+}
+
+func parseMemcachedStats(stats map[string]string) MemcachedStatus {
+	num := func(key string) int64 {
+		n, _ := strconv.ParseInt(stats[key], 10, 64)
+		return n
+	}
+	s := MemcachedStatus{
+		Version:             stats["version"],
+		UptimeSeconds:       num("uptime"),
+		CurrConnections:     num("curr_connections"),
+		MaxConnections:      num("max_connections"),
+		RejectedConnections: num("rejected_connections"),
+		UsedMemoryBytes:     num("bytes"),
+		MaxMemoryBytes:      num("limit_maxbytes"),
+		Items:               num("curr_items"),
+		GetHits:             num("get_hits"),
+		GetMisses:           num("get_misses"),
+		Evictions:           num("evictions"),
+	}
+	s.MemoryUsedPercent = collectors.PercentOf(s.UsedMemoryBytes, s.MaxMemoryBytes)
+	s.HitRatio = collectors.PercentOf(s.GetHits, s.GetHits+s.GetMisses)
+	return s
+}
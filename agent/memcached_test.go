@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateMemcachedInstances(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances []config.MemcachedInstance
+		wantErr   string
+	}{
+		{"tcp", []config.MemcachedInstance{{Name: "sessions", Address: "127.0.0.1:11211"}}, ""},
+		{"socket", []config.MemcachedInstance{{Name: "sessions", Address: "/run/memcached/memcached.sock"}}, ""},
+		{"bad name", []config.MemcachedInstance{{Name: "Sessions", Address: "127.0.0.1:11211"}}, "invalid name"},
+		{"no port", []config.MemcachedInstance{{Name: "sessions", Address: "127.0.0.1"}}, "invalid address"},
+		{"duplicate", []config.MemcachedInstance{{Name: "a", Address: "h:1"}, {Name: "a", Address: "h:2"}}, "duplicate name"},
+		{"timeout", []config.MemcachedInstance{{Name: "a", Address: "h:1", TimeoutSeconds: 61}}, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMemcachedInstances(tt.instances)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeMemcached responde "stats" com reply, linha a linha.
+func fakeMemcached(t *testing.T, reply string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch strings.TrimSpace(line) {
+					case "stats":
+						fmt.Fprint(conn, reply)
+					case "quit":
+						return
+					default:
+						fmt.Fprint(conn, "ERROR\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestCollectMemcached(t *testing.T) {
+	ok := fakeMemcached(t, "STAT pid 42\r\nSTAT uptime 3600\r\nSTAT version 1.6.21\r\n"+
+		"STAT curr_connections 10\r\nSTAT max_connections 1024\r\nSTAT rejected_connections 2\r\n"+
+		"STAT bytes 16777216\r\nSTAT limit_maxbytes 67108864\r\nSTAT curr_items 500\r\n"+
+		"STAT get_hits 900\r\nSTAT get_misses 100\r\nSTAT evictions 7\r\nEND\r\n")
+	failing := fakeMemcached(t, "SERVER_ERROR out of memory\r\n")
+
+	got := collectMemcached([]config.MemcachedInstance{
+		{Name: "sessions", Address: ok},
+		{Name: "broken", Address: failing},
+	})
+	want := map[string]MemcachedStatus{
+		"sessions": {
+			Version: "1.6.21", UptimeSeconds: 3600,
+			CurrConnections: 10, MaxConnections: 1024, RejectedConnections: 2,
+			UsedMemoryBytes: 16777216, MaxMemoryBytes: 67108864, MemoryUsedPercent: 25,
+			Items: 500, GetHits: 900, GetMisses: 100, HitRatio: 90, Evictions: 7,
+		},
+		"broken": {Error: "memcached: SERVER_ERROR out of memory"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"vaultrix-agent/internal/config"
+)
+
+// payloadMetadata devolve nil quando nenhum campo esta preenchido, para o
+// payload nao levar um objeto vazio.
+func payloadMetadata(m *config.HostMetadata) *config.HostMetadata {
+	if m == nil || *m == (config.HostMetadata{}) {
+		return nil
+	}
+	return m
+}
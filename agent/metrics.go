@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// collectMetrics monta as metricas do host a partir das leituras de cada
+// plataforma (metrics_<goos>.go). Leituras que falham ficam zeradas em vez de
+// derrubar a coleta inteira.
+func collectMetrics(ctx context.Context, cfg config.Config) (Metrics, error) {
+	var m Metrics
+	if collectorEnabled(cfg, config.CollectorCPU) {
+		if err := collectorFault(ctx, config.CollectorCPU); err != nil {
+			return Metrics{}, err
+		}
+		m.CPUUsage, _ = readCPUUsage(ctx)
+		m.CPUCores = readCPUCores()
+	}
+	if collectorEnabled(cfg, config.CollectorMemory) {
+		if err := collectorFault(ctx, config.CollectorMemory); err != nil {
+			return Metrics{}, err
+		}
+		if mem, err := readMemory(ctx); err == nil {
+			m.MemoryTotalMB = int64(mem.total / mb)
+			m.MemoryAvailMB = int64(mem.available / mb)
+			m.MemoryUsedMB = m.MemoryTotalMB - m.MemoryAvailMB
+			if m.MemoryTotalMB > 0 {
+				m.MemoryPercent = float64(m.MemoryUsedMB) / float64(m.MemoryTotalMB) * 100
+			}
+		}
+	}
+	if collectorEnabled(cfg, config.CollectorDisk) {
+		if err := collectorFault(ctx, config.CollectorDisk); err != nil {
+			return Metrics{}, err
+		}
+		if disk, err := readRootDisk(ctx, diskRoot(cfg)); err == nil {
+			m.DiskTotalGB = float64(disk.total) / gb
+			m.DiskUsedGB = float64(disk.used) / gb
+			if disk.used+disk.available > 0 {
+				m.DiskPercent = float64(disk.used) / float64(disk.used+disk.available) * 100
+			}
+		}
+	}
+	if collectorEnabled(cfg, config.CollectorLoad) {
+		if err := collectorFault(ctx, config.CollectorLoad); err != nil {
+			return Metrics{}, err
+		}
+		m.LoadAvg1, m.LoadAvg5, m.LoadAvg15, _ = readLoadAverage(ctx)
+	}
+	if collectorEnabled(cfg, config.CollectorDisks) {
+		if err := collectorFault(ctx, config.CollectorDisks); err != nil {
+			return Metrics{}, err
+		}
+		m.Disks = collectDisks(ctx, diskRoot(cfg))
+	}
+	if err := ctx.Err(); err != nil {
+		return Metrics{}, err
+	}
+	return m, nil
+}
+
+const (
+	mb = 1024 * 1024
+	gb = 1024 * 1024 * 1024
+)
+
+// cpuSampleInterval e a janela entre as duas leituras de contadores de CPU.
+const cpuSampleInterval = 500 * time.Millisecond
+
+type memoryReading struct {
+	total     uint64
+	available uint64
+}
+
+// diskReading segue a semantica do df: percentual = usado / (usado + livre
+// para usuarios comuns).
+type diskReading struct {
+	total     uint64
+	used      uint64
+	available uint64
+}
+
+// cpuTimes acumula tempos de CPU para calcular o uso entre duas amostras.
+type cpuTimes struct {
+	busy  uint64
+	total uint64
+}
+
+func cpuPercentBetween(a, b cpuTimes) float64 {
+	if b.total <= a.total {
+		return 0
+	}
+	return float64(b.busy-a.busy) / float64(b.total-a.total) * 100
+}
+
+// sampleCPU le os contadores duas vezes, com cpuSampleInterval de intervalo.
+func sampleCPU(ctx context.Context, read func() (cpuTimes, error)) (float64, error) {
+	first, err := read()
+	if err != nil {
+		return 0, err
+	}
+	select {
+	case <-time.After(cpuSampleInterval):
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	second, err := read()
+	if err != nil {
+		return 0, err
+	}
+	return cpuPercentBetween(first, second), nil
+}
@@ -0,0 +1,83 @@
+//go:build darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"vaultrix-agent/internal/collectors"
+)
+
+// O macOS nao expoe contadores de CPU por sysctl sem cgo; o top em modo
+// logging faz duas amostras e a segunda reflete o uso atual.
+var darwinCPURe = regexp.MustCompile(`CPU usage: ([\d.]+)% user, ([\d.]+)% sys`)
+
+func readCPUUsage(ctx context.Context) (float64, error) {
+	out, err := exec.CommandContext(ctx, "top", "-l", "2", "-n", "0", "-s", "1").Output()
+	if err != nil {
+		return 0, err
+	}
+	matches := darwinCPURe.FindAllStringSubmatch(string(out), -1)
+	if len(matches) == 0 {
+		return 0, errors.New("unexpected top output")
+	}
+	last := matches[len(matches)-1]
+	return parseFloat(last[1]) + parseFloat(last[2]), nil
+}
+
+func readCPUCores() int {
+	return runtime.NumCPU()
+}
+
+func readMemory(ctx context.Context) (memoryReading, error) {
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return memoryReading{}, err
+	}
+	pageSize, err := unix.SysctlUint32("vm.pagesize")
+	if err != nil {
+		return memoryReading{total: total}, err
+	}
+
+	// Paginas livres, inativas e especulativas podem ser reaproveitadas
+	// imediatamente, como o MemAvailable do Linux.
+	out, err := exec.CommandContext(ctx, "vm_stat").Output()
+	if err != nil {
+		return memoryReading{total: total}, err
+	}
+	var pages uint64
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Pages free", "Pages inactive", "Pages speculative":
+			pages += uint64(collectors.ParseInt64(strings.TrimSuffix(strings.TrimSpace(value), ".")))
+		}
+	}
+	return memoryReading{total: total, available: pages * uint64(pageSize)}, nil
+}
+
+func readRootDisk(ctx context.Context, root string) (diskReading, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs("/", &st); err != nil {
+		return diskReading{}, err
+	}
+	bsize := uint64(st.Bsize)
+	return diskReading{
+		total:     st.Blocks * bsize,
+		used:      (st.Blocks - st.Bfree) * bsize,
+		available: st.Bavail * bsize,
+	}, nil
+}
+
+func readLoadAverage(ctx context.Context) (load1, load5, load15 float64, err error) {
+	return sysctlLoadAverage()
+}
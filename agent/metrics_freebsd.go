@@ -0,0 +1,85 @@
+//go:build freebsd
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+func readCPUUsage(ctx context.Context) (float64, error) {
+	return sampleCPU(ctx, readCPTime)
+}
+
+// readCPTime le kern.cp_time: user, nice, sys, intr, idle (longs).
+func readCPTime() (cpuTimes, error) {
+	raw, err := unix.SysctlRaw("kern.cp_time")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	size := len(raw) / 5
+	if size != 4 && size != 8 {
+		return cpuTimes{}, errors.New("unexpected kern.cp_time size")
+	}
+	var t cpuTimes
+	for i := 0; i < 5; i++ {
+		var v uint64
+		if size == 8 {
+			v = binary.LittleEndian.Uint64(raw[i*8 : i*8+8])
+		} else {
+			v = uint64(binary.LittleEndian.Uint32(raw[i*4 : i*4+4]))
+		}
+		t.total += v
+		if i != 4 {
+			t.busy += v
+		}
+	}
+	return t, nil
+}
+
+func readCPUCores() int {
+	return runtime.NumCPU()
+}
+
+func readMemory(ctx context.Context) (memoryReading, error) {
+	total, err := unix.SysctlUint64("hw.physmem")
+	if err != nil {
+		return memoryReading{}, err
+	}
+	pageSize, err := unix.SysctlUint32("hw.pagesize")
+	if err != nil {
+		return memoryReading{total: total}, err
+	}
+	var pages uint64
+	for _, name := range []string{"vm.stats.vm.v_free_count", "vm.stats.vm.v_inactive_count", "vm.stats.vm.v_cache_count"} {
+		if v, err := unix.SysctlUint32(name); err == nil {
+			pages += uint64(v)
+		}
+	}
+	return memoryReading{total: total, available: pages * uint64(pageSize)}, nil
+}
+
+func readRootDisk(ctx context.Context, root string) (diskReading, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs("/", &st); err != nil {
+		return diskReading{}, err
+	}
+	bsize := st.Bsize
+	avail := uint64(0)
+	if st.Bavail > 0 {
+		avail = uint64(st.Bavail) * bsize
+	}
+	return diskReading{
+		total:     st.Blocks * bsize,
+		used:      (st.Blocks - st.Bfree) * bsize,
+		available: avail,
+	}, nil
+}
+
+func readLoadAverage(ctx context.Context) (load1, load5, load15 float64, err error) {
+	return sysctlLoadAverage()
+}
@@ -0,0 +1,108 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/fsutil"
+)
+
+func readCPUUsage(ctx context.Context) (float64, error) {
+	return sampleCPU(ctx, readProcStat)
+}
+
+// readProcStat le a linha agregada "cpu" de /proc/stat.
+func readProcStat() (cpuTimes, error) {
+	b, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	line, _, _ := strings.Cut(string(b), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuTimes{}, errors.New("unexpected /proc/stat format")
+	}
+	var t cpuTimes
+	// user nice system idle iowait irq softirq steal guest guest_nice;
+	// guest ja esta contido em user, por isso so os 8 primeiros contam.
+	for i, f := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		v := uint64(collectors.ParseInt64(f))
+		t.total += v
+		if i != 3 && i != 4 {
+			t.busy += v
+		}
+	}
+	return t, nil
+}
+
+func readCPUCores() int {
+	return runtime.NumCPU()
+}
+
+func readMemory(ctx context.Context) (memoryReading, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return memoryReading{}, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		values[key] = uint64(collectors.ParseInt64(fields[0])) * 1024
+	}
+
+	mem := memoryReading{total: values["MemTotal"]}
+	if avail, ok := values["MemAvailable"]; ok {
+		mem.available = avail
+	} else {
+		// kernels anteriores ao 3.14
+		mem.available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	if mem.total == 0 {
+		return mem, errors.New("MemTotal not found in /proc/meminfo")
+	}
+	return mem, scanner.Err()
+}
+
+// readRootDisk mede o sistema de arquivos em root: "/" ou o / do host
+// montado no container.
+func readRootDisk(ctx context.Context, root string) (diskReading, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil {
+		return diskReading{}, err
+	}
+	bsize := uint64(st.Bsize)
+	return diskReading{
+		total:     st.Blocks * bsize,
+		used:      (st.Blocks - st.Bfree) * bsize,
+		available: st.Bavail * bsize,
+	}, nil
+}
+
+func readLoadAverage(ctx context.Context) (load1, load5, load15 float64, err error) {
+	fields := strings.Fields(fsutil.ReadTrimmed("/proc/loadavg"))
+	if len(fields) < 3 {
+		return 0, 0, 0, errors.New("unexpected /proc/loadavg format")
+	}
+	return parseFloat(fields[0]), parseFloat(fields[1]), parseFloat(fields[2]), nil
+}
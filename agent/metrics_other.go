@@ -0,0 +1,31 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+)
+
+var errUnsupportedPlatform = errors.New("metric not supported on " + runtime.GOOS)
+
+func readCPUUsage(ctx context.Context) (float64, error) {
+	return 0, errUnsupportedPlatform
+}
+
+func readCPUCores() int {
+	return runtime.NumCPU()
+}
+
+func readMemory(ctx context.Context) (memoryReading, error) {
+	return memoryReading{}, errUnsupportedPlatform
+}
+
+func readRootDisk(ctx context.Context, root string) (diskReading, error) {
+	return diskReading{}, errUnsupportedPlatform
+}
+
+func readLoadAverage(ctx context.Context) (load1, load5, load15 float64, err error) {
+	return 0, 0, 0, errUnsupportedPlatform
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+	procGetSystemTimes       = kernel32.NewProc("GetSystemTimes")
+	procGlobalMemoryStatusEx = kernel32.NewProc("GlobalMemoryStatusEx")
+)
+
+func readCPUUsage(ctx context.Context) (float64, error) {
+	return sampleCPU(ctx, readSystemTimes)
+}
+
+// readSystemTimes usa GetSystemTimes; o tempo de kernel ja inclui o idle.
+func readSystemTimes() (cpuTimes, error) {
+	var idle, kernel, user windows.Filetime
+	r, _, err := procGetSystemTimes.Call(
+		uintptr(unsafe.Pointer(&idle)),
+		uintptr(unsafe.Pointer(&kernel)),
+		uintptr(unsafe.Pointer(&user)),
+	)
+	if r == 0 {
+		return cpuTimes{}, err
+	}
+	toUint := func(ft windows.Filetime) uint64 {
+		return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+	}
+	total := toUint(kernel) + toUint(user)
+	return cpuTimes{busy: total - toUint(idle), total: total}, nil
+}
+
+func readCPUCores() int {
+	return runtime.NumCPU()
+}
+
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+func readMemory(ctx context.Context) (memoryReading, error) {
+	st := memoryStatusEx{}
+	st.Length = uint32(unsafe.Sizeof(st))
+	r, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&st)))
+	if r == 0 {
+		return memoryReading{}, err
+	}
+	return memoryReading{total: st.TotalPhys, available: st.AvailPhys}, nil
+}
+
+func readRootDisk(ctx context.Context, root string) (diskReading, error) {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	path, err := windows.UTF16PtrFromString(drive + `\`)
+	if err != nil {
+		return diskReading{}, err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, &total, &free); err != nil {
+		return diskReading{}, err
+	}
+	return diskReading{total: total, used: total - free, available: available}, nil
+}
+
+// O Windows nao tem load average.
+func readLoadAverage(ctx context.Context) (load1, load5, load15 float64, err error) {
+	return 0, 0, 0, errors.New("load average not available on windows")
+}
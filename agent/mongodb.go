@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxMongoDBTimeoutSeconds = 60
+	defaultMongoDBPort       = "27017"
+)
+
+func validateMongoDBInstances(instances []config.MongoDBInstance) error {
+	seen := make(map[string]bool, len(instances))
+	for i, m := range instances {
+		if !config.SinkNamePattern.MatchString(m.Name) {
+			return fmt.Errorf("mongodb[%d]: invalid name %q (lowercase letters, digits, - and _)", i, m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("mongodb: duplicate name %q", m.Name)
+		}
+		seen[m.Name] = true
+		if _, err := parseMongoDBURI(m.URI); err != nil {
+			return fmt.Errorf("%w (instance %s)", err, m.Name)
+		}
+		if m.TimeoutSeconds < 0 || m.TimeoutSeconds > maxMongoDBTimeoutSeconds {
+			return fmt.Errorf("mongodb: %s: timeout_seconds must be between 0 and %d", m.Name, maxMongoDBTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+type mongoURI struct {
+	User       string
+	Password   string
+	Host       string
+	AuthSource string
+}
+
+func (u mongoURI) network() string {
+	if strings.HasPrefix(u.Host, "/") {
+		return "unix"
+	}
+	return "tcp"
+}
+
+// parseMongoDBURI le a URI a mao: net/url nao aceita a lista de hosts nem
+// o socket escapado ("mongodb://%2Frun%2Fmongodb%2Fmongodb-27017.sock").
+// Com varios hosts o coletor fala so com o primeiro, que e o no local.
+func parseMongoDBURI(uri string) (mongoURI, error) {
+	u := mongoURI{}
+	rest, ok := strings.CutPrefix(uri, "mongodb://")
+	if !ok {
+		if strings.HasPrefix(uri, "mongodb+srv://") {
+			return u, errors.New("mongodb: mongodb+srv URIs are not supported; list the host")
+		}
+		return u, errors.New("mongodb: the uri must start with mongodb://")
+	}
+	authority, path := rest, ""
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+	}
+	hosts := authority
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		userinfo := authority[:i]
+		hosts = authority[i+1:]
+		user, password, _ := strings.Cut(userinfo, ":")
+		var err error
+		if u.User, err = url.PathUnescape(user); err != nil {
+			return u, errors.New("mongodb: invalid user in uri")
+		}
+		if u.Password, err = url.PathUnescape(password); err != nil {
+			return u, errors.New("mongodb: invalid password in uri")
+		}
+	}
+	host, err := url.PathUnescape(strings.Split(hosts, ",")[0])
+	if err != nil || host == "" {
+		return u, errors.New("mongodb: the uri has no host")
+	}
+	if !strings.HasPrefix(host, "/") {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), defaultMongoDBPort)
+		}
+	}
+	u.Host = host
+
+	db, rawQuery, _ := strings.Cut(strings.TrimPrefix(path, "/"), "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return u, errors.New("mongodb: invalid uri options")
+	}
+	u.AuthSource = query.Get("authSource")
+	if u.AuthSource == "" {
+		u.AuthSource = "admin"
+		if db != "" {
+			u.AuthSource = db
+		}
+	}
+	if m := query.Get("authMechanism"); m != "" && m != "SCRAM-SHA-256" {
+		return u, fmt.Errorf("mongodb: authMechanism %s is not supported; use SCRAM-SHA-256", m)
+	}
+	if query.Get("tls") == "true" || query.Get("ssl") == "true" {
+		return u, errors.New("mongodb: tls is not supported; point the agent at the local server")
+	}
+	if u.Password != "" && u.User == "" {
+		return u, errors.New("mongodb: the uri has a password but no user")
+	}
+	return u, nil
+}
+
+// redactMongoDBURI esconde a senha para o "config show".
+func redactMongoDBURI(uri string) string {
+	u, err := parseMongoDBURI(uri)
+	if err != nil {
+		return "***"
+	}
+	host := u.Host
+	if u.network() == "unix" {
+		host = url.PathEscape(host)
+	}
+	if u.User == "" {
+		return "mongodb://" + host + "/"
+	}
+	user := u.User
+	if u.Password != "" {
+		user += ":***"
+	}
+	return fmt.Sprintf("mongodb://%s@%s/?authSource=%s", user, host, u.AuthSource)
+}
+
+// MongoDBStatus vem do serverStatus. Os opcounters sao acumulados desde o
+// inicio do servidor. Error vem preenchido quando a instancia nao
+// respondeu, e o resto fica vazio.
+type MongoDBStatus struct {
+	Version              string           `json:"version,omitempty"`
+	UptimeSeconds        int64            `json:"uptime_seconds,omitempty"`
+	Connections          int64            `json:"connections,omitempty"`
+	ConnectionsAvailable int64            `json:"connections_available,omitempty"`
+	ConnectionsCreated   int64            `json:"connections_created_total,omitempty"`
+	Opcounters           map[string]int64 `json:"opcounters_total,omitempty"`
+	ResidentBytes        int64            `json:"resident_bytes,omitempty"`
+	VirtualBytes         int64            `json:"virtual_bytes,omitempty"`
+
+	Replication *MongoDBReplication `json:"replication,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// MongoDBReplication so aparece num membro de replica set. LagSeconds e o
+// atraso deste no em relacao ao primario; Members traz o de cada membro.
+type MongoDBReplication struct {
+	SetName    string          `json:"set_name"`
+	Role       string          `json:"role"`
+	LagSeconds *float64        `json:"lag_seconds,omitempty"`
+	Members    []MongoDBMember `json:"members,omitempty"`
+}
+
+type MongoDBMember struct {
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	Healthy    bool     `json:"healthy"`
+	LagSeconds *float64 `json:"lag_seconds,omitempty"`
+}
+
+// collectMongoDB le todas as instancias em paralelo, cada uma com o seu
+// prazo.
+func collectMongoDB(instances []config.MongoDBInstance) map[string]MongoDBStatus {
+	return collectors.ByName(instances, func(m config.MongoDBInstance) string { return m.Name }, func(m config.MongoDBInstance) MongoDBStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), m.Timeout())
+		defer cancel()
+		s, err := mongoDBStatus(ctx, m)
+		if err != nil {
+			return MongoDBStatus{Error: err.Error()}
+		}
+		return s
+	})
+}
+
+// mongoDBOpcounters sao os contadores que o coletor reporta.
+var mongoDBOpcounters = []string{"insert", "query", "update", "delete", "getmore", "command"}
+
+func mongoDBStatus(ctx context.Context, m config.MongoDBInstance) (MongoDBStatus, error) {
+	uri, err := parseMongoDBURI(m.URI)
+	if err != nil {
+		return MongoDBStatus{}, err
+	}
+	conn, err := dialMongoDB(ctx, uri)
+	if err != nil {
+		return MongoDBStatus{}, err
+	}
+	defer conn.Close()
+
+	doc, err := conn.command(bsonD{{"serverStatus", 1}, {"$db", "admin"}})
+	if err != nil {
+		return MongoDBStatus{}, err
+	}
+	s := MongoDBStatus{
+		UptimeSeconds:        bsonInt(doc["uptime"]),
+		Connections:          bsonInt(bsonPath(doc, "connections", "current")),
+		ConnectionsAvailable: bsonInt(bsonPath(doc, "connections", "available")),
+		ConnectionsCreated:   bsonInt(bsonPath(doc, "connections", "totalCreated")),
+		// mem vem em MiB
+		ResidentBytes: bsonInt(bsonPath(doc, "mem", "resident")) << 20,
+		VirtualBytes:  bsonInt(bsonPath(doc, "mem", "virtual")) << 20,
+	}
+	s.Version, _ = doc["version"].(string)
+	if _, ok := doc["opcounters"].(map[string]any); ok {
+		s.Opcounters = make(map[string]int64, len(mongoDBOpcounters))
+		for _, op := range mongoDBOpcounters {
+			s.Opcounters[op] = bsonInt(bsonPath(doc, "opcounters", op))
+		}
+	}
+
+	// fora de um replica set o comando falha (NoReplicationEnabled), e sem
+	// clusterMonitor tambem: nos dois casos a replicacao so fica de fora
+	if rs, err := conn.command(bsonD{{"replSetGetStatus", 1}, {"$db", "admin"}}); err == nil {
+		s.Replication = mongoDBReplication(rs)
+	}
+	return s, nil
+}
+
+// mongoDBReplication calcula o atraso de cada secundario pela diferenca
+// entre o optimeDate do primario e o dele.
+func mongoDBReplication(rs map[string]any) *MongoDBReplication {
+	members, _ := rs["members"].([]any)
+	r := &MongoDBReplication{}
+	r.SetName, _ = rs["set"].(string)
+
+	var primary time.Time
+	for _, raw := range members {
+		m, _ := raw.(map[string]any)
+		if state, _ := m["stateStr"].(string); state == "PRIMARY" {
+			primary, _ = m["optimeDate"].(time.Time)
+		}
+	}
+	for _, raw := range members {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		member := MongoDBMember{}
+		member.Name, _ = m["name"].(string)
+		state, _ := m["stateStr"].(string)
+		member.State = strings.ToLower(state)
+		health, _ := bsonNumber(m["health"])
+		member.Healthy = health == 1
+		if optime, ok := m["optimeDate"].(time.Time); ok && state == "SECONDARY" && !primary.IsZero() {
+			lag := math.Max(primary.Sub(optime).Seconds(), 0)
+			member.LagSeconds = &lag
+		}
+		if self, _ := m["self"].(bool); self {
+			r.Role = member.State
+			r.LagSeconds = member.LagSeconds
+		}
+		r.Members = append(r.Members, member)
+	}
+	return r
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Cliente minimo do protocolo do MongoDB, como os do MySQL e do PostgreSQL:
+// comandos em OP_MSG (MongoDB 3.6+), um codificador de BSON para os poucos
+// tipos que os comandos usam e um decodificador para as respostas. A
+// autenticacao e SCRAM-SHA-256 (MongoDB 4.0+). Sem TLS: o alvo e o servidor
+// local.
+
+const (
+	mongoOpMsg           = 2013
+	maxMongoReply        = 48 << 20
+	mongoChecksumPresent = 1 << 0
+)
+
+// bsonD e um documento com as chaves na ordem: o nome do comando tem de vir
+// primeiro.
+type bsonD []bsonE
+
+type bsonE struct {
+	Key   string
+	Value any
+}
+
+func appendBSON(b []byte, d bsonD) []byte {
+	start := len(b)
+	b = append(b, 0, 0, 0, 0)
+	for _, e := range d {
+		switch v := e.Value.(type) {
+		case float64:
+			b = appendBSONKey(b, 0x01, e.Key)
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		case string:
+			b = appendBSONKey(b, 0x02, e.Key)
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(v)+1))
+			b = append(append(b, v...), 0)
+		case bsonD:
+			b = appendBSONKey(b, 0x03, e.Key)
+			b = appendBSON(b, v)
+		case []byte:
+			b = appendBSONKey(b, 0x05, e.Key)
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+			b = append(append(b, 0), v...)
+		case bool:
+			b = appendBSONKey(b, 0x08, e.Key)
+			if v {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		case int32:
+			b = appendBSONKey(b, 0x10, e.Key)
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		case int:
+			b = appendBSONKey(b, 0x10, e.Key)
+			b = binary.LittleEndian.AppendUint32(b, uint32(int32(v)))
+		case int64:
+			b = appendBSONKey(b, 0x12, e.Key)
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		default:
+			panic(fmt.Sprintf("bson: unsupported type %T", v))
+		}
+	}
+	b = append(b, 0)
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start))
+	return b
+}
+
+func appendBSONKey(b []byte, kind byte, key string) []byte {
+	return append(append(append(b, kind), key...), 0)
+}
+
+// decodeBSON le um documento para um mapa. Subdocumentos viram mapas,
+// arrays viram []any, datas viram time.Time e ObjectIds viram hex; os
+// numeros ficam no tipo do BSON (int32, int64 ou float64).
+func decodeBSON(b []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	err := walkBSON(b, func(key string, v any) { doc[key] = v })
+	return doc, err
+}
+
+func decodeBSONArray(b []byte) ([]any, error) {
+	var arr []any
+	err := walkBSON(b, func(_ string, v any) { arr = append(arr, v) })
+	return arr, err
+}
+
+var errBSON = errors.New("bson: malformed document")
+
+func walkBSON(b []byte, fn func(key string, v any)) error {
+	if len(b) < 5 || int(binary.LittleEndian.Uint32(b)) != len(b) || b[len(b)-1] != 0 {
+		return errBSON
+	}
+	b = b[4 : len(b)-1]
+	for len(b) > 0 {
+		kind := b[0]
+		end := bytes.IndexByte(b[1:], 0)
+		if end < 0 {
+			return errBSON
+		}
+		key := string(b[1 : 1+end])
+		b = b[end+2:]
+
+		var v any
+		n := 0
+		need := func(size int) bool { return len(b) >= size }
+		switch kind {
+		case 0x01: // double
+			if n = 8; !need(n) {
+				return errBSON
+			}
+			v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case 0x02, 0x0D, 0x0E: // string, javascript, simbolo
+			if !need(4) {
+				return errBSON
+			}
+			size := int(binary.LittleEndian.Uint32(b))
+			if n = 4 + size; size < 1 || !need(n) {
+				return errBSON
+			}
+			v = string(b[4 : n-1])
+		case 0x03, 0x04: // documento, array
+			if !need(4) {
+				return errBSON
+			}
+			if n = int(binary.LittleEndian.Uint32(b)); n < 5 || !need(n) {
+				return errBSON
+			}
+			var err error
+			if kind == 0x03 {
+				v, err = decodeBSON(b[:n])
+			} else {
+				v, err = decodeBSONArray(b[:n])
+			}
+			if err != nil {
+				return err
+			}
+		case 0x05: // binario
+			if !need(5) {
+				return errBSON
+			}
+			size := int(binary.LittleEndian.Uint32(b))
+			if n = 5 + size; size < 0 || !need(n) {
+				return errBSON
+			}
+			v = b[5:n]
+		case 0x06, 0x0A, 0x7F, 0xFF: // undefined, null, maxkey, minkey
+		case 0x07: // ObjectId
+			if n = 12; !need(n) {
+				return errBSON
+			}
+			v = hex.EncodeToString(b[:n])
+		case 0x08: // bool
+			if n = 1; !need(n) {
+				return errBSON
+			}
+			v = b[0] != 0
+		case 0x09: // data em ms
+			if n = 8; !need(n) {
+				return errBSON
+			}
+			v = time.UnixMilli(int64(binary.LittleEndian.Uint64(b))).UTC()
+		case 0x0B: // regex: duas cstrings
+			i := bytes.IndexByte(b, 0)
+			j := -1
+			if i >= 0 {
+				j = bytes.IndexByte(b[i+1:], 0)
+			}
+			if j < 0 {
+				return errBSON
+			}
+			n = i + 1 + j + 1
+		case 0x10: // int32
+			if n = 4; !need(n) {
+				return errBSON
+			}
+			v = int32(binary.LittleEndian.Uint32(b))
+		case 0x11: // timestamp
+			if n = 8; !need(n) {
+				return errBSON
+			}
+			v = binary.LittleEndian.Uint64(b)
+		case 0x12: // int64
+			if n = 8; !need(n) {
+				return errBSON
+			}
+			v = int64(binary.LittleEndian.Uint64(b))
+		case 0x13: // decimal128, que nenhuma metrica usa
+			if n = 16; !need(n) {
+				return errBSON
+			}
+		default:
+			return fmt.Errorf("bson: unsupported type 0x%02x", kind)
+		}
+		fn(key, v)
+		b = b[n:]
+	}
+	return nil
+}
+
+// bsonNumber converte os tipos numericos do BSON; o servidor usa int32,
+// int64 ou double para o mesmo campo conforme a versao e o tamanho.
+func bsonNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func bsonInt(v any) int64 {
+	n, _ := bsonNumber(v)
+	return int64(n)
+}
+
+// bsonPath desce pelos subdocumentos; nil se algum nivel falta.
+func bsonPath(doc map[string]any, keys ...string) any {
+	var v any = doc
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+// mongoServerError e uma resposta com ok: 0.
+type mongoServerError struct {
+	Code     int64
+	CodeName string
+	Message  string
+}
+
+func (e *mongoServerError) Error() string {
+	if e.CodeName != "" {
+		return fmt.Sprintf("mongodb error %d (%s): %s", e.Code, e.CodeName, e.Message)
+	}
+	return fmt.Sprintf("mongodb error %d: %s", e.Code, e.Message)
+}
+
+type mongoConn struct {
+	conn net.Conn
+}
+
+var mongoRequestID atomic.Int32
+
+func dialMongoDB(ctx context.Context, uri mongoURI) (*mongoConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, uri.network(), uri.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c := &mongoConn{conn: conn}
+	if uri.User != "" {
+		if err := c.authenticate(uri); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *mongoConn) Close() error {
+	return c.conn.Close()
+}
+
+// command manda um comando em OP_MSG e devolve a resposta, ou o erro do
+// servidor quando ela vem com ok: 0.
+func (c *mongoConn) command(cmd bsonD) (map[string]any, error) {
+	body := appendBSON(append(make([]byte, 16, 64), 0, 0, 0, 0, 0), cmd)
+	binary.LittleEndian.PutUint32(body[0:], uint32(len(body)))
+	binary.LittleEndian.PutUint32(body[4:], uint32(mongoRequestID.Add(1)))
+	binary.LittleEndian.PutUint32(body[12:], mongoOpMsg)
+	if _, err := c.conn.Write(body); err != nil {
+		return nil, err
+	}
+
+	var header [16]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.LittleEndian.Uint32(header[:]))
+	if n < 16+5 || n > maxMongoReply {
+		return nil, errors.New("mongodb: invalid message length")
+	}
+	if op := binary.LittleEndian.Uint32(header[12:]); op != mongoOpMsg {
+		return nil, fmt.Errorf("mongodb: unexpected opcode %d", op)
+	}
+	msg := make([]byte, n-16)
+	if _, err := io.ReadFull(c.conn, msg); err != nil {
+		return nil, err
+	}
+	flags := binary.LittleEndian.Uint32(msg)
+	msg = msg[4:]
+	if flags&mongoChecksumPresent != 0 && len(msg) >= 4 {
+		msg = msg[:len(msg)-4]
+	}
+	// a resposta de um comando e uma secao do tipo 0
+	if len(msg) < 5 || msg[0] != 0 {
+		return nil, errors.New("mongodb: malformed reply")
+	}
+	size := int(binary.LittleEndian.Uint32(msg[1:]))
+	if size > len(msg)-1 {
+		return nil, errors.New("mongodb: malformed reply")
+	}
+	doc, err := decodeBSON(msg[1 : 1+size])
+	if err != nil {
+		return nil, err
+	}
+	if ok, _ := bsonNumber(doc["ok"]); ok != 1 {
+		e := &mongoServerError{Code: bsonInt(doc["code"])}
+		e.CodeName, _ = doc["codeName"].(string)
+		e.Message, _ = doc["errmsg"].(string)
+		return nil, e
+	}
+	return doc, nil
+}
+
+// authenticate faz o SCRAM-SHA-256 com saslStart e saslContinue.
+func (c *mongoConn) authenticate(uri mongoURI) error {
+	s, err := newSCRAMSHA256(uri.User, uri.Password)
+	if err != nil {
+		return err
+	}
+	reply, err := c.command(bsonD{
+		{"saslStart", 1},
+		{"mechanism", "SCRAM-SHA-256"},
+		{"payload", []byte(s.clientFirst())},
+		{"options", bsonD{{"skipEmptyExchange", true}}},
+		{"$db", uri.AuthSource},
+	})
+	if err != nil {
+		return err
+	}
+	serverFirst, _ := reply["payload"].([]byte)
+	final, err := s.clientFinal(string(serverFirst))
+	if err != nil {
+		return err
+	}
+	// o id volta no tipo em que veio
+	conversation, ok := reply["conversationId"].(int32)
+	if !ok {
+		return errors.New("mongodb: saslStart reply without conversationId")
+	}
+	reply, err = c.command(bsonD{
+		{"saslContinue", 1},
+		{"conversationId", conversation},
+		{"payload", []byte(final)},
+		{"$db", uri.AuthSource},
+	})
+	if err != nil {
+		return err
+	}
+	serverFinal, _ := reply["payload"].([]byte)
+	if !s.verifyServer(string(serverFinal)) {
+		return errors.New("mongodb: invalid SCRAM server signature")
+	}
+	// servidores sem skipEmptyExchange pedem mais uma volta vazia
+	for range 2 {
+		if done, _ := reply["done"].(bool); done {
+			return nil
+		}
+		reply, err = c.command(bsonD{
+			{"saslContinue", 1},
+			{"conversationId", conversation},
+			{"payload", []byte{}},
+			{"$db", uri.AuthSource},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("mongodb: SCRAM conversation did not finish")
+}
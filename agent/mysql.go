@@ -0,0 +1,157 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// MySQLStatus e o que o coletor reporta. Contadores (total de conexoes,
+// slow queries, questions, leituras do buffer pool) sao os acumulados desde
+// o inicio do servidor; a taxa fica com o servidor do vaultrix, que tem os
+// ciclos anteriores.
+type MySQLStatus struct {
+	Version       string `json:"version"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+
+	ThreadsConnected   int64 `json:"threads_connected"`
+	ThreadsRunning     int64 `json:"threads_running"`
+	MaxConnections     int64 `json:"max_connections"`
+	MaxUsedConnections int64 `json:"max_used_connections"`
+	Connections        int64 `json:"connections_total"`
+	AbortedConnects    int64 `json:"aborted_connects_total"`
+	SlowQueries        int64 `json:"slow_queries_total"`
+	Questions          int64 `json:"questions_total"`
+
+	BufferPool  *MySQLBufferPool  `json:"innodb_buffer_pool,omitempty"`
+	Replication *MySQLReplication `json:"replication,omitempty"`
+}
+
+// MySQLBufferPool e o uso do buffer pool do InnoDB, em paginas.
+type MySQLBufferPool struct {
+	PagesTotal   int64   `json:"pages_total"`
+	PagesData    int64   `json:"pages_data"`
+	PagesFree    int64   `json:"pages_free"`
+	PagesDirty   int64   `json:"pages_dirty"`
+	UsedPercent  float64 `json:"used_percent"`
+	ReadRequests int64   `json:"read_requests_total"`
+	// DiskReads sao as leituras que nao acharam a pagina no pool.
+	DiskReads int64 `json:"disk_reads_total"`
+}
+
+// MySQLReplication so aparece em replicas. LagSeconds fica vazio quando o
+// servidor nao sabe o atraso (thread de SQL parada).
+type MySQLReplication struct {
+	IORunning  bool   `json:"io_running"`
+	SQLRunning bool   `json:"sql_running"`
+	LagSeconds *int64 `json:"lag_seconds,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+func collectMySQL(ctx context.Context, cfg *config.MySQLConfig) (*MySQLStatus, error) {
+	dsn, err := config.ParseMySQLDSN(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialMySQL(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	status, err := mysqlKeyValues(conn, "SHOW GLOBAL STATUS")
+	if err != nil {
+		return nil, err
+	}
+	vars, err := mysqlKeyValues(conn, "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('version', 'max_connections')")
+	if err != nil {
+		return nil, err
+	}
+	s := mysqlStatusFrom(status, vars)
+
+	// SHOW REPLICA STATUS e do MySQL 8.0.22+ e do MariaDB 10.5+; os mais
+	// antigos so conhecem SHOW SLAVE STATUS. Sem o privilegio REPLICATION
+	// CLIENT a replicacao so fica de fora.
+	rows, err := conn.query("SHOW REPLICA STATUS")
+	var serr *mysqlServerError
+	if errors.As(err, &serr) && serr.Code == 1064 {
+		rows, err = conn.query("SHOW SLAVE STATUS")
+	}
+	if err == nil && len(rows) > 0 {
+		s.Replication = mysqlReplicationFrom(rows[0])
+	}
+	return s, nil
+}
+
+// mysqlKeyValues le as consultas SHOW de duas colunas (nome, valor).
+func mysqlKeyValues(conn *mysqlConn, sql string) (map[string]string, error) {
+	rows, err := conn.query(sql)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(rows))
+	for _, row := range rows {
+		values[strings.ToLower(row["Variable_name"])] = row["Value"]
+	}
+	return values, nil
+}
+
+func mysqlStatusFrom(status, vars map[string]string) *MySQLStatus {
+	num := func(m map[string]string, key string) int64 {
+		n, _ := strconv.ParseInt(m[key], 10, 64)
+		return n
+	}
+	s := &MySQLStatus{
+		Version:            vars["version"],
+		UptimeSeconds:      num(status, "uptime"),
+		ThreadsConnected:   num(status, "threads_connected"),
+		ThreadsRunning:     num(status, "threads_running"),
+		MaxConnections:     num(vars, "max_connections"),
+		MaxUsedConnections: num(status, "max_used_connections"),
+		Connections:        num(status, "connections"),
+		AbortedConnects:    num(status, "aborted_connects"),
+		SlowQueries:        num(status, "slow_queries"),
+		Questions:          num(status, "questions"),
+	}
+	// sem InnoDB (MyISAM puro) nao ha buffer pool
+	if total := num(status, "innodb_buffer_pool_pages_total"); total > 0 {
+		free := num(status, "innodb_buffer_pool_pages_free")
+		s.BufferPool = &MySQLBufferPool{
+			PagesTotal:   total,
+			PagesData:    num(status, "innodb_buffer_pool_pages_data"),
+			PagesFree:    free,
+			PagesDirty:   num(status, "innodb_buffer_pool_pages_dirty"),
+			UsedPercent:  math.Round(float64(total-free)/float64(total)*10000) / 100,
+			ReadRequests: num(status, "innodb_buffer_pool_read_requests"),
+			DiskReads:    num(status, "innodb_buffer_pool_reads"),
+		}
+	}
+	return s
+}
+
+// mysqlReplicationFrom aceita os nomes novos (Replica_*, Source_*) e os
+// antigos (Slave_*, Master_*).
+func mysqlReplicationFrom(row map[string]string) *MySQLReplication {
+	pick := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := row[k]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+	r := &MySQLReplication{
+		IORunning:  pick("Replica_IO_Running", "Slave_IO_Running") == "Yes",
+		SQLRunning: pick("Replica_SQL_Running", "Slave_SQL_Running") == "Yes",
+		LastError:  cmp.Or(pick("Last_SQL_Error"), pick("Last_IO_Error")),
+	}
+	if lag, err := strconv.ParseInt(pick("Seconds_Behind_Source", "Seconds_Behind_Master"), 10, 64); err == nil {
+		r.LagSeconds = &lag
+	}
+	return r
+}
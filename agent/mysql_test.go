@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestMySQLReplicationFrom(t *testing.T) {
+	lag := int64(12)
+	tests := []struct {
+		name string
+		row  map[string]string
+		want MySQLReplication
+	}{
+		{"replica names", map[string]string{"Replica_IO_Running": "Yes", "Replica_SQL_Running": "Yes", "Seconds_Behind_Source": "12"},
+			MySQLReplication{IORunning: true, SQLRunning: true, LagSeconds: &lag}},
+		{"slave names", map[string]string{"Slave_IO_Running": "Yes", "Slave_SQL_Running": "Yes", "Seconds_Behind_Master": "12"},
+			MySQLReplication{IORunning: true, SQLRunning: true, LagSeconds: &lag}},
+		{"sql stopped", map[string]string{"Slave_IO_Running": "Yes", "Slave_SQL_Running": "No", "Last_SQL_Error": "Duplicate entry"},
+			MySQLReplication{IORunning: true, LastError: "Duplicate entry"}},
+	}
+	for _, tt := range tests {
+		if got := mysqlReplicationFrom(tt.row); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+// fakeMySQL e um servidor que fala o suficiente do protocolo para o
+// coletor: autentica pelo plugin dado e responde aos SHOWs.
+type fakeMySQL struct {
+	t        *testing.T
+	plugin   string
+	password string
+	// fullAuth forca o caminho sem cache do caching_sha2_password
+	fullAuth bool
+	key      *rsa.PrivateKey
+}
+
+var fakeScramble = []byte("abcdefghijklmnopqrst")
+
+func (f fakeMySQL) start() string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+type fakeMySQLConn struct {
+	rw  io.ReadWriter
+	seq byte
+}
+
+func (c *fakeMySQLConn) read() []byte {
+	var h [4]byte
+	if _, err := io.ReadFull(c.rw, h[:]); err != nil {
+		return nil
+	}
+	c.seq = h[3] + 1
+	b := make([]byte, int(h[0])|int(h[1])<<8|int(h[2])<<16)
+	io.ReadFull(c.rw, b)
+	return b
+}
+
+func (c *fakeMySQLConn) write(b []byte) {
+	c.rw.Write(append([]byte{byte(len(b)), byte(len(b) >> 8), byte(len(b) >> 16), c.seq}, b...))
+	c.seq++
+}
+
+func lenenc(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+func (c *fakeMySQLConn) writeError(code uint16, msg string) {
+	c.write(append(binary.LittleEndian.AppendUint16([]byte{0xff}, code), "#42000"+msg...))
+}
+
+func (c *fakeMySQLConn) writeRows(columns []string, rows [][]string) {
+	c.write([]byte{byte(len(columns))})
+	for _, col := range columns {
+		def := append(lenenc("def"), lenenc("")...)
+		def = append(def, lenenc("")...)
+		def = append(def, lenenc("")...)
+		def = append(def, lenenc(col)...)
+		def = append(def, lenenc(col)...)
+		c.write(def)
+	}
+	c.write([]byte{0xfe, 0, 0, 2, 0})
+	for _, row := range rows {
+		var b []byte
+		for _, v := range row {
+			b = append(b, lenenc(v)...)
+		}
+		c.write(b)
+	}
+	c.write([]byte{0xfe, 0, 0, 2, 0})
+}
+
+func (f fakeMySQL) serve(raw net.Conn) {
+	defer raw.Close()
+	c := &fakeMySQLConn{rw: raw}
+	hs := append([]byte{10}, "8.0.36\x00"...)
+	hs = append(hs, 1, 0, 0, 0)
+	hs = append(hs, fakeScramble[:8]...)
+	hs = append(hs, 0)
+	caps := uint32(mysqlClientProtocol41 | mysqlClientSecureConnection | mysqlClientPluginAuth)
+	hs = binary.LittleEndian.AppendUint16(hs, uint16(caps))
+	hs = append(hs, 45, 2, 0)
+	hs = binary.LittleEndian.AppendUint16(hs, uint16(caps>>16))
+	hs = append(hs, 21)
+	hs = append(hs, make([]byte, 10)...)
+	hs = append(append(hs, fakeScramble[8:]...), 0)
+	hs = append(append(hs, f.plugin...), 0)
+	c.write(hs)
+
+	resp := c.read()
+	r := mysqlReader{buf: resp[32:]}
+	user := r.nulString()
+	auth := r.bytes(int(r.bytes(1)[0]))
+	if user != "monitor" || !f.authenticate(c, auth) {
+		c.writeError(1045, "Access denied for user")
+		return
+	}
+	c.write([]byte{0, 0, 0, 2, 0, 0, 0})
+
+	for {
+		pkt := c.read()
+		if len(pkt) == 0 || pkt[0] == mysqlComQuit {
+			return
+		}
+		c.seq = 1
+		sql := string(pkt[1:])
+		switch {
+		case sql == "SHOW GLOBAL STATUS":
+			c.writeRows([]string{"Variable_name", "Value"}, [][]string{
+				{"Uptime", "3600"}, {"Threads_connected", "12"}, {"Threads_running", "3"},
+				{"Max_used_connections", "40"}, {"Connections", "900"}, {"Aborted_connects", "2"},
+				{"Slow_queries", "7"}, {"Questions", "123456"},
+				{"Innodb_buffer_pool_pages_total", "8192"}, {"Innodb_buffer_pool_pages_free", "2048"},
+				{"Innodb_buffer_pool_pages_data", "6000"}, {"Innodb_buffer_pool_pages_dirty", "10"},
+				{"Innodb_buffer_pool_read_requests", "500000"}, {"Innodb_buffer_pool_reads", "300"},
+			})
+		case strings.HasPrefix(sql, "SHOW GLOBAL VARIABLES"):
+			c.writeRows([]string{"Variable_name", "Value"}, [][]string{{"max_connections", "151"}, {"version", "8.0.36"}})
+		case sql == "SHOW REPLICA STATUS":
+			c.writeError(1064, "You have an error in your SQL syntax")
+		case sql == "SHOW SLAVE STATUS":
+			c.writeRows([]string{"Slave_IO_Running", "Slave_SQL_Running", "Seconds_Behind_Master"}, [][]string{{"Yes", "Yes", "4"}})
+		default:
+			c.writeError(1064, "unexpected query")
+		}
+	}
+}
+
+// authenticate confere a resposta como o servidor faz, a partir do hash
+// guardado, sem usar o codigo do cliente.
+func (f fakeMySQL) authenticate(c *fakeMySQLConn, auth []byte) bool {
+	switch f.plugin {
+	case mysqlNativePassword:
+		h1 := sha1.Sum([]byte(f.password))
+		stored := sha1.Sum(h1[:])
+		mix := sha1.Sum(append(append([]byte(nil), fakeScramble...), stored[:]...))
+		for i := range auth {
+			auth[i] ^= mix[i]
+		}
+		got := sha1.Sum(auth)
+		return len(auth) == 20 && got == stored
+	case mysqlCachingSHA2:
+		if !f.fullAuth {
+			h1 := sha256.Sum256([]byte(f.password))
+			stored := sha256.Sum256(h1[:])
+			mix := sha256.Sum256(append(stored[:], fakeScramble...))
+			for i := range auth {
+				auth[i] ^= mix[i]
+			}
+			got := sha256.Sum256(auth)
+			c.write([]byte{1, 3})
+			return len(auth) == 32 && got == stored
+		}
+		c.write([]byte{1, 4})
+		if req := c.read(); !bytes.Equal(req, []byte{2}) {
+			return false
+		}
+		der, _ := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+		c.write(append([]byte{1}, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...))
+		plain, err := rsa.DecryptOAEP(sha1.New(), nil, f.key, c.read(), nil)
+		if err != nil {
+			return false
+		}
+		for i := range plain {
+			plain[i] ^= fakeScramble[i%len(fakeScramble)]
+		}
+		return string(plain) == f.password+"\x00"
+	}
+	return false
+}
+
+func TestCollectMySQL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lag := int64(4)
+	want := &MySQLStatus{
+		Version: "8.0.36", UptimeSeconds: 3600,
+		ThreadsConnected: 12, ThreadsRunning: 3, MaxConnections: 151, MaxUsedConnections: 40,
+		Connections: 900, AbortedConnects: 2, SlowQueries: 7, Questions: 123456,
+		BufferPool: &MySQLBufferPool{
+			PagesTotal: 8192, PagesData: 6000, PagesFree: 2048, PagesDirty: 10,
+			UsedPercent: 75, ReadRequests: 500000, DiskReads: 300,
+		},
+		Replication: &MySQLReplication{IORunning: true, SQLRunning: true, LagSeconds: &lag},
+	}
+	tests := []struct {
+		name     string
+		server   fakeMySQL
+		password string
+		wantErr  string
+	}{
+		{"native password", fakeMySQL{plugin: mysqlNativePassword, password: "secret"}, "secret", ""},
+		{"caching sha2 fast", fakeMySQL{plugin: mysqlCachingSHA2, password: "secret"}, "secret", ""},
+		{"caching sha2 full", fakeMySQL{plugin: mysqlCachingSHA2, password: "secret", fullAuth: true, key: key}, "secret", ""},
+		{"wrong password", fakeMySQL{plugin: mysqlNativePassword, password: "secret"}, "wrong", "mysql error 1045: Access denied for user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.t = t
+			addr := tt.server.start()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			got, err := collectMySQL(ctx, &config.MySQLConfig{DSN: "monitor:" + tt.password + "@tcp(" + addr + ")/"})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("status = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMySQLUnderApps(t *testing.T) {
+	if got := appsJSON(t, config.CollectorMySQL, (*MySQLStatus)(nil), io.EOF); got != "" {
+		t.Errorf("failed collection left apps %s", got)
+	}
+	got := appsJSON(t, config.CollectorMySQL, &MySQLStatus{Version: "8.0.36"}, nil)
+	if !strings.HasPrefix(got, `{"mysql":{"version":"8.0.36"`) {
+		t.Errorf("apps = %s", got)
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Cliente minimo do protocolo do MySQL, so o que o coletor usa: handshake,
+// autenticacao (mysql_native_password e caching_sha2_password) e consultas
+// de texto. Um driver completo seria uma dependencia a mais para meia duzia
+// de SHOWs; TLS nao e suportado, o que basta para o servidor local (socket
+// unix ou 127.0.0.1).
+
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientTransactions     = 0x00002000
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+
+	mysqlComQuit  = 0x01
+	mysqlComQuery = 0x03
+
+	mysqlNativePassword = "mysql_native_password"
+	mysqlCachingSHA2    = "caching_sha2_password"
+
+	mysqlMaxPacket = 1<<24 - 1
+)
+
+// mysqlServerError e um pacote ERR do servidor.
+type mysqlServerError struct {
+	Code    uint16
+	Message string
+}
+
+func (e *mysqlServerError) Error() string {
+	return fmt.Sprintf("mysql error %d: %s", e.Code, e.Message)
+}
+
+type mysqlConn struct {
+	conn net.Conn
+	seq  byte
+	// unix diz se a conexao e por socket, onde a senha pode ir em claro
+	unix bool
+}
+
+// dialMySQL conecta e autentica. O prazo de ctx vale para a conexao inteira.
+func dialMySQL(ctx context.Context, dsn config.MySQLDSN) (*mysqlConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, dsn.Net, dsn.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c := &mysqlConn{conn: conn, unix: dsn.Net == "unix"}
+	if err := c.handshake(dsn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *mysqlConn) Close() error {
+	c.seq = 0
+	c.writePacket([]byte{mysqlComQuit})
+	return c.conn.Close()
+}
+
+func (c *mysqlConn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+			return nil, err
+		}
+		n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+		c.seq = header[3] + 1
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(c.conn, chunk); err != nil {
+			return nil, err
+		}
+		payload = append(payload, chunk...)
+		// pacotes do tamanho maximo continuam no seguinte
+		if n < mysqlMaxPacket {
+			return payload, nil
+		}
+	}
+}
+
+func (c *mysqlConn) writePacket(payload []byte) error {
+	if len(payload) >= mysqlMaxPacket {
+		return errors.New("mysql: packet too large")
+	}
+	buf := make([]byte, 4, 4+len(payload))
+	buf[0], buf[1], buf[2] = byte(len(payload)), byte(len(payload)>>8), byte(len(payload)>>16)
+	buf[3] = c.seq
+	c.seq++
+	_, err := c.conn.Write(append(buf, payload...))
+	return err
+}
+
+func (c *mysqlConn) handshake(dsn config.MySQLDSN) error {
+	pkt, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(pkt) > 0 && pkt[0] == 0xff {
+		return parseMySQLError(pkt)
+	}
+	if len(pkt) == 0 || pkt[0] != 10 {
+		return errors.New("mysql: unsupported protocol version")
+	}
+	r := mysqlReader{buf: pkt[1:]}
+	r.nulString() // versao do servidor
+	r.skip(4)     // id da conexao
+	scramble := append([]byte(nil), r.bytes(8)...)
+	r.skip(1)
+	caps := uint32(binary.LittleEndian.Uint16(r.bytes(2)))
+	r.skip(3) // charset e status
+	caps |= uint32(binary.LittleEndian.Uint16(r.bytes(2))) << 16
+	r.skip(11) // tamanho dos dados de autenticacao e reservado
+	if caps&mysqlClientSecureConnection != 0 {
+		scramble = append(scramble, bytes.TrimRight(r.bytes(13), "\x00")...)
+	}
+	plugin := mysqlNativePassword
+	if caps&mysqlClientPluginAuth != 0 {
+		if name := r.nulString(); name != "" {
+			plugin = name
+		}
+	}
+	if r.err != nil {
+		return errors.New("mysql: malformed handshake")
+	}
+	if caps&mysqlClientProtocol41 == 0 {
+		return errors.New("mysql: server too old (protocol 4.1 required)")
+	}
+
+	auth, err := mysqlScramble(plugin, dsn.Password, scramble)
+	if err != nil {
+		return err
+	}
+	flags := uint32(mysqlClientLongPassword | mysqlClientProtocol41 | mysqlClientTransactions |
+		mysqlClientSecureConnection | mysqlClientPluginAuth)
+	if dsn.DB != "" {
+		flags |= mysqlClientConnectWithDB
+	}
+	resp := binary.LittleEndian.AppendUint32(nil, flags)
+	resp = binary.LittleEndian.AppendUint32(resp, mysqlMaxPacket)
+	resp = append(resp, 45) // utf8mb4_general_ci
+	resp = append(resp, make([]byte, 23)...)
+	resp = append(append(resp, dsn.User...), 0)
+	resp = append(append(resp, byte(len(auth))), auth...)
+	if dsn.DB != "" {
+		resp = append(append(resp, dsn.DB...), 0)
+	}
+	resp = append(append(resp, plugin...), 0)
+	if err := c.writePacket(resp); err != nil {
+		return err
+	}
+	return c.finishAuth(plugin, dsn.Password, scramble)
+}
+
+// finishAuth trata o que vem depois da resposta ao handshake: OK, erro,
+// troca de plugin ou a continuacao do caching_sha2_password.
+func (c *mysqlConn) finishAuth(plugin, password string, scramble []byte) error {
+	for {
+		pkt, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(pkt) == 0 {
+			return errors.New("mysql: empty auth response")
+		}
+		switch pkt[0] {
+		case 0x00:
+			return nil
+		case 0xff:
+			return parseMySQLError(pkt)
+		case 0xfe:
+			// troca de plugin: novo nome e novo scramble
+			r := mysqlReader{buf: pkt[1:]}
+			plugin = r.nulString()
+			scramble = bytes.TrimRight(r.rest(), "\x00")
+			auth, err := mysqlScramble(plugin, password, scramble)
+			if err != nil {
+				return err
+			}
+			if err := c.writePacket(auth); err != nil {
+				return err
+			}
+		case 0x01:
+			if plugin != mysqlCachingSHA2 || len(pkt) < 2 {
+				return errors.New("mysql: unexpected auth data")
+			}
+			switch pkt[1] {
+			case 3: // senha no cache do servidor; o OK vem em seguida
+			case 4:
+				if err := c.fullSHA2Auth(password, scramble); err != nil {
+					return err
+				}
+			default:
+				return errors.New("mysql: unexpected auth data")
+			}
+		default:
+			return errors.New("mysql: unexpected auth response")
+		}
+	}
+}
+
+// fullSHA2Auth manda a senha quando o servidor nao a tem em cache: em claro
+// pelo socket unix e cifrada com a chave RSA do servidor pela rede.
+func (c *mysqlConn) fullSHA2Auth(password string, scramble []byte) error {
+	plain := append([]byte(password), 0)
+	if len(scramble) == 0 {
+		return errors.New("mysql: missing auth scramble")
+	}
+	if c.unix {
+		return c.writePacket(plain)
+	}
+	if err := c.writePacket([]byte{2}); err != nil {
+		return err
+	}
+	pkt, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(pkt) == 0 || pkt[0] != 0x01 {
+		if len(pkt) > 0 && pkt[0] == 0xff {
+			return parseMySQLError(pkt)
+		}
+		return errors.New("mysql: server did not send its public key")
+	}
+	block, _ := pem.Decode(pkt[1:])
+	if block == nil {
+		return errors.New("mysql: invalid server public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("mysql: invalid server public key: %w", err)
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("mysql: server public key is not RSA")
+	}
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	enc, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, plain, nil)
+	if err != nil {
+		return err
+	}
+	return c.writePacket(enc)
+}
+
+// mysqlScramble calcula a resposta do plugin ao desafio do servidor.
+func mysqlScramble(plugin, password string, scramble []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+	switch plugin {
+	case mysqlNativePassword:
+		// SHA1(senha) XOR SHA1(scramble + SHA1(SHA1(senha)))
+		h1 := sha1.Sum([]byte(password))
+		h2 := sha1.Sum(h1[:])
+		h3 := sha1.Sum(append(append([]byte(nil), scramble...), h2[:]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	case mysqlCachingSHA2:
+		// SHA256(senha) XOR SHA256(SHA256(SHA256(senha)) + scramble)
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	}
+	return nil, fmt.Errorf("mysql: unsupported auth plugin %q", plugin)
+}
+
+// query executa uma consulta e devolve as linhas como mapas coluna ->
+// valor; NULL vira ausencia da chave.
+func (c *mysqlConn) query(sql string) ([]map[string]string, error) {
+	c.seq = 0
+	if err := c.writePacket(append([]byte{mysqlComQuery}, sql...)); err != nil {
+		return nil, err
+	}
+	pkt, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(pkt) == 0:
+		return nil, errors.New("mysql: empty response")
+	case pkt[0] == 0xff:
+		return nil, parseMySQLError(pkt)
+	case pkt[0] == 0x00:
+		return nil, nil
+	}
+	r := mysqlReader{buf: pkt}
+	count := r.lenencInt()
+
+	columns := make([]string, 0, count)
+	for range count {
+		def, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		r := mysqlReader{buf: def}
+		for range 4 { // catalog, schema, table, org_table
+			r.lenencString()
+		}
+		columns = append(columns, r.lenencString())
+		if r.err != nil {
+			return nil, errors.New("mysql: malformed column definition")
+		}
+	}
+	if pkt, err := c.readPacket(); err != nil {
+		return nil, err
+	} else if !isMySQLEOF(pkt) {
+		return nil, errors.New("mysql: expected EOF after columns")
+	}
+
+	var rows []map[string]string
+	for {
+		pkt, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		if isMySQLEOF(pkt) {
+			return rows, nil
+		}
+		if len(pkt) > 0 && pkt[0] == 0xff {
+			return nil, parseMySQLError(pkt)
+		}
+		r := mysqlReader{buf: pkt}
+		row := make(map[string]string, len(columns))
+		for _, col := range columns {
+			if len(r.buf) > 0 && r.buf[0] == 0xfb {
+				r.skip(1)
+				continue
+			}
+			row[col] = r.lenencString()
+		}
+		if r.err != nil {
+			return nil, errors.New("mysql: malformed row")
+		}
+		rows = append(rows, row)
+	}
+}
+
+func isMySQLEOF(pkt []byte) bool {
+	return len(pkt) > 0 && pkt[0] == 0xfe && len(pkt) < 9
+}
+
+func parseMySQLError(pkt []byte) error {
+	if len(pkt) < 3 {
+		return errors.New("mysql: malformed error packet")
+	}
+	msg := pkt[3:]
+	// "#" e o SQLSTATE de 5 caracteres
+	if len(msg) >= 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	return &mysqlServerError{Code: binary.LittleEndian.Uint16(pkt[1:3]), Message: string(msg)}
+}
+
+// mysqlReader le os tipos do protocolo; o primeiro erro fica em err e as
+// leituras seguintes devolvem zero.
+type mysqlReader struct {
+	buf []byte
+	err error
+}
+
+func (r *mysqlReader) bytes(n int) []byte {
+	if r.err != nil || len(r.buf) < n {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *mysqlReader) skip(n int) { r.bytes(n) }
+
+func (r *mysqlReader) rest() []byte {
+	b := r.buf
+	r.buf = nil
+	return b
+}
+
+func (r *mysqlReader) nulString() string {
+	i := bytes.IndexByte(r.buf, 0)
+	if i < 0 {
+		return string(r.rest())
+	}
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
+
+func (r *mysqlReader) lenencInt() uint64 {
+	first := r.bytes(1)[0]
+	switch first {
+	case 0xfc:
+		return uint64(binary.LittleEndian.Uint16(r.bytes(2)))
+	case 0xfd:
+		b := r.bytes(3)
+		return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16
+	case 0xfe:
+		return binary.LittleEndian.Uint64(r.bytes(8))
+	}
+	return uint64(first)
+}
+
+func (r *mysqlReader) lenencString() string {
+	n := r.lenencInt()
+	if n > uint64(len(r.buf)) {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	return string(r.bytes(int(n)))
+}
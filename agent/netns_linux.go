@@ -0,0 +1,91 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+// collectContainerInterfaces descobre qual interface veth do host pertence a
+// cada container. Dentro do namespace de rede do container, o iflink de eth0
+// e o ifindex do par veth no host; o sysfs visto por /proc/<pid>/root e o do
+// proprio container.
+func collectContainerInterfaces(ctx context.Context, ep dockerEndpoint, containers []ContainerStatus) (map[string][]ContainerInterface, error) {
+	// Runtimes que ja informam o pid dispensam o inspect.
+	pids := make(map[string]string)
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		switch {
+		case c.State != "running" || c.ID == "":
+		case c.pid > 0:
+			pids[c.Name] = strconv.Itoa(c.pid)
+		default:
+			ids = append(ids, c.ID)
+		}
+	}
+
+	if len(ids) > 0 {
+		args := append([]string{"inspect", "--format", "{{.Name}}|{{.State.Pid}}"}, ids...)
+		out, err := dockerCommand(ctx, ep, args...).Output()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			name, pid, ok := strings.Cut(strings.TrimSpace(line), "|")
+			if ok && pid != "" && pid != "0" {
+				pids[strings.TrimPrefix(name, "/")] = pid
+			}
+		}
+	}
+	if len(pids) == 0 {
+		return nil, nil
+	}
+
+	hostByIndex := hostInterfacesByIndex()
+	result := make(map[string][]ContainerInterface)
+	for name, pid := range pids {
+
+		netDir := filepath.Join("/proc", pid, "root", "sys", "class", "net")
+		entries, err := os.ReadDir(netDir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.Name() == "lo" {
+				continue
+			}
+			iflink := fsutil.ReadTrimmed(filepath.Join(netDir, e.Name(), "iflink"))
+			ifindex := fsutil.ReadTrimmed(filepath.Join(netDir, e.Name(), "ifindex"))
+			if iflink == "" || iflink == ifindex {
+				// sem par veth (ex.: container com --network host)
+				continue
+			}
+			host, ok := hostByIndex[iflink]
+			if !ok {
+				continue
+			}
+			result[name] = append(result[name], ContainerInterface{Name: e.Name(), HostInterface: host})
+		}
+	}
+	return result, nil
+}
+
+func hostInterfacesByIndex() map[string]string {
+	byIndex := make(map[string]string)
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return byIndex
+	}
+	for _, e := range entries {
+		if idx := fsutil.ReadTrimmed(filepath.Join("/sys/class/net", e.Name(), "ifindex")); idx != "" {
+			byIndex[idx] = e.Name()
+		}
+	}
+	return byIndex
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+)
+
+// collectContainerInterfaces depende de /sys/class/net e so existe no Linux.
+func collectContainerInterfaces(ctx context.Context, ep dockerEndpoint, containers []ContainerStatus) (map[string][]ContainerInterface, error) {
+	return nil, nil
+}
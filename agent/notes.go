@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels vaultrix.* anotam containers para o servidor: dono, tier, runbook
+// e silencio. Funcionam igual em docker, podman, containerd e nos labels de
+// pod do modo --kubernetes.
+const (
+	noteLabelPrefix = "vaultrix."
+
+	// Limites para que labels arbitrarios nao inflem o payload.
+	maxNoteValueLen = 256
+	maxNoteExtras   = 16
+)
+
+// ContainerNotes e o que os labels vaultrix.* dizem de um container.
+type ContainerNotes struct {
+	Owner   string `json:"owner,omitempty"`
+	Tier    string `json:"tier,omitempty"`
+	Runbook string `json:"runbook,omitempty"`
+	// Mute pede que o container nao gere avisos; os alertas locais de
+	// "container X not running" tambem o respeitam.
+	Mute bool `json:"mute,omitempty"`
+	// Extra traz os demais vaultrix.<chave>, sem o prefixo.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// containerNotes extrai as notas dos labels; nil quando nao ha nenhum
+// vaultrix.*.
+func containerNotes(labels map[string]string) *ContainerNotes {
+	var notes ContainerNotes
+	found := false
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name, ok := strings.CutPrefix(k, noteLabelPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		found = true
+		value := labels[k]
+		if len(value) > maxNoteValueLen {
+			value = value[:maxNoteValueLen]
+		}
+		switch name {
+		case "owner":
+			notes.Owner = value
+		case "tier":
+			notes.Tier = value
+		case "runbook":
+			notes.Runbook = value
+		case "mute":
+			notes.Mute, _ = strconv.ParseBool(value)
+		default:
+			if len(notes.Extra) == maxNoteExtras {
+				continue
+			}
+			if notes.Extra == nil {
+				notes.Extra = make(map[string]string)
+			}
+			notes.Extra[name] = value
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &notes
+}
+
+func (c ContainerStatus) muted() bool {
+	return c.Notes != nil && c.Notes.Mute
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+const otlpMetricsPath = "/v1/metrics"
+
+func otlpMetricsURL(o *config.OTLPExporter) string {
+	base := strings.TrimSuffix(o.Endpoint, "/")
+	if strings.HasSuffix(base, otlpMetricsPath) {
+		return base
+	}
+	return base + otlpMetricsPath
+}
+
+// exportOTLP converte o payload e o envia ao coletor.
+func exportOTLP(cfg config.Config, o *config.OTLPExporter, payload Payload) error {
+	body, err := json.Marshal(otlpRequest(cfg, payload))
+	if err != nil {
+		return err
+	}
+	return postSink(cfg, "POST", otlpMetricsURL(o), "application/json", o.Headers, body)
+}
+
+// Tipos do ExportMetricsServiceRequest no mapeamento JSON do OTLP: inteiros
+// de 64 bits e timestamps vao como string.
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Unit  string    `json:"unit,omitempty"`
+	Gauge *otlpData `json:"gauge,omitempty"`
+	Sum   *otlpSum  `json:"sum,omitempty"`
+}
+
+type otlpData struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+// otlpSum e um contador cumulativo (aggregationTemporality 2).
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsDouble     *float64        `json:"asDouble,omitempty"`
+	AsInt        string          `json:"asInt,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string        `json:"key"`
+	Value otlpAttrValue `json:"value"`
+}
+
+type otlpAttrValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpBuilder monta as metricas de um payload. Cada metrica vem de um campo
+// do payload e so e emitida se payload_fields permitir esse campo: o
+// coletor nao recebe o que a API do vaultrix tambem nao receberia.
+type otlpBuilder struct {
+	filter  config.FieldFilter
+	time    string
+	metrics []otlpMetric
+	index   map[string]int
+}
+
+func (b *otlpBuilder) permits(field string) bool {
+	return b.filter.Permits(field)
+}
+
+func (b *otlpBuilder) metric(name, unit string, sum bool) *otlpMetric {
+	if i, ok := b.index[name]; ok {
+		return &b.metrics[i]
+	}
+	m := otlpMetric{Name: name, Unit: unit}
+	if sum {
+		m.Sum = &otlpSum{AggregationTemporality: 2, IsMonotonic: true}
+	} else {
+		m.Gauge = &otlpData{}
+	}
+	b.index[name] = len(b.metrics)
+	b.metrics = append(b.metrics, m)
+	return &b.metrics[len(b.metrics)-1]
+}
+
+func (b *otlpBuilder) add(m *otlpMetric, p otlpDataPoint) {
+	p.TimeUnixNano = b.time
+	if m.Sum != nil {
+		m.Sum.DataPoints = append(m.Sum.DataPoints, p)
+	} else {
+		m.Gauge.DataPoints = append(m.Gauge.DataPoints, p)
+	}
+}
+
+func (b *otlpBuilder) double(field, name, unit string, v float64, attrs ...otlpAttribute) {
+	if b.permits(field) {
+		b.add(b.metric(name, unit, false), otlpDataPoint{Attributes: attrs, AsDouble: &v})
+	}
+}
+
+func (b *otlpBuilder) integer(field, name, unit string, sum bool, v int64, attrs ...otlpAttribute) {
+	if b.permits(field) {
+		b.add(b.metric(name, unit, sum), otlpDataPoint{Attributes: attrs, AsInt: strconv.FormatInt(v, 10)})
+	}
+}
+
+func otlpAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAttrValue{StringValue: value}}
+}
+
+// otlpRequest traduz o payload para as convencoes semanticas do
+// OpenTelemetry (system.*, container.*). Um payload de heartbeat nao tem
+// metricas de host validas e so leva as dos containers.
+func otlpRequest(cfg config.Config, payload Payload) otlpMetricsRequest {
+	b := &otlpBuilder{
+		filter: cfg.PayloadFields,
+		time:   strconv.FormatInt(payload.Timestamp.UnixNano(), 10),
+		index:  make(map[string]int),
+	}
+
+	on := func(collector string) bool { return hostMetricsCollected(cfg, payload, collector) }
+	m := payload.Metrics
+	if on(config.CollectorCPU) {
+		b.double("metrics.cpu", "system.cpu.utilization", "1", m.CPUUsage/100)
+		b.integer("metrics.cpu_cores", "system.cpu.logical.count", "{cpu}", false, int64(m.CPUCores))
+	}
+	if on(config.CollectorMemory) {
+		b.integer("metrics.memory_total_mb", "system.memory.limit", "By", false, m.MemoryTotalMB*mb)
+		b.integer("metrics.memory_used_mb", "system.memory.usage", "By", false, m.MemoryUsedMB*mb, otlpAttr("system.memory.state", "used"))
+		b.integer("metrics.memory_avail_mb", "system.memory.usage", "By", false, m.MemoryAvailMB*mb, otlpAttr("system.memory.state", "free"))
+		b.double("metrics.memory_percent", "system.memory.utilization", "1", m.MemoryPercent/100)
+	}
+	if on(config.CollectorLoad) {
+		b.double("metrics.load_avg_1", "system.cpu.load_average.1m", "{thread}", m.LoadAvg1)
+		b.double("metrics.load_avg_5", "system.cpu.load_average.5m", "{thread}", m.LoadAvg5)
+		b.double("metrics.load_avg_15", "system.cpu.load_average.15m", "{thread}", m.LoadAvg15)
+	}
+	if on(config.CollectorDisk) {
+		root := otlpAttr("system.filesystem.mountpoint", "/")
+		b.integer("metrics.disk_total_gb", "system.filesystem.limit", "By", false, int64(m.DiskTotalGB*gb), root)
+		b.integer("metrics.disk_used_gb", "system.filesystem.usage", "By", false, int64(m.DiskUsedGB*gb), root, otlpAttr("system.filesystem.state", "used"))
+		b.double("metrics.disk_percent", "system.filesystem.utilization", "1", m.DiskPercent/100, root)
+	}
+	for _, d := range m.Disks {
+		// "/" ja vem do coletor disk
+		if d.MountPoint == "/" && on(config.CollectorDisk) {
+			continue
+		}
+		attrs := []otlpAttribute{
+			otlpAttr("system.device", d.Device),
+			otlpAttr("system.filesystem.mountpoint", d.MountPoint),
+			otlpAttr("system.filesystem.type", d.FSType),
+		}
+		b.integer("metrics.disks.total_gb", "system.filesystem.limit", "By", false, int64(d.TotalGB*gb), attrs...)
+		b.integer("metrics.disks.used_gb", "system.filesystem.usage", "By", false, int64(d.UsedGB*gb), append(attrs, otlpAttr("system.filesystem.state", "used"))...)
+		b.double("metrics.disks.percent", "system.filesystem.utilization", "1", d.Percent/100, attrs...)
+	}
+
+	for _, c := range payload.Containers {
+		attrs := []otlpAttribute{otlpAttr("container.name", c.Name)}
+		if c.ID != "" && b.permits("containers.id") {
+			attrs = append(attrs, otlpAttr("container.id", c.ID))
+		}
+		if c.Image != "" && b.permits("containers.image") {
+			attrs = append(attrs, otlpAttr("container.image.name", c.Image))
+		}
+		if c.Namespace != "" && b.permits("containers.namespace") {
+			attrs = append(attrs, otlpAttr("k8s.namespace.name", c.Namespace))
+		}
+		if c.Pod != "" && b.permits("containers.pod") {
+			attrs = append(attrs, otlpAttr("k8s.pod.name", c.Pod))
+		}
+		if !b.permits("containers.name") || !collectorEnabled(cfg, config.CollectorDockerStats) {
+			continue
+		}
+		// cada ponto recebe a sua copia ao acrescentar a direcao
+		attrs = slices.Clip(attrs)
+		b.double("containers.cpuPercent", "container.cpu.utilization", "1", c.CPUPercent/100, attrs...)
+		if c.MemUsageBytes > 0 {
+			b.integer("containers.memUsageBytes", "container.memory.usage", "By", false, c.MemUsageBytes, attrs...)
+		}
+		if c.MemLimitBytes > 0 {
+			b.integer("containers.memLimitBytes", "container.memory.limit", "By", false, c.MemLimitBytes, attrs...)
+		}
+		if c.NetRxBytes > 0 || c.NetTxBytes > 0 {
+			b.integer("containers.netRxBytes", "container.network.io", "By", true, c.NetRxBytes, append(attrs, otlpAttr("network.io.direction", "receive"))...)
+			b.integer("containers.netTxBytes", "container.network.io", "By", true, c.NetTxBytes, append(attrs, otlpAttr("network.io.direction", "transmit"))...)
+		}
+		if c.BlockReadBytes > 0 || c.BlockWriteBytes > 0 {
+			b.integer("containers.blockReadBytes", "container.disk.io", "By", true, c.BlockReadBytes, append(attrs, otlpAttr("disk.io.direction", "read"))...)
+			b.integer("containers.blockWriteBytes", "container.disk.io", "By", true, c.BlockWriteBytes, append(attrs, otlpAttr("disk.io.direction", "write"))...)
+		}
+		if c.PIDs > 0 {
+			b.integer("containers.pids", "container.process.count", "{process}", false, c.PIDs, attrs...)
+		}
+	}
+
+	return otlpMetricsRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: b.resource(payload)},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "vaultrix-agent", Version: payload.AgentVersion},
+			Metrics: b.metrics,
+		}},
+	}}}
+}
+
+// resource identifica o host; os campos seguem o mesmo filtro das metricas.
+func (b *otlpBuilder) resource(payload Payload) []otlpAttribute {
+	attrs := []otlpAttribute{
+		otlpAttr("service.name", "vaultrix-agent"),
+		otlpAttr("service.version", payload.AgentVersion),
+	}
+	if h := payload.Host; h != nil {
+		for _, a := range []struct{ field, key, value string }{
+			{"host.hostname", "host.name", h.Hostname},
+			{"host.machine_id", "host.id", h.MachineID},
+			{"host.arch", "host.arch", h.Arch},
+			{"host.os_name", "os.name", h.OSName},
+			{"host.os_version", "os.version", h.OSVersion},
+		} {
+			if a.value != "" && b.permits(a.field) {
+				attrs = append(attrs, otlpAttr(a.key, a.value))
+			}
+		}
+	}
+	return attrs
+}
+
+// otlpSink e o exportador "otlp" ou um item de "sinks" do tipo otlp.
+type otlpSink struct {
+	cfg      config.Config
+	exporter *config.OTLPExporter
+	name     string
+}
+
+func (s otlpSink) Name() string { return s.name }
+
+func (s otlpSink) Send(payload Payload) error {
+	return exportOTLP(s.cfg, s.exporter, payload)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestOTLPExporterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		otlp    *config.OTLPExporter
+		wantErr bool
+	}{
+		{"unset", nil, false},
+		{"default mode", &config.OTLPExporter{Endpoint: "http://otel:4318"}, false},
+		{"only", &config.OTLPExporter{Endpoint: "https://otel.example.com", Mode: config.OTLPOnly}, false},
+		{"bad mode", &config.OTLPExporter{Endpoint: "http://otel:4318", Mode: "instead"}, true},
+		{"grpc scheme", &config.OTLPExporter{Endpoint: "grpc://otel:4317"}, true},
+		{"no host", &config.OTLPExporter{Endpoint: "http://"}, true},
+		{"bad header", &config.OTLPExporter{Endpoint: "http://otel:4318", Headers: map[string]string{"X Key": "v"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.otlp.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOTLPMetricsURL(t *testing.T) {
+	tests := []struct{ endpoint, want string }{
+		{"http://otel:4318", "http://otel:4318/v1/metrics"},
+		{"http://otel:4318/", "http://otel:4318/v1/metrics"},
+		{"https://otel.example.com/v1/metrics", "https://otel.example.com/v1/metrics"},
+		{"https://gw.example.com/otlp", "https://gw.example.com/otlp/v1/metrics"},
+	}
+	for _, tt := range tests {
+		if got := otlpMetricsURL((&config.OTLPExporter{Endpoint: tt.endpoint})); got != tt.want {
+			t.Errorf("metricsURL(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+// otlpPoints indexa os pontos por "metrica|atributos" para as comparacoes.
+func otlpPoints(req otlpMetricsRequest) map[string]otlpDataPoint {
+	points := make(map[string]otlpDataPoint)
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		data := m.Gauge
+		if m.Sum != nil {
+			data = &otlpData{DataPoints: m.Sum.DataPoints}
+		}
+		for _, p := range data.DataPoints {
+			var attrs []string
+			for _, a := range p.Attributes {
+				attrs = append(attrs, a.Key+"="+a.Value.StringValue)
+			}
+			points[m.Name+"|"+strings.Join(attrs, ",")] = p
+		}
+	}
+	return points
+}
+
+func TestOTLPRequest(t *testing.T) {
+	payload := Payload{
+		AgentVersion: "1.2.3",
+		Timestamp:    time.Unix(1700000000, 0),
+		Host:         &HostInfo{Hostname: "web01", MachineID: "abc", Arch: "amd64"},
+		Metrics:      Metrics{CPUUsage: 50, MemoryUsedMB: 2, LoadAvg1: 1.5},
+		Containers: []ContainerStatus{
+			{ID: "c1", Name: "api", Image: "api:1", CPUPercent: 10, NetRxBytes: 100, NetTxBytes: 200},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		heart   bool
+		want    []string
+		missing []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				"system.cpu.utilization|",
+				"system.memory.usage|system.memory.state=used",
+				"system.cpu.load_average.1m|",
+				"container.cpu.utilization|container.name=api,container.id=c1,container.image.name=api:1",
+				"container.network.io|container.name=api,container.id=c1,container.image.name=api:1,network.io.direction=receive",
+				"container.network.io|container.name=api,container.id=c1,container.image.name=api:1,network.io.direction=transmit",
+			},
+		},
+		{
+			name:    "collector off",
+			cfg:     config.Config{Collectors: map[string]bool{config.CollectorCPU: false}},
+			want:    []string{"system.memory.usage|system.memory.state=used"},
+			missing: []string{"system.cpu.utilization|"},
+		},
+		{
+			name:    "heartbeat",
+			heart:   true,
+			want:    []string{"container.cpu.utilization|container.name=api,container.id=c1,container.image.name=api:1"},
+			missing: []string{"system.cpu.utilization|", "system.memory.usage|system.memory.state=used"},
+		},
+		{
+			name:    "field filter",
+			cfg:     config.Config{PayloadFields: config.FieldFilter{Deny: []string{"metrics.load_avg_1", "containers.image"}}},
+			want:    []string{"system.cpu.utilization|", "container.cpu.utilization|container.name=api,container.id=c1"},
+			missing: []string{"system.cpu.load_average.1m|"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := payload
+			p.Heartbeat = tt.heart
+			points := otlpPoints(otlpRequest(tt.cfg, p))
+			for _, key := range tt.want {
+				if _, ok := points[key]; !ok {
+					t.Errorf("missing point %q", key)
+				}
+			}
+			for _, key := range tt.missing {
+				if _, ok := points[key]; ok {
+					t.Errorf("unexpected point %q", key)
+				}
+			}
+		})
+	}
+
+	points := otlpPoints(otlpRequest(config.Config{}, payload))
+	if p := points["system.cpu.utilization|"]; p.AsDouble == nil || *p.AsDouble != 0.5 || p.TimeUnixNano != "1700000000000000000" {
+		t.Errorf("cpu point = %+v", p)
+	}
+	if p := points["system.memory.usage|system.memory.state=used"]; p.AsInt != "2097152" {
+		t.Errorf("memory used = %q, want 2097152", p.AsInt)
+	}
+}
+
+func TestSendPayloadOTLPOnly(t *testing.T) {
+	var got otlpMetricsRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpMetricsPath {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{OTLP: &config.OTLPExporter{Endpoint: srv.URL, Mode: config.OTLPOnly, Headers: map[string]string{"Authorization": "Bearer x"}}}
+	if err := checkConfig(cfg); err != nil {
+		t.Fatalf("checkConfig: %v", err)
+	}
+	if err := sendPayload(cfg, Payload{AgentVersion: "1.2.3", Metrics: Metrics{CPUUsage: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer x" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if len(got.ResourceMetrics) != 1 || got.ResourceMetrics[0].ScopeMetrics[0].Scope.Version != "1.2.3" {
+		t.Errorf("request = %+v", got)
+	}
+}
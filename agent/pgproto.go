@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Cliente minimo do protocolo do PostgreSQL (v3), como o do MySQL em
+// mysqlproto.go: inicio de sessao, autenticacao (trust, senha, md5 e
+// SCRAM-SHA-256) e consultas simples, com os valores em texto. Sem TLS:
+// o alvo e o servidor local.
+
+const pgProtocolVersion = 3 << 16
+
+// pgServerError e um ErrorResponse do servidor.
+type pgServerError struct {
+	Code    string
+	Message string
+}
+
+func (e *pgServerError) Error() string {
+	return fmt.Sprintf("postgres error %s: %s", e.Code, e.Message)
+}
+
+type pgConn struct {
+	conn net.Conn
+}
+
+func dialPostgres(ctx context.Context, dsn config.PostgresDSN) (*pgConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, dsn.Network(), dsn.Address())
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c := &pgConn{conn: conn}
+	if err := c.startup(dsn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *pgConn) Close() error {
+	c.send('X', nil)
+	return c.conn.Close()
+}
+
+func (c *pgConn) send(kind byte, body []byte) error {
+	msg := append([]byte{kind}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...)
+	_, err := c.conn.Write(append(msg, body...))
+	return err
+}
+
+func (c *pgConn) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint32(header[1:]))
+	if n < 4 || n > 64<<20 {
+		return 0, nil, errors.New("postgres: invalid message length")
+	}
+	body := make([]byte, n-4)
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+func (c *pgConn) startup(dsn config.PostgresDSN) error {
+	body := binary.BigEndian.AppendUint32(nil, pgProtocolVersion)
+	for _, kv := range [][2]string{{"user", dsn.User}, {"database", dsn.DB}, {"application_name", "vaultrix-agent"}} {
+		body = append(append(append(append(body, kv[0]...), 0), kv[1]...), 0)
+	}
+	body = append(body, 0)
+	// a mensagem de inicio nao tem o byte de tipo
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	if _, err := c.conn.Write(append(msg, body...)); err != nil {
+		return err
+	}
+
+	var scram *scramSHA256
+	for {
+		kind, body, err := c.receive()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case 'E':
+			return parsePGError(body)
+		case 'Z':
+			return nil
+		case 'R':
+			if len(body) < 4 {
+				return errors.New("postgres: malformed auth request")
+			}
+			code, data := binary.BigEndian.Uint32(body), body[4:]
+			if err := c.authenticate(code, data, dsn, &scram); err != nil {
+				return err
+			}
+		}
+		// ParameterStatus, BackendKeyData e avisos nao interessam
+	}
+}
+
+func (c *pgConn) authenticate(code uint32, data []byte, dsn config.PostgresDSN, scram **scramSHA256) error {
+	switch code {
+	case 0: // AuthenticationOk
+		return nil
+	case 3: // senha em claro
+		return c.send('p', append([]byte(dsn.Password), 0))
+	case 5: // md5
+		if len(data) < 4 {
+			return errors.New("postgres: malformed md5 salt")
+		}
+		return c.send('p', append([]byte(pgMD5Password(dsn.User, dsn.Password, data[:4])), 0))
+	case 10: // SASL: lista de mecanismos
+		mechanisms := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+		if !slices.Contains(mechanisms, "SCRAM-SHA-256") {
+			return fmt.Errorf("postgres: unsupported SASL mechanisms %v", mechanisms)
+		}
+		s, err := newSCRAMSHA256("", dsn.Password)
+		if err != nil {
+			return err
+		}
+		*scram = s
+		first := s.clientFirst()
+		body := append([]byte("SCRAM-SHA-256\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(first)))...)
+		return c.send('p', append(body, first...))
+	case 11: // SASLContinue
+		if *scram == nil {
+			return errors.New("postgres: unexpected SASL continue")
+		}
+		final, err := (*scram).clientFinal(string(data))
+		if err != nil {
+			return err
+		}
+		return c.send('p', []byte(final))
+	case 12: // SASLFinal
+		if *scram == nil || !(*scram).verifyServer(string(data)) {
+			return errors.New("postgres: invalid SCRAM server signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("postgres: unsupported authentication method %d", code)
+}
+
+// pgMD5Password e "md5" + md5(md5(senha + usuario) + salt), em hex.
+func pgMD5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramSHA256 e uma troca SCRAM-SHA-256 (RFC 7677) sem channel binding,
+// usada pelo PostgreSQL e pelo MongoDB.
+type scramSHA256 struct {
+	user        string
+	password    string
+	clientNonce string
+	firstBare   string
+	authMessage string
+	salted      []byte
+}
+
+func newSCRAMSHA256(user, password string) (*scramSHA256, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &scramSHA256{user: user, password: password, clientNonce: base64.StdEncoding.EncodeToString(nonce)}, nil
+}
+
+// clientFirst vai sem usuario no PostgreSQL, que usa o da mensagem de
+// inicio.
+func (s *scramSHA256) clientFirst() string {
+	user := strings.NewReplacer("=", "=3D", ",", "=2C").Replace(s.user)
+	s.firstBare = "n=" + user + ",r=" + s.clientNonce
+	return "n,," + s.firstBare
+}
+
+func (s *scramSHA256) clientFinal(serverFirst string) (string, error) {
+	var nonce, salt string
+	iterations := 0
+	for _, attr := range strings.Split(serverFirst, ",") {
+		key, value, _ := strings.Cut(attr, "=")
+		switch key {
+		case "r":
+			nonce = value
+		case "s":
+			salt = value
+		case "i":
+			iterations, _ = strconv.Atoi(value)
+		}
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil || !strings.HasPrefix(nonce, s.clientNonce) || iterations < 1 {
+		return "", errors.New("scram: invalid server message")
+	}
+
+	s.salted = pbkdf2SHA256([]byte(s.password), saltBytes, iterations)
+	clientKey := hmacSHA256(s.salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + nonce
+	s.authMessage = s.firstBare + "," + serverFirst + "," + withoutProof
+	proof := hmacSHA256(storedKey[:], s.authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (s *scramSHA256) verifyServer(serverFinal string) bool {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(serverFinal, "v="))
+	if err != nil || s.salted == nil {
+		return false
+	}
+	want := hmacSHA256(hmacSHA256(s.salted, "Server Key"), s.authMessage)
+	return hmac.Equal(sig, want)
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// pbkdf2SHA256 deriva uma chave de 32 bytes, um bloco so: o que o SCRAM usa.
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	out := slices.Clone(u)
+	for range iterations - 1 {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for i := range out {
+			out[i] ^= u[i]
+		}
+	}
+	return out
+}
+
+// query executa uma consulta simples e devolve as linhas como mapas
+// coluna -> valor; NULL vira ausencia da chave.
+func (c *pgConn) query(sql string) ([]map[string]string, error) {
+	if err := c.send('Q', append([]byte(sql), 0)); err != nil {
+		return nil, err
+	}
+	var columns []string
+	var rows []map[string]string
+	var qerr error
+	for {
+		kind, body, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case 'T':
+			columns, err = parsePGRowDescription(body)
+			if err != nil {
+				return nil, err
+			}
+		case 'D':
+			row, err := parsePGDataRow(body, columns)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		case 'E':
+			// o servidor ainda manda ReadyForQuery depois do erro
+			qerr = parsePGError(body)
+		case 'Z':
+			return rows, qerr
+		}
+	}
+}
+
+func parsePGRowDescription(body []byte) ([]string, error) {
+	if len(body) < 2 {
+		return nil, errors.New("postgres: malformed row description")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	body = body[2:]
+	columns := make([]string, 0, n)
+	for range n {
+		i := bytes.IndexByte(body, 0)
+		// nome, depois 18 bytes de tabela, coluna, tipo, tamanho e formato
+		if i < 0 || len(body) < i+1+18 {
+			return nil, errors.New("postgres: malformed row description")
+		}
+		columns = append(columns, string(body[:i]))
+		body = body[i+1+18:]
+	}
+	return columns, nil
+}
+
+func parsePGDataRow(body []byte, columns []string) (map[string]string, error) {
+	if len(body) < 2 || int(binary.BigEndian.Uint16(body)) != len(columns) {
+		return nil, errors.New("postgres: malformed data row")
+	}
+	body = body[2:]
+	row := make(map[string]string, len(columns))
+	for _, col := range columns {
+		if len(body) < 4 {
+			return nil, errors.New("postgres: malformed data row")
+		}
+		n := int32(binary.BigEndian.Uint32(body))
+		body = body[4:]
+		if n < 0 {
+			continue
+		}
+		if int(n) > len(body) {
+			return nil, errors.New("postgres: malformed data row")
+		}
+		row[col] = string(body[:n])
+		body = body[n:]
+	}
+	return row, nil
+}
+
+func parsePGError(body []byte) error {
+	e := &pgServerError{}
+	for len(body) > 1 {
+		i := bytes.IndexByte(body[1:], 0)
+		if i < 0 {
+			break
+		}
+		value := string(body[1 : 1+i])
+		switch body[0] {
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		}
+		body = body[i+2:]
+	}
+	return e
+}
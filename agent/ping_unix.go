@@ -0,0 +1,46 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+var (
+	pingLossRe = regexp.MustCompile(`([\d.]+)% packet loss`)
+	// linux: "rtt min/avg/max/mdev = ..."; BSD e busybox: "round-trip ..."
+	pingRTTRe = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+)
+
+// pingCommand limita o tempo total do ping ao prazo da check, para que ele
+// imprima o resumo em vez de ser morto pelo contexto.
+func pingCommand(ctx context.Context, host string, count int, timeout time.Duration) *exec.Cmd {
+	deadline := strconv.Itoa(max(1, int(timeout.Seconds())-1))
+	flag := "-w"
+	if runtime.GOOS == "darwin" || runtime.GOOS == "freebsd" {
+		flag = "-t"
+	}
+	return exec.CommandContext(ctx, "ping", "-n", "-c", strconv.Itoa(count), flag, deadline, host)
+}
+
+// parsePingOutput extrai a perda em porcentagem e o RTT medio em ms. Sem
+// nenhuma resposta nao ha linha de RTT.
+func parsePingOutput(out string) (loss, avg float64, ok bool) {
+	m := pingLossRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, 0, false
+	}
+	loss, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if m := pingRTTRe.FindStringSubmatch(out); m != nil {
+		avg, _ = strconv.ParseFloat(m[1], 64)
+	}
+	return loss, avg, true
+}
@@ -0,0 +1,73 @@
+//go:build !windows
+
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestParsePingOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		out    string
+		loss   float64
+		avg    float64
+		wantOK bool
+	}{
+		{"linux", `PING 10.0.0.1 (10.0.0.1) 56(84) bytes of data.
+
+--- 10.0.0.1 ping statistics ---
+3 packets transmitted, 3 received, 0% packet loss, time 2003ms
+rtt min/avg/max/mdev = 0.412/0.538/0.711/0.127 ms
+`, 0, 0.538, true},
+		{"busybox", `--- 10.0.0.1 ping statistics ---
+4 packets transmitted, 3 packets received, 25% packet loss
+round-trip min/avg/max = 1.201/12.400/30.002 ms
+`, 25, 12.4, true},
+		{"no reply", `--- 10.0.0.9 ping statistics ---
+3 packets transmitted, 0 received, 100% packet loss, time 2030ms
+`, 100, 0, true},
+		{"unknown host", "ping: db.invalid: Name or service not known\n", 0, 0, false},
+	}
+	for _, tt := range tests {
+		loss, avg, ok := parsePingOutput(tt.out)
+		if ok != tt.wantOK || loss != tt.loss || avg != tt.avg {
+			t.Errorf("%s: got %g, %g, %v; want %g, %g, %v", tt.name, loss, avg, ok, tt.loss, tt.avg, tt.wantOK)
+		}
+	}
+}
+
+func TestRunPingCheck(t *testing.T) {
+	// -w 9: o prazo padrao de 10s menos um segundo para o resumo
+	fakeCommand(t, "ping", map[string]string{
+		"-n -c 3 -w 9 10.0.0.1": "3 packets transmitted, 2 received, 33.3333% packet loss, time 2003ms\nrtt min/avg/max/mdev = 1.000/2.600/4.000/1.000 ms\n",
+		"-n -c 3 -w 9 10.0.0.9": "3 packets transmitted, 0 received, 100% packet loss, time 2030ms\n",
+	})
+	if runtime.GOOS == "darwin" || runtime.GOOS == "freebsd" {
+		t.Skip("fake ping answers the linux flags")
+	}
+
+	limit := 10.0
+	tests := []struct {
+		name    string
+		check   config.Check
+		wantUp  bool
+		wantErr string
+	}{
+		{"partial loss", config.Check{Host: "10.0.0.1"}, true, ""},
+		{"loss limit", config.Check{Host: "10.0.0.1", MaxPacketLoss: &limit}, false, "packet loss 33% exceeds 10%"},
+		{"latency limit", config.Check{Host: "10.0.0.1", MaxLatencyMS: 2}, false, "latency 3ms exceeds 2ms"},
+		{"no reply", config.Check{Host: "10.0.0.9"}, false, "no reply"},
+		{"unknown host", config.Check{Host: "db.invalid"}, false, "unexpected arguments: -n -c 3 -w 9 db.invalid"},
+	}
+	for _, tt := range tests {
+		tt.check.Name, tt.check.Type = tt.name, "ping"
+		res := runCheck(tt.check, "")
+		if res.Up != tt.wantUp || res.Error != tt.wantErr {
+			t.Errorf("%s: got up=%v err=%q, want up=%v err=%q", tt.name, res.Up, res.Error, tt.wantUp, tt.wantErr)
+		}
+	}
+}
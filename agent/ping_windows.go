@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// "(25% loss)" e "Average = 12ms", ou as versoes traduzidas do Windows
+	pingLossRe = regexp.MustCompile(`\((\d+)%`)
+	pingAvgRe  = regexp.MustCompile(`= (\d+) ?ms\s*$`)
+)
+
+// pingCommand divide o prazo da check entre os pacotes, ja que o ping do
+// Windows so aceita espera por resposta.
+func pingCommand(ctx context.Context, host string, count int, timeout time.Duration) *exec.Cmd {
+	wait := max(500, int(timeout.Milliseconds())/count-100)
+	return exec.CommandContext(ctx, "ping", "-n", strconv.Itoa(count), "-w", strconv.Itoa(wait), host)
+}
+
+func parsePingOutput(out string) (loss, avg float64, ok bool) {
+	m := pingLossRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, 0, false
+	}
+	loss, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if m := pingAvgRe.FindStringSubmatch(line); m != nil {
+			avg, _ = strconv.ParseFloat(m[1], 64)
+		}
+	}
+	return loss, avg, true
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// O envio e um pipeline de tres etapas, uma funcao cada:
+//
+//	collectPayload   coletores em paralelo, cada um com o seu prazo
+//	transformPayload o que depende do estado entre ciclos: eventos,
+//	                 estabilidade das checks e alertas
+//	deliverPayload   fan-out para os sinks
+//
+// Na ultima etapa cada sink e independente: tem o seu prazo, as suas
+// tentativas, o seu spool e a sua saude. Um destino lento ou fora do ar nao
+// atrasa os demais alem do proprio prazo, e um sink secundario que falha
+// seguidamente e pausado, indo direto para o spool, em vez de custar um
+// timeout a cada ciclo. O filtro de payload_fields e aplicado por cada sink
+// ao serializar.
+func runCycle(cfg config.Config, stateDir string) error {
+	payload := collectPayload(cfg)
+	transformPayload(cfg, stateDir, &payload)
+	return deliverPayload(cfg, stateDir, payload)
+}
+
+// transformPayload completa o retrato do ciclo com o que so o historico
+// local sabe.
+func transformPayload(cfg config.Config, stateDir string, payload *Payload) {
+	events := streamedContainerEvents(cfg, trackContainers(stateDir, payload.allContainers, payload.Timestamp))
+	events = append(events, trackChecks(cfg, stateDir, payload.Checks, payload.Timestamp)...)
+	var alertEvents []PayloadEvent
+	payload.Alerts, alertEvents = evaluateAlerts(cfg, stateDir, *payload)
+	payload.Events = append(events, alertEvents...)
+	sortEvents(payload.Events)
+}
+
+const (
+	defaultSinkRetryBackoffSec = 2
+)
+
+// sinkRetryUnit e a unidade de retry_backoff_seconds; os testes a encurtam.
+var sinkRetryUnit = time.Second
+
+// sendWithPolicy entrega com as tentativas e o prazo da politica. Estourado o prazo,
+// o envio em curso e abandonado, como um coletor travado; se ele ainda
+// chegar, o payload do spool chega de novo (os eventos tem ID para isso).
+func sendWithPolicy(p config.SinkPolicy, sink Sink, payload Payload) error {
+	attempt := func(ctx context.Context) (struct{}, error) {
+		backoff := time.Duration(cmp.Or(p.RetryBackoffSeconds, defaultSinkRetryBackoffSec)) * sinkRetryUnit
+		for i := 0; ; i++ {
+			err := sink.Send(payload)
+			if err == nil || !spoolable(err) || i == p.Retries {
+				return struct{}{}, err
+			}
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return struct{}{}, ctx.Err()
+			}
+			backoff *= 2
+		}
+	}
+	if p.TimeoutSeconds == 0 {
+		_, err := attempt(context.Background())
+		return err
+	}
+	_, err := collectors.Run(time.Duration(p.TimeoutSeconds)*time.Second, attempt)
+	return err
+}
+
+// deliverPayload entrega o payload a todos os sinks e devolve o erro do
+// principal. Sem stateDir (o primeiro envio da instalacao) nada vai para o
+// spool e a saude dos sinks nao e registrada.
+func deliverPayload(cfg config.Config, stateDir string, payload Payload) error {
+	targets := payloadSinks(cfg)
+	var health map[string]*SinkHealth
+	if stateDir != "" {
+		health = loadSinkHealth(stateDir)
+	}
+	now := time.Now().UTC()
+
+	errs := make([]error, len(targets))
+	states := make([]*SinkHealth, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		states[i] = health[t.Name()]
+		if states[i] == nil {
+			states[i] = &SinkHealth{}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// so os secundarios pausam: o erro do principal e o do ciclo
+			if i > 0 && states[i].paused(now) {
+				errs[i] = fmt.Errorf("%w until %s", errSinkPaused, states[i].PausedUntil.Format(time.RFC3339))
+			} else {
+				errs[i] = sendWithPolicy(t.policy, t, payload)
+				states[i].record(errs[i], time.Now().UTC())
+			}
+			spoolResult(cfg, stateDir, t, payload, errs[i])
+		}()
+	}
+	wg.Wait()
+
+	if stateDir != "" {
+		current := make(map[string]*SinkHealth, len(targets))
+		for i, t := range targets {
+			current[t.Name()] = states[i]
+		}
+		if err := saveSinkHealth(stateDir, current); err != nil {
+			fmt.Fprintf(os.Stderr, "sinks: %v\n", err)
+		}
+	}
+	for i := 1; i < len(targets); i++ {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "sink %s: %v\n", targets[i].Name(), errs[i])
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
+
+// spoolResult aplica o spool do sink ao resultado do envio: drena o
+// backlog depois de um sucesso e guarda o payload numa falha que passa
+// sozinha.
+func spoolResult(cfg config.Config, stateDir string, t sinkTarget, payload Payload, err error) {
+	if !t.spool || stateDir == "" {
+		return
+	}
+	dir := sinkStateDir(stateDir, t.Name())
+	switch {
+	case err == nil:
+		flushSpool(cfg, dir, t)
+	case spoolable(err):
+		if serr := spoolPayload(cfg, dir, payload); serr != nil {
+			fmt.Fprintf(os.Stderr, "spool: %s: %v\n", t.Name(), serr)
+		}
+	}
+}
+
+var errSinkPaused = errors.New("paused after repeated failures")
+
+const (
+	sinkHealthFile = "sinks.json"
+
+	// sinkPauseAfter falhas seguidas pausam um sink secundario por um
+	// minuto, dobrando a cada nova falha ate sinkMaxPause.
+	sinkPauseAfter = 3
+	sinkMaxPause   = 30 * time.Minute
+)
+
+// SinkHealth e a saude de um sink entre ciclos, exibida pelo --status.
+type SinkHealth struct {
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	// Failures conta as falhas seguidas; um sucesso zera.
+	Failures    int       `json:"failures,omitempty"`
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+}
+
+func (h *SinkHealth) record(err error, now time.Time) {
+	if err == nil {
+		*h = SinkHealth{LastSuccess: now}
+		return
+	}
+	h.Failures++
+	h.LastFailure, h.LastError = now, err.Error()
+	if h.Failures >= sinkPauseAfter {
+		pause := time.Minute << min(h.Failures-sinkPauseAfter, 5)
+		h.PausedUntil = now.Add(min(pause, sinkMaxPause))
+	}
+}
+
+func (h SinkHealth) paused(now time.Time) bool {
+	return now.Before(h.PausedUntil)
+}
+
+func sinkHealthPath(stateDir string) string {
+	return filepath.Join(stateDir, sinkHealthFile)
+}
+
+func loadSinkHealth(stateDir string) map[string]*SinkHealth {
+	health := map[string]*SinkHealth{}
+	if b, err := os.ReadFile(sinkHealthPath(stateDir)); err == nil {
+		json.Unmarshal(b, &health)
+	}
+	return health
+}
+
+// saveSinkHealth grava a saude dos sinks do config atual; os que sairam do
+// config saem do arquivo.
+func saveSinkHealth(stateDir string, health map[string]*SinkHealth) error {
+	if err := fsutil.EnsureDir(stateDir); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(health, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(sinkHealthPath(stateDir), b, 0o600)
+}
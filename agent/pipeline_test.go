@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestSinkHealthRecord(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("connection refused")
+	tests := []struct {
+		name      string
+		failures  int
+		wantPause time.Duration
+	}{
+		{"one failure", 1, 0},
+		{"below threshold", sinkPauseAfter - 1, 0},
+		{"threshold", sinkPauseAfter, time.Minute},
+		{"doubles", sinkPauseAfter + 2, 4 * time.Minute},
+		{"capped", sinkPauseAfter + 20, sinkMaxPause},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h SinkHealth
+			for i := 0; i < tt.failures; i++ {
+				h.record(failure, now)
+			}
+			if got := h.PausedUntil.Sub(now); tt.wantPause == 0 && !h.PausedUntil.IsZero() || tt.wantPause != 0 && got != tt.wantPause {
+				t.Errorf("pause = %s, want %s", got, tt.wantPause)
+			}
+			if h.Failures != tt.failures || h.LastError != failure.Error() {
+				t.Errorf("health = %+v", h)
+			}
+			h.record(nil, now.Add(time.Hour))
+			if h.Failures != 0 || h.paused(now) || !h.LastSuccess.Equal(now.Add(time.Hour)) {
+				t.Errorf("after success: %+v", h)
+			}
+		})
+	}
+}
+
+// countingSink falha com err nas primeiras fail chamadas.
+type countingSink struct {
+	calls *atomic.Int32
+	fail  int32
+	err   error
+	delay time.Duration
+}
+
+func (s countingSink) Name() string { return "test" }
+
+func (s countingSink) Send(Payload) error {
+	n := s.calls.Add(1)
+	time.Sleep(s.delay)
+	if n <= s.fail {
+		return s.err
+	}
+	return nil
+}
+
+func TestSinkPolicySend(t *testing.T) {
+	unavailable := &apiError{StatusCode: http.StatusServiceUnavailable}
+	tests := []struct {
+		name      string
+		policy    config.SinkPolicy
+		sink      countingSink
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"no retries", config.SinkPolicy{}, countingSink{fail: 1, err: unavailable}, 1, true},
+		{"retried until success", config.SinkPolicy{Retries: 2}, countingSink{fail: 2, err: unavailable}, 3, false},
+		{"retries exhausted", config.SinkPolicy{Retries: 1}, countingSink{fail: 5, err: unavailable}, 2, true},
+		{"rejected payload is not retried", config.SinkPolicy{Retries: 3}, countingSink{fail: 5, err: &apiError{StatusCode: http.StatusBadRequest}}, 1, true},
+		{"timeout", config.SinkPolicy{TimeoutSeconds: 1}, countingSink{delay: 3 * time.Second}, 1, true},
+	}
+	saved := sinkRetryUnit
+	sinkRetryUnit = time.Millisecond
+	defer func() { sinkRetryUnit = saved }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.sink.calls = new(atomic.Int32)
+			err := sendWithPolicy(tt.policy, tt.sink, Payload{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := tt.sink.calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDeliverPayloadPausesSecondarySink(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+	var influxCalls atomic.Int32
+	influx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		influxCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer influx.Close()
+
+	stateDir := t.TempDir()
+	cfg := config.Config{
+		Token:  "0123456789abcdef",
+		ApiURL: api.URL,
+		Sinks:  []config.SinkConfig{{Type: sinkInflux, URL: influx.URL, Spool: true}},
+	}
+	for i := 0; i < sinkPauseAfter+1; i++ {
+		payload := Payload{Timestamp: time.Unix(int64(60*i), 0), Metrics: Metrics{CPUUsage: 1}}
+		if err := deliverPayload(cfg, stateDir, payload); err != nil {
+			t.Fatalf("cycle %d: %v", i, err)
+		}
+	}
+	if got := influxCalls.Load(); got != sinkPauseAfter {
+		t.Errorf("influx calls = %d, want %d (paused afterwards)", got, sinkPauseAfter)
+	}
+	if got := countSpool(sinkStateDir(stateDir, sinkInflux)); got != sinkPauseAfter+1 {
+		t.Errorf("influx spool = %d, want every cycle's payload", got)
+	}
+	health := loadSinkHealth(stateDir)
+	if h := health[sinkInflux]; h == nil || !h.paused(time.Now()) || !strings.Contains(h.LastError, "503") {
+		t.Errorf("influx health = %+v", h)
+	}
+	if h := health[sinkAPI]; h == nil || h.Failures != 0 || h.LastSuccess.IsZero() {
+		t.Errorf("api health = %+v", h)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const defaultConfigPath = "/etc/vaultrix-agent/config.json"
+const defaultStateDir = "/var/lib/vaultrix-agent"
+const defaultPluginsDir = "/etc/vaultrix-agent/plugins"
+const agentBinaryPath = "/usr/local/bin/vaultrix-agent"
+const cronPath = "/etc/cron.d/vaultrix-agent"
+
+// runAsService so tem efeito no Windows, onde o agente roda como servico.
+func runAsService() bool {
+	return false
+}
+
+func installScheduler(cfg config.Config, target, configPath string) error {
+	cron := fmt.Sprintf("*/%d * * * * root %s --once --config %s\n", cfg.Interval, target, configPath)
+	return os.WriteFile(cronPath, []byte(cron), 0o644)
+}
+
+func removeScheduler() error {
+	if err := os.Remove(cronPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func installedScheduler() (string, bool) {
+	if fsutil.FileExists(cronPath) {
+		return "cron", true
+	}
+	return "", false
+}
@@ -0,0 +1,151 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+	"vaultrix-agent/internal/config"
+)
+
+const defaultConfigPath = `C:\ProgramData\vaultrix-agent\config.json`
+const defaultStateDir = `C:\ProgramData\vaultrix-agent\state`
+const defaultPluginsDir = `C:\ProgramData\vaultrix-agent\plugins`
+const agentBinaryPath = `C:\Program Files\vaultrix-agent\vaultrix-agent.exe`
+const serviceName = "vaultrix-agent"
+
+// runAsService assume o controle quando o processo foi iniciado pelo Service
+// Control Manager. No Windows nao ha cron: o servico roda o agente em modo
+// daemon.
+func runAsService() bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false
+	}
+
+	configPath := defaultConfigPath
+	stateDir := defaultStateDir
+	for i := 1; i < len(os.Args)-1; i++ {
+		switch os.Args[i] {
+		case "--config", "-config":
+			configPath = os.Args[i+1]
+		case "--state-dir", "-state-dir":
+			stateDir = os.Args[i+1]
+		}
+	}
+
+	if err := svc.Run(serviceName, &agentService{configPath: configPath, stateDir: stateDir}); err != nil {
+		fatal(err)
+	}
+	return true
+}
+
+type agentService struct {
+	configPath string
+	stateDir   string
+}
+
+func (s *agentService) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	cfg, err := loadConfig(s.configPath)
+	if err != nil {
+		return true, 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runDaemon(ctx, cfg, s.configPath, s.stateDir)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		req := <-requests
+		switch req.Cmd {
+		case svc.Interrogate:
+			changes <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			changes <- svc.Status{State: svc.StopPending}
+			cancel()
+			<-done
+			return false, 0
+		}
+	}
+}
+
+func installScheduler(cfg config.Config, target, configPath string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		if err := removeScheduler(); err != nil {
+			return err
+		}
+	}
+
+	s, err := m.CreateService(serviceName, target, mgr.Config{
+		DisplayName: "Vaultrix Agent",
+		Description: tr("Collects machine metrics and sends them to Vaultrix."),
+		StartType:   mgr.StartAutomatic,
+	}, "--config", configPath)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.Start("--config", configPath)
+}
+
+func removeScheduler() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		// servico ja removido
+		return nil
+	}
+	defer s.Close()
+
+	if st, err := s.Control(svc.Stop); err == nil {
+		deadline := time.Now().Add(10 * time.Second)
+		for st.State != svc.Stopped && time.Now().Before(deadline) {
+			time.Sleep(300 * time.Millisecond)
+			if st, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("remove service: %w", err)
+	}
+	return nil
+}
+
+func installedScheduler() (string, bool) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", false
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return "", false
+	}
+	s.Close()
+	return "windows-service", true
+}
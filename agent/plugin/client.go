@@ -0,0 +1,138 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// Client e o lado do agente: executa o plugin e faz as chamadas RPC.
+type Client struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	reader *bufio.Reader
+	wait   func() error
+	mu     sync.Mutex
+	nextID int64
+}
+
+// NewClient conversa com um plugin que nao e um processo (ex.: um modulo
+// WebAssembly), usando stdin e stdout dados. wait e chamado no Close, depois
+// que o stdin e fechado.
+func NewClient(stdin io.WriteCloser, stdout io.Reader, wait func() error) *Client {
+	return &Client{stdin: stdin, reader: bufio.NewReaderSize(stdout, 64*1024), wait: wait}
+}
+
+// Start executa o plugin em path. O processo e morto quando ctx termina.
+func Start(ctx context.Context, path string, args ...string) (*Client, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &Client{cmd: cmd, stdin: stdin, reader: bufio.NewReaderSize(stdout, 64*1024), wait: cmd.Wait}, nil
+}
+
+// Call envia uma requisicao e decodifica o resultado em out.
+func (c *Client) Call(method string, params, out any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	req := Request{JSONRPC: "2.0", ID: c.nextID, Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = b
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.stdin.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", method, err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("%s: response id %d does not match request %d", method, resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %w", method, resp.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
+
+// Handshake negocia a versao do protocolo.
+func (c *Client) Handshake(agentVersion string) (HandshakeResult, error) {
+	var res HandshakeResult
+	err := c.Call(MethodHandshake, HandshakeParams{ProtocolVersion: ProtocolVersion, AgentVersion: agentVersion}, &res)
+	if err == nil && res.ProtocolVersion != ProtocolVersion {
+		err = fmt.Errorf("handshake: plugin speaks protocol %d, agent speaks %d", res.ProtocolVersion, ProtocolVersion)
+	}
+	if err == nil && res.Name == "" {
+		err = errors.New("handshake: plugin did not report a name")
+	}
+	return res, err
+}
+
+func (c *Client) Schema() (Schema, error) {
+	var s Schema
+	return s, c.Call(MethodSchema, nil, &s)
+}
+
+func (c *Client) Collect(timeoutMS int64) (map[string]any, error) {
+	var res CollectResult
+	err := c.Call(MethodCollect, CollectParams{TimeoutMS: timeoutMS}, &res)
+	return res.Data, err
+}
+
+func (c *Client) Health() (HealthResult, error) {
+	var res HealthResult
+	return res, c.Call(MethodHealth, nil, &res)
+}
+
+// Close fecha o stdin, sinalizando ao plugin que deve sair, e aguarda.
+func (c *Client) Close() error {
+	c.stdin.Close()
+	return c.wait()
+}
+
+// Filter mantem apenas os campos declarados no schema.
+func (s Schema) Filter(data map[string]any) map[string]any {
+	declared := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		declared[f.Name] = true
+	}
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		if declared[k] {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+// Plugin de exemplo: reporta quantos arquivos existem em /tmp. Serve de
+// ponto de partida para plugins de terceiros.
+//
+//	go build -o /etc/vaultrix-agent/plugins/tmpfiles ./plugin/example
+package main
+
+import (
+	"context"
+	"os"
+
+	"vaultrix-agent/plugin"
+)
+
+type tmpFiles struct{}
+
+func (tmpFiles) Info() plugin.Info {
+	return plugin.Info{Name: "tmpfiles", Version: "1.0.0"}
+}
+
+func (tmpFiles) Schema() plugin.Schema {
+	return plugin.Schema{Fields: []plugin.Field{
+		{Name: "count", Type: plugin.TypeGauge, Description: "arquivos em /tmp"},
+	}}
+}
+
+func (tmpFiles) Collect(ctx context.Context) (map[string]any, error) {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"count": len(entries)}, nil
+}
+
+func main() {
+	if err := plugin.Serve(tmpFiles{}); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,28 @@
+package plugin
+
+import "errors"
+
+// Funcoes de host oferecidas a plugins WebAssembly (modulo "vaultrix").
+// Cada uma recebe ponteiro e tamanho do argumento e de um buffer de saida e
+// devolve o tamanho total do resultado; se ele for maior que o buffer, o
+// conteudo vem truncado e a chamada pode ser repetida com um buffer maior.
+// Valores negativos sao os codigos Host* abaixo.
+//
+// O agente so atende o que a config libera para o plugin em
+// wasm_capabilities: caminhos de leitura, comandos e hosts HTTP.
+const (
+	HostModule      = "vaultrix"
+	HostReadFile    = "read_file"
+	HostRunCommand  = "run_command"
+	HostHTTPGet     = "http_get"
+	HostDenied      = -1
+	HostFailed      = -2
+	HostMaxResponse = 1 << 20
+)
+
+var (
+	// ErrDenied indica que a capacidade nao foi liberada para o plugin.
+	ErrDenied = errors.New("capability denied by agent config")
+	// ErrHostFailed indica que o agente tentou e a operacao falhou.
+	ErrHostFailed = errors.New("host call failed")
+)
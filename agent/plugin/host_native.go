@@ -0,0 +1,37 @@
+//go:build !wasip1
+
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ReadFile, RunCommand e HTTPGet permitem escrever um plugin que compila
+// tanto como executavel quanto como WebAssembly (GOOS=wasip1). Fora do
+// WebAssembly elas acessam o sistema diretamente.
+func ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// RunCommand executa name com args e devolve o stdout.
+func RunCommand(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// HTTPGet devolve o corpo da resposta de um GET em url.
+func HTTPGet(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, HostMaxResponse))
+}
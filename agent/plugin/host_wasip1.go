@@ -0,0 +1,50 @@
+//go:build wasip1
+
+package plugin
+
+import (
+	"strings"
+	"unsafe"
+)
+
+//go:wasmimport vaultrix read_file
+func hostReadFile(arg unsafe.Pointer, argLen uint32, buf unsafe.Pointer, bufLen uint32) int64
+
+//go:wasmimport vaultrix run_command
+func hostRunCommand(arg unsafe.Pointer, argLen uint32, buf unsafe.Pointer, bufLen uint32) int64
+
+//go:wasmimport vaultrix http_get
+func hostHTTPGet(arg unsafe.Pointer, argLen uint32, buf unsafe.Pointer, bufLen uint32) int64
+
+// ReadFile le um arquivo do host, se o caminho estiver liberado.
+func ReadFile(path string) ([]byte, error) {
+	return callHost(hostReadFile, path)
+}
+
+// RunCommand executa um comando liberado no host e devolve o stdout. Os
+// argumentos viajam separados por NUL.
+func RunCommand(name string, args ...string) ([]byte, error) {
+	return callHost(hostRunCommand, strings.Join(append([]string{name}, args...), "\x00"))
+}
+
+// HTTPGet faz um GET a partir do host, se o host da url estiver liberado.
+func HTTPGet(url string) ([]byte, error) {
+	return callHost(hostHTTPGet, url)
+}
+
+func callHost(fn func(unsafe.Pointer, uint32, unsafe.Pointer, uint32) int64, arg string) ([]byte, error) {
+	argBytes := []byte(arg)
+	buf := make([]byte, 64*1024)
+	for {
+		n := fn(unsafe.Pointer(unsafe.SliceData(argBytes)), uint32(len(argBytes)), unsafe.Pointer(&buf[0]), uint32(len(buf)))
+		switch {
+		case n == HostDenied:
+			return nil, ErrDenied
+		case n < 0:
+			return nil, ErrHostFailed
+		case int(n) <= len(buf):
+			return buf[:n], nil
+		}
+		buf = make([]byte, n)
+	}
+}
@@ -0,0 +1,57 @@
+// Plugin de exemplo para o runtime WebAssembly: reporta o load average
+// lendo /proc/loadavg pelas funcoes de host, sem acesso direto ao sistema.
+// Compila tambem como executavel comum.
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o /etc/vaultrix-agent/plugins/loadavg.wasm ./plugin/loadavg
+//
+// e, na config do agente:
+//
+//	"wasm_capabilities": {"loadavg": {"read_paths": ["/proc/loadavg"]}}
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/plugin"
+)
+
+type loadAvg struct{}
+
+func (loadAvg) Info() plugin.Info {
+	return plugin.Info{Name: "loadavg", Version: "1.0.0"}
+}
+
+func (loadAvg) Schema() plugin.Schema {
+	return plugin.Schema{Fields: []plugin.Field{
+		{Name: "load1", Type: plugin.TypeGauge},
+		{Name: "load5", Type: plugin.TypeGauge},
+		{Name: "load15", Type: plugin.TypeGauge},
+	}}
+}
+
+func (loadAvg) Collect(ctx context.Context) (map[string]any, error) {
+	b, err := plugin.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return nil, errors.New("unexpected /proc/loadavg format")
+	}
+	data := make(map[string]any, 3)
+	for i, key := range []string{"load1", "load5", "load15"} {
+		v, _ := strconv.ParseFloat(fields[i], 64)
+		data[key] = v
+	}
+	return data, nil
+}
+
+func main() {
+	if err := plugin.Serve(loadAvg{}); err != nil {
+		os.Exit(1)
+	}
+}
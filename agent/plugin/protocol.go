@@ -0,0 +1,150 @@
+// Package plugin define o protocolo de plugins do vaultrix-agent e um SDK
+// minimo para escreve-los em Go.
+//
+// Um plugin e um executavel que conversa com o agente por JSON-RPC 2.0 no
+// stdin/stdout, uma mensagem JSON por linha. O agente sempre chama, nesta
+// ordem: "handshake", "schema" e entao "collect" e/ou "health". Ao fechar o
+// stdin, o plugin deve encerrar.
+//
+// Exemplo de plugin:
+//
+//	type appliance struct{}
+//
+//	func (appliance) Info() plugin.Info { return plugin.Info{Name: "appliance", Version: "1.0.0"} }
+//	func (appliance) Schema() plugin.Schema {
+//		return plugin.Schema{Fields: []plugin.Field{{Name: "sessions", Type: plugin.TypeGauge}}}
+//	}
+//	func (appliance) Collect(ctx context.Context) (map[string]any, error) {
+//		return map[string]any{"sessions": 42}, nil
+//	}
+//
+//	func main() { plugin.Serve(appliance{}) }
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion e a versao do contrato. Mudancas incompativeis incrementam
+// este numero; o agente recusa plugins com versao diferente.
+const ProtocolVersion = 1
+
+// Metodos do protocolo.
+const (
+	MethodHandshake = "handshake"
+	MethodSchema    = "schema"
+	MethodCollect   = "collect"
+	MethodHealth    = "health"
+)
+
+// Codigos de erro JSON-RPC usados pelo SDK.
+const (
+	CodeParseError     = -32700
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Tipos de campo aceitos no schema.
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+	TypeString  = "string"
+	TypeBool    = "bool"
+	TypeObject  = "object"
+)
+
+// Estados de saude de um plugin.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthError    = "error"
+)
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+type HandshakeParams struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	AgentVersion    string `json:"agent_version"`
+}
+
+type HandshakeResult struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+}
+
+// Info identifica o plugin no handshake.
+type Info struct {
+	Name    string
+	Version string
+}
+
+// Schema declara os campos que o plugin pode devolver no collect. Campos nao
+// declarados sao descartados pelo agente.
+type Schema struct {
+	Fields []Field `json:"fields"`
+}
+
+type Field struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type CollectParams struct {
+	TimeoutMS int64 `json:"timeout_ms"`
+}
+
+type CollectResult struct {
+	Data map[string]any `json:"data"`
+}
+
+type HealthResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Validate confere nomes e tipos declarados.
+func (s Schema) Validate() error {
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if f.Name == "" {
+			return errors.New("schema: field without name")
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("schema: duplicate field %q", f.Name)
+		}
+		seen[f.Name] = true
+		switch f.Type {
+		case TypeGauge, TypeCounter, TypeString, TypeBool, TypeObject:
+		default:
+			return fmt.Errorf("schema: field %q has invalid type %q", f.Name, f.Type)
+		}
+	}
+	return nil
+}
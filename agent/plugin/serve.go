@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Plugin e o que um autor de plugin implementa.
+type Plugin interface {
+	Info() Info
+	Schema() Schema
+	Collect(ctx context.Context) (map[string]any, error)
+}
+
+// HealthChecker e opcional; sem ele o plugin responde sempre "ok".
+type HealthChecker interface {
+	Health(ctx context.Context) HealthResult
+}
+
+// Serve atende o agente pelo stdin/stdout ate o stdin ser fechado.
+func Serve(p Plugin) error {
+	return ServeIO(p, os.Stdin, os.Stdout)
+}
+
+// ServeIO e o Serve com leitor e escritor explicitos.
+func ServeIO(p Plugin, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if err := enc.Encode(errorResponse(0, CodeParseError, err.Error())); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := enc.Encode(handle(p, req)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func handle(p Plugin, req Request) Response {
+	switch req.Method {
+	case MethodHandshake:
+		var params HandshakeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, CodeInvalidParams, err.Error())
+		}
+		if params.ProtocolVersion != ProtocolVersion {
+			return errorResponse(req.ID, CodeInvalidParams, fmt.Sprintf("unsupported protocol version %d (plugin speaks %d)", params.ProtocolVersion, ProtocolVersion))
+		}
+		info := p.Info()
+		return resultResponse(req.ID, HandshakeResult{ProtocolVersion: ProtocolVersion, Name: info.Name, Version: info.Version})
+
+	case MethodSchema:
+		return resultResponse(req.ID, p.Schema())
+
+	case MethodCollect:
+		var params CollectParams
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return errorResponse(req.ID, CodeInvalidParams, err.Error())
+			}
+		}
+		ctx := context.Background()
+		if params.TimeoutMS > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(params.TimeoutMS)*time.Millisecond)
+			defer cancel()
+		}
+		data, err := p.Collect(ctx)
+		if err != nil {
+			return errorResponse(req.ID, CodeInternalError, err.Error())
+		}
+		return resultResponse(req.ID, CollectResult{Data: data})
+
+	case MethodHealth:
+		if hc, ok := p.(HealthChecker); ok {
+			return resultResponse(req.ID, hc.Health(context.Background()))
+		}
+		return resultResponse(req.ID, HealthResult{Status: HealthOK})
+	}
+	return errorResponse(req.ID, CodeMethodNotFound, "method not found: "+req.Method)
+}
+
+func resultResponse(id int64, result any) Response {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(id, CodeInternalError, err.Error())
+	}
+	return Response{JSONRPC: "2.0", ID: id, Result: b}
+}
+
+func errorResponse(id int64, code int, message string) Response {
+	return Response{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: message}}
+}
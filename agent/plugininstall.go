@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const pluginDownloadTimeout = 2 * time.Minute
+
+// Tamanho maximo aceito para um artefato de plugin.
+const maxPluginSize = 64 << 20
+
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// pluginManifest descreve um plugin publicado no registry. Ele e baixado de
+// <registry>/<nome>/manifest.json, com a assinatura ed25519 dos mesmos bytes
+// em manifest.json.sig (base64).
+type pluginManifest struct {
+	Name        string           `json:"name"`
+	Version     string           `json:"version"`
+	Description string           `json:"description,omitempty"`
+	Artifacts   []pluginArtifact `json:"artifacts"`
+
+	// Capabilities e o que um artefato .wasm pede; so e gravado na config
+	// com --grant.
+	Capabilities *config.WasmCapabilities `json:"capabilities,omitempty"`
+}
+
+// pluginArtifact e um binario para uma plataforma. Modulos WebAssembly usam
+// os "wasip1" e arch "wasm". URL relativa e resolvida a partir do manifest.
+type pluginArtifact struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+func (a pluginArtifact) wasm() bool {
+	return a.OS == "wasip1" && a.Arch == "wasm"
+}
+
+func runPluginCommand(args []string) {
+	if len(args) == 0 || args[0] != "install" {
+		fmt.Fprintln(os.Stderr, tr("usage: vaultrix-agent plugin install [--config path] [--grant] <name>"))
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("plugin install", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	grant := fs.Bool("grant", false, tr("Grant the .wasm plugin the capabilities requested in its manifest"))
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fatal(errors.New("plugin install: exactly one plugin name is required"))
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	manifest, path, err := installPlugin(cfg, fs.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(trf("Plugin %s %s installed at %s.", manifest.Name, manifest.Version, path))
+
+	if err := enablePlugin(*configPath, cfg, manifest, isWasmPlugin(path), *grant); err != nil {
+		fatal(err)
+	}
+	// a flag pode vir da config remota, que o install nao edita
+	if fresh, err := loadConfig(*configPath); err == nil {
+		effective := withCachedRemoteConfig(fresh, *stateDir)
+		if !collectorEnabled(effective, config.CollectorPlugins) {
+			fmt.Println(tr("The remote config keeps the plugins collector off on this host (collectors or the collector.plugins flag); the plugin will not run until that changes."))
+		}
+	}
+	if isWasmPlugin(path) && manifest.Capabilities != nil && !*grant {
+		caps, _ := json.Marshal(manifest.Capabilities)
+		fmt.Println(trf("The plugin requests the capabilities %s; run again with --grant to grant them.", caps))
+	}
+	recordEvent(*stateDir, eventPluginInstall, manifest.Name+" "+manifest.Version)
+}
+
+// installPlugin baixa e verifica o manifest e o artefato da plataforma e o
+// grava no diretorio de plugins. Um artefato WebAssembly tem preferencia
+// sobre o executavel nativo, por rodar isolado.
+func installPlugin(cfg config.Config, name string) (pluginManifest, string, error) {
+	if !pluginNamePattern.MatchString(name) {
+		return pluginManifest{}, "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	keys, err := parseTrustedKeys("plugin_trusted_keys", cfg.PluginTrustedKeys)
+	if err != nil {
+		return pluginManifest{}, "", err
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return pluginManifest{}, "", err
+	}
+	client.Timeout = pluginDownloadTimeout
+
+	var raw, sig []byte
+	manifestURL, err := callAPI(cfg, cfg.PluginRegistryURLFor, func(registry string) (string, error) {
+		var err error
+		manifestURL := registry + "/" + name + "/manifest.json"
+		if raw, err = registryGet(client, cfg, manifestURL, 1<<20); err != nil {
+			return "", err
+		}
+		sig, err = registryGet(client, cfg, manifestURL+".sig", 4096)
+		return manifestURL, err
+	})
+	if err != nil {
+		return pluginManifest{}, "", err
+	}
+	if err := verifyManifest(keys, raw, sig); err != nil {
+		return pluginManifest{}, "", err
+	}
+
+	var manifest pluginManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return pluginManifest{}, "", fmt.Errorf("manifest: %w", err)
+	}
+	if manifest.Name != name {
+		return manifest, "", fmt.Errorf("manifest is for plugin %q, not %q", manifest.Name, name)
+	}
+	artifact, ok := selectArtifact(manifest.Artifacts)
+	if !ok {
+		return manifest, "", fmt.Errorf("plugin %s has no artifact for %s/%s or wasm", name, runtime.GOOS, runtime.GOARCH)
+	}
+
+	artifactURL, err := resolveArtifactURL(manifestURL, artifact.URL)
+	if err != nil {
+		return manifest, "", err
+	}
+	body, err := fetchArtifact(client, cfg, artifact, artifactURL, maxPluginSize)
+	if err != nil {
+		return manifest, "", err
+	}
+
+	dir := pluginsDir(cfg)
+	if err := fsutil.EnsureDir(dir); err != nil {
+		return manifest, "", err
+	}
+	path, other := filepath.Join(dir, name), filepath.Join(dir, name+".wasm")
+	mode := os.FileMode(0o755)
+	if artifact.wasm() {
+		path, other = other, path
+		mode = 0o644
+	}
+	if err := fsutil.WriteFileAtomic(path, body, mode); err != nil {
+		return manifest, "", err
+	}
+	// Uma versao anterior com o outro formato rodaria em duplicidade.
+	if err := os.Remove(other); err != nil && !os.IsNotExist(err) {
+		return manifest, path, err
+	}
+	return manifest, path, nil
+}
+
+// parseTrustedKeys le as chaves publicas da opcao setting.
+func parseTrustedKeys(setting string, encoded []string) ([]ed25519.PublicKey, error) {
+	if len(encoded) == 0 {
+		return nil, fmt.Errorf("%s is empty; refusing to install unsigned artifacts", setting)
+	}
+	keys := make([]ed25519.PublicKey, 0, len(encoded))
+	for _, e := range encoded {
+		b, err := base64.StdEncoding.DecodeString(e)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s: invalid ed25519 key %q", setting, e)
+		}
+		keys = append(keys, ed25519.PublicKey(b))
+	}
+	return keys, nil
+}
+
+func verifyManifest(keys []ed25519.PublicKey, manifest, sigText []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sigText)))
+	if err != nil {
+		return fmt.Errorf("manifest signature: %w", err)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, manifest, sig) {
+			return nil
+		}
+	}
+	return errors.New("manifest signature does not match any trusted key")
+}
+
+func selectArtifact(artifacts []pluginArtifact) (pluginArtifact, bool) {
+	var native pluginArtifact
+	found := false
+	for _, a := range artifacts {
+		if a.wasm() {
+			return a, true
+		}
+		if a.OS == runtime.GOOS && a.Arch == runtime.GOARCH && !found {
+			native, found = a, true
+		}
+	}
+	return native, found
+}
+
+func resolveArtifactURL(manifestURL, ref string) (string, error) {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return "", err
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// registryGet baixa url com limite de tamanho. O token do agente so e
+// enviado quando o registry e um dos enderecos da API do Vaultrix. Erros
+// HTTP voltam como apiError, para o failover seguir nos 5xx.
+func registryGet(client *http.Client, cfg config.Config, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(cfg.Endpoints(), func(endpoint string) bool {
+		api, err := url.Parse(endpoint)
+		return err == nil && api.Host == req.URL.Host
+	}) {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET %s: %w", rawURL, &apiError{StatusCode: resp.StatusCode})
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: response larger than %d bytes", rawURL, limit)
+	}
+	return body, nil
+}
+
+// fetchArtifact baixa um artefato listado num manifest assinado e confere o
+// sha256 que o manifest traz para ele.
+func fetchArtifact(client *http.Client, cfg config.Config, artifact pluginArtifact, rawURL string, limit int64) ([]byte, error) {
+	body, err := registryGet(client, cfg, rawURL, limit)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), artifact.SHA256) {
+		return nil, fmt.Errorf("checksum of %s does not match the signed manifest", rawURL)
+	}
+	return body, nil
+}
+
+// enablePlugin liga o coletor de plugins, se estiver desligado, e grava as
+// capacidades do plugin .wasm quando concedidas. So as chaves do proprio
+// arquivo sao editadas: padroes do perfil nao vao parar no config. A flag
+// collector.plugins do arquivo, que limitaria o coletor a parte da frota,
+// e removida.
+func enablePlugin(configPath string, cfg config.Config, manifest pluginManifest, wasm, grant bool) error {
+	grantCaps := wasm && grant && manifest.Capabilities != nil
+	if collectorEnabled(cfg, config.CollectorPlugins) && !grantCaps {
+		return nil
+	}
+	pluginFlag := "collector." + config.CollectorPlugins
+	return config.UpdateFile(configPath, func(raw map[string]json.RawMessage) error {
+		if !collectorEnabled(cfg, config.CollectorPlugins) {
+			if enabled, ok := cfg.Collectors[config.CollectorPlugins]; ok && !enabled {
+				if err := setRawKey(raw, "collectors", config.CollectorPlugins, true); err != nil {
+					return err
+				}
+			}
+			if _, ok := cfg.Flags[pluginFlag]; ok {
+				if err := setRawKey(raw, "flags", pluginFlag, nil); err != nil {
+					return err
+				}
+			}
+		}
+		if grantCaps {
+			return setRawKey(raw, "wasm_capabilities", manifest.Name, *manifest.Capabilities)
+		}
+		return nil
+	})
+}
+
+// setRawKey grava key dentro do objeto section do config bruto, criando o
+// objeto se preciso; value nil remove a chave.
+func setRawKey(raw map[string]json.RawMessage, section, key string, value any) error {
+	obj := make(map[string]json.RawMessage)
+	if b, ok := raw[section]; ok {
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return fmt.Errorf("%s: %w", section, err)
+		}
+		if obj == nil {
+			obj = make(map[string]json.RawMessage)
+		}
+	}
+	if value == nil {
+		if _, ok := obj[key]; !ok {
+			return nil
+		}
+		delete(obj, key)
+		if len(obj) == 0 {
+			delete(raw, section)
+			return nil
+		}
+	} else {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		obj[key] = b
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	raw[section] = b
+	return nil
+}
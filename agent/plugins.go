@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/plugin"
+)
+
+const pluginTimeout = 10 * time.Second
+
+// Intervalo entre varreduras do diretorio de plugins no modo daemon.
+const pluginWatchInterval = 10 * time.Second
+
+// PluginResult e o que cada plugin contribui para o payload.
+type PluginResult struct {
+	Version   string         `json:"version,omitempty"`
+	Health    string         `json:"health,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	ErrorKind string         `json:"error_kind,omitempty"`
+}
+
+// discoverPlugins lista os executaveis e os modulos .wasm do diretorio de
+// plugins.
+func discoverPlugins(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || e.Name()[0] == '.' {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !(isExecutable(info) || isWasmPlugin(e.Name())) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// collectPlugins executa todos os plugins em paralelo, cada um com prazo
+// proprio. O resultado e indexado pelo nome declarado no handshake.
+func collectPlugins(cfg config.Config) map[string]PluginResult {
+	var paths []string
+	if reg := activePluginRegistry(); reg != nil {
+		paths = reg.paths()
+	} else {
+		paths = discoverPlugins(pluginsDir(cfg))
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]PluginResult, len(paths))
+	)
+	for _, path := range paths {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			name, res := runPlugin(cfg, path)
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}(path)
+	}
+	wg.Wait()
+	return results
+}
+
+// startPlugin inicia um plugin executavel, ja com os limites de recursos
+// aplicados, ou instancia um modulo WebAssembly.
+func startPlugin(ctx context.Context, cfg config.Config, path string) (*plugin.Client, error) {
+	if isWasmPlugin(path) {
+		return startWasmPlugin(ctx, cfg, path)
+	}
+	name, args, err := pluginCommand(path, cfg.PluginLimits.WithDefaults())
+	if err != nil {
+		return nil, err
+	}
+	return plugin.Start(ctx, name, args...)
+}
+
+func runPlugin(cfg config.Config, path string) (string, PluginResult) {
+	name := filepath.Base(path)
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	client, err := startPlugin(ctx, cfg, path)
+	if err != nil {
+		return name, PluginResult{Error: err.Error(), ErrorKind: classifyError(err)}
+	}
+	defer client.Close()
+
+	hs, err := client.Handshake(version)
+	if err != nil {
+		return name, PluginResult{Error: err.Error(), ErrorKind: classifyError(err)}
+	}
+	name = hs.Name
+	res := PluginResult{Version: hs.Version}
+
+	schema, err := client.Schema()
+	if err == nil {
+		err = schema.Validate()
+	}
+	if err != nil {
+		res.Error, res.ErrorKind = err.Error(), classifyError(err)
+		return name, res
+	}
+
+	if health, err := client.Health(); err == nil {
+		res.Health = health.Status
+	}
+
+	deadline, _ := ctx.Deadline()
+	data, err := client.Collect(time.Until(deadline).Milliseconds())
+	if err != nil {
+		res.Error, res.ErrorKind = err.Error(), classifyError(err)
+		return name, res
+	}
+	res.Data = schema.Filter(data)
+	return name, res
+}
+
+// pluginRegistry mantem, no modo daemon, os plugins validados do diretorio.
+// Arquivos novos ou alterados passam por handshake e schema antes de entrar;
+// arquivos removidos saem na varredura seguinte.
+type pluginRegistry struct {
+	mu      sync.Mutex
+	dir     string
+	cfg     config.Config
+	plugins map[string]registeredPlugin
+}
+
+type registeredPlugin struct {
+	name    string
+	modTime time.Time
+	size    int64
+	valid   bool
+}
+
+var (
+	registryMu             sync.Mutex
+	pluginRegistryInstance *pluginRegistry
+)
+
+func activePluginRegistry() *pluginRegistry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return pluginRegistryInstance
+}
+
+func setPluginRegistry(reg *pluginRegistry) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	pluginRegistryInstance = reg
+}
+
+func newPluginRegistry(cfg config.Config) *pluginRegistry {
+	return &pluginRegistry{
+		dir:     pluginsDir(cfg),
+		cfg:     cfg,
+		plugins: make(map[string]registeredPlugin),
+	}
+}
+
+// paths devolve os plugins validos, em ordem.
+func (r *pluginRegistry) paths() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for path, p := range r.plugins {
+		if p.valid {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *pluginRegistry) watch(ctx context.Context) {
+	ticker := time.NewTicker(pluginWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.scan()
+		}
+	}
+}
+
+func (r *pluginRegistry) scan() {
+	current := make(map[string]os.FileInfo)
+	for _, path := range discoverPlugins(r.dir) {
+		if info, err := os.Stat(path); err == nil {
+			current[path] = info
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for path, p := range r.plugins {
+		if _, ok := current[path]; !ok {
+			delete(r.plugins, path)
+			fmt.Fprintf(os.Stderr, "plugin %s: removed\n", p.name)
+		}
+	}
+	for path, info := range current {
+		prev, ok := r.plugins[path]
+		if ok && prev.modTime.Equal(info.ModTime()) && prev.size == info.Size() {
+			continue
+		}
+		p := registeredPlugin{name: filepath.Base(path), modTime: info.ModTime(), size: info.Size()}
+		name, err := validatePlugin(r.cfg, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plugin %s: rejected: %v\n", p.name, err)
+		} else {
+			p.name, p.valid = name, true
+			fmt.Fprintf(os.Stderr, "plugin %s: registered\n", name)
+		}
+		r.plugins[path] = p
+	}
+}
+
+// validatePlugin confere handshake e schema sem coletar.
+func validatePlugin(cfg config.Config, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	client, err := startPlugin(ctx, cfg, path)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	hs, err := client.Handshake(version)
+	if err != nil {
+		return "", err
+	}
+	schema, err := client.Schema()
+	if err != nil {
+		return hs.Name, err
+	}
+	return hs.Name, schema.Validate()
+}
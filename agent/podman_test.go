@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"vaultrix-agent/internal/collectors"
+)
+
+// fakeCommand poe no PATH um executavel name que responde a cada linha de
+// argumentos em outputs com a saida correspondente e falha com qualquer
+// outra.
+func fakeCommand(t *testing.T, name string, outputs map[string]string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are shell scripts")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in\n"
+	i := 0
+	for args, stdout := range outputs {
+		out := filepath.Join(dir, fmt.Sprintf("out%d", i))
+		if err := os.WriteFile(out, []byte(stdout), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		script += fmt.Sprintf("%q) cat %q ;;\n", args, out)
+		i++
+	}
+	script += "*) echo \"unexpected arguments: $*\" >&2; exit 1 ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCollectPodmanPS(t *testing.T) {
+	// o podman de cada usuario e lido pelo socket da API
+	ep := dockerEndpoint{Host: "unix:///run/user/1000/podman/podman.sock", Runtime: runtimePodman}
+	fakeCommand(t, "podman", map[string]string{"--url " + ep.Host + " ps -a --format json": `[
+		{"Id": "3f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d", "Names": ["web"], "Image": "docker.io/library/nginx:1.25", "State": "running", "Status": "Up 2 hours", "Labels": {"monitor": "true"}},
+		{"Id": "9e8d7c6b5a4f", "Names": [], "Image": "quay.io/app/worker:2", "State": "exited", "Status": "Exited (0) 3 minutes ago"}
+	]`})
+	got, err := collectPodmanPS(context.Background(), ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ContainerStatus{
+		{ID: "3f2a9c1e8b7d", Name: "web", Image: "docker.io/library/nginx:1.25", State: "running", Status: "Up 2 hours", Labels: map[string]string{"monitor": "true"}},
+		{ID: "9e8d7c6b5a4f", Image: "quay.io/app/worker:2", State: "exited", Status: "Exited (0) 3 minutes ago"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCollectPodmanStats(t *testing.T) {
+	stats := `[
+		{"id": "3f2a9c1e8b7d6a5f", "name": "web", "cpu_percent": "1.50%", "mem_usage": "10.5MB / 1.02GB", "mem_percent": "1.03%", "net_io": "1.2kB / 648B", "block_io": "0B / 4.1kB", "pids": 3},
+		{"id": "9e8d7c6b5a4f", "name": "worker", "cpu_percent": "--", "mem_usage": "0B / 0B", "mem_percent": "--", "net_io": "0B / 0B", "block_io": "0B / 0B", "pids": "0"}
+	]`
+	fakeCommand(t, "podman", map[string]string{
+		"stats --no-stream --format json 3f2a9c1e8b7d": stats,
+		// sem ids, todos os containers
+		"stats --no-stream --format json --all": stats,
+	})
+	got, err := collectPodmanStats(context.Background(), dockerEndpoint{Runtime: runtimePodman}, []string{"3f2a9c1e8b7d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d containers, want 2", len(got))
+	}
+	web := got[0]
+	if web.ID != "3f2a9c1e8b7d" || web.Name != "web" || web.CPUPercent != 1.5 || web.MemPercent != 1.03 || web.PIDs != 3 ||
+		web.MemUsage != "10.5MB / 1.02GB" || web.NetIO != "1.2kB / 648B" || web.BlockIO != "0B / 4.1kB" {
+		t.Errorf("web = %+v", web)
+	}
+	if worker := got[1]; worker.CPUPercent != 0 || worker.PIDs != 0 {
+		t.Errorf("worker = %+v", worker)
+	}
+	if all, err := collectPodmanStats(context.Background(), dockerEndpoint{Runtime: runtimePodman}, nil); err != nil || len(all) != 2 {
+		t.Errorf("all containers = %d, %v", len(all), err)
+	}
+}
+
+func TestShortContainerID(t *testing.T) {
+	for id, want := range map[string]string{
+		"3f2a9c1e8b7d6a5f4e3d": "3f2a9c1e8b7d",
+		" 3f2a9c1e8b7d\n":      "3f2a9c1e8b7d",
+		"abc":                  "abc",
+	} {
+		if got := collectors.ShortContainerID(id); got != want {
+			t.Errorf("shortContainerID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"vaultrix-agent/internal/config"
+)
+
+// metricPoint e uma amostra no modelo medida/tags/campos, comum as saidas
+// que nao falam o JSON do payload (InfluxDB, Prometheus).
+type metricPoint struct {
+	measurement string
+	tags        []pointTag
+	fields      []pointField
+}
+
+type pointTag struct {
+	key, value string
+}
+
+type pointField struct {
+	key     string
+	value   float64
+	integer bool
+}
+
+func (p *metricPoint) tag(key, value string) {
+	if value != "" {
+		p.tags = append(p.tags, pointTag{key, value})
+	}
+}
+
+func (p *metricPoint) float(key string, v float64) {
+	p.fields = append(p.fields, pointField{key: key, value: v})
+}
+
+func (p *metricPoint) integer(key string, v int64) {
+	p.fields = append(p.fields, pointField{key: key, value: float64(v), integer: true})
+}
+
+// metricPoints traduz o payload em pontos vaultrix_host, vaultrix_disk e
+// vaultrix_container. Os campos usam os nomes do JSON do payload, e cada um
+// segue payload_fields pelo mesmo caminho ("metrics.cpu",
+// "containers.cpuPercent"). Pontos sem campos ficam de fora.
+func metricPoints(cfg config.Config, payload Payload) []metricPoint {
+	f := cfg.PayloadFields
+	var hostTags []pointTag
+	if h := payload.Host; h != nil {
+		p := metricPoint{}
+		if f.Permits("host.hostname") {
+			p.tag("host", h.Hostname)
+		}
+		if f.Permits("host.machine_id") {
+			p.tag("machine_id", h.MachineID)
+		}
+		hostTags = p.tags
+	}
+	withHost := func(measurement string) metricPoint {
+		return metricPoint{measurement: measurement, tags: append([]pointTag(nil), hostTags...)}
+	}
+
+	var points []metricPoint
+	host := withHost("vaultrix_host")
+	m := payload.Metrics
+	on := func(collector string) bool { return hostMetricsCollected(cfg, payload, collector) }
+	for _, v := range []struct {
+		collector, field string
+		value            float64
+		integer          bool
+	}{
+		{config.CollectorCPU, "cpu", m.CPUUsage, false},
+		{config.CollectorCPU, "cpu_cores", float64(m.CPUCores), true},
+		{config.CollectorMemory, "memory_total_mb", float64(m.MemoryTotalMB), true},
+		{config.CollectorMemory, "memory_avail_mb", float64(m.MemoryAvailMB), true},
+		{config.CollectorMemory, "memory_used_mb", float64(m.MemoryUsedMB), true},
+		{config.CollectorMemory, "memory_percent", m.MemoryPercent, false},
+		{config.CollectorDisk, "disk_total_gb", m.DiskTotalGB, false},
+		{config.CollectorDisk, "disk_used_gb", m.DiskUsedGB, false},
+		{config.CollectorDisk, "disk_percent", m.DiskPercent, false},
+		{config.CollectorLoad, "load_avg_1", m.LoadAvg1, false},
+		{config.CollectorLoad, "load_avg_5", m.LoadAvg5, false},
+		{config.CollectorLoad, "load_avg_15", m.LoadAvg15, false},
+	} {
+		if !on(v.collector) || !f.Permits("metrics."+v.field) {
+			continue
+		}
+		if v.integer {
+			host.integer(v.field, int64(v.value))
+		} else {
+			host.float(v.field, v.value)
+		}
+	}
+	points = append(points, host)
+
+	for _, d := range m.Disks {
+		p := withHost("vaultrix_disk")
+		for _, t := range []struct{ field, key, value string }{
+			{"metrics.disks.device", "device", d.Device},
+			{"metrics.disks.mount_point", "mount_point", d.MountPoint},
+			{"metrics.disks.fs_type", "fs_type", d.FSType},
+		} {
+			if f.Permits(t.field) {
+				p.tag(t.key, t.value)
+			}
+		}
+		for _, v := range []struct {
+			field string
+			value float64
+		}{{"total_gb", d.TotalGB}, {"used_gb", d.UsedGB}, {"percent", d.Percent}} {
+			if f.Permits("metrics.disks." + v.field) {
+				p.float(v.field, v.value)
+			}
+		}
+		points = append(points, p)
+	}
+
+	if collectorEnabled(cfg, config.CollectorDockerStats) && f.Permits("containers.name") {
+		for _, c := range payload.Containers {
+			p := withHost("vaultrix_container")
+			p.tag("name", c.Name)
+			for _, t := range []struct{ field, key, value string }{
+				{"containers.id", "id", c.ID},
+				{"containers.image", "image", c.Image},
+				{"containers.namespace", "namespace", c.Namespace},
+				{"containers.pod", "pod", c.Pod},
+			} {
+				if f.Permits(t.field) {
+					p.tag(t.key, t.value)
+				}
+			}
+			if f.Permits("containers.cpuPercent") {
+				p.float("cpuPercent", c.CPUPercent)
+			}
+			if f.Permits("containers.memPercent") {
+				p.float("memPercent", c.MemPercent)
+			}
+			for _, v := range []struct {
+				field string
+				value int64
+			}{
+				{"memUsageBytes", c.MemUsageBytes},
+				{"memLimitBytes", c.MemLimitBytes},
+				{"netRxBytes", c.NetRxBytes},
+				{"netTxBytes", c.NetTxBytes},
+				{"blockReadBytes", c.BlockReadBytes},
+				{"blockWriteBytes", c.BlockWriteBytes},
+				{"pids", c.PIDs},
+			} {
+				// os campos em bytes sao omitidos quando o runtime nao os informa
+				if v.value > 0 && f.Permits("containers."+v.field) {
+					p.integer(v.field, v.value)
+				}
+			}
+			points = append(points, p)
+		}
+	}
+
+	kept := points[:0]
+	for _, p := range points {
+		if len(p.fields) > 0 {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// hostMetricsCollected diz se as metricas de host de um coletor valem: com
+// o coletor desligado ou num heartbeat os campos estao zerados, e zero seria
+// lido como medicao.
+func hostMetricsCollected(cfg config.Config, payload Payload, collector string) bool {
+	return !payload.Heartbeat && collectorEnabled(cfg, collector)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// pgSampleInterval separa as duas leituras dos contadores de transacoes;
+// os testes o encurtam.
+var pgSampleInterval = time.Second
+
+// PostgresStatus e o que o coletor reporta. TransactionsPerSec vem de duas
+// leituras de pg_stat_database com pgSampleInterval de intervalo, como a
+// CPU; CacheHitPercent e o acumulado desde o ultimo reset das estatisticas.
+type PostgresStatus struct {
+	Version            string  `json:"version"`
+	MaxConnections     int64   `json:"max_connections"`
+	Connections        int64   `json:"connections"`
+	ActiveConnections  int64   `json:"active_connections"`
+	IdleInTransaction  int64   `json:"idle_in_transaction"`
+	TransactionsPerSec float64 `json:"transactions_per_sec"`
+	CacheHitPercent    float64 `json:"cache_hit_percent"`
+
+	// WALBytes precisa de pg_monitor; sem ele fica de fora.
+	WALBytes *int64 `json:"wal_bytes,omitempty"`
+
+	// DatabaseSizes e o tamanho de cada banco, em bytes.
+	DatabaseSizes map[string]int64 `json:"database_sizes,omitempty"`
+
+	Replication *PostgresReplication `json:"replication,omitempty"`
+}
+
+// PostgresReplication aparece numa standby (Role "standby", com o atraso
+// do replay) e num primario com replicas conectadas.
+type PostgresReplication struct {
+	Role       string            `json:"role"`
+	LagSeconds *float64          `json:"lag_seconds,omitempty"`
+	Replicas   []PostgresReplica `json:"replicas,omitempty"`
+}
+
+type PostgresReplica struct {
+	Name       string   `json:"name"`
+	Addr       string   `json:"addr,omitempty"`
+	State      string   `json:"state"`
+	LagSeconds *float64 `json:"lag_seconds,omitempty"`
+}
+
+const (
+	pgQueryConnections = `SELECT current_setting('server_version') AS version,
+  current_setting('max_connections') AS max_connections,
+  count(*) AS total,
+  count(*) FILTER (WHERE state = 'active') AS active,
+  count(*) FILTER (WHERE state = 'idle in transaction') AS idle_in_transaction
+FROM pg_stat_activity WHERE backend_type = 'client backend'`
+	pgQueryDatabaseStats = `SELECT sum(xact_commit + xact_rollback) AS xacts,
+  sum(blks_hit) AS hits, sum(blks_read) AS reads FROM pg_stat_database`
+	pgQueryDatabaseSizes = `SELECT datname, pg_database_size(datname) AS size
+FROM pg_database WHERE datallowconn AND NOT datistemplate`
+	pgQueryWAL      = `SELECT coalesce(sum(size), 0) AS size FROM pg_ls_waldir()`
+	pgQueryRecovery = `SELECT pg_is_in_recovery() AS standby,
+  extract(epoch FROM now() - pg_last_xact_replay_timestamp()) AS lag`
+	pgQueryReplicas = `SELECT application_name, client_addr, state,
+  extract(epoch FROM replay_lag) AS lag FROM pg_stat_replication`
+)
+
+func collectPostgres(ctx context.Context, cfg *config.PostgresConfig) (*PostgresStatus, error) {
+	dsn, err := config.ParsePostgresDSN(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialPostgres(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.query(pgQueryConnections)
+	if err != nil {
+		return nil, err
+	}
+	s := &PostgresStatus{}
+	if len(rows) > 0 {
+		r := rows[0]
+		s.Version = r["version"]
+		s.MaxConnections = pgInt(r["max_connections"])
+		s.Connections = pgInt(r["total"])
+		s.ActiveConnections = pgInt(r["active"])
+		s.IdleInTransaction = pgInt(r["idle_in_transaction"])
+	}
+
+	first, err := conn.query(pgQueryDatabaseStats)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	select {
+	case <-time.After(pgSampleInterval):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	second, err := conn.query(pgQueryDatabaseStats)
+	if err != nil {
+		return nil, err
+	}
+	if len(first) > 0 && len(second) > 0 {
+		delta := pgInt(second[0]["xacts"]) - pgInt(first[0]["xacts"])
+		// a propria consulta abre uma transacao
+		delta = max(delta-1, 0)
+		s.TransactionsPerSec = math.Round(float64(delta)/time.Since(started).Seconds()*100) / 100
+		hits, reads := pgInt(second[0]["hits"]), pgInt(second[0]["reads"])
+		if hits+reads > 0 {
+			s.CacheHitPercent = math.Round(float64(hits)/float64(hits+reads)*10000) / 100
+		}
+	}
+
+	// o que depende de privilegio (pg_monitor) ou de versao so fica de fora
+	if rows, err := conn.query(pgQueryDatabaseSizes); err == nil && len(rows) > 0 {
+		s.DatabaseSizes = make(map[string]int64, len(rows))
+		for _, r := range rows {
+			s.DatabaseSizes[r["datname"]] = pgInt(r["size"])
+		}
+	}
+	if rows, err := conn.query(pgQueryWAL); err == nil && len(rows) > 0 {
+		size := pgInt(rows[0]["size"])
+		s.WALBytes = &size
+	}
+	s.Replication = postgresReplication(conn)
+	return s, nil
+}
+
+func postgresReplication(conn *pgConn) *PostgresReplication {
+	rows, err := conn.query(pgQueryRecovery)
+	if err != nil || len(rows) == 0 {
+		return nil
+	}
+	if rows[0]["standby"] == "t" {
+		return &PostgresReplication{Role: "standby", LagSeconds: pgFloat(rows[0], "lag")}
+	}
+	replicas, err := conn.query(pgQueryReplicas)
+	if err != nil || len(replicas) == 0 {
+		return nil
+	}
+	r := &PostgresReplication{Role: "primary"}
+	for _, row := range replicas {
+		r.Replicas = append(r.Replicas, PostgresReplica{
+			Name:       row["application_name"],
+			Addr:       row["client_addr"],
+			State:      row["state"],
+			LagSeconds: pgFloat(row, "lag"),
+		})
+	}
+	return r
+}
+
+func pgInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// pgFloat devolve nil para NULL, que e como o servidor diz "sem atraso
+// conhecido".
+func pgFloat(row map[string]string, key string) *float64 {
+	v, ok := row[key]
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil
+	}
+	f = math.Round(f*1000) / 1000
+	return &f
+}
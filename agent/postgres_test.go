@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// RFC 7914, secao 11
+	got := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1))
+	if want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"; got != want {
+		t.Errorf("pbkdf2 = %s, want %s", got, want)
+	}
+}
+
+func TestSCRAMSHA256(t *testing.T) {
+	// RFC 7677, secao 3
+	s := &scramSHA256{user: "user", password: "pencil", clientNonce: "rOprNGfwEbeRWgbNEkqO"}
+	if first, want := s.clientFirst(), "n,,n=user,r=rOprNGfwEbeRWgbNEkqO"; first != want {
+		t.Errorf("client first = %q, want %q", first, want)
+	}
+	final, err := s.clientFinal("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="; final != want {
+		t.Errorf("client final = %q, want %q", final, want)
+	}
+	if !s.verifyServer("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=") {
+		t.Error("server signature rejected")
+	}
+	if s.verifyServer("v=AAAA") {
+		t.Error("wrong server signature accepted")
+	}
+	if _, err := s.clientFinal("r=someone-else,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"); err == nil {
+		t.Error("foreign nonce accepted")
+	}
+}
+
+func TestPGMD5Password(t *testing.T) {
+	// o servidor guarda "md5" + md5(senha + usuario) e compara com o salt
+	stored := md5.Sum([]byte("secretmonitor"))
+	want := md5.Sum(append([]byte(hex.EncodeToString(stored[:])), 1, 2, 3, 4))
+	if got := pgMD5Password("monitor", "secret", []byte{1, 2, 3, 4}); got != "md5"+hex.EncodeToString(want[:]) {
+		t.Errorf("md5 password = %q", got)
+	}
+}
+
+// fakePostgres fala o bastante do protocolo v3 para o coletor.
+type fakePostgres struct {
+	t        *testing.T
+	auth     string // trust, md5 ou scram
+	password string
+	standby  bool
+	xacts    []int
+}
+
+func (f *fakePostgres) start() string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+type fakePGConn struct{ rw io.ReadWriter }
+
+func (c fakePGConn) send(kind byte, body []byte) {
+	c.rw.Write(append(append([]byte{kind}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...), body...))
+}
+
+func (c fakePGConn) receive() (byte, []byte) {
+	var h [5]byte
+	if _, err := io.ReadFull(c.rw, h[:]); err != nil {
+		return 0, nil
+	}
+	body := make([]byte, binary.BigEndian.Uint32(h[1:])-4)
+	io.ReadFull(c.rw, body)
+	return h[0], body
+}
+
+func (c fakePGConn) auth(code uint32, data []byte) {
+	c.send('R', append(binary.BigEndian.AppendUint32(nil, code), data...))
+}
+
+func (c fakePGConn) error(code, msg string) {
+	c.send('E', []byte("SERROR\x00C"+code+"\x00M"+msg+"\x00\x00"))
+}
+
+func (c fakePGConn) rows(columns []string, rows [][]string) {
+	desc := binary.BigEndian.AppendUint16(nil, uint16(len(columns)))
+	for _, col := range columns {
+		desc = append(append(desc, col...), 0)
+		desc = append(desc, make([]byte, 18)...)
+	}
+	c.send('T', desc)
+	for _, row := range rows {
+		data := binary.BigEndian.AppendUint16(nil, uint16(len(row)))
+		for _, v := range row {
+			if v == "NULL" {
+				data = binary.BigEndian.AppendUint32(data, 0xffffffff)
+				continue
+			}
+			data = append(binary.BigEndian.AppendUint32(data, uint32(len(v))), v...)
+		}
+		c.send('D', data)
+	}
+	c.send('C', []byte("SELECT\x00"))
+}
+
+func (f *fakePostgres) serve(raw net.Conn) {
+	defer raw.Close()
+	c := fakePGConn{raw}
+	var h [4]byte
+	io.ReadFull(raw, h[:])
+	startup := make([]byte, binary.BigEndian.Uint32(h[:])-4)
+	io.ReadFull(raw, startup)
+	if !bytes.Contains(startup, []byte("user\x00monitor\x00")) {
+		c.error("28000", "role does not exist")
+		return
+	}
+
+	ok := true
+	switch f.auth {
+	case "md5":
+		c.auth(5, []byte{9, 8, 7, 6})
+		_, body := c.receive()
+		ok = string(bytes.TrimRight(body, "\x00")) == pgMD5Password("monitor", f.password, []byte{9, 8, 7, 6})
+	case "scram":
+		c.auth(10, []byte("SCRAM-SHA-256\x00\x00"))
+		_, body := c.receive()
+		first := string(body[len("SCRAM-SHA-256\x00")+4:])
+		bare := strings.TrimPrefix(first, "n,,")
+		nonce := strings.TrimPrefix(bare, "n=,r=") + "server"
+		salt := []byte("0123456789abcdef")
+		serverFirst := "r=" + nonce + ",s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+		c.auth(11, []byte(serverFirst))
+		_, body = c.receive()
+		final := string(body)
+		withoutProof, proof64, _ := strings.Cut(final, ",p=")
+
+		// verificacao do lado do servidor: so com o StoredKey
+		salted := pbkdf2SHA256([]byte(f.password), salt, 4096)
+		storedKey := sha256.Sum256(hmacSHA256(salted, "Client Key"))
+		authMessage := bare + "," + serverFirst + "," + withoutProof
+		proof, _ := base64.StdEncoding.DecodeString(proof64)
+		sig := hmacSHA256(storedKey[:], authMessage)
+		for i := range proof {
+			proof[i] ^= sig[i%len(sig)]
+		}
+		ok = sha256.Sum256(proof) == storedKey
+		if ok {
+			serverSig := hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage)
+			c.auth(12, []byte("v="+base64.StdEncoding.EncodeToString(serverSig)))
+		}
+	}
+	if !ok {
+		c.error("28P01", "password authentication failed for user \"monitor\"")
+		return
+	}
+	c.auth(0, nil)
+	c.send('S', []byte("server_version\x0016.2\x00"))
+	c.send('Z', []byte("I"))
+
+	for {
+		kind, body := c.receive()
+		if kind != 'Q' {
+			return
+		}
+		sql := string(bytes.TrimRight(body, "\x00"))
+		switch sql {
+		case pgQueryConnections:
+			c.rows([]string{"version", "max_connections", "total", "active", "idle_in_transaction"}, [][]string{{"16.2", "100", "12", "3", "1"}})
+		case pgQueryDatabaseStats:
+			xacts := f.xacts[0]
+			f.xacts = f.xacts[1:]
+			c.rows([]string{"xacts", "hits", "reads"}, [][]string{{strconv.Itoa(xacts), "9900", "100"}})
+		case pgQueryDatabaseSizes:
+			c.rows([]string{"datname", "size"}, [][]string{{"postgres", "7500000"}, {"app", "120000000"}})
+		case pgQueryWAL:
+			c.error("42501", "permission denied for function pg_ls_waldir")
+		case pgQueryRecovery:
+			if f.standby {
+				c.rows([]string{"standby", "lag"}, [][]string{{"t", "2.5"}})
+			} else {
+				c.rows([]string{"standby", "lag"}, [][]string{{"f", "NULL"}})
+			}
+		case pgQueryReplicas:
+			c.rows([]string{"application_name", "client_addr", "state", "lag"}, [][]string{{"replica1", "10.0.0.2", "streaming", "NULL"}})
+		default:
+			c.error("42601", "unexpected query")
+		}
+		c.send('Z', []byte("I"))
+	}
+}
+
+func TestCollectPostgres(t *testing.T) {
+	saved := pgSampleInterval
+	pgSampleInterval = 100 * time.Millisecond
+	defer func() { pgSampleInterval = saved }()
+
+	tests := []struct {
+		name     string
+		server   *fakePostgres
+		password string
+		wantRepl *PostgresReplication
+		wantErr  string
+	}{
+		{"trust primary", &fakePostgres{auth: "trust"}, "", &PostgresReplication{Role: "primary", Replicas: []PostgresReplica{{Name: "replica1", Addr: "10.0.0.2", State: "streaming"}}}, ""},
+		{"md5 standby", &fakePostgres{auth: "md5", password: "secret", standby: true}, "secret", &PostgresReplication{Role: "standby", LagSeconds: ptrFloat(2.5)}, ""},
+		{"scram", &fakePostgres{auth: "scram", password: "secret"}, "secret", &PostgresReplication{Role: "primary", Replicas: []PostgresReplica{{Name: "replica1", Addr: "10.0.0.2", State: "streaming"}}}, ""},
+		{"scram wrong password", &fakePostgres{auth: "scram", password: "secret"}, "wrong", nil, `postgres error 28P01: password authentication failed for user "monitor"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.t = t
+			tt.server.xacts = []int{1000, 1021}
+			addr := tt.server.start()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			got, err := collectPostgres(ctx, &config.PostgresConfig{DSN: "postgres://monitor:" + tt.password + "@" + addr + "/postgres"})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 20 transacoes (fora a da propria leitura) em ~100ms
+			if got.TransactionsPerSec < 100 || got.TransactionsPerSec > 200 {
+				t.Errorf("transactions_per_sec = %v, want about 200", got.TransactionsPerSec)
+			}
+			got.TransactionsPerSec = 0
+			want := &PostgresStatus{
+				Version: "16.2", MaxConnections: 100, Connections: 12, ActiveConnections: 3, IdleInTransaction: 1,
+				CacheHitPercent: 99,
+				DatabaseSizes:   map[string]int64{"postgres": 7500000, "app": 120000000},
+				Replication:     tt.wantRepl,
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("status = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func ptrFloat(f float64) *float64 { return &f }
+
+func TestPostgresUnderApps(t *testing.T) {
+	if got := appsJSON(t, config.CollectorPostgres, (*PostgresStatus)(nil), io.EOF); got != "" {
+		t.Errorf("failed collection left apps %s", got)
+	}
+	got := appsJSON(t, config.CollectorPostgres, &PostgresStatus{Version: "16.2"}, nil)
+	if !strings.HasPrefix(got, `{"postgres":{"version":"16.2"`) {
+		t.Errorf("apps = %s", got)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+const preflightTimeout = 10 * time.Second
+
+// preflightError aponta a etapa que falhou e o que o operador deve corrigir.
+type preflightError struct {
+	Step        string
+	Err         error
+	Remediation string
+}
+
+func (e *preflightError) Error() string {
+	return fmt.Sprintf("preflight %s: %v\n  -> %s", e.Step, e.Err, e.Remediation)
+}
+
+func (e *preflightError) Unwrap() error {
+	return e.Err
+}
+
+// apiError carrega o status HTTP devolvido pela API.
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	// sinks como o Pushgateway respondem erros sem corpo
+	if strings.TrimSpace(e.Body) == "" {
+		return fmt.Sprintf("api error: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("api error: %s", e.Body)
+}
+
+// runPreflight valida, antes de instalar, que a maquina consegue de fato
+// entregar dados: DNS, TCP, TLS e por fim um probe autenticado com o token,
+// que a API confere sem gravar telemetria. Com varios enderecos em api_url,
+// basta um alcancavel; os demais aparecem como aviso.
+func runPreflight(cfg config.Config) error {
+	var reachable bool
+	var err error
+	for _, endpoint := range cfg.Endpoints() {
+		endpointErr := checkAPIReachability(cfg, endpoint, printPreflight)
+		if endpointErr == nil {
+			reachable = true
+			continue
+		}
+		if err == nil {
+			err = endpointErr
+		}
+		if len(cfg.Endpoints()) > 1 {
+			step, detail := "api", endpointErr.Error()
+			var pfErr *preflightError
+			if errors.As(endpointErr, &pfErr) {
+				step, detail = pfErr.Step, pfErr.Err.Error()
+			}
+			printPreflightWarning(step, trf("%s unreachable: %s", endpoint, detail))
+		}
+	}
+	if !reachable {
+		return err
+	}
+
+	checked, err := probeAuth(cfg)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			switch {
+			case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+				return &preflightError{"auth", err, tr("token rejected; generate a new machine token in the Vaultrix dashboard")}
+			case apiErr.StatusCode == http.StatusNotFound:
+				return &preflightError{"auth", err, tr("endpoint not found; api-url must end in /api/telemetry")}
+			}
+		}
+		return &preflightError{"auth", err, tr("the API rejected the token probe; check api-url and the server logs")}
+	}
+	if !checked {
+		printPreflight("auth", tr("the API does not support probes; token not checked"))
+		return nil
+	}
+	printPreflight("auth", tr("token accepted"))
+	return nil
+}
+
+// probeAuth manda so o token, marcado como probe. Servidores antigos nao
+// conhecem o probe e respondem 400 por falta de metricas; nesse caso o token
+// fica sem conferir, mas nenhum dado falso entra no historico.
+func probeAuth(cfg config.Config) (bool, error) {
+	body, err := json.Marshal(map[string]any{"token": cfg.Token, "probe": true})
+	if err != nil {
+		return false, err
+	}
+	_, err = callAPI(cfg, nil, func(endpoint string) ([]byte, error) {
+		return postBody(cfg, endpoint, body)
+	})
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkAPIReachability testa DNS, TCP e TLS ate o endpoint (ou o proxy),
+// sem enviar nada. report recebe o detalhe de cada etapa que passou.
+func checkAPIReachability(cfg config.Config, endpoint string, report func(step, detail string)) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return &preflightError{"url", fmt.Errorf("invalid api-url %q", endpoint), tr("use the full URL, e.g. https://vaultrix.example.com/api/telemetry")}
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return &preflightError{"proxy", err, tr("fix proxy_url (http://, https:// or socks5://host:port)")}
+	}
+	proxy, err := client.Transport.(*http.Transport).Proxy(&http.Request{URL: u})
+	if err != nil {
+		return &preflightError{"proxy", err, tr("check HTTP_PROXY/HTTPS_PROXY or proxy_url")}
+	}
+
+	// Com proxy, DNS e TCP sao testados contra o proxy; o destino final so e
+	// alcancavel atraves dele.
+	target := u
+	if proxy != nil {
+		target = proxy
+	}
+	host := target.Hostname()
+	port := target.Port()
+	if port == "" {
+		port = transport.DefaultPort(target.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return &preflightError{"dns", err, trf("could not resolve %s; check /etc/resolv.conf and the host name", host)}
+	}
+	report("dns", fmt.Sprintf("%s -> %v", host, addrs))
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return &preflightError{"tcp", err, trf("port %s on %s unreachable; check firewall, security groups or proxy", port, host)}
+	}
+	conn.Close()
+	report("tcp", net.JoinHostPort(host, port))
+
+	if proxy == nil && u.Scheme == "https" {
+		tlsConn, err := (&tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			return &preflightError{"tls", err, tlsRemediation(err)}
+		}
+		state := tlsConn.(*tls.Conn).ConnectionState()
+		tlsConn.Close()
+		detail := tls.VersionName(state.Version)
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			detail = trf("%s, %s, expires %s", detail, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+		}
+		report("tls", detail)
+	}
+
+	return nil
+}
+
+func tlsRemediation(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return tr("certificate issued by an unknown CA; install the CA on the system (ca-certificates)")
+	case errors.As(err, &hostname):
+		return tr("the certificate does not cover this host; use the name in the certificate")
+	case errors.As(err, &invalid):
+		return tr("certificate expired or not yet valid; check the machine clock (timedatectl)")
+	}
+	return tr("TLS handshake failed; check that the port really serves HTTPS")
+}
+
+func printPreflight(step, detail string) {
+	fmt.Printf("[ok] %-4s %s\n", step, detail)
+}
+
+func printPreflightWarning(step, detail string) {
+	fmt.Printf("[!!] %-4s %s\n", step, detail)
+}
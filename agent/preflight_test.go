@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/transport"
+)
+
+func TestRunPreflightFailures(t *testing.T) {
+	// porta que acabou de ser liberada: a conexao e recusada
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + l.Addr().String() + "/api/telemetry"
+	l.Close()
+
+	// certificado autoassinado, de uma CA que o sistema nao conhece
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		apiURL   string
+		wantStep string
+	}{
+		{"not a url", "vaultrix.example.com", "url"},
+		{"connection refused", closed, "tcp"},
+		{"unknown CA", srv.URL + "/api/telemetry", "tls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runPreflight(config.Config{Token: "t", ApiURL: tt.apiURL})
+			var pe *preflightError
+			if !errors.As(err, &pe) {
+				t.Fatalf("err = %v, want a preflightError", err)
+			}
+			if pe.Step != tt.wantStep {
+				t.Errorf("step = %q, want %q (%v)", pe.Step, tt.wantStep, err)
+			}
+			if pe.Remediation == "" {
+				t.Error("missing remediation")
+			}
+			if tt.wantStep == "tls" {
+				var unknown x509.UnknownAuthorityError
+				if !errors.As(err, &unknown) {
+					t.Errorf("err = %v, want an unknown authority error", err)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		// o probe leva so o token
+		if body["probe"] != true || len(body) != 2 {
+			t.Errorf("probe body = %v", body)
+		}
+		switch body["token"] {
+		case "good":
+			w.WriteHeader(http.StatusNoContent)
+		case "old-server":
+			http.Error(w, "missing metrics", http.StatusBadRequest)
+		default:
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		token       string
+		wantChecked bool
+		wantStatus  int
+	}{
+		{token: "good", wantChecked: true},
+		{token: "old-server"},
+		{token: "revoked", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		checked, err := probeAuth(config.Config{Token: tt.token, ApiURL: srv.URL})
+		if checked != tt.wantChecked {
+			t.Errorf("%s: checked = %v, want %v", tt.token, checked, tt.wantChecked)
+		}
+		var apiErr *apiError
+		switch {
+		case tt.wantStatus == 0 && err != nil:
+			t.Errorf("%s: %v", tt.token, err)
+		case tt.wantStatus != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus):
+			t.Errorf("%s: err = %v, want HTTP %d", tt.token, err, tt.wantStatus)
+		}
+	}
+}
+
+func TestDefaultPort(t *testing.T) {
+	for scheme, want := range map[string]string{"https": "443", "http": "80", "socks5": "1080", "socks5h": "1080"} {
+		if got := transport.DefaultPort(scheme); got != want {
+			t.Errorf("defaultPort(%q) = %q, want %q", scheme, got, want)
+		}
+	}
+}
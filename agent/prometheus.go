@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"vaultrix-agent/internal/config"
+)
+
+// prometheusSink publica as metricas num Pushgateway, no formato texto de
+// exposicao. Cada host e um grupo (job "vaultrix", instance = hostname), e
+// cada envio substitui o grupo inteiro (PUT): containers que sumiram nao
+// ficam para tras. O Pushgateway nao aceita timestamps, por isso este sink
+// nao tem spool: reenviar uma amostra antiga a publicaria como atual.
+type prometheusSink struct {
+	cfg  config.Config
+	conf config.SinkConfig
+}
+
+const pushgatewayJob = "vaultrix"
+
+func (s prometheusSink) Name() string { return s.conf.NameOrDefault() }
+
+func (s prometheusSink) Send(payload Payload) error {
+	instance := s.cfg.Hostname
+	if h := payload.Host; h != nil && h.Hostname != "" {
+		instance = h.Hostname
+	}
+	if instance == "" {
+		instance = "unknown"
+	}
+	endpoint := strings.TrimSuffix(s.conf.URL, "/") + "/metrics/job/" + pushgatewayJob + "/instance/" + url.PathEscape(instance)
+	return postSink(s.cfg, "PUT", endpoint, "text/plain; version=0.0.4", s.conf.Headers, prometheusExposition(s.cfg, payload))
+}
+
+// prometheusExposition agrupa os pontos por metrica (vaultrix_host_cpu,
+// vaultrix_container_mem_usage_bytes), como o formato exige. O hostname ja
+// esta no grupo do Pushgateway e nao se repete como label.
+func prometheusExposition(cfg config.Config, payload Payload) []byte {
+	var order []string
+	samples := make(map[string][]string)
+	for _, p := range metricPoints(cfg, payload) {
+		var labels []string
+		for _, t := range p.tags {
+			if t.key == "host" {
+				continue
+			}
+			labels = append(labels, t.key+`="`+prometheusEscape(t.value)+`"`)
+		}
+		set := ""
+		if len(labels) > 0 {
+			set = "{" + strings.Join(labels, ",") + "}"
+		}
+		for _, f := range p.fields {
+			name := p.measurement + "_" + snakeCase(f.key)
+			if _, ok := samples[name]; !ok {
+				order = append(order, name)
+			}
+			samples[name] = append(samples[name], name+set+" "+strconv.FormatFloat(f.value, 'g', -1, 64))
+		}
+	}
+
+	var b strings.Builder
+	for _, name := range order {
+		b.WriteString("# TYPE " + name + " gauge\n")
+		for _, line := range samples[name] {
+			b.WriteString(line + "\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+func prometheusEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// snakeCase converte os nomes camelCase dos campos de container.
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxRabbitMQTimeoutSeconds = 60
+)
+
+func validateRabbitMQInstances(instances []config.RabbitMQInstance) error {
+	seen := make(map[string]bool, len(instances))
+	for i, r := range instances {
+		if !config.SinkNamePattern.MatchString(r.Name) {
+			return fmt.Errorf("rabbitmq[%d]: invalid name %q (lowercase letters, digits, - and _)", i, r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("rabbitmq: duplicate name %q", r.Name)
+		}
+		seen[r.Name] = true
+		u, err := url.Parse(r.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("rabbitmq: %s: url must be an http(s) URL", r.Name)
+		}
+		if r.Username == "" {
+			return fmt.Errorf("rabbitmq: %s: username is required", r.Name)
+		}
+		if slices.Contains(r.VHosts, "") {
+			return fmt.Errorf("rabbitmq: %s: empty vhost; the default vhost is \"/\"", r.Name)
+		}
+		if r.MaxQueues < 0 {
+			return fmt.Errorf("rabbitmq: %s: max_queues must not be negative", r.Name)
+		}
+		if r.TimeoutSeconds < 0 || r.TimeoutSeconds > maxRabbitMQTimeoutSeconds {
+			return fmt.Errorf("rabbitmq: %s: timeout_seconds must be between 0 and %d", r.Name, maxRabbitMQTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// RabbitMQStatus traz as filas e os nos do cluster. Error vem preenchido
+// quando a API nao respondeu, e o resto fica vazio.
+type RabbitMQStatus struct {
+	Queues []RabbitMQQueue `json:"queues,omitempty"`
+
+	// QueuesTruncated diz que havia mais filas que max_queues.
+	QueuesTruncated bool           `json:"queues_truncated,omitempty"`
+	Nodes           []RabbitMQNode `json:"nodes,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+type RabbitMQQueue struct {
+	VHost     string `json:"vhost"`
+	Name      string `json:"name"`
+	State     string `json:"state,omitempty"`
+	Messages  int64  `json:"messages"`
+	Ready     int64  `json:"messages_ready"`
+	Unacked   int64  `json:"messages_unacked"`
+	Consumers int64  `json:"consumers"`
+}
+
+// RabbitMQNode traz os alarmes que bloqueiam os publicadores: memoria acima
+// do limite e disco abaixo do minimo. Os descritores de arquivo nao tem
+// alarme no RabbitMQ; FDPercent mostra quanto falta para o limite.
+type RabbitMQNode struct {
+	Name          string  `json:"name"`
+	Running       bool    `json:"running"`
+	MemUsedBytes  int64   `json:"mem_used_bytes"`
+	MemLimitBytes int64   `json:"mem_limit_bytes"`
+	MemAlarm      bool    `json:"mem_alarm"`
+	FDUsed        int64   `json:"fd_used"`
+	FDTotal       int64   `json:"fd_total"`
+	FDPercent     float64 `json:"fd_percent"`
+	DiskFreeBytes int64   `json:"disk_free_bytes"`
+	DiskFreeAlarm bool    `json:"disk_free_alarm"`
+}
+
+// rabbitMQQueueColumns e rabbitMQNodeColumns pedem a API so os campos
+// usados; sem isso cada fila vem com dezenas de estatisticas.
+const (
+	rabbitMQQueueColumns = "vhost,name,state,messages,messages_ready,messages_unacknowledged,consumers"
+	rabbitMQNodeColumns  = "name,running,mem_used,mem_limit,mem_alarm,fd_used,fd_total,disk_free,disk_free_alarm"
+)
+
+func collectRabbitMQ(instances []config.RabbitMQInstance) map[string]RabbitMQStatus {
+	return collectors.ByName(instances, func(r config.RabbitMQInstance) string { return r.Name }, func(r config.RabbitMQInstance) RabbitMQStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), r.Timeout())
+		defer cancel()
+		s, err := rabbitMQStatus(ctx, r)
+		if err != nil {
+			return RabbitMQStatus{Error: err.Error()}
+		}
+		return s
+	})
+}
+
+func rabbitMQStatus(ctx context.Context, r config.RabbitMQInstance) (RabbitMQStatus, error) {
+	var s RabbitMQStatus
+	paths := []string{"/api/queues"}
+	if len(r.VHosts) > 0 {
+		paths = paths[:0]
+		for _, vhost := range r.VHosts {
+			paths = append(paths, "/api/queues/"+url.PathEscape(vhost))
+		}
+	}
+	for _, path := range paths {
+		var queues []struct {
+			VHost     string `json:"vhost"`
+			Name      string `json:"name"`
+			State     string `json:"state"`
+			Messages  int64  `json:"messages"`
+			Ready     int64  `json:"messages_ready"`
+			Unacked   int64  `json:"messages_unacknowledged"`
+			Consumers int64  `json:"consumers"`
+		}
+		if err := rabbitMQGet(ctx, r, path+"?columns="+rabbitMQQueueColumns, &queues); err != nil {
+			return s, err
+		}
+		for _, q := range queues {
+			s.Queues = append(s.Queues, RabbitMQQueue(q))
+		}
+	}
+	// as mais cheias primeiro, para o corte por max_queues manter as que
+	// importam
+	slices.SortFunc(s.Queues, func(a, b RabbitMQQueue) int {
+		return cmp.Or(cmp.Compare(b.Messages, a.Messages), cmp.Compare(a.VHost, b.VHost), cmp.Compare(a.Name, b.Name))
+	})
+	if len(s.Queues) > r.MaxQueuesOrDefault() {
+		s.Queues, s.QueuesTruncated = s.Queues[:r.MaxQueuesOrDefault()], true
+	}
+
+	var nodes []struct {
+		Name          string `json:"name"`
+		Running       bool   `json:"running"`
+		MemUsed       int64  `json:"mem_used"`
+		MemLimit      int64  `json:"mem_limit"`
+		MemAlarm      bool   `json:"mem_alarm"`
+		FDUsed        int64  `json:"fd_used"`
+		FDTotal       int64  `json:"fd_total"`
+		DiskFree      int64  `json:"disk_free"`
+		DiskFreeAlarm bool   `json:"disk_free_alarm"`
+	}
+	if err := rabbitMQGet(ctx, r, "/api/nodes?columns="+rabbitMQNodeColumns, &nodes); err != nil {
+		return s, err
+	}
+	for _, n := range nodes {
+		node := RabbitMQNode{
+			Name: n.Name, Running: n.Running,
+			MemUsedBytes: n.MemUsed, MemLimitBytes: n.MemLimit, MemAlarm: n.MemAlarm,
+			FDUsed: n.FDUsed, FDTotal: n.FDTotal,
+			DiskFreeBytes: n.DiskFree, DiskFreeAlarm: n.DiskFreeAlarm,
+		}
+		if n.FDTotal > 0 {
+			node.FDPercent = math.Round(float64(n.FDUsed)/float64(n.FDTotal)*10000) / 100
+		}
+		s.Nodes = append(s.Nodes, node)
+	}
+	return s, nil
+}
+
+func rabbitMQGet(ctx context.Context, r config.RabbitMQInstance, path string, v any) error {
+	return localJSON(ctx, "GET", strings.TrimRight(r.URL, "/")+path, nil, func(req *http.Request) {
+		req.SetBasicAuth(r.Username, r.Password)
+	}, v)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateRabbitMQInstances(t *testing.T) {
+	ok := config.RabbitMQInstance{Name: "mq", URL: "http://127.0.0.1:15672", Username: "monitor"}
+	tests := []struct {
+		name    string
+		edit    func(r *config.RabbitMQInstance)
+		wantErr string
+	}{
+		{"ok", func(r *config.RabbitMQInstance) {}, ""},
+		{"vhosts", func(r *config.RabbitMQInstance) { r.VHosts = []string{"/", "orders"} }, ""},
+		{"bad name", func(r *config.RabbitMQInstance) { r.Name = "MQ" }, "invalid name"},
+		{"bad url", func(r *config.RabbitMQInstance) { r.URL = "127.0.0.1:15672" }, "http(s) URL"},
+		{"no username", func(r *config.RabbitMQInstance) { r.Username = "" }, "username is required"},
+		{"empty vhost", func(r *config.RabbitMQInstance) { r.VHosts = []string{""} }, "empty vhost"},
+		{"negative max", func(r *config.RabbitMQInstance) { r.MaxQueues = -1 }, "max_queues"},
+		{"timeout", func(r *config.RabbitMQInstance) { r.TimeoutSeconds = 61 }, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ok
+			tt.edit(&r)
+			err := validateRabbitMQInstances([]config.RabbitMQInstance{r})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeRabbitMQ responde /api/queues[/vhost] e /api/nodes como a API de
+// gerenciamento, exigindo monitor:secret.
+func fakeRabbitMQ(t *testing.T) *httptest.Server {
+	queues := map[string][]string{
+		"/":      {`{"vhost":"/","name":"emails","state":"running","messages":5,"messages_ready":3,"messages_unacknowledged":2,"consumers":1}`},
+		"orders": {`{"vhost":"orders","name":"new","state":"running","messages":120,"messages_ready":120,"messages_unacknowledged":0,"consumers":0}`, `{"vhost":"orders","name":"paid","state":"idle","messages":0,"messages_ready":0,"messages_unacknowledged":0,"consumers":2}`},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "monitor" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !strings.Contains(r.URL.RawQuery, "columns=") {
+			t.Errorf("request without columns: %s", r.URL)
+		}
+		switch path := r.URL.EscapedPath(); {
+		case path == "/api/nodes":
+			fmt.Fprint(w, `[{"name":"rabbit@mq1","running":true,"mem_used":419430400,"mem_limit":838860800,"mem_alarm":false,"fd_used":900,"fd_total":1000,"disk_free":1073741824,"disk_free_alarm":true}]`)
+		case path == "/api/queues":
+			fmt.Fprint(w, "["+strings.Join(append(queues["/"], queues["orders"]...), ",")+"]")
+		case path == "/api/queues/%2F":
+			fmt.Fprint(w, "["+strings.Join(queues["/"], ",")+"]")
+		case path == "/api/queues/orders":
+			fmt.Fprint(w, "["+strings.Join(queues["orders"], ",")+"]")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCollectRabbitMQ(t *testing.T) {
+	srv := fakeRabbitMQ(t)
+	node := RabbitMQNode{
+		Name: "rabbit@mq1", Running: true, MemUsedBytes: 419430400, MemLimitBytes: 838860800,
+		FDUsed: 900, FDTotal: 1000, FDPercent: 90, DiskFreeBytes: 1073741824, DiskFreeAlarm: true,
+	}
+	emails := RabbitMQQueue{VHost: "/", Name: "emails", State: "running", Messages: 5, Ready: 3, Unacked: 2, Consumers: 1}
+	newOrders := RabbitMQQueue{VHost: "orders", Name: "new", State: "running", Messages: 120, Ready: 120}
+	paid := RabbitMQQueue{VHost: "orders", Name: "paid", State: "idle", Consumers: 2}
+
+	got := collectRabbitMQ([]config.RabbitMQInstance{
+		{Name: "all", URL: srv.URL, Username: "monitor", Password: "secret"},
+		{Name: "default-vhost", URL: srv.URL + "/", Username: "monitor", Password: "secret", VHosts: []string{"/"}},
+		{Name: "top", URL: srv.URL, Username: "monitor", Password: "secret", MaxQueues: 2},
+		{Name: "denied", URL: srv.URL, Username: "monitor", Password: "wrong"},
+	})
+	want := map[string]RabbitMQStatus{
+		"all":           {Queues: []RabbitMQQueue{newOrders, emails, paid}, Nodes: []RabbitMQNode{node}},
+		"default-vhost": {Queues: []RabbitMQQueue{emails}, Nodes: []RabbitMQNode{node}},
+		"top":           {Queues: []RabbitMQQueue{newOrders, emails}, QueuesTruncated: true, Nodes: []RabbitMQNode{node}},
+		"denied":        {Error: "/api/queues: status 401"},
+	}
+	for name, w := range want {
+		if !reflect.DeepEqual(got[name], w) {
+			t.Errorf("%s: got %+v, want %+v", name, got[name], w)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxRedisTimeoutSeconds = 60
+	maxRedisReply          = 1 << 20
+)
+
+func validateRedisInstances(instances []config.RedisInstance) error {
+	seen := make(map[string]bool, len(instances))
+	for i, r := range instances {
+		if !config.SinkNamePattern.MatchString(r.Name) {
+			return fmt.Errorf("redis[%d]: invalid name %q (lowercase letters, digits, - and _)", i, r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("redis: duplicate name %q", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Network() == "tcp" {
+			if _, _, err := net.SplitHostPort(r.Address); err != nil {
+				return fmt.Errorf("redis: %s: invalid address %q; use host:port or a socket path", r.Name, r.Address)
+			}
+		}
+		if r.Username != "" && r.Password == "" {
+			return fmt.Errorf("redis: %s: username requires a password", r.Name)
+		}
+		if r.TimeoutSeconds < 0 || r.TimeoutSeconds > maxRedisTimeoutSeconds {
+			return fmt.Errorf("redis: %s: timeout_seconds must be between 0 and %d", r.Name, maxRedisTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// RedisStatus e o INFO de uma instancia. Hits, misses, evictions e
+// expiracoes sao acumulados desde o inicio do servidor; OpsPerSec e a taxa
+// que o proprio Redis calcula. Error vem preenchido quando a instancia nao
+// respondeu, e o resto fica vazio.
+type RedisStatus struct {
+	Version          string  `json:"version,omitempty"`
+	UptimeSeconds    int64   `json:"uptime_seconds,omitempty"`
+	UsedMemoryBytes  int64   `json:"used_memory_bytes,omitempty"`
+	MaxMemoryBytes   int64   `json:"max_memory_bytes,omitempty"`
+	Fragmentation    float64 `json:"mem_fragmentation_ratio,omitempty"`
+	ConnectedClients int64   `json:"connected_clients,omitempty"`
+	BlockedClients   int64   `json:"blocked_clients,omitempty"`
+	OpsPerSec        int64   `json:"ops_per_sec,omitempty"`
+	KeyspaceHits     int64   `json:"keyspace_hits_total,omitempty"`
+	KeyspaceMisses   int64   `json:"keyspace_misses_total,omitempty"`
+	EvictedKeys      int64   `json:"evicted_keys_total,omitempty"`
+	ExpiredKeys      int64   `json:"expired_keys_total,omitempty"`
+	Keys             int64   `json:"keys,omitempty"`
+
+	// Role e master ou slave, como o Redis diz. Numa replica, MasterLinkUp
+	// e MasterLastIOSeconds dizem se ela esta acompanhando o master.
+	Role                string `json:"role,omitempty"`
+	ConnectedReplicas   int64  `json:"connected_replicas,omitempty"`
+	MasterLinkUp        *bool  `json:"master_link_up,omitempty"`
+	MasterLastIOSeconds *int64 `json:"master_last_io_seconds,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// collectRedis le todas as instancias em paralelo, cada uma com o seu prazo.
+func collectRedis(instances []config.RedisInstance) map[string]RedisStatus {
+	return collectors.ByName(instances, func(r config.RedisInstance) string { return r.Name }, func(r config.RedisInstance) RedisStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), r.Timeout())
+		defer cancel()
+		info, err := redisInfo(ctx, r)
+		if err != nil {
+			return RedisStatus{Error: err.Error()}
+		}
+		return parseRedisInfo(info)
+	})
+}
+
+// redisInfo conecta, autentica se preciso e devolve o texto do INFO.
+func redisInfo(ctx context.Context, r config.RedisInstance) (string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, r.Network(), r.Address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	br := bufio.NewReader(conn)
+
+	if r.Password != "" {
+		args := []string{"AUTH", r.Password}
+		if r.Username != "" {
+			args = []string{"AUTH", r.Username, r.Password}
+		}
+		if _, err := redisCommand(conn, br, args...); err != nil {
+			return "", err
+		}
+	}
+	info, err := redisCommand(conn, br, "INFO")
+	if err != nil {
+		return "", err
+	}
+	redisCommand(conn, br, "QUIT")
+	return info, nil
+}
+
+// redisCommand manda um comando em RESP e le uma resposta simples, de erro
+// ou bulk string, que e tudo o que AUTH e INFO devolvem.
+func redisCommand(w io.Writer, br *bufio.Reader, args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return "", err
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 || n > maxRedisReply {
+			return "", errors.New("redis: invalid bulk reply")
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+	return "", fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+func parseRedisInfo(info string) RedisStatus {
+	values := map[string]string{}
+	var keys int64
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// keyspace: "db0:keys=12,expires=3,avg_ttl=0"
+		if strings.HasPrefix(key, "db") && strings.HasPrefix(value, "keys=") {
+			n, _ := strconv.ParseInt(strings.TrimPrefix(strings.Split(value, ",")[0], "keys="), 10, 64)
+			keys += n
+			continue
+		}
+		values[key] = value
+	}
+	num := func(key string) int64 {
+		n, _ := strconv.ParseInt(values[key], 10, 64)
+		return n
+	}
+	s := RedisStatus{
+		Version:           values["redis_version"],
+		UptimeSeconds:     num("uptime_in_seconds"),
+		UsedMemoryBytes:   num("used_memory"),
+		MaxMemoryBytes:    num("maxmemory"),
+		ConnectedClients:  num("connected_clients"),
+		BlockedClients:    num("blocked_clients"),
+		OpsPerSec:         num("instantaneous_ops_per_sec"),
+		KeyspaceHits:      num("keyspace_hits"),
+		KeyspaceMisses:    num("keyspace_misses"),
+		EvictedKeys:       num("evicted_keys"),
+		ExpiredKeys:       num("expired_keys"),
+		Keys:              keys,
+		Role:              values["role"],
+		ConnectedReplicas: num("connected_slaves"),
+	}
+	s.Fragmentation, _ = strconv.ParseFloat(values["mem_fragmentation_ratio"], 64)
+	if link, ok := values["master_link_status"]; ok {
+		up := link == "up"
+		s.MasterLinkUp = &up
+		// -1 quando a replica nunca falou com o master
+		if lastIO := num("master_last_io_seconds_ago"); lastIO >= 0 {
+			s.MasterLastIOSeconds = &lastIO
+		}
+	}
+	return s
+}
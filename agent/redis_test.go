@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+const testRedisInfo = "# Server\r\nredis_version:7.2.4\r\nuptime_in_seconds:86400\r\n\r\n" +
+	"# Clients\r\nconnected_clients:12\r\nblocked_clients:1\r\n\r\n" +
+	"# Memory\r\nused_memory:1048576\r\nmaxmemory:4194304\r\nmem_fragmentation_ratio:1.25\r\n\r\n" +
+	"# Stats\r\ninstantaneous_ops_per_sec:350\r\nkeyspace_hits:900\r\nkeyspace_misses:100\r\n" +
+	"evicted_keys:5\r\nexpired_keys:40\r\n\r\n" +
+	"# Replication\r\nrole:master\r\nconnected_slaves:2\r\n\r\n" +
+	"# Keyspace\r\ndb0:keys=10,expires=2,avg_ttl=0\r\ndb3:keys=5,expires=0,avg_ttl=0\r\n"
+
+func TestParseRedisInfo(t *testing.T) {
+	up, down := true, false
+	lastIO := int64(1)
+	tests := []struct {
+		name string
+		info string
+		want RedisStatus
+	}{
+		{
+			name: "master",
+			info: testRedisInfo,
+			want: RedisStatus{
+				Version: "7.2.4", UptimeSeconds: 86400,
+				UsedMemoryBytes: 1048576, MaxMemoryBytes: 4194304, Fragmentation: 1.25,
+				ConnectedClients: 12, BlockedClients: 1, OpsPerSec: 350,
+				KeyspaceHits: 900, KeyspaceMisses: 100, EvictedKeys: 5, ExpiredKeys: 40,
+				Keys: 15, Role: "master", ConnectedReplicas: 2,
+			},
+		},
+		{
+			name: "replica in sync",
+			info: "role:slave\r\nmaster_link_status:up\r\nmaster_last_io_seconds_ago:1\r\n",
+			want: RedisStatus{Role: "slave", MasterLinkUp: &up, MasterLastIOSeconds: &lastIO},
+		},
+		{
+			name: "replica never connected",
+			info: "role:slave\r\nmaster_link_status:down\r\nmaster_last_io_seconds_ago:-1\r\n",
+			want: RedisStatus{Role: "slave", MasterLinkUp: &down},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRedisInfo(tt.info); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRedisInstances(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances []config.RedisInstance
+		wantErr   string
+	}{
+		{"tcp", []config.RedisInstance{{Name: "cache", Address: "127.0.0.1:6379"}}, ""},
+		{"socket", []config.RedisInstance{{Name: "cache", Address: "/run/redis/redis.sock"}}, ""},
+		{"acl", []config.RedisInstance{{Name: "cache", Address: "127.0.0.1:6379", Username: "monitor", Password: "x"}}, ""},
+		{"bad name", []config.RedisInstance{{Name: "Cache", Address: "127.0.0.1:6379"}}, "invalid name"},
+		{"no port", []config.RedisInstance{{Name: "cache", Address: "127.0.0.1"}}, "invalid address"},
+		{"duplicate", []config.RedisInstance{{Name: "a", Address: "h:1"}, {Name: "a", Address: "h:2"}}, "duplicate name"},
+		{"username alone", []config.RedisInstance{{Name: "a", Address: "h:1", Username: "u"}}, "requires a password"},
+		{"timeout", []config.RedisInstance{{Name: "a", Address: "h:1", TimeoutSeconds: 61}}, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRedisInstances(tt.instances)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeRedis responde AUTH e INFO em RESP, exigindo a senha quando ha uma.
+func fakeRedis(t *testing.T, user, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeRedis(conn, user, password)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFakeRedis(conn net.Conn, user, password string) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	authed := password == ""
+	for {
+		args, err := readRESPArray(br)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			gotUser, gotPass := "default", args[len(args)-1]
+			if len(args) == 3 {
+				gotUser = args[1]
+			}
+			if gotPass != password || (user != "" && gotUser != user) {
+				fmt.Fprint(conn, "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case "INFO":
+			if !authed {
+				fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+				continue
+			}
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(testRedisInfo), testRedisInfo)
+		case "QUIT":
+			fmt.Fprint(conn, "+OK\r\n")
+			return
+		}
+	}
+}
+
+func readRESPArray(br *bufio.Reader) ([]string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad array %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimRight(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestCollectRedis(t *testing.T) {
+	open := fakeRedis(t, "", "")
+	protected := fakeRedis(t, "", "secret")
+	acl := fakeRedis(t, "monitor", "secret")
+
+	instances := []config.RedisInstance{
+		{Name: "open", Address: open},
+		{Name: "password", Address: protected, Password: "secret"},
+		{Name: "acl", Address: acl, Username: "monitor", Password: "secret"},
+		{Name: "wrong", Address: protected, Password: "nope"},
+		{Name: "noauth", Address: protected},
+	}
+	got := collectRedis(instances)
+
+	for _, name := range []string{"open", "password", "acl"} {
+		if s := got[name]; s.Error != "" || s.Version != "7.2.4" || s.Keys != 15 {
+			t.Errorf("%s: got %+v", name, s)
+		}
+	}
+	errors := map[string]string{"wrong": "WRONGPASS", "noauth": "NOAUTH"}
+	for name, want := range errors {
+		if s := got[name]; !strings.Contains(s.Error, want) || s.Version != "" {
+			t.Errorf("%s: got %+v, want error containing %q", name, s, want)
+		}
+	}
+}
+
+func TestRedisUnderApps(t *testing.T) {
+	got := appsJSON(t, config.CollectorRedis, map[string]RedisStatus{"cache": {Version: "7.2.4"}}, nil)
+	if !strings.HasPrefix(got, `{"redis":{"cache":{"version":"7.2.4"`) {
+		t.Errorf("apps = %s", got)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const remoteConfigTimeout = 10 * time.Second
+
+// remoteConfigFields sao as chaves que a configuracao remota pode alterar.
+// Token, URLs, proxy e diretorio de plugins ficam sempre com o arquivo
+// local: um erro no servidor nao pode desconectar nem executar binarios
+// arbitrarios no agente.
+var remoteConfigFields = []string{
+	"interval_min",
+	"splay_seconds",
+	"collectors",
+	"flags",
+	"containers",
+	"container_rollups",
+	"stats_tiers",
+	"hostname",
+}
+
+// remoteConfigCache guarda a ultima resposta valida e seu ETag.
+type remoteConfigCache struct {
+	ETag      string          `json:"etag,omitempty"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Config    json.RawMessage `json:"config"`
+}
+
+func remoteConfigCachePath(stateDir string) string {
+	return filepath.Join(stateDir, "remote-config.json")
+}
+
+// applyRemoteConfig busca a configuracao remota (com If-None-Match) e a
+// aplica sobre cfg. Em qualquer falha de rede, vale a ultima copia em cache;
+// sem cache, cfg segue como esta.
+func applyRemoteConfig(cfg config.Config, stateDir string) config.Config {
+	if !cfg.RemoteConfig {
+		return cfg
+	}
+
+	cache, _ := loadRemoteConfigCache(stateDir)
+	fresh, err := fetchRemoteConfig(cfg, cache)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "remote config: %v\n", err)
+	} else if fresh != nil {
+		cache = fresh
+		if err := saveRemoteConfigCache(stateDir, cache); err != nil {
+			fmt.Fprintf(os.Stderr, "remote config: %v\n", err)
+		}
+	}
+	if cache == nil || len(cache.Config) == 0 {
+		return cfg
+	}
+
+	merged, err := mergeRemoteConfig(cfg, cache.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "remote config ignored: %v\n", err)
+		return cfg
+	}
+	return merged
+}
+
+// fetchRemoteConfig devolve nil, nil quando o servidor responde 304.
+func fetchRemoteConfig(cfg config.Config, cache *remoteConfigCache) (*remoteConfigCache, error) {
+	return callAPI(cfg, cfg.RemoteConfigURLFor, func(endpoint string) (*remoteConfigCache, error) {
+		return fetchRemoteConfigFrom(cfg, endpoint, cache)
+	})
+}
+
+func fetchRemoteConfigFrom(cfg config.Config, endpoint string, cache *remoteConfigCache) (*remoteConfigCache, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	req.Header.Set("Accept", "application/json")
+	if cache != nil && cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = remoteConfigTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &apiError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("invalid JSON from %s", endpoint)
+	}
+	return &remoteConfigCache{ETag: resp.Header.Get("ETag"), FetchedAt: time.Now().UTC(), Config: body}, nil
+}
+
+// mergeRemoteConfig sobrepoe a cfg apenas as chaves permitidas e valida o
+// resultado.
+func mergeRemoteConfig(cfg config.Config, raw json.RawMessage) (config.Config, error) {
+	var remote map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &remote); err != nil {
+		return cfg, err
+	}
+	allowed := make(map[string]json.RawMessage)
+	for _, key := range remoteConfigFields {
+		if v, ok := remote[key]; ok {
+			allowed[key] = v
+		}
+	}
+	if len(allowed) == 0 {
+		return cfg, nil
+	}
+
+	b, err := json.Marshal(allowed)
+	if err != nil {
+		return cfg, err
+	}
+	merged := cfg
+	// collectors e mesclado chave a chave sobre o local (arquivo e perfil),
+	// para o servidor ligar um coletor sem religar os que o perfil desliga;
+	// flags e os demais mapas e slices sao substituidos
+	merged.Collectors = maps.Clone(cfg.Collectors)
+	if _, ok := allowed["flags"]; ok {
+		merged.Flags = nil
+	}
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return cfg, err
+	}
+	if err := validateConfig(merged); err != nil {
+		return cfg, err
+	}
+	return merged, nil
+}
+
+func loadRemoteConfigCache(stateDir string) (*remoteConfigCache, error) {
+	b, err := os.ReadFile(remoteConfigCachePath(stateDir))
+	if err != nil {
+		return nil, err
+	}
+	var cache remoteConfigCache
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+func saveRemoteConfigCache(stateDir string, cache *remoteConfigCache) error {
+	if err := fsutil.EnsureDir(stateDir); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(remoteConfigCachePath(stateDir), b, 0o600)
+}
+
+// syncSchedulerInterval reagenda o agente quando a configuracao remota muda o
+// intervalo; no modo cron o intervalo fica gravado no arquivo do cron.
+func syncSchedulerInterval(local, effective config.Config, configPath string) {
+	if local.Interval == effective.Interval {
+		return
+	}
+	if _, installed := installedScheduler(); !installed {
+		return
+	}
+	if err := installScheduler(effective, agentBinaryPath, configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "remote config: reschedule: %v\n", err)
+	}
+}
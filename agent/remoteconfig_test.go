@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestMergeRemoteConfig(t *testing.T) {
+	local := config.Config{
+		Token:      "local-token",
+		ApiURL:     "https://vaultrix.example.com/api/telemetry",
+		Interval:   5,
+		Collectors: map[string]bool{"docker": false, "disks": false},
+	}
+	tests := []struct {
+		name   string
+		remote string
+		want   func(config.Config) config.Config
+	}{
+		{
+			name:   "allowed keys",
+			remote: `{"interval_min": 1, "containers": {"exclude": ["k8s_*"]}}`,
+			want: func(c config.Config) config.Config {
+				c.Interval = 1
+				c.ContainerFilter = config.ContainerFilter{Exclude: []string{"k8s_*"}}
+				return c
+			},
+		},
+		{
+			// token, URLs e proxy ficam sempre com o arquivo local
+			name:   "ignores local-only keys",
+			remote: `{"token": "x", "api_url": "https://evil.example.com", "proxy_url": "http://evil:3128", "plugins_dir": "/tmp"}`,
+			want:   func(c config.Config) config.Config { return c },
+		},
+		{
+			// o servidor liga um coletor sem religar os que o arquivo desliga
+			name:   "collectors merge over the local map",
+			remote: `{"collectors": {"docker": true}}`,
+			want: func(c config.Config) config.Config {
+				c.Collectors = map[string]bool{"docker": true, "disks": false}
+				return c
+			},
+		},
+		{
+			name:   "invalid result keeps the local config",
+			remote: `{"interval_min": 1, "container_rollups": "sometimes"}`,
+			want:   func(c config.Config) config.Config { return c },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := mergeRemoteConfig(local, []byte(tt.remote))
+			if want := tt.want(local); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+	if _, err := mergeRemoteConfig(local, []byte(`{"container_rollups": "sometimes"}`)); err == nil {
+		t.Error("want an error for an invalid remote config")
+	}
+}
+
+func TestApplyRemoteConfig(t *testing.T) {
+	var requests int
+	up := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !up {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/api/agent/config" || r.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"interval_min": 15}`))
+	}))
+	defer srv.Close()
+
+	stateDir := t.TempDir()
+	cfg := config.Config{Token: "t", ApiURL: srv.URL + "/api/telemetry", Interval: 5, RemoteConfig: true}
+	// a primeira busca grava o cache; a segunda recebe 304 e usa o cache;
+	// com a API fora, o cache continua valendo
+	for i, state := range []bool{true, true, false} {
+		up = state
+		if got := applyRemoteConfig(cfg, stateDir); got.Interval != 15 {
+			t.Errorf("fetch %d: interval = %d, want 15", i+1, got.Interval)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+	cache, err := loadRemoteConfigCache(stateDir)
+	if err != nil || cache.ETag != `"v1"` {
+		t.Errorf("cache = %+v, %v", cache, err)
+	}
+
+	cfg.RemoteConfig = false
+	if got := applyRemoteConfig(cfg, stateDir); got.Interval != 5 {
+		t.Errorf("disabled: interval = %d, want 5", got.Interval)
+	}
+}
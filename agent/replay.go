@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// replayRetries e quantas vezes um payload e reenviado enquanto a API
+// responde com 5xx/429 ou esta inacessivel.
+const replayRetries = 5
+
+// runReplayCommand reenvia payloads guardados (spool ou copias dele) com os
+// timestamps originais, por exemplo para popular uma instancia nova do
+// backend. Os arquivos nao sao apagados.
+func runReplayCommand(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	from := fs.String("from", "", tr("Directory or file with the payloads to resend"))
+	to := fs.String("to", "", tr("API URL to send to (default: api_url from the config)"))
+	token := fs.String("token", "", tr("Machine token (default: token from the config)"))
+	speed := fs.String("speed", "max", tr("Replay speed relative to the original pace (e.g. 1x, 10x) or max"))
+	fs.Parse(args)
+
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, tr("usage: vaultrix-agent replay --from <dir> [--to <api-url>] [--token <token>] [--speed 10x|max]"))
+		os.Exit(2)
+	}
+	factor, err := parseReplaySpeed(*speed)
+	if err != nil {
+		fatal(err)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil && (*to == "" || *token == "") {
+		fatal(err)
+	}
+	if *to != "" {
+		cfg.ApiURL = *to
+		cfg.ApiURLs = nil
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+
+	items, err := replayItems(*from)
+	if err != nil {
+		fatal(err)
+	}
+
+	sent, skipped := 0, 0
+	var previous time.Time
+	for _, item := range items {
+		payload, err := item.payload()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: skipping %s: %v\n", item.name, err)
+			skipped++
+			continue
+		}
+		if factor > 0 && !previous.IsZero() && payload.Timestamp.After(previous) {
+			time.Sleep(time.Duration(float64(payload.Timestamp.Sub(previous)) / factor))
+		}
+		previous = payload.Timestamp
+
+		payload.Token = cfg.Token
+		payload.Replayed = true
+		if err := replayPayload(cfg, payload); err != nil {
+			if spoolable(err) || classifyError(err) == errKindConfig {
+				fmt.Println(trf("Replayed %d of %d payloads; stopped at %s.", sent, len(items), item.name))
+				fatal(err)
+			}
+			fmt.Fprintf(os.Stderr, "replay: skipping %s: %v\n", item.name, err)
+			skipped++
+			continue
+		}
+		sent++
+	}
+	fmt.Println(trf("Replayed %d of %d payloads (%d skipped).", sent, len(items), skipped))
+}
+
+// replayPayload envia sem aplicar a resposta: uma rotacao de token vinda de
+// outro backend nao pode regravar o config local.
+func replayPayload(cfg config.Config, payload Payload) error {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		_, err := postPayload(cfg, payload)
+		if err == nil || !spoolable(err) || attempt == replayRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// replayItem e um payload a reenviar: um arquivo do spool ou uma linha de
+// um arquivo do sink file (JSONL), ja lida.
+type replayItem struct {
+	name string
+	path string
+	line []byte
+}
+
+func (r replayItem) payload() (Payload, error) {
+	if r.line == nil {
+		return readSpooled(r.path)
+	}
+	var p Payload
+	err := json.Unmarshal(r.line, &p)
+	return p, err
+}
+
+// replayItems lista os payloads em ordem cronologica, que e a ordem dos
+// nomes no spool e nos arquivos rodados do sink file.
+func replayItems(from string) ([]replayItem, error) {
+	info, err := os.Stat(from)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{from}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.json", "*.jsonl"} {
+			matches, err := filepath.Glob(filepath.Join(from, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+
+	var items []replayItem
+	for _, path := range files {
+		if filepath.Ext(path) != ".jsonl" {
+			items = append(items, replayItem{name: filepath.Base(path), path: path})
+			continue
+		}
+		lines, err := readJSONLines(path)
+		if err != nil {
+			return nil, err
+		}
+		for i, line := range lines {
+			items = append(items, replayItem{name: fmt.Sprintf("%s:%d", filepath.Base(path), i+1), path: path, line: line})
+		}
+	}
+	return items, nil
+}
+
+// readJSONLines le as linhas nao vazias de um arquivo JSONL.
+func readJSONLines(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			lines = append(lines, bytes.Clone(line))
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseReplaySpeed aceita "max" (sem espera entre envios), "10x" ou "10".
+func parseReplaySpeed(s string) (float64, error) {
+	if s == "max" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || f <= 0 {
+		return 0, errors.New("invalid --speed: use e.g. 1x, 10x or max")
+	}
+	return f, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayItems(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"payloads.jsonl":                      `{"agent_version":"3"}` + "\n",
+		"payloads-20240101T000000.000Z.jsonl": `{"agent_version":"1"}` + "\n\n" + `{"agent_version":"2"}` + "\n",
+		"notes.txt":                           "not a payload",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := replayItems(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ name, version string }{
+		{"payloads-20240101T000000.000Z.jsonl:1", "1"},
+		{"payloads-20240101T000000.000Z.jsonl:2", "2"},
+		{"payloads.jsonl:1", "3"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("items = %+v, want %d", items, len(want))
+	}
+	for i, w := range want {
+		p, err := items[i].payload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items[i].name != w.name || p.AgentVersion != w.version {
+			t.Errorf("item %d = %s (version %s), want %s (version %s)", i, items[i].name, p.AgentVersion, w.name, w.version)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Labels que o docker compose (e o podman-compose) poe em cada container.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// Modos aceitos em container_rollups.
+const (
+	rollupsOn = "on"
+
+	rollupsOnly = "only"
+)
+
+// ContainerRollup soma o consumo de todos os containers de uma mesma imagem
+// ou projeto compose.
+type ContainerRollup struct {
+	Key           string  `json:"key"`
+	Containers    int     `json:"containers"`
+	Running       int     `json:"running"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemUsageBytes int64   `json:"memUsageBytes"`
+	MemPercent    float64 `json:"memPercent"`
+}
+
+type Rollups struct {
+	Images   []ContainerRollup `json:"images"`
+	Projects []ContainerRollup `json:"projects"`
+}
+
+func validateRollupsMode(mode string) error {
+	switch mode {
+	case "", rollupsOn, config.RollupsOff, rollupsOnly:
+		return nil
+	}
+	return fmt.Errorf("invalid container_rollups %q (use on, off or only)", mode)
+}
+
+func buildRollups(containers []ContainerStatus) *Rollups {
+	images := make(map[string]*ContainerRollup)
+	projects := make(map[string]*ContainerRollup)
+
+	add := func(groups map[string]*ContainerRollup, key string, c ContainerStatus) {
+		r, ok := groups[key]
+		if !ok {
+			r = &ContainerRollup{Key: key}
+			groups[key] = r
+		}
+		r.Containers++
+		if c.State == "running" {
+			r.Running++
+		}
+		r.CPUPercent += c.CPUPercent
+		r.MemPercent += c.MemPercent
+		r.MemUsageBytes += c.MemUsageBytes
+	}
+
+	for _, c := range containers {
+		if c.Image != "" {
+			add(images, c.Image, c)
+		}
+		if c.Project != "" {
+			add(projects, c.Project, c)
+		}
+	}
+
+	return &Rollups{Images: sortedRollups(images), Projects: sortedRollups(projects)}
+}
+
+func sortedRollups(groups map[string]*ContainerRollup) []ContainerRollup {
+	out := make([]ContainerRollup, 0, len(groups))
+	for _, r := range groups {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// setStatBytes preenche os campos em bytes a partir das strings do docker
+// stats, para as fontes que so informam as strings (CLI do docker, podman).
+func (c *ContainerStatus) setStatBytes() {
+	c.MemUsageBytes, c.MemLimitBytes = parseByteSizePair(c.MemUsage)
+	c.NetRxBytes, c.NetTxBytes = parseByteSizePair(c.NetIO)
+	c.BlockReadBytes, c.BlockWriteBytes = parseByteSizePair(c.BlockIO)
+}
+
+// parseByteSizePair interpreta pares como o "1.2GiB / 4GiB" do docker stats.
+func parseByteSizePair(value string) (first, second int64) {
+	a, b, _ := strings.Cut(value, "/")
+	return parseByteSize(a), parseByteSize(b)
+}
+
+// parseByteSize converte tamanhos no formato do docker (B, kB, MB, MiB,
+// GiB...) em bytes.
+func parseByteSize(value string) int64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	i := 0
+	for i < len(value) && (value[i] == '.' || value[i] == ',' || (value[i] >= '0' && value[i] <= '9')) {
+		i++
+	}
+	n := parseFloat(value[:i])
+	unit := strings.ToLower(strings.TrimSpace(value[i:]))
+
+	multipliers := map[string]float64{
+		"": 1, "b": 1,
+		"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40, "pib": 1 << 50,
+	}
+	m, ok := multipliers[unit]
+	if !ok {
+		return 0
+	}
+	return int64(n * m)
+}
+
+// formatBinaryBytes e formatDecimalBytes reproduzem a formatacao do docker
+// stats (memoria em KiB/MiB, rede e disco em kB/MB), para que os valores
+// lidos dos cgroups sigam o mesmo formato.
+func formatBinaryBytes(n uint64) string {
+	return formatBytes(float64(n), 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}, 4)
+}
+
+func formatDecimalBytes(n uint64) string {
+	return formatBytes(float64(n), 1000, []string{"B", "kB", "MB", "GB", "TB", "PB"}, 3)
+}
+
+func formatBytes(size, base float64, units []string, precision int) string {
+	i := 0
+	for size >= base && i < len(units)-1 {
+		size /= base
+		i++
+	}
+	return fmt.Sprintf("%.*g%s", precision, size, units[i])
+}
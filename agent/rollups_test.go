@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSetStatBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ContainerStatus
+		want [6]int64 // mem usado/limite, rede rx/tx, disco leitura/escrita
+	}{
+		{
+			name: "docker stats",
+			in:   ContainerStatus{MemUsage: "1.5GiB / 4GiB", NetIO: "1.2kB / 648B", BlockIO: "12.3MB / 0B"},
+			want: [6]int64{3 << 29, 4 << 30, 1200, 648, 12300000, 0},
+		},
+		{
+			name: "podman units",
+			in:   ContainerStatus{MemUsage: "512KiB / 1MiB", NetIO: "2MB / 3MB", BlockIO: "1TB / 1GB"},
+			want: [6]int64{512 << 10, 1 << 20, 2e6, 3e6, 1e12, 1e9},
+		},
+		{
+			name: "missing and unknown",
+			in:   ContainerStatus{MemUsage: "--", NetIO: ""},
+		},
+	}
+	for _, tt := range tests {
+		c := tt.in
+		c.setStatBytes()
+		got := [6]int64{c.MemUsageBytes, c.MemLimitBytes, c.NetRxBytes, c.NetTxBytes, c.BlockReadBytes, c.BlockWriteBytes}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRollupsProjects(t *testing.T) {
+	containers := []ContainerStatus{
+		{Name: "shop-web-1", Image: "nginx", State: "running", Project: "shop", Service: "web", MemUsageBytes: 100},
+		{Name: "shop-db-1", Image: "postgres", State: "exited", Project: "shop", Service: "db", MemUsageBytes: 50},
+		{Name: "blog", Image: "nginx", State: "running", MemUsageBytes: 10},
+	}
+	r := buildRollups(containers)
+	if len(r.Projects) != 1 {
+		t.Fatalf("projects = %+v, want only shop", r.Projects)
+	}
+	if p := r.Projects[0]; p.Key != "shop" || p.Containers != 2 || p.Running != 1 || p.MemUsageBytes != 150 {
+		t.Errorf("shop = %+v", p)
+	}
+	if len(r.Images) != 2 || r.Images[0].Key != "nginx" || r.Images[0].Containers != 2 {
+		t.Errorf("images = %+v", r.Images)
+	}
+}
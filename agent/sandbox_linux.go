@@ -0,0 +1,77 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+	"vaultrix-agent/internal/config"
+)
+
+// pluginCommand devolve o comando que inicia o plugin ja limitado. O agente
+// se reexecuta como "plugin-exec", aplica os limites em si mesmo e so entao
+// faz exec do plugin, que os herda desde a primeira instrucao; aplicar com
+// prlimit depois do start deixava o inicio do plugin sem limite.
+func pluginCommand(path string, limits config.PluginLimits) (string, []string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", nil, err
+	}
+	return exe, []string{
+		"plugin-exec",
+		strconv.Itoa(limits.MemoryMB),
+		strconv.Itoa(limits.CPUSeconds),
+		strconv.Itoa(limits.MaxOpenFiles),
+		path,
+	}, nil
+}
+
+// runPluginExec e o lado do "plugin-exec": args sao memoria, CPU, arquivos
+// e o caminho do plugin. So volta em caso de erro.
+func runPluginExec(args []string) {
+	if len(args) != 4 {
+		fatal(errors.New("usage: vaultrix-agent plugin-exec <memory_mb> <cpu_seconds> <max_open_files> <plugin>"))
+	}
+	var values [3]int
+	for i := range values {
+		v, err := strconv.Atoi(args[i])
+		if err != nil || v < 0 {
+			fatal(fmt.Errorf("plugin-exec: invalid limit %q", args[i]))
+		}
+		values[i] = v
+	}
+	limits := config.PluginLimits{MemoryMB: values[0], CPUSeconds: values[1], MaxOpenFiles: values[2]}
+	if err := applyPluginLimits(0, limits); err != nil {
+		fatal(err)
+	}
+	path := args[3]
+	fatal(unix.Exec(path, []string{path}, os.Environ()))
+}
+
+// applyPluginLimits restringe o processo pid (0 e o proprio processo):
+// memoria de dados, tempo de CPU, arquivos abertos e prioridade baixa.
+func applyPluginLimits(pid int, limits config.PluginLimits) error {
+	set := func(resource int, value uint64) error {
+		if value == 0 {
+			return nil
+		}
+		return unix.Prlimit(pid, resource, &unix.Rlimit{Cur: value, Max: value}, nil)
+	}
+	if err := set(unix.RLIMIT_DATA, uint64(limits.MemoryMB)*1024*1024); err != nil {
+		return fmt.Errorf("limit memory: %w", err)
+	}
+	if err := set(unix.RLIMIT_CPU, uint64(limits.CPUSeconds)); err != nil {
+		return fmt.Errorf("limit cpu: %w", err)
+	}
+	if err := set(unix.RLIMIT_NOFILE, uint64(limits.MaxOpenFiles)); err != nil {
+		return fmt.Errorf("limit files: %w", err)
+	}
+	if err := unix.Setpriority(unix.PRIO_PROCESS, pid, 10); err != nil {
+		return fmt.Errorf("set priority: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+// TestPluginExecHelper faz o papel do agente reexecutado como plugin-exec;
+// so roda quando chamado por TestPluginLimitsBeforeExec.
+func TestPluginExecHelper(t *testing.T) {
+	if os.Getenv("VAULTRIX_TEST_PLUGIN_EXEC") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	runPluginExec(args[1:])
+}
+
+func TestPluginLimitsBeforeExec(t *testing.T) {
+	// a primeira coisa que o plugin faz e ler os proprios limites
+	plugin := filepath.Join(t.TempDir(), "limits")
+	script := "#!/bin/sh\necho \"$(ulimit -d) $(ulimit -t) $(ulimit -n)\"\n"
+	if err := os.WriteFile(plugin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := config.PluginLimits{MemoryMB: 128, CPUSeconds: 5, MaxOpenFiles: 32}
+	_, args, err := pluginCommand(plugin, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != "plugin-exec" {
+		t.Fatalf("args = %v", args)
+	}
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestPluginExecHelper$", "--"}, args[1:]...)...)
+	cmd.Env = append(os.Environ(), "VAULTRIX_TEST_PLUGIN_EXEC=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	// ulimit -d e em KiB
+	if got, want := strings.TrimSpace(string(out)), "131072 5 32"; got != want {
+		t.Errorf("plugin saw limits %q, want %q", got, want)
+	}
+}
+
+func TestPluginExecDisabledLimit(t *testing.T) {
+	plugin := filepath.Join(t.TempDir(), "limits")
+	if err := os.WriteFile(plugin, []byte("#!/bin/sh\nulimit -t\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	want, err := exec.Command("sh", "-c", "ulimit -t").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// valores negativos desligam o limite; o plugin herda o do agente
+	_, args, err := pluginCommand(plugin, config.PluginLimits{CPUSeconds: -1}.WithDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestPluginExecHelper$", "--"}, args[1:]...)...)
+	cmd.Env = append(os.Environ(), "VAULTRIX_TEST_PLUGIN_EXEC=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if string(out) != string(want) {
+		t.Errorf("cpu limit = %q, want the agent's %q", out, want)
+	}
+}
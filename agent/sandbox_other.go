@@ -0,0 +1,20 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Os limites de recursos so sao suportados no Linux (setrlimit antes do
+// exec); nas demais plataformas o plugin fica limitado apenas pelo prazo de
+// execucao.
+func pluginCommand(path string, limits config.PluginLimits) (string, []string, error) {
+	return path, nil, nil
+}
+
+func runPluginExec(args []string) {
+	fatal(errors.New("plugin-exec is only supported on Linux"))
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxScrapeTimeoutSeconds = 60
+
+	maxScrapeBody = 10 << 20
+)
+
+// ScrapeResult e o que um alvo devolveu no ciclo. Error vem preenchido
+// quando o endpoint nao respondeu ou respondeu fora de 2xx; linhas que nao
+// sao do formato texto do Prometheus sao ignoradas.
+type ScrapeResult struct {
+	Samples   []ScrapedSample `json:"samples"`
+	Truncated int             `json:"truncated,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// ScrapedSample e uma serie lida do endpoint. NaN e infinitos, que o JSON
+// nao representa, ficam de fora.
+type ScrapedSample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+func validateScrapeTargets(targets []config.ScrapeTarget) error {
+	seen := make(map[string]bool, len(targets))
+	for i, s := range targets {
+		if !config.SinkNamePattern.MatchString(s.Name) {
+			return fmt.Errorf("scrape[%d]: invalid name %q (lowercase letters, digits, - and _)", i, s.Name)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("scrape: duplicate name %q", s.Name)
+		}
+		seen[s.Name] = true
+
+		u, err := url.Parse(s.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("scrape: %s: invalid url %q", s.Name, s.URL)
+		}
+		if len(s.Metrics) == 0 {
+			return fmt.Errorf("scrape: %s: metrics is required; list the metric names to forward", s.Name)
+		}
+		for _, pattern := range s.Metrics {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return fmt.Errorf("scrape: %s: invalid metric pattern %q", s.Name, pattern)
+			}
+		}
+		if s.TimeoutSeconds < 0 || s.TimeoutSeconds > maxScrapeTimeoutSeconds {
+			return fmt.Errorf("scrape: %s: timeout_seconds must be between 0 and %d", s.Name, maxScrapeTimeoutSeconds)
+		}
+		if s.MaxSamples < 0 {
+			return fmt.Errorf("scrape: %s: max_samples must not be negative", s.Name)
+		}
+		for name := range s.Headers {
+			if name == "" || strings.ContainsAny(name, ": \r\n") {
+				return fmt.Errorf("scrape: %s: invalid header name %q", s.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// scrapeTargets le todos os alvos em paralelo, cada um com o seu prazo.
+func scrapeTargets(targets []config.ScrapeTarget) map[string]ScrapeResult {
+	return collectors.ByName(targets, func(s config.ScrapeTarget) string { return s.Name }, scrapeTarget)
+}
+
+// scrapeClient nao usa proxy: os alvos sao locais.
+var scrapeClient = &http.Client{
+	Transport: &http.Transport{
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// localJSON chama uma API JSON local (RabbitMQ, Elasticsearch, Jolokia) com
+// o cliente do scrape. body, quando existe, vai serializado; auth poe as
+// credenciais no pedido.
+func localJSON(ctx context.Context, method, rawURL string, body any, auth func(*http.Request), v any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth != nil {
+		auth(req)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "vaultrix-agent/"+version)
+	resp, err := scrapeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		return fmt.Errorf("%s: status %d", req.URL.Path, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxScrapeBody)).Decode(v)
+}
+
+func scrapeTarget(s config.ScrapeTarget) ScrapeResult {
+	res := ScrapeResult{Samples: []ScrapedSample{}}
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	// so o formato texto; sem isso alguns exportadores respondem protobuf
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	req.Header.Set("User-Agent", "vaultrix-agent/"+version)
+	for name, value := range s.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := scrapeClient.Do(req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		res.Error = fmt.Sprintf("status %d", resp.StatusCode)
+		return res
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxScrapeBody+1))
+	if err == nil && len(body) > maxScrapeBody {
+		err = fmt.Errorf("response exceeds %d MB", maxScrapeBody>>20)
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	if err := parseExposition(body, s.Allowed, s.MaxSamplesOrDefault(), &res); err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// parseExposition junta a res as amostras aceitas por allowed, ate limit;
+// as que passarem contam em Truncated.
+func parseExposition(body []byte, allowed func(name string) bool, limit int, res *ScrapeResult) error {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		sample, ok := parseExpositionLine(scanner.Text())
+		if !ok || !allowed(sample.Name) {
+			continue
+		}
+		if len(res.Samples) >= limit {
+			res.Truncated++
+			continue
+		}
+		res.Samples = append(res.Samples, sample)
+	}
+	return scanner.Err()
+}
+
+var errExpositionLine = errors.New("invalid exposition line")
+
+// parseExpositionLine interpreta uma linha do formato texto do Prometheus,
+// `nome{rotulo="valor",...} valor [timestamp]`. Comentarios (HELP, TYPE,
+// o "# EOF" do OpenMetrics) e linhas vazias nao sao amostras.
+func parseExpositionLine(line string) (ScrapedSample, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return ScrapedSample{}, false
+	}
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return ScrapedSample{}, false
+	}
+	sample := ScrapedSample{Name: line[:end]}
+	rest := line[end:]
+	if rest[0] == '{' {
+		labels, after, err := parseExpositionLabels(rest[1:])
+		if err != nil {
+			return ScrapedSample{}, false
+		}
+		sample.Labels, rest = labels, after
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return ScrapedSample{}, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return ScrapedSample{}, false
+	}
+	sample.Value = value
+	return sample, true
+}
+
+// parseExpositionLabels le os rotulos ate o "}" e devolve o resto da linha.
+func parseExpositionLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			if len(labels) == 0 {
+				labels = nil
+			}
+			return labels, s[1:], nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, "", errExpositionLine
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+
+		var value strings.Builder
+		closed := false
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			if c == '"' {
+				s, closed = s[i+1:], true
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return nil, "", errExpositionLine
+		}
+		labels[name] = value.String()
+	}
+}
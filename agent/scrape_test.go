@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestParseExpositionLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ScrapedSample
+		ok   bool
+	}{
+		{"node_load1 0.25", ScrapedSample{Name: "node_load1", Value: 0.25}, true},
+		{"up 1 1714564800000", ScrapedSample{Name: "up", Value: 1}, true},
+		{`http_requests_total{method="GET",code="200"} 1027`, ScrapedSample{Name: "http_requests_total", Labels: map[string]string{"method": "GET", "code": "200"}, Value: 1027}, true},
+		{`msg{text="a \"quoted\", value\n",} 2`, ScrapedSample{Name: "msg", Labels: map[string]string{"text": "a \"quoted\", value\n"}, Value: 2}, true},
+		{`empty{} 3`, ScrapedSample{Name: "empty", Value: 3}, true},
+		{"big 1.5e+09", ScrapedSample{Name: "big", Value: 1.5e9}, true},
+		{"# HELP node_load1 1m load average.", ScrapedSample{}, false},
+		{"# EOF", ScrapedSample{}, false},
+		{"", ScrapedSample{}, false},
+		{"nan_metric NaN", ScrapedSample{}, false},
+		{"inf_metric +Inf", ScrapedSample{}, false},
+		{"novalue", ScrapedSample{}, false},
+		{`broken{label="x 1`, ScrapedSample{}, false},
+		{`broken{label=x} 1`, ScrapedSample{}, false},
+		{"extra 1 2 3", ScrapedSample{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseExpositionLine(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseExpositionLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateScrapeTargets(t *testing.T) {
+	ok := config.ScrapeTarget{Name: "node", URL: "http://localhost:9100/metrics", Metrics: []string{"node_*"}}
+	tests := []struct {
+		name    string
+		edit    func(s *config.ScrapeTarget)
+		wantErr bool
+	}{
+		{"valid", func(s *config.ScrapeTarget) {}, false},
+		{"bad name", func(s *config.ScrapeTarget) { s.Name = "Node" }, true},
+		{"bad url", func(s *config.ScrapeTarget) { s.URL = "localhost:9100" }, true},
+		{"no allowlist", func(s *config.ScrapeTarget) { s.Metrics = nil }, true},
+		{"bad pattern", func(s *config.ScrapeTarget) { s.Metrics = []string{"node_[a"} }, true},
+		{"timeout", func(s *config.ScrapeTarget) { s.TimeoutSeconds = 61 }, true},
+		{"header", func(s *config.ScrapeTarget) { s.Headers = map[string]string{"X Auth": "1"} }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ok
+			tt.edit(&s)
+			if err := validateScrapeTargets([]config.ScrapeTarget{s}); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := validateScrapeTargets([]config.ScrapeTarget{ok, ok}); err == nil {
+		t.Error("duplicate names accepted")
+	}
+}
+
+func TestScrapeTargets(t *testing.T) {
+	const body = `# HELP node_load1 1m load average.
+# TYPE node_load1 gauge
+node_load1 0.5
+node_load5 0.25
+node_cpu_seconds_total{cpu="0",mode="idle"} 100
+node_cpu_seconds_total{cpu="1",mode="idle"} 200
+go_goroutines 12
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	auth := map[string]string{"Authorization": "Bearer secret"}
+
+	got := scrapeTargets([]config.ScrapeTarget{
+		{Name: "node", URL: srv.URL + "/metrics", Headers: auth, Metrics: []string{"node_load?", "node_cpu_seconds_total"}, MaxSamples: 3},
+		{Name: "noauth", URL: srv.URL + "/metrics", Metrics: []string{"*"}},
+		{Name: "missing", URL: srv.URL + "/missing", Metrics: []string{"*"}},
+	})
+
+	want := ScrapeResult{
+		Samples: []ScrapedSample{
+			{Name: "node_load1", Value: 0.5},
+			{Name: "node_load5", Value: 0.25},
+			{Name: "node_cpu_seconds_total", Labels: map[string]string{"cpu": "0", "mode": "idle"}, Value: 100},
+		},
+		Truncated: 1,
+	}
+	if !reflect.DeepEqual(got["node"], want) {
+		t.Errorf("node = %+v, want %+v", got["node"], want)
+	}
+	if got["noauth"].Error != "status 401" || got["missing"].Error != "status 404" {
+		t.Errorf("errors = %q, %q", got["noauth"].Error, got["missing"].Error)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+const (
+	updateDownloadTimeout = 5 * time.Minute
+	updateCheckInterval   = 24 * time.Hour
+	updateStateFile       = "update.json"
+
+	// Tamanho maximo aceito para o binario do agente.
+	maxAgentBinarySize = 128 << 20
+)
+
+// releaseManifest descreve a ultima versao do agente. Como no registry de
+// plugins, e baixado de <update_url>/manifest.json com a assinatura ed25519
+// dos mesmos bytes em manifest.json.sig; cada artefato tem o sha256 do
+// binario.
+type releaseManifest struct {
+	Version   string           `json:"version"`
+	Artifacts []pluginArtifact `json:"artifacts"`
+}
+
+// fetchRelease baixa e verifica o manifest e devolve o artefato deste
+// sistema para a arquitetura arch.
+func fetchRelease(cfg config.Config, arch string) (releaseManifest, pluginArtifact, string, error) {
+	var manifest releaseManifest
+	keys, err := parseTrustedKeys("update_trusted_keys", cfg.UpdateTrustedKeys)
+	if err != nil {
+		return manifest, pluginArtifact{}, "", err
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return manifest, pluginArtifact{}, "", err
+	}
+	client.Timeout = updateDownloadTimeout
+
+	var raw, sig []byte
+	manifestURL, err := callAPI(cfg, cfg.UpdateURLFor, func(base string) (string, error) {
+		var err error
+		manifestURL := base + "/manifest.json"
+		if raw, err = registryGet(client, cfg, manifestURL, 1<<20); err != nil {
+			return "", err
+		}
+		sig, err = registryGet(client, cfg, manifestURL+".sig", 4096)
+		return manifestURL, err
+	})
+	if err != nil {
+		return manifest, pluginArtifact{}, "", err
+	}
+	if err := verifyManifest(keys, raw, sig); err != nil {
+		return manifest, pluginArtifact{}, "", err
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return manifest, pluginArtifact{}, "", fmt.Errorf("manifest: %w", err)
+	}
+	for _, a := range manifest.Artifacts {
+		if a.OS == runtime.GOOS && a.Arch == arch {
+			artifactURL, err := resolveArtifactURL(manifestURL, a.URL)
+			return manifest, a, artifactURL, err
+		}
+	}
+	return manifest, pluginArtifact{}, "", fmt.Errorf("release %s has no build for %s/%s", manifest.Version, runtime.GOOS, arch)
+}
+
+// downloadRelease baixa o binario de um artefato devolvido por fetchRelease
+// e confere o checksum.
+func downloadRelease(cfg config.Config, artifact pluginArtifact, artifactURL string) ([]byte, error) {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = updateDownloadTimeout
+	return fetchArtifact(client, cfg, artifact, artifactURL, maxAgentBinarySize)
+}
+
+// selfUpdate instala a versao publicada se ela for mais nova (ou sempre, com
+// force) e devolve a versao instalada; vazio quando ja estava atualizado.
+func selfUpdate(cfg config.Config, force bool) (string, error) {
+	manifest, artifact, artifactURL, err := fetchRelease(cfg, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	if !force && compareVersions(manifest.Version, version) <= 0 {
+		return "", nil
+	}
+
+	body, err := downloadRelease(cfg, artifact, artifactURL)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	if err := installBinary(target, body, manifest.Version); err != nil {
+		return "", err
+	}
+	return manifest.Version, nil
+}
+
+// installBinary grava o novo binario ao lado do atual, confere que ele roda
+// e informa a versao esperada, e so entao o troca pelo atual.
+func installBinary(target string, body []byte, want string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".new-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0o755); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, tmpPath, "--version").Output()
+	if err != nil {
+		return fmt.Errorf("new binary does not run: %w", err)
+	}
+	// a saida e a de versionString: "vaultrix-agent <versao> ..."
+	if f := strings.Fields(string(out)); len(f) < 2 || compareVersions(f[1], want) != 0 || f[1] == "dev" {
+		return fmt.Errorf("new binary reports %q, expected version %s", strings.TrimSpace(string(out)), want)
+	}
+	return replaceExecutable(tmpPath, target)
+}
+
+// compareVersions compara versoes x.y.z (com ou sem "v"). Builds "dev" e
+// versoes que nao sao numericas ficam abaixo de qualquer release.
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	// sufixos de pre-release nao entram na comparacao
+	v, _, _ = strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+// updateState guarda a ultima verificacao do auto-update; no modo cron cada
+// ciclo e um processo novo.
+type updateState struct {
+	LastCheck time.Time `json:"last_check"`
+}
+
+// autoUpdate verifica uma nova versao no maximo uma vez por
+// updateCheckInterval e a instala. Devolve true quando o binario foi
+// trocado. Builds "dev" nunca sao substituidos sozinhos.
+func autoUpdate(cfg config.Config, stateDir string) bool {
+	if !cfg.AutoUpdate || version == "dev" {
+		return false
+	}
+	cleanupReplacedExecutable()
+
+	path := filepath.Join(stateDir, updateStateFile)
+	var st updateState
+	if b, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(b, &st)
+	}
+	if time.Since(st.LastCheck) < updateCheckInterval {
+		return false
+	}
+	st.LastCheck = time.Now().UTC()
+	if b, err := json.Marshal(st); err == nil {
+		if err := fsutil.EnsureDir(stateDir); err == nil {
+			fsutil.WriteFileAtomic(path, b, 0o600)
+		}
+	}
+
+	installed, err := selfUpdate(cfg, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "auto-update: %v\n", err)
+		return false
+	}
+	if installed == "" {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "auto-update: %s -> %s\n", version, installed)
+	return true
+}
+
+func runSelfUpdateCommand(args []string) {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	check := fs.Bool("check", false, tr("Only report whether a newer version is available"))
+	force := fs.Bool("force", false, tr("Install the published version even if it is not newer"))
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *check {
+		manifest, _, _, err := fetchRelease(cfg, runtime.GOARCH)
+		if err != nil {
+			fatal(err)
+		}
+		if compareVersions(manifest.Version, version) > 0 {
+			fmt.Println(trf("Version %s is available (installed: %s).", manifest.Version, version))
+		} else {
+			fmt.Println(trf("Already up to date (%s).", version))
+		}
+		return
+	}
+
+	cleanupReplacedExecutable()
+	installed, err := selfUpdate(cfg, *force)
+	if err != nil {
+		fatal(err)
+	}
+	if installed == "" {
+		fmt.Println(trf("Already up to date (%s).", version))
+		return
+	}
+	fmt.Println(trf("Updated %s -> %s.", version, installed))
+	if name, ok := installedScheduler(); ok && name != "cron" {
+		fmt.Println(tr("Restart the service to run the new version."))
+	}
+}
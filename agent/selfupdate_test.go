@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.9", 1},
+		{"2.0", "2.0.0", 0},
+		{"2.0.1", "2.0", 1},
+		{"1.3.0-rc1", "1.3.0", 0},
+		{"dev", "0.0.1", -1},
+		{"0.0.1", "dev", 1},
+		{"dev", "abc", 0},
+		{"1.x.0", "1.0.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadReleaseChecksum(t *testing.T) {
+	binary := []byte("vaultrix-agent build")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer srv.Close()
+	sum := sha256.Sum256(binary)
+
+	cfg := config.Config{ApiURL: srv.URL + "/api/telemetry"}
+	body, err := downloadRelease(cfg, pluginArtifact{SHA256: strings.ToUpper(hex.EncodeToString(sum[:]))}, srv.URL+"/agent")
+	if err != nil || !bytes.Equal(body, binary) {
+		t.Fatalf("downloadRelease() = %q, %v", body, err)
+	}
+	if _, err := downloadRelease(cfg, pluginArtifact{SHA256: strings.Repeat("0", 64)}, srv.URL+"/agent"); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("err = %v, want a checksum mismatch", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Sink e um destino dos payloads: a API do vaultrix, o exportador OTLP e
+// cada item de "sinks". A cada ciclo o payload vai a todos em paralelo, e
+// cada sink com spool tem a sua propria fila em disco: um destino fora do
+// ar nao atrasa nem duplica os demais.
+type Sink interface {
+	Name() string
+	Send(payload Payload) error
+}
+
+const (
+	sinkAPI        = "api"
+	sinkInflux     = "influx"
+	sinkOTLP       = "otlp"
+	sinkPrometheus = "prometheus"
+	sinkFile       = "file"
+)
+
+// sinkType descreve um tipo de "sinks": como cria-lo, se o destino e uma
+// URL ou um caminho e se ele aceita spool.
+type sinkType struct {
+	new     func(cfg config.Config, s config.SinkConfig) Sink
+	file    bool
+	spooled bool
+}
+
+var sinkTypes = map[string]sinkType{
+	sinkInflux: {
+		new:     func(cfg config.Config, s config.SinkConfig) Sink { return influxSink{cfg, s} },
+		spooled: true,
+	},
+	sinkOTLP: {
+		new: func(cfg config.Config, s config.SinkConfig) Sink {
+			return otlpSink{cfg, &config.OTLPExporter{Endpoint: s.URL, Headers: s.Headers}, s.NameOrDefault()}
+		},
+		spooled: true,
+	},
+	sinkPrometheus: {
+		new: func(cfg config.Config, s config.SinkConfig) Sink { return prometheusSink{cfg, s} },
+	},
+	sinkFile: {
+		new:  func(cfg config.Config, s config.SinkConfig) Sink { return fileSink{cfg, s} },
+		file: true,
+	},
+}
+
+func validateSinks(cfg config.Config) error {
+	seen := map[string]bool{sinkAPI: true}
+	if cfg.OTLP != nil {
+		seen[sinkOTLP] = true
+	}
+	for i, s := range cfg.Sinks {
+		kind, ok := sinkTypes[s.Type]
+		if !ok {
+			return fmt.Errorf("sinks[%d]: unknown type %q (use influx, otlp, prometheus or file)", i, s.Type)
+		}
+		name := s.NameOrDefault()
+		if !config.SinkNamePattern.MatchString(name) {
+			return fmt.Errorf("sinks[%d]: invalid name %q (lowercase letters, digits, - and _)", i, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("sinks[%d]: name %q is already in use; set a distinct name", i, name)
+		}
+		seen[name] = true
+
+		if kind.file {
+			if !filepath.IsAbs(s.Path) {
+				return fmt.Errorf("sinks[%d]: path must be absolute", i)
+			}
+			if s.MaxMB < 0 || s.MaxFiles < 0 {
+				return fmt.Errorf("sinks[%d]: max_mb and max_files must not be negative", i)
+			}
+		} else {
+			u, err := url.Parse(s.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("sinks[%d]: invalid url %q; use the full http or https URL", i, s.URL)
+			}
+		}
+		if err := s.SinkPolicy.Validate(fmt.Sprintf("sinks[%d]: ", i)); err != nil {
+			return err
+		}
+		if s.Spool && !kind.spooled {
+			return fmt.Errorf("sinks[%d]: %s sinks cannot spool", i, s.Type)
+		}
+		for name := range s.Headers {
+			if name == "" || strings.ContainsAny(name, ": \r\n") {
+				return fmt.Errorf("sinks[%d]: invalid header name %q", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateOffline confere que um host offline tem para onde mandar os
+// payloads e nao depende de recursos que so a API oferece.
+func validateOffline(cfg config.Config) error {
+	if !cfg.Offline {
+		return nil
+	}
+	if len(cfg.Sinks) == 0 && cfg.OTLP == nil {
+		return errors.New("offline requires at least one sink (e.g. a file sink)")
+	}
+	for _, f := range []struct {
+		key string
+		on  bool
+	}{
+		{"command_poll_seconds", cfg.CommandPollSeconds > 0},
+		{"remote_config", cfg.RemoteConfig},
+		{"auto_update", cfg.AutoUpdate},
+	} {
+		if f.on {
+			return fmt.Errorf("%s needs the API and cannot be used with offline", f.key)
+		}
+	}
+	return nil
+}
+
+// sinkTarget e um sink do ciclo, se ele usa spool e como reage a falhas.
+type sinkTarget struct {
+	Sink
+	spool  bool
+	policy config.SinkPolicy
+}
+
+// payloadSinks monta os destinos do config. O primeiro e o principal: o
+// erro dele e o do ciclo (diario, codigo de saida); os dos demais so sao
+// registrados.
+func payloadSinks(cfg config.Config) []sinkTarget {
+	var targets []sinkTarget
+	if cfg.SendsToAPI() {
+		targets = append(targets, sinkTarget{apiSink{cfg}, true, cfg.API})
+	}
+	if cfg.OTLP != nil {
+		targets = append(targets, sinkTarget{otlpSink{cfg, cfg.OTLP, sinkOTLP}, !cfg.SendsToAPI(), config.SinkPolicy{}})
+	}
+	for _, s := range cfg.Sinks {
+		targets = append(targets, sinkTarget{sinkTypes[s.Type].new(cfg, s), s.Spool, s.SinkPolicy})
+	}
+	return targets
+}
+
+// sinkStateDir e onde fica o spool de um sink. O da API continua em
+// <state>/spool, como antes dos sinks.
+func sinkStateDir(stateDir, name string) string {
+	if name == sinkAPI {
+		return stateDir
+	}
+	return filepath.Join(stateDir, "sinks", name)
+}
+
+// sinkBacklogs conta os payloads no spool de cada sink, alem da API.
+func sinkBacklogs(stateDir string) map[string]int {
+	entries, err := os.ReadDir(filepath.Join(stateDir, "sinks"))
+	if err != nil {
+		return nil
+	}
+	backlogs := make(map[string]int)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if n := countSpool(sinkStateDir(stateDir, e.Name())); n > 0 {
+			backlogs[e.Name()] = n
+		}
+	}
+	return backlogs
+}
+
+// apiSink e a API do vaultrix: o payload em JSON, com failover entre os
+// enderecos de api_url e as respostas (rotacao de token) tratadas.
+type apiSink struct {
+	cfg config.Config
+}
+
+func (s apiSink) Name() string { return sinkAPI }
+
+func (s apiSink) Send(payload Payload) error {
+	b, err := postPayload(s.cfg, payload)
+	if err != nil {
+		return err
+	}
+	handleTelemetryResponse(s.cfg, b)
+	return nil
+}
+
+// postSink envia um corpo a um sink pelo mesmo cliente da API (proxy, TLS
+// e pool de conexoes). Respostas fora de 2xx viram apiError, que o spool
+// trata como as da API.
+func postSink(cfg config.Config, method, endpoint, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return &apiError{StatusCode: resp.StatusCode, Body: string(b)}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestValidateSinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{"none", config.Config{}, false},
+		{"influx", config.Config{Sinks: []config.SinkConfig{{Type: sinkInflux, URL: "http://influx:8086/api/v2/write?org=a&bucket=b", Spool: true}}}, false},
+		{"otlp", config.Config{Sinks: []config.SinkConfig{{Type: sinkOTLP, URL: "http://otel:4318"}}}, false},
+		{"prometheus", config.Config{Sinks: []config.SinkConfig{{Type: sinkPrometheus, URL: "http://pushgateway:9091"}}}, false},
+		{"file", config.Config{Sinks: []config.SinkConfig{{Type: sinkFile, Path: "/var/log/vaultrix.jsonl"}}}, false},
+		{"two influx with names", config.Config{Sinks: []config.SinkConfig{
+			{Name: "influx-a", Type: sinkInflux, URL: "http://a:8086/write?db=x"},
+			{Name: "influx-b", Type: sinkInflux, URL: "http://b:8086/write?db=x"},
+		}}, false},
+		{"two influx without names", config.Config{Sinks: []config.SinkConfig{
+			{Type: sinkInflux, URL: "http://a:8086/write?db=x"},
+			{Type: sinkInflux, URL: "http://b:8086/write?db=x"},
+		}}, true},
+		{"name taken by otlp exporter", config.Config{OTLP: &config.OTLPExporter{Endpoint: "http://otel:4318"}, Sinks: []config.SinkConfig{{Type: sinkOTLP, URL: "http://otel2:4318"}}}, true},
+		{"reserved api name", config.Config{Sinks: []config.SinkConfig{{Name: "api", Type: sinkInflux, URL: "http://a:8086"}}}, true},
+		{"invalid name", config.Config{Sinks: []config.SinkConfig{{Name: "My Sink", Type: sinkInflux, URL: "http://a:8086"}}}, true},
+		{"unknown type", config.Config{Sinks: []config.SinkConfig{{Type: "graphite", URL: "http://g:2003"}}}, true},
+		{"bad url", config.Config{Sinks: []config.SinkConfig{{Type: sinkInflux, URL: "influx:8086"}}}, true},
+		{"relative path", config.Config{Sinks: []config.SinkConfig{{Type: sinkFile, Path: "out.jsonl"}}}, true},
+		{"prometheus cannot spool", config.Config{Sinks: []config.SinkConfig{{Type: sinkPrometheus, URL: "http://pg:9091", Spool: true}}}, true},
+		{"bad header", config.Config{Sinks: []config.SinkConfig{{Type: sinkInflux, URL: "http://influx:8086", Headers: map[string]string{"a:b": "c"}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSinks(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateSinks() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPayloadSinks(t *testing.T) {
+	otlp := &config.OTLPExporter{Endpoint: "http://otel:4318"}
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string
+	}{
+		{"api only", config.Config{}, []string{"api"}},
+		{"otlp also", config.Config{OTLP: otlp}, []string{"api", "otlp"}},
+		{"otlp only", config.Config{OTLP: &config.OTLPExporter{Endpoint: "http://otel:4318", Mode: config.OTLPOnly}}, []string{"otlp"}},
+		{"list", config.Config{Sinks: []config.SinkConfig{{Type: sinkFile, Path: "/tmp/x"}, {Name: "pg", Type: sinkPrometheus}}}, []string{"api", "file", "pg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range payloadSinks(tt.cfg) {
+				got = append(got, s.Name())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// flakyServer responde 503 enquanto down for verdadeiro e conta os corpos
+// recebidos com sucesso.
+type flakyServer struct {
+	mu       sync.Mutex
+	down     bool
+	received int
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	io.Copy(io.Discard, r.Body)
+	if f.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	f.received++
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestDeliverPayloadIndependentSpools(t *testing.T) {
+	api := &flakyServer{down: true}
+	apiSrv := httptest.NewServer(api)
+	defer apiSrv.Close()
+	influx := &flakyServer{}
+	influxSrv := httptest.NewServer(influx)
+	defer influxSrv.Close()
+
+	stateDir := t.TempDir()
+	cfg := config.Config{
+		Token:  "0123456789abcdef",
+		ApiURL: apiSrv.URL,
+		Sinks:  []config.SinkConfig{{Type: sinkInflux, URL: influxSrv.URL, Spool: true}},
+	}
+	send := func(minute int) error {
+		return deliverPayload(cfg, stateDir, Payload{Timestamp: time.Unix(int64(60*minute), 0), Metrics: Metrics{CPUUsage: 1}})
+	}
+
+	if err := send(1); err == nil {
+		t.Fatal("API down: want the primary sink's error")
+	}
+	if got := countSpool(stateDir); got != 1 {
+		t.Errorf("API spool = %d, want 1", got)
+	}
+	if got := countSpool(sinkStateDir(stateDir, sinkInflux)); got != 0 || influx.received != 1 {
+		t.Errorf("influx spool = %d, received %d; want 0 and 1", got, influx.received)
+	}
+
+	api.down, influx.down = false, true
+	if err := send(2); err != nil {
+		t.Fatalf("API up: %v", err)
+	}
+	if got := countSpool(stateDir); got != 0 || api.received != 2 {
+		t.Errorf("API spool = %d, received %d; want 0 and 2 (backlog flushed)", got, api.received)
+	}
+	if got := sinkBacklogs(stateDir)[sinkInflux]; got != 1 {
+		t.Errorf("influx backlog = %d, want 1", got)
+	}
+}
+
+func TestPrometheusExposition(t *testing.T) {
+	payload := Payload{
+		Host:    &HostInfo{Hostname: "web01"},
+		Metrics: Metrics{CPUUsage: 12.5, CPUCores: 4},
+		Containers: []ContainerStatus{
+			{Name: "api", CPUPercent: 3, MemUsageBytes: 1024},
+			{Name: "db", CPUPercent: 5, MemUsageBytes: 2048},
+		},
+	}
+	got := string(prometheusExposition(config.Config{}, payload))
+	for _, want := range []string{
+		"# TYPE vaultrix_host_cpu gauge\nvaultrix_host_cpu 12.5\n",
+		"vaultrix_host_cpu_cores 4\n",
+		"# TYPE vaultrix_container_cpu_percent gauge\nvaultrix_container_cpu_percent{name=\"api\"} 3\nvaultrix_container_cpu_percent{name=\"db\"} 5\n",
+		"vaultrix_container_mem_usage_bytes{name=\"db\"} 2048\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "host=") {
+		t.Errorf("host label repeated in:\n%s", got)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"cpuPercent", "cpu_percent"},
+		{"memUsageBytes", "mem_usage_bytes"},
+		{"pids", "pids"},
+		{"load_avg_1", "load_avg_1"},
+	}
+	for _, tt := range tests {
+		if got := snakeCase(tt.in); got != tt.want {
+			t.Errorf("snakeCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vaultrix.jsonl")
+	s := fileSink{config.Config{}, config.SinkConfig{Type: sinkFile, Path: path, MaxMB: 1, MaxFiles: 2}}
+	// dois rodados antigos; o terceiro empurra o mais velho para fora
+	for _, name := range []string{"vaultrix-20240101T000000.000Z.jsonl", "vaultrix-20240102T000000.000Z.jsonl", "vaultrix-notes.jsonl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, make([]byte, 1<<20), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(Payload{Token: "secret", AgentVersion: "1.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, _ := filepath.Glob(filepath.Join(dir, "vaultrix-2*.jsonl"))
+	if len(rotated) != 2 || filepath.Base(rotated[0]) != "vaultrix-20240102T000000.000Z.jsonl" {
+		t.Fatalf("rotated = %v, want the newer old file and the one just rotated", rotated)
+	}
+	if info, err := os.Stat(rotated[1]); err != nil || info.Size() != 1<<20 {
+		t.Fatalf("rotated file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vaultrix-notes.jsonl")); err != nil {
+		t.Errorf("unrelated file removed: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "\n") != 1 || strings.Contains(string(b), "secret") || !strings.Contains(string(b), `"agent_version":"1.0"`) {
+		t.Errorf("file = %q", b)
+	}
+}
+
+func TestOfflineSinks(t *testing.T) {
+	file := config.SinkConfig{Type: sinkFile, Path: "/var/lib/vaultrix-agent/payloads.jsonl"}
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{"file sink without token or api_url", config.Config{Offline: true, Sinks: []config.SinkConfig{file}}, false},
+		{"no sink", config.Config{Offline: true, Token: "0123456789abcdef", ApiURL: "https://v.example.com/api/telemetry"}, true},
+		{"command polling", config.Config{Offline: true, Sinks: []config.SinkConfig{file}, CommandPollSeconds: 30}, true},
+		{"remote config", config.Config{Offline: true, Sinks: []config.SinkConfig{file}, RemoteConfig: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("checkConfig() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(payloadSinks(tt.cfg)) != 1 {
+				t.Errorf("sinks = %d, want only the file sink", len(payloadSinks(tt.cfg)))
+			}
+		})
+	}
+}
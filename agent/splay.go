@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// splayDelay sorteia a espera antes da coleta, entre zero e splay_seconds.
+// Agentes instalados da mesma imagem disparam todos no minuto cheio; o
+// sorteio espalha os envios pelo inicio do intervalo.
+func splayDelay(cfg config.Config) time.Duration {
+	if cfg.SplaySeconds <= 0 {
+		return 0
+	}
+	return rand.N(time.Duration(cfg.SplaySeconds) * time.Second)
+}
+
+// waitSplay espera o splay sorteado e devolve false se ctx terminar antes.
+func waitSplay(ctx context.Context, cfg config.Config) bool {
+	return sleepContext(ctx, splayDelay(cfg))
+}
+
+// sleepContext espera d e devolve false se ctx terminar antes.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func validateSplay(cfg config.Config) error {
+	if cfg.SplaySeconds < 0 {
+		return fmt.Errorf("splay_seconds cannot be negative")
+	}
+	// no cron o ciclo seguinte nao pode comecar antes deste
+	if cfg.SplaySeconds >= max(cfg.Interval, 1)*60 {
+		return fmt.Errorf("splay_seconds must be shorter than the interval (%d min)", max(cfg.Interval, 1))
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// O spool guarda os payloads que nao puderam ser enviados e os reenvia,
+// do mais antigo para o mais novo, quando a API volta. Cada arquivo e um
+// payload; o nome e o timestamp em nanossegundos, para que a ordem
+// alfabetica seja a cronologica.
+const (
+	spoolAggregateSuffix = "-agg.json"
+
+	// spoolWindow e a janela dos agregados gerados na compactacao.
+	spoolWindow = time.Hour
+
+	// spoolFlushBatch limita os reenvios por ciclo, para nao despejar dias
+	// de backlog de uma vez sobre a API que acabou de voltar.
+	spoolFlushBatch = 30
+)
+
+// SpoolAggregate acompanha um payload que resume uma janela de amostras
+// compactadas; o Metrics do payload traz as medias.
+type SpoolAggregate struct {
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Samples     int       `json:"samples"`
+	Min         Metrics   `json:"min"`
+	Max         Metrics   `json:"max"`
+}
+
+func spoolDir(stateDir string) string {
+	return filepath.Join(stateDir, "spool")
+}
+
+// countSpool retorna quantos payloads aguardam reenvio no spool.
+func countSpool(stateDir string) int {
+	return len(listSpool(stateDir))
+}
+
+type spoolEntry struct {
+	path      string
+	size      int64
+	aggregate bool
+}
+
+func listSpool(stateDir string) []spoolEntry {
+	entries, err := os.ReadDir(spoolDir(stateDir))
+	if err != nil {
+		return nil
+	}
+	var list []spoolEntry
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		list = append(list, spoolEntry{
+			path:      filepath.Join(spoolDir(stateDir), e.Name()),
+			size:      info.Size(),
+			aggregate: strings.HasSuffix(e.Name(), spoolAggregateSuffix),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].path < list[j].path })
+	return list
+}
+
+// spoolable diz se vale a pena guardar o payload: so falhas da API ou da
+// rede, que passam sozinhas. Token recusado ou payload invalido nao.
+func spoolable(err error) bool {
+	if errors.Is(err, errSinkPaused) {
+		return true
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	kind := classifyError(err)
+	return kind == errKindTransport || kind == errKindTimeout
+}
+
+func spoolPayload(cfg config.Config, stateDir string, payload Payload) error {
+	dir := spoolDir(stateDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	// O token nao vai para o disco; no reenvio vale o token atual, que pode
+	// ter sido rotacionado nesse meio tempo.
+	payload.Token = ""
+	// o disco recebe o mesmo que a API: payload_fields vale tambem aqui
+	b, err := marshalPayload(cfg, payload)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%019d.json", payload.Timestamp.UnixNano())
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, name), b, 0o600); err != nil {
+		return err
+	}
+	return compactSpool(cfg, stateDir)
+}
+
+// flushSpool reenvia parte do backlog ao sink dono do spool. Para na
+// primeira falha que indique que o destino ainda nao aguenta (5xx, 429,
+// rede); payloads recusados por outros motivos sao descartados para nao
+// travar a fila.
+func flushSpool(cfg config.Config, stateDir string, sink Sink) {
+	for i, e := range listSpool(stateDir) {
+		if i == spoolFlushBatch {
+			return
+		}
+		payload, err := readSpooled(e.path)
+		if err == nil {
+			payload.Token = cfg.Token
+			payload.Replayed = true
+			err = sink.Send(payload)
+			if err != nil && (spoolable(err) || classifyError(err) == errKindConfig) {
+				return
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "spool: dropping %s: %v\n", filepath.Base(e.path), err)
+		}
+		os.Remove(e.path)
+	}
+}
+
+func readSpooled(path string) (Payload, error) {
+	var p Payload
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	return p, json.Unmarshal(b, &p)
+}
+
+func spoolOverLimits(list []spoolEntry, limits config.SpoolLimits) bool {
+	if limits.MaxFiles > 0 && len(list) > limits.MaxFiles {
+		return true
+	}
+	if limits.MaxMB > 0 {
+		var total int64
+		for _, e := range list {
+			total += e.size
+		}
+		return total > int64(limits.MaxMB)<<20
+	}
+	return false
+}
+
+// compactSpool mantem o spool dentro dos limites. As amostras brutas mais
+// antigas viram um agregado (min/max/media) por janela; so quando nao ha
+// mais nada bruto para compactar os agregados mais antigos sao descartados.
+// Assim uma queda de dias perde resolucao, mas nao o historico inteiro.
+func compactSpool(cfg config.Config, stateDir string) error {
+	limits := cfg.Spool.WithDefaults()
+	for {
+		list := listSpool(stateDir)
+		if !spoolOverLimits(list, limits) {
+			return nil
+		}
+
+		window := oldestRawWindow(list)
+		if window == nil {
+			if err := os.Remove(list[0].path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := aggregateSpoolWindow(cfg, stateDir, window); err != nil {
+			// amostra ilegivel nao pode travar a compactacao
+			fmt.Fprintf(os.Stderr, "spool: %v\n", err)
+			for _, e := range window {
+				os.Remove(e.path)
+			}
+		}
+	}
+}
+
+// oldestRawWindow devolve as amostras brutas da janela mais antiga.
+func oldestRawWindow(list []spoolEntry) []spoolEntry {
+	var window []spoolEntry
+	var start time.Time
+	for _, e := range list {
+		if e.aggregate {
+			continue
+		}
+		ts := spoolEntryTime(e)
+		if window == nil {
+			start = ts.Truncate(spoolWindow)
+		} else if !ts.Truncate(spoolWindow).Equal(start) {
+			break
+		}
+		window = append(window, e)
+	}
+	return window
+}
+
+func spoolEntryTime(e spoolEntry) time.Time {
+	name := strings.TrimSuffix(filepath.Base(e.path), ".json")
+	name = strings.TrimSuffix(name, strings.TrimSuffix(spoolAggregateSuffix, ".json"))
+	n, _ := strconv.ParseInt(name, 10, 64)
+	return time.Unix(0, n).UTC()
+}
+
+// aggregateSpoolWindow troca as amostras de uma janela por um unico payload
+// agregado. Containers, checks, plugins, scrapes e textfiles nao sobrevivem a compactacao; os
+// eventos sim, todos os da janela, e as app_metrics, somadas.
+func aggregateSpoolWindow(cfg config.Config, stateDir string, window []spoolEntry) error {
+	var samples []Metrics
+	var events []PayloadEvent
+	var apps *AppMetrics
+	var last Payload
+	for _, e := range window {
+		p, err := readSpooled(e.path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(e.path), err)
+		}
+		if !p.Heartbeat {
+			samples = append(samples, p.Metrics)
+		}
+		events = append(events, p.Events...)
+		apps = mergeAppMetrics(apps, p.AppMetrics)
+		last = p
+	}
+
+	first := spoolEntryTime(window[0])
+	agg := Payload{
+		Host:                   last.Host,
+		Containers:             []ContainerStatus{},
+		ContainerRuntimeStatus: last.ContainerRuntimeStatus,
+		Timestamp:              last.Timestamp,
+		AgentVersion:           last.AgentVersion,
+		AgentCommit:            last.AgentCommit,
+		Metadata:               last.Metadata,
+		Flags:                  last.Flags,
+		Heartbeat:              len(samples) == 0,
+		Events:                 events,
+		AppMetrics:             apps,
+		Aggregate: &SpoolAggregate{
+			WindowStart: first,
+			WindowEnd:   last.Timestamp,
+			Samples:     len(window),
+		},
+	}
+	if len(samples) > 0 {
+		agg.Metrics, agg.Aggregate.Min, agg.Aggregate.Max = aggregateMetrics(samples)
+		agg.Metrics.Disks = last.Metrics.Disks
+	}
+
+	b, err := marshalPayload(cfg, agg)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%019d%s", first.UnixNano(), spoolAggregateSuffix)
+	if err := fsutil.WriteFileAtomic(filepath.Join(spoolDir(stateDir), name), b, 0o600); err != nil {
+		return err
+	}
+	for _, e := range window {
+		os.Remove(e.path)
+	}
+	return nil
+}
+
+// aggregateMetrics calcula media, minimo e maximo de cada campo numerico de
+// Metrics. Campos inteiros tem a media arredondada.
+func aggregateMetrics(samples []Metrics) (avg, lo, hi Metrics) {
+	lo, hi = samples[0], samples[0]
+	lo.Disks, hi.Disks = nil, nil
+	avgV := reflect.ValueOf(&avg).Elem()
+	loV := reflect.ValueOf(&lo).Elem()
+	hiV := reflect.ValueOf(&hi).Elem()
+
+	for i := 0; i < avgV.NumField(); i++ {
+		var sum float64
+		for j, m := range samples {
+			f := reflect.ValueOf(m).Field(i)
+			v, ok := numericValue(f)
+			if !ok {
+				break
+			}
+			sum += v
+			if j == 0 {
+				continue
+			}
+			if lov, _ := numericValue(loV.Field(i)); v < lov {
+				loV.Field(i).Set(f)
+			}
+			if hiv, _ := numericValue(hiV.Field(i)); v > hiv {
+				hiV.Field(i).Set(f)
+			}
+		}
+		setNumericValue(avgV.Field(i), sum/float64(len(samples)))
+	}
+	return avg, lo, hi
+}
+
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int64:
+		return float64(v.Int()), true
+	}
+	return 0, false
+}
+
+func setNumericValue(v reflect.Value, f float64) {
+	switch v.Kind() {
+	case reflect.Float64:
+		v.SetFloat(f)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(int64(math.Round(f)))
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestCompactSpool(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		limits     config.SpoolLimits
+		offsets    []time.Duration // timestamps das amostras, a partir de base
+		wantRaw    int
+		wantAgg    int
+		wantSample int     // amostras no agregado mais antigo
+		wantMinCPU float64 // menor cpu desse agregado
+	}{
+		{
+			name:    "within limits",
+			limits:  config.SpoolLimits{MaxFiles: 5},
+			offsets: []time.Duration{0, time.Minute, 2 * time.Minute},
+			wantRaw: 3,
+		},
+		{
+			name:       "one window becomes one aggregate",
+			limits:     config.SpoolLimits{MaxFiles: 3},
+			offsets:    []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute},
+			wantAgg:    1,
+			wantSample: 4,
+			wantMinCPU: 10,
+		},
+		{
+			// so a janela mais antiga e compactada
+			name:       "oldest window first",
+			limits:     config.SpoolLimits{MaxFiles: 3},
+			offsets:    []time.Duration{0, 30 * time.Minute, 60 * time.Minute, 90 * time.Minute},
+			wantRaw:    2,
+			wantAgg:    1,
+			wantSample: 2,
+			wantMinCPU: 10,
+		},
+		{
+			// sem amostras brutas, o agregado mais antigo e descartado
+			name:       "drops oldest aggregate",
+			limits:     config.SpoolLimits{MaxFiles: 1},
+			offsets:    []time.Duration{0, time.Hour},
+			wantAgg:    1,
+			wantSample: 1,
+			wantMinCPU: 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			cfg := config.Config{
+				Spool:         tt.limits,
+				PayloadFields: config.FieldFilter{Deny: []string{"host.machine_id"}},
+			}
+			for i, off := range tt.offsets {
+				p := Payload{
+					Token:        "secret-token",
+					Host:         &HostInfo{Hostname: "web1", MachineID: "abc"},
+					Metrics:      Metrics{CPUUsage: float64(10 * (i + 1))},
+					Timestamp:    base.Add(off),
+					AgentVersion: "1.0.0",
+					Events:       []PayloadEvent{newEvent(eventCheckDown, "api", "", base.Add(off))},
+				}
+				if err := spoolPayload(cfg, stateDir, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var raw, agg []spoolEntry
+			for _, e := range listSpool(stateDir) {
+				if e.aggregate {
+					agg = append(agg, e)
+				} else {
+					raw = append(raw, e)
+				}
+				b, err := os.ReadFile(e.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// o spool grava o mesmo que a API receberia, sem token
+				if strings.Contains(string(b), "machine_id") || strings.Contains(string(b), "secret-token") {
+					t.Errorf("%s keeps filtered data: %s", filepath.Base(e.path), b)
+				}
+			}
+			if len(raw) != tt.wantRaw || len(agg) != tt.wantAgg {
+				t.Fatalf("raw = %d, aggregates = %d; want %d and %d", len(raw), len(agg), tt.wantRaw, tt.wantAgg)
+			}
+			if tt.wantAgg == 0 {
+				return
+			}
+			p, err := readSpooled(agg[0].path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Aggregate == nil || p.Aggregate.Samples != tt.wantSample {
+				t.Fatalf("aggregate = %+v, want %d samples", p.Aggregate, tt.wantSample)
+			}
+			if len(p.Events) != tt.wantSample {
+				t.Errorf("aggregate events = %d, want one per sample (%d)", len(p.Events), tt.wantSample)
+			}
+			// as amostras sobem de 10 em 10
+			wantMax := tt.wantMinCPU + float64(10*(tt.wantSample-1))
+			wantAvg := (tt.wantMinCPU + wantMax) / 2
+			if p.Metrics.CPUUsage != wantAvg || p.Aggregate.Min.CPUUsage != tt.wantMinCPU || p.Aggregate.Max.CPUUsage != wantMax {
+				t.Errorf("cpu avg/min/max = %g/%g/%g", p.Metrics.CPUUsage, p.Aggregate.Min.CPUUsage, p.Aggregate.Max.CPUUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+// O diretorio de estado sobrevive a atualizacoes do agente, e com ele o que
+// precisa continuar de um binario para o outro: contadores e ultima
+// execucao do diario, estado dos alertas (desde quando disparam, ultimo
+// aviso), historico das checks, ultimo estado dos containers (eventos),
+// saude dos sinks, backlog do spool, cache de DNS, endpoint saudavel e hora
+// da ultima verificacao de update. stateSchemaVersion versiona o formato
+// desses arquivos: quem mudar um deles de forma incompativel sobe a versao
+// e acrescenta a migracao em stateMigrations, para que o binario novo os
+// herde em vez de recomecar do zero.
+const (
+	stateSchemaVersion = 1
+	stateVersionFile   = "state-version.json"
+	handoffFile        = "handoff.json"
+)
+
+type stateMigration struct {
+	to  int
+	run func(stateDir string) error
+}
+
+// stateMigrations levam o estado de uma versao para a seguinte, em ordem.
+// A versao 0 e o estado dos agentes anteriores ao versionamento. Nenhum
+// formato mudou desde entao, entao a versao 1 nao tem conversao: so a marca
+// e gravada.
+var stateMigrations = []stateMigration{
+	{to: 1},
+}
+
+type stateVersion struct {
+	Schema       int    `json:"schema"`
+	AgentVersion string `json:"agent_version,omitempty"`
+}
+
+// migrateState roda as migracoes pendentes no inicio do processo. Antes de
+// mexer nos arquivos, copia-os para backup-schema-<n>, de onde podem ser
+// restaurados se a migracao falhar ou o agente voltar de versao.
+func migrateState(stateDir string) {
+	path := filepath.Join(stateDir, stateVersionFile)
+	var current stateVersion
+	if b, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(b, &current); err != nil {
+			fmt.Fprintf(os.Stderr, "state: %s: %v\n", stateVersionFile, err)
+			return
+		}
+	} else if !os.IsNotExist(err) {
+		return
+	} else if entries, err := os.ReadDir(stateDir); err != nil || len(entries) == 0 {
+		// instalacao nova: nada a migrar
+		current.Schema = stateSchemaVersion
+		writeStateVersion(path, current.Schema)
+		return
+	}
+
+	switch {
+	case current.Schema == stateSchemaVersion:
+		return
+	case current.Schema > stateSchemaVersion:
+		// versao anterior do agente: os arquivos sao JSON e campos novos sao
+		// ignorados, mas seriam perdidos na proxima gravacao. O aviso sai uma
+		// vez, junto com o backup.
+		created, err := backupState(stateDir, current.Schema)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "state: backup: %v\n", err)
+		} else if created {
+			fmt.Fprintf(os.Stderr, "state: written by a newer agent (schema %d, this agent knows %d); a copy was kept in backup-schema-%d\n", current.Schema, stateSchemaVersion, current.Schema)
+		}
+		return
+	}
+
+	if _, err := backupState(stateDir, current.Schema); err != nil {
+		fmt.Fprintf(os.Stderr, "state: backup: %v\n", err)
+		return
+	}
+	for _, m := range stateMigrations {
+		if m.to <= current.Schema {
+			continue
+		}
+		if m.run != nil {
+			if err := m.run(stateDir); err != nil {
+				fmt.Fprintf(os.Stderr, "state: migration to schema %d: %v\n", m.to, err)
+				return
+			}
+		}
+		current.Schema = m.to
+		writeStateVersion(path, current.Schema)
+	}
+}
+
+func writeStateVersion(path string, schema int) {
+	b, _ := json.Marshal(stateVersion{Schema: schema, AgentVersion: version})
+	err := fsutil.EnsureDir(filepath.Dir(path))
+	if err == nil {
+		err = fsutil.WriteFileAtomic(path, b, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "state: %v\n", err)
+	}
+}
+
+// backupState copia os arquivos do topo do diretorio de estado (o spool fica
+// de fora: ele so tem payloads) e diz se criou o backup. Um backup existente
+// nao e sobrescrito.
+func backupState(stateDir string, schema int) (bool, error) {
+	dir := filepath.Join(stateDir, fmt.Sprintf("backup-schema-%d", schema))
+	if _, err := os.Stat(dir); err == nil {
+		return false, nil
+	}
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := fsutil.CopyFile(filepath.Join(stateDir, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// handoff passa ao proximo processo do daemon o horario do proximo ciclo.
+// Sem ele, um restart (auto-update, systemctl restart apos um upgrade)
+// coletaria na hora, logo depois do ciclo anterior, e sairia da cadencia.
+type handoff struct {
+	NextRun time.Time `json:"next_run"`
+}
+
+func saveHandoff(stateDir string, next time.Time) {
+	b, _ := json.Marshal(handoff{NextRun: next.UTC()})
+	err := fsutil.EnsureDir(stateDir)
+	if err == nil {
+		err = fsutil.WriteFileAtomic(filepath.Join(stateDir, handoffFile), b, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "state: %v\n", err)
+	}
+}
+
+// takeHandoff le e apaga o handoff. Devolve quanto esperar ate o ciclo
+// combinado; ok e falso sem handoff ou quando ele nao serve mais (vencido
+// ha mais de um intervalo, ou mais longe que um intervalo).
+func takeHandoff(stateDir string, interval time.Duration) (wait time.Duration, ok bool) {
+	path := filepath.Join(stateDir, handoffFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	os.Remove(path)
+	var h handoff
+	if json.Unmarshal(b, &h) != nil || h.NextRun.IsZero() {
+		return 0, false
+	}
+	wait = time.Until(h.NextRun)
+	if wait > interval || wait < -interval {
+		return 0, false
+	}
+	return max(wait, 0), true
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateState(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantSchema int
+		wantBackup string // diretorio de backup esperado; "" = nenhum
+	}{
+		{
+			name:       "fresh install",
+			wantSchema: stateSchemaVersion,
+		},
+		{
+			name:       "state from before versioning",
+			files:      map[string]string{"alerts.json": `{}`},
+			wantSchema: stateSchemaVersion,
+			wantBackup: "backup-schema-0",
+		},
+		{
+			name:       "current schema",
+			files:      map[string]string{stateVersionFile: `{"schema":1}`, "alerts.json": `{}`},
+			wantSchema: 1,
+		},
+		{
+			// estado de um agente mais novo fica como esta, com um backup
+			name:       "newer schema",
+			files:      map[string]string{stateVersionFile: `{"schema":9}`, "alerts.json": `{}`},
+			wantSchema: 9,
+			wantBackup: "backup-schema-9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			migrateState(dir)
+
+			b, err := os.ReadFile(filepath.Join(dir, stateVersionFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v stateVersion
+			if err := json.Unmarshal(b, &v); err != nil {
+				t.Fatal(err)
+			}
+			if v.Schema != tt.wantSchema {
+				t.Errorf("schema = %d, want %d", v.Schema, tt.wantSchema)
+			}
+
+			backups, _ := filepath.Glob(filepath.Join(dir, "backup-schema-*"))
+			switch {
+			case tt.wantBackup == "" && len(backups) > 0:
+				t.Errorf("unexpected backup %v", backups)
+			case tt.wantBackup != "":
+				if _, err := os.Stat(filepath.Join(dir, tt.wantBackup, "alerts.json")); err != nil {
+					t.Errorf("backup: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTakeHandoff(t *testing.T) {
+	const interval = time.Minute
+	tests := []struct {
+		name     string
+		next     time.Duration // a partir de agora; 0 = sem handoff
+		wantOK   bool
+		wantWait time.Duration
+	}{
+		{name: "none"},
+		{name: "in the future", next: 30 * time.Second, wantOK: true, wantWait: 30 * time.Second},
+		{name: "just missed", next: -10 * time.Second, wantOK: true},
+		{name: "too far ahead", next: 2 * interval},
+		{name: "stale", next: -2 * interval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.next != 0 {
+				saveHandoff(dir, time.Now().Add(tt.next))
+			}
+			wait, ok := takeHandoff(dir, interval)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if d := wait - tt.wantWait; d > time.Second || d < -time.Second {
+				t.Errorf("wait = %v, want about %v", wait, tt.wantWait)
+			}
+			if _, err := os.Stat(filepath.Join(dir, handoffFile)); !os.IsNotExist(err) {
+				t.Errorf("handoff file left behind: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	statsdMaxPacket       = 65535
+	statsdMaxTimerSamples = 10000
+)
+
+// AppMetrics e o agregado do StatsD desde o ciclo anterior. Counters sao a
+// soma na janela (ja corrigida pelo sample rate); gauges, o ultimo valor,
+// que continua valendo nos ciclos seguintes; sets, quantos valores
+// distintos chegaram. Series com tags DogStatsD (|#k:v) viram
+// "nome;k=v", com as tags em ordem.
+type AppMetrics struct {
+	Since    time.Time             `json:"since"`
+	Counters map[string]float64    `json:"counters,omitempty"`
+	Gauges   map[string]float64    `json:"gauges,omitempty"`
+	Timers   map[string]TimerStats `json:"timers,omitempty"`
+	Sets     map[string]int        `json:"sets,omitempty"`
+
+	// Dropped conta linhas invalidas e series alem de max_metrics.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// TimerStats resume as amostras de um timer (ms) ou histograma na janela.
+type TimerStats struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	P95   float64 `json:"p95"`
+}
+
+// statsdAggregator acumula as metricas recebidas ate o proximo flush.
+type statsdAggregator struct {
+	mu         sync.Mutex
+	maxMetrics int
+	since      time.Time
+	counters   map[string]float64
+	gauges     map[string]float64
+	timers     map[string][]float64
+	sets       map[string]map[string]bool
+	dropped    int
+}
+
+func newStatsdAggregator(maxMetrics int, now time.Time) *statsdAggregator {
+	a := &statsdAggregator{maxMetrics: maxMetrics, gauges: map[string]float64{}}
+	a.reset(now)
+	return a
+}
+
+func (a *statsdAggregator) reset(now time.Time) {
+	a.since = now
+	a.counters = map[string]float64{}
+	a.timers = map[string][]float64{}
+	a.sets = map[string]map[string]bool{}
+	a.dropped = 0
+}
+
+// series conta as series distintas, para o limite de max_metrics.
+func (a *statsdAggregator) series() int {
+	return len(a.counters) + len(a.gauges) + len(a.timers) + len(a.sets)
+}
+
+// handlePacket aplica as linhas de um pacote; cada pacote pode trazer
+// varias, separadas por quebra de linha.
+func (a *statsdAggregator) handlePacket(packet []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !a.apply(line) {
+			a.dropped++
+		}
+	}
+}
+
+// apply interpreta "nome:valor|tipo[|@taxa][|#tags]".
+func (a *statsdAggregator) apply(line string) bool {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return false
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return false
+	}
+	raw, kind := fields[0], fields[1]
+	rate := 1.0
+	var tags []string
+	for _, f := range fields[2:] {
+		switch {
+		case strings.HasPrefix(f, "@"):
+			r, err := strconv.ParseFloat(f[1:], 64)
+			if err != nil || r <= 0 || r > 1 {
+				return false
+			}
+			rate = r
+		case strings.HasPrefix(f, "#"):
+			for _, t := range strings.Split(f[1:], ",") {
+				if t != "" {
+					tags = append(tags, strings.Replace(t, ":", "=", 1))
+				}
+			}
+		}
+	}
+	if len(tags) > 0 {
+		sort.Strings(tags)
+		name += ";" + strings.Join(tags, ";")
+	}
+
+	if kind == "s" {
+		set, ok := a.sets[name]
+		if !ok {
+			if a.series() >= a.maxMetrics {
+				return false
+			}
+			set = map[string]bool{}
+			a.sets[name] = set
+		}
+		set[raw] = true
+		return true
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return false
+	}
+	var known bool
+	switch kind {
+	case "c":
+		_, known = a.counters[name]
+	case "g":
+		_, known = a.gauges[name]
+	case "ms", "h", "d":
+		_, known = a.timers[name]
+	default:
+		return false
+	}
+	if !known && a.series() >= a.maxMetrics {
+		return false
+	}
+
+	switch kind {
+	case "c":
+		a.counters[name] += value / rate
+	case "g":
+		// "+n" e "-n" ajustam o valor atual em vez de substitui-lo
+		if raw[0] == '+' || raw[0] == '-' {
+			a.gauges[name] += value
+		} else {
+			a.gauges[name] = value
+		}
+	default:
+		if len(a.timers[name]) < statsdMaxTimerSamples {
+			a.timers[name] = append(a.timers[name], value)
+		}
+	}
+	return true
+}
+
+// flush devolve o agregado da janela e comeca outra. Sem nada recebido
+// desde o inicio do listener, devolve nil.
+func (a *statsdAggregator) flush(now time.Time) *AppMetrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.series() == 0 && a.dropped == 0 {
+		a.since = now
+		return nil
+	}
+	m := &AppMetrics{Since: a.since, Dropped: a.dropped}
+	if len(a.counters) > 0 {
+		m.Counters = a.counters
+	}
+	if len(a.gauges) > 0 {
+		m.Gauges = make(map[string]float64, len(a.gauges))
+		for name, v := range a.gauges {
+			m.Gauges[name] = v
+		}
+	}
+	for name, samples := range a.timers {
+		if m.Timers == nil {
+			m.Timers = make(map[string]TimerStats, len(a.timers))
+		}
+		m.Timers[name] = timerStats(samples)
+	}
+	for name, set := range a.sets {
+		if m.Sets == nil {
+			m.Sets = make(map[string]int, len(a.sets))
+		}
+		m.Sets[name] = len(set)
+	}
+	a.reset(now)
+	return m
+}
+
+func timerStats(samples []float64) TimerStats {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	// p95 pelo metodo do posto mais proximo
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return TimerStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		Mean:  sum / float64(len(sorted)),
+		P95:   sorted[max(rank, 0)],
+	}
+}
+
+// mergeAppMetrics junta duas janelas consecutivas, para a compactacao do
+// spool. Counters somam e gauges ficam com o valor mais recente; timers
+// somam contagens e combinam minimo, maximo e media, e o p95 fica com o
+// maior dos dois, um teto. Sets nao se juntam sem os valores: fica o
+// maior, um piso.
+func mergeAppMetrics(a, b *AppMetrics) *AppMetrics {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	m := &AppMetrics{Since: a.Since, Dropped: a.Dropped + b.Dropped}
+	m.Counters = mergeMaps(a.Counters, b.Counters, func(x, y float64) float64 { return x + y })
+	m.Gauges = mergeMaps(a.Gauges, b.Gauges, func(_, y float64) float64 { return y })
+	m.Sets = mergeMaps(a.Sets, b.Sets, func(x, y int) int { return max(x, y) })
+	m.Timers = mergeMaps(a.Timers, b.Timers, func(x, y TimerStats) TimerStats {
+		count := x.Count + y.Count
+		return TimerStats{
+			Count: count,
+			Min:   min(x.Min, y.Min),
+			Max:   max(x.Max, y.Max),
+			Mean:  (x.Mean*float64(x.Count) + y.Mean*float64(y.Count)) / float64(count),
+			P95:   max(x.P95, y.P95),
+		}
+	})
+	return m
+}
+
+func mergeMaps[V any](a, b map[string]V, merge func(x, y V) V) map[string]V {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	m := make(map[string]V, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		if prev, ok := m[k]; ok {
+			v = merge(prev, v)
+		}
+		m[k] = v
+	}
+	return m
+}
+
+var (
+	statsdMu       sync.Mutex
+	statsdInstance *statsdAggregator
+)
+
+func activeStatsD() *statsdAggregator {
+	statsdMu.Lock()
+	defer statsdMu.Unlock()
+	return statsdInstance
+}
+
+func setStatsD(a *statsdAggregator) {
+	statsdMu.Lock()
+	defer statsdMu.Unlock()
+	statsdInstance = a
+}
+
+// listenStatsD abre o socket e recebe pacotes ate ctx ser cancelado. O
+// endereco so e lido na partida do daemon.
+func listenStatsD(ctx context.Context, cfg *config.StatsDConfig, agg *statsdAggregator) (net.Addr, error) {
+	conn, err := net.ListenPacket("udp", cfg.ListenOrDefault())
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		buf := make([]byte, statsdMaxPacket)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "statsd: %v\n", err)
+				}
+				return
+			}
+			agg.handlePacket(buf[:n])
+		}
+	}()
+	return conn.LocalAddr(), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestStatsdAggregator(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		packet string
+		want   AppMetrics
+	}{
+		{"counter", "hits:1|c\nhits:2|c", AppMetrics{Counters: map[string]float64{"hits": 3}}},
+		{"sample rate", "hits:1|c|@0.5", AppMetrics{Counters: map[string]float64{"hits": 2}}},
+		{"gauge", "queue:5|g\nqueue:7|g", AppMetrics{Gauges: map[string]float64{"queue": 7}}},
+		{"gauge delta", "queue:5|g\nqueue:+2|g\nqueue:-4|g", AppMetrics{Gauges: map[string]float64{"queue": 3}}},
+		{"timer", "rt:10|ms\nrt:30|ms\nrt:20|h", AppMetrics{Timers: map[string]TimerStats{"rt": {Count: 3, Min: 10, Max: 30, Mean: 20, P95: 30}}}},
+		{"set", "users:a|s\nusers:b|s\nusers:a|s", AppMetrics{Sets: map[string]int{"users": 2}}},
+		{"tags", "hits:1|c|#region:br,env:prod", AppMetrics{Counters: map[string]float64{"hits;env=prod;region=br": 1}}},
+		{"malformed", "hits\nhits:x|c\nhits:1|q\nhits:1|c|@2\n:1|c\n\n", AppMetrics{Dropped: 5}},
+		{"max metrics", "a:1|c\nb:1|g\nc:1|ms\na:1|c", AppMetrics{Counters: map[string]float64{"a": 2}, Gauges: map[string]float64{"b": 1}, Dropped: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := newStatsdAggregator(2, t0)
+			agg.handlePacket([]byte(tt.packet))
+			got := agg.flush(t0.Add(time.Minute))
+			tt.want.Since = t0
+			if got == nil || !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("flush = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsdFlushResetsWindow(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	agg := newStatsdAggregator(10, t0)
+	if m := agg.flush(t0); m != nil {
+		t.Fatalf("empty flush = %+v, want nil", m)
+	}
+	agg.handlePacket([]byte("hits:1|c\nqueue:4|g\nrt:5|ms"))
+	agg.flush(t0.Add(time.Minute))
+
+	got := agg.flush(t0.Add(2 * time.Minute))
+	want := &AppMetrics{Since: t0.Add(time.Minute), Gauges: map[string]float64{"queue": 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("second flush = %+v, want %+v (gauges persist, the rest resets)", got, want)
+	}
+}
+
+func TestTimerStatsP95(t *testing.T) {
+	var samples []float64
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, float64(i))
+	}
+	got := timerStats(samples)
+	want := TimerStats{Count: 100, Min: 1, Max: 100, Mean: 50.5, P95: 95}
+	if got != want {
+		t.Errorf("timerStats = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeAppMetrics(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := &AppMetrics{
+		Since:    t0,
+		Counters: map[string]float64{"hits": 2},
+		Gauges:   map[string]float64{"queue": 1},
+		Timers:   map[string]TimerStats{"rt": {Count: 1, Min: 10, Max: 10, Mean: 10, P95: 10}},
+		Sets:     map[string]int{"users": 3},
+	}
+	b := &AppMetrics{
+		Since:    t0.Add(time.Minute),
+		Counters: map[string]float64{"hits": 3, "errors": 1},
+		Gauges:   map[string]float64{"queue": 5},
+		Timers:   map[string]TimerStats{"rt": {Count: 3, Min: 2, Max: 30, Mean: 20, P95: 30}},
+		Sets:     map[string]int{"users": 2},
+		Dropped:  1,
+	}
+	want := &AppMetrics{
+		Since:    t0,
+		Counters: map[string]float64{"hits": 5, "errors": 1},
+		Gauges:   map[string]float64{"queue": 5},
+		Timers:   map[string]TimerStats{"rt": {Count: 4, Min: 2, Max: 30, Mean: 17.5, P95: 30}},
+		Sets:     map[string]int{"users": 3},
+		Dropped:  1,
+	}
+	if got := mergeAppMetrics(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("merge = %+v, want %+v", got, want)
+	}
+	if got := mergeAppMetrics(nil, b); got != b {
+		t.Errorf("merge(nil, b) = %+v, want b", got)
+	}
+}
+
+func TestListenStatsD(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agg := newStatsdAggregator(10, time.Now())
+	cfg := &config.StatsDConfig{Listen: "127.0.0.1:0"}
+	addr, err := listenStatsD(ctx, cfg, agg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte("hits:1|c"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if m := agg.flush(time.Now()); m != nil {
+			if m.Counters["hits"] != 1 {
+				t.Errorf("counters = %v", m.Counters)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("packet not received")
+}
+
+func TestStatsDConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg     *config.StatsDConfig
+		wantErr bool
+	}{
+		{nil, false},
+		{&config.StatsDConfig{}, false},
+		{&config.StatsDConfig{Listen: ":9125"}, false},
+		{&config.StatsDConfig{Listen: "localhost"}, true},
+		{&config.StatsDConfig{MaxMetrics: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Status e o resumo exibido por --status. A primeira linha da saida humana
+// continua sendo INSTALLED/NOT_INSTALLED para scripts existentes.
+type Status struct {
+	Installed    bool        `json:"installed"`
+	Scheduler    string      `json:"scheduler"`
+	Version      string      `json:"version"`
+	ConfigPath   string      `json:"config_path"`
+	ConfigValid  bool        `json:"config_valid"`
+	ConfigError  string      `json:"config_error,omitempty"`
+	LastRun      *RunSummary `json:"last_run,omitempty"`
+	SpoolBacklog int         `json:"spool_backlog"`
+	// SinkBacklogs e o spool de cada sink alem da API, por nome.
+	SinkBacklogs map[string]int `json:"sink_backlogs,omitempty"`
+	// SinkHealth e a saude de cada sink no ultimo ciclo, API incluida.
+	SinkHealth   map[string]*SinkHealth `json:"sink_health,omitempty"`
+	ActiveAlerts []ActiveAlert          `json:"active_alerts"`
+}
+
+func buildStatus(configPath, stateDir string) Status {
+	st := Status{
+		Scheduler:    "none",
+		Version:      version,
+		ConfigPath:   configPath,
+		ActiveAlerts: []ActiveAlert{},
+	}
+	if name, ok := installedScheduler(); ok {
+		st.Installed = true
+		st.Scheduler = name
+	}
+
+	if _, err := loadConfig(configPath); err != nil {
+		st.ConfigError = err.Error()
+	} else {
+		st.ConfigValid = true
+	}
+
+	if j, err := loadJournal(stateDir); err == nil {
+		st.LastRun = j.LastRun
+	}
+	st.SpoolBacklog = countSpool(stateDir)
+	st.SinkBacklogs = sinkBacklogs(stateDir)
+	st.SinkHealth = loadSinkHealth(stateDir)
+	if active := activeAlerts(loadAlertStates(stateDir)); active != nil {
+		st.ActiveAlerts = active
+	}
+	return st
+}
+
+func printStatus(st Status, asJSON bool) {
+	if asJSON {
+		b, _ := json.MarshalIndent(st, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+
+	if st.Installed {
+		fmt.Println("INSTALLED")
+	} else {
+		fmt.Println("NOT_INSTALLED")
+	}
+	fmt.Println("  " + trf("Version:       %s", st.Version))
+	fmt.Println("  " + trf("Scheduler:     %s", st.Scheduler))
+	if st.ConfigValid {
+		fmt.Println("  " + trf("Config:        %s (ok)", st.ConfigPath))
+	} else {
+		fmt.Println("  " + trf("Config:        %s (invalid: %s)", st.ConfigPath, st.ConfigError))
+	}
+	if st.LastRun == nil {
+		fmt.Println("  " + tr("Last run:      never"))
+	} else {
+		fmt.Println("  " + trf("Last run:      %s (%dms)", st.LastRun.Time.Local().Format(time.RFC3339), st.LastRun.DurationMS))
+		if st.LastRun.Sent {
+			fmt.Println("  " + tr("Last send:     ok"))
+		} else if st.LastRun.ErrorKind != "" {
+			fmt.Println("  " + trf("Last send:     failed (%s): %s", st.LastRun.ErrorKind, st.LastRun.Error))
+		} else {
+			fmt.Println("  " + trf("Last send:     failed: %s", st.LastRun.Error))
+		}
+	}
+	fmt.Println("  " + trf("Spool:         %d pending", st.SpoolBacklog))
+	for _, name := range sortedKeys(st.SinkBacklogs) {
+		fmt.Println("    " + trf("%s: %d pending", name, st.SinkBacklogs[name]))
+	}
+	if len(st.SinkHealth) > 0 {
+		fmt.Println("  " + tr("Sinks:"))
+		for _, name := range sortedKeys(st.SinkHealth) {
+			h := st.SinkHealth[name]
+			switch {
+			case h.paused(time.Now()):
+				fmt.Println("    " + trf("%s: paused until %s after %d failures: %s", name, h.PausedUntil.Local().Format(time.RFC3339), h.Failures, h.LastError))
+			case h.Failures > 0:
+				fmt.Println("    " + trf("%s: %d failures in a row: %s", name, h.Failures, h.LastError))
+			default:
+				fmt.Println("    " + trf("%s: ok, last sent %s", name, h.LastSuccess.Local().Format(time.RFC3339)))
+			}
+		}
+	}
+	if len(st.ActiveAlerts) == 0 {
+		fmt.Println("  " + tr("Alerts:        none"))
+	} else {
+		fmt.Println("  " + trf("Alerts:        %d active", len(st.ActiveAlerts)))
+		for _, a := range st.ActiveAlerts {
+			fmt.Printf("    - %s: %s (%s)\n", a.Name, a.When, a.Value)
+		}
+	}
+}
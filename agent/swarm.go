@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+)
+
+const (
+	dockerSwarmTimeout = 15 * time.Second
+
+	// maxSwarmTasks limita as tasks com problema listadas por servico; um
+	// servico em crash loop acumula uma por tentativa.
+	maxSwarmTasks = 10
+)
+
+// SwarmStatus e a visao do cluster Swarm a partir de um manager: os nos e
+// os servicos. Workers nao enxergam o cluster e nao mandam a secao.
+type SwarmStatus struct {
+	NodeID    string         `json:"nodeId"`
+	ClusterID string         `json:"clusterId,omitempty"`
+	Nodes     []SwarmNode    `json:"nodes"`
+	Services  []SwarmService `json:"services"`
+
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// SwarmNode e uma linha do "docker node ls". Status e ready ou down,
+// Availability e active, pause ou drain, e ManagerStatus (leader,
+// reachable ou unreachable) so vem nos managers.
+type SwarmNode struct {
+	ID            string `json:"id"`
+	Hostname      string `json:"hostname"`
+	Role          string `json:"role"`
+	Status        string `json:"status"`
+	Availability  string `json:"availability"`
+	ManagerStatus string `json:"managerStatus,omitempty"`
+	EngineVersion string `json:"engineVersion,omitempty"`
+}
+
+// SwarmService compara as replicas desejadas com as que estao rodando.
+// Servicos global desejam uma por no elegivel. Tasks lista as que deveriam
+// estar rodando e nao estao (pending, preparing, rejected...).
+type SwarmService struct {
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	Image           string      `json:"image,omitempty"`
+	Mode            string      `json:"mode"`
+	DesiredReplicas int         `json:"desiredReplicas"`
+	RunningReplicas int         `json:"runningReplicas"`
+	Tasks           []SwarmTask `json:"tasks,omitempty"`
+}
+
+// SwarmTask e uma task fora do estado desejado, com o erro do agendador ou
+// do container.
+type SwarmTask struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Node  string `json:"node,omitempty"`
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+func (s *SwarmStatus) setEndpoint(name string) { s.Endpoint = name }
+
+// collectSwarm procura o primeiro daemon docker que seja manager de um
+// Swarm; nil quando nenhum e. Os daemons depois dele nem sao consultados.
+func collectSwarm(ctx context.Context, endpoints []dockerEndpoint) (*SwarmStatus, error) {
+	var manager bool
+	found, err := forEachDockerEndpoint(ctx, endpoints, func(ctx context.Context, ep dockerEndpoint) ([]SwarmStatus, error) {
+		if manager || ep.runtime() != runtimeDocker {
+			return nil, nil
+		}
+		s, err := endpointSwarm(ctx, ep)
+		manager = len(s) > 0
+		return s, err
+	})
+	if len(found) > 0 {
+		return &found[0], nil
+	}
+	return nil, err
+}
+
+func endpointSwarm(ctx context.Context, ep dockerEndpoint) ([]SwarmStatus, error) {
+	out, err := dockerCommand(ctx, ep, "info", "--format", "{{json .Swarm}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	s, manager := parseSwarmInfo(out)
+	if !manager {
+		return nil, nil
+	}
+	// sem os nos os servicos ainda valem, e vice-versa
+	var errs []string
+	if s.Nodes, err = swarmNodes(ctx, ep); err != nil {
+		errs = append(errs, "node ls: "+collectors.CommandError(err))
+	}
+	if s.Services, err = swarmServices(ctx, ep); err != nil {
+		errs = append(errs, "service ls: "+collectors.CommandError(err))
+	}
+	s.Error = strings.Join(errs, "; ")
+	return []SwarmStatus{*s}, nil
+}
+
+// parseSwarmInfo le o Swarm do "docker info"; so um manager ativo tem
+// ControlAvailable.
+func parseSwarmInfo(out []byte) (*SwarmStatus, bool) {
+	var raw struct {
+		NodeID           string `json:"NodeID"`
+		LocalNodeState   string `json:"LocalNodeState"`
+		ControlAvailable bool   `json:"ControlAvailable"`
+		Cluster          *struct {
+			ID string `json:"ID"`
+		} `json:"Cluster"`
+	}
+	if json.Unmarshal(out, &raw) != nil || raw.LocalNodeState != "active" || !raw.ControlAvailable {
+		return nil, false
+	}
+	s := &SwarmStatus{NodeID: raw.NodeID, Nodes: []SwarmNode{}, Services: []SwarmService{}}
+	if raw.Cluster != nil {
+		s.ClusterID = raw.Cluster.ID
+	}
+	return s, true
+}
+
+func swarmNodes(ctx context.Context, ep dockerEndpoint) ([]SwarmNode, error) {
+	out, err := dockerCommand(ctx, ep, "node", "ls", "--format", "{{json .}}").Output()
+	if err != nil {
+		return []SwarmNode{}, err
+	}
+	return parseSwarmNodes(string(out)), nil
+}
+
+func parseSwarmNodes(out string) []SwarmNode {
+	nodes := []SwarmNode{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var raw struct {
+			ID            string `json:"ID"`
+			Hostname      string `json:"Hostname"`
+			Status        string `json:"Status"`
+			Availability  string `json:"Availability"`
+			ManagerStatus string `json:"ManagerStatus"`
+			EngineVersion string `json:"EngineVersion"`
+		}
+		if json.Unmarshal([]byte(line), &raw) != nil || raw.ID == "" {
+			continue
+		}
+		n := SwarmNode{
+			ID:            raw.ID,
+			Hostname:      raw.Hostname,
+			Role:          "worker",
+			Status:        strings.ToLower(raw.Status),
+			Availability:  strings.ToLower(raw.Availability),
+			ManagerStatus: strings.ToLower(raw.ManagerStatus),
+			EngineVersion: raw.EngineVersion,
+		}
+		if n.ManagerStatus != "" {
+			n.Role = "manager"
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Hostname < nodes[j].Hostname })
+	return nodes
+}
+
+func swarmServices(ctx context.Context, ep dockerEndpoint) ([]SwarmService, error) {
+	out, err := dockerCommand(ctx, ep, "service", "ls", "--format", "{{json .}}").Output()
+	if err != nil {
+		return []SwarmService{}, err
+	}
+	services := parseSwarmServices(string(out))
+	if len(services) == 0 {
+		return services, nil
+	}
+
+	// So as tasks que deveriam rodar; o historico de tasks encerradas fica de
+	// fora. Sem elas as replicas ainda valem.
+	args := []string{"service", "ps", "--no-trunc", "--filter", "desired-state=running", "--format", "{{json .}}"}
+	for _, s := range services {
+		args = append(args, s.ID)
+	}
+	if out, err := dockerCommand(ctx, ep, args...).Output(); err == nil {
+		addSwarmTasks(services, string(out))
+	}
+	return services, nil
+}
+
+func parseSwarmServices(out string) []SwarmService {
+	services := []SwarmService{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var raw struct {
+			ID       string `json:"ID"`
+			Name     string `json:"Name"`
+			Image    string `json:"Image"`
+			Mode     string `json:"Mode"`
+			Replicas string `json:"Replicas"`
+		}
+		if json.Unmarshal([]byte(line), &raw) != nil || raw.ID == "" {
+			continue
+		}
+		s := SwarmService{ID: raw.ID, Name: raw.Name, Image: raw.Image, Mode: raw.Mode}
+		// "2/3", "3/3 (max 1 per node)" ou, em jobs, "0/1 (1/1 completed)"
+		fmt.Sscanf(raw.Replicas, "%d/%d", &s.RunningReplicas, &s.DesiredReplicas)
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+// addSwarmTasks le o "docker service ps" e anexa a cada servico as tasks
+// cujo estado atual ("Running 2 hours ago") nao e running.
+func addSwarmTasks(services []SwarmService, out string) {
+	byName := make(map[string]*SwarmService, len(services))
+	for i := range services {
+		byName[services[i].Name] = &services[i]
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var raw struct {
+			ID           string `json:"ID"`
+			Name         string `json:"Name"`
+			Node         string `json:"Node"`
+			CurrentState string `json:"CurrentState"`
+			Error        string `json:"Error"`
+		}
+		if json.Unmarshal([]byte(line), &raw) != nil || raw.ID == "" {
+			continue
+		}
+		state, _, _ := strings.Cut(raw.CurrentState, " ")
+		state = strings.ToLower(state)
+		if state == "running" || state == "" {
+			continue
+		}
+		// "web.1" em servicos replicated, "web.<no>" em global
+		name := raw.Name
+		if i := strings.LastIndex(name, "."); i > 0 {
+			name = name[:i]
+		}
+		s := byName[name]
+		if s == nil || len(s.Tasks) >= maxSwarmTasks {
+			continue
+		}
+		s.Tasks = append(s.Tasks, SwarmTask{
+			ID:    collectors.ShortContainerID(raw.ID),
+			Name:  raw.Name,
+			Node:  raw.Node,
+			State: state,
+			Error: strings.TrimSpace(raw.Error),
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSwarmInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		manager bool
+	}{
+		{"manager", `{"NodeID":"n1","LocalNodeState":"active","ControlAvailable":true,"Cluster":{"ID":"c1"}}`, true},
+		{"worker", `{"NodeID":"n2","LocalNodeState":"active","ControlAvailable":false}`, false},
+		{"inactive", `{"NodeID":"","LocalNodeState":"inactive","ControlAvailable":false}`, false},
+		{"garbage", `template: :1: unexpected`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, manager := parseSwarmInfo([]byte(tt.out))
+			if manager != tt.manager {
+				t.Fatalf("manager = %v, want %v", manager, tt.manager)
+			}
+			if manager && (s.NodeID != "n1" || s.ClusterID != "c1") {
+				t.Errorf("got %+v", s)
+			}
+		})
+	}
+}
+
+func TestParseSwarmNodes(t *testing.T) {
+	out := `{"Availability":"Active","EngineVersion":"24.0.7","Hostname":"worker1","ID":"w1","ManagerStatus":"","Self":false,"Status":"Down","TLSStatus":"Ready"}
+{"Availability":"Drain","EngineVersion":"24.0.7","Hostname":"manager1","ID":"m1","ManagerStatus":"Leader","Self":true,"Status":"Ready","TLSStatus":"Ready"}`
+	want := []SwarmNode{
+		{ID: "m1", Hostname: "manager1", Role: "manager", Status: "ready", Availability: "drain", ManagerStatus: "leader", EngineVersion: "24.0.7"},
+		{ID: "w1", Hostname: "worker1", Role: "worker", Status: "down", Availability: "active", EngineVersion: "24.0.7"},
+	}
+	if got := parseSwarmNodes(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSwarmServicesAndTasks(t *testing.T) {
+	services := parseSwarmServices(`{"ID":"s1","Image":"nginx:1.25","Mode":"replicated","Name":"web","Ports":"*:80->80/tcp","Replicas":"2/3"}
+{"ID":"s2","Image":"prom/node-exporter","Mode":"global","Name":"exporter","Ports":"","Replicas":"2/2"}
+{"ID":"s3","Image":"app","Mode":"replicated","Name":"api","Ports":"","Replicas":"1/1 (max 1 per node)"}
+{"ID":"s4","Image":"migrate","Mode":"replicated-job","Name":"migrate","Ports":"","Replicas":"0/1 (1/1 completed)"}`)
+	addSwarmTasks(services, `{"CurrentState":"Running 2 hours ago","DesiredState":"Running","Error":"","ID":"t1","Image":"nginx:1.25","Name":"web.1","Node":"worker1","Ports":""}
+{"CurrentState":"Pending 5 minutes ago","DesiredState":"Running","Error":"no suitable node (insufficient resources on 2 nodes)","ID":"t3aaaaaaaaaaaaaaaa","Image":"nginx:1.25","Name":"web.3","Node":"","Ports":""}
+{"CurrentState":"Running 1 hour ago","DesiredState":"Running","Error":"","ID":"t4","Image":"prom/node-exporter","Name":"exporter.m1","Node":"manager1","Ports":""}`)
+
+	want := []SwarmService{
+		{ID: "s3", Name: "api", Image: "app", Mode: "replicated", DesiredReplicas: 1, RunningReplicas: 1},
+		{ID: "s2", Name: "exporter", Image: "prom/node-exporter", Mode: "global", DesiredReplicas: 2, RunningReplicas: 2},
+		{ID: "s4", Name: "migrate", Image: "migrate", Mode: "replicated-job", DesiredReplicas: 1},
+		{ID: "s1", Name: "web", Image: "nginx:1.25", Mode: "replicated", DesiredReplicas: 3, RunningReplicas: 2, Tasks: []SwarmTask{
+			{ID: "t3aaaaaaaaaa", Name: "web.3", State: "pending", Error: "no suitable node (insufficient resources on 2 nodes)"},
+		}},
+	}
+	if !reflect.DeepEqual(services, want) {
+		t.Errorf("got %+v\nwant %+v", services, want)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const systemdTimeout = 10 * time.Second
+
+// SystemdUnit e o estado de uma unit do systemd: saber se o nginx esta de pe
+// importa tanto quanto a CPU.
+type SystemdUnit struct {
+	Name        string `json:"name"`
+	LoadState   string `json:"loadState"`
+	ActiveState string `json:"activeState"`
+	SubState    string `json:"subState"`
+	Restarts    int    `json:"restarts"`
+	MemoryBytes int64  `json:"memoryBytes,omitempty"`
+}
+
+func validateSystemdUnits(units []string) error {
+	for _, u := range units {
+		// o nome vai para a linha de comando do systemctl
+		if u == "" || strings.HasPrefix(u, "-") || strings.ContainsAny(u, " \t") {
+			return fmt.Errorf("systemd_units: invalid unit %q", u)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"vaultrix-agent/internal/fsutil"
+)
+
+// collectSystemdUnits le o estado das units configuradas, ou de todas as
+// units com falha quando a lista esta vazia, via systemctl show.
+func collectSystemdUnits(ctx context.Context, units []string) ([]SystemdUnit, error) {
+	// mesmo teste do sd_booted(): sem ele o systemctl existe mas nao responde
+	if !fsutil.FileExists("/run/systemd/system") {
+		return nil, nil
+	}
+	if len(units) == 0 {
+		failed, err := failedSystemdUnits(ctx)
+		if err != nil || len(failed) == 0 {
+			return []SystemdUnit{}, err
+		}
+		units = failed
+	}
+
+	args := append([]string{"show", "--property=Id,LoadState,ActiveState,SubState,NRestarts,MemoryCurrent", "--"}, units...)
+	out, err := exec.CommandContext(ctx, "systemctl", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseSystemctlShow(string(out)), nil
+}
+
+func failedSystemdUnits(ctx context.Context) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "systemctl", "list-units", "--state=failed", "--all", "--no-legend", "--plain").Output()
+	if err != nil {
+		return nil, err
+	}
+	var units []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			units = append(units, fields[0])
+		}
+	}
+	return units, nil
+}
+
+// parseSystemctlShow le os blocos CHAVE=valor, um por unit, separados por
+// linha em branco.
+func parseSystemctlShow(out string) []SystemdUnit {
+	units := []SystemdUnit{}
+	for _, block := range strings.Split(strings.TrimSpace(out), "\n\n") {
+		var u SystemdUnit
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+			switch key {
+			case "Id":
+				u.Name = value
+			case "LoadState":
+				u.LoadState = value
+			case "ActiveState":
+				u.ActiveState = value
+			case "SubState":
+				u.SubState = value
+			case "NRestarts":
+				u.Restarts, _ = strconv.Atoi(value)
+			case "MemoryCurrent":
+				// "[not set]" ou UINT64_MAX sem accounting de memoria
+				if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+					u.MemoryBytes = n
+				}
+			}
+		}
+		if u.Name != "" {
+			units = append(units, u)
+		}
+	}
+	return units
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+)
+
+// collectSystemdUnits so existe no Linux.
+func collectSystemdUnits(ctx context.Context, units []string) ([]SystemdUnit, error) {
+	return nil, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// textfile_dir e o equivalente ao collector textfile do node_exporter: os
+// scripts de cron que gravam metricas em <dir>/*.prom continuam funcionando
+// depois da migracao, sem mudar nada. Cada arquivo vai no payload em
+// "textfile", pelo nome, com o horario da ultima escrita: um script que
+// parou de rodar aparece como um arquivo velho, nao como ausencia.
+const (
+	textfileTimeout = 5 * time.Second
+	textfileExt     = ".prom"
+)
+
+// TextfileResult e o conteudo de um arquivo .prom. Como no node_exporter,
+// os arquivos devem ser gravados de forma atomica (escrever ao lado e
+// renomear), ou o ciclo pode ler um arquivo pela metade.
+type TextfileResult struct {
+	ModTime time.Time `json:"mtime"`
+	ScrapeResult
+}
+
+func validateTextfileDir(dir string) error {
+	if dir != "" && !filepath.IsAbs(dir) {
+		return errors.New("textfile_dir must be an absolute path")
+	}
+	return nil
+}
+
+// collectTextfiles le os .prom do diretorio. Um diretorio que nao pode ser
+// lido e erro do coletor; um arquivo ilegivel ou grande demais tem o erro
+// no proprio resultado, e os demais seguem.
+func collectTextfiles(ctx context.Context, dir string) (map[string]TextfileResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := map[string]TextfileResult{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != textfileExt {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		results[strings.TrimSuffix(name, textfileExt)] = readTextfile(filepath.Join(dir, name))
+	}
+	return results, nil
+}
+
+func readTextfile(path string) TextfileResult {
+	res := TextfileResult{ScrapeResult: ScrapeResult{Samples: []ScrapedSample{}}}
+	f, err := os.Open(path)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		res.ModTime = info.ModTime().UTC()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(f, maxScrapeBody+1))
+	if err == nil && len(body) > maxScrapeBody {
+		err = fmt.Errorf("file exceeds %d MB", maxScrapeBody>>20)
+	}
+	if err == nil {
+		err = parseExposition(body, func(string) bool { return true }, config.DefaultScrapeMaxSamples, &res.ScrapeResult)
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
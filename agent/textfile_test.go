@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectTextfiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"backup.prom":  "# TYPE backup_last_success_timestamp gauge\nbackup_last_success_timestamp{job=\"db\"} 1714564800\nbackup_size_bytes 2048\n",
+		"apt.prom":     "apt_upgrades_pending 3\n",
+		".tmp.prom":    "partial 1\n",
+		"notes.txt":    "ignored 1\n",
+		"empty.prom":   "",
+		"invalid.prom": "garbage\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	written := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "apt.prom"), written, written)
+	os.Mkdir(filepath.Join(dir, "sub.prom"), 0o755)
+
+	got, err := collectTextfiles(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	if len(got) != 4 {
+		t.Fatalf("files = %v, want apt, backup, empty and invalid", names)
+	}
+	wantBackup := []ScrapedSample{
+		{Name: "backup_last_success_timestamp", Labels: map[string]string{"job": "db"}, Value: 1714564800},
+		{Name: "backup_size_bytes", Value: 2048},
+	}
+	if !reflect.DeepEqual(got["backup"].Samples, wantBackup) {
+		t.Errorf("backup = %+v, want %+v", got["backup"].Samples, wantBackup)
+	}
+	if apt := got["apt"]; !apt.ModTime.Equal(written) || len(apt.Samples) != 1 {
+		t.Errorf("apt = %+v, want one sample written at %s", apt, written)
+	}
+	if len(got["invalid"].Samples) != 0 || got["invalid"].Error != "" {
+		t.Errorf("invalid = %+v, want no samples and no error", got["invalid"])
+	}
+
+	if _, err := collectTextfiles(context.Background(), filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing directory: want an error")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+// Niveis de amostragem de estatisticas por container.
+const (
+	tierFull      = "full"
+	tierSampled   = "sampled"
+	tierInventory = "inventory"
+)
+
+func validateStatsTiers(tiers []config.StatsTier) error {
+	for i, t := range tiers {
+		switch t.Tier {
+		case tierFull, tierInventory:
+		case tierSampled:
+			if t.Every < 2 {
+				return fmt.Errorf("stats_tiers[%d]: sampled tier requires every >= 2", i)
+			}
+		default:
+			return fmt.Errorf("stats_tiers[%d]: invalid tier %q", i, t.Tier)
+		}
+		f := config.ContainerFilter{Include: t.Match, Label: t.Label}
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("stats_tiers[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// statsCycle numera as execucoes a partir do relogio, para que o modo cron
+// (um processo por execucao) amostre sem precisar guardar estado.
+func statsCycle(cfg config.Config, now time.Time) int64 {
+	interval := cfg.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	return now.Unix() / int64(interval*60)
+}
+
+// statsTargets devolve os IDs dos containers em execucao que devem ter
+// estatisticas coletadas neste ciclo.
+func statsTargets(tiers []config.StatsTier, containers []ContainerStatus, cycle int64) []string {
+	var ids []string
+	for _, c := range containers {
+		if c.State != "running" || c.ID == "" {
+			continue
+		}
+		tier := config.StatsTier{Tier: tierFull}
+		for _, t := range tiers {
+			f := config.ContainerFilter{Include: t.Match, Label: t.Label}
+			if containerMatches(f, c) {
+				tier = t
+				break
+			}
+		}
+		switch tier.Tier {
+		case tierInventory:
+			continue
+		case tierSampled:
+			if cycle%int64(tier.Every) != 0 {
+				continue
+			}
+		}
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+)
+
+// telemetryResponse e o corpo devolvido pela API apos um envio. Quando o
+// servidor decide rotacionar o token, ele envia o novo em rotate_token; o
+// token antigo deve continuar aceito ate o primeiro envio com o novo.
+type telemetryResponse struct {
+	RotateToken string `json:"rotate_token,omitempty"`
+}
+
+// handleTelemetryResponse aplica instrucoes do servidor contidas na resposta.
+func handleTelemetryResponse(cfg config.Config, body []byte) {
+	var resp telemetryResponse
+	if len(body) == 0 || json.Unmarshal(body, &resp) != nil {
+		return
+	}
+	if resp.RotateToken != "" && resp.RotateToken != cfg.Token {
+		if err := rotateToken(cfg, resp.RotateToken); err != nil {
+			fmt.Fprintf(os.Stderr, "token rotation: %v\n", err)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "token rotated")
+	}
+}
+
+// rotateToken troca apenas a chave "token" do arquivo de config, ou o
+// conteudo do token_file quando o token vem de la. Um token vindo de
+// VAULTRIX_TOKEN nao pode ser trocado pelo agente: gravado no config, ele
+// continuaria perdendo para a variavel.
+func rotateToken(cfg config.Config, newToken string) error {
+	if len(newToken) < 16 {
+		return errors.New("new token too short")
+	}
+	if cfg.TokenEnv != "" {
+		return fmt.Errorf("the token comes from %s and cannot be rotated by the agent; update the variable, or use VAULTRIX_TOKEN_FILE or token_file", cfg.TokenEnv)
+	}
+	if cfg.TokenFile != "" {
+		return fsutil.WriteFileAtomic(cfg.TokenFile, []byte(newToken+"\n"), 0o600)
+	}
+	if cfg.ConfigPath == "" {
+		return errors.New("config file unknown; token passed by flags cannot be rotated")
+	}
+	return config.UpdateFile(cfg.ConfigPath, func(raw map[string]json.RawMessage) error {
+		b, err := json.Marshal(newToken)
+		raw["token"] = b
+		return err
+	})
+}
+
+// runRotateTokenCommand pede um token novo ao endpoint dedicado e o aplica.
+func runRotateTokenCommand(args []string) {
+	fs := flag.NewFlagSet("rotate-token", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	body, err := callAPI(cfg, cfg.TokenRotateURLFor, func(endpoint string) ([]byte, error) {
+		return requestTokenRotation(cfg, endpoint)
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil || out.Token == "" {
+		fatal(errors.New("rotate endpoint did not return a token"))
+	}
+	if err := rotateToken(cfg, out.Token); err != nil {
+		fatal(err)
+	}
+	fmt.Println(tr("Token rotated."))
+}
+
+// requestTokenRotation faz o POST de rotacao e devolve o corpo da resposta.
+func requestTokenRotation(cfg config.Config, endpoint string) ([]byte, error) {
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = 15 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode >= 300 {
+		return nil, &apiError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestHandleTelemetryResponseRotatesToken(t *testing.T) {
+	const oldToken = "old-token-0123456789"
+	tests := []struct {
+		name      string
+		body      string
+		wantToken string
+	}{
+		{"rotation", `{"rotate_token": "new-token-0123456789"}`, "new-token-0123456789"},
+		{"no instruction", `{"ok": true}`, oldToken},
+		{"same token", `{"rotate_token": "` + oldToken + `"}`, oldToken},
+		{"too short", `{"rotate_token": "short"}`, oldToken},
+		{"not json", `accepted`, oldToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(`{"token": "`+oldToken+`", "api_url": "https://vaultrix.example.com/api/telemetry", "interval_min": 5}`), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := config.Config{Token: oldToken, ConfigPath: path}
+			handleTelemetryResponse(cfg, []byte(tt.body))
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var raw map[string]any
+			if err := json.Unmarshal(b, &raw); err != nil {
+				t.Fatal(err)
+			}
+			if raw["token"] != tt.wantToken {
+				t.Errorf("token = %v, want %s", raw["token"], tt.wantToken)
+			}
+			// as demais chaves ficam como estavam
+			if raw["interval_min"] != 5.0 || raw["api_url"] != "https://vaultrix.example.com/api/telemetry" {
+				t.Errorf("config rewritten: %s", b)
+			}
+		})
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	// com token_file, o token novo vai para o arquivo e nao para o config
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := rotateToken(config.Config{TokenFile: tokenFile}, "new-token-0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(tokenFile); string(b) != "new-token-0123456789\n" {
+		t.Errorf("token file = %q", b)
+	}
+
+	// gravado no config, o token perderia para a variavel de ambiente
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{"token": "old-token-0123456789"}`), 0o600)
+	if err := rotateToken(config.Config{ConfigPath: configPath, TokenEnv: "VAULTRIX_TOKEN"}, "new-token-0123456789"); err == nil {
+		t.Error("want an error for a token from VAULTRIX_TOKEN")
+	}
+
+	// sem arquivo de config, o token veio das flags
+	if err := rotateToken(config.Config{}, "new-token-0123456789"); err == nil {
+		t.Error("want an error for a token passed by flags")
+	}
+}
+
+func TestTokenRotateURL(t *testing.T) {
+	cfg := config.Config{ApiURL: "https://vaultrix.example.com:8443/api/telemetry"}
+	got, err := cfg.TokenRotateURLFor(cfg.ApiURL)
+	if err != nil || got != "https://vaultrix.example.com:8443/api/agent/token/rotate" {
+		t.Errorf("tokenRotateURL = %q, %v", got, err)
+	}
+}
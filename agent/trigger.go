@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/internal/fsutil"
+	"vaultrix-agent/internal/transport"
+)
+
+// Coleta fora do ciclo ("atualizar agora"). O pedido chega de dois jeitos:
+// "vaultrix-agent trigger" grava o arquivo trigger no diretorio de estado,
+// que o daemon confere a cada triggerPollInterval; e, com
+// command_poll_seconds, o daemon pergunta a API se ha um pedido pendente.
+// No modo cron nao ha daemon para ler o arquivo, e o proprio comando faz o
+// ciclo.
+const (
+	triggerFile         = "trigger"
+	triggerPollInterval = 2 * time.Second
+	// triggerWait e quanto o comando espera o daemon pegar o pedido antes de
+	// concluir que nao ha daemon.
+	triggerWait = 3 * triggerPollInterval
+	// minTriggerGap limita coletas seguidas, para um servidor (ou script)
+	// insistente nao transformar o agente em um loop.
+	minTriggerGap        = 10 * time.Second
+	minCommandPoll       = 10
+	commandsFetchTimeout = 10 * time.Second
+)
+
+type triggerRequest struct {
+	Source      string    `json:"source"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+func triggerPath(stateDir string) string {
+	return filepath.Join(stateDir, triggerFile)
+}
+
+func requestTrigger(stateDir, source string) error {
+	if err := fsutil.EnsureDir(stateDir); err != nil {
+		return err
+	}
+	b, _ := json.Marshal(triggerRequest{Source: source, RequestedAt: time.Now().UTC()})
+	return fsutil.WriteFileAtomic(triggerPath(stateDir), b, 0o600)
+}
+
+// takeTrigger consome um pedido pendente.
+func takeTrigger(stateDir string) (triggerRequest, bool) {
+	var req triggerRequest
+	b, err := os.ReadFile(triggerPath(stateDir))
+	if err != nil {
+		return req, false
+	}
+	os.Remove(triggerPath(stateDir))
+	json.Unmarshal(b, &req)
+	return req, true
+}
+
+func validateCommandPoll(seconds int) error {
+	if seconds != 0 && seconds < minCommandPoll {
+		return fmt.Errorf("command_poll_seconds must be 0 (off) or at least %d", minCommandPoll)
+	}
+	return nil
+}
+
+// agentCommands e a resposta da API: 204 sem corpo quando nao ha nada, ou
+// {"collect": true} para uma coleta imediata.
+type agentCommands struct {
+	Collect bool `json:"collect"`
+}
+
+func fetchCommands(cfg config.Config) (agentCommands, error) {
+	return callAPI(cfg, cfg.CommandsURLFor, func(endpoint string) (agentCommands, error) {
+		return fetchCommandsFrom(cfg, endpoint)
+	})
+}
+
+func fetchCommandsFrom(cfg config.Config, endpoint string) (agentCommands, error) {
+	var cmds agentCommands
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return cmds, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	req.Header.Set("Accept", "application/json")
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return cmds, err
+	}
+	client.Timeout = commandsFetchTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return cmds, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return cmds, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return cmds, nil
+	case resp.StatusCode >= 300:
+		return cmds, &apiError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if err := json.Unmarshal(body, &cmds); err != nil {
+		return cmds, fmt.Errorf("invalid JSON from %s", endpoint)
+	}
+	return cmds, nil
+}
+
+// watchTriggers entrega em out os pedidos de coleta ate ctx acabar. A API
+// so e consultada com command_poll_seconds; cfg devolve a config do ciclo
+// mais recente, que pode ter ligado ou desligado a consulta.
+func watchTriggers(ctx context.Context, stateDir string, cfg func() config.Config, out chan<- string) {
+	ticker := time.NewTicker(triggerPollInterval)
+	defer ticker.Stop()
+	var lastPoll time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if req, ok := takeTrigger(stateDir); ok {
+				offerTrigger(out, orDefault(req.Source, "local"))
+				continue
+			}
+			c := cfg()
+			poll := time.Duration(c.CommandPollSeconds) * time.Second
+			if poll == 0 || now.Sub(lastPoll) < poll {
+				continue
+			}
+			lastPoll = now
+			cmds, err := fetchCommands(c)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "commands: %v\n", err)
+				continue
+			}
+			if cmds.Collect {
+				offerTrigger(out, "api")
+			}
+		}
+	}
+}
+
+// offerTrigger nao bloqueia: um pedido ja pendente cobre o novo.
+func offerTrigger(out chan<- string, source string) {
+	select {
+	case out <- source:
+	default:
+	}
+}
+
+func runTriggerCommand(args []string) {
+	fs := flag.NewFlagSet("trigger", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, tr("Config file path"))
+	stateDir := fs.String("state-dir", defaultStateDir, tr("Agent state directory"))
+	fs.Parse(args)
+
+	if err := requestTrigger(*stateDir, "local"); err != nil {
+		fatal(err)
+	}
+	deadline := time.Now().Add(triggerWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(triggerPollInterval / 4)
+		if _, err := os.Stat(triggerPath(*stateDir)); errors.Is(err, os.ErrNotExist) {
+			fmt.Println(tr("Collection requested; the running agent is sending it now."))
+			return
+		}
+	}
+
+	// ninguem pegou o pedido: modo cron, o ciclo roda aqui
+	os.Remove(triggerPath(*stateDir))
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	transport.SetDNSCacheDir(*stateDir)
+	transport.SetEndpointStateDir(*stateDir)
+	setRolloutStateDir(*stateDir)
+	setLogScanStateDir(*stateDir)
+	migrateState(*stateDir)
+	cfg = applyRemoteConfig(cfg, *stateDir)
+	started := time.Now()
+	err = runCycle(cfg, *stateDir)
+	recordRun(*stateDir, cfg, started, err)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(tr("No running agent found; collected and sent."))
+}
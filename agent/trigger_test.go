@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestTriggerFile(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := takeTrigger(dir); ok {
+		t.Fatal("trigger found in an empty state dir")
+	}
+	if err := requestTrigger(dir, "local"); err != nil {
+		t.Fatal(err)
+	}
+	req, ok := takeTrigger(dir)
+	if !ok || req.Source != "local" || req.RequestedAt.IsZero() {
+		t.Fatalf("takeTrigger() = %+v, %v", req, ok)
+	}
+	if _, ok := takeTrigger(dir); ok {
+		t.Error("trigger taken twice")
+	}
+}
+
+func TestValidateCommandPoll(t *testing.T) {
+	tests := []struct {
+		seconds int
+		wantErr bool
+	}{
+		{0, false},
+		{minCommandPoll, false},
+		{60, false},
+		{5, true},
+		{-1, true},
+	}
+	for _, tt := range tests {
+		if err := validateCommandPoll(tt.seconds); (err != nil) != tt.wantErr {
+			t.Errorf("validateCommandPoll(%d) = %v, wantErr %v", tt.seconds, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFetchCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantCollect bool
+		wantErr     bool
+	}{
+		{"nothing pending", http.StatusNoContent, "", false, false},
+		{"collect", http.StatusOK, `{"collect":true}`, true, false},
+		{"unknown fields", http.StatusOK, `{"collect":false,"other":1}`, false, false},
+		{"server error", http.StatusInternalServerError, "boom", false, true},
+		{"invalid json", http.StatusOK, "<html>", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/agent/commands" || r.Header.Get("Authorization") != "Bearer tok" {
+					t.Errorf("request %s %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			cmds, err := fetchCommands(config.Config{Token: "tok", ApiURL: srv.URL + "/api/telemetry"})
+			if (err != nil) != tt.wantErr || cmds.Collect != tt.wantCollect {
+				t.Errorf("fetchCommands() = %+v, %v; want collect %v, wantErr %v", cmds, err, tt.wantCollect, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOfferTriggerDoesNotBlock(t *testing.T) {
+	out := make(chan string, 1)
+	offerTrigger(out, "api")
+	offerTrigger(out, "local")
+	if got := <-out; got != "api" {
+		t.Errorf("pending trigger = %q, want the first one", got)
+	}
+}
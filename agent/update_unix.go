@@ -0,0 +1,26 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// replaceExecutable troca o binario com um rename, atomico no mesmo
+// diretorio; o processo em execucao continua com o arquivo antigo aberto.
+func replaceExecutable(newPath, target string) error {
+	return os.Rename(newPath, target)
+}
+
+func cleanupReplacedExecutable() {}
+
+// restartAgent reexecuta o binario novo no lugar do processo atual, com os
+// mesmos argumentos.
+func restartAgent() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// replaceExecutable contorna o bloqueio do Windows: o executavel em uso nao
+// pode ser sobrescrito, mas pode ser renomeado. O antigo vira .old e e
+// apagado na proxima execucao.
+func replaceExecutable(newPath, target string) error {
+	old := target + ".old"
+	os.Remove(old)
+	if err := os.Rename(target, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(newPath, target); err != nil {
+		os.Rename(old, target)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
+
+func cleanupReplacedExecutable() {
+	if exe, err := os.Executable(); err == nil {
+		os.Remove(exe + ".old")
+	}
+}
+
+// restartAgent nao tem como reiniciar o servico de dentro dele; a versao
+// nova entra no proximo start.
+func restartAgent() error {
+	return errors.New("restart the service to run the new version")
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	"vaultrix-agent/internal/config"
+	"vaultrix-agent/plugin"
+)
+
+const wasmPageSize = 64 * 1024
+
+// wasmCache guarda os modulos compilados entre ciclos do daemon.
+var wasmCache = wazero.NewCompilationCache()
+
+func isWasmPlugin(path string) bool {
+	return strings.HasSuffix(path, ".wasm")
+}
+
+// wasmPluginName e a chave do plugin em wasm_capabilities.
+func wasmPluginName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".wasm")
+}
+
+// startWasmPlugin instancia o modulo em um runtime proprio, com stdin e
+// stdout ligados ao cliente do protocolo. O limite de memoria vira limite de
+// paginas; o de CPU e o prazo de ctx.
+func startWasmPlugin(ctx context.Context, cfg config.Config, path string) (*plugin.Client, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := wazero.NewRuntimeConfig().WithCompilationCache(wasmCache).WithCloseOnContextDone(true)
+	if limits := cfg.PluginLimits.WithDefaults(); limits.MemoryMB > 0 {
+		rc = rc.WithMemoryLimitPages(uint32(min(limits.MemoryMB*mb/wasmPageSize, 65536)))
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, rc)
+
+	fail := func(err error) (*plugin.Client, error) {
+		rt.Close(context.Background())
+		return nil, err
+	}
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		return fail(err)
+	}
+	host := wasmHost{cfg: cfg, caps: cfg.WasmCapabilities[wasmPluginName(path)]}
+	if err := host.instantiate(ctx, rt); err != nil {
+		return fail(err)
+	}
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		return fail(err)
+	}
+
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	mc := wazero.NewModuleConfig().
+		WithName(wasmPluginName(path)).
+		WithArgs(wasmPluginName(path)).
+		WithStdin(stdinR).
+		WithStdout(stdoutW).
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := rt.InstantiateModule(ctx, compiled, mc)
+		var exit *sys.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 0 {
+			err = nil
+		}
+		stdoutW.CloseWithError(err)
+		stdinR.Close()
+		done <- err
+	}()
+
+	wait := func() error {
+		err := <-done
+		rt.Close(context.Background())
+		return err
+	}
+	return plugin.NewClient(stdinW, stdoutR, wait), nil
+}
+
+// wasmHost implementa as funcoes do modulo "vaultrix" para um plugin.
+type wasmHost struct {
+	cfg  config.Config
+	caps config.WasmCapabilities
+}
+
+type hostCall func(ctx context.Context, arg string) ([]byte, error)
+
+func (h wasmHost) instantiate(ctx context.Context, rt wazero.Runtime) error {
+	b := rt.NewHostModuleBuilder(plugin.HostModule)
+	for name, fn := range map[string]hostCall{
+		plugin.HostReadFile:   h.readFile,
+		plugin.HostRunCommand: h.runCommand,
+		plugin.HostHTTPGet:    h.httpGet,
+	} {
+		b = b.NewFunctionBuilder().WithFunc(wrapHostCall(name, fn)).Export(name)
+	}
+	_, err := b.Instantiate(ctx)
+	return err
+}
+
+// wrapHostCall faz a ponte entre a memoria do modulo e fn.
+func wrapHostCall(name string, fn hostCall) func(context.Context, api.Module, uint32, uint32, uint32, uint32) int64 {
+	return func(ctx context.Context, m api.Module, argPtr, argLen, bufPtr, bufLen uint32) int64 {
+		arg, ok := m.Memory().Read(argPtr, argLen)
+		if !ok {
+			return plugin.HostFailed
+		}
+		out, err := fn(ctx, string(arg))
+		if errors.Is(err, plugin.ErrDenied) {
+			fmt.Fprintf(os.Stderr, "plugin %s: %s denied: %q\n", m.Name(), name, arg)
+			return plugin.HostDenied
+		}
+		if err != nil {
+			return plugin.HostFailed
+		}
+		if !m.Memory().Write(bufPtr, out[:min(len(out), int(bufLen))]) {
+			return plugin.HostFailed
+		}
+		return int64(len(out))
+	}
+}
+
+func (h wasmHost) readFile(ctx context.Context, path string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		return nil, plugin.ErrDenied
+	}
+	// Symlinks sao resolvidos antes da checagem, para nao escapar dos
+	// caminhos liberados.
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	if !pathAllowed(h.caps.ReadPaths, path) || !pathAllowed(h.caps.ReadPaths, resolved) {
+		return nil, plugin.ErrDenied
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, plugin.HostMaxResponse))
+}
+
+func pathAllowed(allowed []string, path string) bool {
+	path = filepath.Clean(path)
+	for _, a := range allowed {
+		a = filepath.Clean(a)
+		if path == a || strings.HasPrefix(path, strings.TrimSuffix(a, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (h wasmHost) runCommand(ctx context.Context, arg string) ([]byte, error) {
+	argv := strings.Split(arg, "\x00")
+	if argv[0] == "" || !slices.Contains(h.caps.Commands, argv[0]) {
+		return nil, plugin.ErrDenied
+	}
+	out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+	if len(out) > plugin.HostMaxResponse {
+		out = out[:plugin.HostMaxResponse]
+	}
+	return out, err
+}
+
+func (h wasmHost) httpGet(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, plugin.ErrDenied
+	}
+	if !slices.Contains(h.caps.HTTPHosts, u.Host) && !slices.Contains(h.caps.HTTPHosts, u.Hostname()) {
+		return nil, plugin.ErrDenied
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newHTTPClient(h.cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Redirecionamentos passariam por hosts nao liberados.
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("http_get: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, plugin.HostMaxResponse))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"vaultrix-agent/internal/collectors"
+	"vaultrix-agent/internal/config"
+)
+
+const (
+	maxStatusPageTimeoutSeconds = 60
+	maxStatusPageBody           = 1 << 20
+)
+
+// webStatusSampleInterval separa as duas leituras do contador de
+// requisicoes, como a CPU; os testes o encurtam.
+var webStatusSampleInterval = time.Second
+
+func validateStatusPages(kind string, pages []config.StatusPage) error {
+	seen := make(map[string]bool, len(pages))
+	for i, p := range pages {
+		if !config.SinkNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("%s[%d]: invalid name %q (lowercase letters, digits, - and _)", kind, i, p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("%s: duplicate name %q", kind, p.Name)
+		}
+		seen[p.Name] = true
+		u, err := url.Parse(p.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s: %s: url must be an http(s) URL", kind, p.Name)
+		}
+		if p.TimeoutSeconds < 0 || p.TimeoutSeconds > maxStatusPageTimeoutSeconds {
+			return fmt.Errorf("%s: %s: timeout_seconds must be between 0 and %d", kind, p.Name, maxStatusPageTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// WebServerStatus e o que o nginx e o Apache reportam. RequestsPerSec vem
+// de duas leituras com webStatusSampleInterval de intervalo; sem contador
+// de requisicoes (Apache sem ExtendedStatus) fica de fora. Workers conta
+// as conexoes (nginx) ou os workers (Apache) por estado.
+type WebServerStatus struct {
+	Version             string           `json:"version,omitempty"`
+	ActiveConnections   int64            `json:"active_connections"`
+	AcceptedConnections int64            `json:"accepted_connections_total,omitempty"`
+	HandledConnections  int64            `json:"handled_connections_total,omitempty"`
+	RequestsTotal       int64            `json:"requests_total,omitempty"`
+	RequestsPerSec      *float64         `json:"requests_per_sec,omitempty"`
+	BusyWorkers         int64            `json:"busy_workers,omitempty"`
+	IdleWorkers         int64            `json:"idle_workers,omitempty"`
+	Workers             map[string]int64 `json:"workers,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// webStatusParser le uma pagina; hasRequests diz se ela traz o contador.
+type webStatusParser func(body []byte) (s WebServerStatus, hasRequests bool, err error)
+
+// collectStatusPages le todas as paginas em paralelo, cada uma com o seu
+// prazo mais o intervalo entre as leituras.
+func collectStatusPages(pages []config.StatusPage, parse webStatusParser, query string) map[string]WebServerStatus {
+	return collectors.ByName(pages, func(p config.StatusPage) string { return p.Name }, func(p config.StatusPage) WebServerStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), p.Timeout()+webStatusSampleInterval)
+		defer cancel()
+		s, err := sampleStatusPage(ctx, statusPageURL(p.URL, query), parse)
+		if err != nil {
+			return WebServerStatus{Error: err.Error()}
+		}
+		return s
+	})
+}
+
+// statusPageURL acrescenta o parametro que pede o formato de maquina
+// ("auto" no Apache), se a URL configurada nao o tiver.
+func statusPageURL(raw, query string) string {
+	if query == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || slices.Contains(strings.Split(u.RawQuery, "&"), query) {
+		return raw
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += query
+	return u.String()
+}
+
+func sampleStatusPage(ctx context.Context, pageURL string, parse webStatusParser) (WebServerStatus, error) {
+	first, hasRequests, err := fetchStatusPage(ctx, pageURL, parse)
+	if err != nil || !hasRequests {
+		return first, err
+	}
+	started := time.Now()
+	select {
+	case <-time.After(webStatusSampleInterval):
+	case <-ctx.Done():
+		return first, ctx.Err()
+	}
+	second, _, err := fetchStatusPage(ctx, pageURL, parse)
+	if err != nil {
+		return second, err
+	}
+	// a primeira leitura tambem entra no contador
+	delta := max(second.RequestsTotal-first.RequestsTotal-1, 0)
+	rate := math.Round(float64(delta)/time.Since(started).Seconds()*100) / 100
+	second.RequestsPerSec = &rate
+	return second, nil
+}
+
+func fetchStatusPage(ctx context.Context, pageURL string, parse webStatusParser) (WebServerStatus, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return WebServerStatus{}, false, err
+	}
+	req.Header.Set("User-Agent", "vaultrix-agent/"+version)
+	// as paginas sao locais, como os alvos do scrape
+	resp, err := scrapeClient.Do(req)
+	if err != nil {
+		return WebServerStatus{}, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxStatusPageBody))
+		return WebServerStatus{}, false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStatusPageBody))
+	if err != nil {
+		return WebServerStatus{}, false, err
+	}
+	return parse(body)
+}
+
+// parseNginxStatus le o stub_status:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseNginxStatus(body []byte) (WebServerStatus, bool, error) {
+	fields := strings.Fields(string(body))
+	s := WebServerStatus{Workers: map[string]int64{}}
+	found := false
+	for i := 0; i+1 < len(fields); i++ {
+		n, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[i] {
+		case "connections:":
+			s.ActiveConnections, found = n, true
+		case "Reading:", "Writing:", "Waiting:":
+			s.Workers[strings.ToLower(strings.TrimSuffix(fields[i], ":"))] = n
+		}
+	}
+	i := slices.Index(fields, "requests")
+	if !found || i < 0 || len(fields) < i+4 {
+		return s, false, errors.New("nginx: not a stub_status page")
+	}
+	var counters [3]int64
+	for j := range counters {
+		n, err := strconv.ParseInt(fields[i+1+j], 10, 64)
+		if err != nil {
+			return s, false, errors.New("nginx: not a stub_status page")
+		}
+		counters[j] = n
+	}
+	s.AcceptedConnections, s.HandledConnections, s.RequestsTotal = counters[0], counters[1], counters[2]
+	return s, true, nil
+}
+
+// apacheScoreboard traduz as letras do Scoreboard do mod_status.
+var apacheScoreboard = map[rune]string{
+	'_': "waiting",
+	'S': "starting",
+	'R': "reading",
+	'W': "sending",
+	'K': "keepalive",
+	'D': "dns",
+	'C': "closing",
+	'L': "logging",
+	'G': "finishing",
+	'I': "idle_cleanup",
+	'.': "open",
+}
+
+// parseApacheStatus le o formato ?auto do mod_status. Total Accesses so
+// existe com ExtendedStatus On (o padrao desde o 2.3.6); ConnsTotal so com
+// a MPM event, e sem ele as conexoes ativas sao os workers ocupados.
+func parseApacheStatus(body []byte) (WebServerStatus, bool, error) {
+	values := map[string]string{}
+	for _, line := range strings.Split(string(body), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if _, ok := values["BusyWorkers"]; !ok {
+		return WebServerStatus{}, false, errors.New("apache: not a mod_status ?auto page")
+	}
+	num := func(key string) int64 {
+		n, _ := strconv.ParseInt(values[key], 10, 64)
+		return n
+	}
+	s := WebServerStatus{
+		Version:       values["ServerVersion"],
+		RequestsTotal: num("Total Accesses"),
+		BusyWorkers:   num("BusyWorkers"),
+		IdleWorkers:   num("IdleWorkers"),
+	}
+	s.ActiveConnections = s.BusyWorkers
+	if _, ok := values["ConnsTotal"]; ok {
+		s.ActiveConnections = num("ConnsTotal")
+	}
+	if board := values["Scoreboard"]; board != "" {
+		s.Workers = map[string]int64{}
+		for _, c := range board {
+			if state, ok := apacheScoreboard[c]; ok {
+				s.Workers[state]++
+			}
+		}
+	}
+	_, hasRequests := values["Total Accesses"]
+	return s, hasRequests, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vaultrix-agent/internal/config"
+)
+
+func TestParseNginxStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    WebServerStatus
+		wantErr bool
+	}{
+		{
+			name: "stub_status",
+			body: "Active connections: 291 \nserver accepts handled requests\n 16630948 16630948 31070465 \nReading: 6 Writing: 179 Waiting: 106 \n",
+			want: WebServerStatus{
+				ActiveConnections: 291, AcceptedConnections: 16630948, HandledConnections: 16630948, RequestsTotal: 31070465,
+				Workers: map[string]int64{"reading": 6, "writing": 179, "waiting": 106},
+			},
+		},
+		{name: "html page", body: "<html><body>Welcome to nginx!</body></html>", wantErr: true},
+		{name: "truncated", body: "Active connections: 1\nserver accepts handled requests\n 10 10\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasRequests, err := parseNginxStatus([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil || !hasRequests {
+				t.Fatalf("err %v, hasRequests %v", err, hasRequests)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseApacheStatus(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		want            WebServerStatus
+		wantHasRequests bool
+		wantErr         bool
+	}{
+		{
+			name: "event mpm",
+			body: "localhost\nServerVersion: Apache/2.4.58 (Unix)\nTotal Accesses: 5000\nTotal kBytes: 1200\nBusyWorkers: 3\nIdleWorkers: 72\nConnsTotal: 9\nScoreboard: __W_K_R...\n",
+			want: WebServerStatus{
+				Version: "Apache/2.4.58 (Unix)", ActiveConnections: 9, RequestsTotal: 5000, BusyWorkers: 3, IdleWorkers: 72,
+				Workers: map[string]int64{"waiting": 4, "sending": 1, "keepalive": 1, "reading": 1, "open": 3},
+			},
+			wantHasRequests: true,
+		},
+		{
+			name: "prefork without extended status",
+			body: "BusyWorkers: 2\nIdleWorkers: 8\nScoreboard: WW________\n",
+			want: WebServerStatus{
+				ActiveConnections: 2, BusyWorkers: 2, IdleWorkers: 8,
+				Workers: map[string]int64{"sending": 2, "waiting": 8},
+			},
+		},
+		{name: "html page", body: "<html><head><title>Apache Status</title></head></html>", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasRequests, err := parseApacheStatus([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hasRequests != tt.wantHasRequests {
+				t.Errorf("hasRequests = %v, want %v", hasRequests, tt.wantHasRequests)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusPageURL(t *testing.T) {
+	tests := []struct{ raw, query, want string }{
+		{"http://127.0.0.1/nginx_status", "", "http://127.0.0.1/nginx_status"},
+		{"http://127.0.0.1/server-status", "auto", "http://127.0.0.1/server-status?auto"},
+		{"http://127.0.0.1/server-status?auto", "auto", "http://127.0.0.1/server-status?auto"},
+		{"http://127.0.0.1/server-status?refresh=5", "auto", "http://127.0.0.1/server-status?refresh=5&auto"},
+	}
+	for _, tt := range tests {
+		if got := statusPageURL(tt.raw, tt.query); got != tt.want {
+			t.Errorf("statusPageURL(%q, %q) = %q, want %q", tt.raw, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStatusPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		pages   []config.StatusPage
+		wantErr string
+	}{
+		{"ok", []config.StatusPage{{Name: "web", URL: "http://127.0.0.1/nginx_status"}}, ""},
+		{"bad name", []config.StatusPage{{Name: "Web", URL: "http://127.0.0.1/"}}, "invalid name"},
+		{"duplicate", []config.StatusPage{{Name: "a", URL: "http://h/"}, {Name: "a", URL: "http://h/"}}, "duplicate name"},
+		{"no scheme", []config.StatusPage{{Name: "a", URL: "127.0.0.1/nginx_status"}}, "http(s) URL"},
+		{"timeout", []config.StatusPage{{Name: "a", URL: "http://h/", TimeoutSeconds: 61}}, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStatusPages("nginx", tt.pages)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCollectStatusPages(t *testing.T) {
+	defer func(d time.Duration) { webStatusSampleInterval = d }(webStatusSampleInterval)
+	webStatusSampleInterval = 50 * time.Millisecond
+
+	// cada leitura soma 11 requisicoes: a propria e mais 10
+	var requests atomic.Int64
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(11)
+		fmt.Fprintf(w, "Active connections: 3\nserver accepts handled requests\n 5 5 %d\nReading: 0 Writing: 1 Waiting: 2\n", n)
+	}))
+	defer nginx.Close()
+	var query atomic.Value
+	apache := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query.Store(r.URL.RawQuery)
+		fmt.Fprint(w, "BusyWorkers: 1\nIdleWorkers: 4\nScoreboard: W____\n")
+	}))
+	defer apache.Close()
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	got := collectStatusPages([]config.StatusPage{
+		{Name: "web", URL: nginx.URL + "/nginx_status"},
+		{Name: "missing", URL: broken.URL + "/nginx_status"},
+	}, parseNginxStatus, "")
+	web := got["web"]
+	if web.Error != "" || web.RequestsPerSec == nil || web.RequestsTotal != 22 {
+		t.Fatalf("web: got %+v", web)
+	}
+	// 10 requisicoes em ~50ms; a folga cobre a lentidao do ambiente
+	if rate := *web.RequestsPerSec; rate <= 0 || rate > 200 {
+		t.Errorf("requests per sec = %v, want about 200", rate)
+	}
+	if got["missing"].Error != "status 404" {
+		t.Errorf("missing: got %+v", got["missing"])
+	}
+
+	got = collectStatusPages([]config.StatusPage{{Name: "web", URL: apache.URL + "/server-status"}}, parseApacheStatus, "auto")
+	if s := got["web"]; s.Error != "" || s.RequestsPerSec != nil || s.BusyWorkers != 1 {
+		t.Errorf("apache: got %+v", s)
+	}
+	if q, _ := query.Load().(string); q != "auto" {
+		t.Errorf("apache query = %q, want auto", q)
+	}
+}
@@ -37,10 +37,21 @@ const telemetrySchema = z.object({
       netIO: z.string().optional(),
       blockIO: z.string().optional(),
       pids: z.number().optional(),
+      memUsageBytes: z.number().optional(),
+      memLimitBytes: z.number().optional(),
+      netRxBytes: z.number().optional(),
+      netTxBytes: z.number().optional(),
+      blockReadBytes: z.number().optional(),
+      blockWriteBytes: z.number().optional(),
     })
   ).optional(),
 })
 
+const probeSchema = z.object({
+  token: z.string().min(16),
+  probe: z.literal(true),
+})
+
 export const runtime = 'nodejs'
 
 export async function POST(request: NextRequest) {
@@ -51,6 +62,19 @@ export async function POST(request: NextRequest) {
     return NextResponse.json({ error: 'Invalid payload' }, { status: 400 })
   }
 
+  // Probe do preflight do agente: so confere o token, sem gravar telemetria
+  if (probeSchema.safeParse(body).success) {
+    const { token } = probeSchema.parse(body)
+    const machine = await prisma.machine.findUnique({
+      where: { telemetryToken: hashToken(token) },
+      select: { isActive: true },
+    })
+    if (!machine || !machine.isActive) {
+      return NextResponse.json({ error: 'Invalid token' }, { status: 401 })
+    }
+    return new NextResponse(null, { status: 204 })
+  }
+
   const validation = telemetrySchema.safeParse(body)
   if (!validation.success) {
     return NextResponse.json(
@@ -145,6 +169,12 @@ async function processAlerts(params: {
     netIO?: string
     blockIO?: string
     pids?: number
+    memUsageBytes?: number
+    memLimitBytes?: number
+    netRxBytes?: number
+    netTxBytes?: number
+    blockReadBytes?: number
+    blockWriteBytes?: number
   }>
 }) {
   try {